@@ -52,6 +52,7 @@ type TestingKnobs struct {
 	AdmissionControl             ModuleTestingKnobs
 	UnusedIndexRecommendKnobs    ModuleTestingKnobs
 	ExternalConnection           ModuleTestingKnobs
+	Cloud                        ModuleTestingKnobs
 	EventExporter                ModuleTestingKnobs
 	EventLog                     ModuleTestingKnobs
 }
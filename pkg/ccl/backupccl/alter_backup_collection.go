@@ -0,0 +1,463 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/featureflag"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// alterBackupCollectionHeader is the header of ALTER BACKUP COLLECTION ...
+// RECOVER LATEST, which returns the full backup LATEST now points at.
+var alterBackupCollectionHeader = colinfo.ResultColumns{
+	{Name: "path", Typ: types.String},
+	{Name: "end_time", Typ: types.Timestamp},
+}
+
+// upgradeBackupCollectionLayoutHeader is the header of ALTER BACKUP COLLECTION
+// ... UPGRADE LAYOUT, which returns a row for each file or directory it moved.
+var upgradeBackupCollectionLayoutHeader = colinfo.ResultColumns{
+	{Name: "change", Typ: types.String},
+	{Name: "source", Typ: types.String},
+	{Name: "destination", Typ: types.String},
+}
+
+func alterBackupCollectionPlanHook(
+	ctx context.Context, stmt tree.Statement, p sql.PlanHookState,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	alterStmt, ok := stmt.(*tree.AlterBackupCollection)
+	if !ok {
+		return nil, nil, nil, false, nil
+	}
+
+	if err := featureflag.CheckEnabled(
+		ctx,
+		p.ExecCfg(),
+		featureBackupEnabled,
+		"ALTER BACKUP COLLECTION",
+	); err != nil {
+		return nil, nil, nil, false, err
+	}
+
+	collectionFn, err := p.TypeAsString(ctx, alterStmt.Collection, "ALTER BACKUP COLLECTION")
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		collection, err := collectionFn()
+		if err != nil {
+			return err
+		}
+		if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, []string{collection}); err != nil {
+			return err
+		}
+
+		store, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, collection, p.User())
+		if err != nil {
+			return errors.Wrapf(err, "connect to external storage")
+		}
+		defer store.Close()
+
+		if alterStmt.UpgradeLayout {
+			return upgradeBackupCollectionLayout(ctx, p, collection, store, resultsCh)
+		}
+
+		subdir, endTime, err := findLatestValidFullBackup(ctx, p, collection, store)
+		if err != nil {
+			return err
+		}
+		if err := backupdest.WriteNewLatestFile(ctx, p.ExecCfg().Settings, store,
+			backuppb.LatestFile{Subdir: subdir}); err != nil {
+			return errors.Wrapf(err, "writing LATEST file in %s",
+				backuputils.RedactURIForErrorMessage(collection))
+		}
+
+		end, err := tree.MakeDTimestamp(timeutil.Unix(0, endTime.WallTime), time.Nanosecond)
+		if err != nil {
+			return err
+		}
+		resultsCh <- tree.Datums{tree.NewDString(subdir), end}
+		return nil
+	}
+	if alterStmt.UpgradeLayout {
+		return fn, upgradeBackupCollectionLayoutHeader, nil, false, nil
+	}
+	return fn, alterBackupCollectionHeader, nil, false, nil
+}
+
+// upgradeBackupCollectionLayout moves the files of a collection that are where
+// older versions wrote them to where current versions write them:
+//   - the incremental backups of each full backup that were written in its
+//     subdirectory move to the incrementals subdirectory of the collection;
+//   - a LATEST file in the base directory of the collection moves to the
+//     latest-history directory.
+//
+// Each moved file is checked to have been copied in full before its source is
+// deleted. The upgrade is idempotent, so that an upgrade that failed part way
+// can be run again. It must not run concurrently with backups into the
+// collection. A locality-aware collection is upgraded by running it against
+// the URI of each locality.
+func upgradeBackupCollectionLayout(
+	ctx context.Context,
+	p sql.PlanHookState,
+	collection string,
+	store cloud.ExternalStorage,
+	resultsCh chan<- tree.Datums,
+) error {
+	if !cloud.SupportsListing(store) {
+		return pgerror.Newf(pgcode.FeatureNotSupported,
+			"cannot upgrade the layout of a collection in storage that does not support listing")
+	}
+	redactedURI := backuputils.RedactURIForErrorMessage(collection)
+
+	subdirs, err := backupdest.ListFullBackupsInCollection(ctx, store)
+	if err != nil {
+		return err
+	}
+	// Find the incremental backups to move before moving any, so that a
+	// collection with incremental backups in both places is left as it is.
+	type legacyIncrementals struct {
+		subdir     string
+		incSubdirs []string
+	}
+	var toMove []legacyIncrementals
+	for _, subdir := range subdirs {
+		subdir = "/" + strings.TrimPrefix(subdir, "/")
+		oldIncs, err := findIncrementalBackups(ctx, p, collection, subdir)
+		if err != nil {
+			return err
+		}
+		if len(oldIncs) == 0 {
+			continue
+		}
+		newIncs, err := findIncrementalBackups(ctx, p, collection,
+			"/"+backupbase.DefaultIncrementalsSubdir+subdir)
+		if err != nil {
+			return err
+		}
+		if len(newIncs) > 0 {
+			return errors.Newf("backup %s in %s has incremental backups in both its "+
+				"subdirectory and the incrementals subdirectory", subdir, redactedURI)
+		}
+		toMove = append(toMove, legacyIncrementals{subdir: subdir, incSubdirs: oldIncs})
+	}
+
+	// Restores that read the incremental backups would fail if they were moved
+	// from under them.
+	protected, err := protectedCollectionLayers(ctx, p.ExecCfg(), store)
+	if err != nil {
+		return err
+	}
+	for _, m := range toMove {
+		for _, incSubdir := range m.incSubdirs {
+			src := strings.TrimPrefix(m.subdir, "/") + incSubdir
+			if jobID, ok := protected[strings.Trim(src, "/")]; ok {
+				return pgerror.Newf(pgcode.ObjectInUse,
+					"incremental backup %s in %s is being read by restore job %d", src, redactedURI, jobID)
+			}
+		}
+	}
+
+	for _, m := range toMove {
+		for _, incSubdir := range m.incSubdirs {
+			src := strings.TrimPrefix(m.subdir, "/") + incSubdir
+			dst := backupbase.DefaultIncrementalsSubdir + m.subdir + incSubdir
+			if err := moveBackupLayer(ctx, store, src, dst); err != nil {
+				return errors.Wrapf(err, "moving incremental backup %s in %s", src, redactedURI)
+			}
+			resultsCh <- tree.Datums{
+				tree.NewDString("incremental"), tree.NewDString(src), tree.NewDString(dst),
+			}
+		}
+		// Check that every incremental backup of the full backup is now found
+		// in the incrementals subdirectory, and none in its old place.
+		newIncs, err := findIncrementalBackups(ctx, p, collection,
+			"/"+backupbase.DefaultIncrementalsSubdir+m.subdir)
+		if err != nil {
+			return err
+		}
+		oldIncs, err := findIncrementalBackups(ctx, p, collection, m.subdir)
+		if err != nil {
+			return err
+		}
+		if len(oldIncs) > 0 || len(newIncs) != len(m.incSubdirs) {
+			return errors.AssertionFailedf("moved %d incremental backups of %s, but found %d in "+
+				"the incrementals subdirectory and %d in its subdirectory",
+				len(m.incSubdirs), m.subdir, len(newIncs), len(oldIncs))
+		}
+	}
+
+	moved, err := moveBaseLatestFile(ctx, p, store)
+	if err != nil {
+		return errors.Wrapf(err, "moving LATEST file in %s", redactedURI)
+	}
+	if moved {
+		resultsCh <- tree.Datums{
+			tree.NewDString("latest"),
+			tree.NewDString(backupbase.LatestFileName),
+			tree.NewDString(backupbase.LatestHistoryDirectory),
+		}
+	}
+	return nil
+}
+
+// findIncrementalBackups returns the subdirectories of the incremental backups
+// in dir of the collection.
+func findIncrementalBackups(
+	ctx context.Context, p sql.PlanHookState, collection, dir string,
+) ([]string, error) {
+	uris, err := backuputils.AppendPaths([]string{collection}, dir)
+	if err != nil {
+		return nil, err
+	}
+	store, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, uris[0], p.User())
+	if err != nil {
+		return nil, errors.Wrapf(err, "make storage")
+	}
+	defer store.Close()
+	return backupdest.FindPriorBackups(ctx, store, false /* includeManifest */)
+}
+
+// moveBackupLayer copies the files of the backup in src of the store to dst,
+// checks their sizes, then deletes them from src. The manifest is copied last
+// and deleted first, so that a move that fails part way never leaves a backup
+// that is found in both places, or in neither.
+func moveBackupLayer(ctx context.Context, store cloud.ExternalStorage, src, dst string) error {
+	var files, manifests []string
+	if err := store.List(ctx, src, "" /* delimiter */, func(f string) error {
+		f = strings.TrimPrefix(f, "/")
+		if f == backupbase.BackupManifestName || f == backupbase.BackupOldManifestName {
+			manifests = append(manifests, f)
+		} else {
+			files = append(files, f)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return errors.AssertionFailedf("no manifest found in %s", src)
+	}
+
+	for _, f := range append(files, manifests...) {
+		if err := store.Copy(ctx, src+"/"+f, dst+"/"+f); err != nil {
+			return err
+		}
+		srcSize, err := store.Size(ctx, src+"/"+f)
+		if err != nil {
+			return err
+		}
+		dstSize, err := store.Size(ctx, dst+"/"+f)
+		if err != nil {
+			return err
+		}
+		if srcSize != dstSize {
+			return errors.Newf("copy of %s has %d bytes rather than %d", f, dstSize, srcSize)
+		}
+	}
+	for _, f := range append(manifests, files...) {
+		if err := store.Delete(ctx, src+"/"+f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveBaseLatestFile moves a LATEST file in the base directory of the store to
+// the latest-history directory, unless that directory already has one, which
+// takes precedence over it, in which case it is only deleted. It returns
+// whether there was a LATEST file in the base directory.
+func moveBaseLatestFile(
+	ctx context.Context, p sql.PlanHookState, store cloud.ExternalStorage,
+) (bool, error) {
+	r, err := store.ReadFile(ctx, backupbase.LatestFileName)
+	if err != nil {
+		if errors.Is(err, cloud.ErrFileDoesNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	data, err := ioctx.ReadAll(ctx, r)
+	r.Close(ctx)
+	if err != nil {
+		return false, err
+	}
+	latest, err := backupdest.DecodeLatestFile(data)
+	if err != nil {
+		return false, err
+	}
+
+	var inHistory bool
+	if err := store.ListWithOptions(ctx, backupbase.LatestHistoryDirectory,
+		cloud.ListOptions{MaxResults: 1}, func(string) error {
+			inHistory = true
+			return nil
+		}); err != nil {
+		return false, err
+	}
+	if !inHistory {
+		if err := backupdest.WriteNewLatestFile(ctx, p.ExecCfg().Settings, store, latest); err != nil {
+			return false, err
+		}
+		r, err := backupdest.FindLatestFile(ctx, store)
+		if err != nil {
+			return false, err
+		}
+		data, err := ioctx.ReadAll(ctx, r)
+		r.Close(ctx)
+		if err != nil {
+			return false, err
+		}
+		written, err := backupdest.DecodeLatestFile(data)
+		if err != nil {
+			return false, err
+		}
+		if written.Subdir != latest.Subdir {
+			return false, errors.Newf("LATEST file in %s points at %q rather than %q",
+				backupbase.LatestHistoryDirectory, written.Subdir, latest.Subdir)
+		}
+	}
+	return true, store.Delete(ctx, backupbase.LatestFileName)
+}
+
+// findLatestValidFullBackup returns the subdirectory and end time of the full
+// backup in the collection with the most recent end time whose manifest can be
+// read and whose data files are all present. Backups that fail these checks,
+// such as encrypted ones whose manifest cannot be read without a key, are
+// skipped with a notice.
+func findLatestValidFullBackup(
+	ctx context.Context, p sql.PlanHookState, collection string, store cloud.ExternalStorage,
+) (string, hlc.Timestamp, error) {
+	// ListFullBackupsInCollection only lists the backups which have a manifest,
+	// i.e. which completed.
+	subdirs, err := backupdest.ListFullBackupsInCollection(ctx, store)
+	if err != nil {
+		return "", hlc.Timestamp{}, err
+	}
+
+	mem := p.ExecCfg().RootMemoryMonitor.MakeBoundAccount()
+	defer mem.Close(ctx)
+
+	type fullBackup struct {
+		subdir  string
+		endTime hlc.Timestamp
+	}
+	var candidates []fullBackup
+	for _, subdir := range subdirs {
+		subdir = "/" + strings.TrimPrefix(subdir, "/")
+		var endTime hlc.Timestamp
+		if err := withFullBackupManifest(ctx, p, &mem, collection, subdir,
+			func(_ context.Context, _ cloud.ExternalStorage, manifest *backuppb.BackupManifest) error {
+				endTime = manifest.EndTime
+				return nil
+			}); err != nil {
+			p.BufferClientNotice(ctx, pgnotice.Newf("skipping backup %s: %v", subdir, err))
+			continue
+		}
+		candidates = append(candidates, fullBackup{subdir: subdir, endTime: endTime})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[j].endTime.Less(candidates[i].endTime)
+	})
+
+	for _, c := range candidates {
+		if err := withFullBackupManifest(ctx, p, &mem, collection, c.subdir,
+			validateFullBackupFiles); err != nil {
+			p.BufferClientNotice(ctx, pgnotice.Newf("skipping backup %s: %v", c.subdir, err))
+			continue
+		}
+		return c.subdir, c.endTime, nil
+	}
+	return "", hlc.Timestamp{}, pgerror.Newf(pgcode.UndefinedFile,
+		"no complete full backup found in %s", backuputils.RedactURIForErrorMessage(collection))
+}
+
+// withFullBackupManifest reads the manifest of the full backup in subdir of
+// the collection and calls fn with it and the store of the backup.
+func withFullBackupManifest(
+	ctx context.Context,
+	p sql.PlanHookState,
+	mem *mon.BoundAccount,
+	collection, subdir string,
+	fn func(ctx context.Context, store cloud.ExternalStorage, manifest *backuppb.BackupManifest) error,
+) error {
+	uris, err := backuputils.AppendPaths([]string{collection}, subdir)
+	if err != nil {
+		return err
+	}
+	store, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, uris[0], p.User())
+	if err != nil {
+		return errors.Wrapf(err, "make storage")
+	}
+	defer store.Close()
+
+	manifest, memSize, err := backupinfo.ReadBackupManifestFromStore(ctx, mem, store,
+		nil /* encryption */, nil /* kmsEnv */, false /* elideFiles */)
+	if err != nil {
+		return err
+	}
+	defer mem.Shrink(ctx, memSize)
+	return fn(ctx, store, &manifest)
+}
+
+// validateFullBackupFiles checks that every data file of a full backup that is
+// stored with it, rather than in the store of a locality, is present.
+func validateFullBackupFiles(
+	ctx context.Context, store cloud.ExternalStorage, manifest *backuppb.BackupManifest,
+) error {
+	it, err := backupinfo.NewLayerFileIter(ctx, store, manifest, nil /* encryption */, nil /* kmsEnv */)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	checked := make(map[string]struct{})
+	var file backuppb.BackupManifest_File
+	for it.Next(&file) {
+		if file.LocalityKV != "" {
+			continue
+		}
+		if _, ok := checked[file.Path]; ok {
+			continue
+		}
+		checked[file.Path] = struct{}{}
+		if _, err := store.Size(ctx, file.Path); err != nil {
+			return errors.Wrapf(err, "checking data file %s", file.Path)
+		}
+	}
+	return it.Err()
+}
+
+func init() {
+	sql.AddPlanHook("alter backup collection", alterBackupCollectionPlanHook)
+}
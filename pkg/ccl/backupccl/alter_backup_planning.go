@@ -89,7 +89,7 @@ func alterBackupPlanHook(
 				if err != nil {
 					return err
 				}
-				subdir = latest
+				subdir = latest.Subdir
 			}
 
 			appendPaths := func(uri string, tailDir string) (string, error) {
@@ -147,6 +147,13 @@ func doAlterBackupPlan(
 	if err != nil {
 		return err
 	}
+	for _, encFile := range opts {
+		// The data key of such a backup is split into shares that are each
+		// encrypted with one KMS, so no single KMS can be used to add another.
+		if encFile.DecryptQuorum > 1 {
+			return errors.New("cannot add a KMS to a backup encrypted with decrypt_quorum")
+		}
+	}
 
 	ioConf := baseStore.ExternalIOConf()
 	kmsEnv := backupencryption.MakeBackupKMSEnv(baseStore.Settings(), &ioConf, p.ExecCfg().DB,
@@ -298,6 +298,16 @@ func processScheduleOptions(
 				continue
 			}
 			s.incArgs.UpdatesLastBackupMetric = updatesLastBackupMetric
+		case optIncChangeThreshold:
+			if s.incArgs == nil {
+				return errors.Newf("%s requires a schedule that runs incremental backups", k)
+			}
+			threshold, err := parseIncChangeThreshold(v)
+			if err != nil {
+				return err
+			}
+			s.fullArgs.IncrementalChangeThreshold = threshold
+			s.incArgs.IncrementalChangeThreshold = threshold
 		default:
 			return errors.Newf("unexpected schedule option: %s = %s", k, v)
 		}
@@ -440,6 +450,7 @@ func processFullBackupRecurrence(
 			s.fullArgs.UpdatesLastBackupMetric,
 			s.incStmt,
 			s.fullArgs.ChainProtectedTimestampRecords,
+			s.fullArgs.IncrementalChangeThreshold,
 		)
 
 		if err != nil {
@@ -449,7 +460,7 @@ func processFullBackupRecurrence(
 		// We don't know if a full backup has completed yet, so pause incremental
 		// until a full backup completes.
 		s.incJob.Pause()
-		s.incJob.SetScheduleStatus("Waiting for initial backup to complete")
+		s.incJob.SetScheduleStatus(waitingForInitialBackupStatus)
 		s.incArgs.DependentScheduleID = s.fullJob.ScheduleID()
 
 		incAny, err := pbtypes.MarshalAny(s.incArgs)
@@ -550,7 +561,7 @@ func processInto(p sql.PlanHookState, eval *alterBackupScheduleEval, s scheduleD
 	// With a new destination, no full backup has completed yet.
 	// Pause incrementals until a full backup completes.
 	s.incJob.Pause()
-	s.incJob.SetScheduleStatus("Waiting for initial backup to complete")
+	s.incJob.SetScheduleStatus(waitingForInitialBackupStatus)
 	s.fullArgs.UnpauseOnSuccess = s.incJob.ScheduleID()
 
 	// Kick off a full backup immediately so we can unpause incrementals.
@@ -640,6 +651,7 @@ func makeAlterBackupScheduleEval(
 			optOnExecFailure:           sql.KVStringOptAny,
 			optOnPreviousRunning:       sql.KVStringOptAny,
 			optUpdatesLastBackupMetric: sql.KVStringOptAny,
+			optIncChangeThreshold:      sql.KVStringOptAny,
 		})
 		if err != nil {
 			return nil, err
@@ -142,3 +142,44 @@ INSERT INTO t1 values (1), (10), (100);
 		backupStmts)
 
 }
+
+func TestAlterBackupSchedulesForCollection(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	th, cleanup := newAlterSchedulesTestHelper(t)
+	defer cleanup()
+
+	th.sqlDB.Exec(t, `
+CREATE DATABASE mydb;
+USE mydb;
+
+CREATE TABLE t1(a int);
+`)
+	th.sqlDB.Exec(t, `CREATE SCHEDULE 'a' FOR BACKUP t1 INTO 'nodelocal://0/backup/a' RECURRING '@daily'`)
+	th.sqlDB.Exec(t, `CREATE SCHEDULE 'a-slash' FOR BACKUP t1 INTO 'nodelocal://0/backup/a/'
+RECURRING '@hourly' FULL BACKUP ALWAYS`)
+	th.sqlDB.Exec(t, `CREATE SCHEDULE 'b' FOR BACKUP t1 INTO 'nodelocal://0/backup/b' RECURRING '@daily'`)
+
+	paused := `SELECT schedule_name, next_run IS NULL FROM system.scheduled_jobs
+WHERE executor_type = 'scheduled-backup-executor' ORDER BY schedule_id`
+
+	th.sqlDB.CheckQueryResults(t,
+		`SELECT label, next_run IS NULL FROM [ALTER BACKUP SCHEDULES FOR COLLECTION 'nodelocal://0/backup/a' PAUSE]`,
+		[][]string{{"a", "true"}, {"a", "true"}, {"a-slash", "true"}})
+	th.sqlDB.CheckQueryResults(t, paused, [][]string{
+		{"a", "true"}, {"a", "true"}, {"a-slash", "true"}, {"b", "true"}, {"b", "false"},
+	})
+
+	// The incremental schedule of 'a' still waits for its first full backup.
+	th.sqlDB.CheckQueryResults(t,
+		`SELECT label, next_run IS NULL FROM [ALTER BACKUP SCHEDULES FOR COLLECTION 'nodelocal://0/backup/a' RESUME]`,
+		[][]string{{"a", "true"}, {"a", "false"}, {"a-slash", "false"}})
+	th.sqlDB.CheckQueryResults(t, paused, [][]string{
+		{"a", "true"}, {"a", "false"}, {"a-slash", "false"}, {"b", "true"}, {"b", "false"},
+	})
+
+	th.sqlDB.CheckQueryResults(t,
+		`SELECT count(*) FROM [ALTER BACKUP SCHEDULES FOR COLLECTION 'nodelocal://0/backup/c' PAUSE]`,
+		[][]string{{"0"}})
+}
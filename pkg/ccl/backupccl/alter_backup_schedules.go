@@ -0,0 +1,217 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/featureflag"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/errors"
+	pbtypes "github.com/gogo/protobuf/types"
+)
+
+const alterBackupSchedulesOp = "ALTER BACKUP SCHEDULES"
+
+// alterBackupSchedulesHeader is the header of ALTER BACKUP SCHEDULES FOR
+// COLLECTION, which returns a row for each schedule it paused or resumed.
+var alterBackupSchedulesHeader = colinfo.ResultColumns{
+	{Name: "schedule_id", Typ: types.Int},
+	{Name: "label", Typ: types.String},
+	{Name: "next_run", Typ: types.TimestampTZ},
+}
+
+func alterBackupSchedulesPlanHook(
+	ctx context.Context, stmt tree.Statement, p sql.PlanHookState,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	alterStmt, ok := stmt.(*tree.AlterBackupSchedules)
+	if !ok {
+		return nil, nil, nil, false, nil
+	}
+
+	if err := featureflag.CheckEnabled(
+		ctx,
+		p.ExecCfg(),
+		featureBackupEnabled,
+		alterBackupSchedulesOp,
+	); err != nil {
+		return nil, nil, nil, false, err
+	}
+
+	collectionFn, err := p.TypeAsString(ctx, alterStmt.Collection, alterBackupSchedulesOp)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		collection, err := collectionFn()
+		if err != nil {
+			return err
+		}
+		return doAlterBackupSchedulesForCollection(ctx, p, collection, alterStmt.Command, resultsCh)
+	}
+	return fn, alterBackupSchedulesHeader, nil, false, nil
+}
+
+// doAlterBackupSchedulesForCollection pauses or resumes every backup schedule
+// whose destinations include the collection.
+//
+// Resuming a schedule schedules its next run, unless it is an incremental
+// schedule that is still waiting for the first run of its full schedule: that
+// one is resumed by the full schedule, as it would have been had it not been
+// paused.
+func doAlterBackupSchedulesForCollection(
+	ctx context.Context,
+	p sql.PlanHookState,
+	collection string,
+	command tree.ScheduleCommand,
+	resultsCh chan<- tree.Datums,
+) error {
+	collectionKey, err := backupDestinationKey(collection)
+	if err != nil {
+		return err
+	}
+	schedules, err := loadBackupSchedulesForCollection(ctx, p, collectionKey)
+	if err != nil {
+		return err
+	}
+
+	isAdmin, err := p.UserHasAdminRole(ctx, p.User())
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		if !isAdmin && schedule.Owner() != p.User() {
+			return pgerror.Newf(pgcode.InsufficientPrivilege, "must be admin or owner of the "+
+				"schedule %d to %s it", schedule.ScheduleID(), command.String())
+		}
+	}
+
+	for _, schedule := range schedules {
+		switch command {
+		case tree.PauseSchedule:
+			schedule.Pause()
+		case tree.ResumeSchedule:
+			if !schedule.IsPaused() || schedule.ScheduleStatus() == waitingForInitialBackupStatus {
+				break
+			}
+			if err := schedule.ScheduleNextRun(); err != nil {
+				return err
+			}
+		default:
+			return errors.AssertionFailedf("unhandled command %s", command)
+		}
+		if err := schedule.Update(ctx, p.ExecCfg().InternalExecutor, p.Txn()); err != nil {
+			return err
+		}
+		telemetry.Inc(sqltelemetry.ScheduledBackupControlCounter(strings.ToLower(command.String())))
+
+		nextRun := tree.DNull
+		if !schedule.IsPaused() {
+			if nextRun, err = tree.MakeDTimestampTZ(schedule.NextRun(), time.Microsecond); err != nil {
+				return err
+			}
+		}
+		resultsCh <- tree.Datums{
+			tree.NewDInt(tree.DInt(schedule.ScheduleID())),
+			tree.NewDString(schedule.ScheduleLabel()),
+			nextRun,
+		}
+	}
+	return nil
+}
+
+// loadBackupSchedulesForCollection returns the backup schedules, in the order
+// of their IDs, that back up into the collection identified by collectionKey,
+// or write their incremental backups to it.
+func loadBackupSchedulesForCollection(
+	ctx context.Context, p sql.PlanHookState, collectionKey string,
+) ([]*jobs.ScheduledJob, error) {
+	env := sql.JobSchedulerEnv(p.ExecCfg())
+	// Run the query as the root user since the privileges on each schedule
+	// are checked before it is altered.
+	rows, cols, err := p.ExecCfg().InternalExecutor.QueryBufferedExWithCols(
+		ctx,
+		"load-backup-schedules",
+		p.Txn(), sessiondata.InternalExecutorOverride{User: username.RootUserName()},
+		fmt.Sprintf("SELECT * FROM %s WHERE executor_type = $1 ORDER BY schedule_id",
+			env.ScheduledJobsTableName()),
+		tree.ScheduledBackupExecutor.InternalName())
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []*jobs.ScheduledJob
+	for _, row := range rows {
+		schedule := jobs.NewScheduledJob(env)
+		if err := schedule.InitFromDatums(row, cols); err != nil {
+			return nil, err
+		}
+		args := &backuppb.ScheduledBackupExecutionArgs{}
+		if err := pbtypes.UnmarshalAny(schedule.ExecutionArgs().Args, args); err != nil {
+			return nil, errors.Wrapf(err, "un-marshaling args of schedule %d", schedule.ScheduleID())
+		}
+		node, err := parser.ParseOne(args.BackupStatement)
+		if err != nil {
+			return nil, err
+		}
+		backupStmt, ok := node.AST.(*tree.Backup)
+		if !ok {
+			return nil, errors.Newf("unexpected node type %T in schedule %d",
+				node.AST, schedule.ScheduleID())
+		}
+
+		dests := append(tree.Exprs(nil), backupStmt.To...)
+		dests = append(dests, backupStmt.Options.IncrementalStorage...)
+		for _, dest := range dests {
+			key, err := backupDestinationKey(tree.AsStringWithFlags(dest, tree.FmtBareStrings))
+			if err != nil {
+				return nil, errors.Wrapf(err, "resolving destination of schedule %d", schedule.ScheduleID())
+			}
+			if key == collectionKey {
+				schedules = append(schedules, schedule)
+				break
+			}
+		}
+	}
+	return schedules, nil
+}
+
+// backupDestinationKey returns the location in storage of the backup
+// destination at uri: its scheme, host and path, without the parameters of the
+// URI, such as its credentials or locality, or a trailing slash.
+func backupDestinationKey(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", backuputils.RedactURLParseError(err)
+	}
+	return fmt.Sprintf("%s://%s%s", strings.ToLower(parsed.Scheme), parsed.Host,
+		strings.TrimSuffix(parsed.Path, "/")), nil
+}
+
+func init() {
+	sql.AddPlanHook("alter backup schedules", alterBackupSchedulesPlanHook)
+}
@@ -0,0 +1,314 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+)
+
+// tenantBackupConcurrency bounds the number of tenants BACKUP VIRTUAL CLUSTER
+// ALL backs up at the same time.
+var tenantBackupConcurrency = settings.RegisterIntSetting(
+	settings.SystemOnly,
+	"bulkio.backup.virtual_cluster_concurrency",
+	"the maximum number of tenants backed up concurrently by BACKUP VIRTUAL CLUSTER ALL",
+	4,
+	settings.PositiveInt,
+)
+
+var allTenantsBackupHeader = colinfo.ResultColumns{
+	{Name: "tenant_id", Typ: types.Int},
+	{Name: "job_id", Typ: types.Int},
+	{Name: "status", Typ: types.String},
+	{Name: "path", Typ: types.String},
+}
+
+// backupAllTenantsPlanHook plans a BACKUP VIRTUAL CLUSTER ALL, which backs up
+// each active tenant into its own collection under the tenants subdirectory
+// of the given collection. Every tenant is backed up by a regular tenant
+// BACKUP job as of the same time, and a TenantBackupSummary of the outcome is
+// written to the collection once they have all finished.
+func backupAllTenantsPlanHook(
+	ctx context.Context, backupStmt *annotatedBackupStatement, p sql.PlanHookState,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	if !backupStmt.Nested || backupStmt.Subdir != nil {
+		return nil, nil, nil, false, errors.New(
+			"BACKUP VIRTUAL CLUSTER ALL must be used with `BACKUP ... INTO [LATEST IN] <collection>`")
+	}
+	if len(backupStmt.To) != 1 {
+		return nil, nil, nil, false, errors.New(
+			"BACKUP VIRTUAL CLUSTER ALL does not support partitioned destinations")
+	}
+	// Only the options that are passed on to the backup of every tenant are
+	// supported.
+	otherOpts := backupStmt.Options
+	otherOpts.CaptureRevisionHistory = nil
+	otherOpts.EncryptionPassphrase = nil
+	otherOpts.EncryptionKMSURI = nil
+	if !otherOpts.IsDefault() {
+		return nil, nil, nil, false, errors.New(
+			"BACKUP VIRTUAL CLUSTER ALL only supports the revision_history, encryption_passphrase and kms options")
+	}
+
+	toFn, err := p.TypeAsString(ctx, backupStmt.To[0], "BACKUP")
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	revisionHistoryFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.CaptureRevisionHistory != nil {
+		revisionHistoryFn, err = p.TypeAsBool(ctx, backupStmt.Options.CaptureRevisionHistory, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	pwFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.EncryptionPassphrase != nil {
+		pwFn, err = p.TypeAsString(ctx, backupStmt.Options.EncryptionPassphrase, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	kmsFn := func() ([]string, error) { return nil, nil }
+	if backupStmt.Options.EncryptionKMSURI != nil {
+		if backupStmt.Options.EncryptionPassphrase != nil {
+			return nil, nil, nil, false,
+				errors.New("cannot have both encryption_passphrase and kms option set")
+		}
+		kmsFn, err = p.TypeAsStringArray(ctx, tree.Exprs(backupStmt.Options.EncryptionKMSURI), "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		ctx, span := tracing.ChildSpan(ctx, backupStmt.StatementTag())
+		defer span.Finish()
+
+		if !p.ExecCfg().Codec.ForSystemTenant() {
+			return pgerror.Newf(pgcode.InsufficientPrivilege, "only the system tenant can backup other tenants")
+		}
+		if !p.ExtendedEvalContext().TxnIsSingleStmt {
+			return errors.Errorf("BACKUP VIRTUAL CLUSTER ALL cannot be used inside a multi-statement transaction")
+		}
+
+		to, err := toFn()
+		if err != nil {
+			return err
+		}
+		if err := checkPrivilegesForBackup(ctx, backupStmt, p, nil /* targetDescs */, []string{to}); err != nil {
+			return err
+		}
+
+		// Every tenant is backed up as of the same time so that the backups are
+		// consistent with one another.
+		endTime := p.ExecCfg().Clock.Now()
+		if backupStmt.AsOf.Expr != nil {
+			asOf, err := p.EvalAsOfTimestamp(ctx, backupStmt.AsOf)
+			if err != nil {
+				return err
+			}
+			endTime = asOf.Timestamp
+		}
+
+		// Re-render the options with their evaluated values, since they are
+		// passed on to the backup of every tenant.
+		var opts tree.BackupOptions
+		revisionHistory, err := revisionHistoryFn()
+		if err != nil {
+			return err
+		}
+		if revisionHistory {
+			opts.CaptureRevisionHistory = tree.DBoolTrue
+		}
+		pw, err := pwFn()
+		if err != nil {
+			return err
+		}
+		if pw != "" {
+			opts.EncryptionPassphrase = tree.NewDString(pw)
+		}
+		kms, err := kmsFn()
+		if err != nil {
+			return err
+		}
+		for _, uri := range kms {
+			opts.EncryptionKMSURI = append(opts.EncryptionKMSURI, tree.NewDString(uri))
+		}
+
+		tenants, err := retrieveAllTenantsMetadata(ctx, p.ExecCfg().InternalExecutor, nil /* txn */)
+		if err != nil {
+			return err
+		}
+		summary := backuppb.TenantBackupSummary{EndTime: endTime}
+		for _, tenant := range tenants {
+			// The system tenant is not backed up, and tenants that are being
+			// added or dropped cannot be.
+			if tenant.ID == roachpb.SystemTenantID.ToUint64() || tenant.State != descpb.TenantInfo_ACTIVE {
+				continue
+			}
+			summary.Tenants = append(summary.Tenants, backuppb.TenantBackupSummary_Tenant{
+				TenantID: roachpb.MakeTenantID(tenant.ID),
+				Path:     backupbase.TenantBackupsSubdir + "/" + strconv.FormatUint(tenant.ID, 10),
+			})
+		}
+
+		toBackup := make(chan int, len(summary.Tenants))
+		for i := range summary.Tenants {
+			toBackup <- i
+		}
+		close(toBackup)
+		workers := int(tenantBackupConcurrency.Get(&p.ExecCfg().Settings.SV))
+		if workers > len(summary.Tenants) {
+			workers = len(summary.Tenants)
+		}
+		// A failed tenant backup does not stop the backups of the other tenants;
+		// its error is recorded in the summary instead.
+		if err := ctxgroup.GroupWorkers(ctx, workers, func(ctx context.Context, _ int) error {
+			for i := range toBackup {
+				res := &summary.Tenants[i]
+				if err := backupTenantIntoCollection(ctx, p, backupStmt, to, opts, endTime, res); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					res.Status = "failed"
+					res.Error = err.Error()
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if err := writeTenantBackupSummary(ctx, p, to, &summary); err != nil {
+			return err
+		}
+
+		var failed int
+		for _, res := range summary.Tenants {
+			if res.Error != "" {
+				failed++
+			}
+			resultsCh <- tree.Datums{
+				tree.NewDInt(tree.DInt(res.TenantID.ToUint64())),
+				tree.NewDInt(tree.DInt(res.JobID)),
+				tree.NewDString(res.Status),
+				tree.NewDString(res.Path),
+			}
+		}
+		if failed > 0 {
+			return errors.Errorf("failed to back up %d of %d tenants", failed, len(summary.Tenants))
+		}
+		return nil
+	}
+	return fn, allTenantsBackupHeader, nil, false, nil
+}
+
+// backupTenantIntoCollection runs a BACKUP of the tenant of res into its
+// subdirectory of the collection as the user of the statement, recording the
+// job that ran it in res.
+func backupTenantIntoCollection(
+	ctx context.Context,
+	p sql.PlanHookState,
+	backupStmt *annotatedBackupStatement,
+	collection string,
+	opts tree.BackupOptions,
+	endTime hlc.Timestamp,
+	res *backuppb.TenantBackupSummary_Tenant,
+) error {
+	uris, err := backuputils.AppendPaths([]string{collection}, res.Path)
+	if err != nil {
+		return err
+	}
+	tenantCollection := uris[0]
+
+	// A tenant created since the last full backup in the collection has no
+	// backups to append to, so it gets a full backup instead.
+	appendToLatest := backupStmt.AppendToLatest
+	if appendToLatest {
+		if _, err := backupdest.ReadLatestFile(ctx, tenantCollection,
+			p.ExecCfg().DistSQLSrv.ExternalStorageFromURI, p.User()); err != nil {
+			if !errors.Is(err, cloud.ErrFileDoesNotExist) {
+				return err
+			}
+			appendToLatest = false
+		}
+	}
+
+	stmt := &tree.Backup{
+		Targets: &tree.BackupTargetList{
+			TenantID: tree.TenantID{Specified: true, ID: res.TenantID.ToUint64()},
+		},
+		To:             tree.StringOrPlaceholderOptList{tree.NewDString(tenantCollection)},
+		Nested:         true,
+		AppendToLatest: appendToLatest,
+		AsOf:           tree.AsOfClause{Expr: tree.NewDString(endTime.AsOfSystemTime())},
+		Options:        opts,
+	}
+	row, err := p.ExecCfg().InternalExecutor.QueryRowEx(
+		ctx, "backup-tenant", nil, /* txn */
+		sessiondata.InternalExecutorOverride{User: p.User()},
+		tree.AsStringWithFlags(stmt, tree.FmtShowPasswords),
+	)
+	if err != nil {
+		return errors.Wrapf(err, "backing up tenant %s", res.TenantID)
+	}
+	if row == nil {
+		return errors.AssertionFailedf("no result from backup of tenant %s", res.TenantID)
+	}
+	res.JobID = int64(tree.MustBeDInt(row[0]))
+	res.Status = string(tree.MustBeDString(row[1]))
+	return nil
+}
+
+// writeTenantBackupSummary writes the summary of a BACKUP VIRTUAL CLUSTER ALL
+// to the collection, named after the time the tenants were backed up as of.
+func writeTenantBackupSummary(
+	ctx context.Context,
+	p sql.PlanHookState,
+	collection string,
+	summary *backuppb.TenantBackupSummary,
+) error {
+	store, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, collection, p.User())
+	if err != nil {
+		return errors.Wrapf(backuputils.RedactURLParseError(err), "opening backup collection %s",
+			backuputils.RedactURIForErrorMessage(collection))
+	}
+	defer store.Close()
+
+	data, err := protoutil.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	name := backupbase.TenantBackupSummariesDirectory +
+		summary.EndTime.GoTime().Format(backupbase.DateBasedIntoFolderName)
+	return cloud.WriteFile(ctx, store, name, bytes.NewReader(data))
+}
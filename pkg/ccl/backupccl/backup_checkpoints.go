@@ -0,0 +1,227 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// Each successful backup records its end time in
+// system.table_backup_checkpoints for every table it covered, so that the
+// recovery point of each table can be monitored regardless of which backup or
+// schedule covers it. The jobs.backup.rpo_seconds metric reports, for each
+// database, how long ago the end time of the least recently backed up of its
+// tables is. Since the metric grows between backups, it is refreshed
+// periodically by each node once it has run a backup.
+
+var rpoMetricRefreshInterval = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.backup.rpo_metric.refresh_interval",
+	"how often the jobs.backup.rpo_seconds metric is refreshed from system.table_backup_checkpoints",
+	time.Minute,
+	settings.PositiveDuration,
+)
+
+// tableBackupCheckpointsBatchSize is the maximum number of tables whose
+// checkpoints are written by a single statement.
+const tableBackupCheckpointsBatchSize = 100
+
+// BackupMetrics are the metrics of backup jobs.
+type BackupMetrics struct {
+	RPOSeconds *aggmetric.AggGauge
+
+	// The metrics of the collections and schedules that backups completed on
+	// this node wrote to. See backup_collection_metrics.go.
+	CollectionRPOSeconds     *aggmetric.AggGauge
+	ChainLength              *aggmetric.AggGauge
+	LastLayerLogicalBytes    *aggmetric.AggGauge
+	LastLayerPhysicalBytes   *aggmetric.AggGauge
+	ScheduleLastLayerSeconds *aggmetric.AggGauge
+
+	refresher sync.Once
+	mu        struct {
+		syncutil.Mutex
+		// databases holds the child of RPOSeconds of each database which has a
+		// backed up table.
+		databases map[string]*aggmetric.Gauge
+		// collections and schedules hold the children of the collection and
+		// schedule metrics, by the collection and schedule they describe.
+		collections map[string]*collectionMetrics
+		schedules   map[scheduleMetricsKey]*scheduleMetrics
+	}
+}
+
+var _ metric.Struct = &BackupMetrics{}
+
+// MetricStruct implements the metric.Struct interface.
+func (*BackupMetrics) MetricStruct() {}
+
+func makeBackupMetrics(time.Duration) metric.Struct {
+	m := &BackupMetrics{
+		RPOSeconds: aggmetric.MakeBuilder("database").Gauge(metric.Metadata{
+			Name: "jobs.backup.rpo_seconds",
+			Help: "Seconds since the end time of the last backup of the least recently " +
+				"backed up table of each database",
+			Measurement: "Seconds",
+			Unit:        metric.Unit_SECONDS,
+			MetricType:  io_prometheus_client.MetricType_GAUGE,
+		}),
+	}
+	m.mu.databases = make(map[string]*aggmetric.Gauge)
+	m.initCollectionMetrics()
+	return m
+}
+
+// recordTableBackupCheckpoints records the end time of the backup described by
+// the manifest for each of the tables it covered. A table's checkpoint is only
+// moved forward, so that a backup that finishes after a more recent one does
+// not roll it back.
+func recordTableBackupCheckpoints(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	jobID jobspb.JobID,
+	manifest *backuppb.BackupManifest,
+) error {
+	if !execCfg.Settings.Version.IsActive(ctx, clusterversion.V23_1TableBackupCheckpointsTable) {
+		return nil
+	}
+
+	var tables []*descpb.TableDescriptor
+	for i := range manifest.Descriptors {
+		if t, _, _, _, _ := descpb.GetDescriptors(&manifest.Descriptors[i]); t != nil && !t.Dropped() {
+			tables = append(tables, t)
+		}
+	}
+	endTime := manifest.EndTime.GoTime()
+	for len(tables) > 0 {
+		batch := tables
+		if len(batch) > tableBackupCheckpointsBatchSize {
+			batch = batch[:tableBackupCheckpointsBatchSize]
+		}
+		tables = tables[len(batch):]
+
+		var values strings.Builder
+		args := []interface{}{endTime, int64(jobID)}
+		for i, t := range batch {
+			if i > 0 {
+				values.WriteString(", ")
+			}
+			fmt.Fprintf(&values, "($%d, $%d, $1, $2)", len(args)+1, len(args)+2)
+			args = append(args, int64(t.ID), int64(t.ParentID))
+		}
+		if _, err := execCfg.InternalExecutor.ExecEx(ctx, "record-table-backup-checkpoints",
+			nil /* txn */, sessiondata.NodeUserSessionDataOverride,
+			fmt.Sprintf(`INSERT INTO system.table_backup_checkpoints (table_id, database_id, end_time, job_id)
+VALUES %s
+ON CONFLICT (table_id) DO UPDATE
+  SET database_id = excluded.database_id, end_time = excluded.end_time, job_id = excluded.job_id
+  WHERE excluded.end_time > system.table_backup_checkpoints.end_time`, values.String()),
+			args...,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshRPO updates the child of RPOSeconds of each database with backed up
+// tables which still exist, and removes those of the others.
+func (m *BackupMetrics) refreshRPO(ctx context.Context, execCfg *sql.ExecutorConfig) error {
+	if !execCfg.Settings.Version.IsActive(ctx, clusterversion.V23_1TableBackupCheckpointsTable) {
+		return nil
+	}
+	rows, err := execCfg.InternalExecutor.QueryBufferedEx(ctx, "backup-rpo-metric",
+		nil /* txn */, sessiondata.NodeUserSessionDataOverride,
+		`SELECT db.name, min(c.end_time)
+FROM system.table_backup_checkpoints AS c
+JOIN system.namespace AS t ON t.id = c.table_id
+JOIN system.namespace AS db ON db.id = c.database_id AND db."parentID" = 0
+GROUP BY db.name`)
+	if err != nil {
+		return err
+	}
+
+	now := timeutil.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		name := string(tree.MustBeDString(row[0]))
+		endTime := tree.MustBeDTimestamp(row[1]).Time
+		g, ok := m.mu.databases[name]
+		if !ok {
+			g = m.RPOSeconds.AddChild(name)
+			m.mu.databases[name] = g
+		}
+		g.Update(int64(now.Sub(endTime).Seconds()))
+		seen[name] = struct{}{}
+	}
+	for name, g := range m.mu.databases {
+		if _, ok := seen[name]; !ok {
+			g.Destroy()
+			delete(m.mu.databases, name)
+		}
+	}
+	return nil
+}
+
+// maybeStartRPORefresher starts refreshing RPOSeconds, and the ages reported
+// by the collection and schedule metrics, periodically on this node, unless it
+// already is.
+func (m *BackupMetrics) maybeStartRPORefresher(execCfg *sql.ExecutorConfig) {
+	m.refresher.Do(func() {
+		stopper := execCfg.DistSQLSrv.Stopper
+		ctx := execCfg.AmbientCtx.AnnotateCtx(context.Background())
+		if err := stopper.RunAsyncTask(ctx, "backup-rpo-metric", func(ctx context.Context) {
+			ctx, cancel := stopper.WithCancelOnQuiesce(ctx)
+			defer cancel()
+			timer := timeutil.NewTimer()
+			defer timer.Stop()
+			for {
+				if err := m.refreshRPO(ctx, execCfg); err != nil {
+					log.Warningf(ctx, "failed to refresh backup RPO metric: %v", err)
+				}
+				m.refreshCollectionAges(timeutil.Now())
+				timer.Reset(rpoMetricRefreshInterval.Get(&execCfg.Settings.SV))
+				select {
+				case <-timer.C:
+					timer.Read = true
+				case <-ctx.Done():
+					return
+				}
+			}
+		}); err != nil {
+			log.Warningf(ctx, "failed to start refreshing backup RPO metric: %v", err)
+		}
+	})
+}
+
+func init() {
+	jobs.MakeBackupMetricsHook = makeBackupMetrics
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	gosql "database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableBackupCheckpoints(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	tc, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	conn := sqlDB.DB.(*gosql.DB)
+	databaseID := sqlutils.QueryDatabaseID(t, conn, "data")
+	tableID := sqlutils.QueryTableID(t, conn, "data", "public", "bank")
+	sqlDB.Exec(t, `CREATE DATABASE other`)
+	sqlDB.Exec(t, `CREATE TABLE other.t (a INT PRIMARY KEY)`)
+
+	var jobID int64
+	sqlDB.QueryRow(t, `BACKUP DATABASE data INTO $1 WITH detached`, localFoo).Scan(&jobID)
+	sqlDB.Exec(t, `SHOW JOB WHEN COMPLETE $1`, jobID)
+
+	// Only the tables covered by the backup have a checkpoint.
+	sqlDB.CheckQueryResults(t,
+		`SELECT table_id, database_id, job_id FROM system.table_backup_checkpoints`,
+		[][]string{{fmt.Sprint(tableID), fmt.Sprint(databaseID), fmt.Sprint(jobID)}})
+	var endTimeBefore string
+	sqlDB.QueryRow(t, `SELECT end_time::STRING FROM system.table_backup_checkpoints`).Scan(&endTimeBefore)
+
+	// A later backup moves the checkpoint forward.
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, localFoo)
+	var moved bool
+	sqlDB.QueryRow(t, `SELECT end_time > $1::TIMESTAMP FROM system.table_backup_checkpoints`,
+		endTimeBefore).Scan(&moved)
+	require.True(t, moved)
+
+	m := tc.Server(0).JobRegistry().(*jobs.Registry).MetricsStruct().Backup.(*BackupMetrics)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	require.Contains(t, m.mu.databases, "data")
+	require.NotContains(t, m.mu.databases, "other")
+	require.GreaterOrEqual(t, m.mu.databases["data"].Value(), int64(0))
+}
+
+func TestBackupProtection(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.protected (a INT PRIMARY KEY) WITH (backup_protection = true)`)
+	sqlDB.Exec(t, `CREATE TABLE d.unprotected (a INT PRIMARY KEY)`)
+
+	// Only the table with backup_protection set needs a backup.
+	sqlDB.Exec(t, `TRUNCATE d.unprotected`)
+	sqlDB.ExpectErr(t, `cannot truncate table "protected": it has backup_protection set`,
+		`TRUNCATE d.protected`)
+	sqlDB.ExpectErr(t, `cannot drop table "protected": it has backup_protection set`,
+		`DROP TABLE d.protected`)
+
+	// Setting it on the database protects each of its tables.
+	sqlDB.Exec(t, `ALTER DATABASE d SET (backup_protection = true)`)
+	sqlDB.ExpectErr(t, `cannot truncate table "unprotected"`, `TRUNCATE d.unprotected`)
+	sqlDB.ExpectErr(t, `cannot drop table`, `DROP DATABASE d CASCADE`)
+
+	// Once a backup covers the tables, they can be truncated and dropped.
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO $1`, localFoo)
+	sqlDB.Exec(t, `TRUNCATE d.protected`)
+	sqlDB.Exec(t, `DROP TABLE d.unprotected`)
+
+	// Until the backup falls out of the window.
+	sqlDB.Exec(t, `SET CLUSTER SETTING sql.backup_protection.window = '1ns'`)
+	sqlDB.ExpectErr(t, `cannot drop table "protected"`, `DROP DATABASE d CASCADE`)
+	sqlDB.Exec(t, `ALTER DATABASE d RESET (backup_protection)`)
+	sqlDB.Exec(t, `ALTER TABLE d.protected RESET (backup_protection)`)
+	sqlDB.Exec(t, `DROP DATABASE d CASCADE`)
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// The health of each backup collection is reported by gauges labeled by the
+// collection, and that of each backup schedule by gauges labeled by the
+// collection and the schedule. A node only reports the collections and
+// schedules of the backups that completed on it, as of the last of those
+// backups, so the gauges of a collection should be aggregated across nodes by
+// their maximum or minimum rather than their sum. The collection is labeled by
+// the scheme, host and path of its URI, which never contain its credentials.
+//
+// Besides /_status/vars, these metrics are served with the other metrics of
+// backups on their own path, /metrics/backup, so that they can be scraped
+// apart from the metrics of the rest of the node.
+
+// collectionMetrics are the children of the collection metrics of
+// BackupMetrics which describe one collection.
+type collectionMetrics struct {
+	rpoSeconds    *aggmetric.Gauge
+	chainLength   *aggmetric.Gauge
+	logicalBytes  *aggmetric.Gauge
+	physicalBytes *aggmetric.Gauge
+	// endTime is the end time of the last backup into the collection, which
+	// rpoSeconds is refreshed from.
+	endTime time.Time
+}
+
+type scheduleMetricsKey struct {
+	collection string
+	scheduleID int64
+}
+
+// scheduleMetrics is the child of ScheduleLastLayerSeconds which describes
+// one schedule.
+type scheduleMetrics struct {
+	lastLayerSeconds *aggmetric.Gauge
+	endTime          time.Time
+}
+
+func (m *BackupMetrics) initCollectionMetrics() {
+	collectionBuilder := aggmetric.MakeBuilder("collection")
+	m.CollectionRPOSeconds = collectionBuilder.Gauge(metric.Metadata{
+		Name:        "jobs.backup.collection.rpo_seconds",
+		Help:        "Seconds since the end time of the last backup into each collection",
+		Measurement: "Seconds",
+		Unit:        metric.Unit_SECONDS,
+		MetricType:  io_prometheus_client.MetricType_GAUGE,
+	})
+	m.ChainLength = collectionBuilder.Gauge(metric.Metadata{
+		Name: "jobs.backup.collection.chain_length",
+		Help: "Number of backups in the chain of the last backup into each collection, " +
+			"including its full backup",
+		Measurement: "Backups",
+		Unit:        metric.Unit_COUNT,
+		MetricType:  io_prometheus_client.MetricType_GAUGE,
+	})
+	m.LastLayerLogicalBytes = collectionBuilder.Gauge(metric.Metadata{
+		Name:        "jobs.backup.collection.last_layer_logical_bytes",
+		Help:        "Logical bytes backed up by the last backup into each collection",
+		Measurement: "Storage",
+		Unit:        metric.Unit_BYTES,
+		MetricType:  io_prometheus_client.MetricType_GAUGE,
+	})
+	m.LastLayerPhysicalBytes = collectionBuilder.Gauge(metric.Metadata{
+		Name:        "jobs.backup.collection.last_layer_physical_bytes",
+		Help:        "Bytes written to external storage by the last backup into each collection",
+		Measurement: "Storage",
+		Unit:        metric.Unit_BYTES,
+		MetricType:  io_prometheus_client.MetricType_GAUGE,
+	})
+	m.ScheduleLastLayerSeconds = aggmetric.MakeBuilder("collection", "schedule").Gauge(metric.Metadata{
+		Name: "jobs.backup.schedule.last_layer_age_seconds",
+		Help: "Seconds since the end time of the last backup of each schedule, " +
+			"by the collection it backed up into",
+		Measurement: "Seconds",
+		Unit:        metric.Unit_SECONDS,
+		MetricType:  io_prometheus_client.MetricType_GAUGE,
+	})
+	m.mu.collections = make(map[string]*collectionMetrics)
+	m.mu.schedules = make(map[scheduleMetricsKey]*scheduleMetrics)
+}
+
+// recordCollectionBackup updates the metrics of the collection at
+// collectionURI with the backup described by manifest, which was written into
+// it, unless a more recent backup into it already has.
+func (m *BackupMetrics) recordCollectionBackup(
+	collectionURI string, manifest *backuppb.BackupManifest, now time.Time,
+) {
+	collection, err := backupDestinationKey(collectionURI)
+	if err != nil {
+		return
+	}
+	endTime := manifest.EndTime.GoTime()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.mu.collections[collection]
+	if !ok {
+		c = &collectionMetrics{
+			rpoSeconds:    m.CollectionRPOSeconds.AddChild(collection),
+			chainLength:   m.ChainLength.AddChild(collection),
+			logicalBytes:  m.LastLayerLogicalBytes.AddChild(collection),
+			physicalBytes: m.LastLayerPhysicalBytes.AddChild(collection),
+		}
+		m.mu.collections[collection] = c
+	} else if endTime.Before(c.endTime) {
+		return
+	}
+	c.endTime = endTime
+	c.rpoSeconds.Update(int64(now.Sub(endTime).Seconds()))
+	c.chainLength.Update(int64(manifest.Layer) + 1)
+	c.logicalBytes.Update(manifest.EntryCounts.DataSize)
+	c.physicalBytes.Update(manifest.PhysicalSize)
+}
+
+// recordScheduledBackup updates the metric of the schedule with the given ID
+// with the end time of a backup it ran into the collection at collectionURI.
+func (m *BackupMetrics) recordScheduledBackup(
+	collectionURI string, scheduleID int64, endTime time.Time, now time.Time,
+) {
+	collection, err := backupDestinationKey(collectionURI)
+	if err != nil {
+		return
+	}
+	key := scheduleMetricsKey{collection: collection, scheduleID: scheduleID}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.mu.schedules[key]
+	if !ok {
+		s = &scheduleMetrics{
+			lastLayerSeconds: m.ScheduleLastLayerSeconds.AddChild(
+				collection, strconv.FormatInt(scheduleID, 10)),
+		}
+		m.mu.schedules[key] = s
+	} else if endTime.Before(s.endTime) {
+		return
+	}
+	s.endTime = endTime
+	s.lastLayerSeconds.Update(int64(now.Sub(endTime).Seconds()))
+}
+
+// refreshCollectionAges updates the metrics of collections and schedules which
+// grow between backups.
+func (m *BackupMetrics) refreshCollectionAges(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.mu.collections {
+		c.rpoSeconds.Update(int64(now.Sub(c.endTime).Seconds()))
+	}
+	for _, s := range m.mu.schedules {
+		s.lastLayerSeconds.Update(int64(now.Sub(s.endTime).Seconds()))
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"io"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupCollectionMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	tc, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, localFoo)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, localFoo+"/")
+
+	httpClient, err := tc.Server(0).GetUnauthenticatedHTTPClient()
+	require.NoError(t, err)
+	defer httpClient.CloseIdleConnections()
+	resp, err := httpClient.Get(tc.Server(0).AdminURL() + "/metrics/backup")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	// The backups are reported under the same collection, with or without the
+	// trailing slash of its URI.
+	require.Contains(t, string(body), `jobs_backup_collection_chain_length{collection="nodelocal://0/foo"} 3`)
+	require.Contains(t, string(body), `jobs_backup_collection_rpo_seconds{collection="nodelocal://0/foo"}`)
+	require.Contains(t, string(body), `jobs_backup_collection_last_layer_physical_bytes{collection="nodelocal://0/foo"}`)
+	require.Contains(t, string(body), "jobs_backup_resume_completed")
+	// The other metrics of the node are not.
+	require.NotContains(t, string(body), "sql_ddl_started_count")
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/errors"
+)
+
+// cloudBackupCompression is the compression of the backups to cloud storage
+// that are not run with the compression option. Storage in the cloud is billed
+// by size, so they default to the codec that compresses the most, while
+// backups to other destinations keep the codecs backups always used. Until the
+// cluster has upgraded to the version that reads the other codecs, all backups
+// keep those codecs, since the nodes that have not upgraded could otherwise
+// not resume the backups, nor read the chains they are part of.
+var cloudBackupCompression = settings.RegisterValidatedStringSetting(
+	settings.TenantWritable,
+	"bulkio.backup.compression.cloud_default",
+	"compression of backups to s3, gs or azure that do not set the compression option, "+
+		"in the syntax of the option; empty for the default codecs",
+	backupinfo.CompressionCodecZstd,
+	func(_ *settings.Values, s string) error {
+		_, err := backupinfo.ParseCompression(s)
+		return err
+	},
+)
+
+// resolveBackupCompression validates the value of the compression option,
+// returning it if it is set, and the default compression of backups to the
+// destination at uri otherwise.
+func resolveBackupCompression(
+	ctx context.Context,
+	st *cluster.Settings,
+	compression string,
+	set bool,
+	uri string,
+	user username.SQLUsername,
+) (string, error) {
+	if !st.Version.IsActive(ctx, clusterversion.V23_1BackupCompression) {
+		if set {
+			return "", errors.New(
+				"cannot use the compression option until the cluster has fully upgraded to 23.1")
+		}
+		return "", nil
+	}
+	if set {
+		if _, err := backupinfo.ParseCompression(compression); err != nil {
+			return "", err
+		}
+		return compression, nil
+	}
+	conf, err := cloud.ExternalStorageConfFromURI(uri, user)
+	if err != nil {
+		return "", err
+	}
+	switch conf.Provider {
+	case cloudpb.ExternalStorageProvider_s3, cloudpb.ExternalStorageProvider_gs,
+		cloudpb.ExternalStorageProvider_azure:
+		return cloudBackupCompression.Get(&st.SV), nil
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupCompression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	const numAccounts = 100
+	_, sqlDB, rawDir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.ExpectErr(t, "unknown compression codec",
+		`BACKUP DATABASE data TO $1 WITH compression = 'lz4'`, localFoo+"/bad")
+	sqlDB.ExpectErr(t, "compression levels only apply to metadata",
+		`BACKUP DATABASE data TO $1 WITH compression = 'data=zstd:level=3'`, localFoo+"/bad")
+
+	// The layers of the chain are each compressed differently.
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1 WITH compression = 'zstd:level=7'`, localFoo+"/full")
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1 INCREMENTAL FROM $2`, localFoo+"/inc1", localFoo+"/full")
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1 INCREMENTAL FROM $2, $3
+WITH compression = 'data=snappy,metadata=gzip:level=9'`,
+		localFoo+"/inc2", localFoo+"/full", localFoo+"/inc1")
+
+	for _, tc := range []struct {
+		dir      string
+		zstd     bool
+		expected backuppb.Compression
+		sstCodec string
+	}{
+		{"full", true, backuppb.Compression{DataCodec: "zstd", MetadataCodec: "zstd", MetadataLevel: 7}, "ZSTD"},
+		// Backups to nodelocal default to the codecs backups always used.
+		{"inc1", false, backuppb.Compression{}, "Snappy"},
+		{"inc2", false, backuppb.Compression{DataCodec: "snappy", MetadataCodec: "gzip", MetadataLevel: 9}, "Snappy"},
+	} {
+		t.Run(tc.dir, func(t *testing.T) {
+			dir := filepath.Join(rawDir, "foo", tc.dir)
+			manifestBytes, err := os.ReadFile(filepath.Join(dir, backupbase.BackupManifestName))
+			require.NoError(t, err)
+			require.Equal(t, tc.zstd, backupread.IsZstdCompressed(manifestBytes))
+			require.Equal(t, !tc.zstd, backupinfo.IsGZipped(manifestBytes))
+			manifestBytes, err = backupinfo.DecompressData(ctx, nil, manifestBytes)
+			require.NoError(t, err)
+			var manifest backuppb.BackupManifest
+			require.NoError(t, protoutil.Unmarshal(manifestBytes, &manifest))
+			require.Equal(t, tc.expected, manifest.Compression)
+
+			ssts, err := filepath.Glob(filepath.Join(dir, "data", "*.sst"))
+			require.NoError(t, err)
+			require.NotEmpty(t, ssts)
+			f, err := vfs.Default.Open(ssts[0])
+			require.NoError(t, err)
+			r, err := sstable.NewReader(f, sstable.ReaderOptions{})
+			require.NoError(t, err)
+			defer r.Close()
+			require.Equal(t, tc.sstCodec, r.Properties.CompressionName)
+		})
+	}
+
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM $1, $2, $3 WITH new_db_name = 'data2'`,
+		localFoo+"/full", localFoo+"/inc1", localFoo+"/inc2")
+	sqlDB.CheckQueryResults(t, `SELECT * FROM data2.bank`, sqlDB.QueryStr(t, `SELECT * FROM data.bank`))
+	sqlDB.Exec(t, `SHOW BACKUP $1`, localFoo+"/full")
+}
@@ -0,0 +1,400 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+)
+
+// A changefeed with format=backup_kv, started with the end time of the latest
+// backup of a chain as its cursor, writes the KVs of the chain's tables as
+// they change, along with the timestamps it resolves, into the changes
+// subdirectory of the chain's incrementals. A BACKUP ... INTO LATEST IN ...
+// WITH consolidate_changes then builds the next incremental backup of the
+// chain from those changes rather than by exporting the chain's spans: it is
+// as of the latest timestamp the changefeed resolved, and holds the latest
+// version of each key changed since the previous backup.
+//
+// The layer such a backup writes is like that of any other incremental backup
+// without revision history, so it is restored like one. The changes it
+// consolidated are deleted once it has been written; changes above its end
+// time are left for the next one.
+
+// changesResolvedExt is the extension of the files in which a cloud storage
+// changefeed records the timestamps it resolved.
+const changesResolvedExt = ".RESOLVED"
+
+// changesDataExt is the extension of the files in which a changefeed with
+// format=backup_kv writes changes.
+const changesDataExt = ".ndjson"
+
+// parseChangesResolvedFile returns the timestamp recorded by the resolved file
+// with the given name, which a cloud storage changefeed formats as the wall
+// time of the timestamp to the nanosecond followed by its logical component.
+func parseChangesResolvedFile(name string) (hlc.Timestamp, bool) {
+	if !strings.HasSuffix(name, changesResolvedExt) {
+		return hlc.Timestamp{}, false
+	}
+	s := strings.TrimSuffix(path.Base(name), changesResolvedExt)
+	const wallLayout = `20060102150405`
+	if len(s) != len(wallLayout)+9+10 {
+		return hlc.Timestamp{}, false
+	}
+	t, err := time.Parse(wallLayout, s[:len(wallLayout)])
+	if err != nil {
+		return hlc.Timestamp{}, false
+	}
+	nanos, err := strconv.ParseInt(s[len(wallLayout):len(wallLayout)+9], 10, 64)
+	if err != nil {
+		return hlc.Timestamp{}, false
+	}
+	logical, err := strconv.ParseInt(s[len(wallLayout)+9:], 10, 32)
+	if err != nil {
+		return hlc.Timestamp{}, false
+	}
+	return hlc.Timestamp{WallTime: t.UnixNano() + nanos, Logical: int32(logical)}, true
+}
+
+// latestResolvedChanges returns the latest timestamp resolved by the
+// changefeed writing the changes of the chain an incremental backup into dest
+// consolidates.
+func latestResolvedChanges(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	dest backupdest.ResolvedDestination,
+) (hlc.Timestamp, error) {
+	if len(dest.PrevBackupURIs) == 0 || dest.ChangesURI == "" {
+		return hlc.Timestamp{}, errors.New("consolidate_changes requires a previous backup in the chain")
+	}
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, dest.ChangesURI, user)
+	if err != nil {
+		return hlc.Timestamp{}, err
+	}
+	defer store.Close()
+
+	var latest hlc.Timestamp
+	if err := store.List(ctx, "", "", func(name string) error {
+		if ts, ok := parseChangesResolvedFile(name); ok {
+			latest.Forward(ts)
+		}
+		return nil
+	}); err != nil {
+		return hlc.Timestamp{}, errors.Wrap(err, "listing changes")
+	}
+	if latest.IsEmpty() {
+		return hlc.Timestamp{}, errors.WithHintf(
+			errors.Newf("no resolved changes found in %s",
+				backuputils.RedactURIForErrorMessage(dest.ChangesURI)),
+			"start a changefeed with format = '%s' and resolved writing into that directory",
+			"backup_kv")
+	}
+	return latest, nil
+}
+
+// checkConsolidatedManifest checks that the manifest of a backup consolidating
+// changes covers the same descriptors, at the same versions, as the previous
+// backup of its chain, since the changefeed that wrote the changes only
+// tracked the data of the tables it was started on.
+func checkConsolidatedManifest(manifest, prev *backuppb.BackupManifest) error {
+	if len(manifest.IntroducedSpans) > 0 {
+		return errors.New("consolidate_changes cannot be used when tables were added to the backup " +
+			"since the previous backup; take a regular incremental backup instead")
+	}
+	prevVersions := make(map[descpb.ID]descpb.DescriptorVersion, len(prev.Descriptors))
+	for i := range prev.Descriptors {
+		id, version, _, _, _ := descpb.GetDescriptorMetadata(&prev.Descriptors[i])
+		prevVersions[id] = version
+	}
+	if len(prevVersions) != len(manifest.Descriptors) {
+		return errors.New("consolidate_changes cannot be used when descriptors were added or dropped " +
+			"since the previous backup; take a regular incremental backup instead")
+	}
+	for i := range manifest.Descriptors {
+		id, version, _, _, _ := descpb.GetDescriptorMetadata(&manifest.Descriptors[i])
+		if prevVersion, ok := prevVersions[id]; !ok || prevVersion != version {
+			return errors.Newf("consolidate_changes cannot be used since descriptor %d changed "+
+				"since the previous backup; take a regular incremental backup instead", id)
+		}
+	}
+	return nil
+}
+
+// changesFile is a file of changes read by consolidateChanges.
+type changesFile struct {
+	name string
+	// maxTS is the timestamp of the latest change in the file.
+	maxTS hlc.Timestamp
+}
+
+// readChanges returns the changes in the data files of store that are in
+// (start, end], along with the files it read.
+func readChanges(
+	ctx context.Context, store cloud.ExternalStorage, mem *mon.BoundAccount, start, end hlc.Timestamp,
+) ([]storage.MVCCKeyValue, []changesFile, error) {
+	var names []string
+	if err := store.List(ctx, "", "", func(name string) error {
+		if strings.HasSuffix(name, changesDataExt) {
+			names = append(names, strings.TrimPrefix(name, "/"))
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, errors.Wrap(err, "listing changes")
+	}
+
+	var kvs []storage.MVCCKeyValue
+	files := make([]changesFile, 0, len(names))
+	for _, name := range names {
+		f := changesFile{name: name}
+		if err := func() error {
+			r, err := store.ReadFile(ctx, name)
+			if err != nil {
+				return err
+			}
+			defer r.Close(ctx)
+			br := bufio.NewReader(ioctx.ReaderCtxAdapter(ctx, r))
+			for {
+				line, err := br.ReadBytes('\n')
+				if line = bytes.TrimSpace(line); len(line) > 0 {
+					var kv backupbase.ChangefeedKV
+					if err := json.Unmarshal(line, &kv); err != nil {
+						return errors.Wrapf(err, "decoding change in %s", name)
+					}
+					f.maxTS.Forward(kv.Timestamp)
+					if start.Less(kv.Timestamp) && kv.Timestamp.LessEq(end) {
+						mvccKV := storage.MVCCKeyValue{
+							Key:   storage.MVCCKey{Key: kv.Key, Timestamp: kv.Timestamp},
+							Value: kv.Value,
+						}
+						if err := mem.Grow(ctx, int64(len(kv.Key)+len(kv.Value))); err != nil {
+							return err
+						}
+						kvs = append(kvs, mvccKV)
+					}
+				}
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}(); err != nil {
+			return nil, nil, errors.Wrapf(err, "reading changes from %s", name)
+		}
+		files = append(files, f)
+	}
+	return kvs, files, nil
+}
+
+// consolidateChanges writes the data files of a backup consolidating changes,
+// holding the latest version of each key of its spans changed since the
+// previous backup of its chain, followed by its manifest.
+func consolidateChanges(
+	ctx context.Context,
+	execCtx sql.JobExecContext,
+	details jobspb.BackupDetails,
+	defaultStore cloud.ExternalStorage,
+	backupManifest *backuppb.BackupManifest,
+	statsCache *stats.TableStatisticsCache,
+) (roachpb.RowCount, error) {
+	execCfg := execCtx.ExecCfg()
+	kmsEnv := backupencryption.MakeBackupKMSEnv(execCfg.Settings, &execCfg.ExternalIODirConfig,
+		execCfg.DB, execCtx.User(), execCfg.InternalExecutor)
+
+	changesStore, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, details.ChangesURI, execCtx.User())
+	if err != nil {
+		return roachpb.RowCount{}, err
+	}
+	defer changesStore.Close()
+
+	mem := execCfg.RootMemoryMonitor.MakeBoundAccount()
+	defer mem.Close(ctx)
+	kvs, files, err := readChanges(ctx, changesStore, &mem, backupManifest.StartTime, backupManifest.EndTime)
+	if err != nil {
+		return roachpb.RowCount{}, err
+	}
+
+	// Keep the latest version of each key, as an incremental backup without
+	// revision history does.
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key.Less(kvs[j].Key) })
+	latest := kvs[:0]
+	for _, kv := range kvs {
+		if len(latest) > 0 && latest[len(latest)-1].Key.Key.Equal(kv.Key.Key) {
+			continue
+		}
+		latest = append(latest, kv)
+	}
+
+	// The data files of a backup relying on encryption-at-rest are written
+	// unencrypted; only its metadata is encrypted.
+	var encryptionKey []byte
+	if details.EncryptionOptions != nil && !backupManifest.EncryptionAtRestOnly {
+		if encryptionKey, err = backupencryption.GetEncryptionKey(ctx, details.EncryptionOptions,
+			&kmsEnv); err != nil {
+			return roachpb.RowCount{}, err
+		}
+	}
+
+	pkIDs := make(map[uint64]bool)
+	for i := range backupManifest.Descriptors {
+		if t, _, _, _, _ := descpb.GetDescriptors(&backupManifest.Descriptors[i]); t != nil {
+			pkIDs[roachpb.BulkOpSummaryID(uint64(t.ID), uint64(t.PrimaryIndex.ID))] = true
+		}
+	}
+
+	spans := append(roachpb.Spans(nil), backupManifest.Spans...)
+	sort.Sort(spans)
+	backupManifest.Files = backupManifest.Files[:0]
+	backupManifest.EntryCounts = roachpb.RowCount{}
+	backupManifest.PhysicalSize = 0
+	i := 0
+	for _, span := range spans {
+		// Changes outside the spans of the backup, such as those of rows a row
+		// filter excludes, are dropped.
+		for i < len(latest) && latest[i].Key.Key.Compare(span.Key) < 0 {
+			i++
+		}
+		j := i
+		for j < len(latest) && latest[j].Key.Key.Compare(span.EndKey) < 0 {
+			j++
+		}
+		if j == i {
+			continue
+		}
+		file, err := writeChangesFile(ctx, execCtx, defaultStore, span, latest[i:j],
+			len(backupManifest.Files), encryptionKey, backupManifest, pkIDs)
+		if err != nil {
+			return roachpb.RowCount{}, err
+		}
+		backupManifest.Files = append(backupManifest.Files, file)
+		backupManifest.EntryCounts.Add(file.EntryCounts)
+		backupManifest.PhysicalSize += file.Trailer.Size
+		i = j
+	}
+	backupManifest.ID = uuid.MakeV4()
+
+	if err := writeBackupMetadata(ctx, execCfg.Settings, defaultStore, details.EncryptionOptions,
+		&kmsEnv, backupManifest, nil /* files */, statsCache); err != nil {
+		return roachpb.RowCount{}, err
+	}
+
+	// The consolidated changes are now part of the chain. Changes the changefeed
+	// wrote above the end time of the backup are kept for the next one.
+	for _, f := range files {
+		if f.maxTS.LessEq(backupManifest.EndTime) {
+			if err := changesStore.Delete(ctx, f.name); err != nil {
+				log.Warningf(ctx, "failed to delete consolidated changes %s: %v", f.name, err)
+			}
+		}
+	}
+	if err := changesStore.List(ctx, "", "", func(name string) error {
+		if ts, ok := parseChangesResolvedFile(name); ok && ts.LessEq(backupManifest.EndTime) {
+			if err := changesStore.Delete(ctx, strings.TrimPrefix(name, "/")); err != nil {
+				log.Warningf(ctx, "failed to delete consolidated resolved timestamp %s: %v", name, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Warningf(ctx, "failed to list consolidated changes: %v", err)
+	}
+
+	return backupManifest.EntryCounts, nil
+}
+
+// writeChangesFile writes kvs, the changes within span, to the next data file
+// of a backup consolidating changes.
+func writeChangesFile(
+	ctx context.Context,
+	execCtx sql.JobExecContext,
+	store cloud.ExternalStorage,
+	span roachpb.Span,
+	kvs []storage.MVCCKeyValue,
+	fileNum int,
+	encryptionKey []byte,
+	backupManifest *backuppb.BackupManifest,
+	pkIDs map[uint64]bool,
+) (backuppb.BackupManifest_File, error) {
+	codec := execCtx.ExecCfg().Codec
+	summary := roachpb.BulkOpSummary{EntryCounts: make(map[uint64]int64)}
+	sstFile := &storage.MemFile{}
+	sst := storage.MakeBackupSSTWriterWithCompression(ctx, execCtx.ExecCfg().Settings, sstFile,
+		backupinfo.DataSSTCompression(backupManifest.Compression.DataCodec))
+	defer sst.Close()
+	for _, kv := range kvs {
+		if err := sst.PutRawMVCC(kv.Key, kv.Value); err != nil {
+			return backuppb.BackupManifest_File{}, err
+		}
+		summary.DataSize += int64(len(kv.Key.Key) + len(kv.Value))
+		if _, tableID, indexID, err := codec.DecodeIndexPrefix(kv.Key.Key); err == nil {
+			summary.EntryCounts[roachpb.BulkOpSummaryID(uint64(tableID), uint64(indexID))]++
+		}
+	}
+	if err := sst.Finish(); err != nil {
+		return backuppb.BackupManifest_File{}, err
+	}
+
+	data := sstFile.Data()
+	if encryptionKey != nil {
+		var err error
+		if data, err = storageccl.EncryptFile(data, encryptionKey); err != nil {
+			return backuppb.BackupManifest_File{}, err
+		}
+	}
+	name := fmt.Sprintf("data/%d.sst", fileNum)
+	if err := cloud.WriteFile(ctx, store, name, bytes.NewReader(data)); err != nil {
+		return backuppb.BackupManifest_File{}, errors.Wrapf(err, "writing %s", name)
+	}
+
+	return backuppb.BackupManifest_File{
+		Span:        span,
+		Path:        name,
+		EntryCounts: countRows(summary, pkIDs),
+		StartTime:   backupManifest.StartTime,
+		EndTime:     backupManifest.EndTime,
+		Trailer: backuppb.BackupManifest_FileTrailer{
+			Size:   int64(len(data)),
+			CRC32C: crc32.Checksum(data, crc32cTable),
+			Span:   roachpb.Span{Key: kvs[0].Key.Key, EndKey: kvs[len(kvs)-1].Key.Key.Next()},
+		},
+	}, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// A backup run with the coordinator option is one of the backups of several
+// clusters, e.g. those of the shards of an application, which are taken as of
+// the same end time so that the clusters can be restored to a point that is
+// consistent across them. The clusters negotiate the end time through the
+// coordinator, a location of external storage that each of them can write to
+// and that is used for a single coordinated backup:
+//
+//  1. Each cluster proposes the time its backup was planned at, by writing it
+//     to the proposals directory of the coordinator under its cluster ID.
+//  2. Each cluster waits for all of the clusters to have proposed a time, and
+//     takes the latest of them as the end time of its backup. As they all
+//     read the same proposals, they all pick the same end time.
+//  3. Each cluster waits for its clock to reach the end time, and backs up as
+//     of it.
+//
+// The manifest of each of the backups records the end time and the clusters
+// that took part, along with the collection each of them backed up into.
+//
+// The targets of a coordinated backup are resolved when it is planned, so a
+// table created between then and the negotiated end time in a database being
+// backed up is not included.
+
+// coordinatedBackupProposalsDirectory is the directory of the coordinator to
+// which each cluster writes the end time it proposes.
+const coordinatedBackupProposalsDirectory = "proposals"
+
+var coordinatedBackupTimeout = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.backup.coordination_timeout",
+	"the time a backup run with the coordinator option waits for the other clusters to propose "+
+		"an end time for their backups before failing",
+	5*time.Minute,
+	settings.PositiveDuration,
+).WithPublic()
+
+// coordinatedBackupPollInterval is how often a coordinated backup checks
+// whether the other clusters have proposed an end time.
+var coordinatedBackupPollInterval = time.Second
+
+// negotiateCoordinatedBackup negotiates the end time of a backup run with the
+// coordinator option with the other clusters taking part in it. It is
+// idempotent, so that a job that is resumed before it has persisted the result
+// negotiates the same end time again.
+func negotiateCoordinatedBackup(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	details jobspb.BackupDetails,
+) (backuppb.CoordinatedBackup, error) {
+	coordination := details.Coordination
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, coordination.CoordinatorURI, user)
+	if err != nil {
+		return backuppb.CoordinatedBackup{}, err
+	}
+	defer store.Close()
+	coordinator, err := cloud.SanitizeExternalStorageURI(coordination.CoordinatorURI, nil /* extraParams */)
+	if err != nil {
+		return backuppb.CoordinatedBackup{}, err
+	}
+	collection, err := cloud.SanitizeExternalStorageURI(details.Destination.To[0], nil /* extraParams */)
+	if err != nil {
+		return backuppb.CoordinatedBackup{}, err
+	}
+
+	// The proposal is the end time the backup was planned with, which is only
+	// replaced in the job's details once negotiated, so a resumed job proposes
+	// the same time again.
+	proposal := backuppb.CoordinatedBackup_Cluster{
+		ClusterID:       execCfg.NodeInfo.LogicalClusterID(),
+		Collection:      collection,
+		ProposedEndTime: details.EndTime,
+	}
+	data, err := protoutil.Marshal(&proposal)
+	if err != nil {
+		return backuppb.CoordinatedBackup{}, err
+	}
+	if err := cloud.WriteFile(ctx, store,
+		path.Join(coordinatedBackupProposalsDirectory, proposal.ClusterID.String()),
+		bytes.NewReader(data)); err != nil {
+		return backuppb.CoordinatedBackup{}, errors.Wrapf(err, "proposing an end time to coordinator %s",
+			backuputils.RedactURIForErrorMessage(coordination.CoordinatorURI))
+	}
+
+	timeout := coordinatedBackupTimeout.Get(&execCfg.Settings.SV)
+	deadline := timeutil.Now().Add(timeout)
+	var clusters []backuppb.CoordinatedBackup_Cluster
+	for {
+		clusters, err = readCoordinatedBackupProposals(ctx, store)
+		if err != nil {
+			return backuppb.CoordinatedBackup{}, err
+		}
+		if len(clusters) >= int(coordination.NumClusters) {
+			break
+		}
+		if timeutil.Now().After(deadline) {
+			return backuppb.CoordinatedBackup{}, errors.WithHintf(
+				errors.Newf("only %d of the %d clusters of the coordinated backup proposed an end time within %s",
+					len(clusters), coordination.NumClusters, timeout),
+				"run the backups of the other clusters with the same coordinator, or raise %s",
+				coordinatedBackupTimeout.Key())
+		}
+		log.VEventf(ctx, 2, "%d of the %d clusters of the coordinated backup proposed an end time",
+			len(clusters), coordination.NumClusters)
+		select {
+		case <-ctx.Done():
+			return backuppb.CoordinatedBackup{}, ctx.Err()
+		case <-time.After(coordinatedBackupPollInterval):
+		}
+	}
+	if len(clusters) > int(coordination.NumClusters) {
+		return backuppb.CoordinatedBackup{}, errors.WithHint(
+			errors.Newf("%d clusters proposed an end time to coordinator %s, but the backup is coordinated "+
+				"between %d of them", len(clusters),
+				backuputils.RedactURIForErrorMessage(coordination.CoordinatorURI), coordination.NumClusters),
+			"use a new coordinator location for each coordinated backup")
+	}
+
+	result := backuppb.CoordinatedBackup{Coordinator: coordinator, Clusters: clusters}
+	for _, c := range clusters {
+		result.EndTime.Forward(c.ProposedEndTime)
+	}
+	// The clocks of the clusters are independent, so the end time proposed by
+	// another cluster can be ahead of this one's clock, and must be waited for
+	// before it can be read as of.
+	if wait := result.EndTime.GoTime().Sub(execCfg.Clock.Now().GoTime()); wait > timeout {
+		return backuppb.CoordinatedBackup{}, errors.Newf(
+			"the negotiated end time %s is %s ahead of the clock of this cluster",
+			result.EndTime, wait)
+	}
+	if err := execCfg.Clock.SleepUntil(ctx, result.EndTime); err != nil {
+		return backuppb.CoordinatedBackup{}, err
+	}
+	log.Infof(ctx, "negotiated end time %s with %d clusters through coordinator %s",
+		result.EndTime, len(clusters), coordinator)
+	return result, nil
+}
+
+// readCoordinatedBackupProposals returns the clusters that proposed an end
+// time to the coordinator in store, ordered by cluster ID.
+func readCoordinatedBackupProposals(
+	ctx context.Context, store cloud.ExternalStorage,
+) ([]backuppb.CoordinatedBackup_Cluster, error) {
+	var names []string
+	if err := store.List(ctx, coordinatedBackupProposalsDirectory, "", func(name string) error {
+		names = append(names, strings.TrimPrefix(name, "/"))
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "listing the proposals of the coordinated backup")
+	}
+
+	clusters := make([]backuppb.CoordinatedBackup_Cluster, 0, len(names))
+	for _, name := range names {
+		r, err := store.ReadFile(ctx, path.Join(coordinatedBackupProposalsDirectory, name))
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioctx.ReadAll(ctx, r)
+		r.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var c backuppb.CoordinatedBackup_Cluster
+		if err := protoutil.Unmarshal(data, &c); err != nil {
+			return nil, errors.Wrapf(err, "reading the proposal %s of the coordinated backup", name)
+		}
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return bytes.Compare(clusters[i].ClusterID.GetBytes(), clusters[j].ClusterID.GetBytes()) < 0
+	})
+	return clusters, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoordinatedBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	defer func(old time.Duration) { coordinatedBackupPollInterval = old }(coordinatedBackupPollInterval)
+	coordinatedBackupPollInterval = 10 * time.Millisecond
+
+	ctx := context.Background()
+	dir, dirCleanup := testutils.TempDir(t)
+	defer dirCleanup()
+
+	// Both clusters share an external IO directory, which holds the collection
+	// of each of them and the coordinator.
+	const numClusters = 2
+	var sqlDBs [numClusters]*sqlutils.SQLRunner
+	for i := range sqlDBs {
+		s, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+		defer s.Stopper().Stop(ctx)
+		sqlDBs[i] = sqlutils.MakeSQLRunner(db)
+		sqlDBs[i].Exec(t, `CREATE DATABASE d`)
+		sqlDBs[i].Exec(t, `CREATE TABLE d.t (k INT PRIMARY KEY)`)
+		sqlDBs[i].Exec(t, `INSERT INTO d.t VALUES ($1)`, i)
+	}
+
+	t.Run("options", func(t *testing.T) {
+		sqlDB := sqlDBs[0]
+		sqlDB.ExpectErr(t, "must be used together",
+			`BACKUP DATABASE d INTO 'nodelocal://1/options' WITH coordinator = 'nodelocal://1/coordinator'`)
+		sqlDB.ExpectErr(t, "must be at least 2",
+			`BACKUP DATABASE d INTO 'nodelocal://1/options'
+			WITH coordinator = 'nodelocal://1/coordinator', coordinated_clusters = 1`)
+		sqlDB.ExpectErr(t, "cannot be used with AS OF SYSTEM TIME",
+			`BACKUP DATABASE d INTO 'nodelocal://1/options' AS OF SYSTEM TIME '-1s'
+			WITH coordinator = 'nodelocal://1/coordinator', coordinated_clusters = 2`)
+	})
+
+	t.Run("aligned", func(t *testing.T) {
+		var jobIDs [numClusters]jobspb.JobID
+		for i, sqlDB := range sqlDBs {
+			sqlDB.QueryRow(t, fmt.Sprintf(`BACKUP DATABASE d INTO 'nodelocal://1/cluster%d'
+				WITH detached, coordinator = 'nodelocal://1/aligned', coordinated_clusters = %d`,
+				i, numClusters)).Scan(&jobIDs[i])
+		}
+		for i, sqlDB := range sqlDBs {
+			jobutils.WaitForJobToSucceed(t, sqlDB, jobIDs[i])
+		}
+
+		// The backups of the clusters are as of the same end time, and each of
+		// them records all of the clusters.
+		var endTimes [numClusters]string
+		for i, sqlDB := range sqlDBs {
+			collection := fmt.Sprintf("nodelocal://1/cluster%d", i)
+			sqlDB.QueryRow(t, fmt.Sprintf(`SELECT DISTINCT end_time::STRING FROM [SHOW BACKUP LATEST IN '%s']`,
+				collection)).Scan(&endTimes[i])
+			sqlDB.CheckQueryResults(t, fmt.Sprintf(
+				`SELECT jsonb_array_length(manifest->'coordination'->'clusters')
+				FROM [SHOW BACKUP LATEST IN '%s' WITH as_json]`, collection),
+				[][]string{{fmt.Sprint(numClusters)}})
+		}
+		require.Equal(t, endTimes[0], endTimes[1])
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		sqlDB := sqlDBs[0]
+		sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.coordination_timeout = '100ms'`)
+		defer sqlDB.Exec(t, `RESET CLUSTER SETTING bulkio.backup.coordination_timeout`)
+		sqlDB.ExpectErr(t, "only 1 of the 2 clusters of the coordinated backup proposed an end time",
+			`BACKUP DATABASE d INTO 'nodelocal://1/timeout'
+			WITH coordinator = 'nodelocal://1/lonely', coordinated_clusters = 2`)
+	})
+}
@@ -0,0 +1,301 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/volatility"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+)
+
+// The prices used by crdb_internal.estimate_backup_cost for the providers it
+// knows the pricing model of. They default to the list prices of the standard
+// storage class of each provider and of egress to the internet, and can be
+// set to the prices negotiated with the provider.
+var (
+	s3StoragePrice = settings.RegisterFloatSetting(
+		settings.TenantWritable,
+		"bulkio.backup.cost_estimate.s3.storage_price_per_gib_month",
+		"price of storing a GiB in S3 for a month, used to estimate the cost of backups",
+		0.023,
+		settings.NonNegativeFloat,
+	)
+	s3EgressPrice = settings.RegisterFloatSetting(
+		settings.TenantWritable,
+		"bulkio.backup.cost_estimate.s3.egress_price_per_gib",
+		"price of reading a GiB out of S3, used to estimate the cost of restores",
+		0.09,
+		settings.NonNegativeFloat,
+	)
+	gcsStoragePrice = settings.RegisterFloatSetting(
+		settings.TenantWritable,
+		"bulkio.backup.cost_estimate.gs.storage_price_per_gib_month",
+		"price of storing a GiB in Google Cloud Storage for a month, used to estimate the cost of backups",
+		0.020,
+		settings.NonNegativeFloat,
+	)
+	gcsEgressPrice = settings.RegisterFloatSetting(
+		settings.TenantWritable,
+		"bulkio.backup.cost_estimate.gs.egress_price_per_gib",
+		"price of reading a GiB out of Google Cloud Storage, used to estimate the cost of restores",
+		0.12,
+		settings.NonNegativeFloat,
+	)
+	azureStoragePrice = settings.RegisterFloatSetting(
+		settings.TenantWritable,
+		"bulkio.backup.cost_estimate.azure.storage_price_per_gib_month",
+		"price of storing a GiB in Azure Blob Storage for a month, used to estimate the cost of backups",
+		0.0184,
+		settings.NonNegativeFloat,
+	)
+	azureEgressPrice = settings.RegisterFloatSetting(
+		settings.TenantWritable,
+		"bulkio.backup.cost_estimate.azure.egress_price_per_gib",
+		"price of reading a GiB out of Azure Blob Storage, used to estimate the cost of restores",
+		0.087,
+		settings.NonNegativeFloat,
+	)
+)
+
+// backupProviderPrices returns the settings holding the storage and egress
+// prices of provider, or false if the cost of storing backups with it is not
+// estimated, e.g. because they are stored on the nodes themselves.
+func backupProviderPrices(
+	provider cloudpb.ExternalStorageProvider,
+) (storage, egress *settings.FloatSetting, ok bool) {
+	switch provider {
+	case cloudpb.ExternalStorageProvider_s3:
+		return s3StoragePrice, s3EgressPrice, true
+	case cloudpb.ExternalStorageProvider_gs:
+		return gcsStoragePrice, gcsEgressPrice, true
+	case cloudpb.ExternalStorageProvider_azure:
+		return azureStoragePrice, azureEgressPrice, true
+	default:
+		return nil, nil, false
+	}
+}
+
+const bytesPerGiB = 1 << 30
+
+// backupCostEstimate is the estimated cost of a backup chain.
+type backupCostEstimate struct {
+	provider cloudpb.ExternalStorageProvider
+	size     backuppb.BackupChainSize
+	// storagePerMonth is the cost of storing the chain for a month, and
+	// restoreEgress the cost of reading all of it to restore it.
+	storagePerMonth, restoreEgress float64
+	priced                         bool
+}
+
+func makeBackupCostEstimate(
+	sv *settings.Values, provider cloudpb.ExternalStorageProvider, size backuppb.BackupChainSize,
+) backupCostEstimate {
+	est := backupCostEstimate{provider: provider, size: size}
+	storage, egress, ok := backupProviderPrices(provider)
+	if !ok {
+		return est
+	}
+	gib := float64(size.PhysicalSize) / bytesPerGiB
+	est.storagePerMonth = gib * storage.Get(sv)
+	est.restoreEgress = gib * egress.Get(sv)
+	est.priced = true
+	return est
+}
+
+func (e backupCostEstimate) toJSON() (json.JSON, error) {
+	b := json.NewObjectBuilder(7)
+	b.Add("provider", json.FromString(e.provider.String()))
+	b.Add("layers", json.FromInt(int(e.size.NumLayers)))
+	b.Add("logical_bytes", json.FromInt64(e.size.LogicalSize))
+	b.Add("physical_bytes", json.FromInt64(e.size.PhysicalSize))
+	if !e.priced {
+		b.Add("storage_cost_per_month", json.NullJSONValue)
+		b.Add("restore_egress_cost", json.NullJSONValue)
+		return b.Build(), nil
+	}
+	storage, err := json.FromFloat64(e.storagePerMonth)
+	if err != nil {
+		return nil, err
+	}
+	egress, err := json.FromFloat64(e.restoreEgress)
+	if err != nil {
+		return nil, err
+	}
+	b.Add("storage_cost_per_month", storage)
+	b.Add("restore_egress_cost", egress)
+	return b.Build(), nil
+}
+
+// estimateBackupCost estimates the cost of the backup chain in subdir of the
+// collection, which may be LATEST. Its size is the one the chain recorded as
+// it grew if it did, and is otherwise summed up from the manifests of its
+// layers, which requires them to be unencrypted.
+func estimateBackupCost(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	collectionURI, subdir string,
+) (backupCostEstimate, error) {
+	conf, err := cloud.ExternalStorageConfFromURI(collectionURI, user)
+	if err != nil {
+		return backupCostEstimate{}, err
+	}
+	mkStore := execCfg.DistSQLSrv.ExternalStorageFromURI
+	latest := backuppb.LatestFile{Subdir: subdir}
+	if strings.EqualFold(subdir, backupbase.LatestFileName) {
+		latest, err = backupdest.ReadLatestFile(ctx, collectionURI, mkStore, user)
+		if err != nil {
+			return backupCostEstimate{}, err
+		}
+		subdir = latest.Subdir
+	}
+
+	store, err := mkStore(ctx, collectionURI, user)
+	if err != nil {
+		return backupCostEstimate{}, errors.Wrapf(err, "connect to external storage")
+	}
+	defer store.Close()
+	size, found, err := backupdest.ReadLatestChainSize(ctx, store, latest)
+	if err != nil {
+		return backupCostEstimate{}, err
+	}
+	if !found {
+		size, err = backupChainSizeFromManifests(ctx, execCfg, user, collectionURI, subdir)
+		if err != nil {
+			return backupCostEstimate{}, err
+		}
+	}
+	return makeBackupCostEstimate(&execCfg.Settings.SV, conf.Provider, size), nil
+}
+
+// backupChainSizeFromManifests sums up the sizes of the layers of the backup
+// chain in subdir of the collection recorded in their manifests.
+func backupChainSizeFromManifests(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	collectionURI, subdir string,
+) (backuppb.BackupChainSize, error) {
+	mkStore := execCfg.DistSQLSrv.ExternalStorageFromURI
+	fullyResolvedDest, err := backuputils.AppendPaths([]string{collectionURI}, subdir)
+	if err != nil {
+		return backuppb.BackupChainSize{}, err
+	}
+	baseStore, err := mkStore(ctx, fullyResolvedDest[0], user)
+	if err != nil {
+		return backuppb.BackupChainSize{}, errors.Wrapf(err, "make storage")
+	}
+	defer baseStore.Close()
+
+	fullyResolvedIncrementalsDirectory, err := backupdest.ResolveIncrementalsBackupLocation(
+		ctx, user, execCfg, nil /* explicitIncrementalCollections */, []string{collectionURI}, subdir)
+	if err != nil {
+		if !errors.Is(err, cloud.ErrListingUnsupported) {
+			return backuppb.BackupChainSize{}, err
+		}
+		log.Warningf(ctx, "storage sink %s does not support listing, only estimating the cost "+
+			"of the full backup", backuputils.RedactURIForErrorMessage(collectionURI))
+	}
+	incStores, cleanupFn, err := backupdest.MakeBackupDestinationStores(ctx, user, mkStore,
+		fullyResolvedIncrementalsDirectory)
+	if err != nil {
+		return backuppb.BackupChainSize{}, err
+	}
+	defer func() {
+		if err := cleanupFn(); err != nil {
+			log.Warningf(ctx, "failed to close incremental store: %+v", err)
+		}
+	}()
+
+	mem := execCfg.RootMemoryMonitor.MakeBoundAccount()
+	defer mem.Close(ctx)
+	_, manifests, _, memReserved, err := backupdest.ResolveBackupManifests(
+		ctx, &mem, []cloud.ExternalStorage{baseStore}, incStores, mkStore, fullyResolvedDest,
+		fullyResolvedIncrementalsDirectory, hlc.Timestamp{}, nil /* encryption */, nil /* kmsEnv */, user,
+		false /* elideFiles */)
+	defer mem.Shrink(ctx, memReserved)
+	if err != nil {
+		return backuppb.BackupChainSize{}, errors.Wrapf(err,
+			"backup chain %s does not record its size and its manifests could not be read", subdir)
+	}
+
+	var size backuppb.BackupChainSize
+	for i := range manifests {
+		size.EndTime = manifests[i].EndTime
+		size.NumLayers++
+		size.LogicalSize += manifests[i].EntryCounts.DataSize
+		// Backups taken before their physical size was recorded are assumed to
+		// have written as many bytes as they backed up.
+		if manifests[i].PhysicalSize != 0 {
+			size.PhysicalSize += manifests[i].PhysicalSize
+		} else {
+			size.PhysicalSize += manifests[i].EntryCounts.DataSize
+		}
+	}
+	return size, nil
+}
+
+func init() {
+	overload := tree.Overload{
+		Types: tree.ArgTypes{
+			{Name: "collection", Typ: types.String},
+			{Name: "subdir", Typ: types.String},
+		},
+		ReturnType: tree.FixedReturnType(types.Jsonb),
+		Fn: func(ctx context.Context, evalCtx *eval.Context, args tree.Datums) (tree.Datum, error) {
+			isAdmin, err := evalCtx.SessionAccessor.HasAdminRole(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if !isAdmin {
+				return nil, pgerror.New(pgcode.InsufficientPrivilege,
+					"crdb_internal.estimate_backup_cost is restricted to the admin role")
+			}
+			execCfg := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
+			est, err := estimateBackupCost(ctx, execCfg, evalCtx.SessionData().User(),
+				string(tree.MustBeDString(args[0])), string(tree.MustBeDString(args[1])))
+			if err != nil {
+				return nil, err
+			}
+			j, err := est.toJSON()
+			if err != nil {
+				return nil, err
+			}
+			return tree.NewDJSON(j), nil
+		},
+		Info: "Estimates the cost of storing the backup chain in the given subdirectory, which may be " +
+			"'LATEST', of a collection for a month, and of reading all of it back to restore it. " +
+			"The prices of each provider are set by the bulkio.backup.cost_estimate.* cluster settings.",
+		Volatility: volatility.Volatile,
+	}
+
+	utilccl.RegisterCCLBuiltin("crdb_internal.estimate_backup_cost",
+		`Estimates the monthly storage cost and the restore egress cost of a backup chain.`,
+		overload)
+}
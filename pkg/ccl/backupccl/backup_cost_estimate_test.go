@@ -0,0 +1,54 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeBackupCostEstimate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	s3StoragePrice.Override(ctx, &st.SV, 0.02)
+	s3EgressPrice.Override(ctx, &st.SV, 0.1)
+
+	size := backuppb.BackupChainSize{
+		NumLayers:    3,
+		LogicalSize:  40 << 30,
+		PhysicalSize: 10 << 30,
+	}
+
+	est := makeBackupCostEstimate(&st.SV, cloudpb.ExternalStorageProvider_s3, size)
+	require.True(t, est.priced)
+	require.InDelta(t, 0.2, est.storagePerMonth, 1e-9)
+	require.InDelta(t, 1.0, est.restoreEgress, 1e-9)
+	j, err := est.toJSON()
+	require.NoError(t, err)
+	require.Equal(t, `{"layers": 3, "logical_bytes": 42949672960, "physical_bytes": 10737418240, `+
+		`"provider": "s3", "restore_egress_cost": 1, "storage_cost_per_month": 0.2}`, j.String())
+
+	// Backups stored on the nodes themselves are not priced.
+	est = makeBackupCostEstimate(&st.SV, cloudpb.ExternalStorageProvider_nodelocal, size)
+	require.False(t, est.priced)
+	j, err = est.toJSON()
+	require.NoError(t, err)
+	require.Equal(t, `{"layers": 3, "logical_bytes": 42949672960, "physical_bytes": 10737418240, `+
+		`"provider": "nodelocal", "restore_egress_cost": null, "storage_cost_per_month": null}`, j.String())
+}
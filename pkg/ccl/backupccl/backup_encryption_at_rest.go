@@ -0,0 +1,117 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl/engineccl/enginepbccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/errorutil"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
+)
+
+// A backup to nodelocal storage on nodes whose stores are protected by
+// encryption-at-rest can be taken with rely_on_encryption_at_rest, in which
+// case its data files are not encrypted a second time by the backup. Its
+// metadata files are still encrypted, so that the backup is read with the
+// same encryption options as any other, and the IDs of the active store keys
+// of the destination nodes are recorded in its manifest.
+//
+// The external IO directory of a node is expected to be on the same encrypted
+// volume as its stores; BACKUP can only check that all of the node's stores
+// have encryption-at-rest enabled.
+
+// encryptionAtRestKeyIDs checks that every URI in uris is nodelocal storage on
+// nodes all of whose stores have encryption-at-rest enabled, and returns the
+// IDs of their active store keys.
+func encryptionAtRestKeyIDs(
+	ctx context.Context, p sql.PlanHookState, uris []string,
+) ([]string, error) {
+	ss, err := p.ExecCfg().NodesStatusServer.OptionalNodesStatusServer(
+		errorutil.FeatureNotAvailableToNonSystemTenantsIssue)
+	if err != nil {
+		return nil, errors.Wrap(err, "rely_on_encryption_at_rest")
+	}
+
+	nodes := make(map[roachpb.NodeID]struct{})
+	var allNodes bool
+	for _, uri := range uris {
+		conf, err := cloud.ExternalStorageConfFromURI(uri, p.User())
+		if err != nil {
+			return nil, err
+		}
+		if conf.Provider != cloudpb.ExternalStorageProvider_nodelocal {
+			return nil, errors.Newf(
+				"rely_on_encryption_at_rest can only be used with nodelocal destinations, got %s",
+				redact.SafeString(conf.Provider.String()))
+		}
+		if conf.LocalFileConfig.NodeID == 0 {
+			// Every node writes the files it produces to its own external IO
+			// directory.
+			allNodes = true
+		} else {
+			nodes[conf.LocalFileConfig.NodeID] = struct{}{}
+		}
+	}
+	if allNodes {
+		resp, err := ss.ListNodesInternal(ctx, &serverpb.NodesRequest{})
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range resp.Nodes {
+			nodes[n.Desc.NodeID] = struct{}{}
+		}
+	}
+
+	keyIDs := make(map[string]struct{})
+	for nodeID := range nodes {
+		resp, err := ss.Stores(ctx, &serverpb.StoresRequest{NodeId: nodeID.String()})
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking encryption-at-rest on n%d", nodeID)
+		}
+		for _, store := range resp.Stores {
+			var status enginepbccl.EncryptionStatus
+			if err := protoutil.Unmarshal(store.EncryptionStatus, &status); err != nil {
+				return nil, err
+			}
+			if status.ActiveStoreKey == nil ||
+				status.ActiveStoreKey.EncryptionType == enginepbccl.EncryptionType_Plaintext {
+				return nil, errors.WithHint(
+					errors.Newf("encryption-at-rest is not enabled on store s%d of n%d", store.StoreID, nodeID),
+					"omit rely_on_encryption_at_rest to encrypt the data files of the backup")
+			}
+			keyIDs[status.ActiveStoreKey.KeyId] = struct{}{}
+		}
+	}
+
+	res := make([]string, 0, len(keyIDs))
+	for id := range keyIDs {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// checkEncryptionAtRestMatchesPrevious checks that an incremental backup
+// agrees with the previous backup in its chain on whether its data files are
+// encrypted, since a restore reads all of them with the same options.
+func checkEncryptionAtRestMatchesPrevious(encryptionAtRestOnly, prev bool) error {
+	if encryptionAtRestOnly != prev {
+		return errors.New("rely_on_encryption_at_rest must be used for all or none of the backups in a chain")
+	}
+	return nil
+}
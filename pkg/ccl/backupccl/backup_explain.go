@@ -0,0 +1,277 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// EXPLAIN BACKUP and EXPLAIN RESTORE plan the statement like any other, but
+// rather than running its job they describe what the job would do: where the
+// backup would be written to or read from, the layers of the chain it would
+// extend or restore, the spans it would back up or restore and an estimate of
+// their size, and the nodes that would run it. The destination of a BACKUP is
+// resolved the way its job resolves it, so its EXPLAIN shows the subdirectory
+// and prior layers the job would pick if it were started at the same time.
+// Neither writes anything.
+
+// explainHeader is the header of the results of EXPLAIN BACKUP and EXPLAIN
+// RESTORE, which like those of EXPLAIN are lines of text.
+var explainHeader = colinfo.ResultColumns{
+	{Name: "info", Typ: types.String},
+}
+
+func emitExplainRow(resultsCh chan<- tree.Datums, format string, args ...interface{}) {
+	resultsCh <- tree.Datums{tree.NewDString(fmt.Sprintf(format, args...))}
+}
+
+// emitExplainURI emits a row naming uri, with its secrets removed.
+func emitExplainURI(resultsCh chan<- tree.Datums, name, uri string) error {
+	sanitized, err := cloud.SanitizeExternalStorageURI(uri, nil /* extraParams */)
+	if err != nil {
+		return err
+	}
+	emitExplainRow(resultsCh, "%s: %s", name, sanitized)
+	return nil
+}
+
+// explainBackup resolves the destination and manifest of the backup with the
+// given details and describes them.
+func explainBackup(
+	ctx context.Context, p sql.PlanHookState, details jobspb.BackupDetails, resultsCh chan<- tree.Datums,
+) error {
+	dest, err := backupdest.ResolveDest(ctx, p.ExecCfg(), backupdest.ResolveOptions{
+		User:            p.User(),
+		Destination:     details.Destination,
+		EndTime:         details.EndTime,
+		IncrementalFrom: details.IncrementalFrom,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "resolving backup destination %s",
+			strings.Join(backuputils.RedactURIsForErrorMessage(details.Destination.To), ", "))
+	}
+	_, manifest, err := getBackupDetailAndManifest(ctx, p.ExecCfg(), p.Txn(), details, p.User(), dest)
+	if err != nil {
+		return err
+	}
+
+	if dest.CollectionURI != "" {
+		if err := emitExplainURI(resultsCh, "collection", dest.CollectionURI); err != nil {
+			return err
+		}
+	}
+	if dest.ChosenSubdir != "" {
+		emitExplainRow(resultsCh, "subdir: %s", dest.ChosenSubdir)
+	}
+	for _, uri := range details.Destination.IncrementalStorage {
+		if err := emitExplainURI(resultsCh, "incremental location", uri); err != nil {
+			return err
+		}
+	}
+	if err := emitExplainURI(resultsCh, "destination", dest.DefaultURI); err != nil {
+		return err
+	}
+	localities := make([]string, 0, len(dest.URIsByLocalityKV))
+	for kv := range dest.URIsByLocalityKV {
+		localities = append(localities, kv)
+	}
+	sort.Strings(localities)
+	for _, kv := range localities {
+		if err := emitExplainURI(resultsCh, "destination of locality "+kv,
+			dest.URIsByLocalityKV[kv]); err != nil {
+			return err
+		}
+	}
+
+	if len(dest.PrevBackupURIs) == 0 {
+		emitExplainRow(resultsCh, "kind: full")
+	} else {
+		emitExplainRow(resultsCh, "kind: incremental")
+	}
+	for _, uri := range dest.PrevBackupURIs {
+		if err := emitExplainURI(resultsCh, "prior layer", uri); err != nil {
+			return err
+		}
+	}
+	if !manifest.StartTime.IsEmpty() {
+		emitExplainRow(resultsCh, "start time: %s", manifest.StartTime.GoTime())
+	}
+	emitExplainRow(resultsCh, "end time: %s", manifest.EndTime.GoTime())
+
+	spans := append(append([]roachpb.Span(nil), manifest.Spans...), manifest.IntroducedSpans...)
+	emitExplainRow(resultsCh, "spans: %d", len(manifest.Spans))
+	if len(manifest.IntroducedSpans) > 0 {
+		emitExplainRow(resultsCh, "introduced spans: %d", len(manifest.IntroducedSpans))
+	}
+	// The size is estimated from the live bytes of the ranges the spans overlap,
+	// so it overstates that of spans covering part of a range, and that of an
+	// incremental backup, which only exports what changed.
+	var liveBytes int64
+	for _, span := range spans {
+		pieces, err := fetchRangeLiveBytes(ctx, p.ExecCfg(), span)
+		if err != nil {
+			return err
+		}
+		for _, piece := range pieces {
+			liveBytes += piece.liveBytes
+		}
+	}
+	emitExplainRow(resultsCh, "estimated size: %s", humanizeutil.IBytes(liveBytes))
+	if len(spans) == 0 {
+		return nil
+	}
+
+	dsp := p.DistSQLPlanner()
+	planCtx, _, err := dsp.SetupAllNodesPlanningWithLocalityFilter(
+		ctx, p.ExtendedEvalContext(), p.ExecCfg(), details.ExecutionLocality,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to determine nodes on which to run")
+	}
+	partitions, err := dsp.PartitionSpans(ctx, planCtx, spans)
+	if err != nil {
+		return err
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].SQLInstanceID < partitions[j].SQLInstanceID
+	})
+	for _, partition := range partitions {
+		emitExplainRow(resultsCh, "node %d: %d spans", partition.SQLInstanceID, len(partition.Spans))
+	}
+	return nil
+}
+
+// explainRestore describes the restore of the tables and tenants from the
+// layers of the backup chain at uris, with the given manifests.
+func explainRestore(
+	ctx context.Context,
+	p sql.PlanHookState,
+	collectionURI string,
+	subdir string,
+	incrementalDirs []string,
+	uris []string,
+	manifests []backuppb.BackupManifest,
+	tablesByID map[descpb.ID]*tabledesc.Mutable,
+	tenants []descpb.TenantInfoWithUsage,
+	oldTenantID *roachpb.TenantID,
+	schemaOnly bool,
+	executionLocality roachpb.Locality,
+	resultsCh chan<- tree.Datums,
+) error {
+	if subdir != "" {
+		if err := emitExplainURI(resultsCh, "collection", collectionURI); err != nil {
+			return err
+		}
+		emitExplainRow(resultsCh, "subdir: %s", subdir)
+	}
+	for _, uri := range incrementalDirs {
+		if err := emitExplainURI(resultsCh, "incremental location", uri); err != nil {
+			return err
+		}
+	}
+	for i, uri := range uris {
+		if err := emitExplainURI(resultsCh, fmt.Sprintf("layer %d", i), uri); err != nil {
+			return err
+		}
+		emitExplainRow(resultsCh, "layer %d end time: %s", i, manifests[i].EndTime.GoTime())
+	}
+
+	// The spans are those of the tables and tenants as they are in the backup,
+	// before they are rewritten.
+	var spans []roachpb.Span
+	if len(tablesByID) > 0 {
+		tables := make([]catalog.TableDescriptor, 0, len(tablesByID))
+		for _, table := range tablesByID {
+			tables = append(tables, table)
+		}
+		backupCodec := keys.SystemSQLCodec
+		latest := &manifests[len(manifests)-1]
+		if len(latest.Spans) != 0 && !latest.HasTenants() {
+			_, backupTenantID, err := keys.DecodeTenantPrefix(latest.Spans[0].Key)
+			if err != nil {
+				return err
+			}
+			backupCodec = keys.MakeSQLCodec(backupTenantID)
+		}
+		spans = append(spans, spansForAllRestoreTableIndexes(backupCodec, tables, nil, schemaOnly)...)
+	}
+	for _, tenant := range tenants {
+		id := roachpb.MakeTenantID(tenant.ID)
+		if oldTenantID != nil {
+			id = *oldTenantID
+		}
+		prefix := keys.MakeTenantPrefix(id)
+		spans = append(spans, roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()})
+	}
+	emitExplainRow(resultsCh, "spans: %d", len(spans))
+
+	// The size is estimated from the files of every layer that overlap the
+	// spans, so it includes the revisions of the keys in each of them. The
+	// files of the chain are visited in the order of their spans, so a single
+	// sweep over the sorted spans finds those that overlap each file.
+	merged, _ := roachpb.MergeSpans(&spans)
+	it, err := backupinfo.NewChainFileIter(ctx, nil /* stores */, manifests, nil /* encryption */, nil /* kmsEnv */)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	var files int
+	var dataSize int64
+	var f backuppb.BackupManifest_File
+	for i := 0; ; {
+		if _, ok := it.Next(&f); !ok {
+			break
+		}
+		for i < len(merged) && merged[i].EndKey.Compare(f.Span.Key) <= 0 {
+			i++
+		}
+		if i < len(merged) && merged[i].Overlaps(f.Span) {
+			files++
+			dataSize += f.EntryCounts.DataSize
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	emitExplainRow(resultsCh, "files: %d", files)
+	emitExplainRow(resultsCh, "estimated size: %s", humanizeutil.IBytes(dataSize))
+
+	_, sqlInstanceIDs, err := p.DistSQLPlanner().SetupAllNodesPlanningWithLocalityFilter(
+		ctx, p.ExtendedEvalContext(), p.ExecCfg(), executionLocality,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to determine nodes on which to run")
+	}
+	sort.Slice(sqlInstanceIDs, func(i, j int) bool { return sqlInstanceIDs[i] < sqlInstanceIDs[j] })
+	for _, id := range sqlInstanceIDs {
+		emitExplainRow(resultsCh, "node %d", id)
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
@@ -32,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/joberror"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/protectedts"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -91,6 +93,65 @@ func filterSpans(includes []roachpb.Span, excludes []roachpb.Span) []roachpb.Spa
 	return cov.Slice()
 }
 
+// splitSpansByTable splits the spans that hold the data of several tables at
+// the boundaries between those tables. Spans outside of the table key space of
+// codec are returned as they are.
+func splitSpansByTable(codec keys.SQLCodec, spans []roachpb.Span) []roachpb.Span {
+	var res []roachpb.Span
+	for _, sp := range spans {
+		for {
+			_, tableID, err := codec.DecodeTablePrefix(sp.Key)
+			if err != nil {
+				res = append(res, sp)
+				break
+			}
+			next := codec.TablePrefix(tableID + 1)
+			if sp.EndKey.Compare(next) <= 0 {
+				res = append(res, sp)
+				break
+			}
+			res = append(res, roachpb.Span{Key: sp.Key, EndKey: next})
+			sp.Key = next
+		}
+	}
+	return res
+}
+
+// exportFrontier tracks which parts of the spans of a backup have been
+// exported. Spans introduced by an incremental backup are exported separately
+// from the rest, so they are tracked separately too.
+type exportFrontier struct {
+	spans, introducedSpans       []roachpb.Span
+	exported, exportedIntroduced roachpb.SpanGroup
+}
+
+// makeExportFrontier returns an exportFrontier for the spans of manifest,
+// accounting for the files already exported to it.
+func makeExportFrontier(manifest *backuppb.BackupManifest) *exportFrontier {
+	f := &exportFrontier{spans: manifest.Spans, introducedSpans: manifest.IntroducedSpans}
+	f.add(manifest.Files...)
+	return f
+}
+
+// add records the spans of files as exported.
+func (f *exportFrontier) add(files ...backuppb.BackupManifest_File) {
+	for _, file := range files {
+		if file.StartTime.IsEmpty() && !file.EndTime.IsEmpty() {
+			f.exportedIntroduced.Add(file.Span)
+		} else {
+			f.exported.Add(file.Span)
+		}
+	}
+}
+
+// remaining returns the spans that are yet to be exported.
+func (f *exportFrontier) remaining() []roachpb.Span {
+	var remaining roachpb.SpanGroup
+	remaining.Add(filterSpans(f.spans, f.exported.Slice())...)
+	remaining.Add(filterSpans(f.introducedSpans, f.exportedIntroduced.Slice())...)
+	return remaining.Slice()
+}
+
 // clusterNodeCount returns the approximate number of nodes in the cluster.
 func clusterNodeCount(gw gossip.OptionalGossip) (int, error) {
 	g, err := gw.OptionalErr(47970)
@@ -122,10 +183,14 @@ func clusterNodeCount(gw gossip.OptionalGossip) (int, error) {
 //   - <dir> is given by the user and may be cloud storage
 //   - Each file contains data for a key range that doesn't overlap with any other
 //     file.
+//
+// The data files are written to dataURI, which is defaultURI unless the
+// backup keeps its metadata apart from its data.
 func backup(
 	ctx context.Context,
 	execCtx sql.JobExecContext,
 	defaultURI string,
+	dataURI string,
 	urisByLocalityKV map[string]string,
 	settings *cluster.Settings,
 	defaultStore cloud.ExternalStorage,
@@ -134,6 +199,9 @@ func backup(
 	backupManifest *backuppb.BackupManifest,
 	makeExternalStorage cloud.ExternalStorageFactory,
 	encryption *jobspb.BackupEncryptionOptions,
+	uploadOptions cloudpb.UploadOptions,
+	highPriority bool,
+	allowMissingLocalities bool,
 	statsCache *stats.TableStatisticsCache,
 ) (roachpb.RowCount, error) {
 	resumerSpan := tracing.SpanFromContext(ctx)
@@ -151,9 +219,32 @@ func backup(
 		}
 	}
 
-	// Subtract out any completed spans.
+	// Subtract out any completed spans, and those a previous attempt found
+	// unchanged.
+	completedSpans = append(completedSpans, backupManifest.SkippedSpans...)
 	spans := filterSpans(backupManifest.Spans, completedSpans)
 	introducedSpans := filterSpans(backupManifest.IntroducedSpans, completedIntroducedSpans)
+	if skipUnchangedTables.Get(&execCtx.ExecCfg().Settings.SV) {
+		skipped, err := unchangedTableSpans(ctx, execCtx.ExecCfg(), backupManifest, spans)
+		if err != nil {
+			log.Warningf(ctx, "failed to find unchanged tables to skip: %v", err)
+		} else if len(skipped) > 0 {
+			backupManifest.SkippedSpans = append(backupManifest.SkippedSpans, skipped...)
+			spans = filterSpans(spans, skipped)
+		}
+	}
+	if backupManifest.PerTableFiles {
+		// Filtering merges adjacent spans, so split them back up at table
+		// boundaries to ensure no exported span holds the data of several tables.
+		codec := execCtx.ExecCfg().Codec
+		spans = splitSpansByTable(codec, spans)
+		introducedSpans = splitSpansByTable(codec, introducedSpans)
+	}
+
+	var spanStats *backupSpanStats
+	if statsAwareFileSizing.Get(&execCtx.ExecCfg().Settings.SV) {
+		spans, introducedSpans, spanStats = sizeBackupSpans(ctx, execCtx.ExecCfg(), spans, introducedSpans)
+	}
 
 	pkIDs := make(map[uint64]bool)
 	for i := range backupManifest.Descriptors {
@@ -166,8 +257,12 @@ func backup(
 	dsp := execCtx.DistSQLPlanner()
 
 	// We don't return the compatible nodes here since PartitionSpans will
-	// filter out incompatible nodes.
-	planCtx, _, err := dsp.SetupAllNodesPlanning(ctx, evalCtx, execCtx.ExecCfg())
+	// filter out incompatible nodes, as well as those that do not match the
+	// execution locality of the backup, if any.
+	executionLocality := job.Details().(jobspb.BackupDetails).ExecutionLocality
+	planCtx, _, err := dsp.SetupAllNodesPlanningWithLocalityFilter(
+		ctx, evalCtx, execCtx.ExecCfg(), executionLocality,
+	)
 	if err != nil {
 		return roachpb.RowCount{}, errors.Wrap(err, "failed to determine nodes on which to run")
 	}
@@ -176,6 +271,13 @@ func backup(
 		&execCtx.ExecCfg().ExternalIODirConfig, execCtx.ExecCfg().DB, execCtx.User(),
 		execCtx.ExecCfg().InternalExecutor)
 
+	// The data files of a backup relying on encryption-at-rest are written
+	// unencrypted; only its metadata is encrypted.
+	dataEncryption := encryption
+	if backupManifest.EncryptionAtRestOnly {
+		dataEncryption = nil
+	}
+
 	backupSpecs, err := distBackupPlanSpecs(
 		ctx,
 		planCtx,
@@ -185,17 +287,25 @@ func backup(
 		spans,
 		introducedSpans,
 		pkIDs,
-		defaultURI,
+		dataURI,
 		urisByLocalityKV,
-		encryption,
+		dataEncryption,
 		&kmsEnv,
 		roachpb.MVCCFilter(backupManifest.MVCCFilter),
 		backupManifest.StartTime,
 		backupManifest.EndTime,
+		uploadOptions,
+		backupManifest.PerTableFiles,
+		highPriority,
+		allowMissingLocalities,
+		backupManifest.Compression.DataCodec,
 	)
 	if err != nil {
 		return roachpb.RowCount{}, err
 	}
+	if spanStats != nil {
+		spanStats.setTargetFileSizes(backupSpecs)
+	}
 
 	numTotalSpans := 0
 	for _, spec := range backupSpecs {
@@ -215,6 +325,42 @@ func backup(
 		}
 	}
 
+	frontier := makeExportFrontier(backupManifest)
+
+	// The files the backup writes are collected in a list that spills to
+	// temporary storage past its memory budget, rather than in the manifest,
+	// which would need to hold all of them in memory.
+	backupMon := execCtx.ExecCfg().DistSQLSrv.BackupMonitor
+	fileListMon := mon.NewMonitorInheritWithLimit("backup-file-list",
+		backupinfo.FileListMemoryBudget.Get(&settings.SV), backupMon)
+	fileListMon.StartNoReserved(ctx, backupMon)
+	defer fileListMon.Stop(ctx)
+	fileListMem := fileListMon.MakeBoundAccount()
+	defer fileListMem.Close(ctx)
+	files := backupinfo.NewFileList(&fileListMem, execCtx.ExecCfg().DistSQLSrv.TempStorage)
+	defer files.Close(ctx)
+	for _, file := range backupManifest.Files {
+		if err := files.Add(ctx, file); err != nil {
+			return roachpb.RowCount{}, err
+		}
+	}
+	backupManifest.Files = nil
+
+	writeCheckpoint := func(ctx context.Context) {
+		err := backupinfo.WriteBackupManifestCheckpointWithFiles(
+			ctx, defaultURI, encryption, &kmsEnv, backupManifest, files, execCtx.ExecCfg(), execCtx.User(),
+		)
+		if err != nil {
+			log.Errorf(ctx, "unable to checkpoint backup descriptor: %+v", err)
+		}
+		if err := job.SetProgress(ctx, nil /* txn */, jobspb.BackupProgress{
+			RemainingSpans: frontier.remaining(),
+		}); err != nil {
+			log.Warningf(ctx, "unable to record backup export frontier: %+v", err)
+		}
+		lastCheckpoint = timeutil.Now()
+	}
+
 	progCh := make(chan *execinfrapb.RemoteProducerMetadata_BulkProcessorProgress)
 	checkpointLoop := func(ctx context.Context) error {
 		// When a processor is done exporting a span, it will send a progress update
@@ -230,10 +376,19 @@ func backup(
 				backupManifest.RevisionStartTime = progDetails.RevStartTime
 			}
 			for _, file := range progDetails.Files {
-				backupManifest.Files = append(backupManifest.Files, file)
+				if err := files.Add(ctx, file); err != nil {
+					return err
+				}
 				backupManifest.EntryCounts.Add(file.EntryCounts)
 				numBackedUpFiles++
 			}
+			backupManifest.PhysicalSize += progDetails.PhysicalSize
+			for _, kv := range progDetails.MissingLocalityKVs {
+				log.Warningf(ctx, "files of locality %s are being written to the default destination", kv)
+				recordMissingLocality(backupManifest, kv)
+			}
+			frontier.add(progDetails.Files...)
+			frontier.add(progDetails.EmptySpans...)
 
 			// Signal that an ExportRequest finished to update job progress.
 			for i := int32(0); i < progDetails.CompletedSpans; i++ {
@@ -248,13 +403,7 @@ func backup(
 					RevisionStartTime: backupManifest.RevisionStartTime,
 				})
 
-				err := backupinfo.WriteBackupManifestCheckpoint(
-					ctx, defaultURI, encryption, &kmsEnv, backupManifest, execCtx.ExecCfg(), execCtx.User(),
-				)
-				if err != nil {
-					log.Errorf(ctx, "unable to checkpoint backup descriptor: %+v", err)
-				}
-				lastCheckpoint = timeutil.Now()
+				writeCheckpoint(ctx)
 				if execCtx.ExecCfg().TestingKnobs.AfterBackupCheckpoint != nil {
 					execCtx.ExecCfg().TestingKnobs.AfterBackupCheckpoint()
 				}
@@ -265,6 +414,8 @@ func backup(
 
 	resumerSpan.RecordStructured(&types.StringValue{Value: "starting DistSQL backup execution"})
 	runBackup := func(ctx context.Context) error {
+		ctx, sp := startJobPhaseSpan(ctx, backupPhaseExport, job.ID(), defaultURI)
+		defer sp.Finish()
 		return distBackup(
 			ctx,
 			execCtx,
@@ -276,17 +427,30 @@ func backup(
 	}
 
 	if err := ctxgroup.GoAndWait(ctx, jobProgressLoop, checkpointLoop, runBackup); err != nil {
+		if errors.Is(err, sql.ErrPlanChanged) {
+			// Checkpoint the files exported so far before the flow is re-planned,
+			// so that the next one does not export their spans again.
+			writeCheckpoint(ctx)
+		}
 		return roachpb.RowCount{}, errors.Wrapf(err, "exporting %d ranges", errors.Safe(numTotalSpans))
 	}
 
+	// Everything has been exported, so clear the frontier.
+	if err := job.SetProgress(ctx, nil /* txn */, jobspb.BackupProgress{}); err != nil {
+		log.Warningf(ctx, "unable to clear backup export frontier: %+v", err)
+	}
+
 	backupID := uuid.MakeV4()
 	backupManifest.ID = backupID
 	// Write additional partial descriptors to each node for partitioned backups.
 	if len(storageByLocalityKV) > 0 {
 		resumerSpan.RecordStructured(&types.StringValue{Value: "writing partition descriptors for partitioned backup"})
 		filesByLocalityKV := make(map[string][]backuppb.BackupManifest_File)
-		for _, file := range backupManifest.Files {
-			filesByLocalityKV[file.LocalityKV] = append(filesByLocalityKV[file.LocalityKV], file)
+		if err := files.Iterate(ctx, func(file *backuppb.BackupManifest_File) error {
+			filesByLocalityKV[file.LocalityKV] = append(filesByLocalityKV[file.LocalityKV], *file)
+			return nil
+		}); err != nil {
+			return roachpb.RowCount{}, err
 		}
 
 		nextPartitionedDescFilenameID := 1
@@ -298,7 +462,6 @@ func backup(
 			filename := fmt.Sprintf("%s_%d_%s", backupPartitionDescriptorPrefix,
 				nextPartitionedDescFilenameID, backupinfo.SanitizeLocalityKV(kv))
 			nextPartitionedDescFilenameID++
-			backupManifest.PartitionDescriptorFilenames = append(backupManifest.PartitionDescriptorFilenames, filename)
 			desc := backuppb.BackupPartitionDescriptor{
 				LocalityKV: kv,
 				Files:      filesByLocalityKV[kv],
@@ -312,20 +475,77 @@ func backup(
 				}
 				defer store.Close()
 				return backupinfo.WriteBackupPartitionDescriptor(ctx, store, filename,
-					encryption, &kmsEnv, &desc)
+					backupencryption.LocalityEncryptionOptions(encryption, kv), &kmsEnv,
+					backupManifest.Compression, &desc)
 			}(); err != nil {
-				return roachpb.RowCount{}, err
+				// A locality whose files were all written to the default destination
+				// needs no partition descriptor to be restored.
+				if !allowMissingLocalities || len(desc.Files) > 0 {
+					return roachpb.RowCount{}, err
+				}
+				log.Warningf(ctx, "skipping the partition descriptor of missing locality %s: %v", kv, err)
+				recordMissingLocality(backupManifest, kv)
+				continue
 			}
+			backupManifest.PartitionDescriptorFilenames = append(backupManifest.PartitionDescriptorFilenames, filename)
 		}
 	}
 
-	resumerSpan.RecordStructured(&types.StringValue{Value: "writing backup manifest"})
-	if err := backupinfo.WriteBackupManifest(ctx, defaultStore, backupbase.BackupManifestName,
-		encryption, &kmsEnv, backupManifest); err != nil {
+	if err := func() error {
+		ctx, sp := startJobPhaseSpan(ctx, backupPhaseWriteManifest, job.ID(), defaultURI)
+		defer sp.Finish()
+		return writeBackupMetadata(ctx, settings, defaultStore, encryption, &kmsEnv, backupManifest,
+			files, statsCache)
+	}(); err != nil {
 		return roachpb.RowCount{}, err
 	}
+
+	return backupManifest.EntryCounts, nil
+}
+
+// recordMissingLocality records in the manifest of a backup run with
+// allow_missing_localities that the destination of the locality kv could not
+// be written.
+func recordMissingLocality(m *backuppb.BackupManifest, kv string) {
+	for _, missing := range m.MissingLocalityKVs {
+		if missing == kv {
+			return
+		}
+	}
+	m.MissingLocalityKVs = append(m.MissingLocalityKVs, kv)
+}
+
+// writeBackupMetadata writes the manifest of a backup whose data files have
+// all been written, along with the statistics of its tables, to defaultStore.
+// The data files are those of files if it is non-nil, and those of the manifest
+// otherwise.
+func writeBackupMetadata(
+	ctx context.Context,
+	settings *cluster.Settings,
+	defaultStore cloud.ExternalStorage,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	backupManifest *backuppb.BackupManifest,
+	files *backupinfo.FileList,
+	statsCache *stats.TableStatisticsCache,
+) error {
+	resumerSpan := tracing.SpanFromContext(ctx)
+	// The stub is written first, so that every manifest a chain is built from
+	// has one.
+	if err := backupinfo.WriteBackupLayerStub(ctx, defaultStore, backupManifest); err != nil {
+		return err
+	}
+	resumerSpan.RecordStructured(&types.StringValue{Value: "writing backup manifest"})
+	if err := backupinfo.WriteBackupManifestWithFiles(ctx, defaultStore, backupbase.BackupManifestName,
+		encryption, kmsEnv, backupManifest, files); err != nil {
+		return err
+	}
 	var tableStatistics []*stats.TableStatisticProto
 	for i := range backupManifest.Descriptors {
+		if statsCache == nil {
+			// The backup was run with statistics = false.
+			break
+		}
 		if tbl, _, _, _, _ := descpb.GetDescriptors(&backupManifest.Descriptors[i]); tbl != nil {
 			tableDesc := tabledesc.NewBuilder(tbl).BuildImmutableTable()
 			// Collect all the table stats for this table.
@@ -351,22 +571,22 @@ func backup(
 	}
 
 	resumerSpan.RecordStructured(&types.StringValue{Value: "writing backup table statistics"})
-	if err := backupinfo.WriteTableStatistics(ctx, defaultStore, encryption, &kmsEnv, &statsTable); err != nil {
-		return roachpb.RowCount{}, err
+	if err := backupinfo.WriteTableStatistics(ctx, defaultStore, encryption, kmsEnv, &statsTable); err != nil {
+		return err
 	}
 
 	if backupinfo.WriteMetadataSST.Get(&settings.SV) {
-		if err := backupinfo.WriteBackupMetadataSST(ctx, defaultStore, encryption, &kmsEnv, backupManifest,
-			tableStatistics); err != nil {
+		if err := backupinfo.WriteBackupMetadataSST(ctx, defaultStore, encryption, kmsEnv, backupManifest,
+			files, tableStatistics); err != nil {
 			err = errors.Wrap(err, "writing forward-compat metadata sst")
 			if !build.IsRelease() {
-				return roachpb.RowCount{}, err
+				return err
 			}
 			log.Warningf(ctx, "%+v", err)
 		}
 	}
 
-	return backupManifest.EntryCounts, nil
+	return nil
 }
 
 func releaseProtectedTimestamp(
@@ -402,8 +622,29 @@ func (b *backupResumer) ForceRealSpan() bool {
 	return true
 }
 
+// redactJobError redacts the secrets of the URIs in an error that a backup or
+// restore job fails with, which the job registry logs and persists in the job,
+// keeping the markers that tell the registry to pause or retry the job.
+func redactJobError(err error) error {
+	redacted := backuputils.RedactURIsInError(err)
+	if redacted == err {
+		return err
+	}
+	if jobs.IsPauseSelfError(err) {
+		redacted = jobs.MarkPauseRequestError(redacted)
+	}
+	if jobs.IsRetryJobError(err) {
+		redacted = jobs.MarkAsRetryJobError(redacted)
+	}
+	if jobs.IsPermanentJobError(err) {
+		redacted = jobs.MarkAsPermanentJobError(redacted)
+	}
+	return redacted
+}
+
 // Resume is part of the jobs.Resumer interface.
-func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
+func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) (retErr error) {
+	defer func() { retErr = redactJobError(retErr) }()
 	// The span is finished by the registry executing the job.
 	resumerSpan := tracing.SpanFromContext(ctx)
 	details := b.job.Details().(jobspb.BackupDetails)
@@ -416,13 +657,52 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 	// during a previous resumption of this job.
 	defaultURI := details.URI
 	var backupDest backupdest.ResolvedDestination
+	var coordination *backuppb.CoordinatedBackup
 	if details.URI == "" {
-		var err error
-		backupDest, err = backupdest.ResolveDest(ctx, p.User(), details.Destination, details.EndTime,
-			details.IncrementalFrom, p.ExecCfg())
-		if err != nil {
+		if details.Coordination != nil {
+			// A coordinated backup is as of the end time negotiated with the other
+			// clusters, which names the directory of an incremental backup, so it
+			// is negotiated before the destination is resolved.
+			negotiated, err := negotiateCoordinatedBackup(ctx, p.ExecCfg(), p.User(), details)
+			if err != nil {
+				return err
+			}
+			details.EndTime = negotiated.EndTime
+			coordination = &negotiated
+		}
+		resolveDest := func() error {
+			ctx, sp := startJobPhaseSpan(ctx, backupPhaseResolveDestination, b.job.ID(), details.Destination.To[0])
+			defer sp.Finish()
+			var err error
+			backupDest, err = backupdest.ResolveDest(ctx, p.ExecCfg(), backupdest.ResolveOptions{
+				User:            p.User(),
+				Destination:     details.Destination,
+				EndTime:         details.EndTime,
+				IncrementalFrom: details.IncrementalFrom,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "resolving backup destination %s",
+					strings.Join(backuputils.RedactURIsForErrorMessage(details.Destination.To), ", "))
+			}
+			return nil
+		}
+		if err := resolveDest(); err != nil {
 			return err
 		}
+		if details.ConsolidateChanges {
+			// A backup consolidating changes is as of the latest timestamp resolved
+			// by the changefeed writing them. That timestamp names the directory of
+			// the backup, so the destination is resolved again once it is known.
+			endTime, err := latestResolvedChanges(ctx, p.ExecCfg(), p.User(), backupDest)
+			if err != nil {
+				return err
+			}
+			details.EndTime = endTime
+			if err := resolveDest(); err != nil {
+				return err
+			}
+			details.ChangesURI = backupDest.ChangesURI
+		}
 		defaultURI = backupDest.DefaultURI
 	}
 
@@ -484,6 +764,19 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 		// Reset backupDetails so nobody accidentally uses it.
 		backupDetails = jobspb.BackupDetails{} //lint:ignore SA4006 intentionally clearing so no one uses this.
 		backupManifest = &m
+		backupManifest.Coordination = coordination
+
+		// A backup run with skip_if_unchanged that finds nothing changed since
+		// the previous backup of its chain completes here, before it protects
+		// its spans or writes its checkpoint. If it cannot tell, it is written.
+		if details.SkipIfUnchanged {
+			unchanged, err := incrementalBackupUnchanged(ctx, p.ExecCfg(), backupManifest)
+			if err != nil {
+				log.Warningf(ctx, "failed to check whether backup is unchanged, writing it: %v", err)
+			} else if unchanged {
+				return b.skipUnchangedBackup(ctx, p, details, backupManifest)
+			}
+		}
 
 		// Now that we have resolved the details, and manifest, write a protected
 		// timestamp record on the backup's target spans/schema object.
@@ -560,11 +853,27 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 			return err
 		}
 
+		b.recordCollectionEvent(ctx, p.ExecCfg(), p.User(), backupdest.CollectionEvent{
+			Type:        backupdest.CollectionEventBackupStarted,
+			Subdir:      details.Destination.Subdir,
+			Incremental: !details.StartTime.IsEmpty(),
+		})
+
 		// Collect telemetry, once per backup after resolving its destination.
 		lic := utilccl.CheckEnterpriseEnabled(
 			p.ExecCfg().Settings, p.ExecCfg().NodeInfo.LogicalClusterID(), p.ExecCfg().Organization(), "",
 		) != nil
 		collectTelemetry(ctx, m, initialDetails, details, lic, b.job.ID())
+	} else if details.SkipIfUnchanged {
+		// The job may have been resumed after it was skipped, but before it
+		// completed.
+		skipped, err := backupinfo.BackupSkippedMarkerExists(ctx, p.ExecCfg(), details.URI, p.User())
+		if err != nil {
+			return err
+		}
+		if skipped {
+			return b.maybeNotifyScheduledJobCompletion(ctx, jobs.StatusSucceeded, nil /* jobErr */, p.ExecCfg())
+		}
 	}
 
 	// For all backups, partitioned or not, the main BACKUP manifest is stored at
@@ -616,6 +925,11 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 		}
 	}
 
+	dataURI := details.URI
+	if details.DataURI != "" {
+		dataURI = details.DataURI
+	}
+
 	statsCache := p.ExecCfg().TableStatsCache
 	// We retry on pretty generic failures -- any rpc error. If a worker node were
 	// to restart, it would produce this kind of error, but there may be other
@@ -631,6 +945,10 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 
 	// We want to retry a backup if there are transient failures (i.e. worker nodes
 	// dying), so if we receive a retryable error, re-plan and retry the backup.
+	if details.SkipStatistics {
+		statsCache = nil
+	}
+
 	var res roachpb.RowCount
 	var retryCount int32
 	for r := retry.StartWithCtx(ctx, retryOpts); r.Next(); {
@@ -640,20 +958,28 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 			AttemptNumber: retryCount,
 			RetryError:    tracing.RedactAndTruncateError(err),
 		})
-		res, err = backup(
-			ctx,
-			p,
-			details.URI,
-			details.URIsByLocalityKV,
-			p.ExecCfg().Settings,
-			defaultStore,
-			storageByLocalityKV,
-			b.job,
-			backupManifest,
-			p.ExecCfg().DistSQLSrv.ExternalStorage,
-			details.EncryptionOptions,
-			statsCache,
-		)
+		if details.ConsolidateChanges {
+			res, err = consolidateChanges(ctx, p, details, defaultStore, backupManifest, statsCache)
+		} else {
+			res, err = backup(
+				ctx,
+				p,
+				details.URI,
+				dataURI,
+				details.URIsByLocalityKV,
+				p.ExecCfg().Settings,
+				defaultStore,
+				storageByLocalityKV,
+				b.job,
+				backupManifest,
+				p.ExecCfg().DistSQLSrv.ExternalStorage,
+				details.EncryptionOptions,
+				details.UploadOptions,
+				details.HighPriority,
+				details.AllowMissingLocalities,
+				statsCache,
+			)
+		}
 		if err == nil {
 			break
 		}
@@ -662,7 +988,13 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 			return errors.Wrap(err, "failed to run backup")
 		}
 
-		log.Warningf(ctx, `BACKUP job encountered retryable error: %+v`, err)
+		if errors.Is(err, sql.ErrPlanChanged) {
+			// Re-planning does not count as a retry.
+			log.Infof(ctx, "re-planning BACKUP job: %v", err)
+			r.Reset()
+		} else {
+			log.Warningf(ctx, `BACKUP job encountered retryable error: %+v`, err)
+		}
 
 		// Reload the backup manifest to pick up any spans we may have completed on
 		// previous attempts.
@@ -732,11 +1064,59 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 		}
 		defer c.Close()
 
-		if err := backupdest.WriteNewLatestFile(ctx, p.ExecCfg().Settings, c, suffix); err != nil {
+		latest := backupdest.MakeLatestFile(suffix, backupManifest.EndTime,
+			backupManifest.EntryCounts.DataSize, backupManifest.PhysicalSize, details.EncryptionInfo)
+		if err := backupdest.WriteNewLatestFile(ctx, p.ExecCfg().Settings, c, latest); err != nil {
+			return err
+		}
+		if err := maybeNotifyLatestWebhook(ctx, p.ExecCfg(), b.job.ID(),
+			details.Destination.Subdir, backupManifest); err != nil {
 			return err
 		}
 	}
 
+	// Record the size of the chain this backup added a layer to, so that SHOW
+	// BACKUPS can report it. The backup has succeeded at this point, so failing
+	// to do so is only logged.
+	if details.CollectionURI != "" {
+		if err := func() error {
+			c, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, details.CollectionURI, p.User())
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			return backupdest.UpdateChainSize(ctx, c, details.Destination.Subdir,
+				backupManifest.StartTime.IsEmpty(), backupManifest.EndTime,
+				backupManifest.EntryCounts.DataSize, backupManifest.PhysicalSize)
+		}(); err != nil {
+			log.Warningf(ctx, "failed to record the size of backup chain %s: %v",
+				details.Destination.Subdir, err)
+		}
+	}
+	b.recordCollectionEvent(ctx, p.ExecCfg(), p.User(), backupdest.CollectionEvent{
+		Type:         backupdest.CollectionEventBackupSucceeded,
+		Subdir:       details.Destination.Subdir,
+		Incremental:  !backupManifest.StartTime.IsEmpty(),
+		EndTime:      backupManifest.EndTime.AsOfSystemTime(),
+		LogicalSize:  backupManifest.EntryCounts.DataSize,
+		PhysicalSize: backupManifest.PhysicalSize,
+	})
+
+	// Record the end time of this backup for each table it covered, which the
+	// RPO metric is computed from. As above, failing to do so is only logged.
+	if err := recordTableBackupCheckpoints(ctx, p.ExecCfg(), b.job.ID(), backupManifest); err != nil {
+		log.Warningf(ctx, "failed to record table backup checkpoints: %v", err)
+	}
+	if m, ok := p.ExecCfg().JobRegistry.MetricsStruct().Backup.(*BackupMetrics); ok {
+		if err := m.refreshRPO(ctx, p.ExecCfg()); err != nil {
+			log.Warningf(ctx, "failed to refresh backup RPO metric: %v", err)
+		}
+		if details.CollectionURI != "" {
+			m.recordCollectionBackup(details.CollectionURI, backupManifest, timeutil.Now())
+		}
+		m.maybeStartRPORefresher(p.ExecCfg())
+	}
+
 	b.backupStats = res
 
 	// Collect telemetry.
@@ -772,7 +1152,7 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 		logJobCompletion(ctx, b.getTelemetryEventType(), b.job.ID(), true, nil)
 	}
 
-	return b.maybeNotifyScheduledJobCompletion(ctx, jobs.StatusSucceeded, p.ExecCfg())
+	return b.maybeNotifyScheduledJobCompletion(ctx, jobs.StatusSucceeded, nil /* jobErr */, p.ExecCfg())
 }
 
 // ReportResults implements JobResultsReporter interface.
@@ -809,7 +1189,8 @@ func getBackupDetailAndManifest(
 	defer mem.Close(ctx)
 
 	prevBackups, encryptionOptions, memSize, err := backupinfo.FetchPreviousBackups(ctx, &mem, user,
-		makeCloudStorage, backupDestination.PrevBackupURIs, *initialDetails.EncryptionOptions, &kmsEnv)
+		makeCloudStorage, backupDestination.PrevBackupURIs, *initialDetails.EncryptionOptions, &kmsEnv,
+		false /* elideFiles */)
 
 	if err != nil {
 		return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
@@ -817,6 +1198,11 @@ func getBackupDetailAndManifest(
 	defer func() {
 		mem.Shrink(ctx, memSize)
 	}()
+	for i := range prevBackups {
+		if err := backupread.CheckReadable(&prevBackups[i], execCfg.Settings.Version.BinaryVersion()); err != nil {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
+		}
+	}
 
 	if len(prevBackups) > 0 {
 		baseManifest := prevBackups[0]
@@ -828,7 +1214,22 @@ func getBackupDetailAndManifest(
 		if err := requireEnterprise(execCfg, "incremental"); err != nil {
 			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
 		}
+		// The data of every backup in a chain must be found the same way, either
+		// alongside its manifest or at the same path in the data collection.
+		if baseManifest.DataURI == "" && backupDestination.DataURI != "" {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, errors.New(
+				"cannot append a backup with metadata_uri to a backup chain written without it")
+		}
+		if baseManifest.DataURI != "" && backupDestination.DataURI == "" {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, errors.New(
+				"cannot append a backup without metadata_uri to a backup chain written with it")
+		}
 		lastEndTime := prevBackups[len(prevBackups)-1].EndTime
+		if initialDetails.ConsolidateChanges && initialDetails.EndTime.LessEq(lastEndTime) {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{},
+				errors.Newf("no changes were resolved since the previous backup's end time of %s",
+					lastEndTime.GoTime())
+		}
 		if lastEndTime.Compare(initialDetails.EndTime) > 0 {
 			return jobspb.BackupDetails{}, backuppb.BackupManifest{},
 				errors.Newf("`AS OF SYSTEM TIME` %s must be greater than "+
@@ -843,6 +1244,15 @@ func getBackupDetailAndManifest(
 		localityKVs[i] = k
 		i++
 	}
+	if enc := initialDetails.EncryptionOptions; enc != nil {
+		for localityKV := range enc.RawKMSURIsByLocalityKV {
+			if _, ok := backupDestination.URIsByLocalityKV[localityKV]; !ok {
+				return jobspb.BackupDetails{}, backuppb.BackupManifest{}, errors.Newf(
+					"%s specifies a KMS URI for locality %s, which has no backup destination",
+					backupOptKMSByLocality, localityKV)
+			}
+		}
+	}
 
 	for i := range prevBackups {
 		prevBackup := prevBackups[i]
@@ -894,6 +1304,7 @@ func getBackupDetailAndManifest(
 	if err != nil {
 		return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
 	}
+	updatedDetails.DataURI = backupDestination.DataURI
 
 	backupManifest, err := createBackupManifest(
 		ctx,
@@ -904,6 +1315,33 @@ func getBackupDetailAndManifest(
 	if err != nil {
 		return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
 	}
+	if updatedDetails.ConsolidateChanges {
+		if err := checkConsolidatedManifest(&backupManifest, &prevBackups[len(prevBackups)-1]); err != nil {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
+		}
+	}
+
+	if updatedDetails.DataURI != "" {
+		backupManifest.DataURI, err = cloud.SanitizeExternalStorageURI(updatedDetails.DataURI, nil /* extraParams */)
+		if err != nil {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
+		}
+	}
+
+	if len(prevBackups) > 0 {
+		backupManifest.Generation = prevBackups[0].Generation
+		backupManifest.Layer = int32(len(prevBackups))
+	} else if backupDestination.CollectionURI != "" {
+		collection, err := makeCloudStorage(ctx, backupDestination.CollectionURI, user)
+		if err != nil {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
+		}
+		defer collection.Close()
+		backupManifest.Generation, err = backupdest.NextCollectionGeneration(ctx, collection)
+		if err != nil {
+			return jobspb.BackupDetails{}, backuppb.BackupManifest{}, err
+		}
+	}
 
 	return updatedDetails, backupManifest, nil
 }
@@ -955,11 +1393,16 @@ func (b *backupResumer) readManifestOnResume(
 		return nil, 0, errors.Newf("cannot resume backup started on another cluster (%s != %s)",
 			desc.ClusterID, cfg.NodeInfo.LogicalClusterID())
 	}
+	// The job may have been started by a newer node than this one.
+	if err := backupread.CheckReadable(&desc, cfg.Settings.Version.BinaryVersion()); err != nil {
+		mem.Shrink(ctx, memSize)
+		return nil, 0, err
+	}
 	return &desc, memSize, nil
 }
 
 func (b *backupResumer) maybeNotifyScheduledJobCompletion(
-	ctx context.Context, jobStatus jobs.Status, exec *sql.ExecutorConfig,
+	ctx context.Context, jobStatus jobs.Status, jobErr error, exec *sql.ExecutorConfig,
 ) error {
 	env := scheduledjobs.ProdJobSchedulerEnv
 	if knobs, ok := exec.DistSQLSrv.TestingKnobs.JobsTestingKnobs.(*jobs.TestingKnobs); ok {
@@ -968,7 +1411,9 @@ func (b *backupResumer) maybeNotifyScheduledJobCompletion(
 		}
 	}
 
+	var scheduleID int64
 	err := exec.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		scheduleID = 0
 		// We cannot rely on b.job containing created_by_id because on job
 		// resumption the registry does not populate the resumer's CreatedByInfo.
 		datums, err := exec.InternalExecutor.QueryRowEx(
@@ -988,7 +1433,7 @@ func (b *backupResumer) maybeNotifyScheduledJobCompletion(
 			return nil
 		}
 
-		scheduleID := int64(tree.MustBeDInt(datums[0]))
+		scheduleID = int64(tree.MustBeDInt(datums[0]))
 		if err := jobs.NotifyJobTermination(
 			ctx, env, b.job.ID(), jobStatus, b.job.Details(), scheduleID, exec.InternalExecutor, txn); err != nil {
 			return errors.Wrapf(err,
@@ -996,13 +1441,33 @@ func (b *backupResumer) maybeNotifyScheduledJobCompletion(
 		}
 		return nil
 	})
-	return err
+	if err != nil || scheduleID == 0 {
+		return err
+	}
+
+	status := jobStatus
+	if jobs.HasErrJobCanceled(jobErr) {
+		status = jobs.StatusCanceled
+	}
+	var stats *roachpb.RowCount
+	if jobStatus == jobs.StatusSucceeded {
+		stats = &b.backupStats
+		details := b.job.Details().(jobspb.BackupDetails)
+		if m, ok := exec.JobRegistry.MetricsStruct().Backup.(*BackupMetrics); ok && details.CollectionURI != "" {
+			m.recordScheduledBackup(details.CollectionURI, scheduleID, details.EndTime.GoTime(), env.Now())
+		}
+	}
+	recordScheduledBackupRun(ctx, exec.Settings, exec.InternalExecutor, env.Now(), makeScheduledBackupRun(
+		scheduleID, b.job.ID(), string(status), timeutil.FromUnixMicros(b.job.Payload().StartedMicros),
+		b.job.Details().(jobspb.BackupDetails), stats, jobErr))
+	return nil
 }
 
 // OnFailOrCancel is part of the jobs.Resumer interface.
 func (b *backupResumer) OnFailOrCancel(
 	ctx context.Context, execCtx interface{}, jobErr error,
-) error {
+) (retErr error) {
+	defer func() { retErr = redactJobError(retErr) }()
 	telemetry.Count("backup.total.failed")
 	telemetry.CountBucketed("backup.duration-sec.failed",
 		int64(timeutil.Since(timeutil.FromUnixMicros(b.job.Payload().StartedMicros)).Seconds()))
@@ -1011,6 +1476,18 @@ func (b *backupResumer) OnFailOrCancel(
 	p := execCtx.(sql.JobExecContext)
 	cfg := p.ExecCfg()
 	b.deleteCheckpoint(ctx, cfg, p.User())
+	{
+		details := b.job.Details().(jobspb.BackupDetails)
+		ev := backupdest.CollectionEvent{
+			Type:        backupdest.CollectionEventBackupFailed,
+			Subdir:      details.Destination.Subdir,
+			Incremental: !details.StartTime.IsEmpty(),
+		}
+		if jobErr != nil {
+			ev.Error = jobErr.Error()
+		}
+		b.recordCollectionEvent(ctx, cfg, p.User(), ev)
+	}
 	if err := cfg.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
 		details := b.job.Details().(jobspb.BackupDetails)
 		return releaseProtectedTimestamp(ctx, txn, cfg.ProtectedTimestampProvider,
@@ -1022,7 +1499,7 @@ func (b *backupResumer) OnFailOrCancel(
 	// This should never return an error unless resolving the schedule that the
 	// job is being run under fails. This could happen if the schedule is dropped
 	// while the job is executing.
-	if err := b.maybeNotifyScheduledJobCompletion(ctx, jobs.StatusFailed,
+	if err := b.maybeNotifyScheduledJobCompletion(ctx, jobs.StatusFailed, jobErr,
 		execCtx.(sql.JobExecContext).ExecCfg()); err != nil {
 		log.Errorf(ctx, "failed to notify job %d on completion of OnFailOrCancel: %+v",
 			b.job.ID(), err)
@@ -1063,6 +1540,29 @@ func (b *backupResumer) deleteCheckpoint(
 	}
 }
 
+// recordCollectionEvent appends ev, about this job, to the event log of the
+// collection the job backs up into, if any. Failing to do so is only logged, as
+// the event log is informational.
+func (b *backupResumer) recordCollectionEvent(
+	ctx context.Context, cfg *sql.ExecutorConfig, user username.SQLUsername, ev backupdest.CollectionEvent,
+) {
+	details := b.job.Details().(jobspb.BackupDetails)
+	if details.CollectionURI == "" || !backupdest.CollectionEventsEnabled.Get(&cfg.Settings.SV) {
+		return
+	}
+	ev.JobID = b.job.ID()
+	if err := func() error {
+		c, err := cfg.DistSQLSrv.ExternalStorageFromURI(ctx, details.CollectionURI, user)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		return backupdest.RecordCollectionEvent(ctx, cfg.Settings, c, ev)
+	}(); err != nil {
+		log.Warningf(ctx, "failed to record %s event of backup job %d: %v", ev.Type, ev.JobID, err)
+	}
+}
+
 func (b *backupResumer) getTelemetryEventType() eventpb.RecoveryEventType {
 	if b.job.Details().(jobspb.BackupDetails).ScheduleID != 0 {
 		return scheduledBackupJobEventType
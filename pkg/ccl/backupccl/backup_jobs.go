@@ -0,0 +1,185 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// Cluster backups taken with the jobs option capture the changefeeds that are
+// running or paused in the manifest. system.jobs itself is not restored by a
+// cluster restore, so a cluster restore recreates them, paused and rekeyed to
+// the restored tables, for an operator to resume once the restored cluster is
+// ready to emit to their sinks again. Other jobs, such as schema changes, are
+// tied to the descriptors and state of the cluster they ran in and are not
+// captured.
+
+// getResumableJobs returns the changefeed jobs that are not finished as of
+// asOf.
+func getResumableJobs(
+	ctx context.Context, ie *sql.InternalExecutor, asOf hlc.Timestamp,
+) ([]backuppb.BackedUpJob, error) {
+	rows, err := ie.QueryBuffered(ctx, "backup-get-jobs", nil, /* txn */
+		fmt.Sprintf(`SELECT id, status, payload, progress FROM system.jobs
+AS OF SYSTEM TIME %s WHERE status IN ($1, $2, $3, $4) ORDER BY id`, asOf.AsOfSystemTime()),
+		jobs.StatusPending, jobs.StatusRunning, jobs.StatusPaused, jobs.StatusPauseRequested)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading jobs")
+	}
+	var backedUp []backuppb.BackedUpJob
+	for _, row := range rows {
+		payload, err := jobs.UnmarshalPayload(row[2])
+		if err != nil {
+			return nil, err
+		}
+		if payload.Type() != jobspb.TypeChangefeed {
+			continue
+		}
+		job := backuppb.BackedUpJob{
+			ID:      int64(tree.MustBeDInt(row[0])),
+			Status:  string(tree.MustBeDString(row[1])),
+			Payload: []byte(tree.MustBeDBytes(row[2])),
+		}
+		if row[3] != tree.DNull {
+			job.Progress = []byte(tree.MustBeDBytes(row[3]))
+		}
+		backedUp = append(backedUp, job)
+	}
+	return backedUp, nil
+}
+
+// restoreJobs recreates the jobs captured in manifest, paused, unless the
+// restore was asked to skip them or already restored them.
+func (r *restoreResumer) restoreJobs(
+	ctx context.Context, execCfg *sql.ExecutorConfig, manifest backuppb.BackupManifest,
+) error {
+	details := r.job.Details().(jobspb.RestoreDetails)
+	if details.SkipJobs || details.JobsRestored || len(manifest.Jobs) == 0 {
+		return nil
+	}
+	return execCfg.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		restoreTime := txn.ReadTimestamp()
+		for _, job := range manifest.Jobs {
+			payload, progress, ok, err := rewriteBackedUpJob(
+				job, details.DescriptorRewrites, manifest.EndTime, restoreTime)
+			if err != nil {
+				return errors.Wrapf(err, "rewriting job %d", job.ID)
+			}
+			if !ok {
+				log.Infof(ctx, "not restoring job %d as not all of its tables were restored", job.ID)
+				continue
+			}
+			payloadBytes, err := protoutil.Marshal(&payload)
+			if err != nil {
+				return err
+			}
+			progressBytes, err := protoutil.Marshal(&progress)
+			if err != nil {
+				return err
+			}
+			if _, err := execCfg.InternalExecutor.Exec(ctx, "restore-job", txn,
+				`INSERT INTO system.jobs (id, status, payload, progress) VALUES ($1, $2, $3, $4)`,
+				execCfg.JobRegistry.MakeJobID(), jobs.StatusPaused, payloadBytes, progressBytes,
+			); err != nil {
+				return errors.Wrapf(err, "restoring job %d", job.ID)
+			}
+		}
+		details.JobsRestored = true
+		return r.job.SetDetails(ctx, txn, details)
+	})
+}
+
+// rewriteBackedUpJob returns the payload and progress of the restored copy of
+// a changefeed captured in a backup that ended at backupTime. The descriptor
+// IDs it refers to are rewritten according to rewrites, and ok is false if
+// any of them was not restored. The restored data has no history before the
+// restore, so the changefeed resumes from restoreTime; the changes between its
+// high-water and backupTime, which it may not have emitted, are noted in the
+// pause reason.
+func rewriteBackedUpJob(
+	job backuppb.BackedUpJob,
+	rewrites jobspb.DescRewriteMap,
+	backupTime, restoreTime hlc.Timestamp,
+) (payload jobspb.Payload, progress jobspb.Progress, ok bool, err error) {
+	if err := protoutil.Unmarshal(job.Payload, &payload); err != nil {
+		return jobspb.Payload{}, jobspb.Progress{}, false, err
+	}
+	if err := protoutil.Unmarshal(job.Progress, &progress); err != nil {
+		return jobspb.Payload{}, jobspb.Progress{}, false, err
+	}
+	cf := payload.GetChangefeed()
+	if cf == nil {
+		return jobspb.Payload{}, jobspb.Progress{}, false,
+			errors.AssertionFailedf("unexpected job type %s", payload.Type())
+	}
+
+	rewriteID := func(id descpb.ID) (descpb.ID, bool) {
+		rewrite, ok := rewrites[id]
+		if !ok {
+			return 0, false
+		}
+		return rewrite.ID, true
+	}
+	for i, id := range payload.DescriptorIDs {
+		if payload.DescriptorIDs[i], ok = rewriteID(id); !ok {
+			return jobspb.Payload{}, jobspb.Progress{}, false, nil
+		}
+	}
+	tables := make(jobspb.ChangefeedTargets, len(cf.Tables))
+	for id, target := range cf.Tables {
+		newID, ok := rewriteID(id)
+		if !ok {
+			return jobspb.Payload{}, jobspb.Progress{}, false, nil
+		}
+		tables[newID] = target
+	}
+	cf.Tables = tables
+	for i := range cf.TargetSpecifications {
+		spec := &cf.TargetSpecifications[i]
+		if spec.TableID, ok = rewriteID(spec.TableID); !ok {
+			return jobspb.Payload{}, jobspb.Progress{}, false, nil
+		}
+	}
+
+	var highWater hlc.Timestamp
+	if hw := progress.GetHighWater(); hw != nil {
+		highWater = *hw
+	}
+	if highWater.IsEmpty() {
+		// The initial scan had not completed, so it is redone over the restored
+		// data.
+		cf.StatementTime = restoreTime
+		payload.PauseReason = fmt.Sprintf(
+			"restored from a backup as of %s before its initial scan completed", backupTime)
+		progress.Progress = &jobspb.Progress_HighWater{}
+	} else {
+		payload.PauseReason = fmt.Sprintf(
+			"restored from a backup as of %s; changes between %s and %s may not have been emitted",
+			backupTime, highWater, backupTime)
+		progress.Progress = &jobspb.Progress_HighWater{HighWater: &restoreTime}
+	}
+	// The checkpoint and protected timestamp record belong to the backed up
+	// cluster.
+	progress.Details = &jobspb.Progress_Changefeed{Changefeed: &jobspb.ChangefeedProgress{}}
+	progress.RunningStatus = ""
+	return payload, progress, true, nil
+}
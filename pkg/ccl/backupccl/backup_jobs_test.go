@@ -0,0 +1,100 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteBackedUpJob(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	backupTime := hlc.Timestamp{WallTime: 100}
+	restoreTime := hlc.Timestamp{WallTime: 200}
+	rewrites := jobspb.DescRewriteMap{
+		104: {ID: 204},
+		105: {ID: 205},
+	}
+
+	makeJob := func(t *testing.T, ids []descpb.ID, highWater *hlc.Timestamp) backuppb.BackedUpJob {
+		cf := jobspb.ChangefeedDetails{
+			Tables:        make(jobspb.ChangefeedTargets),
+			SinkURI:       "kafka://sink",
+			StatementTime: hlc.Timestamp{WallTime: 10},
+		}
+		for _, id := range ids {
+			cf.Tables[id] = jobspb.ChangefeedTargetTable{StatementTimeName: "t"}
+			cf.TargetSpecifications = append(cf.TargetSpecifications,
+				jobspb.ChangefeedTargetSpecification{TableID: id})
+		}
+		payload := jobspb.Payload{
+			DescriptorIDs: ids,
+			Details:       jobspb.WrapPayloadDetails(cf),
+		}
+		progress := jobspb.Progress{
+			Progress: &jobspb.Progress_HighWater{HighWater: highWater},
+			Details: jobspb.WrapProgressDetails(jobspb.ChangefeedProgress{
+				ProtectedTimestampRecord: uuid.MakeV4(),
+			}),
+			RunningStatus: "running",
+		}
+		payloadBytes, err := protoutil.Marshal(&payload)
+		require.NoError(t, err)
+		progressBytes, err := protoutil.Marshal(&progress)
+		require.NoError(t, err)
+		return backuppb.BackedUpJob{ID: 1, Status: "running", Payload: payloadBytes, Progress: progressBytes}
+	}
+
+	t.Run("rewritten", func(t *testing.T) {
+		job := makeJob(t, []descpb.ID{104, 105}, &hlc.Timestamp{WallTime: 90})
+		payload, progress, ok, err := rewriteBackedUpJob(job, rewrites, backupTime, restoreTime)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, []descpb.ID{204, 205}, payload.DescriptorIDs)
+		cf := payload.GetChangefeed()
+		require.Len(t, cf.Tables, 2)
+		require.Contains(t, cf.Tables, descpb.ID(204))
+		require.Contains(t, cf.Tables, descpb.ID(205))
+		require.Equal(t, descpb.ID(204), cf.TargetSpecifications[0].TableID)
+		require.Equal(t, descpb.ID(205), cf.TargetSpecifications[1].TableID)
+		require.Equal(t, hlc.Timestamp{WallTime: 10}, cf.StatementTime)
+		require.Equal(t, restoreTime, *progress.GetHighWater())
+		require.Equal(t, uuid.UUID{}, progress.GetChangefeed().ProtectedTimestampRecord)
+		require.Empty(t, progress.RunningStatus)
+		require.Contains(t, payload.PauseReason, "may not have been emitted")
+	})
+
+	t.Run("initial scan incomplete", func(t *testing.T) {
+		job := makeJob(t, []descpb.ID{104}, nil)
+		payload, progress, ok, err := rewriteBackedUpJob(job, rewrites, backupTime, restoreTime)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, restoreTime, payload.GetChangefeed().StatementTime)
+		require.Nil(t, progress.GetHighWater())
+		require.Contains(t, payload.PauseReason, "before its initial scan completed")
+	})
+
+	t.Run("table not restored", func(t *testing.T) {
+		job := makeJob(t, []descpb.ID{104, 106}, &hlc.Timestamp{WallTime: 90})
+		_, _, ok, err := rewriteBackedUpJob(job, rewrites, backupTime, restoreTime)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
+)
+
+// A locality-aware backup encrypted with a KMS can be given a KMS URI for each
+// of its localities with the kms_by_locality option, e.g. so that the data of
+// each region is only ever encrypted with a key of that region. The files
+// written to the destination of such a locality, its data and partition
+// descriptor, are then encrypted with a data key of its own, encrypted with
+// the KMS of the locality, while the rest of the backup is encrypted with the
+// data key of the kms option. The encrypted data keys of the localities are
+// recorded in the ENCRYPTION-INFO file of the full backup, and incremental
+// backups and restores of the chain must be given the KMS URIs of the
+// localities to decrypt them.
+
+const backupOptKMSByLocality = "kms_by_locality"
+
+// kmsURIsByLocalityFn returns a function that evaluates the kms_by_locality
+// option of a BACKUP or RESTORE into the KMS URIs of the localities.
+func kmsURIsByLocalityFn(
+	ctx context.Context, p sql.PlanHookState, opts tree.KVOptions, op string,
+) (func() (map[string]string, error), error) {
+	// The processors of older versions ignore the data keys of the localities in
+	// their specs, and would use the data key of the kms option for every file.
+	if !p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.V23_1BackupKMSByLocality) {
+		return nil, errors.Newf(
+			"cannot use the %s option until the cluster has fully upgraded to 23.1", backupOptKMSByLocality)
+	}
+	fns := make(map[string]func() (string, error), len(opts))
+	for _, opt := range opts {
+		locality := string(opt.Key)
+		if err := (&roachpb.Tier{}).FromString(locality); err != nil {
+			return nil, errors.Wrapf(err, "invalid locality in %s", backupOptKMSByLocality)
+		}
+		if opt.Value == nil {
+			return nil, errors.Newf("%s requires a KMS URI for locality %s", backupOptKMSByLocality, locality)
+		}
+		if _, ok := fns[locality]; ok {
+			return nil, errors.Newf("%s specifies locality %s multiple times", backupOptKMSByLocality, locality)
+		}
+		fn, err := p.TypeAsString(ctx, opt.Value, op)
+		if err != nil {
+			return nil, err
+		}
+		fns[locality] = fn
+	}
+	return func() (map[string]string, error) {
+		kmsURIs := make(map[string]string, len(fns))
+		for locality, fn := range fns {
+			kmsURI, err := fn()
+			if err != nil {
+				return nil, err
+			}
+			kmsURIs[locality] = kmsURI
+		}
+		return kmsURIs, nil
+	}, nil
+}
+
+// redactKMSURIsByLocality returns the kms_by_locality option of the
+// description of a job, with the KMS URIs of the localities redacted.
+func redactKMSURIsByLocality(kmsURIsByLocality map[string]string) (tree.KVOptions, error) {
+	if len(kmsURIsByLocality) == 0 {
+		return nil, nil
+	}
+	localities := make([]string, 0, len(kmsURIsByLocality))
+	for locality := range kmsURIsByLocality {
+		localities = append(localities, locality)
+	}
+	sort.Strings(localities)
+	opts := make(tree.KVOptions, 0, len(localities))
+	for _, locality := range localities {
+		redactedURI, err := cloud.RedactKMSURI(kmsURIsByLocality[locality])
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tree.KVOption{Key: tree.Name(locality), Value: tree.NewDString(redactedURI)})
+	}
+	return opts, nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/httputil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/errors"
+)
+
+// latestWebhookURL is the HTTPS endpoint that is notified of every backup that
+// updates the LATEST file of its collection, so that external catalogs can
+// learn of new backups without polling the collection.
+var latestWebhookURL = settings.RegisterValidatedStringSetting(
+	settings.TenantWritable,
+	"bulkio.backup.latest_webhook.url",
+	"if set, an HTTPS endpoint that is sent a POST describing every backup that "+
+		"updates the LATEST file of its collection",
+	"",
+	func(_ *settings.Values, s string) error {
+		if s == "" {
+			return nil
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" {
+			return errors.Newf("webhook URL must use https, got %q", u.Scheme)
+		}
+		return nil
+	},
+).WithPublic()
+
+// latestWebhookMaxRetries is how many times a failed notification is retried.
+var latestWebhookMaxRetries = settings.RegisterIntSetting(
+	settings.TenantWritable,
+	"bulkio.backup.latest_webhook.max_retries",
+	"the number of times a failed notification of bulkio.backup.latest_webhook.url is retried",
+	5,
+	settings.NonNegativeInt,
+).WithPublic()
+
+// latestWebhookTimeout is how long a single notification attempt may take.
+var latestWebhookTimeout = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.backup.latest_webhook.timeout",
+	"the amount of time a single notification of bulkio.backup.latest_webhook.url may take",
+	10*time.Second,
+	settings.PositiveDuration,
+).WithPublic()
+
+// latestWebhookFailurePolicy is what a backup does when it cannot notify the
+// webhook.
+type latestWebhookFailurePolicy int64
+
+const (
+	// latestWebhookLog logs the failure, and the backup succeeds.
+	latestWebhookLog latestWebhookFailurePolicy = iota
+	// latestWebhookFail fails the backup. The backup itself is complete and
+	// restorable: the failure only surfaces that the external catalog missed
+	// it.
+	latestWebhookFail
+)
+
+var latestWebhookOnFailure = settings.RegisterEnumSetting(
+	settings.TenantWritable,
+	"bulkio.backup.latest_webhook.on_failure",
+	"what a backup does when it cannot notify bulkio.backup.latest_webhook.url: "+
+		"log the failure, or fail the backup job (the backup remains restorable)",
+	"log",
+	map[int64]string{
+		int64(latestWebhookLog):  "log",
+		int64(latestWebhookFail): "fail",
+	},
+).WithPublic()
+
+// latestWebhookFile is a data file of a backup in a latestWebhookPayload.
+type latestWebhookFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	CRC32C uint32 `json:"crc32c"`
+}
+
+// latestWebhookPayload is the JSON body posted to the webhook.
+type latestWebhookPayload struct {
+	BackupID     string              `json:"backup_id"`
+	JobID        jobspb.JobID        `json:"job_id"`
+	Subdir       string              `json:"subdir"`
+	EndTime      string              `json:"end_time"`
+	DataSize     int64               `json:"data_size"`
+	PhysicalSize int64               `json:"physical_size"`
+	Rows         int64               `json:"rows"`
+	Files        []latestWebhookFile `json:"files"`
+}
+
+func makeLatestWebhookPayload(
+	jobID jobspb.JobID, subdir string, manifest *backuppb.BackupManifest,
+) latestWebhookPayload {
+	p := latestWebhookPayload{
+		BackupID:     manifest.ID.String(),
+		JobID:        jobID,
+		Subdir:       subdir,
+		EndTime:      manifest.EndTime.AsOfSystemTime(),
+		DataSize:     manifest.EntryCounts.DataSize,
+		PhysicalSize: manifest.PhysicalSize,
+		Rows:         manifest.EntryCounts.Rows,
+		Files:        make([]latestWebhookFile, 0, len(manifest.Files)),
+	}
+	seen := make(map[string]struct{}, len(manifest.Files))
+	for _, f := range manifest.Files {
+		// A data file can hold several of the manifest's files.
+		if _, ok := seen[f.Path]; ok {
+			continue
+		}
+		seen[f.Path] = struct{}{}
+		p.Files = append(p.Files, latestWebhookFile{
+			Path: f.Path, Size: f.Trailer.Size, CRC32C: f.Trailer.CRC32C,
+		})
+	}
+	return p
+}
+
+// maybeNotifyLatestWebhook posts the backup described by manifest, which was
+// just recorded in the LATEST file of its collection, to
+// bulkio.backup.latest_webhook.url if it is set. Failed attempts are retried
+// with backoff; once retries are exhausted, the error is returned only if the
+// failure policy is to fail the backup.
+func maybeNotifyLatestWebhook(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	jobID jobspb.JobID,
+	subdir string,
+	manifest *backuppb.BackupManifest,
+) error {
+	sv := &execCfg.Settings.SV
+	webhookURL := latestWebhookURL.Get(sv)
+	if webhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(makeLatestWebhookPayload(jobID, subdir, manifest))
+	if err != nil {
+		return err
+	}
+
+	client := httputil.NewClientWithTimeout(latestWebhookTimeout.Get(sv))
+	if knobs := execCfg.BackupRestoreTestingKnobs; knobs != nil && knobs.LatestWebhookClient != nil {
+		client = &httputil.Client{Client: knobs.LatestWebhookClient}
+	}
+	opts := retry.Options{InitialBackoff: time.Second, MaxBackoff: 30 * time.Second}
+	maxRetries := int(latestWebhookMaxRetries.Get(sv))
+	// Retry.Next does not bound the attempts when MaxRetries is 0, so they are
+	// counted here instead.
+	for attempt, r := 0, retry.StartWithCtx(ctx, opts); r.Next(); attempt++ {
+		if err = postLatestWebhook(ctx, client, webhookURL, body); err == nil {
+			return nil
+		}
+		log.Warningf(ctx, "failed to notify backup webhook (attempt %d): %v", attempt+1, err)
+		if attempt >= maxRetries {
+			break
+		}
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	err = errors.Wrap(err, "notifying bulkio.backup.latest_webhook.url")
+	if latestWebhookFailurePolicy(latestWebhookOnFailure.Get(sv)) == latestWebhookFail {
+		return err
+	}
+	log.Errorf(ctx, "%v", err)
+	return nil
+}
+
+func postLatestWebhook(
+	ctx context.Context, client *httputil.Client, webhookURL string, body []byte,
+) error {
+	resp, err := client.Post(ctx, webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the attempt is not reported successful before the
+	// endpoint finished handling it.
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Newf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
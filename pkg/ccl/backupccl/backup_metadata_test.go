@@ -11,14 +11,17 @@ package backupccl_test
 import (
 	"context"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/blobs"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -77,6 +80,64 @@ func TestMetadataSST(t *testing.T) {
 	checkMetadata(ctx, t, tc, userfile2)
 }
 
+// TestBackupGenerations checks that the full backups into a collection are
+// assigned increasing generations, which the incremental backups of their
+// chains inherit along with their position in the chain.
+func TestBackupGenerations(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	tc, sqlDB, _, cleanupFn := backuputils.BackupRestoreTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	const collectionURI = "nodelocal://1/generations"
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	collection, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, collectionURI, username.RootUserName())
+	require.NoError(t, err)
+	defer collection.Close()
+
+	// layers returns the generation and layer recorded by each backup of the
+	// chain in subdir, in the order they were taken.
+	layers := func(subdir string) [][2]int64 {
+		full, err := testingReadBackupManifest(ctx, collection, subdir+"/"+backupbase.BackupManifestName)
+		require.NoError(t, err)
+		res := [][2]int64{{full.Generation, int64(full.Layer)}}
+
+		incrementals := backupbase.DefaultIncrementalsSubdir + subdir
+		var manifests []string
+		require.NoError(t, collection.List(ctx, incrementals, "", func(p string) error {
+			if strings.HasSuffix(p, "/"+backupbase.BackupManifestName) {
+				manifests = append(manifests, p)
+			}
+			return nil
+		}))
+		sort.Strings(manifests)
+		for _, p := range manifests {
+			m, err := testingReadBackupManifest(ctx, collection, incrementals+p)
+			require.NoError(t, err)
+			res = append(res, [2]int64{m.Generation, int64(m.Layer)})
+		}
+		return res
+	}
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, collectionURI)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, collectionURI)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, collectionURI)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, collectionURI)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, collectionURI)
+
+	subdirs := sqlDB.QueryStr(t, `SHOW BACKUPS IN $1`, collectionURI)
+	require.Len(t, subdirs, 2)
+	require.Equal(t, [][2]int64{{1, 0}, {1, 1}, {1, 2}}, layers(subdirs[0][0]))
+	require.Equal(t, [][2]int64{{2, 0}, {2, 1}}, layers(subdirs[1][0]))
+
+	generation, err := backupdest.ReadCollectionGeneration(ctx, collection)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), generation)
+}
+
 func checkMetadata(
 	ctx context.Context, t *testing.T, tc *testcluster.TestCluster, backupLoc string,
 ) {
@@ -125,6 +186,7 @@ func checkMetadata(
 		checkIntroducedSpans(ctx, t, m, bm)
 	}
 	checkFiles(ctx, t, m, bm)
+	checkLayerFileIter(ctx, t, store, m, bm, &kmsEnv)
 	checkTenants(ctx, t, m, bm)
 	checkStats(ctx, t, store, m, bm, &kmsEnv)
 }
@@ -205,6 +267,59 @@ func checkFiles(
 	require.Equal(t, m.Files, metaFiles)
 }
 
+// checkLayerFileIter verifies that a LayerFileIterator yields the manifest's
+// files when the file list is in memory and when it has to be streamed from
+// the metadata SST or from the manifest, and that it can be abandoned part way
+// through.
+func checkLayerFileIter(
+	ctx context.Context,
+	t *testing.T,
+	store cloud.ExternalStorage,
+	m *backuppb.BackupManifest,
+	bm *backupinfo.BackupMetadata,
+	kmsEnv cloud.KMSEnv,
+) {
+	collectFrom := func(it backupinfo.LayerFileIterator, limit int) []backuppb.BackupManifest_File {
+		defer it.Close()
+
+		var files []backuppb.BackupManifest_File
+		var file backuppb.BackupManifest_File
+		for len(files) < limit && it.Next(&file) {
+			files = append(files, file)
+		}
+		require.NoError(t, it.Err())
+		return files
+	}
+	collect := func(manifest *backuppb.BackupManifest, limit int) []backuppb.BackupManifest_File {
+		it, err := backupinfo.NewLayerFileIter(ctx, store, manifest, nil /* encryption */, kmsEnv)
+		require.NoError(t, err)
+		return collectFrom(it, limit)
+	}
+	collectManifest := func(limit int) []backuppb.BackupManifest_File {
+		it, err := backupread.NewManifestFileIter(ctx, store, nil /* encryption */, kmsEnv)
+		require.NoError(t, err)
+		return collectFrom(it, limit)
+	}
+
+	// bm.BackupManifest has had its file list stripped, so the files are read
+	// from the metadata SST.
+	require.Equal(t, m.Files, collect(m, len(m.Files)))
+	require.Equal(t, m.Files, collect(&bm.BackupManifest, len(m.Files)))
+	require.Equal(t, m.Files, collectManifest(len(m.Files)))
+	if len(m.Files) > 1 {
+		require.Equal(t, m.Files[:1], collect(&bm.BackupManifest, 1))
+		require.Equal(t, m.Files[:1], collectManifest(1))
+	}
+
+	// A manifest read without its file list has the rest of its fields.
+	elided, _, err := backupinfo.ReadBackupManifestFromStore(ctx, nil /* mem */, store,
+		nil /* encryption */, kmsEnv, true /* elideFiles */)
+	require.NoError(t, err)
+	require.Empty(t, elided.Files)
+	require.Equal(t, m.Spans, elided.Spans)
+	require.Equal(t, m.EndTime, elided.EndTime)
+}
+
 func checkSpans(
 	ctx context.Context, t *testing.T, m *backuppb.BackupManifest, bm *backupinfo.BackupMetadata,
 ) {
@@ -11,19 +11,24 @@ package backupccl
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/build"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupresolver"
 	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
 	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/featureflag"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
@@ -49,6 +54,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/syntheticprivilege"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/interval"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
@@ -65,6 +71,30 @@ const (
 	backupOptDebugMetadataSST = "debug_dump_metadata_sst"
 	backupOptEncDir           = "encryption_info_dir"
 	backupOptCheckFiles       = "check_files"
+	backupOptCheckKMS         = "check_kms"
+	backupOptUploadParallel   = "upload_parallelism"
+	backupOptPartSize         = "part_size"
+	backupOptUploadBufferMem  = "upload_buffer_memory"
+	backupOptMetadataURI      = "metadata_uri"
+	backupOptPriority         = "priority"
+	backupOptFingerprint      = "fingerprint"
+	backupOptCoordinator      = "coordinator"
+	backupOptCoordinated      = "coordinated_clusters"
+
+	// backupPriorityBackground, the default priority of backups, admits their
+	// work as elastic work, which yields to foreground traffic when the
+	// cluster is under load.
+	backupPriorityBackground = "background"
+	// backupPriorityHigh admits the work of backups alongside foreground
+	// traffic.
+	backupPriorityHigh = "high"
+
+	// maxUploadParallelism bounds the upload_parallelism option, since every
+	// part being uploaded at once is buffered in memory.
+	maxUploadParallelism = 64
+	// maxAppTokenLength bounds the app_token option, which is meant to hold an
+	// identifier rather than data, and is copied into every manifest.
+	maxAppTokenLength = 1 << 10
 	// backupPartitionDescriptorPrefix is the file name prefix for serialized
 	// BackupPartitionDescriptor protos.
 	backupPartitionDescriptorPrefix = "BACKUP_PART"
@@ -73,6 +103,21 @@ const (
 		"in favour of a fine-grained privilege model explained here <link>. In a future release, to run"
 )
 
+// resolveBackupPriority validates the value of the priority option, returning
+// whether the backup should run at high priority.
+func resolveBackupPriority(priority string) (highPriority bool, err error) {
+	switch priority {
+	case "", backupPriorityBackground:
+		return false, nil
+	case backupPriorityHigh:
+		return true, nil
+	default:
+		return false, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s must be one of '%s' or '%s', got %q",
+			backupOptPriority, backupPriorityBackground, backupPriorityHigh, priority)
+	}
+}
+
 type tableAndIndex struct {
 	tableID descpb.ID
 	indexID descpb.IndexID
@@ -189,6 +234,20 @@ func resolveOptionsForBackupJobDescription(
 	newOpts := tree.BackupOptions{
 		CaptureRevisionHistory: opts.CaptureRevisionHistory,
 		Detached:               opts.Detached,
+		Statistics:             opts.Statistics,
+		Comments:               opts.Comments,
+		ZoneConfigs:            opts.ZoneConfigs,
+		Jobs:                   opts.Jobs,
+		DecryptQuorum:          opts.DecryptQuorum,
+		SubdirFormat:           opts.SubdirFormat,
+		PerTableFiles:          opts.PerTableFiles,
+		SchemaChangePolicy:     opts.SchemaChangePolicy,
+		RelyOnEncryptionAtRest: opts.RelyOnEncryptionAtRest,
+		Priority:               opts.Priority,
+		AllowMissingLocalities: opts.AllowMissingLocalities,
+		Compression:            opts.Compression,
+		SkipIfUnchanged:        opts.SkipIfUnchanged,
+		AppToken:               opts.AppToken,
 	}
 
 	if opts.EncryptionPassphrase != nil {
@@ -226,6 +285,7 @@ func GetRedactedBackupNode(
 		Targets:        backup.Targets,
 		Nested:         backup.Nested,
 		AppendToLatest: backup.AppendToLatest,
+		Failover:       backup.Failover,
 	}
 
 	// We set Subdir to the directory resolved during BACKUP planning.
@@ -279,14 +339,35 @@ func backupJobDescription(
 	to []string,
 	incrementalFrom []string,
 	kmsURIs []string,
+	kmsURIsByLocality map[string]string,
 	resolvedSubdir string,
 	incrementalStorage []string,
+	metadataURI string,
+	coordinatorURI string,
 ) (string, error) {
 	b, err := GetRedactedBackupNode(backup, to, incrementalFrom, kmsURIs,
 		resolvedSubdir, incrementalStorage, true /* hasBeenPlanned */)
 	if err != nil {
 		return "", err
 	}
+	b.Options.KMSURIByLocality, err = redactKMSURIsByLocality(kmsURIsByLocality)
+	if err != nil {
+		return "", err
+	}
+	if metadataURI != "" {
+		sanitizedURI, err := cloud.SanitizeExternalStorageURI(metadataURI, nil /* extraParams */)
+		if err != nil {
+			return "", err
+		}
+		b.Options.MetadataURI = tree.NewDString(sanitizedURI)
+	}
+	if coordinatorURI != "" {
+		sanitizedURI, err := cloud.SanitizeExternalStorageURI(coordinatorURI, nil /* extraParams */)
+		if err != nil {
+			return "", err
+		}
+		b.Options.Coordinator = tree.NewDString(sanitizedURI)
+	}
 
 	ann := p.ExtendedEvalContext().Annotations
 	return tree.AsStringWithFQNames(b, ann), nil
@@ -378,7 +459,8 @@ func checkPrivilegesForBackup(
 	{
 		// Cluster and tenant backups require the `BACKUP` system privilege.
 		requiresBackupSystemPrivilege := backupStmt.Coverage() == tree.AllDescriptors ||
-			(backupStmt.Targets != nil && backupStmt.Targets.TenantID.IsSet())
+			(backupStmt.Targets != nil &&
+				(backupStmt.Targets.TenantID.IsSet() || backupStmt.Targets.AllTenants))
 
 		var hasBackupSystemPrivilege bool
 		if p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.SystemPrivilegesTable) {
@@ -504,6 +586,10 @@ func backupPlanHook(
 		return nil, nil, nil, false, err
 	}
 
+	if backupStmt.Targets != nil && backupStmt.Targets.AllTenants {
+		return backupAllTenantsPlanHook(ctx, backupStmt, p)
+	}
+
 	// Deprecation notice for `BACKUP TO` syntax. Remove this once the syntax is
 	// deleted in 22.2.
 	if !backupStmt.Nested {
@@ -538,10 +624,45 @@ func backupPlanHook(
 		return nil, nil, nil, false, err
 	}
 
+	var uploadParallelismFn func() (int64, error)
+	if backupStmt.Options.UploadParallelism != nil {
+		uploadParallelismFn, err = p.TypeAsInt(ctx, backupStmt.Options.UploadParallelism, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	var partSizeFn func() (string, error)
+	if backupStmt.Options.PartSize != nil {
+		partSizeFn, err = p.TypeAsString(ctx, backupStmt.Options.PartSize, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	var uploadBufferMemoryFn func() (string, error)
+	if backupStmt.Options.UploadBufferMemory != nil {
+		uploadBufferMemoryFn, err = p.TypeAsString(ctx, backupStmt.Options.UploadBufferMemory, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	var tableFilter *tree.BackupTableFilter
+	tableFilterFn := func() ([]string, error) { return nil, nil }
+	if backupStmt.Targets != nil && backupStmt.Targets.TableFilter != nil {
+		tableFilter = backupStmt.Targets.TableFilter
+		tableFilterFn, err = p.TypeAsStringArray(ctx, tableFilter.Patterns, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	detached := false
 	if backupStmt.Options.Detached == tree.DBoolTrue {
 		detached = true
 	}
+	if backupStmt.ExplainOnly && detached {
+		return nil, nil, nil, false, errors.New("EXPLAIN BACKUP does not run a job and cannot be DETACHED")
+	}
 	revisionHistoryFn := func() (bool, error) { return false, nil } // Defaults to false.
 	if backupStmt.Options.CaptureRevisionHistory != nil {
 		revisionHistoryFn, err = p.TypeAsBool(ctx, backupStmt.Options.CaptureRevisionHistory, "BACKUP")
@@ -550,6 +671,133 @@ func backupPlanHook(
 		}
 	}
 
+	statisticsFn := func() (bool, error) { return true, nil } // Defaults to true.
+	if backupStmt.Options.Statistics != nil {
+		statisticsFn, err = p.TypeAsBool(ctx, backupStmt.Options.Statistics, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	commentsFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.Comments != nil {
+		commentsFn, err = p.TypeAsBool(ctx, backupStmt.Options.Comments, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	zoneConfigsFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.ZoneConfigs != nil {
+		zoneConfigsFn, err = p.TypeAsBool(ctx, backupStmt.Options.ZoneConfigs, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	jobsFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.Jobs != nil {
+		jobsFn, err = p.TypeAsBool(ctx, backupStmt.Options.Jobs, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	perTableFilesFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.PerTableFiles != nil {
+		perTableFilesFn, err = p.TypeAsBool(ctx, backupStmt.Options.PerTableFiles, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	allowMissingLocalitiesFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.AllowMissingLocalities != nil {
+		allowMissingLocalitiesFn, err = p.TypeAsBool(ctx, backupStmt.Options.AllowMissingLocalities, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	skipIfUnchangedFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.SkipIfUnchanged != nil {
+		skipIfUnchangedFn, err = p.TypeAsBool(ctx, backupStmt.Options.SkipIfUnchanged, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	relyOnEncryptionAtRestFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.RelyOnEncryptionAtRest != nil {
+		relyOnEncryptionAtRestFn, err = p.TypeAsBool(ctx, backupStmt.Options.RelyOnEncryptionAtRest, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	schemaChangePolicyFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.SchemaChangePolicy != nil {
+		schemaChangePolicyFn, err = p.TypeAsString(ctx, backupStmt.Options.SchemaChangePolicy, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	consolidateChangesFn := func() (bool, error) { return false, nil }
+	if backupStmt.Options.ConsolidateChanges != nil {
+		consolidateChangesFn, err = p.TypeAsBool(ctx, backupStmt.Options.ConsolidateChanges, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	executionLocalityFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.ExecutionLocality != nil {
+		executionLocalityFn, err = p.TypeAsString(ctx, backupStmt.Options.ExecutionLocality, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	priorityFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.Priority != nil {
+		priorityFn, err = p.TypeAsString(ctx, backupStmt.Options.Priority, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	compressionFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.Compression != nil {
+		compressionFn, err = p.TypeAsString(ctx, backupStmt.Options.Compression, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	appTokenFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.AppToken != nil {
+		appTokenFn, err = p.TypeAsString(ctx, backupStmt.Options.AppToken, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	metadataURIFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.MetadataURI != nil {
+		metadataURIFn, err = p.TypeAsString(ctx, backupStmt.Options.MetadataURI, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	coordinatorFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.Coordinator != nil {
+		coordinatorFn, err = p.TypeAsString(ctx, backupStmt.Options.Coordinator, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	coordinatedClustersFn := func() (int64, error) { return 0, nil }
+	if backupStmt.Options.CoordinatedClusters != nil {
+		coordinatedClustersFn, err = p.TypeAsInt(ctx, backupStmt.Options.CoordinatedClusters, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	subdirFormatFn := func() (string, error) { return "", nil }
+	if backupStmt.Options.SubdirFormat != nil {
+		subdirFormatFn, err = p.TypeAsString(ctx, backupStmt.Options.SubdirFormat, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	encryptionParams := jobspb.BackupEncryptionOptions{Mode: jobspb.EncryptionMode_None}
 
 	var pwFn func() (string, error)
@@ -562,6 +810,23 @@ func backupPlanHook(
 		encryptionParams.Mode = jobspb.EncryptionMode_Passphrase
 	}
 
+	var decryptQuorumFn func() (int64, error)
+	if backupStmt.Options.DecryptQuorum != nil {
+		if backupStmt.Options.EncryptionKMSURI == nil {
+			return nil, nil, nil, false, errors.New("decrypt_quorum requires the kms option")
+		}
+		// Older versions expect the data key of a backup encrypted with a KMS to
+		// be encrypted whole, and cannot resume a backup that splits it.
+		if !p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.V23_1BackupDecryptQuorum) {
+			return nil, nil, nil, false, errors.New(
+				"cannot use the decrypt_quorum option until the cluster has fully upgraded to 23.1")
+		}
+		decryptQuorumFn, err = p.TypeAsInt(ctx, backupStmt.Options.DecryptQuorum, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	var kmsFn func() ([]string, error)
 	if backupStmt.Options.EncryptionKMSURI != nil {
 		if encryptionParams.Mode != jobspb.EncryptionMode_None {
@@ -583,12 +848,23 @@ func backupPlanHook(
 		encryptionParams.Mode = jobspb.EncryptionMode_KMS
 	}
 
+	var kmsByLocalityFn func() (map[string]string, error)
+	if backupStmt.Options.KMSURIByLocality != nil {
+		if backupStmt.Options.EncryptionKMSURI == nil {
+			return nil, nil, nil, false, errors.Newf("%s requires the kms option", backupOptKMSByLocality)
+		}
+		kmsByLocalityFn, err = kmsURIsByLocalityFn(ctx, p, backupStmt.Options.KMSURIByLocality, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
 		// TODO(dan): Move this span into sql.
 		ctx, span := tracing.ChildSpan(ctx, stmt.StatementTag())
 		defer span.Finish()
 
-		if !(p.ExtendedEvalContext().TxnIsSingleStmt || detached) {
+		if !(p.ExtendedEvalContext().TxnIsSingleStmt || detached || backupStmt.ExplainOnly) {
 			return errors.Errorf("BACKUP cannot be used inside a multi-statement transaction without DETACHED option")
 		}
 
@@ -601,11 +877,25 @@ func backupPlanHook(
 		if err != nil {
 			return err
 		}
-		if len(to) > 1 {
+		if backupStmt.Failover {
+			if err := requireEnterprise(p.ExecCfg(), "failover destinations"); err != nil {
+				return err
+			}
+		} else if len(to) > 1 {
 			if err := requireEnterprise(p.ExecCfg(), "partitioned destinations"); err != nil {
 				return err
 			}
 		}
+		// A backup with a failover destination is written as a locality aware
+		// backup, with the secondary collection as the destination of a locality
+		// that no node has, to which the backup processors fail over instead.
+		destinationTo := to
+		if backupStmt.Failover {
+			destinationTo, err = backupdest.FailoverDestinations(to[0], to[1])
+			if err != nil {
+				return err
+			}
+		}
 
 		incrementalFrom, err := incrementalFromFn()
 		if err != nil {
@@ -619,10 +909,55 @@ func backupPlanHook(
 		if !backupStmt.Nested && len(incrementalStorage) > 0 {
 			return errors.New("incremental_location option not supported with `BACKUP TO` syntax")
 		}
+		if backupStmt.Failover && len(incrementalStorage) > 0 {
+			return errors.New("incremental_location option not supported with FAILOVER destinations")
+		}
 		if len(incrementalStorage) > 0 && (len(incrementalStorage) != len(to)) {
 			return errors.New("the incremental_location option must contain the same number of locality" +
 				" aware URIs as the full backup destination")
 		}
+		metadataURI, err := metadataURIFn()
+		if err != nil {
+			return err
+		}
+		if metadataURI != "" {
+			if err := checkMetadataURIOptions(backupStmt, len(to), len(incrementalStorage)); err != nil {
+				return err
+			}
+			if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, []string{metadataURI}); err != nil {
+				return err
+			}
+		}
+		coordinatorURI, err := coordinatorFn()
+		if err != nil {
+			return err
+		}
+		coordinatedClusters, err := coordinatedClustersFn()
+		if err != nil {
+			return err
+		}
+		if coordinatorURI != "" || coordinatedClusters != 0 {
+			if err := checkCoordinatorOptions(backupStmt, coordinatorURI, coordinatedClusters); err != nil {
+				return err
+			}
+			if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, []string{coordinatorURI}); err != nil {
+				return err
+			}
+			if err := requireEnterprise(p.ExecCfg(), "coordinated backups"); err != nil {
+				return err
+			}
+		}
+		writeURIs := append(append([]string(nil), to...), incrementalStorage...)
+		if metadataURI != "" {
+			writeURIs = append(writeURIs, metadataURI)
+		}
+		if coordinatorURI != "" {
+			writeURIs = append(writeURIs, coordinatorURI)
+		}
+		if err := backupdest.CheckConnectionCapability(ctx, p.ExecCfg(), "BACKUP",
+			connectionpb.CapabilityBackupWrite, writeURIs...); err != nil {
+			return err
+		}
 
 		var asOfInterval int64
 		endTime := p.ExecCfg().Clock.Now()
@@ -650,6 +985,24 @@ func backupPlanHook(
 			if err != nil {
 				return err
 			}
+			if decryptQuorumFn != nil {
+				quorum, err := decryptQuorumFn()
+				if err != nil {
+					return err
+				}
+				if quorum < 1 || quorum > int64(len(encryptionParams.RawKmsUris)) {
+					return pgerror.Newf(pgcode.InvalidParameterValue,
+						"decrypt_quorum must be between 1 and the number of KMS URIs, %d",
+						len(encryptionParams.RawKmsUris))
+				}
+				encryptionParams.DecryptQuorum = int32(quorum)
+			}
+			if kmsByLocalityFn != nil {
+				encryptionParams.RawKMSURIsByLocalityKV, err = kmsByLocalityFn()
+				if err != nil {
+					return err
+				}
+			}
 			if err := requireEnterprise(p.ExecCfg(), "encryption"); err != nil {
 				return err
 			}
@@ -665,26 +1018,260 @@ func backupPlanHook(
 			}
 		}
 
+		consolidateChanges, err := consolidateChangesFn()
+		if err != nil {
+			return err
+		}
+		if consolidateChanges {
+			if err := checkConsolidateChangesOptions(backupStmt, subdir, len(to), revisionHistory); err != nil {
+				return err
+			}
+			if metadataURI != "" {
+				return errors.New("consolidate_changes cannot be used with metadata_uri")
+			}
+			if coordinatorURI != "" {
+				return errors.Errorf("consolidate_changes cannot be used with %q", backupOptCoordinator)
+			}
+			if err := requireEnterprise(p.ExecCfg(), "consolidate_changes"); err != nil {
+				return err
+			}
+		}
+
+		skipIfUnchanged, err := skipIfUnchangedFn()
+		if err != nil {
+			return err
+		}
+		if skipIfUnchanged {
+			// The changes consolidated from a changefeed and the end time agreed
+			// with other clusters both require the layer to be written.
+			if consolidateChanges {
+				return errors.New("skip_if_unchanged cannot be used with consolidate_changes")
+			}
+			if coordinatorURI != "" {
+				return errors.Errorf("skip_if_unchanged cannot be used with %q", backupOptCoordinator)
+			}
+		}
+
+		appToken, err := appTokenFn()
+		if err != nil {
+			return err
+		}
+		if len(appToken) > maxAppTokenLength {
+			return pgerror.Newf(pgcode.InvalidParameterValue,
+				"app_token must be at most %d bytes", maxAppTokenLength)
+		}
+		if appToken != "" && skipIfUnchanged {
+			// A skipped backup writes no manifest to record the token in.
+			return errors.New("app_token cannot be used with skip_if_unchanged")
+		}
+
+		uploadOptions, err := evalUploadOptions(uploadParallelismFn, partSizeFn, uploadBufferMemoryFn)
+		if err != nil {
+			return err
+		}
+
+		statistics, err := statisticsFn()
+		if err != nil {
+			return err
+		}
+		comments, err := commentsFn()
+		if err != nil {
+			return err
+		}
+		zoneConfigs, err := zoneConfigsFn()
+		if err != nil {
+			return err
+		}
+		if backupStmt.Coverage() == tree.AllDescriptors {
+			// Cluster backups include system.comments and system.zones in full.
+			if (backupStmt.Options.Comments != nil && !comments) ||
+				(backupStmt.Options.ZoneConfigs != nil && !zoneConfigs) {
+				return errors.New("cluster backups always include comments and zone configurations; " +
+					"use skip_comments or skip_zone_configs when restoring them instead")
+			}
+			comments, zoneConfigs = false, false
+		}
+		includeJobs, err := jobsFn()
+		if err != nil {
+			return err
+		}
+		if includeJobs && backupStmt.Coverage() != tree.AllDescriptors {
+			return errors.New("the jobs option is only supported for cluster backups")
+		}
+
+		perTableFiles, err := perTableFilesFn()
+		if err != nil {
+			return err
+		}
+		if perTableFiles && backupStmt.Targets != nil && backupStmt.Targets.TenantID.IsSet() {
+			return errors.New("per_table_files cannot be used when backing up a tenant")
+		}
+
+		allowMissingLocalities, err := allowMissingLocalitiesFn()
+		if err != nil {
+			return err
+		}
+		if allowMissingLocalities && (len(to) < 2 || backupStmt.Failover) {
+			return errors.New("allow_missing_localities can only be used with locality-aware destinations")
+		}
+		if len(encryptionParams.RawKMSURIsByLocalityKV) > 0 {
+			if len(to) < 2 || backupStmt.Failover {
+				return errors.Newf("%s can only be used with locality-aware destinations", backupOptKMSByLocality)
+			}
+			// The files of a locality written to the default destination instead
+			// would be encrypted with a key that is not the default one.
+			if allowMissingLocalities {
+				return errors.Newf("%s cannot be used with allow_missing_localities", backupOptKMSByLocality)
+			}
+		}
+
+		schemaChangePolicy, err := schemaChangePolicyFn()
+		if err != nil {
+			return err
+		}
+		schemaChangePolicy, err = resolveSchemaChangePolicy(schemaChangePolicy)
+		if err != nil {
+			return err
+		}
+		if schemaChangePolicy == backupSchemaChangePolicyWait {
+			if backupStmt.AsOf.Expr != nil {
+				return errors.Newf("schema_change_policy = '%s' cannot be used with AS OF SYSTEM TIME",
+					backupSchemaChangePolicyWait)
+			}
+			if !p.ExtendedEvalContext().TxnIsSingleStmt {
+				return errors.Newf("schema_change_policy = '%s' cannot be used inside a multi-statement transaction",
+					backupSchemaChangePolicyWait)
+			}
+		}
+
+		executionLocality, err := executionLocalityFn()
+		if err != nil {
+			return err
+		}
+		executionLocalityFilter, err := parseExecutionLocality(executionLocality)
+		if err != nil {
+			return err
+		}
+
+		priority, err := priorityFn()
+		if err != nil {
+			return err
+		}
+		highPriority, err := resolveBackupPriority(priority)
+		if err != nil {
+			return err
+		}
+
+		compression, err := compressionFn()
+		if err != nil {
+			return err
+		}
+		compression, err = resolveBackupCompression(ctx, p.ExecCfg().Settings, compression,
+			backupStmt.Options.Compression != nil, to[0], p.User())
+		if err != nil {
+			return err
+		}
+
+		subdirFormat, err := subdirFormatFn()
+		if err != nil {
+			return err
+		}
+		if backupStmt.Options.SubdirFormat != nil {
+			if !backupStmt.Nested || backupStmt.AppendToLatest || subdir != "" {
+				return errors.New("subdir_format can only be used to name a new full backup " +
+					"in a collection; use `BACKUP INTO <collectionURI>` without a subdirectory")
+			}
+			if err := backupdest.ValidateSubdirFormat(subdirFormat); err != nil {
+				return err
+			}
+		}
+
+		tablePatterns, err := tableFilterFn()
+		if err != nil {
+			return err
+		}
+		if tableFilter != nil && revisionHistory {
+			// Revision history backups capture the history of every table in a
+			// complete database, which would defeat the filter.
+			return errors.New("EXCLUDE TABLES and INCLUDE TABLES cannot be used with revision_history")
+		}
+
+		var rowFilter tree.Expr
+		if backupStmt.Targets != nil && backupStmt.Targets.RowFilter != nil {
+			rowFilter = backupStmt.Targets.RowFilter
+			if revisionHistory {
+				// The history of the rows that moved out of the filtered spans would
+				// be lost to a restore as of an earlier time.
+				return errors.New("a row filter cannot be used with revision_history")
+			}
+		}
+
 		var targetDescs []catalog.Descriptor
 		var completeDBs []descpb.ID
 		var requestedDBs []catalog.DatabaseDescriptor
 		var descsByTablePattern map[tree.TablePattern]catalog.Descriptor
 
-		switch backupStmt.Coverage() {
-		case tree.RequestedDescriptors:
-			var err error
-			targetDescs, completeDBs, requestedDBs, descsByTablePattern, err = backupresolver.ResolveTargetsToDescriptors(ctx, p, endTime, backupStmt.Targets)
-			if err != nil {
-				return errors.Wrap(err, "failed to resolve targets specified in the BACKUP stmt")
+		resolveTargets := func() error {
+			switch backupStmt.Coverage() {
+			case tree.RequestedDescriptors:
+				var err error
+				targetDescs, completeDBs, requestedDBs, descsByTablePattern, err = backupresolver.ResolveTargetsToDescriptors(ctx, p, endTime, backupStmt.Targets)
+				if err != nil {
+					return errors.Wrap(err, "failed to resolve targets specified in the BACKUP stmt")
+				}
+				if tableFilter != nil {
+					targetDescs, err = backupresolver.FilterDatabaseTables(
+						targetDescs, completeDBs, tablePatterns, tableFilter.Exclude)
+					if err != nil {
+						return err
+					}
+				}
+			case tree.AllDescriptors:
+				var err error
+				targetDescs, completeDBs, err = fullClusterTargetsBackup(ctx, p.ExecCfg(), endTime)
+				if err != nil {
+					return err
+				}
+			default:
+				return errors.AssertionFailedf("unexpected descriptor coverage %v", backupStmt.Coverage())
 			}
-		case tree.AllDescriptors:
-			var err error
-			targetDescs, completeDBs, err = fullClusterTargetsBackup(ctx, p.ExecCfg(), endTime)
-			if err != nil {
-				return err
+			return nil
+		}
+		if err := resolveTargets(); err != nil {
+			return err
+		}
+
+		if schemaChangePolicy != backupSchemaChangePolicyBackupAnyway {
+			// Waiting resolves the targets again as of after the schema changes
+			// finished, and new schema changes may have started in the meantime,
+			// so we keep waiting until there are none or we time out.
+			waitCtx, cancel := context.WithTimeout(ctx, schemaChangeWaitTimeout.Get(&p.ExecCfg().Settings.SV))
+			defer cancel()
+			var waitedFor []jobspb.JobID
+			for {
+				tables, jobIDs := schemaChangesInProgress(targetDescs)
+				if len(jobIDs) == 0 {
+					break
+				}
+				if schemaChangePolicy == backupSchemaChangePolicyError {
+					return errSchemaChangesInProgress(tables, jobIDs)
+				}
+				// Waiting stops at paused jobs, which would otherwise have us
+				// wait for them again and again.
+				if reflect.DeepEqual(jobIDs, waitedFor) {
+					return pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+						"the schema changes in progress on tables %s (jobs %v) are paused",
+						strings.Join(tables, ", "), jobIDs)
+				}
+				if err := waitForSchemaChanges(waitCtx, p, tables, jobIDs); err != nil {
+					return err
+				}
+				waitedFor = jobIDs
+				endTime = p.ExecCfg().Clock.Now()
+				if err := resolveTargets(); err != nil {
+					return err
+				}
 			}
-		default:
-			return errors.AssertionFailedf("unexpected descriptor coverage %v", backupStmt.Coverage())
 		}
 
 		// Check BACKUP privileges.
@@ -693,8 +1280,35 @@ func backupPlanHook(
 			return err
 		}
 
+		var rowFilterSpansToBackup []roachpb.Span
+		if rowFilter != nil {
+			rowFilterSpansToBackup, err = rowFilterSpans(ctx, p, targetDescs, rowFilter)
+			if err != nil {
+				return err
+			}
+		}
+
+		relyOnEncryptionAtRest, err := relyOnEncryptionAtRestFn()
+		if err != nil {
+			return err
+		}
+		var encryptionAtRestKeyIDs []string
+		if relyOnEncryptionAtRest {
+			if encryptionParams.Mode == jobspb.EncryptionMode_None {
+				return errors.New("rely_on_encryption_at_rest can only be used with encryption_passphrase or kms")
+			}
+			encryptionAtRestKeyIDs, err = encryptionAtRestKeyIDs(ctx, p, append(append([]string(nil), to...), incrementalStorage...))
+			if err != nil {
+				return err
+			}
+		}
+
 		initialDetails := jobspb.BackupDetails{
-			Destination:         jobspb.BackupDetails_Destination{To: to, IncrementalStorage: incrementalStorage},
+			Destination: jobspb.BackupDetails_Destination{
+				To:                 destinationTo,
+				IncrementalStorage: incrementalStorage,
+				MetadataURI:        metadataURI,
+			},
 			EndTime:             endTime,
 			RevisionHistory:     revisionHistory,
 			IncrementalFrom:     incrementalFrom,
@@ -704,6 +1318,44 @@ func backupPlanHook(
 			AsOfInterval:        asOfInterval,
 			Detached:            detached,
 			ApplicationName:     p.SessionData().ApplicationName,
+			UploadOptions:       uploadOptions,
+			SkipStatistics:      !statistics,
+			IncludeComments:     comments,
+			IncludeZoneConfigs:  zoneConfigs,
+			IncludeJobs:         includeJobs,
+			PerTableFiles:       perTableFiles,
+			ConsolidateChanges:  consolidateChanges,
+			ExecutionLocality:   executionLocalityFilter,
+			HighPriority:        highPriority,
+			Compression:         compression,
+			AppToken:            appToken,
+		}
+		if allowMissingLocalities {
+			initialDetails.AllowMissingLocalities = true
+		}
+		if skipIfUnchanged {
+			initialDetails.SkipIfUnchanged = true
+		}
+		if coordinatorURI != "" {
+			initialDetails.Coordination = &jobspb.BackupDetails_Coordination{
+				CoordinatorURI: coordinatorURI,
+				NumClusters:    int32(coordinatedClusters),
+			}
+		}
+		if relyOnEncryptionAtRest {
+			initialDetails.EncryptionAtRestOnly = true
+			initialDetails.EncryptionAtRestKeyIDs = encryptionAtRestKeyIDs
+		}
+		if tableFilter != nil {
+			if tableFilter.Exclude {
+				initialDetails.ExcludedTablePatterns = tablePatterns
+			} else {
+				initialDetails.IncludedTablePatterns = tablePatterns
+			}
+		}
+		if rowFilter != nil {
+			initialDetails.RowFilter = tree.AsString(rowFilter)
+			initialDetails.RowFilterSpans = rowFilterSpansToBackup
 		}
 		if backupStmt.CreatedByInfo != nil && backupStmt.CreatedByInfo.Name == jobs.CreatedByScheduledJobs {
 			initialDetails.ScheduleID = backupStmt.CreatedByInfo.ID
@@ -728,6 +1380,8 @@ func backupPlanHook(
 			}
 		}
 
+		jobID := p.ExecCfg().JobRegistry.MakeJobID()
+
 		if backupStmt.Nested {
 			if backupStmt.AppendToLatest {
 				initialDetails.Destination.Subdir = backupbase.LatestFileName
@@ -736,6 +1390,9 @@ func backupPlanHook(
 			} else if subdir != "" {
 				initialDetails.Destination.Subdir = "/" + strings.TrimPrefix(subdir, "/")
 				initialDetails.Destination.Exists = true
+			} else if subdirFormat != "" {
+				initialDetails.Destination.Subdir = backupdest.FormatSubdir(subdirFormat, endTime.GoTime(), jobID)
+				initialDetails.Destination.SubdirFormat = subdirFormat
 			} else {
 				initialDetails.Destination.Subdir = endTime.GoTime().Format(backupbase.DateBasedIntoFolderName)
 			}
@@ -748,13 +1405,18 @@ func backupPlanHook(
 			initialDetails.SpecificTenantIds = []roachpb.TenantID{roachpb.MakeTenantID(backupStmt.Targets.TenantID.ID)}
 		}
 
-		jobID := p.ExecCfg().JobRegistry.MakeJobID()
+		if backupStmt.ExplainOnly {
+			return explainBackup(ctx, p, initialDetails, resultsCh)
+		}
 
 		description, err := backupJobDescription(p,
 			backupStmt.Backup, to, incrementalFrom,
 			encryptionParams.RawKmsUris,
+			encryptionParams.RawKMSURIsByLocalityKV,
 			initialDetails.Destination.Subdir,
 			initialDetails.Destination.IncrementalStorage,
+			metadataURI,
+			coordinatorURI,
 		)
 		if err != nil {
 			return err
@@ -815,6 +1477,9 @@ func backupPlanHook(
 		return sj.ReportExecutionResults(ctx, resultsCh)
 	}
 
+	if backupStmt.ExplainOnly {
+		return fn, explainHeader, nil, false, nil
+	}
 	if detached {
 		return fn, jobs.DetachedJobExecutionResultHeader, nil, false, nil
 	}
@@ -862,6 +1527,9 @@ func collectTelemetry(
 	if len(backupDetails.URIsByLocalityKV) > 1 {
 		countSource("backup.partitioned")
 	}
+	if _, ok := backupDetails.URIsByLocalityKV[backupdest.FailoverLocalityValue]; ok {
+		countSource("backup.failover")
+	}
 	if backupManifest.MVCCFilter == backuppb.MVCCFilter_All {
 		countSource("backup.revision-history")
 	}
@@ -878,7 +1546,7 @@ func collectTelemetry(
 		countSource("backup.nested")
 		timeBaseSubdir := true
 		if _, err := time.Parse(backupbase.DateBasedIntoFolderName,
-			initialDetails.Destination.Subdir); err != nil {
+			initialDetails.Destination.Subdir); err != nil && initialDetails.Destination.SubdirFormat == "" {
 			timeBaseSubdir = false
 		}
 		if backupDetails.StartTime.IsEmpty() {
@@ -1095,8 +1763,10 @@ func getProtectedTimestampTargetForBackup(backupManifest backuppb.BackupManifest
 	//
 	// This includes explicit `BACKUP DATABASE` targets as well as expansions as a
 	// result of `BACKUP TABLE db.*`. In both cases we want to write a protected
-	// timestamp record that covers the entire database.
-	if len(backupManifest.CompleteDbs) > 0 {
+	// timestamp record that covers the entire database, unless the tables of the
+	// databases were filtered by EXCLUDE TABLES or INCLUDE TABLES, in which case
+	// only the tables that were backed up are protected.
+	if len(backupManifest.CompleteDbs) > 0 && !hasTablePatterns(backupManifest) {
 		return ptpb.MakeSchemaObjectsTarget(backupManifest.CompleteDbs)
 	}
 
@@ -1217,6 +1887,177 @@ func checkForNewTables(
 	return nil
 }
 
+// evalUploadOptions evaluates the upload_parallelism, part_size and
+// upload_buffer_memory options of a BACKUP, any of which may be unset. Unset
+// options are left zero so that the storage provider's defaults are used.
+func evalUploadOptions(
+	parallelismFn func() (int64, error), partSizeFn, bufferMemoryFn func() (string, error),
+) (cloudpb.UploadOptions, error) {
+	var opts cloudpb.UploadOptions
+	if parallelismFn != nil {
+		parallelism, err := parallelismFn()
+		if err != nil {
+			return opts, err
+		}
+		if parallelism < 1 || parallelism > maxUploadParallelism {
+			return opts, pgerror.Newf(pgcode.InvalidParameterValue,
+				"%s must be between 1 and %d", backupOptUploadParallel, maxUploadParallelism)
+		}
+		opts.Parallelism = int32(parallelism)
+	}
+	evalSize := func(fn func() (string, error), name string) (int64, error) {
+		if fn == nil {
+			return 0, nil
+		}
+		s, err := fn()
+		if err != nil {
+			return 0, err
+		}
+		size, err := humanizeutil.ParseBytes(s)
+		if err != nil {
+			return 0, pgerror.Wrapf(err, pgcode.InvalidParameterValue, "invalid %s", name)
+		}
+		if size <= 0 {
+			return 0, pgerror.Newf(pgcode.InvalidParameterValue, "%s must be positive", name)
+		}
+		return size, nil
+	}
+	var err error
+	if opts.PartSize, err = evalSize(partSizeFn, backupOptPartSize); err != nil {
+		return opts, err
+	}
+	if opts.BufferMemory, err = evalSize(bufferMemoryFn, backupOptUploadBufferMem); err != nil {
+		return opts, err
+	}
+	if opts.PartSize > 0 && opts.BufferMemory > 0 && opts.PartSize > opts.BufferMemory {
+		return opts, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s must not exceed %s", backupOptPartSize, backupOptUploadBufferMem)
+	}
+	return opts, nil
+}
+
+// parseExecutionLocality parses the locality filter of the execution_locality
+// option of a BACKUP or RESTORE, e.g. 'region=us-east1,zone=us-east1-b'. The
+// empty filter, which matches every node, is returned if the option is unset.
+func parseExecutionLocality(filter string) (roachpb.Locality, error) {
+	var locality roachpb.Locality
+	if filter == "" {
+		return locality, nil
+	}
+	if err := locality.Set(filter); err != nil {
+		return roachpb.Locality{}, pgerror.Wrapf(err, pgcode.InvalidParameterValue,
+			"invalid execution_locality %q", filter)
+	}
+	return locality, nil
+}
+
+// checkConsolidateChangesOptions checks that a BACKUP with consolidate_changes
+// is an incremental backup into an existing chain whose layer can be built
+// from the changes a backup_kv changefeed wrote next to the chain's
+// incremental backups.
+func checkConsolidateChangesOptions(
+	backupStmt *annotatedBackupStatement, subdir string, numTo int, revisionHistory bool,
+) error {
+	if !backupStmt.Nested || (!backupStmt.AppendToLatest && subdir == "") {
+		return errors.New("consolidate_changes can only be used with an incremental backup into " +
+			"an existing chain; use `BACKUP INTO LATEST IN <collectionURI>` or name the chain's subdirectory")
+	}
+	if backupStmt.Coverage() == tree.AllDescriptors ||
+		(backupStmt.Targets != nil && (backupStmt.Targets.TenantID.IsSet() || backupStmt.Targets.AllTenants)) {
+		return errors.New("consolidate_changes cannot be used with cluster or tenant backups")
+	}
+	if backupStmt.AsOf.Expr != nil {
+		return errors.New("consolidate_changes cannot be used with AS OF SYSTEM TIME; " +
+			"the backup is taken as of the latest timestamp the changefeed resolved")
+	}
+	if revisionHistory {
+		return errors.New("consolidate_changes cannot be used with revision_history")
+	}
+	if numTo > 1 || backupStmt.Failover {
+		return errors.New("consolidate_changes cannot be used with locality-aware or FAILOVER destinations")
+	}
+	return nil
+}
+
+// checkMetadataURIOptions checks that a BACKUP with metadata_uri writes into a
+// single collection, whose backups keep their metadata in the collection given
+// by the option.
+func checkMetadataURIOptions(
+	backupStmt *annotatedBackupStatement, numTo int, numIncrementalStorage int,
+) error {
+	if !backupStmt.Nested {
+		return errors.Errorf("%q option is only supported with the"+
+			" 'BACKUP ... INTO [destination]' syntax", backupOptMetadataURI)
+	}
+	if numTo > 1 || backupStmt.Failover {
+		return errors.Errorf("%q cannot be used with locality-aware or FAILOVER destinations",
+			backupOptMetadataURI)
+	}
+	if numIncrementalStorage > 0 {
+		return errors.Errorf("%q cannot be used with %q", backupOptMetadataURI, backupOptIncStorage)
+	}
+	return nil
+}
+
+// checkCoordinatorOptions checks that a BACKUP coordinated with the backups of
+// other clusters names the location they coordinate through and how many of
+// them there are, and lets its end time be negotiated with them.
+func checkCoordinatorOptions(
+	backupStmt *annotatedBackupStatement, coordinatorURI string, coordinatedClusters int64,
+) error {
+	if coordinatorURI == "" || coordinatedClusters == 0 {
+		return errors.Errorf("%q and %q must be used together", backupOptCoordinator, backupOptCoordinated)
+	}
+	if coordinatedClusters < 2 {
+		return pgerror.Newf(pgcode.InvalidParameterValue,
+			"%q must be at least 2", backupOptCoordinated)
+	}
+	if backupStmt.AsOf.Expr != nil {
+		return errors.Errorf("%q cannot be used with AS OF SYSTEM TIME; "+
+			"the backup is taken as of the end time negotiated with the other clusters", backupOptCoordinator)
+	}
+	// Each coordinated backup negotiates through a location of its own, which
+	// a schedule would reuse for all of its backups.
+	if backupStmt.CreatedByInfo != nil && backupStmt.CreatedByInfo.Name == jobs.CreatedByScheduledJobs {
+		return errors.Errorf("%q cannot be used with scheduled backups", backupOptCoordinator)
+	}
+	return nil
+}
+
+// hasTablePatterns returns whether the tables of the complete databases of the
+// backup were filtered by EXCLUDE TABLES or INCLUDE TABLES patterns.
+func hasTablePatterns(m backuppb.BackupManifest) bool {
+	return len(m.ExcludedTablePatterns) > 0 || len(m.IncludedTablePatterns) > 0
+}
+
+// checkTablePatternsMatchPrevious checks that an incremental backup restricts
+// the tables of its databases with the same EXCLUDE TABLES or INCLUDE TABLES
+// patterns as the previous backup in its chain, so that every layer of the
+// chain covers the same tables.
+func checkTablePatternsMatchPrevious(
+	jobDetails jobspb.BackupDetails, prev backuppb.BackupManifest,
+) error {
+	equal := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if !equal(jobDetails.ExcludedTablePatterns, prev.ExcludedTablePatterns) ||
+		!equal(jobDetails.IncludedTablePatterns, prev.IncludedTablePatterns) {
+		return errors.Newf("the table name patterns of this backup (excluded: %q, included: %q)"+
+			" differ from those of the previous backup (excluded: %q, included: %q)",
+			jobDetails.ExcludedTablePatterns, jobDetails.IncludedTablePatterns,
+			prev.ExcludedTablePatterns, prev.IncludedTablePatterns)
+	}
+	return nil
+}
+
 func getTenantInfo(
 	ctx context.Context, execCfg *sql.ExecutorConfig, txn *kv.Txn, jobDetails jobspb.BackupDetails,
 ) ([]roachpb.Span, []descpb.TenantInfoWithUsage, error) {
@@ -1328,9 +2169,12 @@ func createBackupManifest(
 	spans = append(spans, tenantSpans...)
 	tenants = append(tenants, tenantInfos...)
 
-	tableSpans, err := spansForAllTableIndexes(execCfg, tables, revs)
-	if err != nil {
-		return backuppb.BackupManifest{}, err
+	tableSpans := jobDetails.RowFilterSpans
+	if jobDetails.RowFilter == "" {
+		tableSpans, err = spansForAllTableIndexes(execCfg, tables, revs)
+		if err != nil {
+			return backuppb.BackupManifest{}, err
+		}
 	}
 	spans = append(spans, tableSpans...)
 
@@ -1347,7 +2191,16 @@ func createBackupManifest(
 			dbsInPrev[d] = struct{}{}
 		}
 
+		if err := checkEncryptionAtRestMatchesPrevious(jobDetails.EncryptionAtRestOnly, prevBackups[len(prevBackups)-1].EncryptionAtRestOnly); err != nil {
+			return backuppb.BackupManifest{}, err
+		}
 		if !jobDetails.FullCluster {
+			if err := checkTablePatternsMatchPrevious(jobDetails, prevBackups[len(prevBackups)-1]); err != nil {
+				return backuppb.BackupManifest{}, err
+			}
+			if err := checkRowFilterMatchesPrevious(jobDetails.RowFilter, prevBackups[len(prevBackups)-1].RowFilter); err != nil {
+				return backuppb.BackupManifest{}, err
+			}
 			if err := checkForNewTables(ctx, execCfg.Codec, execCfg.DB, targetDescs, tablesInPrev, dbsInPrev, priorIDs, startTime, endTime); err != nil {
 				return backuppb.BackupManifest{}, err
 			}
@@ -1364,6 +2217,9 @@ func createBackupManifest(
 		if err != nil {
 			return backuppb.BackupManifest{}, err
 		}
+		if jobDetails.RowFilter != "" {
+			reintroducedSpans = restrictSpansToRowFilter(reintroducedSpans, jobDetails.RowFilterSpans)
+		}
 		newSpans = append(newSpans, reintroducedSpans...)
 	}
 
@@ -1380,21 +2236,64 @@ func createBackupManifest(
 	}
 
 	backupManifest := backuppb.BackupManifest{
-		StartTime:           startTime,
-		EndTime:             endTime,
-		MVCCFilter:          mvccFilter,
-		Descriptors:         descriptorProtos,
-		Tenants:             tenants,
-		DescriptorChanges:   revs,
-		CompleteDbs:         jobDetails.ResolvedCompleteDbs,
-		Spans:               spans,
-		IntroducedSpans:     newSpans,
-		FormatVersion:       backupinfo.BackupFormatDescriptorTrackingVersion,
-		BuildInfo:           build.GetInfo(),
-		ClusterVersion:      execCfg.Settings.Version.ActiveVersion(ctx).Version,
-		ClusterID:           execCfg.NodeInfo.LogicalClusterID(),
-		StatisticsFilenames: statsFiles,
-		DescriptorCoverage:  coverage,
+		StartTime:              startTime,
+		EndTime:                endTime,
+		MVCCFilter:             mvccFilter,
+		Descriptors:            descriptorProtos,
+		Tenants:                tenants,
+		DescriptorChanges:      revs,
+		CompleteDbs:            jobDetails.ResolvedCompleteDbs,
+		Spans:                  spans,
+		IntroducedSpans:        newSpans,
+		FormatVersion:          backupinfo.BackupFormatDescriptorTrackingVersion,
+		BuildInfo:              build.GetInfo(),
+		ClusterVersion:         execCfg.Settings.Version.ActiveVersion(ctx).Version,
+		ClusterID:              execCfg.NodeInfo.LogicalClusterID(),
+		StatisticsFilenames:    statsFiles,
+		DescriptorCoverage:     coverage,
+		ExcludedTablePatterns:  jobDetails.ExcludedTablePatterns,
+		IncludedTablePatterns:  jobDetails.IncludedTablePatterns,
+		PerTableFiles:          jobDetails.PerTableFiles,
+		RowFilter:              jobDetails.RowFilter,
+		EncryptionAtRestOnly:   jobDetails.EncryptionAtRestOnly,
+		EncryptionAtRestKeyIDs: jobDetails.EncryptionAtRestKeyIDs,
+		AppToken:               jobDetails.AppToken,
+	}
+	backupManifest.Compression, err = backupinfo.ParseCompression(jobDetails.Compression)
+	if err != nil {
+		return backuppb.BackupManifest{}, err
+	}
+	if enc := jobDetails.EncryptionOptions; enc != nil {
+		backupManifest.DecryptQuorum = enc.DecryptQuorum
+		for localityKV := range enc.KMSInfoByLocalityKV {
+			backupManifest.KMSLocalityKVs = append(backupManifest.KMSLocalityKVs, localityKV)
+		}
+		sort.Strings(backupManifest.KMSLocalityKVs)
+	}
+	if jobDetails.IncludeComments {
+		backupManifest.Comments, err = getDescriptorComments(ctx, execCfg.InternalExecutor,
+			descriptorProtos, endTime)
+		if err != nil {
+			return backuppb.BackupManifest{}, err
+		}
+	}
+	if jobDetails.IncludeZoneConfigs {
+		backupManifest.ZoneConfigs, err = getDescriptorZoneConfigs(ctx, execCfg.InternalExecutor,
+			descriptorProtos, endTime)
+		if err != nil {
+			return backuppb.BackupManifest{}, err
+		}
+	}
+	backupManifest.RowLevelTTLSchedules, err = getRowLevelTTLSchedules(ctx, execCfg.InternalExecutor,
+		descriptorProtos, endTime)
+	if err != nil {
+		return backuppb.BackupManifest{}, err
+	}
+	if jobDetails.IncludeJobs {
+		backupManifest.Jobs, err = getResumableJobs(ctx, execCfg.InternalExecutor, endTime)
+		if err != nil {
+			return backuppb.BackupManifest{}, err
+		}
 	}
 	if err := checkCoverage(ctx, backupManifest.Spans, append(prevBackups, backupManifest)); err != nil {
 		return backuppb.BackupManifest{}, errors.Wrap(err, "new backup would not cover expected time")
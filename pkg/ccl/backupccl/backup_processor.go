@@ -11,10 +11,14 @@ package backupccl
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalioaudit"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/batcheval"
@@ -27,13 +31,16 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
 	"github.com/cockroachdb/cockroach/pkg/sql/rowexec"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/admission"
 	"github.com/cockroachdb/cockroach/pkg/util/admission/admissionpb"
 	"github.com/cockroachdb/cockroach/pkg/util/bulk"
 	"github.com/cockroachdb/cockroach/pkg/util/contextutil"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
@@ -79,8 +86,65 @@ var (
 		"split backup data on timestamps when writing revision history",
 		true,
 	)
+
+	localityRateLimits = settings.RegisterValidatedStringSetting(
+		settings.TenantWritable,
+		"bulkio.backup.locality_rate_limits",
+		"comma-separated list of per-node limits on the number of bytes per second written by "+
+			"locality-aware backups to the destination of a locality, e.g. "+
+			"'region=eu-west=100MiB,default=1GiB', where default is the default destination",
+		"",
+		func(_ *settings.Values, s string) error {
+			_, err := parseLocalityRateLimits(s)
+			return err
+		},
+	)
 )
 
+// parseLocalityRateLimits parses the value of the
+// bulkio.backup.locality_rate_limits setting into the limits, in bytes per
+// second, keyed by locality tier.
+func parseLocalityRateLimits(s string) (map[string]int64, error) {
+	limits := make(map[string]int64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndexByte(entry, '=')
+		if i < 0 {
+			return nil, errors.Newf("invalid locality rate limit %q: expected <locality>=<bytes per second>", entry)
+		}
+		tier, limit := entry[:i], strings.TrimSuffix(entry[i+1:], "/s")
+		if tier != backupdest.DefaultLocalityValue && !strings.Contains(tier, "=") {
+			return nil, errors.Newf("invalid locality %q: expected a key=value locality tier or %q",
+				tier, backupdest.DefaultLocalityValue)
+		}
+		bytes, err := humanizeutil.ParseBytes(limit)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid rate limit for locality %q", tier)
+		}
+		if bytes <= 0 {
+			return nil, errors.Newf("rate limit for locality %q must be positive", tier)
+		}
+		if _, ok := limits[tier]; ok {
+			return nil, errors.Newf("locality %q specified multiple times", tier)
+		}
+		limits[tier] = bytes
+	}
+	return limits, nil
+}
+
+// localityWriteLimiter returns the limiter for the writes to the destination of
+// the given locality tier, updated to the current value of its limit.
+func localityWriteLimiter(
+	sv *settings.Values, limiters *cloud.LocalityWriteLimiters, tier string,
+) *quotapool.RateLimiter {
+	// The setting was validated when it was set.
+	limits, _ := parseLocalityRateLimits(localityRateLimits.Get(sv))
+	return limiters.Get(tier, limits[tier])
+}
+
 const backupProcessorName = "backupDataProcessor"
 
 // TODO(pbardea): It would be nice if we could add some DistSQL processor tests
@@ -249,6 +313,7 @@ func runBackupProcessor(
 ) error {
 	backupProcessorSpan := tracing.SpanFromContext(ctx)
 	clusterSettings := flowCtx.Cfg.Settings
+	ctx = externalioaudit.WithPrincipal(ctx, spec.User(), jobspb.JobID(spec.JobID))
 
 	totalSpans := len(spec.Spans) + len(spec.IntroducedSpans)
 	todo := make(chan spanAndTime, totalSpans)
@@ -270,6 +335,10 @@ func runBackupProcessor(
 
 	destURI := spec.DefaultURI
 	var destLocalityKV string
+	// The destination of the failover locality is not matched against the
+	// node's locality; it is only written to when writing to the destination
+	// of the node fails.
+	failoverURI, hasFailover := spec.URIsByLocalityKV[backupdest.FailoverLocalityValue]
 
 	if len(spec.URIsByLocalityKV) > 0 {
 		var localitySinkURI string
@@ -277,6 +346,9 @@ func runBackupProcessor(
 		// over less specific ones so search back to front.
 		for i := len(flowCtx.EvalCtx.Locality.Tiers) - 1; i >= 0; i-- {
 			tier := flowCtx.EvalCtx.Locality.Tiers[i].String()
+			if tier == backupdest.FailoverLocalityValue {
+				continue
+			}
 			if dest, ok := spec.URIsByLocalityKV[tier]; ok {
 				localitySinkURI = dest
 				destLocalityKV = tier
@@ -305,6 +377,11 @@ func runBackupProcessor(
 
 	returnedSpansChan := make(chan exportedSpan, 1)
 
+	exportPriority := admissionpb.BulkNormalPri
+	if spec.HighPriority {
+		exportPriority = admissionpb.NormalPri
+	}
+
 	grp := ctxgroup.WithContext(ctx)
 	// Start a goroutine that will then start a group of goroutines which each
 	// pull spans off of `todo` and send export requests. Any spans that encounter
@@ -318,7 +395,17 @@ func runBackupProcessor(
 		//  *2). See #49798.
 		numSenders := int(kvserver.ExportRequestsLimit.Get(&clusterSettings.SV)) * 2
 
-		return ctxgroup.GroupWorkers(ctx, numSenders, func(ctx context.Context, _ int) error {
+		// Each worker accumulates the stats of the ExportRequests it evaluates
+		// locally and hands them over when it exits, so that the workers do not
+		// contend on the mutex of the processor's span for every response. The
+		// stats of all the workers are then recorded on the span at once.
+		workerStats := make([]backuppb.ExportStats, numSenders)
+		defer func() { recordExportStats(backupProcessorSpan, workerStats) }()
+
+		return ctxgroup.GroupWorkers(ctx, numSenders, func(ctx context.Context, worker int) error {
+			var exportStats backuppb.ExportStats
+			defer func() { workerStats[worker] = exportStats }()
+
 			readTime := spec.BackupEndTime.GoTime()
 
 			// priority becomes true when we're sending re-attempts of reads far enough
@@ -395,10 +482,12 @@ func runBackupProcessor(
 						// after creating a single SST.
 						header.TargetBytes = 1
 						admissionHeader := roachpb.AdmissionHeader{
-							// Export requests are currently assigned BulkNormalPri.
+							// Export requests are assigned BulkNormalPri, which admits
+							// them as elastic work, unless the backup runs at high
+							// priority.
 							//
 							// TODO(dt): Consider linking this to/from the UserPriority field.
-							Priority:                 int32(admissionpb.BulkNormalPri),
+							Priority:                 int32(exportPriority),
 							CreateTime:               timeutil.Now().UnixNano(),
 							Source:                   roachpb.AdmissionHeader_FROM_SQL,
 							NoMemoryReservedAtSource: true,
@@ -489,6 +578,28 @@ func runBackupProcessor(
 							log.Warning(ctx, "unexpected multi-file response using header.TargetBytes = 1")
 						}
 
+						// A span with no data to export produces no files, but the sink
+						// still needs to hear about it to advance the export frontier.
+						if len(resp.Files) == 0 {
+							ret := exportedSpan{
+								metadata:       backuppb.BackupManifest_File{Span: span.span},
+								completedSpans: completedSpans,
+								atKeyBoundary:  true,
+							}
+							if resp.ResumeSpan != nil {
+								ret.metadata.Span.EndKey = resp.ResumeSpan.Key
+							}
+							if span.start != spec.BackupStartTime {
+								ret.metadata.StartTime = span.start
+								ret.metadata.EndTime = span.end
+							}
+							select {
+							case returnedSpansChan <- ret:
+							case <-ctxDone:
+								return ctx.Err()
+							}
+						}
+
 						for i, file := range resp.Files {
 							entryCounts := countRows(file.Exported, spec.PKIDs)
 
@@ -520,8 +631,8 @@ func runBackupProcessor(
 							}
 						}
 
-						// Emit the stats for the processed ExportRequest.
-						recordExportStats(backupProcessorSpan, resp, timeutil.Since(requestSentAt))
+						// Accumulate the stats for the processed ExportRequest.
+						addExportStats(&exportStats, resp, timeutil.Since(requestSentAt))
 						span = resumeSpan
 					}
 				default:
@@ -540,13 +651,49 @@ func runBackupProcessor(
 	// contents to cloud storage.
 	grp.GoCtx(func(ctx context.Context) error {
 		sinkConf := sstSinkConf{
-			id:       flowCtx.NodeID.SQLInstanceID(),
-			enc:      spec.Encryption,
-			progCh:   progCh,
-			settings: &flowCtx.Cfg.Settings.SV,
+			id:             flowCtx.NodeID.SQLInstanceID(),
+			enc:            spec.Encryption,
+			progCh:         progCh,
+			settings:       &flowCtx.Cfg.Settings.SV,
+			targetFileSize: spec.TargetFileSize,
+			jobID:          jobspb.JobID(spec.JobID),
+			destURI:        destURI,
+			dataCodec:      spec.DataCodec,
+		}
+		// Encrypting and uploading the files the backup writes is CPU-intensive
+		// too, so unless the backup runs at high priority it is paced like the
+		// export requests that produce them.
+		if factory := flowCtx.Cfg.AdmissionPacerFactory; factory != nil && !spec.HighPriority {
+			sinkConf.pacer = factory.NewPacer(sstSinkPacerUnit, admission.WorkInfo{
+				TenantID:   roachpb.SystemTenantID,
+				Priority:   admissionpb.BulkNormalPri,
+				CreateTime: timeutil.Now().UnixNano(),
+			})
+		}
+		// The files written to the destination of a locality with a data key of
+		// its own are encrypted with that key.
+		if enc, ok := spec.EncryptionByLocalityKV[destLocalityKV]; ok {
+			sinkConf.enc = enc
+		}
+		storageOpts := []cloud.ExternalStorageOption{cloud.WithUploadOptions(spec.UploadOptions)}
+		// The writes of locality-aware backups to the destination of each
+		// locality, including the default one, are subject to the rate limit of
+		// that locality.
+		if limiters := flowCtx.Cfg.BackupLocalityWriteLimiters; limiters != nil && len(spec.URIsByLocalityKV) > 0 {
+			sinkConf.writeLimiters = limiters
+			sinkConf.localityKV = destLocalityKV
+			if sinkConf.localityKV == "" {
+				sinkConf.localityKV = backupdest.DefaultLocalityValue
+			}
+			storageOpts = append(storageOpts, cloud.WithWriteLimiter(
+				localityWriteLimiter(sinkConf.settings, limiters, sinkConf.localityKV)))
+		}
+		if spec.PerTableFiles {
+			sinkConf.perTableFiles = true
+			sinkConf.codec = flowCtx.Codec()
 		}
 
-		storage, err := flowCtx.Cfg.ExternalStorage(ctx, dest)
+		storage, err := flowCtx.Cfg.ExternalStorage(ctx, dest, storageOpts...)
 		if err != nil {
 			return err
 		}
@@ -559,11 +706,42 @@ func runBackupProcessor(
 		defer func() {
 			err := sink.Close()
 			err = errors.CombineErrors(storage.Close(), err)
+			if sink.failover != nil {
+				err = errors.CombineErrors(sink.failover.Close(), err)
+			}
 			if err != nil {
 				log.Warningf(ctx, "failed to close backup sink(s): % #v", pretty.Formatter(err))
 			}
 		}()
 
+		if hasFailover {
+			failoverDest, err := cloud.ExternalStorageConfFromURI(failoverURI, spec.User())
+			if err != nil {
+				return err
+			}
+			failoverStorage, err := flowCtx.Cfg.ExternalStorage(ctx, failoverDest,
+				cloud.WithUploadOptions(spec.UploadOptions))
+			if err != nil {
+				return err
+			}
+			sink.failover = failoverStorage
+			sink.failoverLocalityKV = backupdest.FailoverLocalityValue
+		} else if spec.AllowMissingLocalities && destLocalityKV != "" {
+			// The files of this locality are written to the default destination
+			// instead of failing the backup once its destination can't be written.
+			defaultDest, err := cloud.ExternalStorageConfFromURI(spec.DefaultURI, spec.User())
+			if err != nil {
+				return err
+			}
+			defaultStorage, err := flowCtx.Cfg.ExternalStorage(ctx, defaultDest,
+				cloud.WithUploadOptions(spec.UploadOptions))
+			if err != nil {
+				return err
+			}
+			sink.failover = defaultStorage
+			sink.missingLocalityKV = destLocalityKV
+		}
+
 		for returnedSpans := range returnedSpansChan {
 			returnedSpans.metadata.LocalityKV = destLocalityKV
 			if err := sink.push(ctx, returnedSpans); err != nil {
@@ -576,18 +754,27 @@ func runBackupProcessor(
 	return grp.Wait()
 }
 
-// recordExportStats emits a StructuredEvent containing the stats about the
-// evaluated ExportRequest.
-func recordExportStats(
-	sp *tracing.Span, resp *roachpb.ExportResponse, exportDuration time.Duration,
+// addExportStats adds the stats about the evaluated ExportRequest to stats.
+func addExportStats(
+	stats *backuppb.ExportStats, resp *roachpb.ExportResponse, exportDuration time.Duration,
 ) {
+	respStats := backuppb.ExportStats{Duration: exportDuration}
+	for _, f := range resp.Files {
+		respStats.NumFiles++
+		respStats.DataSize += int64(len(f.SST))
+	}
+	stats.Combine(&respStats)
+}
+
+// recordExportStats emits a StructuredEvent containing the stats about the
+// ExportRequests evaluated by all the workers of the processor.
+func recordExportStats(sp *tracing.Span, workerStats []backuppb.ExportStats) {
 	if sp == nil {
 		return
 	}
-	exportStats := backuppb.ExportStats{Duration: exportDuration}
-	for _, f := range resp.Files {
-		exportStats.NumFiles++
-		exportStats.DataSize += int64(len(f.SST))
+	var exportStats backuppb.ExportStats
+	for i := range workerStats {
+		exportStats.Combine(&workerStats[i])
 	}
 	sp.RecordStructured(&exportStats)
 }
@@ -10,24 +10,36 @@ package backupccl
 
 import (
 	"context"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
 	"github.com/cockroachdb/cockroach/pkg/sql/physicalplan"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
-	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/errors"
 )
 
+var replanBackupOnDrainFrequency = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.backup.drain_replan_frequency",
+	"frequency at which BACKUP checks whether a node running part of its flow has begun draining, to restart the flow from the last checkpoint without it (0=disabled)",
+	10*time.Second,
+	settings.NonNegativeDuration,
+)
+
 func distBackupPlanSpecs(
 	ctx context.Context,
 	planCtx *sql.PlanningCtx,
@@ -43,6 +55,11 @@ func distBackupPlanSpecs(
 	kmsEnv cloud.KMSEnv,
 	mvccFilter roachpb.MVCCFilter,
 	startTime, endTime hlc.Timestamp,
+	uploadOptions cloudpb.UploadOptions,
+	perTableFiles bool,
+	highPriority bool,
+	allowMissingLocalities bool,
+	dataCodec string,
 ) (map[base.SQLInstanceID]*execinfrapb.BackupDataSpec, error) {
 	var span *tracing.Span
 	ctx, span = tracing.ChildSpan(ctx, "backupccl.distBackupPlanSpecs")
@@ -66,18 +83,8 @@ func distBackupPlanSpecs(
 	}
 
 	if encryption != nil && encryption.Mode == jobspb.EncryptionMode_KMS {
-		kms, err := cloud.KMSFromURI(ctx, encryption.KMSInfo.Uri, kmsEnv)
-		if err != nil {
-			return nil, err
-		}
-		defer func() {
-			err := kms.Close()
-			if err != nil {
-				log.Infof(ctx, "failed to close KMS: %+v", err)
-			}
-		}()
-
-		encryption.Key, err = kms.Decrypt(ctx, encryption.KMSInfo.EncryptedDataKey)
+		var err error
+		encryption.Key, err = backupencryption.GetEncryptionKey(ctx, encryption, kmsEnv)
 		if err != nil {
 			return nil, errors.Wrap(err,
 				"failed to decrypt data key before starting BackupDataProcessor")
@@ -89,22 +96,33 @@ func distBackupPlanSpecs(
 	if encryption != nil {
 		fileEncryption = &roachpb.FileEncryptionOptions{Key: encryption.Key}
 	}
+	fileEncryptionByLocalityKV, err := backupencryption.GetLocalityEncryptionKeys(ctx, encryption, kmsEnv)
+	if err != nil {
+		return nil, errors.Wrap(err,
+			"failed to decrypt locality data keys before starting BackupDataProcessor")
+	}
 
 	// First construct spans based on span partitions. Then add on
 	// introducedSpans based on how those partition.
 	sqlInstanceIDToSpec := make(map[base.SQLInstanceID]*execinfrapb.BackupDataSpec)
 	for _, partition := range spanPartitions {
 		spec := &execinfrapb.BackupDataSpec{
-			JobID:            jobID,
-			Spans:            partition.Spans,
-			DefaultURI:       defaultURI,
-			URIsByLocalityKV: urisByLocalityKV,
-			MVCCFilter:       mvccFilter,
-			Encryption:       fileEncryption,
-			PKIDs:            pkIDs,
-			BackupStartTime:  startTime,
-			BackupEndTime:    endTime,
-			UserProto:        user.EncodeProto(),
+			JobID:                  jobID,
+			Spans:                  partition.Spans,
+			DefaultURI:             defaultURI,
+			URIsByLocalityKV:       urisByLocalityKV,
+			MVCCFilter:             mvccFilter,
+			Encryption:             fileEncryption,
+			EncryptionByLocalityKV: fileEncryptionByLocalityKV,
+			PKIDs:                  pkIDs,
+			BackupStartTime:        startTime,
+			BackupEndTime:          endTime,
+			UserProto:              user.EncodeProto(),
+			UploadOptions:          uploadOptions,
+			PerTableFiles:          perTableFiles,
+			HighPriority:           highPriority,
+			AllowMissingLocalities: allowMissingLocalities,
+			DataCodec:              dataCodec,
 		}
 		sqlInstanceIDToSpec[partition.SQLInstanceID] = spec
 	}
@@ -117,16 +135,22 @@ func distBackupPlanSpecs(
 			// which is not the leaseholder for any of the spans, but is for an
 			// introduced span.
 			spec := &execinfrapb.BackupDataSpec{
-				JobID:            jobID,
-				IntroducedSpans:  partition.Spans,
-				DefaultURI:       defaultURI,
-				URIsByLocalityKV: urisByLocalityKV,
-				MVCCFilter:       mvccFilter,
-				Encryption:       fileEncryption,
-				PKIDs:            pkIDs,
-				BackupStartTime:  startTime,
-				BackupEndTime:    endTime,
-				UserProto:        user.EncodeProto(),
+				JobID:                  jobID,
+				IntroducedSpans:        partition.Spans,
+				DefaultURI:             defaultURI,
+				URIsByLocalityKV:       urisByLocalityKV,
+				MVCCFilter:             mvccFilter,
+				Encryption:             fileEncryption,
+				EncryptionByLocalityKV: fileEncryptionByLocalityKV,
+				PKIDs:                  pkIDs,
+				BackupStartTime:        startTime,
+				BackupEndTime:          endTime,
+				UserProto:              user.EncodeProto(),
+				UploadOptions:          uploadOptions,
+				PerTableFiles:          perTableFiles,
+				HighPriority:           highPriority,
+				AllowMissingLocalities: allowMissingLocalities,
+				DataCodec:              dataCodec,
 			}
 			sqlInstanceIDToSpec[partition.SQLInstanceID] = spec
 		}
@@ -183,32 +207,45 @@ func distBackup(
 
 	dsp.FinalizePlan(planCtx, p)
 
-	metaFn := func(_ context.Context, meta *execinfrapb.ProducerMetadata) error {
-		if meta.BulkProcessorProgress != nil {
-			// Send the progress up a level to be written to the manifest.
-			progCh <- meta.BulkProcessorProgress
-		}
-		return nil
-	}
-
-	rowResultWriter := sql.NewRowResultWriter(nil)
-
-	recv := sql.MakeDistSQLReceiver(
-		ctx,
-		sql.NewMetadataCallbackWriter(rowResultWriter, metaFn),
-		tree.Rows,
-		nil,   /* rangeCache */
-		noTxn, /* txn - the flow does not read or write the database */
-		nil,   /* clockUpdater */
-		evalCtx.Tracing,
-		evalCtx.ExecCfg.ContentionRegistry,
-		nil, /* testingPushCallback */
+	drainChecker, stopDrainChecker := sql.DrainingInstanceChecker(p, execCtx,
+		func() time.Duration { return replanBackupOnDrainFrequency.Get(execCtx.ExecCfg().SV()) },
 	)
-	defer recv.Release()
 
 	defer close(progCh)
-	// Copy the evalCtx, as dsp.Run() might change it.
-	evalCtxCopy := *evalCtx
-	dsp.Run(ctx, planCtx, noTxn, p, recv, &evalCtxCopy, nil /* finishedSetupFn */)
-	return rowResultWriter.Err()
+	g := ctxgroup.WithContext(ctx)
+	g.GoCtx(func(ctx context.Context) error {
+		defer stopDrainChecker()
+
+		metaFn := func(_ context.Context, meta *execinfrapb.ProducerMetadata) error {
+			if meta.BulkProcessorProgress != nil {
+				// Send the progress up a level to be written to the manifest.
+				progCh <- meta.BulkProcessorProgress
+			}
+			return nil
+		}
+
+		rowResultWriter := sql.NewRowResultWriter(nil)
+
+		recv := sql.MakeDistSQLReceiver(
+			ctx,
+			sql.NewMetadataCallbackWriter(rowResultWriter, metaFn),
+			tree.Rows,
+			nil,   /* rangeCache */
+			noTxn, /* txn - the flow does not read or write the database */
+			nil,   /* clockUpdater */
+			evalCtx.Tracing,
+			evalCtx.ExecCfg.ContentionRegistry,
+			nil, /* testingPushCallback */
+		)
+		defer recv.Release()
+
+		// Copy the evalCtx, as dsp.Run() might change it.
+		evalCtxCopy := *evalCtx
+		dsp.Run(ctx, planCtx, noTxn, p, recv, &evalCtxCopy, nil /* finishedSetupFn */)
+		return rowResultWriter.Err()
+	})
+
+	g.GoCtx(drainChecker)
+
+	return g.Wait()
 }
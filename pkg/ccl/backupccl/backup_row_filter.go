@@ -0,0 +1,193 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
+)
+
+// A backup of a single table can be restricted to the rows matching a
+// predicate, as in `BACKUP TABLE t WHERE region = 'eu' INTO ...`. The predicate
+// is turned into spans of the primary index of the table, so it must fully
+// constrain its key. It may only reference the leading key columns that every
+// index of the table shares, in the same order and directions, so that the
+// spans of the primary index carry over to the other indexes. The crdb_region
+// column of REGIONAL BY ROW tables is such a column.
+//
+// Since a row is found in the spans by its key, a row whose key moves in or out
+// of them, e.g. because its region changed, is respectively written to or
+// deleted from them, so every layer of a chain of filtered backups, which must
+// all use the same predicate, is consistent. The predicate is recorded in the
+// manifest, and RESTORE only restores the spans it selects, and records it in
+// the comment of the restored table.
+
+// rowFilterSpans returns the spans of the indexes of the single table in
+// targetDescs that hold the rows matching filter.
+func rowFilterSpans(
+	ctx context.Context, p sql.PlanHookState, targetDescs []catalog.Descriptor, filter tree.Expr,
+) ([]roachpb.Span, error) {
+	var table catalog.TableDescriptor
+	for _, desc := range targetDescs {
+		if t, ok := desc.(catalog.TableDescriptor); ok {
+			if table != nil {
+				return nil, errors.New("a row filter can only be used when backing up a single table")
+			}
+			table = t
+		}
+	}
+	if table == nil || !table.IsPhysicalTable() || table.IsSequence() {
+		return nil, errors.New("a row filter can only be used when backing up a single table")
+	}
+
+	// The leading key columns of the primary index that every other index also
+	// starts with.
+	primary := table.GetPrimaryIndex()
+	shared := primary.NumKeyColumns()
+	for _, idx := range table.PublicNonPrimaryIndexes() {
+		n := 0
+		for n < shared && n < idx.NumKeyColumns() &&
+			idx.GetKeyColumnID(n) == primary.GetKeyColumnID(n) &&
+			idx.GetKeyColumnDirection(n) == primary.GetKeyColumnDirection(n) {
+			n++
+		}
+		shared = n
+	}
+	if _, err := tree.SimpleVisit(filter, func(expr tree.Expr) (bool, tree.Expr, error) {
+		name, ok := expr.(*tree.UnresolvedName)
+		if !ok {
+			return true, expr, nil
+		}
+		col, err := table.FindColumnWithName(tree.Name(name.Parts[0]))
+		if err != nil {
+			return false, nil, err
+		}
+		for i := 0; i < shared; i++ {
+			if primary.GetKeyColumnID(i) == col.GetID() {
+				return false, expr, nil
+			}
+		}
+		return false, nil, errors.WithHint(
+			errors.Newf("row filter %q references column %q, which is not a leading key column of every index of table %q",
+				tree.AsString(filter), col.GetName(), table.GetName()),
+			"a row filter can only reference the leading key columns shared by all the indexes of the table")
+	}); err != nil {
+		return nil, err
+	}
+
+	// The descriptors resolved for the backup do not carry the metadata of the
+	// user-defined types of their columns, which building the spans needs.
+	mut := tabledesc.NewBuilder(table.TableDesc()).BuildExistingMutableTable()
+	for i := range mut.Columns {
+		if typ := mut.Columns[i].Type; typ.UserDefined() {
+			hydrated, err := p.SemaCtx().TypeResolver.ResolveTypeByOID(ctx, typ.Oid())
+			if err != nil {
+				return nil, err
+			}
+			mut.Columns[i].Type = hydrated
+		}
+	}
+	hydrated := mut.ImmutableCopy().(catalog.TableDescriptor)
+
+	codec := p.ExecCfg().Codec
+	primarySpans, _, err := p.ConstrainPrimaryIndexSpanByExpr(ctx, sql.MustFullyConstrain,
+		tree.NewUnqualifiedTableName(tree.Name(table.GetName())), hydrated,
+		&p.ExtendedEvalContext().Context, p.SemaCtx(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	primaryPrefix := rowenc.MakeIndexKeyPrefix(codec, table.GetID(), primary.GetID())
+	spans := append([]roachpb.Span(nil), primarySpans...)
+	for _, idx := range table.PublicNonPrimaryIndexes() {
+		prefix := rowenc.MakeIndexKeyPrefix(codec, table.GetID(), idx.GetID())
+		for _, sp := range primarySpans {
+			spans = append(spans, roachpb.Span{
+				Key:    append(roachpb.Key(prefix).Clone(), sp.Key[len(primaryPrefix):]...),
+				EndKey: append(roachpb.Key(prefix).Clone(), sp.EndKey[len(primaryPrefix):]...),
+			})
+		}
+	}
+	spans, _ = roachpb.MergeSpans(&spans)
+	return spans, nil
+}
+
+// checkRowFilterMatchesPrevious checks that an incremental backup uses the same
+// row filter as the previous backup in its chain.
+func checkRowFilterMatchesPrevious(rowFilter, prevRowFilter string) error {
+	if rowFilter != prevRowFilter {
+		return errors.Newf("the row filter of this backup (%q) differs from that of the previous backup (%q)",
+			rowFilter, prevRowFilter)
+	}
+	return nil
+}
+
+// restrictSpansToRowFilter returns the parts of spans that are covered by
+// rowFilterSpans.
+func restrictSpansToRowFilter(spans, rowFilterSpans []roachpb.Span) []roachpb.Span {
+	var restricted []roachpb.Span
+	for _, sp := range spans {
+		for _, f := range rowFilterSpans {
+			if i := sp.Intersect(f); i.Valid() {
+				restricted = append(restricted, i)
+			}
+		}
+	}
+	return restricted
+}
+
+// commentRowFilteredTables appends the row filter of the restored backup to the
+// comment of the restored tables, so that they are not mistaken for complete
+// ones.
+func commentRowFilteredTables(
+	ctx context.Context, execCfg *sql.ExecutorConfig, details jobspb.RestoreDetails,
+) error {
+	if details.RowFilter == "" {
+		return nil
+	}
+	note := fmt.Sprintf("restored from a backup only containing the rows matching %s", details.RowFilter)
+	return execCfg.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		for _, table := range details.TableDescs {
+			comment := note
+			row, err := execCfg.InternalExecutor.QueryRow(ctx, "restore-get-row-filter-comment", txn,
+				`SELECT comment FROM system.comments WHERE type = $1 AND object_id = $2 AND sub_id = 0`,
+				int(keys.TableCommentType), table.ID)
+			if err != nil {
+				return err
+			}
+			if row != nil {
+				existing := string(tree.MustBeDString(row[0]))
+				if strings.Contains(existing, note) {
+					// The comment was already written by a previous attempt.
+					continue
+				}
+				comment = fmt.Sprintf("%s (%s)", existing, note)
+			}
+			if _, err := execCfg.InternalExecutor.Exec(ctx, "restore-row-filter-comment", txn,
+				`UPSERT INTO system.comments (type, object_id, sub_id, comment) VALUES ($1, $2, 0, $3)`,
+				int(keys.TableCommentType), table.ID, comment,
+			); err != nil {
+				return errors.Wrap(err, "commenting restored table")
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
+)
+
+// The values of the schema_change_policy option, which determines what BACKUP
+// does about tables it backs up that have declarative schema changes in
+// progress. Restoring such tables restores them in their intermediate state.
+const (
+	// backupSchemaChangePolicyBackupAnyway backs the tables up regardless.
+	// This is the default.
+	backupSchemaChangePolicyBackupAnyway = "backup_anyway"
+	// backupSchemaChangePolicyWait waits for the schema changes to finish
+	// before choosing the time the backup is as of.
+	backupSchemaChangePolicyWait = "wait"
+	// backupSchemaChangePolicyError fails the backup.
+	backupSchemaChangePolicyError = "error"
+)
+
+// schemaChangeWaitTimeout bounds how long a backup with schema_change_policy
+// = 'wait' waits for the schema changes on the tables it backs up.
+var schemaChangeWaitTimeout = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.backup.schema_change_wait_timeout",
+	"the maximum amount of time a backup with schema_change_policy = 'wait' waits for the "+
+		"schema changes in progress on the tables it backs up to finish",
+	5*time.Minute,
+	settings.PositiveDuration,
+)
+
+// resolveSchemaChangePolicy validates the value of the schema_change_policy
+// option, returning the default if it is unset.
+func resolveSchemaChangePolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return backupSchemaChangePolicyBackupAnyway, nil
+	case backupSchemaChangePolicyBackupAnyway, backupSchemaChangePolicyWait, backupSchemaChangePolicyError:
+		return policy, nil
+	default:
+		return "", errors.Errorf("schema_change_policy must be one of '%s', '%s' or '%s', got %q",
+			backupSchemaChangePolicyBackupAnyway, backupSchemaChangePolicyWait,
+			backupSchemaChangePolicyError, policy)
+	}
+}
+
+// schemaChangesInProgress returns the tables among descs that have declarative
+// schema changes in progress and the jobs running those schema changes.
+func schemaChangesInProgress(descs []catalog.Descriptor) ([]string, []jobspb.JobID) {
+	var tables []string
+	var jobIDs []jobspb.JobID
+	for _, desc := range descs {
+		table, ok := desc.(catalog.TableDescriptor)
+		if !ok || table.Dropped() {
+			continue
+		}
+		if state := table.GetDeclarativeSchemaChangerState(); state != nil {
+			tables = append(tables, tree.NameString(table.GetName()))
+			jobIDs = append(jobIDs, state.JobID)
+		}
+	}
+	return tables, jobIDs
+}
+
+// errSchemaChangesInProgress returns the error a backup with
+// schema_change_policy = 'error' fails with.
+func errSchemaChangesInProgress(tables []string, jobIDs []jobspb.JobID) error {
+	return pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+		"cannot back up tables %s because they have schema changes in progress (jobs %v); "+
+			"use schema_change_policy = '%s' to wait for them to finish",
+		strings.Join(tables, ", "), jobIDs, backupSchemaChangePolicyWait)
+}
+
+// waitForSchemaChanges waits for the jobs of the schema changes in progress
+// on the backed up tables to finish, whether or not they succeed, or pause. It
+// gives up once the deadline of ctx, set from
+// bulkio.backup.schema_change_wait_timeout, has passed.
+func waitForSchemaChanges(
+	ctx context.Context, p sql.PlanHookState, tables []string, jobIDs []jobspb.JobID,
+) error {
+	err := p.ExecCfg().JobRegistry.WaitForJobsIgnoringJobErrors(
+		ctx, p.ExecCfg().InternalExecutor, jobIDs,
+	)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+			"timed out after %s waiting for the schema changes in progress on tables %s (jobs %v)",
+			schemaChangeWaitTimeout.Get(&p.ExecCfg().Settings.SV),
+			strings.Join(tables, ", "), jobIDs)
+	}
+	return err
+}
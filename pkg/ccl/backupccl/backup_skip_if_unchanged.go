@@ -0,0 +1,144 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/errors"
+)
+
+// An incremental backup run with skip_if_unchanged first checks whether
+// anything it would back up changed since the previous backup of its chain,
+// and if nothing did, it writes a BACKUP-SKIPPED marker in place of its layer
+// and succeeds without exporting anything. Schedules running frequent
+// incremental backups of idle clusters then leave a small file behind for
+// each run rather than a manifest and the metadata of an empty layer.
+//
+// The backup is unchanged if the spans it covers, and those of the descriptor
+// table, so that no schema change is missed, are unchanged between its start
+// and end time as told by the MVCC stats of their ranges; see
+// spansUnchangedSince. A skipped backup adds no layer, so the next backup of
+// the chain starts from the end time of the same previous backup, and the
+// protected timestamp of its schedule is left where that backup put it.
+
+// skipIfUnchangedMaxWait bounds how long an incremental backup run with
+// skip_if_unchanged waits for the resolved timestamp of its spans to reach its
+// end time, which it must before it can tell they are unchanged. A backup
+// which times out is written as usual.
+const skipIfUnchangedMaxWait = time.Minute
+
+// incrementalBackupUnchanged returns whether nothing covered by the
+// incremental backup with the given manifest changed since its start time.
+// Full backups, and backups which introduce spans, are never unchanged.
+func incrementalBackupUnchanged(
+	ctx context.Context, execCfg *sql.ExecutorConfig, manifest *backuppb.BackupManifest,
+) (bool, error) {
+	if manifest.StartTime.IsEmpty() || len(manifest.IntroducedSpans) > 0 {
+		return false, nil
+	}
+	descPrefix := execCfg.Codec.TablePrefix(keys.DescriptorTableID)
+	spans := append([]roachpb.Span{{Key: descPrefix, EndKey: descPrefix.PrefixEnd()}},
+		manifest.Spans...)
+	if err := waitForResolvedTimestamp(ctx, execCfg, spans, manifest.EndTime); err != nil {
+		return false, err
+	}
+	return spansUnchangedSince(ctx, execCfg, spans, manifest.StartTime, manifest.EndTime)
+}
+
+// waitForResolvedTimestamp waits until the resolved timestamp of each of spans
+// is at or after end, or skipIfUnchangedMaxWait has passed.
+func waitForResolvedTimestamp(
+	ctx context.Context, execCfg *sql.ExecutorConfig, spans []roachpb.Span, end hlc.Timestamp,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, skipIfUnchangedMaxWait)
+	defer cancel()
+	opts := retry.Options{InitialBackoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+	for _, span := range spans {
+		for r := retry.StartWithCtx(ctx, opts); ; {
+			resolved, err := execCfg.DB.QueryResolvedTimestamp(ctx, span.Key, span.EndKey, false /* nearest */)
+			if err != nil {
+				return err
+			}
+			if end.LessEq(resolved) {
+				break
+			}
+			if !r.Next() {
+				return errors.Wrapf(ctx.Err(), "waiting for the resolved timestamp of %s to reach %s",
+					span, end)
+			}
+		}
+	}
+	return nil
+}
+
+// skipUnchangedBackup completes the backup with the given details and
+// manifest, which was found unchanged, without writing it. The marker is
+// written before the resolved details are persisted, so that a job resumed
+// with them finds it and has nothing left to do.
+func (b *backupResumer) skipUnchangedBackup(
+	ctx context.Context,
+	p sql.JobExecContext,
+	details jobspb.BackupDetails,
+	manifest *backuppb.BackupManifest,
+) error {
+	log.Infof(ctx, "skipping backup to %s, unchanged since %s",
+		details.Destination.Subdir, manifest.StartTime)
+	if err := backupinfo.WriteBackupSkippedMarker(ctx, p.ExecCfg(), details.URI, p.User(),
+		manifest.StartTime, manifest.EndTime); err != nil {
+		return err
+	}
+	if err := b.job.Update(ctx, nil, func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+		if err := md.CheckRunningOrReverting(); err != nil {
+			return err
+		}
+		md.Payload.Details = jobspb.WrapPayloadDetails(details)
+		ju.UpdatePayload(md.Payload)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	b.recordCollectionEvent(ctx, p.ExecCfg(), p.User(), backupdest.CollectionEvent{
+		Type:        backupdest.CollectionEventBackupSkipped,
+		Subdir:      details.Destination.Subdir,
+		Incremental: true,
+		EndTime:     manifest.EndTime.AsOfSystemTime(),
+	})
+
+	// The data covered by the backup is the same as of its end time as it is
+	// as of the end time of the previous backup, so its tables are as recent
+	// in the chain as if the backup had been written.
+	if err := recordTableBackupCheckpoints(ctx, p.ExecCfg(), b.job.ID(), manifest); err != nil {
+		log.Warningf(ctx, "failed to record table backup checkpoints: %v", err)
+	}
+	if m, ok := p.ExecCfg().JobRegistry.MetricsStruct().Backup.(*BackupMetrics); ok {
+		if err := m.refreshRPO(ctx, p.ExecCfg()); err != nil {
+			log.Warningf(ctx, "failed to refresh backup RPO metric: %v", err)
+		}
+		m.maybeStartRPORefresher(p.ExecCfg())
+	}
+
+	telemetry.Count("backup.total.skipped")
+	return b.maybeNotifyScheduledJobCompletion(ctx, jobs.StatusSucceeded, nil /* jobErr */, p.ExecCfg())
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupSkipIfUnchanged(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	const numAccounts = 10
+	tc, sqlDB, rawDir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+	kvDB := tc.Server(0).DB()
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING kv.closed_timestamp.target_duration = '100ms'`)
+	sqlDB.ExpectErr(t, "skip_if_unchanged cannot be used with consolidate_changes",
+		`BACKUP DATABASE data INTO LATEST IN $1 WITH skip_if_unchanged, consolidate_changes`, localFoo)
+
+	// Give the table ranges of its own, so that the writes of other tables do
+	// not move the stats of its ranges.
+	var bankID uint32
+	sqlDB.QueryRow(t, `SELECT 'data.bank'::regclass::int`).Scan(&bankID)
+	prefix := keys.SystemSQLCodec.TablePrefix(bankID)
+	for _, key := range []roachpb.Key{prefix, prefix.PrefixEnd()} {
+		require.NoError(t, kvDB.AdminSplit(ctx, key, hlc.MaxTimestamp /* expirationTime */))
+	}
+
+	// The stats of a range only track the second of its last update, so let the
+	// splits and the writes each happen in a second of their own.
+	time.Sleep(time.Second)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+	time.Sleep(time.Second)
+
+	// layers returns the directories of the incremental backups of the chain,
+	// split by whether they were written or skipped.
+	layers := func() (written, skipped []string) {
+		incDir := filepath.Join(rawDir, "foo", backupbase.DefaultIncrementalsSubdir)
+		require.NoError(t, filepath.Walk(incDir, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			require.NoError(t, err)
+			switch info.Name() {
+			case backupbase.BackupManifestName:
+				written = append(written, filepath.Dir(path))
+			case backupinfo.BackupSkippedMarkerName:
+				skipped = append(skipped, filepath.Dir(path))
+			}
+			return nil
+		}))
+		return written, skipped
+	}
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1 WITH skip_if_unchanged`, localFoo)
+	written, skipped := layers()
+	require.Empty(t, written)
+	require.Len(t, skipped, 1)
+
+	// A write to the table, or a schema change, gets the next backup written.
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1 WHERE id = 1`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1 WITH skip_if_unchanged`, localFoo)
+	written, skipped = layers()
+	require.Len(t, written, 1)
+	require.Len(t, skipped, 1)
+
+	time.Sleep(time.Second)
+	sqlDB.Exec(t, `CREATE TABLE data.other (id INT PRIMARY KEY)`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1 WITH skip_if_unchanged`, localFoo)
+	written, _ = layers()
+	require.Len(t, written, 2)
+
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = 'data2'`, localFoo)
+	sqlDB.CheckQueryResults(t, `SELECT * FROM data2.bank`, sqlDB.QueryStr(t, `SELECT * FROM data.bank`))
+	sqlDB.CheckQueryResults(t, `SHOW TABLES FROM data2`, sqlDB.QueryStr(t, `SHOW TABLES FROM data`))
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+)
+
+// An incremental backup exports the revisions of its spans between the end of
+// the previous backup of its chain and its own end time, and most of the time
+// spent exporting the spans of a table that has not been written to is spent
+// finding there is nothing to export. With the setting below, an incremental
+// backup first looks for the tables that are unchanged since the previous
+// backup, and does not send ExportRequests for their spans at all. A table is
+// unchanged if:
+//
+//  1. Its descriptor was last modified before the start time of the backup.
+//  2. None of the ranges overlapping its spans were written to since then, as
+//     told by the last update time of the MVCC stats of those ranges, which is
+//     moved forward by every write, and they hold no intents.
+//  3. The resolved timestamp of its spans is at or after the end time of the
+//     backup, so no write below the end time can still be committed there.
+//     Unlike an ExportRequest, which reads at the end time and so keeps later
+//     writes above it, a skipped span is not read, so a backup started less
+//     than the closed timestamp target duration after its end time finds
+//     nothing to skip.
+//
+// The stats of a range only track the time of its last update to the second,
+// so a range written to in the second the previous backup ended in is always
+// exported. Ranges shared by several tables are written to by each of them, so
+// a table is only skipped if none of the tables it shares its ranges with has
+// changed either.
+//
+// The spans of the skipped tables remain in the spans of the manifest, since
+// the backup covers them like its other spans, but are also recorded in its
+// skipped spans, so that a resumed backup does not export them either.
+
+var skipUnchangedTables = settings.RegisterBoolSetting(
+	settings.TenantWritable,
+	"bulkio.backup.skip_unchanged_tables.enabled",
+	"if set, incremental backups do not export the spans of tables that are unchanged "+
+		"since the previous backup of their chain",
+	false,
+)
+
+// unchangedTableSpans returns the parts of spans that belong to tables that
+// are unchanged since the start time of the incremental backup with the given
+// manifest. Tables that are introduced by the backup are never skipped.
+func unchangedTableSpans(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	manifest *backuppb.BackupManifest,
+	spans []roachpb.Span,
+) ([]roachpb.Span, error) {
+	if manifest.StartTime.IsEmpty() {
+		return nil, nil
+	}
+	var skipped []roachpb.Span
+	for i := range manifest.Descriptors {
+		t, _, _, _, _ := descpb.GetDescriptors(&manifest.Descriptors[i])
+		if t == nil || t.State != descpb.DescriptorState_PUBLIC ||
+			manifest.StartTime.Less(t.ModificationTime) {
+			continue
+		}
+		prefix := execCfg.Codec.TablePrefix(uint32(t.ID))
+		tableSpan := roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()}
+		if overlapsAny(tableSpan, manifest.IntroducedSpans) {
+			continue
+		}
+		var pieces []roachpb.Span
+		for _, span := range spans {
+			if piece := tableSpan.Intersect(span); piece.Valid() {
+				pieces = append(pieces, piece)
+			}
+		}
+		if len(pieces) == 0 {
+			continue
+		}
+		unchanged, err := spansUnchangedSince(ctx, execCfg, pieces, manifest.StartTime, manifest.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		if unchanged {
+			log.VEventf(ctx, 1, "skipping %d spans of unchanged table %d", len(pieces), t.ID)
+			skipped = append(skipped, pieces...)
+		}
+	}
+	return skipped, nil
+}
+
+// spansUnchangedSince returns whether there can be no revisions of the keys in
+// spans in (start, end].
+func spansUnchangedSince(
+	ctx context.Context, execCfg *sql.ExecutorConfig, spans []roachpb.Span, start, end hlc.Timestamp,
+) (bool, error) {
+	startSecond := start.WallTime / int64(time.Second)
+	errChanged := errors.New("changed")
+	for _, span := range spans {
+		// The resolved timestamp is checked first, so that the stats fetched
+		// after it reflect every write at or below the end time.
+		resolved, err := execCfg.DB.QueryResolvedTimestamp(ctx, span.Key, span.EndKey, false /* nearest */)
+		if err != nil {
+			return false, err
+		}
+		if resolved.Less(end) {
+			return false, nil
+		}
+		err = forEachRangeStats(ctx, execCfg, span, func(_ roachpb.Span, ms enginepb.MVCCStats) error {
+			if ms.LastUpdateNanos/int64(time.Second) >= startSecond ||
+				ms.IntentCount != 0 || ms.ContainsEstimates != 0 {
+				return errChanged
+			}
+			return nil
+		})
+		if errors.Is(err, errChanged) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func overlapsAny(span roachpb.Span, spans []roachpb.Span) bool {
+	for _, sp := range spans {
+		if span.Overlaps(sp) {
+			return true
+		}
+	}
+	return false
+}
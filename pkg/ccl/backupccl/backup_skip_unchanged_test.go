@@ -0,0 +1,87 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupSkipUnchangedTables(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	const numAccounts = 10
+	tc, sqlDB, rawDir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+	kvDB := tc.Server(0).DB()
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING kv.closed_timestamp.target_duration = '100ms'`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.skip_unchanged_tables.enabled = true`)
+	sqlDB.Exec(t, `CREATE TABLE data.other (id INT PRIMARY KEY)`)
+	sqlDB.Exec(t, `INSERT INTO data.other VALUES (1), (2)`)
+
+	// Give each table ranges of its own, so that writes to one of them do not
+	// move the stats of the ranges of the other.
+	var bankID, otherID uint32
+	sqlDB.QueryRow(t, `SELECT 'data.bank'::regclass::int`).Scan(&bankID)
+	sqlDB.QueryRow(t, `SELECT 'data.other'::regclass::int`).Scan(&otherID)
+	for _, id := range []uint32{bankID, otherID} {
+		prefix := keys.SystemSQLCodec.TablePrefix(id)
+		for _, key := range []roachpb.Key{prefix, prefix.PrefixEnd()} {
+			require.NoError(t, kvDB.AdminSplit(ctx, key, hlc.MaxTimestamp /* expirationTime */))
+		}
+	}
+
+	// The stats of a range only track the second of its last update, so let the
+	// splits and the writes each happen in a second of their own.
+	time.Sleep(time.Second)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1`, localFoo+"/full")
+	time.Sleep(time.Second)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1 WHERE id = 1`)
+	time.Sleep(2 * time.Second)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1 AS OF SYSTEM TIME '-1s' INCREMENTAL FROM $2`,
+		localFoo+"/inc", localFoo+"/full")
+
+	manifestBytes, err := os.ReadFile(filepath.Join(rawDir, "foo", "inc", backupbase.BackupManifestName))
+	require.NoError(t, err)
+	manifestBytes, err = backupinfo.DecompressData(ctx, nil, manifestBytes)
+	require.NoError(t, err)
+	var manifest backuppb.BackupManifest
+	require.NoError(t, protoutil.Unmarshal(manifestBytes, &manifest))
+
+	otherPrefix := keys.SystemSQLCodec.TablePrefix(otherID)
+	bankPrefix := keys.SystemSQLCodec.TablePrefix(bankID)
+	otherSpan := roachpb.Span{Key: otherPrefix, EndKey: otherPrefix.PrefixEnd()}
+	bankSpan := roachpb.Span{Key: bankPrefix, EndKey: bankPrefix.PrefixEnd()}
+	require.True(t, overlapsAny(otherSpan, manifest.SkippedSpans), "skipped %v", manifest.SkippedSpans)
+	require.False(t, overlapsAny(bankSpan, manifest.SkippedSpans), "skipped %v", manifest.SkippedSpans)
+
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM $1, $2 WITH new_db_name = 'data2'`,
+		localFoo+"/full", localFoo+"/inc")
+	for _, table := range []string{"bank", "other"} {
+		sqlDB.CheckQueryResults(t, `SELECT * FROM data2.`+table,
+			sqlDB.QueryStr(t, `SELECT * FROM data.`+table))
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvclient/kvcoord"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// Without stats-aware sizing, each span a backup exports is handed to the
+// processors as a whole, and each processor cuts the files it writes whenever
+// they exceed bulkio.backup.file_size. A dense table thus becomes a single
+// span exported by a single worker, and the last file each processor writes is
+// whatever data was left over, however small.
+//
+// With stats-aware sizing, the backup fetches the live bytes (as tracked by
+// MVCCStats) of the ranges overlapping its spans when it is planned, and:
+//
+//  1. Cuts each span at range boundaries into chunks of about the target file
+//     size, which are exported separately. Sparse ranges are grouped into a
+//     single chunk, while a range holding more than the target forms a chunk
+//     of its own.
+//  2. Sets the file size of each processor so that the data it is expected to
+//     export is split evenly between as many files as the target requires.
+//
+// The live bytes of a range straddling the edge of a span are counted in full
+// for it, and live bytes only approximate the size of the exported data, which
+// also holds the older revisions of a backup with revision_history, so the
+// files are only about uniformly sized.
+
+var statsAwareFileSizing = settings.RegisterBoolSetting(
+	settings.TenantWritable,
+	"bulkio.backup.stats_aware_file_sizing.enabled",
+	"if set, backups partition the spans they export and size the files they write "+
+		"using the MVCC stats of the ranges of those spans",
+	false,
+)
+
+// backupSpanStatsBatchSize is the number of ranges whose stats are fetched by
+// a single call to the RangeStatsFetcher.
+const backupSpanStatsBatchSize = 1000
+
+// rangeLiveBytes is the piece of a span in a single range, and the live bytes
+// of that range.
+type rangeLiveBytes struct {
+	span      roachpb.Span
+	liveBytes int64
+}
+
+// backupSpanStats holds the live bytes of the ranges overlapping the spans
+// exported by a backup.
+type backupSpanStats struct {
+	target int64
+	pieces []rangeLiveBytes
+}
+
+// sizeBackupSpans cuts the spans and introduced spans of a backup into chunks
+// of about the target file size, and returns the stats it cut them with, from
+// which the file sizes of the processors are set once they are planned. The
+// spans are returned unchanged, along with nil stats, if their stats could not
+// be fetched, since sizing is only an optimization.
+func sizeBackupSpans(
+	ctx context.Context, execCfg *sql.ExecutorConfig, spans, introducedSpans []roachpb.Span,
+) (_, _ []roachpb.Span, _ *backupSpanStats) {
+	stats := &backupSpanStats{target: targetFileSize.Get(&execCfg.Settings.SV)}
+	chunk := func(spans []roachpb.Span) ([]roachpb.Span, error) {
+		var chunks []roachpb.Span
+		for _, span := range spans {
+			pieces, err := fetchRangeLiveBytes(ctx, execCfg, span)
+			if err != nil {
+				return nil, err
+			}
+			stats.pieces = append(stats.pieces, pieces...)
+			chunks = append(chunks, chunkBackupSpan(pieces, stats.target)...)
+		}
+		return chunks, nil
+	}
+
+	chunkedSpans, err := chunk(spans)
+	if err != nil {
+		log.Warningf(ctx, "failed to size backup spans from their stats: %v", err)
+		return spans, introducedSpans, nil
+	}
+	chunkedIntroducedSpans, err := chunk(introducedSpans)
+	if err != nil {
+		log.Warningf(ctx, "failed to size backup spans from their stats: %v", err)
+		return spans, introducedSpans, nil
+	}
+	log.VEventf(ctx, 1, "cut %d spans and %d introduced spans into %d and %d chunks of about %d live bytes",
+		len(spans), len(introducedSpans), len(chunkedSpans), len(chunkedIntroducedSpans), stats.target)
+	return chunkedSpans, chunkedIntroducedSpans, stats
+}
+
+// fetchRangeLiveBytes returns the pieces of span in each of the ranges it
+// overlaps, in order, with the live bytes of those ranges.
+func fetchRangeLiveBytes(
+	ctx context.Context, execCfg *sql.ExecutorConfig, span roachpb.Span,
+) ([]rangeLiveBytes, error) {
+	var pieces []rangeLiveBytes
+	if err := forEachRangeStats(ctx, execCfg, span, func(piece roachpb.Span, ms enginepb.MVCCStats) error {
+		pieces = append(pieces, rangeLiveBytes{span: piece, liveBytes: ms.LiveBytes})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return pieces, nil
+}
+
+// forEachRangeStats calls fn, in order, with the piece of span in each of the
+// ranges it overlaps and the MVCC stats of that range.
+func forEachRangeStats(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	span roachpb.Span,
+	fn func(piece roachpb.Span, ms enginepb.MVCCStats) error,
+) error {
+	rs, err := keys.SpanAddr(span)
+	if err != nil {
+		return err
+	}
+	var pieces []roachpb.Span
+	ri := kvcoord.MakeRangeIterator(execCfg.DistSender)
+	for ri.Seek(ctx, rs.Key, kvcoord.Ascending); ; ri.Next(ctx) {
+		if !ri.Valid() {
+			return ri.Error()
+		}
+		desc := ri.Desc()
+		piece := rs
+		if piece.Key.Less(desc.StartKey) {
+			piece.Key = desc.StartKey
+		}
+		if desc.EndKey.Less(piece.EndKey) {
+			piece.EndKey = desc.EndKey
+		}
+		pieces = append(pieces, piece.AsRawSpanWithNoLocals())
+		if !ri.NeedAnother(rs) {
+			break
+		}
+	}
+
+	for batch := pieces; len(batch) > 0; {
+		n := len(batch)
+		if n > backupSpanStatsBatchSize {
+			n = backupSpanStatsBatchSize
+		}
+		rangeKeys := make([]roachpb.Key, n)
+		for i := range rangeKeys {
+			rangeKeys[i] = batch[i].Key
+		}
+		resps, err := execCfg.RangeStatsFetcher.RangeStats(ctx, rangeKeys...)
+		if err != nil {
+			return err
+		}
+		for i, resp := range resps {
+			if err := fn(batch[i], resp.MVCCStats); err != nil {
+				return err
+			}
+		}
+		batch = batch[n:]
+	}
+	return nil
+}
+
+// chunkBackupSpan groups the consecutive pieces of a span into chunks of about
+// target live bytes. A piece is added to the chunk before it unless that would
+// take the chunk further past the target than it is short of it, and a
+// remainder of less than half of the target at the end of the span is folded
+// into the chunk before it.
+func chunkBackupSpan(pieces []rangeLiveBytes, target int64) []roachpb.Span {
+	var chunks []roachpb.Span
+	var cur roachpb.Span
+	var curBytes int64
+	for _, p := range pieces {
+		if curBytes > 0 && curBytes+p.liveBytes-target > target-curBytes {
+			chunks = append(chunks, cur)
+			cur, curBytes = roachpb.Span{}, 0
+		}
+		if cur.Key == nil {
+			cur.Key = p.span.Key
+		}
+		cur.EndKey = p.span.EndKey
+		curBytes += p.liveBytes
+		if curBytes >= target {
+			chunks = append(chunks, cur)
+			cur, curBytes = roachpb.Span{}, 0
+		}
+	}
+	if cur.Key != nil {
+		if len(chunks) > 0 && curBytes < target/2 {
+			chunks[len(chunks)-1].EndKey = cur.EndKey
+		} else {
+			chunks = append(chunks, cur)
+		}
+	}
+	return chunks
+}
+
+// setTargetFileSizes sets the file size of each of the backup processors
+// specs are planned for from the live bytes of the spans it exports.
+func (s *backupSpanStats) setTargetFileSizes(
+	specs map[base.SQLInstanceID]*execinfrapb.BackupDataSpec,
+) {
+	for _, spec := range specs {
+		var g roachpb.SpanGroup
+		g.Add(spec.Spans...)
+		g.Add(spec.IntroducedSpans...)
+		var liveBytes int64
+		for _, p := range s.pieces {
+			if g.Contains(p.span.Key) {
+				liveBytes += p.liveBytes
+			}
+		}
+		spec.TargetFileSize = evenBackupFileSize(liveBytes, s.target)
+	}
+}
+
+// evenBackupFileSize returns the size of the files in which liveBytes are
+// written so that none of them exceeds target and they are all about the same
+// size, rather than files of the target size followed by a smaller last one.
+func evenBackupFileSize(liveBytes, target int64) int64 {
+	if liveBytes <= target {
+		return target
+	}
+	files := (liveBytes + target - 1) / target
+	return (liveBytes + files - 1) / files
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkBackupSpan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	// pieces returns the pieces of the span [a, a+len(liveBytes)) cut at each
+	// letter, with the given live bytes.
+	pieces := func(liveBytes ...int64) []rangeLiveBytes {
+		res := make([]rangeLiveBytes, len(liveBytes))
+		for i, b := range liveBytes {
+			res[i] = rangeLiveBytes{
+				span:      roachpb.Span{Key: roachpb.Key{'a' + byte(i)}, EndKey: roachpb.Key{'a' + byte(i) + 1}},
+				liveBytes: b,
+			}
+		}
+		return res
+	}
+	span := func(start, end byte) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key{start}, EndKey: roachpb.Key{end}}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		pieces   []rangeLiveBytes
+		expected []roachpb.Span
+	}{
+		{name: "empty"},
+		{name: "single", pieces: pieces(5), expected: []roachpb.Span{span('a', 'b')}},
+		{name: "sparse", pieces: pieces(1, 2, 3, 1), expected: []roachpb.Span{span('a', 'e')}},
+		{name: "grouped", pieces: pieces(4, 4, 2, 5, 5), expected: []roachpb.Span{span('a', 'd'), span('d', 'f')}},
+		{name: "dense", pieces: pieces(30, 20), expected: []roachpb.Span{span('a', 'b'), span('b', 'c')}},
+		{name: "dense after sparse", pieces: pieces(3, 30, 1), expected: []roachpb.Span{span('a', 'b'), span('b', 'd')}},
+		{name: "remainder", pieces: pieces(10, 6), expected: []roachpb.Span{span('a', 'b'), span('b', 'c')}},
+		{name: "folded remainder", pieces: pieces(10, 4), expected: []roachpb.Span{span('a', 'c')}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, chunkBackupSpan(tc.pieces, 10))
+		})
+	}
+}
+
+func TestEvenBackupFileSize(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	for _, tc := range []struct {
+		liveBytes, expected int64
+	}{
+		{liveBytes: 0, expected: 100},
+		{liveBytes: 100, expected: 100},
+		{liveBytes: 101, expected: 51},
+		{liveBytes: 250, expected: 84},
+		{liveBytes: 1000, expected: 100},
+	} {
+		t.Run(fmt.Sprint(tc.liveBytes), func(t *testing.T) {
+			require.Equal(t, tc.expected, evenBackupFileSize(tc.liveBytes, 100))
+		})
+	}
+}
+
+func TestBackupStatsAwareFileSizing(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 1000
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, multiNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `ALTER TABLE data.bank SPLIT AT SELECT generate_series(100, $1, 100)`, numAccounts)
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.stats_aware_file_sizing.enabled = true`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.file_size = '10KiB'`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+
+	var files int
+	sqlDB.QueryRow(t, `SELECT count(*) FROM [SHOW BACKUP FILES FROM LATEST IN $1]`, localFoo).Scan(&files)
+	require.Greater(t, files, 1)
+
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = 'data2'`, localFoo)
+	sqlDB.CheckQueryResults(t, `SELECT count(*), sum(balance) FROM data2.bank`,
+		sqlDB.QueryStr(t, `SELECT count(*), sum(balance) FROM data.bank`))
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
@@ -64,8 +65,39 @@ const (
 	telemetryOptionSkipMissingSequences      = "skip_missing_sequences"
 	telemetryOptionSkipMissingSequenceOwners = "skip_missing_sequence_owners"
 	telemetryOptionSkipMissingViews          = "skip_missing_views"
+	telemetryOptionRestoreFKToExisting       = "restore_fk_to_existing"
 	telemetryOptionSkipLocalitiesCheck       = "skip_localities_check"
 	telemetryOptionSchemaOnly                = "schema_only"
+	telemetryOptionLatestValue               = "latest_value"
+	telemetryOptionLatestAsOf                = "latest_as_of"
+	telemetryOptionMinLatestTime             = "min_latest_time"
+	telemetryOptionExcludeTables             = "exclude_tables"
+	telemetryOptionIncludeTables             = "include_tables"
+	telemetryOptionRowFilter                 = "row_filter"
+	telemetryOptionSkipStatistics            = "skip_statistics"
+	telemetryOptionSkipComments              = "skip_comments"
+	telemetryOptionSkipZoneConfigs           = "skip_zone_configs"
+	telemetryOptionSkipJobs                  = "skip_jobs"
+	telemetryOptionPauseTTLSchedules         = "pause_ttl_schedules"
+	telemetryOptionRemapRegions              = "remap_regions"
+	telemetryOptionComments                  = "comments"
+	telemetryOptionZoneConfigs               = "zone_configs"
+	telemetryOptionJobs                      = "jobs"
+	telemetryOptionDecryptQuorum             = "decrypt_quorum"
+	telemetryOptionSubdirFormat              = "subdir_format"
+	telemetryOptionOnConflict                = "on_conflict"
+	telemetryOptionPerTableFiles             = "per_table_files"
+	telemetryOptionRelyOnEncryptionAtRest    = "rely_on_encryption_at_rest"
+	telemetryOptionMetadataURI               = "metadata_uri"
+	telemetryOptionDeferredData              = "deferred_data"
+	telemetryOptionConsolidateChanges        = "consolidate_changes"
+	telemetryOptionHighPriority              = "high_priority"
+	telemetryOptionAllowMissingLocalities    = "allow_missing_localities"
+	telemetryOptionCoordinator               = "coordinator"
+	telemetryOptionCompression               = "compression"
+	telemetryOptionSkipIfUnchanged           = "skip_if_unchanged"
+	telemetryOptionAppToken                  = "app_token"
+	telemetryOptionDeferConstraintValidation = "defer_constraint_validation"
 )
 
 // logBackupTelemetry publishes an eventpb.RecoveryEvent about a manually
@@ -105,7 +137,7 @@ func createBackupRecoveryEvent(
 	timeBaseSubdir := true
 	var subdirType string
 	if _, err := time.Parse(backupbase.DateBasedIntoFolderName,
-		initialDetails.Destination.Subdir); err != nil {
+		initialDetails.Destination.Subdir); err != nil && initialDetails.Destination.SubdirFormat == "" {
 		timeBaseSubdir = false
 	}
 
@@ -152,6 +184,63 @@ func createBackupRecoveryEvent(
 	if initialDetails.Detached {
 		options = append(options, telemetryOptionDetached)
 	}
+	if len(initialDetails.ExcludedTablePatterns) > 0 {
+		options = append(options, telemetryOptionExcludeTables)
+	}
+	if len(initialDetails.IncludedTablePatterns) > 0 {
+		options = append(options, telemetryOptionIncludeTables)
+	}
+	if initialDetails.RowFilter != "" {
+		options = append(options, telemetryOptionRowFilter)
+	}
+	if initialDetails.SkipStatistics {
+		options = append(options, telemetryOptionSkipStatistics)
+	}
+	if initialDetails.IncludeComments {
+		options = append(options, telemetryOptionComments)
+	}
+	if initialDetails.IncludeZoneConfigs {
+		options = append(options, telemetryOptionZoneConfigs)
+	}
+	if initialDetails.IncludeJobs {
+		options = append(options, telemetryOptionJobs)
+	}
+	if enc := initialDetails.EncryptionOptions; enc != nil && enc.DecryptQuorum > 1 {
+		options = append(options, telemetryOptionDecryptQuorum)
+	}
+	if initialDetails.Destination.SubdirFormat != "" {
+		options = append(options, telemetryOptionSubdirFormat)
+	}
+	if initialDetails.PerTableFiles {
+		options = append(options, telemetryOptionPerTableFiles)
+	}
+	if initialDetails.EncryptionAtRestOnly {
+		options = append(options, telemetryOptionRelyOnEncryptionAtRest)
+	}
+	if initialDetails.ConsolidateChanges {
+		options = append(options, telemetryOptionConsolidateChanges)
+	}
+	if initialDetails.HighPriority {
+		options = append(options, telemetryOptionHighPriority)
+	}
+	if initialDetails.Destination.MetadataURI != "" {
+		options = append(options, telemetryOptionMetadataURI)
+	}
+	if initialDetails.AllowMissingLocalities {
+		options = append(options, telemetryOptionAllowMissingLocalities)
+	}
+	if initialDetails.Coordination != nil {
+		options = append(options, telemetryOptionCoordinator)
+	}
+	if initialDetails.Compression != "" {
+		options = append(options, telemetryOptionCompression)
+	}
+	if initialDetails.SkipIfUnchanged {
+		options = append(options, telemetryOptionSkipIfUnchanged)
+	}
+	if initialDetails.AppToken != "" {
+		options = append(options, telemetryOptionAppToken)
+	}
 
 	event := eventpb.RecoveryEvent{
 		RecoveryType:            recoveryType,
@@ -217,10 +306,15 @@ func getPassphraseAndKMS(
 		case jobspb.EncryptionMode_Passphrase:
 			passphrase = true
 		case jobspb.EncryptionMode_KMS:
-			if enc.KMSInfo != nil {
-				parsedKMSURI, err := url.ParseRequestURI(enc.KMSInfo.Uri)
+			kmsInfo := enc.KMSInfo
+			if kmsInfo == nil && len(enc.KeyShares) > 0 {
+				kmsInfo = &enc.KeyShares[0]
+			}
+			if kmsInfo != nil {
+				parsedKMSURI, err := url.ParseRequestURI(kmsInfo.Uri)
 				if err != nil {
-					log.Warningf(ctx, "failed to parse KMS URI %s: %v", enc.KMSInfo.Uri, err)
+					log.Warningf(ctx, "failed to parse KMS URI %s: %v",
+						backuputils.RedactURIForErrorMessage(kmsInfo.Uri), backuputils.RedactURLParseError(err))
 				} else {
 					kms = parsedKMSURI.Scheme
 				}
@@ -237,7 +331,8 @@ func parseStorageAndAuth(uri string) (string, string, error) {
 	authType := cloud.AuthParamSpecified
 	parsedURI, err := url.Parse(uri)
 	if err != nil {
-		return "", "", errors.Wrapf(err, "failed to parse URI %s for telemetry", uri)
+		return "", "", errors.Wrapf(backuputils.RedactURLParseError(err),
+			"failed to parse URI %s for telemetry", backuputils.RedactURIForErrorMessage(uri))
 	}
 	storageType = parsedURI.Scheme
 	auth := parsedURI.Query().Get(cloud.AuthParam)
@@ -385,6 +480,9 @@ func logRestoreTelemetry(
 	if opts.SkipMissingFKs {
 		options = append(options, telemetryOptionSkipMissingFK)
 	}
+	if opts.RestoreFKToExisting {
+		options = append(options, telemetryOptionRestoreFKToExisting)
+	}
 	if opts.SkipMissingViews {
 		options = append(options, telemetryOptionSkipMissingViews)
 	}
@@ -403,6 +501,45 @@ func logRestoreTelemetry(
 	if opts.SchemaOnly {
 		options = append(options, telemetryOptionSchemaOnly)
 	}
+	if opts.DeferredData {
+		options = append(options, telemetryOptionDeferredData)
+	}
+	if opts.LatestValue != nil {
+		options = append(options, telemetryOptionLatestValue)
+	}
+	if opts.LatestAsOf != nil {
+		options = append(options, telemetryOptionLatestAsOf)
+	}
+	if opts.MinLatestTime != nil {
+		options = append(options, telemetryOptionMinLatestTime)
+	}
+	if opts.SkipStatistics {
+		options = append(options, telemetryOptionSkipStatistics)
+	}
+	if opts.SkipComments {
+		options = append(options, telemetryOptionSkipComments)
+	}
+	if opts.SkipZoneConfigs {
+		options = append(options, telemetryOptionSkipZoneConfigs)
+	}
+	if opts.SkipJobs {
+		options = append(options, telemetryOptionSkipJobs)
+	}
+	if opts.PauseTTLSchedules {
+		options = append(options, telemetryOptionPauseTTLSchedules)
+	}
+	if opts.RemapRegions != nil {
+		options = append(options, telemetryOptionRemapRegions)
+	}
+	if opts.OnConflict != nil {
+		options = append(options, telemetryOptionOnConflict)
+	}
+	if opts.MetadataURI != nil {
+		options = append(options, telemetryOptionMetadataURI)
+	}
+	if opts.DeferConstraintValidation {
+		options = append(options, telemetryOptionDeferConstraintValidation)
+	}
 	sort.Strings(options)
 
 	event := &eventpb.RecoveryEvent{
@@ -14,11 +14,14 @@ import (
 	gosql "database/sql"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"math"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
@@ -181,6 +184,24 @@ func TestBackupRestoreSingleNodeLocal(t *testing.T) {
 	backupAndRestore(ctx, t, tc, []string{localFoo}, []string{localFoo}, numAccounts)
 }
 
+// TestBackupRestoreSpilledFileList tests a backup whose list of files exceeds
+// its memory budget, and so is spilled to temporary storage.
+func TestBackupRestoreSpilledFileList(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 1000
+	ctx := context.Background()
+	tc, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, multiNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.file_size = '1'`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.file_list_memory_budget = '1'`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.checkpoint_interval = '0'`)
+
+	backupAndRestore(ctx, t, tc, []string{localFoo}, []string{localFoo}, numAccounts)
+}
+
 func TestBackupRestoreMultiNodeLocal(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -1625,6 +1646,38 @@ func TestBackupRestoreResume(t *testing.T) {
 			sqlDB.QueryStr(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE data.bank`),
 		)
 	})
+
+	t.Run("restore-completed-spans", func(t *testing.T) {
+		sqlDB := sqlutils.MakeSQLRunner(outerDB.DB)
+		restoreDir := "nodelocal://0/restore-completed-spans-directory"
+		sqlDB.Exec(t, `BACKUP DATABASE DATA TO $1`, restoreDir)
+		sqlDB.Exec(t, `CREATE DATABASE restoredb2`)
+		restoreDatabaseID := sqlutils.QueryDatabaseID(t, sqlDB.DB, "restoredb2")
+		restoreTableID, err := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig).
+			DescIDGenerator.GenerateUniqueDescID(ctx)
+		require.NoError(t, err)
+		tablePrefix := keys.SystemSQLCodec.TablePrefix(uint32(backupTableDesc.GetID()))
+		createAndWaitForJob(
+			t, sqlDB, []descpb.ID{restoreTableID},
+			jobspb.RestoreDetails{
+				DescriptorRewrites: map[descpb.ID]*jobspb.DescriptorRewrite{
+					backupTableDesc.GetID(): {
+						ParentID:       descpb.ID(restoreDatabaseID),
+						ParentSchemaID: descpb.ID(restoreDatabaseID + 1),
+						ID:             restoreTableID,
+					},
+				},
+				URIs: []string{restoreDir},
+			},
+			jobspb.RestoreProgress{
+				CompletedSpans: []roachpb.Span{{Key: tablePrefix, EndKey: tablePrefix.PrefixEnd()}},
+			},
+		)
+		// The whole table was recorded as completed, so none of it is restored.
+		var restoredCount int64
+		sqlDB.QueryRow(t, `SELECT count(*) FROM restoredb2.bank`).Scan(&restoredCount)
+		require.Equal(t, int64(0), restoredCount)
+	})
 }
 
 // TestBackupRestoreControlJob tests that PAUSE JOB, RESUME JOB, and CANCEL JOB
@@ -4307,6 +4360,109 @@ func TestRegionalKMSEncryptedBackup(t *testing.T) {
 	})
 }
 
+// TestDecryptQuorumEncryptedBackup performs a BACKUP whose data key is split
+// across three KMSs with decrypt_quorum = 2, and checks that it can be
+// restored with any two of them but not with a single one.
+func TestDecryptQuorumEncryptedBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	_, sqlDB, rawDir, cleanupFn := backupRestoreTestSetup(t, multiNode, 3, InitManualReplication)
+	defer cleanupFn()
+
+	setupBackupEncryptedTest(ctx, t, sqlDB)
+	kmsURIs := constructMockKMSURIsWithKeyID([]string{"abc", "def", "ghi"})
+	sqlDB.Exec(t, fmt.Sprintf(`BACKUP DATABASE neverappears INTO $1 WITH %s, decrypt_quorum = 2`,
+		concatMultiRegionKMSURIs(kmsURIs)), localFoo)
+	checkBackupFilesEncrypted(t, rawDir)
+	before := sqlDB.QueryStr(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE neverappears.neverappears`)
+
+	// An incremental backup only needs a quorum of the KMSs.
+	sqlDB.Exec(t, fmt.Sprintf(`BACKUP DATABASE neverappears INTO LATEST IN $1 WITH %s`,
+		concatMultiRegionKMSURIs(kmsURIs[1:])), localFoo)
+
+	for _, uris := range [][]string{kmsURIs[:2], kmsURIs[1:], {kmsURIs[0], kmsURIs[2]}} {
+		sqlDB.Exec(t, `DROP DATABASE neverappears CASCADE`)
+		sqlDB.Exec(t, fmt.Sprintf(`RESTORE DATABASE neverappears FROM LATEST IN $1 WITH %s`,
+			concatMultiRegionKMSURIs(uris)), localFoo)
+		sqlDB.CheckQueryResults(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE neverappears.neverappears`, before)
+	}
+
+	// SHOW BACKUP is passed the kms option once for each of the KMSs.
+	sqlDB.Exec(t, `SHOW BACKUP LATEST IN $1 WITH kms = $2, kms = $3`, localFoo, kmsURIs[0], kmsURIs[2])
+	sqlDB.ExpectErr(t, "decrypt_quorum = 2, but only 1 of the provided KMS URIs can be used",
+		`SHOW BACKUP LATEST IN $1 WITH kms = $2`, localFoo, kmsURIs[0])
+
+	sqlDB.Exec(t, `DROP DATABASE neverappears CASCADE`)
+	sqlDB.ExpectErr(t, "decrypt_quorum = 2, but only 1 of the provided KMS URIs can be used",
+		fmt.Sprintf(`RESTORE DATABASE neverappears FROM LATEST IN $1 WITH %s`,
+			concatMultiRegionKMSURIs(kmsURIs[:1])), localFoo)
+
+	sqlDB.ExpectErr(t, "decrypt_quorum must be between 1 and the number of KMS URIs, 3",
+		fmt.Sprintf(`BACKUP INTO $1 WITH %s, decrypt_quorum = 4`, concatMultiRegionKMSURIs(kmsURIs)),
+		localFoo+"/other")
+	sqlDB.ExpectErr(t, "decrypt_quorum requires the kms option",
+		`BACKUP INTO $1 WITH decrypt_quorum = 2`, localFoo+"/other")
+	sqlDB.ExpectErr(t, "cannot add a KMS to a backup encrypted with decrypt_quorum",
+		fmt.Sprintf(`ALTER BACKUP LATEST IN '%s' ADD NEW_KMS = '%s' WITH OLD_KMS = '%s'`,
+			localFoo, constructMockKMSURIsWithKeyID([]string{"jkl"})[0], kmsURIs[0]))
+}
+
+func TestBackupRestoreKMSByLocality(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 300
+	args := base.TestClusterArgs{ServerArgsPerNode: map[int]base.TestServerArgs{}}
+	for i, region := range []string{"west", "east", "east"} {
+		args.ServerArgsPerNode[i] = base.TestServerArgs{
+			Locality: roachpb.Locality{Tiers: []roachpb.Tier{{Key: "region", Value: region}}},
+		}
+	}
+	ctx := context.Background()
+	_, sqlDB, rawDir, cleanupFn := backupRestoreTestSetupWithParams(t, multiNode, numAccounts,
+		InitManualReplication, args)
+	defer cleanupFn()
+
+	// Move the lease of part of the table to a node in region=east, so that its
+	// data is written to the destination of that locality.
+	for _, stmt := range []string{
+		`ALTER TABLE data.bank SPLIT AT VALUES (100)`,
+		`ALTER TABLE data.bank EXPERIMENTAL_RELOCATE VALUES (ARRAY[2], 100)`,
+	} {
+		testutils.SucceedsSoon(t, func() error {
+			_, err := sqlDB.DB.ExecContext(ctx, stmt)
+			return err
+		})
+	}
+
+	collections := []interface{}{
+		fmt.Sprintf("%s/default?COCKROACH_LOCALITY=%s", localFoo, url.QueryEscape("default")),
+		fmt.Sprintf("%s/east?COCKROACH_LOCALITY=%s", localFoo, url.QueryEscape("region=east")),
+	}
+	kmsURIs := constructMockKMSURIsWithKeyID([]string{"abc", "def"})
+	opts := fmt.Sprintf(`kms = '%s', kms_by_locality = ('region=east' = '%s')`, kmsURIs[0], kmsURIs[1])
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO ($1, $2) WITH `+opts, collections...)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN ($1, $2) WITH `+opts, collections...)
+	checkBackupFilesEncrypted(t, rawDir)
+	before := sqlDB.QueryStr(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE data.bank`)
+
+	sqlDB.Exec(t, `DROP DATABASE data CASCADE`)
+	sqlDB.ExpectErr(t, "locality region=east .* must be passed with kms_by_locality",
+		fmt.Sprintf(`RESTORE DATABASE data FROM LATEST IN ($1, $2) WITH kms = '%s'`, kmsURIs[0]),
+		collections...)
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM LATEST IN ($1, $2) WITH `+opts, collections...)
+	sqlDB.CheckQueryResults(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE data.bank`, before)
+
+	sqlDB.ExpectErr(t, "kms_by_locality can only be used with locality-aware destinations",
+		`BACKUP DATABASE data INTO $1 WITH `+opts, localFoo+"/other")
+	sqlDB.ExpectErr(t, "kms_by_locality requires the kms option",
+		fmt.Sprintf(`BACKUP DATABASE data INTO ($1, $2) WITH kms_by_locality = ('region=east' = '%s')`,
+			kmsURIs[1]), collections...)
+}
+
 type testKMSEnv struct {
 	settings         *cluster.Settings
 	externalIOConfig *base.ExternalIODirConfig
@@ -8055,7 +8211,7 @@ func TestReadBackupManifestMemoryMonitoring(t *testing.T) {
 	require.NoError(t, backupinfo.WriteBackupManifest(ctx, storage, "testmanifest", encOpts,
 		&kmsEnv, desc))
 	_, sz, err := backupinfo.ReadBackupManifest(ctx, &mem, storage, "testmanifest",
-		encOpts, &kmsEnv)
+		encOpts, &kmsEnv, false /* elideFiles */)
 	require.NoError(t, err)
 	mem.Shrink(ctx, sz)
 	mem.Close(ctx)
@@ -9704,10 +9860,12 @@ func TestBackupNoOverwriteCheckpoint(t *testing.T) {
 	// to list from.
 	r, err := backupdest.FindLatestFile(ctx, store)
 	require.NoError(t, err)
-	latest, err := ioctx.ReadAll(ctx, r)
-	latestFilePath := (string)(latest)
+	data, err := ioctx.ReadAll(ctx, r)
 	require.NoError(t, err)
 	r.Close(ctx)
+	latest, err := backupdest.DecodeLatestFile(data)
+	require.NoError(t, err)
+	latestFilePath := latest.Subdir
 
 	var actualNumCheckpointsWritten int
 	require.NoError(t, store.List(ctx, latestFilePath+"/progress/", "", func(f string) error {
@@ -10448,3 +10606,438 @@ $$;
 	require.NoError(t, err)
 
 }
+
+// TestExportFrontier checks that the export frontier of a backup tracks the
+// spans that are yet to be exported, separately for introduced spans.
+func TestExportFrontier(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	sp := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	file := func(span roachpb.Span, introduced bool) backuppb.BackupManifest_File {
+		f := backuppb.BackupManifest_File{Span: span}
+		if introduced {
+			// Introduced spans are exported from the beginning of time up to the
+			// start of the incremental backup.
+			f.EndTime = hlc.Timestamp{WallTime: 1}
+		}
+		return f
+	}
+
+	manifest := &backuppb.BackupManifest{
+		Spans:           []roachpb.Span{sp("a", "f")},
+		IntroducedSpans: []roachpb.Span{sp("c", "d")},
+		Files:           []backuppb.BackupManifest_File{file(sp("a", "b"), false)},
+	}
+	frontier := makeExportFrontier(manifest)
+	require.Equal(t, []roachpb.Span{sp("b", "f")}, frontier.remaining())
+
+	// An incremental export of an introduced span does not cover it.
+	frontier.add(file(sp("b", "d"), false))
+	require.Equal(t, []roachpb.Span{sp("c", "f")}, frontier.remaining())
+
+	frontier.add(file(sp("c", "d"), true), file(sp("d", "f"), false))
+	require.Empty(t, frontier.remaining())
+}
+
+// TestSplitSpansByTable checks that spans holding the data of several tables
+// are split at the boundaries between them, which per_table_files backups rely
+// on to write the data of each table to separate files.
+func TestSplitSpansByTable(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	for name, codec := range map[string]keys.SQLCodec{
+		"system":    keys.SystemSQLCodec,
+		"secondary": keys.MakeSQLCodec(roachpb.MakeTenantID(10)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			index := func(tableID, indexID uint32) roachpb.Key {
+				return codec.IndexPrefix(tableID, indexID)
+			}
+			spans := []roachpb.Span{
+				// A span within a single table is left as is.
+				{Key: index(100, 1), EndKey: index(100, 2)},
+				// A span across three tables is split in three.
+				{Key: index(101, 2), EndKey: index(103, 2)},
+				// A span ending at the start of the next table is not split.
+				{Key: index(104, 1), EndKey: codec.TablePrefix(105)},
+			}
+			require.Equal(t, []roachpb.Span{
+				{Key: index(100, 1), EndKey: index(100, 2)},
+				{Key: index(101, 2), EndKey: codec.TablePrefix(102)},
+				{Key: codec.TablePrefix(102), EndKey: codec.TablePrefix(103)},
+				{Key: codec.TablePrefix(103), EndKey: index(103, 2)},
+				{Key: index(104, 1), EndKey: codec.TablePrefix(105)},
+			}, splitSpansByTable(codec, spans))
+
+			require.Equal(t, descpb.ID(101), tableIDForKey(codec, index(101, 2)))
+		})
+	}
+
+	// Spans outside of the table key space, such as tenant spans in a backup of
+	// the system tenant, are not split.
+	tenantSpan := roachpb.Span{
+		Key:    keys.MakeTenantPrefix(roachpb.MakeTenantID(10)),
+		EndKey: keys.MakeTenantPrefix(roachpb.MakeTenantID(11)),
+	}
+	require.Equal(t, []roachpb.Span{tenantSpan},
+		splitSpansByTable(keys.SystemSQLCodec, []roachpb.Span{tenantSpan}))
+	require.Equal(t, descpb.ID(0), tableIDForKey(keys.SystemSQLCodec, tenantSpan.Key))
+}
+
+// TestExternalIOAudit checks that the accesses to external storage made by
+// backup and restore jobs are recorded once the audit is enabled. The accesses
+// are written asynchronously, once flushed.
+func TestExternalIOAudit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://0/unaudited'`)
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM crdb_internal.external_io_audit`, [][]string{{"0"}})
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING cloud.external_io_audit.flush_interval = '10ms'`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING cloud.external_io_audit.enabled = true`)
+	var backupJobID jobspb.JobID
+	sqlDB.QueryRow(t, `BACKUP DATABASE data INTO 'nodelocal://0/audited' WITH detached`).Scan(&backupJobID)
+	jobutils.WaitForJobToSucceed(t, sqlDB, backupJobID)
+
+	sqlDB.CheckQueryResultsRetry(t, fmt.Sprintf(`
+SELECT DISTINCT principal, operation, uri LIKE 'nodelocal://%%/audited%%'
+  FROM crdb_internal.external_io_audit WHERE job_id = %d AND bytes > 0`, backupJobID),
+		[][]string{{"root", "write", "true"}})
+
+	var restoreJobID jobspb.JobID
+	sqlDB.QueryRow(t, `RESTORE DATABASE data FROM LATEST IN 'nodelocal://0/audited' WITH new_db_name = 'data2', detached`).Scan(&restoreJobID)
+	jobutils.WaitForJobToSucceed(t, sqlDB, restoreJobID)
+
+	sqlDB.CheckQueryResultsRetry(t, fmt.Sprintf(`
+SELECT count(*) > 0 FROM crdb_internal.external_io_audit
+ WHERE job_id = %d AND principal = 'root' AND operation = 'read'`, restoreJobID),
+		[][]string{{"true"}})
+}
+
+// TestBackupLatestWebhook checks that the webhook set in
+// bulkio.backup.latest_webhook.url is notified of the backups that update a
+// LATEST file, and that the failure policy applies once retries are exhausted.
+func TestBackupLatestWebhook(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	var mu syncutil.Mutex
+	var payloads []latestWebhookPayload
+	var failures int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var p latestWebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payloads = append(payloads, p)
+	}))
+	defer srv.Close()
+
+	const numAccounts = 10
+	params := base.TestClusterArgs{}
+	params.ServerArgs.Knobs.BackupRestore = &sql.BackupRestoreTestingKnobs{
+		LatestWebhookClient: srv.Client(),
+	}
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetupWithParams(t, singleNode, numAccounts, InitManualReplication, params)
+	defer cleanupFn()
+
+	sqlDB.ExpectErr(t, "must use https",
+		`SET CLUSTER SETTING bulkio.backup.latest_webhook.url = 'http://example.com'`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.latest_webhook.url = $1`, srv.URL)
+
+	// A failed notification is retried.
+	mu.Lock()
+	failures = 1
+	mu.Unlock()
+	var jobID jobspb.JobID
+	sqlDB.QueryRow(t, `BACKUP DATABASE data INTO 'nodelocal://0/foo' WITH detached`).Scan(&jobID)
+	jobutils.WaitForJobToSucceed(t, sqlDB, jobID)
+
+	// Incremental backups do not update LATEST.
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN 'nodelocal://0/foo'`)
+
+	var subdir string
+	sqlDB.QueryRow(t, `SELECT max(path) FROM [SHOW BACKUPS IN 'nodelocal://0/foo']`).Scan(&subdir)
+	mu.Lock()
+	require.Len(t, payloads, 1)
+	require.Equal(t, jobID, payloads[0].JobID)
+	require.Equal(t, strings.TrimPrefix(subdir, "/"), strings.TrimPrefix(payloads[0].Subdir, "/"))
+	require.Equal(t, int64(numAccounts), payloads[0].Rows)
+	require.NotEmpty(t, payloads[0].Files)
+	for _, f := range payloads[0].Files {
+		require.NotZero(t, f.Size)
+	}
+	mu.Unlock()
+
+	// Once retries are exhausted, the backup only fails if the policy says so.
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.latest_webhook.max_retries = 0`)
+	mu.Lock()
+	failures = 2
+	mu.Unlock()
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://0/foo'`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.latest_webhook.on_failure = 'fail'`)
+	sqlDB.ExpectErr(t, "notifying bulkio.backup.latest_webhook.url",
+		`BACKUP DATABASE data INTO 'nodelocal://0/foo'`)
+	mu.Lock()
+	require.Len(t, payloads, 1)
+	mu.Unlock()
+}
+
+// TestBackupMetadataURI checks that SHOW BACKUP and RESTORE resolve LATEST and
+// the backups of a chain in the replica of a collection given with
+// metadata_uri, while reading their data files from the collection itself.
+func TestBackupMetadataURI(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, dir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://1/primary'`)
+	sqlDB.Exec(t, `INSERT INTO data.bank VALUES (1000, 1, 'inc')`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN 'nodelocal://1/primary'`)
+
+	// Replicate everything but the data files of the backups, which are then
+	// only readable from the collection.
+	primaryDir, replicaDir := filepath.Join(dir, "primary"), filepath.Join(dir, "replica")
+	require.NoError(t, filepath.Walk(primaryDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(primaryDir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(replicaDir, rel), 0755)
+		}
+		if strings.Contains(rel, "/data/") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(replicaDir, rel), content, 0644)
+	}))
+
+	// The replica has not caught up on the latest backup of the collection.
+	sqlDB.Exec(t, `INSERT INTO data.bank VALUES (1001, 1, 'unreplicated')`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://1/primary'`)
+
+	var layers int
+	sqlDB.QueryRow(t, `SELECT count(DISTINCT end_time) FROM [SHOW BACKUP FROM LATEST IN 'nodelocal://1/primary'
+WITH metadata_uri = 'nodelocal://1/replica']`).Scan(&layers)
+	require.Equal(t, 2, layers)
+	sqlDB.Exec(t, `SHOW BACKUP FROM LATEST IN 'nodelocal://1/primary'
+WITH check_files, metadata_uri = 'nodelocal://1/replica'`)
+
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM LATEST IN 'nodelocal://1/primary'
+WITH new_db_name = 'data2', metadata_uri = 'nodelocal://1/replica'`)
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM data2.bank`, [][]string{{"11"}})
+
+	sqlDB.ExpectErr(t, `"metadata_uri" cannot be used with "incremental_location"`,
+		`RESTORE DATABASE data FROM LATEST IN 'nodelocal://1/primary'
+WITH new_db_name = 'data3', metadata_uri = 'nodelocal://1/replica', incremental_location = 'nodelocal://1/inc'`)
+	sqlDB.ExpectErr(t, `"metadata_uri" can only be used with the following syntax`,
+		`SHOW BACKUP 'nodelocal://1/primary' WITH metadata_uri = 'nodelocal://1/replica'`)
+}
+
+// TestRestoreDeferredData checks that a schema_only restore run with
+// deferred_data publishes the restored tables right away and then loads their
+// data in a second job.
+func TestRestoreDeferredData(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://1/deferred'`)
+
+	sqlDB.ExpectErr(t, "to set the deferred_data option, the schema_only option must be set",
+		`RESTORE DATABASE data FROM LATEST IN 'nodelocal://1/deferred' WITH new_db_name = 'data2', deferred_data`)
+	sqlDB.ExpectErr(t, "cannot be used with verify_backup_table_data",
+		`RESTORE DATABASE data FROM LATEST IN 'nodelocal://1/deferred'
+WITH new_db_name = 'data2', schema_only, verify_backup_table_data, deferred_data`)
+	sqlDB.ExpectErr(t, "can only be used to restore tables and databases",
+		`RESTORE FROM LATEST IN 'nodelocal://1/deferred' WITH schema_only, deferred_data`)
+
+	var schemaJobID jobspb.JobID
+	sqlDB.QueryRow(t, `RESTORE DATABASE data FROM LATEST IN 'nodelocal://1/deferred'
+WITH new_db_name = 'data2', schema_only, deferred_data, detached`).Scan(&schemaJobID)
+	jobutils.WaitForJobToSucceed(t, sqlDB, schemaJobID)
+
+	var dataJobID jobspb.JobID
+	sqlDB.QueryRow(t, `SELECT job_id FROM [SHOW JOBS] WHERE description LIKE $1`,
+		fmt.Sprintf("loading the data of restore job %d%%", schemaJobID)).Scan(&dataJobID)
+	jobutils.WaitForJobToSucceed(t, sqlDB, dataJobID)
+
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM data2.bank`, [][]string{{"10"}})
+	sqlDB.CheckQueryResults(t,
+		`SELECT * FROM data2.bank ORDER BY id`, sqlDB.QueryStr(t, `SELECT * FROM data.bank ORDER BY id`))
+}
+
+// TestBackupWithMetadataURI checks that a backup written with metadata_uri
+// keeps its LATEST file and manifests in the metadata collection and its data
+// files in the collection, and that it is restored through both.
+func TestBackupWithMetadataURI(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, dir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://1/bulk' WITH metadata_uri = 'nodelocal://1/meta'`)
+	sqlDB.Exec(t, `INSERT INTO data.bank VALUES (1000, 1, 'inc')`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN 'nodelocal://1/bulk' WITH metadata_uri = 'nodelocal://1/meta'`)
+
+	var manifests, dataFiles []string
+	for _, collection := range []string{"bulk", "meta"} {
+		require.NoError(t, filepath.Walk(filepath.Join(dir, collection), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch {
+			case info.Name() == backupbase.BackupManifestName:
+				manifests = append(manifests, collection)
+			case strings.HasSuffix(info.Name(), ".sst") && strings.Contains(path, "/data/"):
+				dataFiles = append(dataFiles, collection)
+			}
+			return nil
+		}))
+	}
+	require.Equal(t, []string{"meta", "meta"}, manifests)
+	require.NotEmpty(t, dataFiles)
+	for _, collection := range dataFiles {
+		require.Equal(t, "bulk", collection)
+	}
+
+	var layers int
+	sqlDB.QueryRow(t, `SELECT count(DISTINCT end_time) FROM [SHOW BACKUP FROM LATEST IN 'nodelocal://1/bulk'
+WITH metadata_uri = 'nodelocal://1/meta']`).Scan(&layers)
+	require.Equal(t, 2, layers)
+	sqlDB.Exec(t, `SHOW BACKUP FROM LATEST IN 'nodelocal://1/bulk'
+WITH check_files, metadata_uri = 'nodelocal://1/meta'`)
+
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM LATEST IN 'nodelocal://1/bulk'
+WITH new_db_name = 'data2', metadata_uri = 'nodelocal://1/meta'`)
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM data2.bank`, [][]string{{"11"}})
+
+	sqlDB.ExpectErr(t, `was written apart from its metadata`,
+		`RESTORE DATABASE data FROM LATEST IN 'nodelocal://1/meta' WITH new_db_name = 'data3'`)
+	sqlDB.ExpectErr(t, `cannot append a backup without metadata_uri to a backup chain written with it`,
+		`BACKUP DATABASE data INTO LATEST IN 'nodelocal://1/meta'`)
+	sqlDB.ExpectErr(t, `"metadata_uri" cannot be used with "incremental_location"`,
+		`BACKUP DATABASE data INTO 'nodelocal://1/bulk'
+WITH metadata_uri = 'nodelocal://1/meta', incremental_location = 'nodelocal://1/inc'`)
+	sqlDB.ExpectErr(t, `"metadata_uri" option is only supported with the`,
+		`BACKUP DATABASE data TO 'nodelocal://1/old' WITH metadata_uri = 'nodelocal://1/meta'`)
+}
+
+// TestRestoreJobErrorRedactsURIs checks that the secrets of the URIs in the
+// error that a restore job fails with are redacted out of the error persisted
+// in the job.
+func TestRestoreJobErrorRedactsURIs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	faults := cloud.NewFaultInjector()
+	params := base.TestClusterArgs{}
+	params.ServerArgs.Knobs.Cloud = &cloud.TestingKnobs{FaultInjector: faults}
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetupWithParams(t, singleNode, 10, InitManualReplication, params)
+	defer cleanupFn()
+
+	const collection = localFoo + "?AWS_SECRET_ACCESS_KEY=hunter2"
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, collection)
+
+	// Fail the reads of the data files with an error holding the URI of the
+	// file, as the errors of some storage providers do.
+	faults.AddRule(cloud.FaultInjectionRule{
+		Op:      cloud.AccessRead,
+		Pattern: "data/*.sst",
+		Err:     errors.Newf("reading %s: access denied", collection),
+	})
+	var jobID jobspb.JobID
+	sqlDB.QueryRow(t, `RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = 'data2', detached`,
+		collection).Scan(&jobID)
+	jobutils.WaitForJobToFail(t, sqlDB, jobID)
+
+	var jobErr string
+	sqlDB.QueryRow(t, `SELECT error FROM [SHOW JOB $1]`, jobID).Scan(&jobErr)
+	require.Contains(t, jobErr, "access denied")
+	require.Contains(t, jobErr, "AWS_SECRET_ACCESS_KEY=redacted")
+	require.NotContains(t, jobErr, "hunter2")
+}
+
+// TestIncrementalBackupKeepsLatestFile checks that an incremental backup into
+// a chain does not rewrite the LATEST file, which could otherwise name its
+// chain again after a full backup into the same collection started a newer
+// one, and that the size of the chain is still read from the chain size files
+// its layers record.
+func TestIncrementalBackupKeepsLatestFile(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	tc, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, localFoo, username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+	readLatest := func() backuppb.LatestFile {
+		latest, err := backupdest.ReadLatestFile(ctx, localFoo,
+			execCfg.DistSQLSrv.ExternalStorageFromURI, username.RootUserName())
+		require.NoError(t, err)
+		return latest
+	}
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+	older := readLatest()
+	require.Equal(t, int32(1), older.Chain.NumLayers)
+
+	// An incremental backup into the most recent chain leaves its LATEST file
+	// as the full backup wrote it, and records the size of the chain apart.
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, localFoo)
+	require.Equal(t, older, readLatest())
+	size, found, err := backupdest.ReadLatestChainSize(ctx, store, older)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, int32(2), size.NumLayers)
+	require.True(t, older.Chain.EndTime.Less(size.EndTime))
+
+	// An incremental backup into an older chain, finishing after the full
+	// backup of a newer one, does not name the older chain again.
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+	newer := readLatest()
+	require.NotEqual(t, older.Subdir, newer.Subdir)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1 IN $2`, older.Subdir, localFoo)
+	require.Equal(t, newer, readLatest())
+	size, found, err = backupdest.ReadLatestChainSize(ctx, store, older)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, int32(3), size.NumLayers)
+}
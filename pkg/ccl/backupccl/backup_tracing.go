@@ -0,0 +1,57 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Backup and restore jobs run each of their phases in a span of its own, a
+// child of the span the job is run in. Like every other span, these are
+// exported to the tracing collector the cluster is configured with (see
+// trace.opentelemetry.collector, trace.jaeger.agent and trace.zipkin.collector),
+// so the time a job spends in each phase shows up in an existing tracing stack.
+// The spans are tagged with the ID of the job and the collection it backs up
+// into or restores from, stripped of credentials, so that the phases of a job
+// or of the jobs of a collection can be found there.
+
+// The phases of backup and restore jobs.
+const (
+	backupPhaseResolveDestination = "backup.resolve_destination"
+	backupPhaseExport             = "backup.export"
+	backupPhaseWriteSST           = "backup.write_sst"
+	backupPhaseWriteManifest      = "backup.write_manifest"
+	restorePhaseResolveBackups    = "restore.resolve_backups"
+	restorePhaseIngest            = "restore.ingest"
+)
+
+// The tags of the spans of the phases of backup and restore jobs.
+const (
+	jobPhaseJobIDTag      = "job-id"
+	jobPhaseCollectionTag = "collection"
+)
+
+// startJobPhaseSpan starts the span of phase of the backup or restore job with
+// the given ID, which reads or writes collection. The caller is responsible for
+// finishing the span.
+func startJobPhaseSpan(
+	ctx context.Context, phase string, jobID jobspb.JobID, collection string,
+) (context.Context, *tracing.Span) {
+	ctx, sp := tracing.ChildSpan(ctx, phase)
+	sp.SetTag(jobPhaseJobIDTag, attribute.Int64Value(int64(jobID)))
+	if collection != "" {
+		sp.SetTag(jobPhaseCollectionTag, attribute.StringValue(backuputils.RedactURIForErrorMessage(collection)))
+	}
+	return ctx, sp
+}
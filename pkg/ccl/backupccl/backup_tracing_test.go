@@ -0,0 +1,49 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing/tracingpb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartJobPhaseSpan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tr := tracing.NewTracer()
+	ctx, getRecording := tracing.ContextWithRecordingSpan(context.Background(), tr, "job")
+
+	_, sp := startJobPhaseSpan(ctx, backupPhaseExport, jobspb.JobID(42),
+		"s3://bucket/collection?AWS_ACCESS_KEY_ID=id&AWS_SECRET_ACCESS_KEY=secret")
+	sp.Finish()
+
+	rec, ok := getRecording().FindSpan(backupPhaseExport)
+	require.True(t, ok)
+	tags := rec.FindTagGroup(tracingpb.AnonymousTagGroupName)
+	require.NotNil(t, tags)
+	jobID, ok := tags.FindTag(jobPhaseJobIDTag)
+	require.True(t, ok)
+	require.Equal(t, "42", jobID)
+	collection, ok := tags.FindTag(jobPhaseCollectionTag)
+	require.True(t, ok)
+	require.Contains(t, collection, "s3://bucket/collection")
+	require.NotContains(t, collection, "secret")
+
+	// The span of a job that is not traced is a no-op.
+	_, sp = startJobPhaseSpan(context.Background(), backupPhaseExport, jobspb.JobID(42), "")
+	sp.Finish()
+}
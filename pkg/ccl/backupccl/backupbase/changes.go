@@ -0,0 +1,24 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupbase
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// ChangefeedKV is a KV written by a changefeed with format=backup_kv, as a
+// line of JSON in its output files, to be consolidated into an incremental
+// backup.
+type ChangefeedKV struct {
+	Key roachpb.Key `json:"key"`
+	// Value is the raw bytes of the value of the KV, empty for a deletion.
+	Value     []byte        `json:"value,omitempty"`
+	Timestamp hlc.Timestamp `json:"ts"`
+}
@@ -43,4 +43,38 @@ const (
 	// DefaultIncrementalsSubdir is the default name of the subdirectory to which
 	// incremental backups will be written.
 	DefaultIncrementalsSubdir = "incrementals"
+
+	// TenantBackupsSubdir is the subdirectory of a collection under which
+	// BACKUP VIRTUAL CLUSTER ALL writes the collection of each tenant.
+	TenantBackupsSubdir = "tenants"
+
+	// TenantBackupSummariesDirectory is the directory of a collection where
+	// BACKUP VIRTUAL CLUSTER ALL writes the TenantBackupSummary of each run.
+	TenantBackupSummariesDirectory = backupMetadataDirectory + "/" + "tenant-summaries"
+
+	// ChainSizesDirectory is the directory of a collection where each layer of
+	// a backup chain writes the cumulative size of the chain, under the
+	// subdirectory of the chain.
+	ChainSizesDirectory = backupMetadataDirectory + "/" + "sizes"
+
+	// CollectionFingerprintName is the name of the file in a collection which
+	// records the cluster backing up into it.
+	CollectionFingerprintName = backupMetadataDirectory + "/" + "FINGERPRINT"
+
+	// CollectionGenerationName is the name of the file in a collection which
+	// records the generation of the most recent full backup into it.
+	CollectionGenerationName = backupMetadataDirectory + "/" + "GENERATION"
+
+	// CollectionProtectionsDirectory is the directory of a collection where
+	// each restore reading backups in it registers the layers it reads.
+	CollectionProtectionsDirectory = backupMetadataDirectory + "/" + "protections"
+
+	// CollectionEventsDirectory is the directory of a collection where the
+	// events of the collection are recorded.
+	CollectionEventsDirectory = "events"
+
+	// ChangesDirectory is the subdirectory of the incrementals of a backup chain
+	// into which a changefeed with format=backup_kv writes the changes that
+	// BACKUP ... WITH consolidate_changes turns into incremental backups.
+	ChangesDirectory = "changes"
 )
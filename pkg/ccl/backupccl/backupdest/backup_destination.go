@@ -9,20 +9,23 @@
 package backupdest
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
 	"net/url"
 	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
-	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
@@ -34,7 +37,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/errors"
@@ -44,6 +49,12 @@ const (
 	// DefaultLocalityValue is the default locality tag used in a locality aware
 	// backup/restore when an explicit COCKROACH_LOCALITY is not specified.
 	DefaultLocalityValue = "default"
+	// FailoverLocalityValue is the locality tag of the secondary collection of
+	// a `BACKUP ... INTO ... FAILOVER (<primary>, <secondary>)` backup, which
+	// also tags the files that were written to it. No node has this locality,
+	// so files are only written to the secondary when writing them to the
+	// primary fails.
+	FailoverLocalityValue = "crdb_backup_failover=secondary"
 	// includeManifest is a named const that can be passed to FindPriorBackups.
 	includeManifest = true
 	// OmitManifest is a named const that can be passed to FindPriorBackups.
@@ -98,6 +109,46 @@ type ResolvedDestination struct {
 
 	// PrevBackupURIs is the list of full paths for previous backups in the chain.
 	PrevBackupURIs []string
+
+	// ChangesURI is the directory of the incrementals of the chain into which a
+	// changefeed with format=backup_kv writes the changes to consolidate into
+	// incremental backups. It is only set for an incremental backup.
+	ChangesURI string
+
+	// DataURI is the location the data files of the backup are written to if
+	// the destination has a metadata collection, in which case the other URIs
+	// are locations in the metadata collection. It is empty otherwise.
+	DataURI string
+}
+
+// ResolveOptions are the inputs to ResolveDest.
+type ResolveOptions struct {
+	// User is the user the destination is resolved as.
+	User username.SQLUsername
+
+	// Destination is the destination of the backup as specified by the user.
+	Destination jobspb.BackupDetails_Destination
+
+	// EndTime is the time the backup is as of, which names the subdirectory of
+	// an incremental backup. If it is unset, the current time of Clock is used.
+	EndTime hlc.Timestamp
+
+	// Clock is used when EndTime is unset. If nil, the clock of the
+	// ExecutorConfig is used.
+	Clock *hlc.Clock
+
+	// IncrementalFrom is the list of previous backups given by the deprecated
+	// `BACKUP TO ... INCREMENTAL FROM` syntax.
+	IncrementalFrom []string
+
+	// AllowFullBackupUserSubdir, if set, overrides the
+	// bulkio.backup.deprecated_full_backup_with_subdir.enabled cluster setting.
+	AllowFullBackupUserSubdir *bool
+
+	// DryRun skips the checks that a backup can be written to the resolved
+	// destination, so that a destination can be inspected without failing
+	// because of the backups already in it.
+	DryRun bool
 }
 
 // ResolveDest resolves the true destination of a backup. The backup command
@@ -109,15 +160,43 @@ type ResolvedDestination struct {
 // explicitly, or due to the auto-append feature), it will resolve the
 // encryption options based on the base backup, as well as find all previous
 // backup manifests in the backup chain.
+//
+// If the destination has a metadata collection, the backup is resolved in it,
+// as that is where the LATEST file and the manifests of the chain are, and the
+// data of the backup is placed at the same path in the collection.
 func ResolveDest(
-	ctx context.Context,
-	user username.SQLUsername,
-	dest jobspb.BackupDetails_Destination,
-	endTime hlc.Timestamp,
-	incrementalFrom []string,
-	execCfg *sql.ExecutorConfig,
+	ctx context.Context, execCfg *sql.ExecutorConfig, opts ResolveOptions,
 ) (ResolvedDestination, error) {
+	if metadataURI := opts.Destination.MetadataURI; metadataURI != "" {
+		dataCollectionURI := opts.Destination.To[0]
+		opts.Destination.To = []string{metadataURI}
+		opts.Destination.MetadataURI = ""
+		resolved, err := ResolveDest(ctx, execCfg, opts)
+		if err != nil {
+			return ResolvedDestination{}, err
+		}
+		dataURIs, err := RebaseURIs([]string{resolved.DefaultURI}, metadataURI, dataCollectionURI)
+		if err != nil {
+			return ResolvedDestination{}, err
+		}
+		resolved.DataURI = dataURIs[0]
+		return resolved, nil
+	}
+
 	makeCloudStorage := execCfg.DistSQLSrv.ExternalStorageFromURI
+	user, dest, incrementalFrom := opts.User, opts.Destination, opts.IncrementalFrom
+	endTime := opts.EndTime
+	if endTime.IsEmpty() {
+		clock := opts.Clock
+		if clock == nil {
+			clock = execCfg.Clock
+		}
+		endTime = clock.Now()
+	}
+	allowFullBackupUserSubdir := featureFullBackupUserSubdir.Get(execCfg.SV())
+	if opts.AllowFullBackupUserSubdir != nil {
+		allowFullBackupUserSubdir = *opts.AllowFullBackupUserSubdir
+	}
 
 	defaultURI, _, err := GetURIsByLocalityKV(dest.To, "")
 	if err != nil {
@@ -135,7 +214,19 @@ func ResolveDest(
 			if err != nil {
 				return ResolvedDestination{}, err
 			}
-			chosenSuffix = latest
+			chosenSuffix = latest.Subdir
+		}
+
+		if !opts.DryRun {
+			collection, err := makeCloudStorage(ctx, collectionURI, user)
+			if err != nil {
+				return ResolvedDestination{}, err
+			}
+			defer collection.Close()
+			if err := claimCollection(ctx, collection, collectionURI, execCfg.NodeInfo.LogicalClusterID(),
+				collectionMismatchPolicy(collectionClusterMismatch.Get(execCfg.SV()))); err != nil {
+				return ResolvedDestination{}, err
+			}
 		}
 	}
 
@@ -168,16 +259,17 @@ func ResolveDest(
 	if err != nil {
 		return ResolvedDestination{}, err
 	}
-	if exists && !dest.Exists && chosenSuffix != "" {
+	if exists && !dest.Exists && chosenSuffix != "" && !opts.DryRun {
 		// We disallow a user from writing a full backup to a path in a collection containing an
 		// existing backup iff we're 99.9% confident this backup was planned on a 22.1 node.
 		return ResolvedDestination{},
 			errors.Newf("A full backup already exists in %s. "+
 				"Consider running an incremental backup to this full backup via `BACKUP INTO '%s' IN '%s'`",
-				plannedBackupDefaultURI, chosenSuffix, dest.To[0])
+				backuputils.RedactURIForErrorMessage(plannedBackupDefaultURI), chosenSuffix,
+				backuputils.RedactURIForErrorMessage(dest.To[0]))
 
 	} else if !exists {
-		if dest.Exists {
+		if dest.Exists && !opts.DryRun {
 			// Implies the user passed a subdirectory in their backup command, either
 			// explicitly or using LATEST; however, we could not find an existing
 			// backup in that subdirectory.
@@ -187,7 +279,7 @@ func ResolveDest(
 			// enabled' to true.
 			// - 22.2+: the backup will fail unconditionally.
 			// TODO (msbutler): throw error in 22.2
-			if !featureFullBackupUserSubdir.Get(execCfg.SV()) {
+			if !allowFullBackupUserSubdir {
 				return ResolvedDestination{},
 					errors.Errorf("A full backup cannot be written to %q, a user defined subdirectory. "+
 						"To take a full backup, remove the subdirectory from the backup command "+
@@ -239,8 +331,8 @@ func ResolveDest(
 	for _, prior := range priors {
 		priorURI, err := url.Parse(priorsDefaultURI)
 		if err != nil {
-			return ResolvedDestination{}, errors.Wrapf(err, "parsing default backup location %s",
-				priorsDefaultURI)
+			return ResolvedDestination{}, errors.Wrapf(backuputils.RedactURLParseError(err),
+				"parsing default backup location %s", backuputils.RedactURIForErrorMessage(priorsDefaultURI))
 		}
 		priorURI.Path = backuputils.JoinURLPath(priorURI.Path, prior)
 		prevBackupURIs = append(prevBackupURIs, priorURI.String())
@@ -253,6 +345,10 @@ func ResolveDest(
 	if err != nil {
 		return ResolvedDestination{}, err
 	}
+	changesURI, _, err := GetURIsByLocalityKV(fullyResolvedIncrementalsLocation, "/"+backupbase.ChangesDirectory)
+	if err != nil {
+		return ResolvedDestination{}, err
+	}
 
 	return ResolvedDestination{
 		CollectionURI:    collectionURI,
@@ -260,20 +356,24 @@ func ResolveDest(
 		ChosenSubdir:     chosenSuffix,
 		URIsByLocalityKV: urisByLocalityKV,
 		PrevBackupURIs:   prevBackupURIs,
+		ChangesURI:       changesURI,
 	}, nil
 }
 
-// ReadLatestFile reads the LATEST file from collectionURI and returns the path
-// stored in the file.
+// ReadLatestFile reads the LATEST file from collectionURI and returns the
+// chain it names. The chain is summarized unless the file was written by an
+// older version, in which case only its Subdir is set.
 func ReadLatestFile(
 	ctx context.Context,
 	collectionURI string,
 	makeCloudStorage cloud.ExternalStorageFromURIFactory,
 	user username.SQLUsername,
-) (string, error) {
+) (backuppb.LatestFile, error) {
+	redactedURI := backuputils.RedactURIForErrorMessage(collectionURI)
 	collection, err := makeCloudStorage(ctx, collectionURI, user)
 	if err != nil {
-		return "", err
+		return backuppb.LatestFile{}, errors.Wrapf(backuputils.RedactURLParseError(err),
+			"opening backup collection %s", redactedURI)
 	}
 	defer collection.Close()
 
@@ -281,18 +381,24 @@ func ReadLatestFile(
 
 	if err != nil {
 		if errors.Is(err, cloud.ErrFileDoesNotExist) {
-			return "", pgerror.Wrapf(err, pgcode.UndefinedFile, "path does not contain a completed latest backup")
+			return backuppb.LatestFile{}, pgerror.Wrapf(err, pgcode.UndefinedFile,
+				"path %s does not contain a completed latest backup", redactedURI)
 		}
-		return "", pgerror.WithCandidateCode(err, pgcode.Io)
+		return backuppb.LatestFile{}, pgerror.WithCandidateCode(err, pgcode.Io)
+	}
+	data, err := ioctx.ReadAll(ctx, latestFile)
+	latestFile.Close(ctx)
+	if err != nil {
+		return backuppb.LatestFile{}, errors.Wrapf(err, "reading LATEST file in %s", redactedURI)
 	}
-	latest, err := ioctx.ReadAll(ctx, latestFile)
+	latest, err := DecodeLatestFile(data)
 	if err != nil {
-		return "", err
+		return backuppb.LatestFile{}, errors.Wrapf(err, "decoding LATEST file in %s", redactedURI)
 	}
-	if len(latest) == 0 {
-		return "", errors.Errorf("malformed LATEST file")
+	if latest.Subdir == "" {
+		return backuppb.LatestFile{}, errors.Errorf("malformed LATEST file in %s", redactedURI)
 	}
-	return string(latest), nil
+	return latest, nil
 }
 
 // FindLatestFile returns a ioctx.ReaderCloserCtx of the most recent LATEST
@@ -310,14 +416,13 @@ func FindLatestFile(
 
 	// We name files such that the most recent latest file will always
 	// be at the top, so just grab the first filename.
-	err := exportStore.List(ctx, backupbase.LatestHistoryDirectory, "", func(p string) error {
-		p = strings.TrimPrefix(p, "/")
-		latestFile = p
-		latestFileFound = true
-		// We only want the first latest file so return an error that it is
-		// done listing.
-		return cloud.ErrListingDone
-	})
+	err := exportStore.ListWithOptions(ctx, backupbase.LatestHistoryDirectory,
+		cloud.ListOptions{MaxResults: 1}, func(p string) error {
+			p = strings.TrimPrefix(p, "/")
+			latestFile = p
+			latestFileFound = true
+			return nil
+		})
 	// If the list failed because the storage used does not support listing,
 	// such as http, we can try reading the non-timestamped backup latest
 	// file directly. This can still fail if it is a mixed cluster and the
@@ -327,7 +432,7 @@ func FindLatestFile(
 		if err == nil {
 			return r, nil
 		}
-	} else if err != nil && !errors.Is(err, cloud.ErrListingDone) {
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -344,17 +449,46 @@ func FindLatestFile(
 	return r, nil
 }
 
-// WriteNewLatestFile writes a new LATEST file to both the base directory
-// and latest-history directory, depending on cluster version.
+// WriteNewLatestFile writes a new LATEST file naming latest, encoded with
+// EncodeLatestFile, to both the base directory and latest-history directory,
+// depending on cluster version. The update is recorded in the event log of the
+// collection.
 func WriteNewLatestFile(
-	ctx context.Context, settings *cluster.Settings, exportStore cloud.ExternalStorage, suffix string,
+	ctx context.Context,
+	settings *cluster.Settings,
+	exportStore cloud.ExternalStorage,
+	latest backuppb.LatestFile,
 ) error {
-	// HTTP storage does not support listing and so we cannot rely on the
-	// above-mentioned List method to return us the most recent latest file.
-	// Instead, we disregard write once semantics and always read and write
-	// a non-timestamped latest file for HTTP.
-	if exportStore.Conf().Provider == cloudpb.ExternalStorageProvider_http {
-		return cloud.WriteFile(ctx, exportStore, backupbase.LatestFileName, strings.NewReader(suffix))
+	if err := writeNewLatestFile(ctx, settings, exportStore, latest); err != nil {
+		return err
+	}
+	ev := CollectionEvent{Type: CollectionEventLatestUpdated, Subdir: latest.Subdir}
+	if !latest.Chain.EndTime.IsEmpty() {
+		ev.EndTime = latest.Chain.EndTime.AsOfSystemTime()
+	}
+	if err := RecordCollectionEvent(ctx, settings, exportStore, ev); err != nil {
+		log.Warningf(ctx, "failed to record the update of the LATEST file: %v", err)
+	}
+	return nil
+}
+
+func writeNewLatestFile(
+	ctx context.Context,
+	settings *cluster.Settings,
+	exportStore cloud.ExternalStorage,
+	latest backuppb.LatestFile,
+) error {
+	data, err := EncodeLatestFile(ctx, settings, latest)
+	if err != nil {
+		return err
+	}
+
+	// Storage that does not support listing, such as HTTP storage or plugins
+	// that do not list, cannot rely on the above-mentioned List method to
+	// return us the most recent latest file. Instead, we disregard write once
+	// semantics and always read and write a non-timestamped latest file.
+	if !cloud.SupportsListing(exportStore) {
+		return cloud.WriteFile(ctx, exportStore, backupbase.LatestFileName, bytes.NewReader(data))
 	}
 
 	// We timestamp the latest files in order to enforce write once backups.
@@ -363,7 +497,7 @@ func WriteNewLatestFile(
 	// sorted to the top. This will be the last latest file we write. It
 	// Takes the one's complement of the timestamp so that files are sorted
 	// lexicographically such that the most recent is always the top.
-	return cloud.WriteFile(ctx, exportStore, newTimestampedLatestFileName(), strings.NewReader(suffix))
+	return cloud.WriteFile(ctx, exportStore, newTimestampedLatestFileName(), bytes.NewReader(data))
 }
 
 // newTimestampedLatestFileName returns a string of a new latest filename
@@ -401,10 +535,214 @@ func CheckForLatestFileInCollection(
 	return true, nil
 }
 
+// ValidateLatestSubdir checks that subdir within the collection at
+// collectionURI contains a backup. It is used when LATEST is pinned to a
+// caller-supplied subdirectory instead of being read from the LATEST file.
+func ValidateLatestSubdir(
+	ctx context.Context,
+	collectionURI string,
+	subdir string,
+	makeCloudStorage cloud.ExternalStorageFromURIFactory,
+	user username.SQLUsername,
+) error {
+	uris, err := backuputils.AppendPaths([]string{collectionURI}, subdir)
+	if err != nil {
+		return backuputils.RedactURLParseError(err)
+	}
+	redactedURI := backuputils.RedactURIForErrorMessage(uris[0])
+	store, err := makeCloudStorage(ctx, uris[0], user)
+	if err != nil {
+		return errors.Wrapf(err, "opening backup %s", redactedURI)
+	}
+	defer store.Close()
+
+	exists, err := containsManifest(ctx, store)
+	if err != nil {
+		return pgerror.WithCandidateCode(errors.Wrapf(err, "reading backup %s", redactedURI), pgcode.Io)
+	}
+	if !exists {
+		return pgerror.Newf(pgcode.UndefinedFile,
+			"path %s does not contain a completed backup", redactedURI)
+	}
+	return nil
+}
+
+// FindLatestSubdirAsOf returns the subdirectory of the most recent full backup
+// in the collection at collectionURI that was taken at or before asOf. It is
+// an alternative to the LATEST file for callers that need to resolve LATEST
+// deterministically, e.g. when a just-written LATEST file may not yet be
+// visible on an eventually consistent store.
+//
+// Only subdirectories named by BACKUP INTO, which encode the end time of the
+// full backup, are considered, and those of backups that failed or are still
+// running, which hold neither a manifest nor a metadata SST, are skipped.
+func FindLatestSubdirAsOf(
+	ctx context.Context,
+	collectionURI string,
+	asOf time.Time,
+	makeCloudStorage cloud.ExternalStorageFromURIFactory,
+	user username.SQLUsername,
+) (string, error) {
+	redactedURI := backuputils.RedactURIForErrorMessage(collectionURI)
+	collection, err := makeCloudStorage(ctx, collectionURI, user)
+	if err != nil {
+		return "", errors.Wrapf(backuputils.RedactURLParseError(err),
+			"opening backup collection %s", redactedURI)
+	}
+	defer collection.Close()
+
+	fullBackups, err := ListFullBackupsInCollection(ctx, collection)
+	if err != nil {
+		return "", pgerror.WithCandidateCode(
+			errors.Wrapf(err, "listing backups in %s", redactedURI), pgcode.Io)
+	}
+
+	type candidate struct {
+		subdir  string
+		endTime time.Time
+	}
+	var candidates []candidate
+	for _, subdir := range fullBackups {
+		if !strings.HasPrefix(subdir, "/") {
+			subdir = "/" + subdir
+		}
+		endTime, err := time.Parse(backupbase.DateBasedIntoFolderName, subdir)
+		if err != nil {
+			// Not a subdirectory chosen by BACKUP INTO.
+			continue
+		}
+		if endTime.After(asOf) {
+			continue
+		}
+		candidates = append(candidates, candidate{subdir: subdir, endTime: endTime})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].endTime.After(candidates[j].endTime)
+	})
+	for _, c := range candidates {
+		completed, err := containsCompletedBackup(ctx, collection, c.subdir)
+		if err != nil {
+			return "", pgerror.WithCandidateCode(
+				errors.Wrapf(err, "reading backup %s%s", redactedURI, c.subdir), pgcode.Io)
+		}
+		if completed {
+			return c.subdir, nil
+		}
+	}
+	return "", pgerror.Newf(pgcode.UndefinedFile,
+		"path %s does not contain a full backup taken at or before %s", redactedURI, asOf)
+}
+
+// containsCompletedBackup returns whether the subdirectory subdir of the
+// collection holds the manifest or the metadata SST of a backup, which are only
+// written once the backup completes.
+func containsCompletedBackup(
+	ctx context.Context, collection cloud.ExternalStorage, subdir string,
+) (bool, error) {
+	for _, name := range []string{backupbase.BackupManifestName, backupinfo.MetadataSSTName} {
+		r, err := collection.ReadFile(ctx, path.Join(strings.TrimPrefix(subdir, "/"), name))
+		if err != nil {
+			if errors.Is(err, cloud.ErrFileDoesNotExist) {
+				continue
+			}
+			return false, err
+		}
+		r.Close(ctx)
+		return true, nil
+	}
+	return false, nil
+}
+
+// WaitForLatestFile reads the LATEST file of the collection at collectionURI
+// until it names a chain which ends at or after minEndTime. Right after a
+// backup, a stale cache or an eventually consistent store may still return the
+// LATEST file of the previous chain, or none at all, so both are retried until
+// ctx is done.
+func WaitForLatestFile(
+	ctx context.Context,
+	collectionURI string,
+	minEndTime hlc.Timestamp,
+	makeCloudStorage cloud.ExternalStorageFromURIFactory,
+	user username.SQLUsername,
+) (backuppb.LatestFile, error) {
+	redactedURI := backuputils.RedactURIForErrorMessage(collectionURI)
+	opts := retry.Options{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+	var lastErr error
+	for r := retry.StartWithCtx(ctx, opts); r.Next(); {
+		latest, err := ReadLatestFile(ctx, collectionURI, makeCloudStorage, user)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if pgerror.GetPGCode(err) != pgcode.UndefinedFile {
+				return backuppb.LatestFile{}, err
+			}
+			lastErr = err
+			continue
+		}
+		ok, err := LatestFileEndsAtOrAfter(latest, minEndTime)
+		if err != nil {
+			return backuppb.LatestFile{}, errors.Wrapf(err, "reading LATEST file in %s", redactedURI)
+		}
+		if !ok {
+			// The LATEST file only summarizes its chain as of its full backup;
+			// the incremental layers added since are recorded in the chain
+			// size files of the collection.
+			ok, err = chainEndsAtOrAfter(ctx, collectionURI, latest, minEndTime, makeCloudStorage, user)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return backuppb.LatestFile{}, errors.Wrapf(err,
+					"reading size of backup chain %s in %s", latest.Subdir, redactedURI)
+			}
+		}
+		if ok {
+			return latest, nil
+		}
+		lastErr = pgerror.Newf(pgcode.UndefinedFile,
+			"LATEST file in %s names %s, which ends before %s",
+			redactedURI, latest.Subdir, minEndTime.AsOfSystemTime())
+	}
+	if lastErr == nil {
+		return backuppb.LatestFile{}, ctx.Err()
+	}
+	return backuppb.LatestFile{}, errors.Wrapf(lastErr,
+		"waiting for the LATEST file in %s to name a backup ending at or after %s",
+		redactedURI, minEndTime.AsOfSystemTime())
+}
+
+// chainEndsAtOrAfter returns true if the most recent layer of the chain latest
+// names, as recorded in the chain size files of the collection at
+// collectionURI, ends at or after ts.
+func chainEndsAtOrAfter(
+	ctx context.Context,
+	collectionURI string,
+	latest backuppb.LatestFile,
+	ts hlc.Timestamp,
+	makeCloudStorage cloud.ExternalStorageFromURIFactory,
+	user username.SQLUsername,
+) (bool, error) {
+	collection, err := makeCloudStorage(ctx, collectionURI, user)
+	if err != nil {
+		return false, err
+	}
+	defer collection.Close()
+	size, found, err := ReadLatestChainSize(ctx, collection, latest)
+	if err != nil || !found {
+		return false, err
+	}
+	return ts.LessEq(size.EndTime), nil
+}
+
 func getLocalityAndBaseURI(uri, appendPath string) (string, string, error) {
 	parsedURI, err := url.Parse(uri)
 	if err != nil {
-		return "", "", err
+		return "", "", backuputils.RedactURLParseError(err)
 	}
 	q := parsedURI.Query()
 	localityKV := q.Get(cloud.LocalityURLParam)
@@ -472,6 +810,76 @@ func GetURIsByLocalityKV(
 	return defaultURI, urisByLocalityKV, nil
 }
 
+// FailoverDestinations returns the locality aware destination URIs that a
+// `BACKUP ... INTO ... FAILOVER (<primary>, <secondary>)` backup is written to:
+// the primary collection is the default locality, and the secondary collection
+// is tagged with FailoverLocalityValue.
+func FailoverDestinations(primary, secondary string) ([]string, error) {
+	to := make([]string, 0, 2)
+	for _, dest := range []struct {
+		uri        string
+		localityKV string
+	}{
+		{uri: primary, localityKV: DefaultLocalityValue},
+		{uri: secondary, localityKV: FailoverLocalityValue},
+	} {
+		parsedURI, err := url.Parse(dest.uri)
+		if err != nil {
+			return nil, backuputils.RedactURLParseError(err)
+		}
+		q := parsedURI.Query()
+		if q.Get(cloud.LocalityURLParam) != "" {
+			return nil, errors.Errorf("%s is not supported for FAILOVER destinations",
+				cloud.LocalityURLParam)
+		}
+		q.Set(cloud.LocalityURLParam, dest.localityKV)
+		parsedURI.RawQuery = q.Encode()
+		to = append(to, parsedURI.String())
+	}
+	return to, nil
+}
+
+// subdirFormatJobID is replaced by the ID of the backup job in a subdir_format.
+const subdirFormatJobID = "{job_id}"
+
+// FormatSubdir returns the name of the subdirectory of the full backup taken
+// at endTime by job jobID, according to format. The format is a Go time layout
+// in which subdirFormatJobID is replaced by the job ID.
+func FormatSubdir(format string, endTime time.Time, jobID jobspb.JobID) string {
+	parts := strings.Split(format, subdirFormatJobID)
+	for i := range parts {
+		parts[i] = endTime.UTC().Format(parts[i])
+	}
+	subdir := strings.Join(parts, strconv.FormatInt(int64(jobID), 10))
+	return "/" + strings.TrimPrefix(subdir, "/")
+}
+
+// ValidateSubdirFormat checks that the full backup subdirectory names
+// generated from format can be found by SHOW BACKUPS and RESTORE, and that
+// they differ between backups. ResolveDest rejects the name if a full backup
+// already exists under it nonetheless.
+func ValidateSubdirFormat(format string) error {
+	t := time.Date(2022, 11, 22, 13, 14, 15, 0, time.UTC)
+	subdir := FormatSubdir(format, t, 1)
+	dirs := strings.Split(strings.TrimPrefix(subdir, "/"), "/")
+	if len(dirs) != 3 {
+		return errors.Newf("subdir_format %q must name a subdirectory three levels deep, "+
+			"e.g. '2006/01/02-150405', to be listed by SHOW BACKUPS", format)
+	}
+	for _, dir := range dirs {
+		if dir == "" || dir == "." || dir == ".." {
+			return errors.Newf("subdir_format %q must not contain empty, '.' or '..' path elements", format)
+		}
+	}
+	// Backups taken a second apart by different jobs must not share a name.
+	if subdir == FormatSubdir(format, t.Add(time.Second), 1) &&
+		subdir == FormatSubdir(format, t, 2) {
+		return errors.Newf("subdir_format %q must include the seconds of the backup time "+
+			"or %s so that each full backup is written to a new subdirectory", format, subdirFormatJobID)
+	}
+	return nil
+}
+
 // ListFullBackupsInCollection lists full backup paths in the collection
 // of an export store
 func ListFullBackupsInCollection(
@@ -498,7 +906,9 @@ func ListFullBackupsInCollection(
 // manifests and metadata required to RESTORE. If only one layer is explicitly
 // provided, it is inspected to see if it contains "appended" layers internally
 // that are then expanded into the result layers returned, similar to if those
-// layers had been specified in `from` explicitly.
+// layers had been specified in `from` explicitly. If elideFiles is set, the
+// manifests are returned without their file lists, for callers that stream
+// them with backupinfo.NewLayerFileIter.
 func ResolveBackupManifests(
 	ctx context.Context,
 	mem *mon.BoundAccount,
@@ -511,6 +921,7 @@ func ResolveBackupManifests(
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
 	user username.SQLUsername,
+	elideFiles bool,
 ) (
 	defaultURIs []string,
 	// mainBackupManifests contains the manifest located at each defaultURI in the backup chain.
@@ -529,7 +940,7 @@ func ResolveBackupManifests(
 		}
 	}()
 	baseManifest, memSize, err := backupinfo.ReadBackupManifestFromStore(ctx, mem, baseStores[0],
-		encryption, kmsEnv)
+		encryption, kmsEnv, elideFiles)
 	if err != nil {
 		return nil, nil, nil, 0, err
 	}
@@ -593,7 +1004,7 @@ func ResolveBackupManifests(
 			enc = *encryption
 		}
 		defaultManifestsForEachLayer, _, memSize, err := backupinfo.FetchPreviousBackups(ctx, mem, user,
-			mkStore, defaultURIs[1:], enc, kmsEnv)
+			mkStore, defaultURIs[1:], enc, kmsEnv, elideFiles)
 		if err != nil {
 			return nil, nil, nil, 0, err
 		}
@@ -635,6 +1046,70 @@ func ResolveBackupManifests(
 	return validatedDefaultURIs, validatedMainBackupManifests, validatedLocalityInfo, totalMemSize, nil
 }
 
+// ResolveBackupChainEnds is a variant of ResolveBackupManifests for long
+// backup chains, whose incremental backups are in incSubdirs of the
+// incrementals directories. It returns the URIs of every layer of the chain but
+// only reads the manifests of its full backup and of its last incremental
+// backup, leaving those of the others and the locality info of all of them to
+// be resolved by the restore job.
+func ResolveBackupChainEnds(
+	ctx context.Context,
+	mem *mon.BoundAccount,
+	baseStores []cloud.ExternalStorage,
+	mkStore cloud.ExternalStorageFromURIFactory,
+	fullyResolvedBaseDirectory []string,
+	fullyResolvedIncrementalsDirectory []string,
+	incSubdirs []string,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	user username.SQLUsername,
+) (
+	defaultURIs []string,
+	// endManifests contains the manifests of the first and last layers.
+	endManifests []backuppb.BackupManifest,
+	reservedMemSize int64,
+	_ error,
+) {
+	ctx, sp := tracing.ChildSpan(ctx, "backupdest.ResolveBackupChainEnds")
+	defer sp.Finish()
+
+	if len(incSubdirs) == 0 {
+		return nil, nil, 0, errors.AssertionFailedf("backup chain has no incremental backups")
+	}
+	var ownedMemSize int64
+	defer func() {
+		if ownedMemSize != 0 {
+			mem.Shrink(ctx, ownedMemSize)
+		}
+	}()
+	baseManifest, memSize, err := backupinfo.ReadBackupManifestFromStore(ctx, mem, baseStores[0],
+		encryption, kmsEnv, false /* elideFiles */)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	ownedMemSize += memSize
+
+	defaultURIs = make([]string, len(incSubdirs)+1)
+	defaultURIs[0] = fullyResolvedBaseDirectory[0]
+	for i, incSubdir := range incSubdirs {
+		uris, err := backuputils.AppendPaths(fullyResolvedIncrementalsDirectory[:1], incSubdir)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		defaultURIs[i+1] = uris[0]
+	}
+	lastManifest, memSize, err := backupinfo.ReadBackupManifestFromURI(ctx, mem,
+		defaultURIs[len(defaultURIs)-1], user, mkStore, encryption, kmsEnv, false /* elideFiles */)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	ownedMemSize += memSize
+
+	totalMemSize := ownedMemSize
+	ownedMemSize = 0
+	return defaultURIs, []backuppb.BackupManifest{baseManifest, lastManifest}, totalMemSize, nil
+}
+
 // DeprecatedResolveBackupManifestsExplicitIncrementals reads the
 // BACKUP_MANIFEST files from the incremental backup locations that have been
 // explicitly provided by the user in `from`. The method uses the manifest file
@@ -686,7 +1161,7 @@ func DeprecatedResolveBackupManifestsExplicitIncrementals(
 
 		var memSize int64
 		mainBackupManifests[i], memSize, err = backupinfo.ReadBackupManifestFromStore(ctx, mem,
-			stores[0], encryption, kmsEnv)
+			stores[0], encryption, kmsEnv, false /* elideFiles */)
 		if err != nil {
 			return nil, nil, nil, 0, err
 		}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	_ "github.com/cockroachdb/cockroach/pkg/cloud/impl" // register cloud storage providers
@@ -27,7 +28,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -61,7 +64,8 @@ func TestBackupRestoreResolveDestination(t *testing.T) {
 		storage, err := externalStorageFromURI(ctx, collectionURI, username.RootUserName())
 		defer storage.Close()
 		require.NoError(t, err)
-		require.NoError(t, backupdest.WriteNewLatestFile(ctx, storage.Settings(), storage, latestBackupSuffix))
+		require.NoError(t, backupdest.WriteNewLatestFile(ctx, storage.Settings(), storage,
+			backuppb.LatestFile{Subdir: latestBackupSuffix}))
 	}
 
 	// localizeURI returns a slice of just the base URI if localities is nil.
@@ -123,13 +127,12 @@ func TestBackupRestoreResolveDestination(t *testing.T) {
 					defaultDest, localitiesDest, err := backupdest.GetURIsByLocalityKV(to, "")
 					require.NoError(t, err)
 
-					backupDest, err := backupdest.ResolveDest(
-						ctx, username.RootUserName(),
-						jobspb.BackupDetails_Destination{To: to},
-						endTime,
-						incrementalFrom,
-						&execCfg,
-					)
+					backupDest, err := backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+						User:            username.RootUserName(),
+						Destination:     jobspb.BackupDetails_Destination{To: to},
+						EndTime:         endTime,
+						IncrementalFrom: incrementalFrom,
+					})
 					require.NoError(t, err)
 
 					// Not an INTO backup, so no collection of suffix info.
@@ -190,13 +193,11 @@ func TestBackupRestoreResolveDestination(t *testing.T) {
 				) {
 					endTime := hlc.Timestamp{WallTime: backupTime.UnixNano()}
 
-					backupDest, err := backupdest.ResolveDest(
-						ctx, username.RootUserName(),
-						jobspb.BackupDetails_Destination{To: to},
-						endTime,
-						nil, /* incrementalFrom */
-						&execCfg,
-					)
+					backupDest, err := backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+						User:        username.RootUserName(),
+						Destination: jobspb.BackupDetails_Destination{To: to},
+						EndTime:     endTime,
+					})
 					require.NoError(t, err)
 
 					// Not a backup collection.
@@ -336,14 +337,13 @@ func TestBackupRestoreResolveDestination(t *testing.T) {
 					if expectedIncDir != "" {
 						fullBackupExists = true
 					}
-					backupDest, err := backupdest.ResolveDest(
-						ctx, username.RootUserName(),
-						jobspb.BackupDetails_Destination{To: collectionTo, Subdir: subdir,
+					backupDest, err := backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+						User: username.RootUserName(),
+						Destination: jobspb.BackupDetails_Destination{To: collectionTo, Subdir: subdir,
 							IncrementalStorage: incrementalTo, Exists: fullBackupExists},
-						endTime,
-						incrementalFrom,
-						&execCfg,
-					)
+						EndTime:         endTime,
+						IncrementalFrom: incrementalFrom,
+					})
 					require.NoError(t, err)
 
 					localityDests := make(map[string]string, len(localityCollections))
@@ -557,6 +557,267 @@ func TestBackupRestoreResolveDestination(t *testing.T) {
 			})
 		})
 	}
+
+	t.Run("options", func(t *testing.T) {
+		collection := fmt.Sprintf("nodelocal://1/%s?AUTH=implicit", t.Name())
+		existing, err := backuputils.AppendPaths([]string{collection}, "/2020/12/25-060000.00")
+		require.NoError(t, err)
+		writeManifest(t, existing[0])
+
+		// Writing a full backup to a subdirectory that already has one fails,
+		// unless it is a dry run.
+		dest := jobspb.BackupDetails_Destination{To: []string{collection}, Subdir: "/2020/12/25-060000.00"}
+		_, err = backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+			User:        username.RootUserName(),
+			Destination: dest,
+		})
+		require.ErrorContains(t, err, "A full backup already exists")
+		backupDest, err := backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+			User:        username.RootUserName(),
+			Destination: dest,
+			DryRun:      true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, existing[0], backupDest.DefaultURI)
+
+		// A full backup to a user defined subdirectory is only allowed if the
+		// deprecated behavior is enabled.
+		dest = jobspb.BackupDetails_Destination{To: []string{collection}, Subdir: "/user-subdir", Exists: true}
+		_, err = backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+			User:        username.RootUserName(),
+			Destination: dest,
+		})
+		require.ErrorContains(t, err, "a user defined subdirectory")
+		allow := true
+		backupDest, err = backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+			User:                      username.RootUserName(),
+			Destination:               dest,
+			AllowFullBackupUserSubdir: &allow,
+		})
+		require.NoError(t, err)
+		require.Nil(t, backupDest.PrevBackupURIs)
+	})
 }
 
 // TODO(pbardea): Add tests for resolveBackupCollection.
+
+// TestCollectionFingerprint checks that a backup into a collection records the
+// backing up cluster in the collection's fingerprint, and that a backup into a
+// collection owned by another cluster fails unless it is allowed to take the
+// collection over.
+func TestCollectionFingerprint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tc, sqlDB, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	clusterID := execCfg.NodeInfo.LogicalClusterID()
+
+	collection := fmt.Sprintf("nodelocal://1/%s?AUTH=implicit", t.Name())
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, collection, username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+
+	resolve := func(subdir string) error {
+		_, err := backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+			User:        username.RootUserName(),
+			Destination: jobspb.BackupDetails_Destination{To: []string{collection}, Subdir: subdir},
+		})
+		return err
+	}
+	requireFingerprint := func(expected backuppb.CollectionFingerprint) {
+		fingerprint, found, err := backupdest.ReadCollectionFingerprint(ctx, store)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, expected, fingerprint)
+	}
+
+	// The first backup into the collection claims it, and later ones leave the
+	// fingerprint as is.
+	require.NoError(t, resolve("/2020/12/25-060000.00"))
+	requireFingerprint(backuppb.CollectionFingerprint{ClusterID: clusterID, Generation: 1})
+	require.NoError(t, resolve("/2020/12/25-070000.00"))
+	requireFingerprint(backuppb.CollectionFingerprint{ClusterID: clusterID, Generation: 1})
+
+	// Another cluster took the collection over.
+	otherCluster := backuppb.CollectionFingerprint{ClusterID: uuid.MakeV4(), Generation: 2}
+	data, err := protoutil.Marshal(&otherCluster)
+	require.NoError(t, err)
+	require.NoError(t, cloud.WriteFile(ctx, store, backupbase.CollectionFingerprintName, bytes.NewReader(data)))
+
+	require.ErrorContains(t, resolve("/2020/12/25-080000.00"), "belongs to cluster")
+	requireFingerprint(otherCluster)
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.collection_cluster_mismatch = 'warn'`)
+	require.NoError(t, resolve("/2020/12/25-080000.00"))
+	requireFingerprint(backuppb.CollectionFingerprint{ClusterID: clusterID, Generation: 3})
+}
+
+// TestResolveDestInjectedFaults checks that ResolveDest fails with the errors
+// of the operations on the collection it relies on, as injected by the
+// cloud.debug.fault_injection setting.
+func TestResolveDestInjectedFaults(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tc, sqlDB, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+
+	collection := fmt.Sprintf("nodelocal://1/%s?AUTH=implicit", t.Name())
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, collection, username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, backupdest.WriteNewLatestFile(ctx, store.Settings(), store,
+		backuppb.LatestFile{Subdir: "/2020/12/25-060000.00"}))
+
+	resolve := func(subdir string) error {
+		_, err := backupdest.ResolveDest(ctx, &execCfg, backupdest.ResolveOptions{
+			User:        username.RootUserName(),
+			Destination: jobspb.BackupDetails_Destination{To: []string{collection}, Subdir: subdir},
+		})
+		return err
+	}
+	injectFaults := func(faults string) {
+		sqlDB.Exec(t, `SET CLUSTER SETTING cloud.debug.fault_injection = $1`, faults)
+	}
+	defer injectFaults("")
+
+	sqlDB.ExpectErr(t, "unknown operation",
+		`SET CLUSTER SETTING cloud.debug.fault_injection = 'rename:*'`)
+
+	// The fingerprint of the collection cannot be read, or written.
+	injectFaults("read:metadata/FINGERPRINT")
+	require.ErrorIs(t, resolve("/2020/12/25-070000.00"), cloud.ErrInjectedFault)
+	injectFaults("write:metadata/FINGERPRINT")
+	require.ErrorIs(t, resolve("/2020/12/25-070000.00"), cloud.ErrInjectedFault)
+
+	// The LATEST files cannot be listed.
+	injectFaults("list:metadata/latest")
+	require.ErrorIs(t, resolve(backupbase.LatestFileName), cloud.ErrInjectedFault)
+
+	// The second read of a LATEST file fails, but not the first.
+	injectFaults("read:metadata/latest/*:2")
+	require.NoError(t, resolve(backupbase.LatestFileName))
+	require.ErrorIs(t, resolve(backupbase.LatestFileName), cloud.ErrInjectedFault)
+	require.NoError(t, resolve(backupbase.LatestFileName))
+
+	// Slow reads are waited for.
+	injectFaults("read:metadata/latest/*:0:100ms")
+	start := timeutil.Now()
+	require.NoError(t, resolve(backupbase.LatestFileName))
+	require.GreaterOrEqual(t, timeutil.Since(start), 100*time.Millisecond)
+
+	injectFaults("")
+	require.NoError(t, resolve("/2020/12/25-070000.00"))
+}
+
+// TestFindLatestSubdirAsOf checks that LATEST is resolved as of a time to the
+// most recent completed full backup taken at or before it, skipping the
+// directories of backups that are still running.
+func TestFindLatestSubdirAsOf(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tc, _, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	collection := fmt.Sprintf("nodelocal://1/%s", t.Name())
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, collection, username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+
+	writeFile := func(name string) {
+		require.NoError(t, cloud.WriteFile(ctx, store, name, bytes.NewReader([]byte("contents"))))
+	}
+	// Two completed full backups, and a third that is still running and has
+	// only written its checkpoint and some of its data so far.
+	writeFile("2020/12/25-060000.00/" + backupbase.BackupManifestName)
+	writeFile("2020/12/25-070000.00/" + backupbase.BackupManifestName)
+	writeFile("2020/12/25-080000.00/progress/BACKUP-CHECKPOINT-1")
+	writeFile("2020/12/25-080000.00/data/1.sst")
+
+	find := func(asOf time.Time) (string, error) {
+		return backupdest.FindLatestSubdirAsOf(ctx, collection, asOf,
+			execCfg.DistSQLSrv.ExternalStorageFromURI, username.RootUserName())
+	}
+	day := time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		asOf     time.Time
+		expected string
+	}{
+		{asOf: day.Add(6 * time.Hour), expected: "/2020/12/25-060000.00"},
+		{asOf: day.Add(7*time.Hour - time.Second), expected: "/2020/12/25-060000.00"},
+		{asOf: day.Add(7 * time.Hour), expected: "/2020/12/25-070000.00"},
+		{asOf: day.Add(9 * time.Hour), expected: "/2020/12/25-070000.00"},
+	} {
+		subdir, err := find(c.asOf)
+		require.NoError(t, err)
+		require.Equal(t, c.expected, subdir, "as of %s", c.asOf)
+	}
+
+	_, err = find(day)
+	require.ErrorContains(t, err, "does not contain a full backup taken at or before")
+}
+
+func TestFormatSubdir(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	endTime := time.Date(2022, 6, 7, 8, 9, 10, 0, time.UTC)
+	for _, tc := range []struct {
+		format   string
+		expected string
+		err      string
+	}{
+		{format: "2006/01/02-150405-{job_id}", expected: "/2022/06/07-080910-123"},
+		{format: "/{job_id}/2006-01-02/150405", expected: "/123/2022-06-07/080910"},
+		{format: "daily/2006-01-02/{job_id}", expected: "/daily/2022-06-07/123"},
+		{format: "2006/01-02-150405", err: "three levels deep"},
+		{format: "2006/01/02/150405", err: "three levels deep"},
+		{format: "2006//150405", err: "must not contain empty"},
+		{format: "2006/01/02", err: "must include the seconds"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			err := backupdest.ValidateSubdirFormat(tc.format)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, backupdest.FormatSubdir(tc.format, endTime, 123))
+		})
+	}
+}
+
+func TestFailoverDestinations(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	to, err := backupdest.FailoverDestinations("nodelocal://1/primary", "s3://bucket/secondary?AUTH=implicit")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"nodelocal://1/primary?COCKROACH_LOCALITY=default",
+		"s3://bucket/secondary?AUTH=implicit&COCKROACH_LOCALITY=crdb_backup_failover%3Dsecondary",
+	}, to)
+
+	defaultURI, urisByLocalityKV, err := backupdest.GetURIsByLocalityKV(to, "/subdir")
+	require.NoError(t, err)
+	require.Equal(t, "nodelocal://1/primary/subdir", defaultURI)
+	require.Equal(t, map[string]string{
+		backupdest.FailoverLocalityValue: "s3://bucket/secondary/subdir?AUTH=implicit",
+	}, urisByLocalityKV)
+
+	_, err = backupdest.FailoverDestinations("nodelocal://1/primary?COCKROACH_LOCALITY=default", "nodelocal://1/secondary")
+	require.ErrorContains(t, err, "COCKROACH_LOCALITY is not supported for FAILOVER destinations")
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// chainSizeFilePrefix prefixes the names of the files a backup chain records
+// its size in.
+const chainSizeFilePrefix = "SIZE-"
+
+// chainSizeDir returns the directory of a collection in which the backup
+// chain in subdir records its size.
+func chainSizeDir(subdir string) string {
+	return path.Join(backupbase.ChainSizesDirectory, strings.TrimPrefix(subdir, "/"))
+}
+
+// chainSizeFileName returns the name of the file in which a layer of the
+// backup chain in subdir ending at endTime records the size of the chain. Like
+// the names of the LATEST files, the names sort such that the most recent
+// layer is listed first.
+func chainSizeFileName(subdir string, endTime hlc.Timestamp) string {
+	buf := encoding.EncodeUint64Descending(nil, uint64(endTime.WallTime))
+	buf = encoding.EncodeUint32Descending(buf, uint32(endTime.Logical))
+	return chainSizeDir(subdir) + "/" + chainSizeFilePrefix + hex.EncodeToString(buf)
+}
+
+// ReadChainSize returns the size of the backup chain in subdir of the
+// collection in store, as recorded by its most recent layer. It returns false
+// if no layer of the chain recorded its size, such as if the chain was started
+// before sizes were recorded or store does not support listing.
+func ReadChainSize(
+	ctx context.Context, store cloud.ExternalStorage, subdir string,
+) (backuppb.BackupChainSize, bool, error) {
+	var name string
+	err := store.List(ctx, chainSizeDir(subdir)+"/", "", func(p string) error {
+		p = strings.TrimPrefix(p, "/")
+		if !strings.HasPrefix(p, chainSizeFilePrefix) {
+			return nil
+		}
+		name = p
+		return cloud.ErrListingDone
+	})
+	if err != nil && !errors.Is(err, cloud.ErrListingDone) {
+		if errors.Is(err, cloud.ErrListingUnsupported) {
+			return backuppb.BackupChainSize{}, false, nil
+		}
+		return backuppb.BackupChainSize{}, false, err
+	}
+	if name == "" {
+		return backuppb.BackupChainSize{}, false, nil
+	}
+
+	r, err := store.ReadFile(ctx, chainSizeDir(subdir)+"/"+name)
+	if err != nil {
+		return backuppb.BackupChainSize{}, false, err
+	}
+	defer r.Close(ctx)
+	data, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return backuppb.BackupChainSize{}, false, err
+	}
+	var size backuppb.BackupChainSize
+	if err := protoutil.Unmarshal(data, &size); err != nil {
+		return backuppb.BackupChainSize{}, false, errors.Wrapf(err, "reading size of backup chain %s", subdir)
+	}
+	return size, true, nil
+}
+
+// UpdateChainSize records the size of the backup chain in subdir of the
+// collection in store once a layer ending at endTime, with the given logical
+// and physical size, has been added to it. Nothing is recorded for an
+// incremental layer of a chain whose earlier layers did not record its size.
+func UpdateChainSize(
+	ctx context.Context,
+	store cloud.ExternalStorage,
+	subdir string,
+	isFull bool,
+	endTime hlc.Timestamp,
+	logicalSize, physicalSize int64,
+) error {
+	var size backuppb.BackupChainSize
+	if !isFull {
+		var found bool
+		var err error
+		size, found, err = ReadChainSize(ctx, store, subdir)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return nil
+		}
+		if endTime.LessEq(size.EndTime) {
+			// The layer was already recorded, by an earlier attempt of its job.
+			return nil
+		}
+	}
+	size.EndTime = endTime
+	size.NumLayers++
+	size.LogicalSize += logicalSize
+	size.PhysicalSize += physicalSize
+
+	data, err := protoutil.Marshal(&size)
+	if err != nil {
+		return err
+	}
+	return cloud.WriteFile(ctx, store, chainSizeFileName(subdir, endTime), bytes.NewReader(data))
+}
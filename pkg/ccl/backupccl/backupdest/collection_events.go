@@ -0,0 +1,153 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+)
+
+// A collection records what happens to it in an append-only log of events in
+// its events directory, so that tools with access to the collection but not
+// to the cluster backing up into it can tell whether its backups are healthy.
+// Each event is a JSON file of its own, named such that the events list in the
+// order they were recorded.
+
+// CollectionEventsEnabled controls whether collections record their events.
+var CollectionEventsEnabled = settings.RegisterBoolSetting(
+	settings.TenantWritable,
+	"bulkio.backup.collection_events.enabled",
+	"if enabled, backups into a collection, and updates of its LATEST file, are "+
+		"recorded in an append-only log in the events directory of the collection",
+	false,
+).WithPublic()
+
+// CollectionEventType is the type of an event of a collection.
+type CollectionEventType string
+
+const (
+	// CollectionEventBackupStarted is recorded once a backup into the
+	// collection has resolved its destination.
+	CollectionEventBackupStarted CollectionEventType = "backup_started"
+	// CollectionEventBackupSucceeded is recorded once a backup into the
+	// collection has completed.
+	CollectionEventBackupSucceeded CollectionEventType = "backup_succeeded"
+	// CollectionEventBackupFailed is recorded once a backup into the collection
+	// has failed or was canceled.
+	CollectionEventBackupFailed CollectionEventType = "backup_failed"
+	// CollectionEventBackupSkipped is recorded once an incremental backup run
+	// with skip_if_unchanged has found nothing changed since the previous
+	// backup of its chain, and so completed without writing a layer.
+	CollectionEventBackupSkipped CollectionEventType = "backup_skipped"
+	// CollectionEventLatestUpdated is recorded once the LATEST file of the
+	// collection was written.
+	CollectionEventLatestUpdated CollectionEventType = "latest_updated"
+)
+
+// CollectionEvent is an event of a collection.
+type CollectionEvent struct {
+	Type CollectionEventType `json:"type"`
+	// Time is when the event was recorded.
+	Time time.Time `json:"time"`
+	// JobID is the ID of the backup job the event is about, if any.
+	JobID jobspb.JobID `json:"job_id,omitempty"`
+	// Subdir is the subdirectory of the backup chain the event is about.
+	Subdir string `json:"subdir,omitempty"`
+	// Incremental is set if the backup the event is about is an incremental
+	// one.
+	Incremental bool `json:"incremental,omitempty"`
+	// EndTime is the end time of the backup the event is about, as a decimal
+	// timestamp usable with AS OF SYSTEM TIME.
+	EndTime string `json:"end_time,omitempty"`
+	// LogicalSize and PhysicalSize are the sizes of a completed backup.
+	LogicalSize  int64 `json:"logical_size,omitempty"`
+	PhysicalSize int64 `json:"physical_size,omitempty"`
+	// Error is the error a backup failed with.
+	Error string `json:"error,omitempty"`
+}
+
+const collectionEventSuffix = ".json"
+
+// collectionEventFileName returns the name of the file of ev. The name starts
+// with the time of the event, padded so that names sort in time order, and
+// ends with a random suffix so that events recorded at the same time do not
+// overwrite each other.
+func collectionEventFileName(ev CollectionEvent) string {
+	return fmt.Sprintf("%s/%019d-%s-%s%s", backupbase.CollectionEventsDirectory,
+		ev.Time.UnixNano(), ev.Type, uuid.FastMakeV4().Short(), collectionEventSuffix)
+}
+
+// RecordCollectionEvent appends ev to the event log of the collection in
+// store, if collections record their events. The time of ev is set to now if
+// it is not set.
+func RecordCollectionEvent(
+	ctx context.Context, settings *cluster.Settings, store cloud.ExternalStorage, ev CollectionEvent,
+) error {
+	if settings == nil || !CollectionEventsEnabled.Get(&settings.SV) {
+		return nil
+	}
+	if ev.Time.IsZero() {
+		ev.Time = timeutil.Now()
+	}
+	ev.Time = ev.Time.UTC()
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return cloud.WriteFile(ctx, store, collectionEventFileName(ev), bytes.NewReader(data))
+}
+
+// ReadCollectionEvents calls fn with each event in the event log of the
+// collection in store, in the order they were recorded.
+func ReadCollectionEvents(
+	ctx context.Context, store cloud.ExternalStorage, fn func(CollectionEvent) error,
+) error {
+	var names []string
+	if err := store.List(ctx, backupbase.CollectionEventsDirectory+"/", "", func(p string) error {
+		if p = strings.TrimPrefix(p, "/"); strings.HasSuffix(p, collectionEventSuffix) {
+			names = append(names, p)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, name := range names {
+		r, err := store.ReadFile(ctx, backupbase.CollectionEventsDirectory+"/"+name)
+		if err != nil {
+			return err
+		}
+		data, err := ioctx.ReadAll(ctx, r)
+		r.Close(ctx)
+		if err != nil {
+			return err
+		}
+		var ev CollectionEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return errors.Wrapf(err, "reading collection event %s", name)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionEvents(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tc, _, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	st := execCfg.Settings
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx,
+		fmt.Sprintf("nodelocal://1/%s", t.Name()), username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+
+	readEvents := func() []backupdest.CollectionEvent {
+		var events []backupdest.CollectionEvent
+		require.NoError(t, backupdest.ReadCollectionEvents(ctx, store, func(ev backupdest.CollectionEvent) error {
+			events = append(events, ev)
+			return nil
+		}))
+		return events
+	}
+
+	// Nothing is recorded unless collections record their events.
+	started := backupdest.CollectionEvent{
+		Type:   backupdest.CollectionEventBackupStarted,
+		Time:   time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+		JobID:  1,
+		Subdir: "/2022/10/01-120000.00",
+	}
+	require.NoError(t, backupdest.RecordCollectionEvent(ctx, st, store, started))
+	require.NoError(t, backupdest.WriteNewLatestFile(ctx, st, store,
+		backuppb.LatestFile{Subdir: started.Subdir}))
+	require.Empty(t, readEvents())
+
+	backupdest.CollectionEventsEnabled.Override(ctx, &st.SV, true)
+	succeeded := backupdest.CollectionEvent{
+		Type:         backupdest.CollectionEventBackupSucceeded,
+		Time:         started.Time.Add(time.Minute),
+		JobID:        1,
+		Subdir:       started.Subdir,
+		EndTime:      "1664625600000000000.0000000000",
+		LogicalSize:  100,
+		PhysicalSize: 40,
+	}
+	failed := backupdest.CollectionEvent{
+		Type:        backupdest.CollectionEventBackupFailed,
+		Time:        started.Time.Add(time.Hour),
+		JobID:       2,
+		Subdir:      started.Subdir,
+		Incremental: true,
+		Error:       "boom",
+	}
+	// Events list in the order they were recorded, not written.
+	require.NoError(t, backupdest.RecordCollectionEvent(ctx, st, store, failed))
+	require.NoError(t, backupdest.RecordCollectionEvent(ctx, st, store, succeeded))
+	require.NoError(t, backupdest.RecordCollectionEvent(ctx, st, store, started))
+	require.Equal(t, []backupdest.CollectionEvent{started, succeeded, failed}, readEvents())
+
+	// Writing a LATEST file records its update.
+	require.NoError(t, backupdest.WriteNewLatestFile(ctx, st, store,
+		backuppb.LatestFile{Subdir: started.Subdir}))
+	events := readEvents()
+	require.Len(t, events, 4)
+	require.Equal(t, backupdest.CollectionEventLatestUpdated, events[3].Type)
+	require.Equal(t, started.Subdir, events[3].Subdir)
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+)
+
+// The first backup into a collection records the ID of its cluster in the
+// collection's fingerprint. A backup into a collection whose fingerprint names
+// another cluster, which would interleave the backups of both clusters in the
+// collection's LATEST history, either fails or takes the collection over,
+// depending on bulkio.backup.collection_cluster_mismatch.
+//
+// External storage offers no compare-and-swap, so two clusters backing up into
+// a new collection at the same time may both claim it; the next backup of the
+// cluster that lost the race then detects the mismatch.
+
+// collectionMismatchPolicy is what a backup does when the collection it backs
+// up into was written to by another cluster.
+type collectionMismatchPolicy int64
+
+const (
+	// collectionMismatchError fails the backup.
+	collectionMismatchError collectionMismatchPolicy = iota
+	// collectionMismatchWarn logs a warning, and the backup takes the
+	// collection over.
+	collectionMismatchWarn
+)
+
+var collectionClusterMismatch = settings.RegisterEnumSetting(
+	settings.TenantWritable,
+	"bulkio.backup.collection_cluster_mismatch",
+	"what a backup into a collection that another cluster backed up into does: "+
+		"fail the backup, or log a warning and record the backing up cluster as the owner of the collection",
+	"error",
+	map[int64]string{
+		int64(collectionMismatchError): "error",
+		int64(collectionMismatchWarn):  "warn",
+	},
+).WithPublic()
+
+// ReadCollectionFingerprint returns the fingerprint of the collection in store.
+// It returns false if no backup into the collection recorded one, such as if
+// all backups were taken before fingerprints were recorded.
+func ReadCollectionFingerprint(
+	ctx context.Context, store cloud.ExternalStorage,
+) (backuppb.CollectionFingerprint, bool, error) {
+	r, err := store.ReadFile(ctx, backupbase.CollectionFingerprintName)
+	if err != nil {
+		if errors.Is(err, cloud.ErrFileDoesNotExist) {
+			return backuppb.CollectionFingerprint{}, false, nil
+		}
+		return backuppb.CollectionFingerprint{}, false, err
+	}
+	defer r.Close(ctx)
+	data, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return backuppb.CollectionFingerprint{}, false, err
+	}
+	var fingerprint backuppb.CollectionFingerprint
+	if err := protoutil.Unmarshal(data, &fingerprint); err != nil {
+		return backuppb.CollectionFingerprint{}, false, errors.Wrap(err, "reading collection fingerprint")
+	}
+	return fingerprint, true, nil
+}
+
+// claimCollection checks that the collection in store was not backed up into
+// by a cluster other than the one with the given ID, and records the cluster
+// as the owner of the collection if it is not yet.
+func claimCollection(
+	ctx context.Context,
+	store cloud.ExternalStorage,
+	collectionURI string,
+	clusterID uuid.UUID,
+	policy collectionMismatchPolicy,
+) error {
+	fingerprint, found, err := ReadCollectionFingerprint(ctx, store)
+	if err != nil {
+		return err
+	}
+	if found && fingerprint.ClusterID.Equal(clusterID) {
+		return nil
+	}
+	if found {
+		if policy == collectionMismatchError {
+			return errors.WithHintf(
+				errors.Newf("backup collection %s belongs to cluster %s",
+					backuputils.RedactURIForErrorMessage(collectionURI), fingerprint.ClusterID),
+				"back up into another collection, or set %s to 'warn' to take the collection over",
+				collectionClusterMismatch.Key())
+		}
+		log.Warningf(ctx, "backup collection %s belonged to cluster %s, recording cluster %s as its owner",
+			backuputils.RedactURIForErrorMessage(collectionURI), fingerprint.ClusterID, clusterID)
+	}
+	fingerprint.ClusterID = clusterID
+	fingerprint.Generation++
+
+	data, err := protoutil.Marshal(&fingerprint)
+	if err != nil {
+		return err
+	}
+	return cloud.WriteFile(ctx, store, backupbase.CollectionFingerprintName, bytes.NewReader(data))
+}
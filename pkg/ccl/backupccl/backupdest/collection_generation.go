@@ -0,0 +1,73 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// Every full backup into a collection is assigned the next generation of the
+// collection, which the incremental backups of its chain inherit. Along with
+// the position of each layer in its chain, the generation is recorded in the
+// manifest of every backup, so that a tool mirroring the collection can tell
+// which chains and layers it has not copied yet without listing the whole
+// collection.
+//
+// Like the fingerprint of the collection, the generation is updated without
+// compare-and-swap, so full backups started at the same time may be assigned
+// the same generation. A full backup that does not complete leaves a gap in
+// the generations.
+
+// ReadCollectionGeneration returns the generation most recently assigned to a
+// full backup in the collection in store, or zero if none was.
+func ReadCollectionGeneration(ctx context.Context, store cloud.ExternalStorage) (int64, error) {
+	r, err := store.ReadFile(ctx, backupbase.CollectionGenerationName)
+	if err != nil {
+		if errors.Is(err, cloud.ErrFileDoesNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer r.Close(ctx)
+	data, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return 0, err
+	}
+	var generation backuppb.CollectionGeneration
+	if err := protoutil.Unmarshal(data, &generation); err != nil {
+		return 0, errors.Wrap(err, "reading collection generation")
+	}
+	return generation.Generation, nil
+}
+
+// NextCollectionGeneration assigns the next generation of the collection in
+// store to a full backup, and returns it.
+func NextCollectionGeneration(ctx context.Context, store cloud.ExternalStorage) (int64, error) {
+	current, err := ReadCollectionGeneration(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+	generation := backuppb.CollectionGeneration{Generation: current + 1}
+	data, err := protoutil.Marshal(&generation)
+	if err != nil {
+		return 0, err
+	}
+	if err := cloud.WriteFile(ctx, store, backupbase.CollectionGenerationName, bytes.NewReader(data)); err != nil {
+		return 0, errors.Wrap(err, "writing collection generation")
+	}
+	return generation.Generation, nil
+}
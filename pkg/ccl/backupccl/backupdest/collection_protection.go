@@ -0,0 +1,126 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// A restore of backups in a collection registers a protection in the metadata
+// of the collection for as long as it runs, which lists the layers of the chain
+// it reads. Anything that deletes or moves the layers of a collection, such as
+// the pruning of backups past a retention policy, must leave the protected
+// layers in place, or the restore fails part way.
+//
+// A protection is named after the job that registered it, which rewrites it
+// when it is resumed and removes it once it is done. A job that is removed
+// before then, e.g. when the cluster running it is lost, leaves its protection
+// behind, so protections of jobs that are no longer running are to be ignored.
+
+// WriteCollectionProtection registers protection in the collection in store.
+func WriteCollectionProtection(
+	ctx context.Context, store cloud.ExternalStorage, protection backuppb.CollectionProtection,
+) error {
+	data, err := protoutil.Marshal(&protection)
+	if err != nil {
+		return err
+	}
+	if err := cloud.WriteFile(ctx, store, collectionProtectionName(jobspb.JobID(protection.JobID)),
+		bytes.NewReader(data)); err != nil {
+		return errors.Wrap(err, "writing collection protection")
+	}
+	return nil
+}
+
+// RemoveCollectionProtection removes the protection registered by the job
+// with the given ID in the collection in store, if any.
+func RemoveCollectionProtection(
+	ctx context.Context, store cloud.ExternalStorage, jobID jobspb.JobID,
+) error {
+	if err := store.Delete(ctx, collectionProtectionName(jobID)); err != nil &&
+		!errors.Is(err, cloud.ErrFileDoesNotExist) {
+		return errors.Wrap(err, "removing collection protection")
+	}
+	return nil
+}
+
+// ReadCollectionProtections returns the protections registered in the
+// collection in store.
+func ReadCollectionProtections(
+	ctx context.Context, store cloud.ExternalStorage,
+) ([]backuppb.CollectionProtection, error) {
+	var names []string
+	if err := store.List(ctx, backupbase.CollectionProtectionsDirectory, "", func(name string) error {
+		names = append(names, strings.TrimPrefix(name, "/"))
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "listing collection protections")
+	}
+
+	protections := make([]backuppb.CollectionProtection, 0, len(names))
+	for _, name := range names {
+		r, err := store.ReadFile(ctx, path.Join(backupbase.CollectionProtectionsDirectory, name))
+		if err != nil {
+			if errors.Is(err, cloud.ErrFileDoesNotExist) {
+				// The protection was removed since it was listed.
+				continue
+			}
+			return nil, err
+		}
+		data, err := ioctx.ReadAll(ctx, r)
+		r.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var protection backuppb.CollectionProtection
+		if err := protoutil.Unmarshal(data, &protection); err != nil {
+			return nil, errors.Wrapf(err, "reading collection protection %s", name)
+		}
+		protections = append(protections, protection)
+	}
+	return protections, nil
+}
+
+// CollectionRelativePath returns the path of the backup at uri relative to
+// the collection, or false if the backup is not in the collection.
+func CollectionRelativePath(collection, uri string) (string, bool) {
+	c, err := url.Parse(collection)
+	if err != nil {
+		return "", false
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", false
+	}
+	if c.Scheme != u.Scheme || c.Host != u.Host {
+		return "", false
+	}
+	prefix := strings.TrimSuffix(c.Path, "/") + "/"
+	if !strings.HasPrefix(u.Path, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(u.Path, prefix), true
+}
+
+func collectionProtectionName(jobID jobspb.JobID) string {
+	return path.Join(backupbase.CollectionProtectionsDirectory, strconv.FormatInt(int64(jobID), 10))
+}
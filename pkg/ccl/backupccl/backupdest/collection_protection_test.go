@@ -0,0 +1,84 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionProtections(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tc, _, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx,
+		fmt.Sprintf("nodelocal://1/%s", t.Name()), username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+
+	protections, err := backupdest.ReadCollectionProtections(ctx, store)
+	require.NoError(t, err)
+	require.Empty(t, protections)
+
+	first := backuppb.CollectionProtection{JobID: 1, Layers: []string{"2022/10/01-120000.00"}}
+	second := backuppb.CollectionProtection{JobID: 2, Layers: []string{
+		"2022/10/02-120000.00", "incrementals/2022/10/02-120000.00/20221003/120000.00",
+	}}
+	require.NoError(t, backupdest.WriteCollectionProtection(ctx, store, first))
+	require.NoError(t, backupdest.WriteCollectionProtection(ctx, store, second))
+	protections, err = backupdest.ReadCollectionProtections(ctx, store)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []backuppb.CollectionProtection{first, second}, protections)
+
+	require.NoError(t, backupdest.RemoveCollectionProtection(ctx, store, jobspb.JobID(1)))
+	// Removing a protection that does not exist is not an error.
+	require.NoError(t, backupdest.RemoveCollectionProtection(ctx, store, jobspb.JobID(1)))
+	protections, err = backupdest.ReadCollectionProtections(ctx, store)
+	require.NoError(t, err)
+	require.Equal(t, []backuppb.CollectionProtection{second}, protections)
+}
+
+func TestCollectionRelativePath(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		collection, uri string
+		expected        string
+		ok              bool
+	}{
+		{"nodelocal://1/foo", "nodelocal://1/foo/2022/10/01-120000.00", "2022/10/01-120000.00", true},
+		{"nodelocal://1/foo/", "nodelocal://1/foo/2022/10/01-120000.00", "2022/10/01-120000.00", true},
+		{"s3://bucket/foo?AUTH=implicit", "s3://bucket/foo/incrementals/2022/10/01-120000.00/20221002/120000.00?AUTH=implicit",
+			"incrementals/2022/10/01-120000.00/20221002/120000.00", true},
+		{"nodelocal://1/foo", "nodelocal://1/foobar/2022/10/01-120000.00", "", false},
+		{"nodelocal://1/foo", "nodelocal://2/foo/2022/10/01-120000.00", "", false},
+		{"nodelocal://1/foo", "s3://1/foo/2022/10/01-120000.00", "", false},
+	} {
+		actual, ok := backupdest.CollectionRelativePath(tc.collection, tc.uri)
+		require.Equal(t, tc.ok, ok, "%s in %s", tc.uri, tc.collection)
+		require.Equal(t, tc.expected, actual, "%s in %s", tc.uri, tc.collection)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+)
+
+// CheckConnectionCapability returns an error if any of the External
+// Connections that uris refer to can't be used for the operations of
+// capability, such as an External Connection limited to backup-write being
+// used by a RESTORE. uris which don't refer to an External Connection are
+// ignored, as are empty ones.
+func CheckConnectionCapability(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	op string,
+	capability connectionpb.ConnectionCapability,
+	uris ...string,
+) error {
+	return execCfg.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		for _, uri := range uris {
+			if uri == "" {
+				continue
+			}
+			if err := externalconn.CheckCapability(
+				ctx, execCfg.InternalExecutor, txn, uri, op, capability,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -16,10 +16,15 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/errors"
 )
@@ -34,6 +39,18 @@ const (
 	listingDelimDataSlash = "data/"
 )
 
+// orderLayersByEndTime makes FindPriorBackups check the order of the layers it
+// finds, which are named after their end time as told by the clock of the
+// node that planned them, against the end times recorded in their stubs.
+var orderLayersByEndTime = settings.RegisterBoolSetting(
+	settings.TenantWritable,
+	"backup.chain.order_by_end_time.enabled",
+	"if enabled, the incremental backups of a chain are ordered by the end times recorded in them "+
+		"rather than by the names of their directories, and chains on which they disagree, as a skewed "+
+		"clock can make them, are refused",
+	false,
+)
+
 // backupSubdirRE identifies the portion of a larger path that refers to the full backup subdirectory.
 var backupSubdirRE = regexp.MustCompile(`(.*)/([0-9]{4}/[0-9]{2}/[0-9]{2}-[0-9]{6}.[0-9]{2}/?)$`)
 
@@ -65,7 +82,14 @@ func FindPriorBackups(
 	defer sp.Finish()
 
 	var prev []string
-	if err := store.List(ctx, "", listingDelimDataSlash, func(p string) error {
+	opts := cloud.ListOptions{Delimiter: listingDelimDataSlash}
+	if err := store.ListWithOptions(ctx, "", opts, func(p string) error {
+		// The subdirectories of the incremental backups are named after their
+		// date, and the other files in store sort after them, so the listing can
+		// stop at the first name that does not start with a digit.
+		if len(p) > 1 && p[1] > '9' {
+			return cloud.ErrListingDone
+		}
 		if ok, err := path.Match(incBackupSubdirGlob+backupbase.BackupManifestName, p); err != nil {
 			return err
 		} else if ok {
@@ -84,13 +108,52 @@ func FindPriorBackups(
 			prev = append(prev, p)
 		}
 		return nil
-	}); err != nil {
+	}); err != nil && !errors.Is(err, cloud.ErrListingDone) {
 		return nil, errors.Wrap(err, "reading previous backup layers")
 	}
 	sort.Strings(prev)
+	if st := store.Settings(); st != nil && orderLayersByEndTime.Get(&st.SV) {
+		if err := checkLayerOrder(ctx, store, prev, includeManifest); err != nil {
+			return nil, err
+		}
+	}
 	return prev, nil
 }
 
+// checkLayerOrder returns an error if the end times recorded in the stubs of
+// the layers at the given paths of store, ordered by name, are not increasing,
+// such that ordering them by end time would order them differently. Layers
+// written without a stub are not checked.
+func checkLayerOrder(
+	ctx context.Context, store cloud.ExternalStorage, layers []string, includeManifest bool,
+) error {
+	var prevLayer string
+	var prevEnd hlc.Timestamp
+	for _, layer := range layers {
+		dir := layer
+		if includeManifest {
+			dir = path.Dir(layer)
+		}
+		stub, ok, err := backupinfo.ReadBackupLayerStub(ctx, store, dir)
+		if err != nil {
+			return errors.Wrapf(err, "reading the end time of backup %s", dir)
+		}
+		if !ok {
+			continue
+		}
+		if prevLayer != "" && stub.EndTime.LessEq(prevEnd) {
+			return errors.WithHint(
+				pgerror.Newf(pgcode.DataException,
+					"backup %s ends at %s, but the backup %s named before it ends at %s",
+					dir, stub.EndTime.GoTime(), prevLayer, prevEnd.GoTime()),
+				"the clock of a node that planned them may have been skewed; "+
+					"the chain cannot be extended or restored in this order")
+		}
+		prevLayer, prevEnd = dir, stub.EndTime
+	}
+	return nil
+}
+
 // backupsFromLocation is a small helper function to retrieve all prior
 // backups from the specified location.
 func backupsFromLocation(
@@ -9,9 +9,20 @@
 package backupdest_test
 
 import (
+	"bytes"
+	"context"
+	"path"
 	"testing"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/stretchr/testify/require"
@@ -34,3 +45,52 @@ func TestJoinURLPath(t *testing.T) {
 	require.Equal(t, "/../path", backuputils.JoinURLPath("/top", "../../path"))
 
 }
+
+func TestFindPriorBackupsOrderedByEndTime(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tc, sqlDB, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, "nodelocal://1/inc", username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+
+	// writeLayer writes a layer with a manifest, and a stub with the given end
+	// time unless it is empty.
+	writeLayer := func(dir string, end hlc.Timestamp) {
+		require.NoError(t, cloud.WriteFile(ctx, store, path.Join(dir, backupbase.BackupManifestName),
+			bytes.NewReader(nil)))
+		if !end.IsEmpty() {
+			layerStore, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, "nodelocal://1/inc"+dir,
+				username.RootUserName())
+			require.NoError(t, err)
+			defer layerStore.Close()
+			require.NoError(t, backupinfo.WriteBackupLayerStub(ctx, layerStore,
+				&backuppb.BackupManifest{EndTime: end}))
+		}
+	}
+	writeLayer("/20220101/120000.00", hlc.Timestamp{WallTime: 10})
+	writeLayer("/20220101/130000.00", hlc.Timestamp{})
+	writeLayer("/20220101/140000.00", hlc.Timestamp{WallTime: 20})
+	expected := []string{"/20220101/120000.00", "/20220101/130000.00", "/20220101/140000.00"}
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING backup.chain.order_by_end_time.enabled = true`)
+	prev, err := backupdest.FindPriorBackups(ctx, store, backupdest.OmitManifest)
+	require.NoError(t, err)
+	require.Equal(t, expected, prev)
+
+	// A layer named after the others, but ending before them, is refused.
+	writeLayer("/20220101/150000.00", hlc.Timestamp{WallTime: 15})
+	_, err = backupdest.FindPriorBackups(ctx, store, backupdest.OmitManifest)
+	require.ErrorContains(t, err, "backup /20220101/150000.00 ends at")
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING backup.chain.order_by_end_time.enabled = false`)
+	prev, err = backupdest.FindPriorBackups(ctx, store, backupdest.OmitManifest)
+	require.NoError(t, err)
+	require.Equal(t, append(expected, "/20220101/150000.00"), prev)
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// LatestFileVersion is the version of the format of the LatestFile protos
+// written to LATEST files. Version 0 is that of the LATEST files that hold
+// only the subdir of a chain, which DecodeLatestFile returns without a
+// summary of the chain.
+const LatestFileVersion = 1
+
+// latestFileMagic prefixes the LatestFile protos written to LATEST files. It
+// starts with a NUL byte, which no subdir contains, so that it tells them apart
+// from the LATEST files that hold a bare subdir.
+const latestFileMagic = "\x00crdb-latest"
+
+// encryptionFingerprintLen is the length of the encryption fingerprints of
+// LATEST files.
+const encryptionFingerprintLen = 16
+
+// intoFolderNamePrecision is the precision of the end times in the names of
+// the subdirs chosen by BACKUP INTO.
+const intoFolderNamePrecision = 10 * time.Millisecond
+
+// LatestFileEndsAtOrAfter returns true if the full backup of the chain latest
+// names ends at or after ts. LATEST files of version 0 do not record the end time of their
+// chain, so it is read from the name of the subdir, which BACKUP INTO records
+// to the hundredth of a second; an error is returned for any other subdir.
+func LatestFileEndsAtOrAfter(latest backuppb.LatestFile, ts hlc.Timestamp) (bool, error) {
+	if latest.Version > 0 {
+		return ts.LessEq(latest.Chain.EndTime), nil
+	}
+	subdir := latest.Subdir
+	if !strings.HasPrefix(subdir, "/") {
+		subdir = "/" + subdir
+	}
+	endTime, err := time.Parse(backupbase.DateBasedIntoFolderName, subdir)
+	if err != nil {
+		return false, errors.Newf("LATEST file names %s, whose end time is unknown", latest.Subdir)
+	}
+	return !endTime.Before(ts.GoTime().Truncate(intoFolderNamePrecision)), nil
+}
+
+// MakeLatestFile returns the LatestFile of the chain in subdir once its full
+// backup, ending at endTime with the given logical and physical size and
+// encrypted with info, was taken.
+func MakeLatestFile(
+	subdir string,
+	endTime hlc.Timestamp,
+	logicalSize, physicalSize int64,
+	info *jobspb.EncryptionInfo,
+) backuppb.LatestFile {
+	return backuppb.LatestFile{
+		Version: LatestFileVersion,
+		Subdir:  subdir,
+		Chain: backuppb.BackupChainSize{
+			EndTime:      endTime,
+			NumLayers:    1,
+			LogicalSize:  logicalSize,
+			PhysicalSize: physicalSize,
+		},
+		EncryptionFingerprint: EncryptionFingerprint(info),
+	}
+}
+
+// EncryptionFingerprint returns the fingerprint of the encryption info of a
+// backup chain, or nil if the chain is not encrypted.
+func EncryptionFingerprint(info *jobspb.EncryptionInfo) []byte {
+	if info == nil {
+		return nil
+	}
+	h := sha256.New()
+	h.Write(info.Salt)
+	ids := make([]string, 0, len(info.EncryptedDataKeyByKMSMasterKeyID))
+	for id := range info.EncryptedDataKeyByKMSMasterKeyID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		h.Write([]byte{0})
+		h.Write([]byte(id))
+	}
+	return h.Sum(nil)[:encryptionFingerprintLen]
+}
+
+// EncodeLatestFile returns the content of a LATEST file naming latest. It is
+// only a LatestFile proto once every node of the cluster can decode one, and
+// if latest summarizes its chain; otherwise it is the subdir of the chain.
+func EncodeLatestFile(
+	ctx context.Context, settings *cluster.Settings, latest backuppb.LatestFile,
+) ([]byte, error) {
+	if latest.Version == 0 || settings == nil ||
+		!settings.Version.IsActive(ctx, clusterversion.V23_1StructuredLatestFiles) {
+		return []byte(latest.Subdir), nil
+	}
+	data, err := protoutil.Marshal(&latest)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(latestFileMagic), data...), nil
+}
+
+// DecodeLatestFile decodes the content of a LATEST file, which is either a
+// LatestFile proto or, for files written by older versions, the subdir of the
+// chain it names.
+func DecodeLatestFile(data []byte) (backuppb.LatestFile, error) {
+	if !bytes.HasPrefix(data, []byte(latestFileMagic)) {
+		return backuppb.LatestFile{Subdir: string(data)}, nil
+	}
+	var latest backuppb.LatestFile
+	if err := protoutil.Unmarshal(data[len(latestFileMagic):], &latest); err != nil {
+		return backuppb.LatestFile{}, err
+	}
+	if latest.Version > LatestFileVersion {
+		return backuppb.LatestFile{}, errors.Newf(
+			"LATEST file has version %d, which is newer than the supported version %d",
+			latest.Version, LatestFileVersion)
+	}
+	return latest, nil
+}
+
+// ReadLatestChainSize returns the size of the chain latest names. LATEST files
+// are only written by the full backups that start their chain, so the size is
+// read from the chain size files of the collection in store, which each layer
+// of the chain updates, and only taken from the summary of the chain in latest
+// if no layer recorded it there. It returns false if neither records it.
+func ReadLatestChainSize(
+	ctx context.Context, store cloud.ExternalStorage, latest backuppb.LatestFile,
+) (backuppb.BackupChainSize, bool, error) {
+	size, found, err := ReadChainSize(ctx, store, latest.Subdir)
+	if err != nil || found {
+		return size, found, err
+	}
+	if latest.Version > 0 {
+		return latest.Chain, true, nil
+	}
+	return backuppb.BackupChainSize{}, false, nil
+}
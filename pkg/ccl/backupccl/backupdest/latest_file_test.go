@@ -0,0 +1,106 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestFileEncoding(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	info := &jobspb.EncryptionInfo{
+		Salt:                             []byte("salt"),
+		EncryptedDataKeyByKMSMasterKeyID: map[string][]byte{"b": nil, "a": nil},
+	}
+	latest := backupdest.MakeLatestFile("/2022/10/16-120000.00", hlc.Timestamp{WallTime: 10},
+		100 /* logicalSize */, 40 /* physicalSize */, info)
+	require.Len(t, latest.EncryptionFingerprint, 16)
+	require.Equal(t, latest.EncryptionFingerprint, backupdest.EncryptionFingerprint(info))
+	require.Nil(t, backupdest.EncryptionFingerprint(nil))
+
+	// Once every node can decode them, LATEST files summarize their chain.
+	st := cluster.MakeTestingClusterSettings()
+	data, err := backupdest.EncodeLatestFile(ctx, st, latest)
+	require.NoError(t, err)
+	decoded, err := backupdest.DecodeLatestFile(data)
+	require.NoError(t, err)
+	require.Equal(t, latest, decoded)
+
+	// Until then, they hold only the subdir of the chain.
+	old := cluster.MakeTestingClusterSettingsWithVersions(
+		clusterversion.ByKey(clusterversion.V23_1StructuredLatestFiles-1),
+		clusterversion.TestingBinaryMinSupportedVersion,
+		true, /* initializeVersion */
+	)
+	data, err = backupdest.EncodeLatestFile(ctx, old, latest)
+	require.NoError(t, err)
+	require.Equal(t, latest.Subdir, string(data))
+	decoded, err = backupdest.DecodeLatestFile(data)
+	require.NoError(t, err)
+	require.Equal(t, backuppb.LatestFile{Subdir: latest.Subdir}, decoded)
+
+	// Files of a newer version than this binary's are rejected.
+	latest.Version = backupdest.LatestFileVersion + 1
+	data, err = backupdest.EncodeLatestFile(ctx, st, latest)
+	require.NoError(t, err)
+	_, err = backupdest.DecodeLatestFile(data)
+	require.Error(t, err)
+}
+
+func TestLatestFileEndsAtOrAfter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	endTime := hlc.Timestamp{WallTime: time.Date(2022, 10, 16, 12, 0, 0, 5e6, time.UTC).UnixNano(), Logical: 1}
+	latest := backupdest.MakeLatestFile("/2022/10/16-120000.00", endTime,
+		0 /* logicalSize */, 0 /* physicalSize */, nil /* info */)
+	legacy := backuppb.LatestFile{Subdir: latest.Subdir}
+
+	for _, tc := range []struct {
+		name     string
+		latest   backuppb.LatestFile
+		ts       hlc.Timestamp
+		expected bool
+		err      string
+	}{
+		{name: "before", latest: latest, ts: endTime.Prev(), expected: true},
+		{name: "at", latest: latest, ts: endTime, expected: true},
+		{name: "after", latest: latest, ts: endTime.Next(), expected: false},
+		// The subdirs chosen by BACKUP INTO record their end time to the
+		// hundredth of a second.
+		{name: "legacy at", latest: legacy, ts: endTime, expected: true},
+		{name: "legacy after", latest: legacy, ts: endTime.Add(int64(10*time.Millisecond), 0), expected: false},
+		{name: "legacy custom subdir", latest: backuppb.LatestFile{Subdir: "/nightly"}, ts: endTime,
+			err: "whose end time is unknown"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := backupdest.LatestFileEndsAtOrAfter(tc.latest, tc.ts)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, ok)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/errors"
+)
+
+// A backup collection can be read through a read-only replica of it, e.g. a
+// geo-replicated bucket closer to the cluster, passed with the metadata_uri
+// option of SHOW BACKUP and RESTORE. The LATEST file, the manifests and the
+// list of incremental backups are then read from the replica, while the data
+// files, which the replica may not have caught up on yet, are still read from
+// the collection itself. Since a replica has the same layout as its
+// collection, the URIs of the backups resolved in the replica are rebased onto
+// the collection with RebaseURIs.
+//
+// A backup can also keep its metadata apart from its data from the start, by
+// being written with the metadata_uri option of BACKUP. Its LATEST file,
+// manifests and encryption info are then only written to the metadata
+// collection, and its data files at the same path in the collection, which
+// its manifest records. It is read the same way as through a replica.
+
+// RebaseURIs returns the URIs in uris, which must be at or below the URI from,
+// at the same paths below the URI to. For example, rebasing
+// 's3://replica/backups/2022/10/14-120000.00' from 's3://replica/backups' to
+// 'gs://primary/backups?AUTH=implicit' returns
+// 'gs://primary/backups/2022/10/14-120000.00?AUTH=implicit'.
+func RebaseURIs(uris []string, from, to string) ([]string, error) {
+	fromURI, err := url.Parse(from)
+	if err != nil {
+		return nil, backuputils.RedactURLParseError(err)
+	}
+	fromPath := strings.TrimSuffix(fromURI.Path, "/")
+	res := make([]string, len(uris))
+	for i, uri := range uris {
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			return nil, backuputils.RedactURLParseError(err)
+		}
+		if parsed.Scheme != fromURI.Scheme || parsed.Host != fromURI.Host ||
+			(parsed.Path != fromPath && !strings.HasPrefix(parsed.Path, fromPath+"/")) {
+			return nil, errors.AssertionFailedf("%s is not in the backup collection %s",
+				backuputils.RedactURIForErrorMessage(uri), backuputils.RedactURIForErrorMessage(from))
+		}
+		rebased, err := backuputils.AppendPaths([]string{to}, strings.TrimPrefix(parsed.Path, fromPath))
+		if err != nil {
+			return nil, err
+		}
+		res[i] = rebased[0]
+	}
+	return res, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebaseURIs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		name     string
+		uris     []string
+		from, to string
+		expected []string
+		err      string
+	}{
+		{
+			name: "full and incremental",
+			uris: []string{
+				"s3://replica/backups/2022/10/14-120000.00?AWS_REGION=eu-west-1",
+				"s3://replica/backups/incrementals/2022/10/14-120000.00/20221014/130000.00?AWS_REGION=eu-west-1",
+			},
+			from: "s3://replica/backups?AWS_REGION=eu-west-1",
+			to:   "gs://primary/backups?AUTH=implicit",
+			expected: []string{
+				"gs://primary/backups/2022/10/14-120000.00?AUTH=implicit",
+				"gs://primary/backups/incrementals/2022/10/14-120000.00/20221014/130000.00?AUTH=implicit",
+			},
+		},
+		{
+			name:     "collection root",
+			uris:     []string{"nodelocal://1/replica/"},
+			from:     "nodelocal://1/replica",
+			to:       "nodelocal://2/primary",
+			expected: []string{"nodelocal://2/primary"},
+		},
+		{
+			name: "outside of the collection",
+			uris: []string{"nodelocal://1/replica-2/full"},
+			from: "nodelocal://1/replica",
+			to:   "nodelocal://1/primary",
+			err:  "is not in the backup collection",
+		},
+		{
+			name: "other host",
+			uris: []string{"s3://other/backups/full"},
+			from: "s3://replica/backups",
+			to:   "s3://primary/backups",
+			err:  "is not in the backup collection",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := backupdest.RebaseURIs(tc.uris, tc.from, tc.to)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res)
+		})
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+)
+
+// StoreCompatCheck names one of the behaviors of an external storage endpoint
+// that the backup destination logic relies upon.
+type StoreCompatCheck string
+
+const (
+	// CompatCheckReadWrite verifies that an object can be written and then read
+	// back with identical contents.
+	CompatCheckReadWrite StoreCompatCheck = "read-after-write"
+	// CompatCheckOverwrite verifies that an existing object can be replaced.
+	// Write-once (object lock) buckets are expected to fail this check, which
+	// is only required when listing is unsupported.
+	CompatCheckOverwrite StoreCompatCheck = "overwrite"
+	// CompatCheckListOrder verifies that listing returns objects in
+	// lexicographical order, which FindLatestFile relies upon to only consume
+	// the first result.
+	CompatCheckListOrder StoreCompatCheck = "list-order"
+	// CompatCheckListDelimiter verifies that listing with a delimiter groups
+	// data files so that FindPriorBackups discovers incremental layers.
+	CompatCheckListDelimiter StoreCompatCheck = "list-delimiter"
+	// CompatCheckTimestampedLatest verifies that the most recently written
+	// timestamped LATEST file is the one resolved by FindLatestFile.
+	CompatCheckTimestampedLatest StoreCompatCheck = "timestamped-latest"
+	// CompatCheckDelete verifies that objects can be deleted.
+	CompatCheckDelete StoreCompatCheck = "delete"
+)
+
+// StoreCompatResult is the outcome of a single StoreCompatCheck.
+type StoreCompatResult struct {
+	Check StoreCompatCheck
+	// Err is nil if the check passed.
+	Err error
+}
+
+// Passed returns true if the check succeeded.
+func (r StoreCompatResult) Passed() bool {
+	return r.Err == nil
+}
+
+// StoreCompatReport is the result of running CheckStoreCompatibility against
+// an external storage endpoint.
+type StoreCompatReport struct {
+	Results []StoreCompatResult
+}
+
+// StoreCompatFeature is a backup feature whose availability depends on the
+// behavior of the destination.
+type StoreCompatFeature struct {
+	Name string
+	// Requires lists the checks that must pass for the feature to work.
+	Requires []StoreCompatCheck
+	// RequiresOneOf lists checks of which at least one must pass, if non-empty.
+	RequiresOneOf []StoreCompatCheck
+}
+
+// StoreCompatFeatures lists the backup features whose availability is reported
+// by StoreCompatReport.Supports.
+var StoreCompatFeatures = []StoreCompatFeature{
+	{
+		// BACKUP INTO writes the LATEST pointer either as a timestamped file in
+		// the latest history directory, or, for stores that cannot list, by
+		// overwriting a single LATEST file.
+		Name:          "BACKUP INTO",
+		Requires:      []StoreCompatCheck{CompatCheckReadWrite},
+		RequiresOneOf: []StoreCompatCheck{CompatCheckTimestampedLatest, CompatCheckOverwrite},
+	},
+	{
+		Name:     "BACKUP INTO LATEST (incremental)",
+		Requires: []StoreCompatCheck{CompatCheckReadWrite, CompatCheckListDelimiter},
+		RequiresOneOf: []StoreCompatCheck{
+			CompatCheckTimestampedLatest, CompatCheckOverwrite,
+		},
+	},
+	{
+		Name:     "locality-aware BACKUP",
+		Requires: []StoreCompatCheck{CompatCheckReadWrite, CompatCheckListDelimiter},
+	},
+	{
+		Name:     "incremental_location",
+		Requires: []StoreCompatCheck{CompatCheckReadWrite, CompatCheckListDelimiter},
+	},
+}
+
+func (r StoreCompatReport) passed(check StoreCompatCheck) bool {
+	for _, res := range r.Results {
+		if res.Check == check {
+			return res.Passed()
+		}
+	}
+	return false
+}
+
+// Supports returns true if every check required by the feature passed.
+func (r StoreCompatReport) Supports(f StoreCompatFeature) bool {
+	for _, check := range f.Requires {
+		if !r.passed(check) {
+			return false
+		}
+	}
+	if len(f.RequiresOneOf) == 0 {
+		return true
+	}
+	for _, check := range f.RequiresOneOf {
+		if r.passed(check) {
+			return true
+		}
+	}
+	return false
+}
+
+// storeCompatScratchPrefix is the prefix of the scratch directory, within the
+// checked URI, where CheckStoreCompatibility writes its objects.
+const storeCompatScratchPrefix = "crdb-store-compat-check-"
+
+// CheckStoreCompatibility exercises the behaviors of the external storage at
+// uri that backup destination resolution depends upon -- listing order,
+// delimiter handling, overwrite semantics and the timestamped LATEST scheme --
+// and reports which of them work. All objects are written to, and removed
+// from, a fresh scratch directory within uri.
+//
+// An error is only returned if the scratch directory could not be opened;
+// failures of individual checks are recorded in the report.
+func CheckStoreCompatibility(
+	ctx context.Context,
+	uri string,
+	makeCloudStorage cloud.ExternalStorageFromURIFactory,
+	user username.SQLUsername,
+) (StoreCompatReport, error) {
+	ctx, sp := tracing.ChildSpan(ctx, "backupdest.CheckStoreCompatibility")
+	defer sp.Finish()
+
+	scratchDir := fmt.Sprintf("%s%d", storeCompatScratchPrefix, timeutil.Now().UnixNano())
+	scratchURIs, err := backuputils.AppendPaths([]string{uri}, scratchDir)
+	if err != nil {
+		return StoreCompatReport{}, backuputils.RedactURLParseError(err)
+	}
+	store, err := makeCloudStorage(ctx, scratchURIs[0], user)
+	if err != nil {
+		return StoreCompatReport{}, errors.Wrapf(err, "opening %s",
+			backuputils.RedactURIForErrorMessage(scratchURIs[0]))
+	}
+	defer store.Close()
+
+	var report StoreCompatReport
+	record := func(check StoreCompatCheck, err error) {
+		report.Results = append(report.Results, StoreCompatResult{Check: check, Err: err})
+	}
+
+	record(CompatCheckReadWrite, checkReadAfterWrite(ctx, store))
+	record(CompatCheckOverwrite, checkOverwrite(ctx, store))
+	record(CompatCheckListOrder, checkListOrder(ctx, store))
+	record(CompatCheckListDelimiter, func() error {
+		incURIs, err := backuputils.AppendPaths(scratchURIs, storeCompatIncrementalsDir)
+		if err != nil {
+			return err
+		}
+		incStore, err := makeCloudStorage(ctx, incURIs[0], user)
+		if err != nil {
+			return err
+		}
+		defer incStore.Close()
+		return checkListDelimiter(ctx, store, incStore)
+	}())
+	record(CompatCheckTimestampedLatest, checkTimestampedLatest(ctx, store))
+	record(CompatCheckDelete, cleanupStoreCompatScratch(ctx, store))
+	return report, nil
+}
+
+func readFileContents(ctx context.Context, store cloud.ExternalStorage, name string) ([]byte, error) {
+	r, err := store.ReadFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close(ctx)
+	return ioctx.ReadAll(ctx, r)
+}
+
+func checkReadAfterWrite(ctx context.Context, store cloud.ExternalStorage) error {
+	const name = "read-after-write"
+	contents := []byte("read after write")
+	if err := cloud.WriteFile(ctx, store, name, bytes.NewReader(contents)); err != nil {
+		return errors.Wrap(err, "writing object")
+	}
+	read, err := readFileContents(ctx, store, name)
+	if err != nil {
+		return errors.Wrap(err, "reading object")
+	}
+	if !bytes.Equal(read, contents) {
+		return errors.Newf("read %q, expected %q", read, contents)
+	}
+	return nil
+}
+
+func checkOverwrite(ctx context.Context, store cloud.ExternalStorage) error {
+	const name = "overwrite"
+	if err := cloud.WriteFile(ctx, store, name, strings.NewReader("first")); err != nil {
+		return errors.Wrap(err, "writing object")
+	}
+	if err := cloud.WriteFile(ctx, store, name, strings.NewReader("second")); err != nil {
+		return errors.Wrap(err, "overwriting object")
+	}
+	read, err := readFileContents(ctx, store, name)
+	if err != nil {
+		return errors.Wrap(err, "reading object")
+	}
+	if string(read) != "second" {
+		return errors.Newf("read %q after overwrite, expected %q", read, "second")
+	}
+	return nil
+}
+
+func checkListOrder(ctx context.Context, store cloud.ExternalStorage) error {
+	const dir = "list-order/"
+	// Write the objects out of order so that a store which returns results in
+	// creation order is detected.
+	names := []string{"c", "a", "d", "b"}
+	for _, name := range names {
+		if err := cloud.WriteFile(ctx, store, dir+name, strings.NewReader(name)); err != nil {
+			return errors.Wrap(err, "writing object")
+		}
+	}
+	var listed []string
+	if err := store.List(ctx, dir, "", func(p string) error {
+		listed = append(listed, strings.TrimPrefix(p, "/"))
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "listing objects")
+	}
+	expected := append([]string(nil), names...)
+	sort.Strings(expected)
+	if strings.Join(listed, ",") != strings.Join(expected, ",") {
+		return errors.Newf("listed %v, expected %v", listed, expected)
+	}
+	return nil
+}
+
+// storeCompatIncrementalsDir is the scratch subdirectory that mimics the
+// layout of an incrementals directory.
+const storeCompatIncrementalsDir = "incrementals"
+
+func checkListDelimiter(
+	ctx context.Context, store cloud.ExternalStorage, incStore cloud.ExternalStorage,
+) error {
+	// Mimic the layout of an incrementals directory with two layers, each with
+	// data files that the delimiter should collapse.
+	layers := []string{"/20220101/120000.00", "/20220102/120000.00"}
+	for _, layer := range layers {
+		for _, name := range []string{
+			backupbase.BackupManifestName, "data/1.sst", "data/2.sst",
+		} {
+			p := storeCompatIncrementalsDir + layer + "/" + name
+			if err := cloud.WriteFile(ctx, store, p, strings.NewReader(name)); err != nil {
+				return errors.Wrap(err, "writing object")
+			}
+		}
+	}
+
+	if err := incStore.List(ctx, "", listingDelimDataSlash, func(p string) error {
+		if strings.Contains(p, "/"+listingDelimDataSlash) && !strings.HasSuffix(p, listingDelimDataSlash) {
+			return errors.Newf("listing returned %q which should have been grouped by delimiter %q",
+				p, listingDelimDataSlash)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	found, err := FindPriorBackups(ctx, incStore, OmitManifest)
+	if err != nil {
+		return err
+	}
+	if strings.Join(found, ",") != strings.Join(layers, ",") {
+		return errors.Newf("found incremental layers %v, expected %v", found, layers)
+	}
+	return nil
+}
+
+func checkTimestampedLatest(ctx context.Context, store cloud.ExternalStorage) error {
+	for _, suffix := range []string{"/first", "/second"} {
+		if err := WriteNewLatestFile(ctx, store.Settings(), store,
+			backuppb.LatestFile{Subdir: suffix}); err != nil {
+			return errors.Wrap(err, "writing LATEST file")
+		}
+	}
+	r, err := FindLatestFile(ctx, store)
+	if err != nil {
+		return errors.Wrap(err, "finding LATEST file")
+	}
+	defer r.Close(ctx)
+	data, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return errors.Wrap(err, "reading LATEST file")
+	}
+	latest, err := DecodeLatestFile(data)
+	if err != nil {
+		return errors.Wrap(err, "decoding LATEST file")
+	}
+	if latest.Subdir != "/second" {
+		return errors.Newf("resolved LATEST to %q, expected %q", latest.Subdir, "/second")
+	}
+	return nil
+}
+
+// cleanupStoreCompatScratch deletes every object written by the checks.
+func cleanupStoreCompatScratch(ctx context.Context, store cloud.ExternalStorage) error {
+	var toDelete []string
+	if err := store.List(ctx, "", "", func(p string) error {
+		toDelete = append(toDelete, strings.TrimPrefix(p, "/"))
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "listing objects to delete")
+	}
+	for _, p := range toDelete {
+		if err := store.Delete(ctx, p); err != nil {
+			return errors.Wrapf(err, "deleting %s", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupdest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckStoreCompatibility runs the store compatibility checks against
+// nodelocal storage, which supports every behavior backups rely upon.
+func TestCheckStoreCompatibility(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tc, _, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode, 1,
+		backuputils.InitManualReplication)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+	makeStore := execCfg.DistSQLSrv.ExternalStorageFromURI
+
+	uri := fmt.Sprintf("nodelocal://1/%s", t.Name())
+	report, err := backupdest.CheckStoreCompatibility(ctx, uri, makeStore, username.RootUserName())
+	require.NoError(t, err)
+
+	require.Len(t, report.Results, 6)
+	for _, res := range report.Results {
+		require.NoError(t, res.Err, "check %s", res.Check)
+	}
+	for _, f := range backupdest.StoreCompatFeatures {
+		require.True(t, report.Supports(f), "feature %s", f.Name)
+	}
+
+	// The checks must clean up after themselves.
+	store, err := makeStore(ctx, uri, username.RootUserName())
+	require.NoError(t, err)
+	defer store.Close()
+	var leftover []string
+	require.NoError(t, store.List(ctx, "", "", func(p string) error {
+		leftover = append(leftover, p)
+		return nil
+	}))
+	require.Empty(t, leftover)
+}
@@ -22,10 +22,12 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
 	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/errors"
 )
@@ -248,23 +250,210 @@ func MakeNewEncryptionOptions(
 			return nil, nil, errors.Wrap(err, "failed to generate DataKey")
 		}
 
-		encryptedDataKeyByKMSMasterKeyID, defaultKMSInfo, err :=
-			GetEncryptedDataKeyByKMSMasterKeyID(ctx, encryptionParams.RawKmsUris, plaintextDataKey, kmsEnv)
-		if err != nil {
-			return nil, nil, err
+		if encryptionParams.DecryptQuorum > 1 {
+			encryptionOptions, encryptionInfo, err = encryptDataKeyShares(ctx, encryptionParams.RawKmsUris,
+				encryptionParams.DecryptQuorum, plaintextDataKey, kmsEnv)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			encryptedDataKeyByKMSMasterKeyID, defaultKMSInfo, err :=
+				GetEncryptedDataKeyByKMSMasterKeyID(ctx, encryptionParams.RawKmsUris, plaintextDataKey, kmsEnv)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			encryptedDataKeyMapForProto := make(map[string][]byte)
+			encryptedDataKeyByKMSMasterKeyID.RangeOverMap(
+				func(masterKeyID HashedMasterKeyID, dataKey []byte) {
+					encryptedDataKeyMapForProto[string(masterKeyID)] = dataKey
+				})
+
+			encryptionInfo = &jobspb.EncryptionInfo{EncryptedDataKeyByKMSMasterKeyID: encryptedDataKeyMapForProto}
+			encryptionOptions = &jobspb.BackupEncryptionOptions{
+				Mode:    jobspb.EncryptionMode_KMS,
+				KMSInfo: defaultKMSInfo,
+			}
 		}
 
-		encryptedDataKeyMapForProto := make(map[string][]byte)
-		encryptedDataKeyByKMSMasterKeyID.RangeOverMap(
-			func(masterKeyID HashedMasterKeyID, dataKey []byte) {
-				encryptedDataKeyMapForProto[string(masterKeyID)] = dataKey
-			})
+		for localityKV, kmsURI := range encryptionParams.RawKMSURIsByLocalityKV {
+			if err := addLocalityDataKey(ctx, localityKV, kmsURI, encryptionOptions, encryptionInfo,
+				kmsEnv); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return encryptionOptions, encryptionInfo, nil
+}
 
-		encryptionInfo = &jobspb.EncryptionInfo{EncryptedDataKeyByKMSMasterKeyID: encryptedDataKeyMapForProto}
-		encryptionOptions = &jobspb.BackupEncryptionOptions{
-			Mode:    jobspb.EncryptionMode_KMS,
-			KMSInfo: defaultKMSInfo,
+// addLocalityDataKey generates a data key for the files written to the
+// destination of the locality with localityKV, encrypts it with the KMS of
+// kmsURI, and records it in the encryption options and info of a backup.
+func addLocalityDataKey(
+	ctx context.Context,
+	localityKV, kmsURI string,
+	encryptionOptions *jobspb.BackupEncryptionOptions,
+	encryptionInfo *jobspb.EncryptionInfo,
+	kmsEnv cloud.KMSEnv,
+) error {
+	plaintextDataKey := make([]byte, 32)
+	if _, err := cryptorand.Read(plaintextDataKey); err != nil {
+		return errors.Wrap(err, "failed to generate DataKey")
+	}
+	masterKeyID, encryptedDataKey, err := GetEncryptedDataKeyFromURI(ctx, plaintextDataKey, kmsURI, kmsEnv)
+	if err != nil {
+		return errors.Wrapf(err, "encrypting the data key of locality %s", localityKV)
+	}
+
+	if encryptionOptions.KMSInfoByLocalityKV == nil {
+		encryptionOptions.KMSInfoByLocalityKV = make(map[string]jobspb.BackupEncryptionOptions_KMSInfo)
+	}
+	encryptionOptions.KMSInfoByLocalityKV[localityKV] = jobspb.BackupEncryptionOptions_KMSInfo{
+		Uri:              kmsURI,
+		EncryptedDataKey: encryptedDataKey,
+	}
+
+	encryptedDataKeyByKMSMasterKeyID := NewEncryptedDataKeyMap()
+	encryptedDataKeyByKMSMasterKeyID.AddEncryptedDataKey(PlaintextMasterKeyID(masterKeyID), encryptedDataKey)
+	encryptedDataKeyMapForProto := make(map[string][]byte)
+	encryptedDataKeyByKMSMasterKeyID.RangeOverMap(
+		func(masterKeyID HashedMasterKeyID, dataKey []byte) {
+			encryptedDataKeyMapForProto[string(masterKeyID)] = dataKey
+		})
+	if encryptionInfo.EncryptionInfoByLocalityKV == nil {
+		encryptionInfo.EncryptionInfoByLocalityKV = make(map[string]jobspb.EncryptionInfo)
+	}
+	encryptionInfo.EncryptionInfoByLocalityKV[localityKV] = jobspb.EncryptionInfo{
+		EncryptedDataKeyByKMSMasterKeyID: encryptedDataKeyMapForProto,
+	}
+	return nil
+}
+
+// MakeLocalityKMSEncryptionOptions sets the KMS and encrypted data key pairs of
+// the localities of a backup encrypted with kms_by_locality in encryption, the
+// options to decrypt the rest of the backup. The data key of each locality
+// must be decrypted by the KMS URI given for it in kmsURIsByLocalityKV, as
+// recorded in one of the ENCRYPTION-INFO files of the backup, encInfos.
+func MakeLocalityKMSEncryptionOptions(
+	ctx context.Context,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsURIsByLocalityKV map[string]string,
+	encInfos []jobspb.EncryptionInfo,
+	kmsEnv cloud.KMSEnv,
+) error {
+	for _, encInfo := range encInfos {
+		for localityKV, localityEncInfo := range encInfo.EncryptionInfoByLocalityKV {
+			if _, ok := encryption.KMSInfoByLocalityKV[localityKV]; ok {
+				continue
+			}
+			kmsURI, ok := kmsURIsByLocalityKV[localityKV]
+			if !ok {
+				return errors.Newf("the files of locality %s are encrypted with a key of their own, "+
+					"whose KMS URI must be passed with kms_by_locality", localityKV)
+			}
+			kmsInfo, err := ValidateKMSURIsAgainstFullBackup(ctx, []string{kmsURI},
+				NewEncryptedDataKeyMapFromProtoMap(localityEncInfo.EncryptedDataKeyByKMSMasterKeyID), kmsEnv)
+			if err != nil {
+				return errors.Wrapf(err, "locality %s", localityKV)
+			}
+			if encryption.KMSInfoByLocalityKV == nil {
+				encryption.KMSInfoByLocalityKV = make(map[string]jobspb.BackupEncryptionOptions_KMSInfo)
+			}
+			encryption.KMSInfoByLocalityKV[localityKV] = *kmsInfo
+		}
+	}
+	for localityKV := range kmsURIsByLocalityKV {
+		if _, ok := encryption.KMSInfoByLocalityKV[localityKV]; !ok {
+			return errors.Newf("the files of locality %s are not encrypted with a key of their own", localityKV)
+		}
+	}
+	return nil
+}
+
+// LocalityEncryptionOptions returns the options to encrypt or decrypt the
+// files written to the destination of the locality with localityKV, which are
+// those of its own data key if it has one, and encryption otherwise.
+func LocalityEncryptionOptions(
+	encryption *jobspb.BackupEncryptionOptions, localityKV string,
+) *jobspb.BackupEncryptionOptions {
+	if encryption == nil {
+		return nil
+	}
+	kmsInfo, ok := encryption.KMSInfoByLocalityKV[localityKV]
+	if !ok {
+		return encryption
+	}
+	return &jobspb.BackupEncryptionOptions{
+		Mode:    jobspb.EncryptionMode_KMS,
+		KMSInfo: &kmsInfo,
+	}
+}
+
+// GetLocalityEncryptionKeys returns the decrypted data key of each locality of
+// a backup encrypted with kms_by_locality, keyed by the locality.
+func GetLocalityEncryptionKeys(
+	ctx context.Context, encryption *jobspb.BackupEncryptionOptions, kmsEnv cloud.KMSEnv,
+) (map[string]*roachpb.FileEncryptionOptions, error) {
+	if encryption == nil || len(encryption.KMSInfoByLocalityKV) == 0 {
+		return nil, nil
+	}
+	keys := make(map[string]*roachpb.FileEncryptionOptions, len(encryption.KMSInfoByLocalityKV))
+	for localityKV := range encryption.KMSInfoByLocalityKV {
+		key, err := GetEncryptionKey(ctx, LocalityEncryptionOptions(encryption, localityKV), kmsEnv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "locality %s", localityKV)
 		}
+		keys[localityKV] = &roachpb.FileEncryptionOptions{Key: key}
+	}
+	return keys, nil
+}
+
+// encryptDataKeyShares splits plaintextDataKey into one share per KMS URI,
+// any quorum of which recover it, and encrypts each share with its KMS.
+func encryptDataKeyShares(
+	ctx context.Context,
+	kmsURIs []string,
+	quorum int32,
+	plaintextDataKey []byte,
+	kmsEnv cloud.KMSEnv,
+) (*jobspb.BackupEncryptionOptions, *jobspb.EncryptionInfo, error) {
+	shares, err := splitSecret(plaintextDataKey, len(kmsURIs), int(quorum))
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedShareByKMSMasterKeyID := NewEncryptedDataKeyMap()
+	keyShares := make([]jobspb.BackupEncryptionOptions_KMSInfo, len(kmsURIs))
+	for i, kmsURI := range kmsURIs {
+		masterKeyID, encryptedShare, err := GetEncryptedDataKeyFromURI(ctx, shares[i], kmsURI, kmsEnv)
+		if err != nil {
+			return nil, nil, err
+		}
+		encryptedShareByKMSMasterKeyID.AddEncryptedDataKey(PlaintextMasterKeyID(masterKeyID),
+			encryptedShare)
+		keyShares[i] = jobspb.BackupEncryptionOptions_KMSInfo{
+			Uri:              kmsURI,
+			EncryptedDataKey: encryptedShare,
+		}
+	}
+	// Two URIs of the same master key would let that single key decrypt two
+	// shares.
+	if len(encryptedShareByKMSMasterKeyID.m) != len(kmsURIs) {
+		return nil, nil, errors.New("decrypt_quorum requires the KMS URIs to use distinct master keys")
+	}
+
+	encryptedShareMapForProto := make(map[string][]byte)
+	encryptedShareByKMSMasterKeyID.RangeOverMap(
+		func(masterKeyID HashedMasterKeyID, share []byte) {
+			encryptedShareMapForProto[string(masterKeyID)] = share
+		})
+	encryptionInfo := &jobspb.EncryptionInfo{
+		EncryptedDataKeyByKMSMasterKeyID: encryptedShareMapForProto,
+		DecryptQuorum:                    quorum,
+	}
+	encryptionOptions := &jobspb.BackupEncryptionOptions{
+		Mode:          jobspb.EncryptionMode_KMS,
+		DecryptQuorum: quorum,
+		KeyShares:     keyShares,
 	}
 	return encryptionOptions, encryptionInfo, nil
 }
@@ -384,25 +573,103 @@ func GetEncryptionFromBase(
 				Key:  storageccl.GenerateKey([]byte(encryptionParams.RawPassphrae), opts[0].Salt),
 			}
 		case jobspb.EncryptionMode_KMS:
-			var defaultKMSInfo *jobspb.BackupEncryptionOptions_KMSInfo
-			for _, encFile := range opts {
-				defaultKMSInfo, err = ValidateKMSURIsAgainstFullBackup(ctx, encryptionParams.RawKmsUris,
-					NewEncryptedDataKeyMapFromProtoMap(encFile.EncryptedDataKeyByKMSMasterKeyID), kmsEnv)
-				if err == nil {
-					break
-				}
-			}
+			encryptionOptions, err = MakeKMSEncryptionOptions(ctx, encryptionParams.RawKmsUris, opts,
+				kmsEnv)
 			if err != nil {
 				return nil, err
 			}
-			encryptionOptions = &jobspb.BackupEncryptionOptions{
-				Mode:    jobspb.EncryptionMode_KMS,
-				KMSInfo: defaultKMSInfo}
+			if err := MakeLocalityKMSEncryptionOptions(ctx, encryptionOptions,
+				encryptionParams.RawKMSURIsByLocalityKV, opts, kmsEnv); err != nil {
+				return nil, err
+			}
 		}
 	}
 	return encryptionOptions, nil
 }
 
+// MakeKMSEncryptionOptions returns the options to decrypt a backup with the
+// KMS URIs, which must have been used to encrypt it as recorded in one of the
+// ENCRYPTION-INFO files of the backup, encInfos.
+func MakeKMSEncryptionOptions(
+	ctx context.Context, kmsURIs []string, encInfos []jobspb.EncryptionInfo, kmsEnv cloud.KMSEnv,
+) (*jobspb.BackupEncryptionOptions, error) {
+	// A backup could have been encrypted with multiple KMS keys that are
+	// stored across ENCRYPTION-INFO files. Iterate over all ENCRYPTION-INFO
+	// files to check if the KMS URIs have been used to encrypt the backup at
+	// least once.
+	var err error
+	for _, encInfo := range encInfos {
+		if encInfo.DecryptQuorum > 1 {
+			var keyShares []jobspb.BackupEncryptionOptions_KMSInfo
+			keyShares, err = findEncryptedDataKeyShares(ctx, kmsURIs, encInfo, kmsEnv)
+			if err == nil {
+				return &jobspb.BackupEncryptionOptions{
+					Mode:          jobspb.EncryptionMode_KMS,
+					DecryptQuorum: encInfo.DecryptQuorum,
+					KeyShares:     keyShares,
+				}, nil
+			}
+			continue
+		}
+		var defaultKMSInfo *jobspb.BackupEncryptionOptions_KMSInfo
+		defaultKMSInfo, err = ValidateKMSURIsAgainstFullBackup(ctx, kmsURIs,
+			NewEncryptedDataKeyMapFromProtoMap(encInfo.EncryptedDataKeyByKMSMasterKeyID), kmsEnv)
+		if err == nil {
+			return &jobspb.BackupEncryptionOptions{
+				Mode:    jobspb.EncryptionMode_KMS,
+				KMSInfo: defaultKMSInfo,
+			}, nil
+		}
+	}
+	return nil, err
+}
+
+// findEncryptedDataKeyShares returns the KMS and encrypted key share pairs of
+// the KMS URIs in an ENCRYPTION-INFO file of a backup encrypted with a decrypt
+// quorum. KMS URIs whose master key cannot be determined, for instance
+// because the KMS is unreachable, are skipped, as long as enough remain to
+// reach the quorum.
+func findEncryptedDataKeyShares(
+	ctx context.Context, kmsURIs []string, encInfo jobspb.EncryptionInfo, kmsEnv cloud.KMSEnv,
+) ([]jobspb.BackupEncryptionOptions_KMSInfo, error) {
+	encryptedShares := NewEncryptedDataKeyMapFromProtoMap(encInfo.EncryptedDataKeyByKMSMasterKeyID)
+	var keyShares []jobspb.BackupEncryptionOptions_KMSInfo
+	for i, kmsURI := range kmsURIs {
+		id, err := kmsMasterKeyID(ctx, kmsURI, kmsEnv)
+		if err != nil {
+			log.Warningf(ctx, "skipping KMS URI %d of %d: %v", i+1, len(kmsURIs), err)
+			continue
+		}
+		encryptedShare, err := encryptedShares.getEncryptedDataKey(PlaintextMasterKeyID(id))
+		if err != nil {
+			return nil, errors.Wrap(err,
+				"one of the provided URIs was not used when encrypting the base BACKUP")
+		}
+		keyShares = append(keyShares, jobspb.BackupEncryptionOptions_KMSInfo{
+			Uri:              kmsURI,
+			EncryptedDataKey: encryptedShare,
+		})
+	}
+	if len(keyShares) < int(encInfo.DecryptQuorum) {
+		return nil, errors.Newf(
+			"the backup was encrypted with decrypt_quorum = %d, but only %d of the provided KMS URIs can be used",
+			encInfo.DecryptQuorum, len(keyShares))
+	}
+	return keyShares, nil
+}
+
+// kmsMasterKeyID returns the master key ID of the KMS specified by kmsURI.
+func kmsMasterKeyID(ctx context.Context, kmsURI string, kmsEnv cloud.KMSEnv) (string, error) {
+	kms, err := cloud.KMSFromURI(ctx, kmsURI, kmsEnv)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = kms.Close()
+	}()
+	return kms.MasterKeyID()
+}
+
 // GetEncryptionKey returns the decrypted plaintext data key to be used for
 // encryption.
 func GetEncryptionKey(
@@ -415,27 +682,58 @@ func GetEncryptionKey(
 	case jobspb.EncryptionMode_Passphrase:
 		return encryption.Key, nil
 	case jobspb.EncryptionMode_KMS:
+		if encryption.DecryptQuorum > 1 {
+			return decryptDataKeyShares(ctx, encryption, kmsEnv)
+		}
 		// Contact the selected KMS to derive the decrypted data key.
 		// TODO(pbardea): Add a check here if encryption.KMSInfo is unexpectedly nil
 		// here to avoid a panic, and return an error instead.
-		kms, err := cloud.KMSFromURI(ctx, encryption.KMSInfo.Uri, kmsEnv)
+		plaintextDataKey, err := decryptWithKMS(ctx, *encryption.KMSInfo, kmsEnv)
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, "failed to decrypt data key")
 		}
+		return plaintextDataKey, nil
+	}
 
-		defer func() {
-			_ = kms.Close()
-		}()
+	return nil, errors.New("invalid encryption mode")
+}
 
-		plaintextDataKey, err := kms.Decrypt(ctx, encryption.KMSInfo.EncryptedDataKey)
+// decryptDataKeyShares decrypts key shares with their KMS until it has
+// enough to reach the decrypt quorum, and combines them into the data key.
+func decryptDataKeyShares(
+	ctx context.Context, encryption *jobspb.BackupEncryptionOptions, kmsEnv cloud.KMSEnv,
+) ([]byte, error) {
+	quorum := int(encryption.DecryptQuorum)
+	shares := make([][]byte, 0, quorum)
+	var decryptErr error
+	for _, keyShare := range encryption.KeyShares {
+		share, err := decryptWithKMS(ctx, keyShare, kmsEnv)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to decrypt data key")
+			decryptErr = errors.CombineErrors(decryptErr, err)
+			continue
+		}
+		shares = append(shares, share)
+		if len(shares) == quorum {
+			return combineShares(shares)
 		}
-
-		return plaintextDataKey, nil
 	}
+	return nil, errors.CombineErrors(errors.Newf(
+		"failed to decrypt data key: decrypted %d of the %d key shares it needs",
+		len(shares), quorum), decryptErr)
+}
 
-	return nil, errors.New("invalid encryption mode")
+// decryptWithKMS decrypts the encrypted data key of kmsInfo with its KMS.
+func decryptWithKMS(
+	ctx context.Context, kmsInfo jobspb.BackupEncryptionOptions_KMSInfo, kmsEnv cloud.KMSEnv,
+) ([]byte, error) {
+	kms, err := cloud.KMSFromURI(ctx, kmsInfo.Uri, kmsEnv)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = kms.Close()
+	}()
+	return kms.Decrypt(ctx, kmsInfo.EncryptedDataKey)
 }
 
 // ReadEncryptionOptions takes in a backup location and tries to find
@@ -459,18 +757,8 @@ func ReadEncryptionOptions(
 	// encrypt the backup recently, so we iterate the ENCRYPTION-INFO
 	// files from latest to oldest.
 	for i := len(files) - 1; i >= 0; i-- {
-		r, err := src.ReadFile(ctx, files[i])
+		currentEncInfo, err := ReadEncryptionInfoFile(ctx, src, files[i])
 		if err != nil {
-			return nil, errors.Wrap(err, encryptionReadErrorMsg)
-		}
-		defer r.Close(ctx)
-
-		encInfoBytes, err := ioctx.ReadAll(ctx, r)
-		if err != nil {
-			return nil, errors.Wrap(err, encryptionReadErrorMsg)
-		}
-		var currentEncInfo jobspb.EncryptionInfo
-		if err := protoutil.Unmarshal(encInfoBytes, &currentEncInfo); err != nil {
 			return nil, err
 		}
 		encInfo = append(encInfo, currentEncInfo)
@@ -478,6 +766,60 @@ func ReadEncryptionOptions(
 	return encInfo, nil
 }
 
+// ReadEncryptionInfoFile reads the ENCRYPTION-INFO file with the given name.
+func ReadEncryptionInfoFile(
+	ctx context.Context, src cloud.ExternalStorage, file string,
+) (jobspb.EncryptionInfo, error) {
+	const encryptionReadErrorMsg = `could not find or read encryption information`
+
+	r, err := src.ReadFile(ctx, file)
+	if err != nil {
+		return jobspb.EncryptionInfo{}, errors.Wrap(err, encryptionReadErrorMsg)
+	}
+	defer r.Close(ctx)
+
+	encInfoBytes, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return jobspb.EncryptionInfo{}, errors.Wrap(err, encryptionReadErrorMsg)
+	}
+	var encInfo jobspb.EncryptionInfo
+	if err := protoutil.Unmarshal(encInfoBytes, &encInfo); err != nil {
+		return jobspb.EncryptionInfo{}, err
+	}
+	return encInfo, nil
+}
+
+// CheckKMSURIAgainstEncryptionInfo checks that the data key in an
+// ENCRYPTION-INFO file can be decrypted with the KMS URI. It returns whether
+// the file has a data key encrypted with the master key of the KMS, and an
+// error if the KMS could not be reached or the data key could not be
+// decrypted.
+func CheckKMSURIAgainstEncryptionInfo(
+	ctx context.Context, kmsURI string, encInfo jobspb.EncryptionInfo, kmsEnv cloud.KMSEnv,
+) (bool, error) {
+	kms, err := cloud.KMSFromURI(ctx, kmsURI, kmsEnv)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = kms.Close()
+	}()
+
+	id, err := kms.MasterKeyID()
+	if err != nil {
+		return false, err
+	}
+	encryptedDataKey, err := NewEncryptedDataKeyMapFromProtoMap(
+		encInfo.EncryptedDataKeyByKMSMasterKeyID).getEncryptedDataKey(PlaintextMasterKeyID(id))
+	if err != nil {
+		return false, nil //nolint:returnerrcheck
+	}
+	if _, err := kms.Decrypt(ctx, encryptedDataKey); err != nil {
+		return true, errors.Wrap(err, "failed to decrypt data key")
+	}
+	return true, nil
+}
+
 // GetEncryptionInfoFiles reads the ENCRYPTION-INFO files from external storage.
 func GetEncryptionInfoFiles(ctx context.Context, dest cloud.ExternalStorage) ([]string, error) {
 	var files []string
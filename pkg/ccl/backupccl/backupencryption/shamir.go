@@ -0,0 +1,140 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupencryption
+
+import (
+	cryptorand "crypto/rand"
+
+	"github.com/cockroachdb/errors"
+)
+
+// The data key of a backup encrypted with a decrypt quorum is split with
+// Shamir's secret sharing scheme over GF(2^8): every byte of the key is the
+// constant term of a random polynomial of degree quorum-1, and a share holds
+// the value of each of those polynomials at the x coordinate of the share,
+// which is stored in its last byte. Any quorum of shares recover the key by
+// interpolating the polynomials at zero, while fewer reveal nothing about it.
+
+// maxShares is the number of distinct non-zero x coordinates in GF(2^8).
+const maxShares = 255
+
+// gfExp and gfLog are the exponent and logarithm tables of GF(2^8) with the
+// AES reduction polynomial x^8 + x^4 + x^3 + x + 1, for the generator 3.
+var gfExp, gfLog [256]byte
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+		// Multiply x by the generator, x+1.
+		xtime := x << 1
+		if x&0x80 != 0 {
+			xtime ^= 0x1b
+		}
+		x ^= xtime
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])+int(gfLog[b]))%255]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])-int(gfLog[b])+255)%255]
+}
+
+// splitSecret splits secret into n shares, any quorum of which recover it.
+func splitSecret(secret []byte, n, quorum int) ([][]byte, error) {
+	if quorum < 2 || quorum > n {
+		return nil, errors.AssertionFailedf("invalid quorum %d for %d shares", quorum, n)
+	}
+	if n > maxShares {
+		return nil, errors.Newf("cannot split a secret into more than %d shares", maxShares)
+	}
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+	coefficients := make([]byte, quorum)
+	for b, s := range secret {
+		coefficients[0] = s
+		if _, err := cryptorand.Read(coefficients[1:]); err != nil {
+			return nil, errors.Wrap(err, "failed to generate key share")
+		}
+		for _, share := range shares {
+			// Evaluate the polynomial at the x coordinate of the share with
+			// Horner's method.
+			x := share[len(secret)]
+			var y byte
+			for c := quorum - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			share[b] = y
+		}
+	}
+	return shares, nil
+}
+
+// combineShares recovers the secret that was split into shares. It returns a
+// different secret if fewer shares than the quorum of the split are passed.
+func combineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.AssertionFailedf("no key shares to combine")
+	}
+	n := len(shares[0]) - 1
+	if n < 0 {
+		return nil, errors.New("empty key share")
+	}
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		if len(share) != n+1 {
+			return nil, errors.New("key shares have different lengths")
+		}
+		xs[i] = share[n]
+		if xs[i] == 0 {
+			return nil, errors.New("key share has an invalid x coordinate")
+		}
+		for j := 0; j < i; j++ {
+			if xs[j] == xs[i] {
+				return nil, errors.New("duplicate key shares")
+			}
+		}
+	}
+
+	// Interpolate the polynomials at zero: the secret is the sum over the
+	// shares of y_i times the Lagrange basis polynomial of x_i evaluated at
+	// zero, prod_{j != i} x_j / (x_j - x_i). Subtraction is xor in GF(2^8).
+	basis := make([]byte, len(shares))
+	for i := range shares {
+		num, den := byte(1), byte(1)
+		for j := range shares {
+			if i == j {
+				continue
+			}
+			num = gfMul(num, xs[j])
+			den = gfMul(den, xs[j]^xs[i])
+		}
+		basis[i] = gfDiv(num, den)
+	}
+	secret := make([]byte, n)
+	for b := range secret {
+		for i, share := range shares {
+			secret[b] ^= gfMul(share[b], basis[i])
+		}
+	}
+	return secret, nil
+}
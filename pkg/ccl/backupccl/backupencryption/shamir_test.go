@@ -0,0 +1,79 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupencryption
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/randutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGaloisField(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Every non-zero element has a distinct logarithm.
+	for a := 1; a < 256; a++ {
+		require.Equal(t, byte(a), gfExp[gfLog[a]])
+	}
+	require.Equal(t, byte(0xc1), gfMul(0x57, 0x83))
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			require.Equal(t, byte(a), gfDiv(gfMul(byte(a), byte(b)), byte(b)))
+		}
+	}
+}
+
+func TestSplitAndCombineShares(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	rng, _ := randutil.NewTestRand()
+	secret := randutil.RandBytes(rng, 32)
+
+	for _, tc := range []struct{ n, quorum int }{{2, 2}, {3, 2}, {5, 3}, {7, 7}} {
+		shares, err := splitSecret(secret, tc.n, tc.quorum)
+		require.NoError(t, err)
+		require.Len(t, shares, tc.n)
+
+		// Any quorum of shares, in any order, recover the secret.
+		for i := 0; i < 10; i++ {
+			perm := rng.Perm(tc.n)
+			subset := make([][]byte, tc.quorum)
+			for j := range subset {
+				subset[j] = shares[perm[j]]
+			}
+			combined, err := combineShares(subset)
+			require.NoError(t, err)
+			require.Equal(t, secret, combined)
+		}
+
+		// So do more shares than the quorum.
+		combined, err := combineShares(shares)
+		require.NoError(t, err)
+		require.Equal(t, secret, combined)
+
+		// Fewer do not.
+		combined, err = combineShares(shares[:tc.quorum-1])
+		require.NoError(t, err)
+		require.NotEqual(t, secret, combined)
+	}
+
+	_, err := splitSecret(secret, 3, 4)
+	require.Error(t, err)
+	_, err = splitSecret(secret, 256, 2)
+	require.Error(t, err)
+
+	shares, err := splitSecret(secret, 3, 2)
+	require.NoError(t, err)
+	_, err = combineShares([][]byte{shares[0], shares[0]})
+	require.Error(t, err)
+	_, err = combineShares([][]byte{shares[0], shares[1][1:]})
+	require.Error(t, err)
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
@@ -57,13 +58,15 @@ var iterOpts = storage.IterOptions{
 
 // WriteBackupMetadataSST is responsible for constructing and writing the
 // `metadata.sst` to dest. This file contains the metadata corresponding to this
-// backup.
+// backup. The files of the backup are those of files if it is non-nil, and
+// those of manifest otherwise.
 func WriteBackupMetadataSST(
 	ctx context.Context,
 	dest cloud.ExternalStorage,
 	enc *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
 	manifest *backuppb.BackupManifest,
+	files *FileList,
 	stats []*stats.TableStatisticProto,
 ) error {
 	var w io.WriteCloser
@@ -80,7 +83,7 @@ func WriteBackupMetadataSST(
 		return err
 	}
 
-	if err := constructMetadataSST(ctx, dest, enc, kmsEnv, w, manifest, stats); err != nil {
+	if err := constructMetadataSST(ctx, dest, enc, kmsEnv, w, manifest, files, stats); err != nil {
 		return err
 	}
 
@@ -124,10 +127,12 @@ func constructMetadataSST(
 	kmsEnv cloud.KMSEnv,
 	w io.Writer,
 	m *backuppb.BackupManifest,
+	files *FileList,
 	stats []*stats.TableStatisticProto,
 ) error {
 	// TODO(dt): use a seek-optimized SST writer instead.
-	sst := storage.MakeBackupSSTWriter(ctx, dest.Settings(), w)
+	sst := storage.MakeBackupSSTWriterWithCompression(ctx, dest.Settings(), w,
+		metadataSSTCompression(m))
 	defer sst.Close()
 
 	// The following steps must be done in-order, by key prefix.
@@ -140,7 +145,7 @@ func constructMetadataSST(
 		return err
 	}
 
-	if err := writeFilesToMetadata(ctx, sst, m, dest, enc, kmsEnv, FileInfoPath); err != nil {
+	if err := writeFilesToMetadata(ctx, sst, m, files, dest, enc, kmsEnv, FileInfoPath); err != nil {
 		return err
 	}
 
@@ -248,6 +253,7 @@ func writeFilesToMetadata(
 	ctx context.Context,
 	sst storage.SSTWriter,
 	m *backuppb.BackupManifest,
+	files *FileList,
 	dest cloud.ExternalStorage,
 	enc *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
@@ -258,23 +264,33 @@ func writeFilesToMetadata(
 		return err
 	}
 	defer w.Close()
-	fileSST := storage.MakeBackupSSTWriter(ctx, dest.Settings(), w)
+	fileSST := storage.MakeBackupSSTWriterWithCompression(ctx, dest.Settings(), w,
+		metadataSSTCompression(m))
 	defer fileSST.Close()
 
-	// Sort and write all of the files into a single file info SST.
-	sort.Slice(m.Files, func(i, j int) bool {
-		cmp := m.Files[i].Span.Key.Compare(m.Files[j].Span.Key)
-		return cmp < 0 || (cmp == 0 && strings.Compare(m.Files[i].Path, m.Files[j].Path) < 0)
-	})
-
-	for _, i := range m.Files {
-		b, err := protoutil.Marshal(&i)
+	writeFile := func(f *backuppb.BackupManifest_File) error {
+		b, err := protoutil.Marshal(f)
 		if err != nil {
 			return err
 		}
-		if err := fileSST.PutUnversioned(encodeFileSSTKey(i.Span.Key, i.Path), b); err != nil {
+		return fileSST.PutUnversioned(encodeFileSSTKey(f.Span.Key, f.Path), b)
+	}
+	if files != nil {
+		// The list iterates over its files in the order of their keys.
+		if err := files.Iterate(ctx, writeFile); err != nil {
 			return err
 		}
+	} else {
+		// Sort and write all of the files into a single file info SST.
+		sort.Slice(m.Files, func(i, j int) bool {
+			cmp := m.Files[i].Span.Key.Compare(m.Files[j].Span.Key)
+			return cmp < 0 || (cmp == 0 && strings.Compare(m.Files[i].Path, m.Files[j].Path) < 0)
+		})
+		for i := range m.Files {
+			if err := writeFile(&m.Files[i]); err != nil {
+				return err
+			}
+		}
 	}
 
 	err = fileSST.Finish()
@@ -1038,6 +1054,87 @@ func (fi *FileIterator) Next(file *backuppb.BackupManifest_File) bool {
 	return true
 }
 
+// LayerFileIterator iterates over the files of a single backup layer.
+type LayerFileIterator interface {
+	// Next behaves like FileIterator.Next.
+	Next(file *backuppb.BackupManifest_File) bool
+	// Err returns the iterator's error.
+	Err() error
+	// Close closes the iterator.
+	Close()
+}
+
+var _ LayerFileIterator = &FileIterator{}
+
+// sliceFileIterator is a LayerFileIterator over a file list that is already in
+// memory.
+type sliceFileIterator struct {
+	files []backuppb.BackupManifest_File
+	idx   int
+}
+
+var _ LayerFileIterator = &sliceFileIterator{}
+var _ LayerFileIterator = &backupread.ManifestFileIterator{}
+
+// Next implements the LayerFileIterator interface.
+func (si *sliceFileIterator) Next(file *backuppb.BackupManifest_File) bool {
+	if si.idx >= len(si.files) {
+		return false
+	}
+	*file = si.files[si.idx]
+	si.idx++
+	return true
+}
+
+// Err implements the LayerFileIterator interface.
+func (si *sliceFileIterator) Err() error {
+	return nil
+}
+
+// Close implements the LayerFileIterator interface.
+func (si *sliceFileIterator) Close() {}
+
+// NewLayerFileIter returns an iterator over the files of the backup layer
+// described by manifest, whose metadata lives in store.
+//
+// If the manifest was loaded with its file list, the iterator walks that list
+// in place. Otherwise the files are streamed from the layer's metadata SST, if
+// it has one, or else from its manifest, so that a caller that stops iterating
+// early never unmarshals the remainder of the file list.
+func NewLayerFileIter(
+	ctx context.Context,
+	store cloud.ExternalStorage,
+	manifest *backuppb.BackupManifest,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+) (LayerFileIterator, error) {
+	if len(manifest.Files) > 0 {
+		return &sliceFileIterator{files: manifest.Files}, nil
+	}
+
+	r, err := store.ReadFile(ctx, MetadataSSTName)
+	if err != nil {
+		if errors.Is(err, cloud.ErrFileDoesNotExist) {
+			// The manifest was read without its file list, or has none.
+			it, err := backupread.NewManifestFileIter(ctx, store, encryption, kmsEnv)
+			if err != nil {
+				return nil, err
+			}
+			return it, nil
+		}
+		return nil, err
+	}
+	if err := r.Close(ctx); err != nil {
+		return nil, err
+	}
+
+	meta, err := NewBackupMetadata(ctx, store, MetadataSSTName, encryption, kmsEnv)
+	if err != nil {
+		return nil, err
+	}
+	return meta.NewFileIter(ctx)
+}
+
 // DescIterator is a simple iterator to iterate over descpb.Descriptors.
 type DescIterator struct {
 	backing bytesIter
@@ -0,0 +1,152 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupinfo
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+)
+
+// ChainFileIterator iterates over the files of every layer of a backup chain
+// in the order of their spans, as if the file lists of the layers were merged,
+// without building the merged list. It only holds the next file of each layer,
+// so the memory it needs grows with the length of the chain rather than with
+// the number of files in it.
+//
+// The files of each layer must be sorted by span, as they are when written.
+// Files with equal spans are returned in the order of their layers.
+type ChainFileIterator struct {
+	layers []LayerFileIterator
+	heap   chainFileHeap
+	// started is set once the heap holds the first file of every layer.
+	started bool
+	err     error
+}
+
+// chainFileHeapItem is the next file of a layer.
+type chainFileHeapItem struct {
+	file  backuppb.BackupManifest_File
+	layer int
+}
+
+type chainFileHeap []chainFileHeapItem
+
+var _ heap.Interface = &chainFileHeap{}
+
+func (h chainFileHeap) Len() int      { return len(h) }
+func (h chainFileHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h chainFileHeap) Less(i, j int) bool {
+	if cmp := bytes.Compare(h[i].file.Span.Key, h[j].file.Span.Key); cmp != 0 {
+		return cmp < 0
+	}
+	if cmp := bytes.Compare(h[i].file.Span.EndKey, h[j].file.Span.EndKey); cmp != 0 {
+		return cmp < 0
+	}
+	return h[i].layer < h[j].layer
+}
+func (h *chainFileHeap) Push(x interface{}) { *h = append(*h, x.(chainFileHeapItem)) }
+func (h *chainFileHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// MergeLayerFileIters returns an iterator over the files of layers, the i-th
+// of which iterates over the files of layer i of a chain. The returned
+// iterator takes ownership of layers, and closes them when it is closed.
+func MergeLayerFileIters(layers []LayerFileIterator) *ChainFileIterator {
+	return &ChainFileIterator{layers: layers, heap: make(chainFileHeap, 0, len(layers))}
+}
+
+// NewChainFileIter returns an iterator over the files of the chain of backups
+// described by manifests, whose metadata lives in stores. Each layer is read
+// as by NewLayerFileIter. stores may be nil if every manifest was loaded with
+// its file list.
+func NewChainFileIter(
+	ctx context.Context,
+	stores []cloud.ExternalStorage,
+	manifests []backuppb.BackupManifest,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+) (*ChainFileIterator, error) {
+	layers := make([]LayerFileIterator, 0, len(manifests))
+	for i := range manifests {
+		if stores == nil {
+			layers = append(layers, &sliceFileIterator{files: manifests[i].Files})
+			continue
+		}
+		it, err := NewLayerFileIter(ctx, stores[i], &manifests[i], encryption, kmsEnv)
+		if err != nil {
+			for _, l := range layers {
+				l.Close()
+			}
+			return nil, err
+		}
+		layers = append(layers, it)
+	}
+	return MergeLayerFileIters(layers), nil
+}
+
+// advance pushes the next file of layer onto the heap, if it has one.
+func (ci *ChainFileIterator) advance(layer int) bool {
+	item := chainFileHeapItem{layer: layer}
+	if ci.layers[layer].Next(&item.file) {
+		heap.Push(&ci.heap, item)
+		return true
+	}
+	ci.err = ci.layers[layer].Err()
+	return ci.err == nil
+}
+
+// Next sets file to the next file of the chain, and returns the layer it
+// belongs to. ok is false once every file was returned, or if the iterator
+// failed, in which case Err returns the error.
+func (ci *ChainFileIterator) Next(file *backuppb.BackupManifest_File) (layer int, ok bool) {
+	if ci.err != nil {
+		return 0, false
+	}
+	if !ci.started {
+		ci.started = true
+		for i := range ci.layers {
+			if !ci.advance(i) {
+				if ci.err != nil {
+					return 0, false
+				}
+			}
+		}
+	}
+	if ci.heap.Len() == 0 {
+		return 0, false
+	}
+	item := heap.Pop(&ci.heap).(chainFileHeapItem)
+	if !ci.advance(item.layer) && ci.err != nil {
+		return 0, false
+	}
+	*file = item.file
+	return item.layer, true
+}
+
+// Err returns the iterator's error.
+func (ci *ChainFileIterator) Err() error {
+	return ci.err
+}
+
+// Close closes the iterators of every layer.
+func (ci *ChainFileIterator) Close() {
+	for _, l := range ci.layers {
+		l.Close()
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupinfo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/randutil"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// failingFileIterator returns its files, and then fails.
+type failingFileIterator struct {
+	sliceFileIterator
+}
+
+func (fi *failingFileIterator) Err() error {
+	return errors.New("boom")
+}
+
+func TestChainFileIterator(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	rng, _ := randutil.NewTestRand()
+	key := func(i int) roachpb.Key { return roachpb.Key(fmt.Sprintf("%04d", i)) }
+
+	type layerFile struct {
+		layer int
+		file  backuppb.BackupManifest_File
+	}
+	for _, numLayers := range []int{0, 1, 2, 5} {
+		t.Run(fmt.Sprintf("layers=%d", numLayers), func(t *testing.T) {
+			manifests := make([]backuppb.BackupManifest, numLayers)
+			var expected []layerFile
+			for layer := range manifests {
+				for i, n := 0, rng.Intn(20); i < n; i++ {
+					start := rng.Intn(100)
+					f := backuppb.BackupManifest_File{
+						Span: roachpb.Span{Key: key(start), EndKey: key(start + 1 + rng.Intn(10))},
+						Path: fmt.Sprintf("%d-%d.sst", layer, i),
+					}
+					manifests[layer].Files = append(manifests[layer].Files, f)
+					expected = append(expected, layerFile{layer: layer, file: f})
+				}
+				sort.Sort(BackupFileDescriptors(manifests[layer].Files))
+			}
+			// The files are expected in the order of their spans, and then of
+			// their layers.
+			sort.SliceStable(expected, func(i, j int) bool {
+				pair := BackupFileDescriptors{expected[i].file, expected[j].file}
+				if pair.Less(0, 1) || pair.Less(1, 0) {
+					return pair.Less(0, 1)
+				}
+				return expected[i].layer < expected[j].layer
+			})
+
+			it, err := NewChainFileIter(ctx, nil /* stores */, manifests, nil /* encryption */, nil /* kmsEnv */)
+			require.NoError(t, err)
+			defer it.Close()
+			var actual []layerFile
+			var file backuppb.BackupManifest_File
+			for {
+				layer, ok := it.Next(&file)
+				if !ok {
+					break
+				}
+				actual = append(actual, layerFile{layer: layer, file: file})
+			}
+			require.NoError(t, it.Err())
+			require.Equal(t, len(expected), len(actual))
+			for i := range expected {
+				require.Equal(t, expected[i].layer, actual[i].layer, "file %d", i)
+				require.Equal(t, expected[i].file.Span, actual[i].file.Span, "file %d", i)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		files := []backuppb.BackupManifest_File{{Span: roachpb.Span{Key: key(1), EndKey: key(2)}}}
+		it := MergeLayerFileIters([]LayerFileIterator{
+			&sliceFileIterator{files: files},
+			&failingFileIterator{sliceFileIterator{files: files}},
+		})
+		defer it.Close()
+		var file backuppb.BackupManifest_File
+		for {
+			if _, ok := it.Next(&file); !ok {
+				break
+			}
+		}
+		require.EqualError(t, it.Err(), "boom")
+	})
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupinfo
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/sstable"
+	gzip "github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The codecs the files of a backup may be compressed with. The data SSTs can
+// be compressed with snappy or zstd, and the metadata with gzip or zstd.
+const (
+	CompressionCodecGzip   = "gzip"
+	CompressionCodecSnappy = "snappy"
+	CompressionCodecZstd   = "zstd"
+)
+
+const (
+	compressionTargetData     = "data"
+	compressionTargetMetadata = "metadata"
+	compressionLevelPrefix    = "level="
+)
+
+// ParseCompression parses the value of the compression option of BACKUP.
+//
+// The value is a codec, optionally followed by a level, e.g. 'zstd:level=7',
+// which compresses every file of the backup that the codec applies to, or a
+// comma-separated list of codecs prefixed by the files they apply to, e.g.
+// 'data=zstd,metadata=gzip:level=9'. Levels only apply to the metadata: the
+// storage engine compresses the blocks of data SSTs at a fixed level for each
+// codec. An empty value leaves every file to its default codec.
+func ParseCompression(spec string) (backuppb.Compression, error) {
+	var c backuppb.Compression
+	if strings.TrimSpace(spec) == "" {
+		return c, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		target := ""
+		for _, t := range []string{compressionTargetData, compressionTargetMetadata} {
+			if strings.HasPrefix(part, t+"=") {
+				target, part = t, strings.TrimPrefix(part, t+"=")
+				break
+			}
+		}
+		codec, level := part, 0
+		if i := strings.IndexByte(part, ':'); i >= 0 {
+			codec = part[:i]
+			opt := part[i+1:]
+			if !strings.HasPrefix(opt, compressionLevelPrefix) {
+				return c, pgerror.Newf(pgcode.InvalidParameterValue,
+					"invalid compression option %q; expected %s<level>", opt, compressionLevelPrefix)
+			}
+			var err error
+			if level, err = strconv.Atoi(strings.TrimPrefix(opt, compressionLevelPrefix)); err != nil {
+				return c, pgerror.Wrapf(err, pgcode.InvalidParameterValue, "invalid compression level")
+			}
+		}
+
+		data := target != compressionTargetMetadata &&
+			(codec == CompressionCodecSnappy || codec == CompressionCodecZstd)
+		metadata := target != compressionTargetData &&
+			(codec == CompressionCodecGzip || codec == CompressionCodecZstd)
+		switch {
+		case !data && !metadata && target == "":
+			return c, pgerror.Newf(pgcode.InvalidParameterValue,
+				"unknown compression codec %q; expected one of %s, %s or %s",
+				codec, CompressionCodecGzip, CompressionCodecSnappy, CompressionCodecZstd)
+		case !data && !metadata:
+			return c, pgerror.Newf(pgcode.InvalidParameterValue,
+				"%s cannot be compressed with %q", target, codec)
+		case level != 0 && !metadata:
+			return c, pgerror.Newf(pgcode.InvalidParameterValue,
+				"compression levels only apply to metadata")
+		}
+		if data {
+			if c.DataCodec != "" {
+				return c, pgerror.Newf(pgcode.InvalidParameterValue,
+					"compression of data specified multiple times")
+			}
+			c.DataCodec = codec
+		}
+		if metadata {
+			if c.MetadataCodec != "" {
+				return c, pgerror.Newf(pgcode.InvalidParameterValue,
+					"compression of metadata specified multiple times")
+			}
+			if err := checkCompressionLevel(codec, level); err != nil {
+				return c, err
+			}
+			c.MetadataCodec = codec
+			c.MetadataLevel = int32(level)
+		}
+	}
+	return c, nil
+}
+
+// checkCompressionLevel checks that level, if set, is a level of codec.
+func checkCompressionLevel(codec string, level int) error {
+	if level == 0 {
+		return nil
+	}
+	lo, hi := 1, 0
+	switch codec {
+	case CompressionCodecGzip:
+		lo, hi = gzip.BestSpeed, gzip.BestCompression
+	case CompressionCodecZstd:
+		hi = 22
+	}
+	if level < lo || level > hi {
+		return pgerror.Newf(pgcode.InvalidParameterValue,
+			"compression level of %s must be between %d and %d", codec, lo, hi)
+	}
+	return nil
+}
+
+// DataSSTCompression returns the compression of the blocks of data SSTs
+// compressed with codec.
+func DataSSTCompression(codec string) sstable.Compression {
+	switch codec {
+	case CompressionCodecSnappy:
+		return sstable.SnappyCompression
+	case CompressionCodecZstd:
+		return sstable.ZstdCompression
+	default:
+		return sstable.DefaultCompression
+	}
+}
+
+// metadataSSTCompression returns the compression of the blocks of the
+// metadata SSTs of the backup described by m. Like the data SSTs, they can
+// only be compressed with the codecs of the storage engine, so they are only
+// compressed with the codec of the rest of the metadata if it is zstd.
+func metadataSSTCompression(m *backuppb.BackupManifest) sstable.Compression {
+	if m.Compression.MetadataCodec == CompressionCodecZstd {
+		return sstable.ZstdCompression
+	}
+	return sstable.DefaultCompression
+}
+
+// newMetadataCompressor returns a writer which compresses what is written to
+// it into w, with the codec and level of the metadata of c.
+func newMetadataCompressor(c backuppb.Compression, w io.Writer) (io.WriteCloser, error) {
+	switch c.MetadataCodec {
+	case "", CompressionCodecGzip:
+		level := gzip.DefaultCompression
+		if c.MetadataLevel != 0 {
+			level = int(c.MetadataLevel)
+		}
+		return gzip.NewWriterLevel(w, level)
+	case CompressionCodecZstd:
+		level := zstd.SpeedDefault
+		if c.MetadataLevel != 0 {
+			level = zstd.EncoderLevelFromZstd(int(c.MetadataLevel))
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+	default:
+		return nil, errors.AssertionFailedf("unsupported metadata compression codec %q", c.MetadataCodec)
+	}
+}
+
+// compressData compresses data buffer with the codec of the metadata of c
+// and returns compressed bytes.
+func compressData(c backuppb.Compression, descBuf []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := newMetadataCompressor(c, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(descBuf); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupinfo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCompression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	for _, tc := range []struct {
+		spec     string
+		expected backuppb.Compression
+		err      string
+	}{
+		{spec: ""},
+		{spec: "zstd", expected: backuppb.Compression{DataCodec: "zstd", MetadataCodec: "zstd"}},
+		{spec: "ZSTD:level=7", expected: backuppb.Compression{DataCodec: "zstd", MetadataCodec: "zstd", MetadataLevel: 7}},
+		{spec: "gzip:level=9", expected: backuppb.Compression{MetadataCodec: "gzip", MetadataLevel: 9}},
+		{spec: "snappy", expected: backuppb.Compression{DataCodec: "snappy"}},
+		{spec: "data=zstd, metadata=gzip:level=1", expected: backuppb.Compression{DataCodec: "zstd", MetadataCodec: "gzip", MetadataLevel: 1}},
+		{spec: "lz4", err: "unknown compression codec"},
+		{spec: "data=gzip", err: `data cannot be compressed with "gzip"`},
+		{spec: "metadata=snappy", err: `metadata cannot be compressed with "snappy"`},
+		{spec: "snappy:level=3", err: "compression levels only apply to metadata"},
+		{spec: "zstd:fast", err: "invalid compression option"},
+		{spec: "zstd:level=x", err: "invalid compression level"},
+		{spec: "zstd:level=23", err: "must be between 1 and 22"},
+		{spec: "gzip:level=10", err: "must be between 1 and 9"},
+		{spec: "zstd,data=snappy", err: "compression of data specified multiple times"},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			c, err := ParseCompression(tc.spec)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, c)
+		})
+	}
+}
+
+func TestCompressData(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	data := []byte("the metadata of a backup, the metadata of a backup")
+	for _, c := range []backuppb.Compression{
+		{},
+		{MetadataCodec: CompressionCodecGzip, MetadataLevel: 9},
+		{MetadataCodec: CompressionCodecZstd},
+		{MetadataCodec: CompressionCodecZstd, MetadataLevel: 19},
+	} {
+		compressed, err := compressData(c, data)
+		require.NoError(t, err)
+		require.True(t, backupread.IsCompressed(compressed))
+		require.Equal(t, c.MetadataCodec == CompressionCodecZstd, backupread.IsZstdCompressed(compressed))
+		decompressed, err := DecompressData(ctx, nil, compressed)
+		require.NoError(t, err)
+		require.Equal(t, data, decompressed)
+	}
+}
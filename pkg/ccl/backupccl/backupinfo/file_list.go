@@ -0,0 +1,182 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupinfo
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/diskmap"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// FileListMemoryBudget is the memory a backup job may use to hold the list of
+// the files it has written before spilling it to temporary storage.
+var FileListMemoryBudget = settings.RegisterByteSizeSetting(
+	settings.TenantWritable,
+	"bulkio.backup.file_list_memory_budget",
+	"memory a backup job may use to hold the list of the files it has written, "+
+		"past which the list is spilled to temporary storage (0 to never spill it)",
+	128<<20,
+)
+
+// fileSizeOverhead is the memory a file in a FileList uses beyond its encoded
+// size.
+const fileSizeOverhead = int64(unsafe.Sizeof(backuppb.BackupManifest_File{}))
+
+// FileList is the list of the files written by a backup job, which for
+// backups of clusters with millions of ranges may not fit in memory. It holds
+// the files in memory as long as its memory account allows it to, past which
+// it spills all of them to a sorted map in temporary storage.
+type FileList struct {
+	mem         *mon.BoundAccount
+	tempStorage diskmap.Factory
+
+	files   []backuppb.BackupManifest_File
+	memUsed int64
+
+	disk       diskmap.SortedDiskMap
+	diskWriter diskmap.SortedDiskMapBatchWriter
+
+	len int
+}
+
+// NewFileList returns an empty FileList that accounts for the memory it uses
+// in mem and spills to tempStorage. If tempStorage is nil, it returns the
+// errors of mem instead of spilling.
+func NewFileList(mem *mon.BoundAccount, tempStorage diskmap.Factory) *FileList {
+	return &FileList{mem: mem, tempStorage: tempStorage}
+}
+
+// Add adds file to the list.
+func (l *FileList) Add(ctx context.Context, file backuppb.BackupManifest_File) error {
+	l.len++
+	if l.disk == nil {
+		size := int64(file.Size()) + fileSizeOverhead
+		err := l.mem.Grow(ctx, size)
+		if err == nil {
+			l.files = append(l.files, file)
+			l.memUsed += size
+			return nil
+		}
+		if pgerror.GetPGCode(err) != pgcode.OutOfMemory || l.tempStorage == nil {
+			return err
+		}
+		if err := l.spill(ctx); err != nil {
+			return err
+		}
+	}
+	return l.put(&file)
+}
+
+// spill moves the files held in memory to temporary storage, to which all the
+// files added later are written too.
+func (l *FileList) spill(ctx context.Context) error {
+	log.Infof(ctx, "spilling the list of %d backup files to temporary storage", len(l.files))
+	l.disk = l.tempStorage.NewSortedDiskMap()
+	l.diskWriter = l.disk.NewBatchWriter()
+	for i := range l.files {
+		if err := l.put(&l.files[i]); err != nil {
+			return err
+		}
+	}
+	l.files = nil
+	l.mem.Shrink(ctx, l.memUsed)
+	l.memUsed = 0
+	return nil
+}
+
+func (l *FileList) put(file *backuppb.BackupManifest_File) error {
+	v, err := protoutil.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return l.diskWriter.Put(encodeFileSSTKey(file.Span.Key, file.Path), v)
+}
+
+// Len returns the number of files in the list.
+func (l *FileList) Len() int {
+	return l.len
+}
+
+// Spilled returns whether the list was spilled to temporary storage.
+func (l *FileList) Spilled() bool {
+	return l.disk != nil
+}
+
+// Iterate calls fn on each file in the list, in the order of their start keys
+// and then of their paths, which is the order they're written in the file info
+// SST of the backup metadata. The file passed to fn is only valid until fn
+// returns.
+func (l *FileList) Iterate(
+	ctx context.Context, fn func(file *backuppb.BackupManifest_File) error,
+) error {
+	if l.disk == nil {
+		sort.Slice(l.files, func(i, j int) bool {
+			if cmp := bytes.Compare(l.files[i].Span.Key, l.files[j].Span.Key); cmp != 0 {
+				return cmp < 0
+			}
+			return strings.Compare(l.files[i].Path, l.files[j].Path) < 0
+		})
+		for i := range l.files {
+			if err := fn(&l.files[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := l.diskWriter.Flush(); err != nil {
+		return err
+	}
+	it := l.disk.NewIterator()
+	defer it.Close()
+	var file backuppb.BackupManifest_File
+	for it.Rewind(); ; it.Next() {
+		if ok, err := it.Valid(); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+		file.Reset()
+		if err := protoutil.Unmarshal(it.UnsafeValue(), &file); err != nil {
+			return errors.Wrap(err, "decoding spilled backup file")
+		}
+		if err := fn(&file); err != nil {
+			return err
+		}
+	}
+}
+
+// Close releases the memory and temporary storage used by the list.
+func (l *FileList) Close(ctx context.Context) {
+	if l.diskWriter != nil {
+		if err := l.diskWriter.Close(ctx); err != nil {
+			log.Warningf(ctx, "failed to close spilled backup file list: %v", err)
+		}
+		l.diskWriter = nil
+	}
+	if l.disk != nil {
+		l.disk.Close(ctx)
+		l.disk = nil
+	}
+	l.files = nil
+	l.mem.Shrink(ctx, l.memUsed)
+	l.memUsed = 0
+}
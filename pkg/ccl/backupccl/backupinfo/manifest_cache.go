@@ -0,0 +1,145 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupinfo
+
+import (
+	"crypto/sha256"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// preparedManifestCacheSize bounds the memory used by PreparedManifests.
+var preparedManifestCacheSize = settings.RegisterByteSizeSetting(
+	settings.TenantWritable,
+	"bulkio.restore.prepared_manifest_cache.size",
+	"the maximum size of the backup manifests cached on a node by PREPARE RESTORE; "+
+		"0 disables the cache",
+	256<<20,
+)
+
+// PreparedManifests caches the manifests read by PREPARE RESTORE on this node,
+// so that a later RESTORE of the same backups can skip reading them from
+// external storage. The manifest of a completed backup never changes, so a
+// cached manifest is served until it is evicted to make room for others.
+var PreparedManifests = &ManifestCache{}
+
+// ManifestCache is a size-bounded cache of backup manifests, keyed by the
+// storage and file they were read from and the encryption options they were
+// decrypted with.
+type ManifestCache struct {
+	mu struct {
+		syncutil.Mutex
+		entries map[manifestCacheKey]manifestCacheEntry
+		// order holds the keys of entries in insertion order, oldest first.
+		order []manifestCacheKey
+		size  int64
+	}
+}
+
+type manifestCacheKey [sha256.Size]byte
+
+type manifestCacheEntry struct {
+	manifest *backuppb.BackupManifest
+	size     int64
+}
+
+// makeManifestCacheKey returns the cache key for the manifest in filename in
+// store, read with encryption. The key covers the encryption options so that
+// a manifest is only served to readers that could have decrypted it.
+func makeManifestCacheKey(
+	store cloud.ExternalStorage, filename string, encryption *jobspb.BackupEncryptionOptions,
+) (manifestCacheKey, error) {
+	conf := store.Conf()
+	confBytes, err := protoutil.Marshal(&conf)
+	if err != nil {
+		return manifestCacheKey{}, err
+	}
+	h := sha256.New()
+	_, _ = h.Write(confBytes)
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(filename))
+	_, _ = h.Write([]byte{0})
+	if encryption != nil {
+		encBytes, err := protoutil.Marshal(encryption)
+		if err != nil {
+			return manifestCacheKey{}, err
+		}
+		_, _ = h.Write(encBytes)
+	}
+	var key manifestCacheKey
+	copy(key[:], h.Sum(nil))
+	return key, nil
+}
+
+// get returns a copy of the cached manifest read from filename in store with
+// encryption, if any.
+func (c *ManifestCache) get(
+	store cloud.ExternalStorage, filename string, encryption *jobspb.BackupEncryptionOptions,
+) (backuppb.BackupManifest, bool, error) {
+	key, err := makeManifestCacheKey(store, filename, encryption)
+	if err != nil {
+		return backuppb.BackupManifest{}, false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.mu.entries[key]
+	if !ok {
+		return backuppb.BackupManifest{}, false, nil
+	}
+	// Callers are free to modify the manifests they read, so hand out a copy.
+	return *protoutil.Clone(e.manifest).(*backuppb.BackupManifest), true, nil
+}
+
+// Add caches a copy of the manifest read from filename in store with
+// encryption, evicting the oldest entries as needed to stay within the
+// configured size. It returns false if the manifest could not be cached, e.g.
+// because it is larger than the cache.
+func (c *ManifestCache) Add(
+	store cloud.ExternalStorage,
+	filename string,
+	encryption *jobspb.BackupEncryptionOptions,
+	manifest *backuppb.BackupManifest,
+) (bool, error) {
+	maxSize := preparedManifestCacheSize.Get(&store.Settings().SV)
+	size := int64(manifest.Size())
+	if size > maxSize {
+		return false, nil
+	}
+	key, err := makeManifestCacheKey(store, filename, encryption)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mu.entries == nil {
+		c.mu.entries = make(map[manifestCacheKey]manifestCacheEntry)
+	}
+	if _, ok := c.mu.entries[key]; ok {
+		return true, nil
+	}
+	for c.mu.size+size > maxSize && len(c.mu.order) > 0 {
+		oldest := c.mu.order[0]
+		c.mu.order = c.mu.order[1:]
+		c.mu.size -= c.mu.entries[oldest].size
+		delete(c.mu.entries, oldest)
+	}
+	c.mu.entries[key] = manifestCacheEntry{
+		manifest: protoutil.Clone(manifest).(*backuppb.BackupManifest),
+		size:     size,
+	}
+	c.mu.order = append(c.mu.order, key)
+	c.mu.size += size
+	return true, nil
+}
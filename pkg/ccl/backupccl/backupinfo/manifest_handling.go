@@ -11,9 +11,10 @@ package backupinfo
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"path"
 	"sort"
 	"strconv"
@@ -22,25 +23,19 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
-	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
 	descpb "github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
-	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/stats"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
@@ -54,7 +49,6 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/errors"
-	gzip "github.com/klauspost/compress/gzip"
 )
 
 // Files that may appear in a backup directory.
@@ -62,7 +56,7 @@ const (
 	// BackupManifestChecksumSuffix indicates where the checksum for the manifest
 	// is stored if present. It can be found in the name of the backup manifest +
 	// this suffix.
-	BackupManifestChecksumSuffix = "-CHECKSUM"
+	BackupManifestChecksumSuffix = backupread.ManifestChecksumSuffix
 
 	// BackupManifestCheckpointName is the file name used to store the serialized
 	// BackupManifest proto while the backup is in progress.
@@ -76,6 +70,15 @@ const (
 	// lock the bucket from running concurrent backups to the same destination.
 	BackupLockFilePrefix = "BACKUP-LOCK-"
 
+	// BackupSkippedMarkerName is the file name written in place of the
+	// BACKUP_MANIFEST of an incremental backup run with skip_if_unchanged that
+	// found nothing changed since the previous backup of its chain.
+	BackupSkippedMarkerName = "BACKUP-SKIPPED"
+
+	// BackupLayerStubName is the file name of the stub recording the times
+	// covered by a backup; see backuppb.BackupLayerStub.
+	BackupLayerStubName = "BACKUP-LAYER-STUB"
+
 	// BackupFormatDescriptorTrackingVersion added tracking of complete DBs.
 	BackupFormatDescriptorTrackingVersion uint32 = 1
 
@@ -94,18 +97,10 @@ var WriteMetadataSST = settings.RegisterBoolSetting(
 	util.ConstantWithMetamorphicTestBool("write-metadata-sst", false),
 )
 
-// IsGZipped detects whether the given bytes represent GZipped data. This check
-// is used rather than a standard implementation such as http.DetectContentType
-// since some zipped data may be mis-identified by that method. We've seen
-// gzipped data incorrectly identified as "application/vnd.ms-fontobject". The
-// magic bytes are from the MIME sniffing algorithm http.DetectContentType is
-// based which can be found at https://mimesniff.spec.whatwg.org/.
-//
-// This method is only used to detect if protobufs are GZipped, and there are no
-// conflicts between the starting bytes of a protobuf and these magic bytes.
+// IsGZipped detects whether the given bytes represent GZipped data. See
+// backupread.IsGZipped.
 func IsGZipped(dat []byte) bool {
-	gzipPrefix := []byte("\x1F\x8B\x08")
-	return bytes.HasPrefix(dat, gzipPrefix)
+	return backupread.IsGZipped(dat)
 }
 
 // BackupFileDescriptors is an alias on which to implement sort's interface.
@@ -122,7 +117,7 @@ func (r BackupFileDescriptors) Less(i, j int) bool {
 
 // ReadBackupManifestFromURI creates an export store from the given URI, then
 // reads and unmarshalls a BackupManifest at the standard location in the
-// export storage.
+// export storage, as by ReadBackupManifestFromStore.
 func ReadBackupManifestFromURI(
 	ctx context.Context,
 	mem *mon.BoundAccount,
@@ -131,6 +126,7 @@ func ReadBackupManifestFromURI(
 	makeExternalStorageFromURI cloud.ExternalStorageFromURIFactory,
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
+	elideFiles bool,
 ) (backuppb.BackupManifest, int64, error) {
 	exportStore, err := makeExternalStorageFromURI(ctx, uri, user)
 
@@ -138,25 +134,41 @@ func ReadBackupManifestFromURI(
 		return backuppb.BackupManifest{}, 0, err
 	}
 	defer exportStore.Close()
-	return ReadBackupManifestFromStore(ctx, mem, exportStore, encryption, kmsEnv)
+	return ReadBackupManifestFromStore(ctx, mem, exportStore, encryption, kmsEnv, elideFiles)
 }
 
 // ReadBackupManifestFromStore reads and unmarshalls a BackupManifest from the
 // store and returns it with the size it reserved for it from the boundAccount.
+// If elideFiles is set, the manifest is returned without its file list, which
+// NewLayerFileIter can then stream from the store.
 func ReadBackupManifestFromStore(
 	ctx context.Context,
 	mem *mon.BoundAccount,
 	exportStore cloud.ExternalStorage,
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
+	elideFiles bool,
 ) (backuppb.BackupManifest, int64, error) {
 	ctx, sp := tracing.ChildSpan(ctx, "backupinfo.ReadBackupManifestFromStore")
 	defer sp.Finish()
+	if cached, ok, err := PreparedManifests.get(exportStore, backupbase.BackupManifestName, encryption); err != nil {
+		return backuppb.BackupManifest{}, 0, err
+	} else if ok {
+		if elideFiles {
+			cached.Files = nil
+		}
+		memSize := int64(cached.Size())
+		if err := mem.Grow(ctx, memSize); err != nil {
+			return backuppb.BackupManifest{}, 0, err
+		}
+		cached.Dir = exportStore.Conf()
+		return cached, memSize, nil
+	}
 	backupManifest, memSize, err := ReadBackupManifest(ctx, mem, exportStore, backupbase.BackupManifestName,
-		encryption, kmsEnv)
+		encryption, kmsEnv, elideFiles)
 	if err != nil {
 		oldManifest, newMemSize, newErr := ReadBackupManifest(ctx, mem, exportStore, backupbase.BackupOldManifestName,
-			encryption, kmsEnv)
+			encryption, kmsEnv, elideFiles)
 		if newErr != nil {
 			return backuppb.BackupManifest{}, 0, err
 		}
@@ -169,31 +181,10 @@ func ReadBackupManifestFromStore(
 	return backupManifest, memSize, nil
 }
 
-// compressData compresses data buffer and returns compressed
-// bytes (i.e. gzip format).
-func compressData(descBuf []byte) ([]byte, error) {
-	gzipBuf := bytes.NewBuffer([]byte{})
-	gz := gzip.NewWriter(gzipBuf)
-	if _, err := gz.Write(descBuf); err != nil {
-		return nil, err
-	}
-	if err := gz.Close(); err != nil {
-		return nil, err
-	}
-	return gzipBuf.Bytes(), nil
-}
-
-// DecompressData decompresses gzip data buffer and returns decompressed bytes.
+// DecompressData decompresses a gzip or zstd data buffer and returns
+// decompressed bytes.
 func DecompressData(ctx context.Context, mem *mon.BoundAccount, descBytes []byte) ([]byte, error) {
-	r, err := gzip.NewReader(bytes.NewBuffer(descBytes))
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		// Swallow any errors, this is only a read operation.
-		_ = r.Close()
-	}()
-	return mon.ReadAll(ctx, ioctx.ReaderAdapter(r), mem)
+	return backupread.DecompressData(ctx, mem, descBytes)
 }
 
 // ReadBackupCheckpointManifest reads and unmarshals a BACKUP-CHECKPOINT
@@ -223,14 +214,14 @@ func ReadBackupCheckpointManifest(
 			return backuppb.BackupManifest{}, 0, err
 		}
 		// Pass checksumFile as nil to indicate it was not found.
-		return readManifest(ctx, mem, encryption, kmsEnv, checkpointFile, nil)
+		return backupread.ReadManifest(ctx, mem, encryption, kmsEnv, checkpointFile, nil)
 	}
 	defer checksumFile.Close(ctx)
-	return readManifest(ctx, mem, encryption, kmsEnv, checkpointFile, checksumFile)
+	return backupread.ReadManifest(ctx, mem, encryption, kmsEnv, checkpointFile, checksumFile)
 }
 
 // ReadBackupManifest reads and unmarshals a BackupManifest from filename in the
-// provided export store.
+// provided export store, without its file list if elideFiles is set.
 func ReadBackupManifest(
 	ctx context.Context,
 	mem *mon.BoundAccount,
@@ -238,132 +229,12 @@ func ReadBackupManifest(
 	filename string,
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
+	elideFiles bool,
 ) (backuppb.BackupManifest, int64, error) {
 	ctx, sp := tracing.ChildSpan(ctx, "backupinfo.ReadBackupManifest")
 	defer sp.Finish()
 
-	manifestFile, err := exportStore.ReadFile(ctx, filename)
-	if err != nil {
-		return backuppb.BackupManifest{}, 0, err
-	}
-	defer manifestFile.Close(ctx)
-
-	// Look for a checksum, if one is not found it could be an older backup,
-	// but we want to continue anyway.
-	checksumFile, err := exportStore.ReadFile(ctx, filename+BackupManifestChecksumSuffix)
-	if err != nil {
-		if !errors.Is(err, cloud.ErrFileDoesNotExist) {
-			return backuppb.BackupManifest{}, 0, err
-		}
-		// Pass checksumFile as nil to indicate it was not found.
-		return readManifest(ctx, mem, encryption, kmsEnv, manifestFile, nil)
-	}
-	defer checksumFile.Close(ctx)
-	return readManifest(ctx, mem, encryption, kmsEnv, manifestFile, checksumFile)
-}
-
-// readManifest reads and unmarshals a BackupManifest from filename in the
-// provided export store. If the passed bound account is not nil, the bytes read
-// are reserved from it as it is read and then the approximate in-memory size
-// (the total decompressed serialized byte size) is reserved as well before
-// deserialization and returned so that callers can then shrink the bound acct
-// by that amount when they release the returned manifest.
-func readManifest(
-	ctx context.Context,
-	mem *mon.BoundAccount,
-	encryption *jobspb.BackupEncryptionOptions,
-	kmsEnv cloud.KMSEnv,
-	manifestReader ioctx.ReadCloserCtx,
-	checksumReader ioctx.ReadCloserCtx,
-) (backuppb.BackupManifest, int64, error) {
-	ctx, sp := tracing.ChildSpan(ctx, "backupinfo.readManifest")
-	defer sp.Finish()
-
-	descBytes, err := mon.ReadAll(ctx, manifestReader, mem)
-	if err != nil {
-		return backuppb.BackupManifest{}, 0, err
-	}
-	defer func() {
-		mem.Shrink(ctx, int64(cap(descBytes)))
-	}()
-	if checksumReader != nil {
-		// If there is a checksum file present, check that it matches.
-		checksumFileData, err := ioctx.ReadAll(ctx, checksumReader)
-		if err != nil {
-			return backuppb.BackupManifest{}, 0, errors.Wrap(err, "reading checksum file")
-		}
-		checksum, err := GetChecksum(descBytes)
-		if err != nil {
-			return backuppb.BackupManifest{}, 0, errors.Wrap(err, "calculating checksum of manifest")
-		}
-		if !bytes.Equal(checksumFileData, checksum) {
-			return backuppb.BackupManifest{}, 0, errors.Newf("checksum mismatch; expected %s, got %s",
-				hex.EncodeToString(checksumFileData), hex.EncodeToString(checksum))
-		}
-	}
-
-	var encryptionKey []byte
-	if encryption != nil {
-		encryptionKey, err = backupencryption.GetEncryptionKey(ctx, encryption, kmsEnv)
-		if err != nil {
-			return backuppb.BackupManifest{}, 0, err
-		}
-		plaintextBytes, err := storageccl.DecryptFile(ctx, descBytes, encryptionKey, mem)
-		if err != nil {
-			return backuppb.BackupManifest{}, 0, err
-		}
-		mem.Shrink(ctx, int64(cap(descBytes)))
-		descBytes = plaintextBytes
-	}
-
-	if IsGZipped(descBytes) {
-		decompressedBytes, err := DecompressData(ctx, mem, descBytes)
-		if err != nil {
-			return backuppb.BackupManifest{}, 0, errors.Wrap(
-				err, "decompressing backup manifest")
-		}
-		// Release the compressed bytes from the monitor before we switch descBytes
-		// to point at the decompressed bytes, since the deferred release will later
-		// release the latter.
-		mem.Shrink(ctx, int64(cap(descBytes)))
-		descBytes = decompressedBytes
-	}
-
-	approxMemSize := int64(len(descBytes))
-	if err := mem.Grow(ctx, approxMemSize); err != nil {
-		return backuppb.BackupManifest{}, 0, err
-	}
-
-	var backupManifest backuppb.BackupManifest
-	if err := protoutil.Unmarshal(descBytes, &backupManifest); err != nil {
-		mem.Shrink(ctx, approxMemSize)
-		if encryption == nil && storageccl.AppearsEncrypted(descBytes) {
-			return backuppb.BackupManifest{}, 0, errors.Wrapf(
-				err, "file appears encrypted -- try specifying one of \"%s\" or \"%s\"",
-				backupencryption.BackupOptEncPassphrase, backupencryption.BackupOptEncKMS)
-		}
-		return backuppb.BackupManifest{}, 0, err
-	}
-	for _, d := range backupManifest.Descriptors {
-		// Calls to GetTable are generally frowned upon.
-		// This specific call exists to provide backwards compatibility with
-		// backups created prior to version 19.1. Starting in v19.1 the
-		// ModificationTime is always written in backups for all versions
-		// of table descriptors. In earlier cockroach versions only later
-		// table descriptor versions contain a non-empty ModificationTime.
-		// Later versions of CockroachDB use the MVCC timestamp to fill in
-		// the ModificationTime for table descriptors. When performing a restore
-		// we no longer have access to that MVCC timestamp but we can set it
-		// to a value we know will be safe.
-		//
-		// nolint:descriptormarshal
-		if t := d.GetTable(); t == nil {
-			continue
-		} else if t.Version == 1 && t.ModificationTime.IsEmpty() {
-			t.ModificationTime = hlc.Timestamp{WallTime: 1}
-		}
-	}
-	return backupManifest, approxMemSize, nil
+	return backupread.ReadManifestFile(ctx, mem, exportStore, filename, encryption, kmsEnv, elideFiles)
 }
 
 func readBackupPartitionDescriptor(
@@ -403,7 +274,7 @@ func readBackupPartitionDescriptor(
 		descBytes = plaintextData
 	}
 
-	if IsGZipped(descBytes) {
+	if backupread.IsCompressed(descBytes) {
 		decompressedData, err := DecompressData(ctx, mem, descBytes)
 		if err != nil {
 			return backuppb.BackupPartitionDescriptor{}, 0, errors.Wrap(
@@ -528,6 +399,88 @@ func WriteBackupLock(
 	return cloud.WriteFile(ctx, defaultStore, lockFileName, bytes.NewReader([]byte("lock")))
 }
 
+// WriteBackupSkippedMarker writes the marker of an incremental backup that
+// was skipped since nothing changed in (start, end] to defaultURI. The marker
+// is not read by restores, which find no manifest in its directory, and only
+// records the skipped interval for those inspecting the collection.
+func WriteBackupSkippedMarker(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	defaultURI string,
+	user username.SQLUsername,
+	start, end hlc.Timestamp,
+) error {
+	defaultStore, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, defaultURI, user)
+	if err != nil {
+		return err
+	}
+	defer defaultStore.Close()
+
+	marker := fmt.Sprintf("unchanged from %s to %s\n", start.AsOfSystemTime(), end.AsOfSystemTime())
+	return cloud.WriteFile(ctx, defaultStore, BackupSkippedMarkerName, bytes.NewReader([]byte(marker)))
+}
+
+// BackupSkippedMarkerExists returns whether the backup at defaultURI was
+// skipped, having found nothing changed since the previous backup of its
+// chain.
+func BackupSkippedMarkerExists(
+	ctx context.Context, execCfg *sql.ExecutorConfig, defaultURI string, user username.SQLUsername,
+) (bool, error) {
+	defaultStore, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, defaultURI, user)
+	if err != nil {
+		return false, err
+	}
+	defer defaultStore.Close()
+
+	r, err := defaultStore.ReadFile(ctx, BackupSkippedMarkerName)
+	if err == nil {
+		r.Close(ctx)
+		return true, nil
+	} else if errors.Is(err, cloud.ErrFileDoesNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+// WriteBackupLayerStub writes the stub recording the times covered by the
+// backup with the given manifest to exportStore.
+func WriteBackupLayerStub(
+	ctx context.Context, exportStore cloud.ExternalStorage, desc *backuppb.BackupManifest,
+) error {
+	stub := backuppb.BackupLayerStub{StartTime: desc.StartTime, EndTime: desc.EndTime}
+	buf, err := protoutil.Marshal(&stub)
+	if err != nil {
+		return err
+	}
+	return cloud.WriteFile(ctx, exportStore, BackupLayerStubName, bytes.NewReader(buf))
+}
+
+// ReadBackupLayerStub reads the stub of the backup at the given path of store.
+// It returns false if the backup has none, as backups written by versions
+// which did not write them do not.
+func ReadBackupLayerStub(
+	ctx context.Context, store cloud.ExternalStorage, dir string,
+) (backuppb.BackupLayerStub, bool, error) {
+	var stub backuppb.BackupLayerStub
+	filename := path.Join(dir, BackupLayerStubName)
+	r, err := store.ReadFile(ctx, filename)
+	if err != nil {
+		if errors.Is(err, cloud.ErrFileDoesNotExist) {
+			return stub, false, nil
+		}
+		return stub, false, err
+	}
+	defer r.Close(ctx)
+	buf, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return stub, false, err
+	}
+	if err := protoutil.Unmarshal(buf, &stub); err != nil {
+		return stub, false, errors.Wrapf(err, "reading %s", filename)
+	}
+	return stub, true, nil
+}
+
 // WriteBackupManifest compresses and writes the passed in BackupManifest `desc`
 // to `exportStore`.
 func WriteBackupManifest(
@@ -537,71 +490,147 @@ func WriteBackupManifest(
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
 	desc *backuppb.BackupManifest,
+) error {
+	return WriteBackupManifestWithFiles(ctx, exportStore, filename, encryption, kmsEnv, desc,
+		nil /* files */)
+}
+
+// WriteBackupManifestWithFiles is like WriteBackupManifest, but the files of
+// the backup are those of files if it is non-nil, in which case desc should
+// hold none.
+func WriteBackupManifestWithFiles(
+	ctx context.Context,
+	exportStore cloud.ExternalStorage,
+	filename string,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	desc *backuppb.BackupManifest,
+	files *FileList,
 ) error {
 	ctx, sp := tracing.ChildSpan(ctx, "backupinfo.WriteBackupManifest")
 	defer sp.Finish()
 
 	sort.Sort(BackupFileDescriptors(desc.Files))
 
-	descBuf, err := protoutil.Marshal(desc)
+	checksum, err := writeManifestFile(ctx, exportStore, filename, encryption, kmsEnv, desc, files)
 	if err != nil {
 		return err
 	}
 
-	descBuf, err = compressData(descBuf)
-	if err != nil {
-		return errors.Wrap(err, "compressing backup manifest")
+	// Write the checksum file after we've successfully wrote the manifest.
+	if err := cloud.WriteFile(ctx, exportStore,
+		filename+BackupManifestChecksumSuffix, bytes.NewReader(checksum)); err != nil {
+		return errors.Wrap(err, "writing manifest checksum")
 	}
 
+	return nil
+}
+
+// manifestFilesFieldTag is the tag of the files field of an encoded
+// BackupManifest: its field number followed by the length-delimited wire type.
+const manifestFilesFieldTag = 4<<3 | 2
+
+// writeManifestFile writes desc, compressed and encrypted if encryption is
+// set, to filename in exportStore, and returns its checksum. The files of files
+// are written as entries of the files field of the encoded manifest, which
+// decodes as if they had been part of desc, so the manifest of a backup
+// whose list of files was spilled to disk is never assembled in memory. The
+// oldest version that can read desc is recorded in it first.
+func writeManifestFile(
+	ctx context.Context,
+	exportStore cloud.ExternalStorage,
+	filename string,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	desc *backuppb.BackupManifest,
+	files *FileList,
+) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	var w io.WriteCloser
+	defer func() {
+		cancel() // cancel before Close() to abort write on err returns.
+		if w != nil {
+			w.Close()
+		}
+	}()
+
+	w, err := exportStore.Writer(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	hash := backupread.NewChecksumHash()
+	var sink io.WriteCloser = storageccl.NopCloser{Writer: io.MultiWriter(w, hash)}
 	if encryption != nil {
 		encryptionKey, err := backupencryption.GetEncryptionKey(ctx, encryption, kmsEnv)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		descBuf, err = storageccl.EncryptFile(descBuf, encryptionKey)
-		if err != nil {
-			return err
+		if sink, err = storageccl.EncryptingWriter(sink, encryptionKey); err != nil {
+			return nil, err
 		}
 	}
-
-	if err := cloud.WriteFile(ctx, exportStore, filename, bytes.NewReader(descBuf)); err != nil {
-		return err
+	gz, err := newMetadataCompressor(desc.Compression, sink)
+	if err != nil {
+		return nil, err
 	}
 
-	// Write the checksum file after we've successfully wrote the manifest.
-	checksum, err := GetChecksum(descBuf)
+	desc.MinReaderVersion = backupread.MinReaderVersion(desc)
+	descBuf, err := protoutil.Marshal(desc)
 	if err != nil {
-		return errors.Wrap(err, "calculating checksum")
+		return nil, err
 	}
-
-	if err := cloud.WriteFile(ctx, exportStore,
-		filename+BackupManifestChecksumSuffix, bytes.NewReader(checksum)); err != nil {
-		return errors.Wrap(err, "writing manifest checksum")
+	if _, err := gz.Write(descBuf); err != nil {
+		return nil, errors.Wrap(err, "compressing backup manifest")
+	}
+	if files != nil {
+		var entry []byte
+		var lenBuf [binary.MaxVarintLen64]byte
+		if err := files.Iterate(ctx, func(file *backuppb.BackupManifest_File) error {
+			fileBuf, err := protoutil.Marshal(file)
+			if err != nil {
+				return err
+			}
+			entry = append(entry[:0], manifestFilesFieldTag)
+			entry = append(entry, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(fileBuf)))]...)
+			entry = append(entry, fileBuf...)
+			_, err = gz.Write(entry)
+			return err
+		}); err != nil {
+			return nil, errors.Wrap(err, "compressing backup manifest files")
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, errors.Wrap(err, "compressing backup manifest")
+	}
+	if err := sink.Close(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Explicitly close to flush and check for errors do so before defer's cancel
+	// which would abort. Then nil out w to avoid defer double-closing.
+	err = w.Close()
+	w = nil
+	if err != nil {
+		return nil, err
+	}
+	return backupread.ChecksumFromHash(hash), nil
 }
 
 // GetChecksum returns a 32 bit keyed-checksum for the given data.
 func GetChecksum(data []byte) ([]byte, error) {
-	const checksumSizeBytes = 4
-	hash := sha256.New()
-	if _, err := hash.Write(data); err != nil {
-		return nil, errors.Wrap(err,
-			`"It never returns an error." -- https://golang.org/pkg/hash`)
-	}
-	return hash.Sum(nil)[:checksumSizeBytes], nil
+	return backupread.GetChecksum(data)
 }
 
 // WriteBackupPartitionDescriptor writes metadata (containing a locality KV and
 // partial file listing) for a partitioned BACKUP to one of the stores in the
-// backup.
+// backup, compressed like the rest of the metadata of the backup.
 func WriteBackupPartitionDescriptor(
 	ctx context.Context,
 	exportStore cloud.ExternalStorage,
 	filename string,
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
+	compression backuppb.Compression,
 	desc *backuppb.BackupPartitionDescriptor,
 ) error {
 	ctx, sp := tracing.ChildSpan(ctx, "backupinfo.WriteBackupPartitionDescriptor")
@@ -611,7 +640,7 @@ func WriteBackupPartitionDescriptor(
 	if err != nil {
 		return err
 	}
-	descBuf, err = compressData(descBuf)
+	descBuf, err = compressData(compression, descBuf)
 	if err != nil {
 		return errors.Wrap(err, "compressing backup partition descriptor")
 	}
@@ -686,7 +715,7 @@ func LoadBackupManifestsAtTime(
 	}()
 	for i, uri := range uris {
 		desc, memSize, err := ReadBackupManifestFromURI(ctx, mem, uri, user, makeExternalStorageFromURI,
-			encryption, kmsEnv)
+			encryption, kmsEnv, false /* elideFiles */)
 		if err != nil {
 			return nil, 0, errors.Wrapf(err, "failed to read backup descriptor")
 		}
@@ -732,6 +761,17 @@ func GetLocalityInfo(
 		if prefix != "" {
 			filename = path.Join(prefix, filename)
 		}
+		// The descriptor of a locality that has a key of its own is encrypted
+		// with that key, and its filename ends with the locality.
+		partitionEncryption := encryption
+		if encryption != nil {
+			for kv := range encryption.KMSInfoByLocalityKV {
+				if strings.HasSuffix(filename, "_"+SanitizeLocalityKV(kv)) {
+					partitionEncryption = backupencryption.LocalityEncryptionOptions(encryption, kv)
+					break
+				}
+			}
+		}
 		found := false
 		for i, store := range stores {
 			// Iterate through the available stores in case the user moved a locality
@@ -743,7 +783,7 @@ func GetLocalityInfo(
 			// claims are stored in two different localities, are actually stored in
 			// the same place.
 			if desc, _, err := readBackupPartitionDescriptor(ctx, nil /*mem*/, store, filename,
-				encryption, kmsEnv); err == nil {
+				partitionEncryption, kmsEnv); err == nil {
 				if desc.BackupID != mainBackupManifest.ID {
 					return info, errors.Errorf(
 						"expected backup part to have backup ID %s, found %s",
@@ -834,20 +874,7 @@ func ValidateEndTimeAndTruncate(
 func GetBackupIndexAtTime(
 	backupManifests []backuppb.BackupManifest, asOf hlc.Timestamp,
 ) (int, error) {
-	if len(backupManifests) == 0 {
-		return -1, errors.New("expected a nonempty backup manifest list, got an empty list")
-	}
-	backupManifestIndex := len(backupManifests) - 1
-	if asOf.IsEmpty() {
-		return backupManifestIndex, nil
-	}
-	for ind, b := range backupManifests {
-		if asOf.Less(b.StartTime) {
-			break
-		}
-		backupManifestIndex = ind
-	}
-	return backupManifestIndex, nil
+	return backupread.IndexAtTime(backupManifests, asOf)
 }
 
 // LoadSQLDescsFromBackupsAtTime returns the Descriptors found in the last
@@ -855,72 +882,7 @@ func GetBackupIndexAtTime(
 func LoadSQLDescsFromBackupsAtTime(
 	backupManifests []backuppb.BackupManifest, asOf hlc.Timestamp,
 ) ([]catalog.Descriptor, backuppb.BackupManifest, error) {
-	lastBackupManifest := backupManifests[len(backupManifests)-1]
-
-	if asOf.IsEmpty() {
-		if lastBackupManifest.DescriptorCoverage != tree.AllDescriptors {
-			descs, err := BackupManifestDescriptors(&lastBackupManifest)
-			return descs, lastBackupManifest, err
-		}
-
-		// Cluster backups with revision history may have included previous database
-		// versions of database descriptors in lastBackupManifest.Descriptors. Find
-		// the correct set of descriptors by going through their revisions. See
-		// #68541.
-		asOf = lastBackupManifest.EndTime
-	}
-
-	for _, b := range backupManifests {
-		if asOf.Less(b.StartTime) {
-			break
-		}
-		lastBackupManifest = b
-	}
-	if len(lastBackupManifest.DescriptorChanges) == 0 {
-		descs, err := BackupManifestDescriptors(&lastBackupManifest)
-		return descs, lastBackupManifest, err
-	}
-
-	byID := make(map[descpb.ID]catalog.DescriptorBuilder, len(lastBackupManifest.Descriptors))
-	for _, rev := range lastBackupManifest.DescriptorChanges {
-		if asOf.Less(rev.Time) {
-			break
-		}
-		if rev.Desc == nil {
-			delete(byID, rev.ID)
-		} else {
-			byID[rev.ID] = newDescriptorBuilder(rev.Desc, rev.Time)
-		}
-	}
-
-	allDescs := make([]catalog.Descriptor, 0, len(byID))
-	for _, b := range byID {
-		if b == nil {
-			continue
-		}
-		// A revision may have been captured before it was in a DB that is
-		// backed up -- if the DB is missing, filter the object.
-		if err := b.RunPostDeserializationChanges(); err != nil {
-			return nil, backuppb.BackupManifest{}, err
-		}
-		desc := b.BuildCreatedMutable()
-		var isObject bool
-		switch d := desc.(type) {
-		case catalog.TableDescriptor:
-			// Filter out revisions in the dropped state.
-			if d.GetState() == descpb.DescriptorState_DROP {
-				continue
-			}
-			isObject = true
-		case catalog.TypeDescriptor, catalog.SchemaDescriptor:
-			isObject = true
-		}
-		if isObject && byID[desc.GetParentID()] == nil {
-			continue
-		}
-		allDescs = append(allDescs, desc)
-	}
-	return allDescs, lastBackupManifest, nil
+	return backupread.DescriptorsAtTime(backupManifests, asOf)
 }
 
 // SanitizeLocalityKV returns a sanitized version of the input string where all
@@ -1076,58 +1038,14 @@ func TempCheckpointFileNameForJob(jobID jobspb.JobID) string {
 func BackupManifestDescriptors(
 	backupManifest *backuppb.BackupManifest,
 ) ([]catalog.Descriptor, error) {
-	ret := make([]catalog.Descriptor, 0, len(backupManifest.Descriptors))
-	for i := range backupManifest.Descriptors {
-		b := newDescriptorBuilder(&backupManifest.Descriptors[i], backupManifest.EndTime)
-		if b == nil {
-			continue
-		}
-		if err := b.RunPostDeserializationChanges(); err != nil {
-			return nil, err
-		}
-		ret = append(ret, b.BuildCreatedMutable())
-	}
-	return ret, nil
+	return backupread.ManifestDescriptors(backupManifest)
 }
 
 // NewDescriptorForManifest returns a descriptor instance for a protobuf
-// to be added to a backup manifest or in a backup job.
-// In these cases, we know that the ModificationTime field has already correctly
-// been set in the descriptor protobuf, because this descriptor has been read
-// from storage and therefore has been updated using the MVCC timestamp.
+// to be added to a backup manifest or in a backup job. See
+// backupread.NewDescriptorForManifest.
 func NewDescriptorForManifest(descProto *descpb.Descriptor) catalog.Descriptor {
-	b := newDescriptorBuilder(descProto, hlc.Timestamp{})
-	if b == nil {
-		return nil
-	}
-	// No need to call RunPostDeserializationChanges, because the descriptor has
-	// been read from storage and therefore this has been called already.
-	//
-	// Return a mutable descriptor because that's what the call sites assume.
-	// TODO(postamar): revisit that assumption.
-	return b.BuildCreatedMutable()
-}
-
-// newDescriptorBuilder constructs a catalog.DescriptorBuilder instance
-// initialized using the descriptor protobuf message and a fake MVCC timestamp
-// for the purpose of setting the descriptor's ModificationTime field to a
-// valid value if it's still unset.
-func newDescriptorBuilder(
-	descProto *descpb.Descriptor, fakeMVCCTimestamp hlc.Timestamp,
-) catalog.DescriptorBuilder {
-	tbl, db, typ, sc, f := descpb.GetDescriptors(descProto)
-	if tbl != nil {
-		return tabledesc.NewBuilderWithMVCCTimestamp(tbl, fakeMVCCTimestamp)
-	} else if db != nil {
-		return dbdesc.NewBuilderWithMVCCTimestamp(db, fakeMVCCTimestamp)
-	} else if typ != nil {
-		return typedesc.NewBuilderWithMVCCTimestamp(typ, fakeMVCCTimestamp)
-	} else if sc != nil {
-		return schemadesc.NewBuilderWithMVCCTimestamp(sc, fakeMVCCTimestamp)
-	} else if f != nil {
-		return funcdesc.NewBuilderWithMVCCTimestamp(f, fakeMVCCTimestamp)
-	}
-	return nil
+	return backupread.NewDescriptorForManifest(descProto)
 }
 
 // WriteBackupManifestCheckpoint writes a new BACKUP-CHECKPOINT MANIFEST and
@@ -1143,6 +1061,23 @@ func WriteBackupManifestCheckpoint(
 	desc *backuppb.BackupManifest,
 	execCfg *sql.ExecutorConfig,
 	user username.SQLUsername,
+) error {
+	return WriteBackupManifestCheckpointWithFiles(ctx, storageURI, encryption, kmsEnv, desc,
+		nil /* files */, execCfg, user)
+}
+
+// WriteBackupManifestCheckpointWithFiles is like WriteBackupManifestCheckpoint,
+// but the files of the backup are those of files if it is non-nil, in which
+// case desc should hold none.
+func WriteBackupManifestCheckpointWithFiles(
+	ctx context.Context,
+	storageURI string,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	desc *backuppb.BackupManifest,
+	files *FileList,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
 ) error {
 	ctx, sp := tracing.ChildSpan(ctx, "backupinfo.WriteBackupManifestCheckpoint")
 	defer sp.Finish()
@@ -1155,27 +1090,6 @@ func WriteBackupManifestCheckpoint(
 
 	sort.Sort(BackupFileDescriptors(desc.Files))
 
-	descBuf, err := protoutil.Marshal(desc)
-	if err != nil {
-		return err
-	}
-
-	descBuf, err = compressData(descBuf)
-	if err != nil {
-		return errors.Wrap(err, "compressing backup manifest")
-	}
-
-	if encryption != nil {
-		encryptionKey, err := backupencryption.GetEncryptionKey(ctx, encryption, kmsEnv)
-		if err != nil {
-			return err
-		}
-		descBuf, err = storageccl.EncryptFile(descBuf, encryptionKey)
-		if err != nil {
-			return err
-		}
-	}
-
 	// We timestamp the checkpoint files in order to enforce write once backups.
 	// When the job goes to read these timestamped files, it will List
 	// the checkpoints and pick the file whose name is lexicographically
@@ -1183,13 +1097,13 @@ func WriteBackupManifestCheckpoint(
 	// details refer to newTimestampedCheckpointFileName.
 	filename := NewTimestampedCheckpointFileName()
 
-	// HTTP storage does not support listing and so we cannot rely on the
-	// above-mentioned List method to return us the latest checkpoint file.
+	// Storage that does not support listing, such as HTTP storage, cannot rely
+	// on the above-mentioned List method to return us the latest checkpoint file.
 	// Instead, we will write a checkpoint once with a well-known filename,
 	// and teach the job to always reach for that filename in the face of
 	// a resume. We may lose progress, but this is a cost we are willing
 	// to pay to uphold write-once semantics.
-	if defaultStore.Conf().Provider == cloudpb.ExternalStorageProvider_http {
+	if !cloud.SupportsListing(defaultStore) {
 		// TODO (darryl): We should do this only for file not found or directory
 		// does not exist errors. As of right now we only specifically wrap
 		// ReadFile errors for file not found so this is not possible yet.
@@ -1206,17 +1120,13 @@ func WriteBackupManifestCheckpoint(
 		}
 	}
 
-	err = cloud.WriteFile(ctx, defaultStore, BackupProgressDirectory+"/"+filename, bytes.NewReader(descBuf))
+	checksum, err := writeManifestFile(ctx, defaultStore, BackupProgressDirectory+"/"+filename,
+		encryption, kmsEnv, desc, files)
 	if err != nil {
-		return errors.Wrap(err, "calculating checksum")
+		return errors.Wrap(err, "writing checkpoint")
 	}
 
 	// Write the checksum file after we've successfully wrote the checkpoint.
-	checksum, err := GetChecksum(descBuf)
-	if err != nil {
-		return errors.Wrap(err, "calculating checksum")
-	}
-
 	err = cloud.WriteFile(ctx, defaultStore, BackupProgressDirectory+"/"+filename+BackupManifestChecksumSuffix, bytes.NewReader(checksum))
 	if err != nil {
 		return err
@@ -1299,7 +1209,8 @@ func NewTimestampedCheckpointFileName() string {
 
 // FetchPreviousBackups takes a list of URIs of previous backups and returns
 // their manifest as well as the encryption options of the first backup in the
-// chain.
+// chain. If elideFiles is set, the manifests are returned without their file
+// lists.
 func FetchPreviousBackups(
 	ctx context.Context,
 	mem *mon.BoundAccount,
@@ -1308,6 +1219,7 @@ func FetchPreviousBackups(
 	prevBackupURIs []string,
 	encryptionParams jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
+	elideFiles bool,
 ) ([]backuppb.BackupManifest, *jobspb.BackupEncryptionOptions, int64, error) {
 	ctx, sp := tracing.ChildSpan(ctx, "backupinfo.FetchPreviousBackups")
 	defer sp.Finish()
@@ -1323,7 +1235,7 @@ func FetchPreviousBackups(
 		return nil, nil, 0, err
 	}
 	prevBackups, size, err := getBackupManifests(ctx, mem, user, makeCloudStorage, prevBackupURIs,
-		encryptionOptions, kmsEnv)
+		encryptionOptions, kmsEnv, elideFiles)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -1341,6 +1253,7 @@ func getBackupManifests(
 	backupURIs []string,
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
+	elideFiles bool,
 ) ([]backuppb.BackupManifest, int64, error) {
 	manifests := make([]backuppb.BackupManifest, len(backupURIs))
 	if len(backupURIs) == 0 {
@@ -1371,7 +1284,7 @@ func getBackupManifests(
 			// descriptors around.
 			uri := backupURIs[i]
 			desc, size, err := ReadBackupManifestFromURI(
-				ctx, &subMem, uri, user, makeCloudStorage, encryption, kmsEnv,
+				ctx, &subMem, uri, user, makeCloudStorage, encryption, kmsEnv, elideFiles,
 			)
 			if err != nil {
 				return errors.Wrapf(err, "failed to read backup from %q",
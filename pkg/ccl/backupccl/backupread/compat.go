@@ -0,0 +1,119 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupread
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/errors"
+)
+
+// Most fields added to the manifest are safe for older versions to ignore,
+// but some change the meaning of the rest of the manifest, such that a version
+// which does not know them would read the backup wrong without noticing. Each
+// such field is registered as a feature of manifests, along with the first
+// version that reads it, and a manifest using any of them records the newest
+// of those versions as the oldest that can read it. Readers refuse the
+// manifests that require a version newer than their binary, naming the
+// version they require, before they act on them.
+//
+// Fields that are not of this kind need not be registered: a version which
+// does not know them keeps them as unrecognized fields of the manifest, so
+// that a manifest it reads and writes back, such as the checkpoint of a job
+// that it resumed, keeps them too.
+
+// ManifestFeature is a feature of backups that versions older than MinReader
+// would misread in the manifests that use it.
+type ManifestFeature struct {
+	// MinReader is the first version which reads manifests using the feature.
+	MinReader roachpb.Version
+	// Uses returns whether m uses the feature.
+	Uses func(m *backuppb.BackupManifest) bool
+}
+
+var manifestFeatures []ManifestFeature
+
+// RegisterManifestFeature adds a feature to the ones the oldest version which
+// can read a manifest is computed from.
+func RegisterManifestFeature(f ManifestFeature) {
+	manifestFeatures = append(manifestFeatures, f)
+}
+
+// MinReaderVersion returns the oldest version that can read m: the newest of
+// the versions required by the features it uses, or by the version which
+// wrote it, if that required a newer one. It is empty if any version can.
+func MinReaderVersion(m *backuppb.BackupManifest) roachpb.Version {
+	v := m.MinReaderVersion
+	for _, f := range manifestFeatures {
+		if v.Less(f.MinReader) && f.Uses(m) {
+			v = f.MinReader
+		}
+	}
+	return v
+}
+
+// CheckReadable returns an error if m requires a newer version to read it
+// than binaryVersion, the version of the binary reading it.
+func CheckReadable(m *backuppb.BackupManifest, binaryVersion roachpb.Version) error {
+	if m.MinReaderVersion == (roachpb.Version{}) || m.MinReaderVersion.LessEq(binaryVersion) {
+		return nil
+	}
+	return errors.WithHintf(
+		pgerror.Newf(pgcode.FeatureNotSupported,
+			"backup written by %s requires a %s reader, but this node runs %s",
+			versionString(ManifestVersion(m)), m.MinReaderVersion, binaryVersion),
+		"read the backup from a cluster running version %s or later", m.MinReaderVersion)
+}
+
+func init() {
+	minReader := clusterversion.ByKey(clusterversion.V23_1BackupManifestMinReaderVersion)
+	RegisterManifestFeature(ManifestFeature{
+		// Older versions look for the data files next to the manifest.
+		MinReader: minReader,
+		Uses:      func(m *backuppb.BackupManifest) bool { return m.DataURI != "" },
+	})
+	RegisterManifestFeature(ManifestFeature{
+		// Older versions would restore the rows matching the filter as the whole
+		// table.
+		MinReader: minReader,
+		Uses:      func(m *backuppb.BackupManifest) bool { return m.RowFilter != "" },
+	})
+	RegisterManifestFeature(ManifestFeature{
+		// Older versions would try to decrypt the data files.
+		MinReader: minReader,
+		Uses:      func(m *backuppb.BackupManifest) bool { return m.EncryptionAtRestOnly },
+	})
+	RegisterManifestFeature(ManifestFeature{
+		// Older versions would read the compressed blocks of the data files as
+		// if they were not.
+		MinReader: minReader,
+		Uses:      func(m *backuppb.BackupManifest) bool { return m.Compression.DataCodec != "" },
+	})
+	RegisterManifestFeature(ManifestFeature{
+		// Older versions only decompress the metadata files they find gzipped,
+		// and would read those compressed with another codec as they are.
+		MinReader: minReader,
+		Uses:      func(m *backuppb.BackupManifest) bool { return m.Compression.MetadataCodec != "" },
+	})
+	RegisterManifestFeature(ManifestFeature{
+		// Older versions would take the encrypted shares of the data key for
+		// whole data keys.
+		MinReader: clusterversion.ByKey(clusterversion.V23_1BackupDecryptQuorum),
+		Uses:      func(m *backuppb.BackupManifest) bool { return m.DecryptQuorum > 1 },
+	})
+	RegisterManifestFeature(ManifestFeature{
+		// Older versions would decrypt the files of every locality with the data
+		// key of the rest of the backup.
+		MinReader: clusterversion.ByKey(clusterversion.V23_1BackupKMSByLocality),
+		Uses:      func(m *backuppb.BackupManifest) bool { return len(m.KMSLocalityKVs) > 0 },
+	})
+}
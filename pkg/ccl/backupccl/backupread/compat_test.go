@@ -0,0 +1,90 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupread_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestMinReaderVersion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	minReader := clusterversion.ByKey(clusterversion.V23_1BackupManifestMinReaderVersion)
+	older := clusterversion.ByKey(clusterversion.V23_1RestoreIndex)
+
+	m := &backuppb.BackupManifest{}
+	require.Equal(t, roachpb.Version{}, backupread.MinReaderVersion(m))
+	require.NoError(t, backupread.CheckReadable(m, older))
+
+	m.DataURI = "nodelocal://1/data"
+	m.MinReaderVersion = backupread.MinReaderVersion(m)
+	require.Equal(t, minReader, m.MinReaderVersion)
+	require.NoError(t, backupread.CheckReadable(m, minReader))
+	require.ErrorContains(t, backupread.CheckReadable(m, older),
+		"requires a "+minReader.String()+" reader, but this node runs "+older.String())
+
+	// So does metadata compressed with a codec other than gzip.
+	require.Equal(t, minReader, backupread.MinReaderVersion(&backuppb.BackupManifest{
+		Compression: backuppb.Compression{MetadataCodec: "zstd"},
+	}))
+
+	// Features added after the manifest recorded its oldest reader require the
+	// versions that added them.
+	require.Equal(t, clusterversion.ByKey(clusterversion.V23_1BackupDecryptQuorum),
+		backupread.MinReaderVersion(&backuppb.BackupManifest{DataURI: "nodelocal://1/data", DecryptQuorum: 2}))
+	require.Equal(t, roachpb.Version{}, backupread.MinReaderVersion(&backuppb.BackupManifest{DecryptQuorum: 1}))
+	require.Equal(t, clusterversion.ByKey(clusterversion.V23_1BackupKMSByLocality),
+		backupread.MinReaderVersion(&backuppb.BackupManifest{KMSLocalityKVs: []string{"region=east"}}))
+
+	// The version required by a newer writer is kept, even if this version does
+	// not know why it was required.
+	newer := roachpb.Version{Major: minReader.Major + 1, Minor: 1}
+	m.MinReaderVersion = newer
+	require.Equal(t, newer, backupread.MinReaderVersion(m))
+	require.Error(t, backupread.CheckReadable(m, minReader))
+}
+
+func TestManifestUnknownFields(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	written := backuppb.BackupManifest{EndTime: hlc.Timestamp{WallTime: 10}, Layer: 2}
+	buf, err := protoutil.Marshal(&written)
+	require.NoError(t, err)
+
+	// Append a varint field with a number this version does not know, as a newer
+	// version would have written it.
+	var unknown []byte
+	var varint [binary.MaxVarintLen64]byte
+	unknown = append(unknown, varint[:binary.PutUvarint(varint[:], 1000<<3)]...)
+	unknown = append(unknown, varint[:binary.PutUvarint(varint[:], 7)]...)
+	buf = append(buf, unknown...)
+
+	var read backuppb.BackupManifest
+	require.NoError(t, protoutil.Unmarshal(buf, &read))
+	require.Equal(t, written.EndTime, read.EndTime)
+	require.Equal(t, written.Layer, read.Layer)
+
+	rewritten, err := protoutil.Marshal(&read)
+	require.NoError(t, err)
+	require.True(t, bytes.Contains(rewritten, unknown), "unknown field lost: %x", rewritten)
+}
@@ -0,0 +1,460 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupread
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+	gzip "github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ManifestChecksumSuffix indicates where the checksum for a manifest is
+// stored if present. It can be found in the name of the manifest + this
+// suffix.
+const ManifestChecksumSuffix = "-CHECKSUM"
+
+// IsGZipped detects whether the given bytes represent GZipped data. This check
+// is used rather than a standard implementation such as http.DetectContentType
+// since some zipped data may be mis-identified by that method. We've seen
+// gzipped data incorrectly identified as "application/vnd.ms-fontobject". The
+// magic bytes are from the MIME sniffing algorithm http.DetectContentType is
+// based which can be found at https://mimesniff.spec.whatwg.org/.
+//
+// This method is only used to detect if protobufs are GZipped, and there are no
+// conflicts between the starting bytes of a protobuf and these magic bytes.
+func IsGZipped(dat []byte) bool {
+	gzipPrefix := []byte("\x1F\x8B\x08")
+	return bytes.HasPrefix(dat, gzipPrefix)
+}
+
+// IsZstdCompressed detects whether the given bytes represent data compressed
+// with zstd, by the magic number of its frames. Like IsGZipped, it is only used
+// on the metadata of backups, whose encoded protobufs never start with it.
+func IsZstdCompressed(dat []byte) bool {
+	zstdPrefix := []byte("\x28\xB5\x2F\xFD")
+	return bytes.HasPrefix(dat, zstdPrefix)
+}
+
+// IsCompressed detects whether the given bytes represent data compressed with
+// any of the codecs the metadata of backups may be compressed with. Backups
+// record the codec of their metadata, but a chain may mix codecs, and older
+// backups do not record it, so readers detect it from the data instead.
+func IsCompressed(dat []byte) bool {
+	return IsGZipped(dat) || IsZstdCompressed(dat)
+}
+
+// DecompressData decompresses a gzip or zstd data buffer and returns
+// decompressed bytes.
+func DecompressData(ctx context.Context, mem *mon.BoundAccount, descBytes []byte) ([]byte, error) {
+	if IsZstdCompressed(descBytes) {
+		r, err := zstd.NewReader(bytes.NewReader(descBytes))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return mon.ReadAll(ctx, ioctx.ReaderAdapter(r), mem)
+	}
+	r, err := gzip.NewReader(bytes.NewBuffer(descBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Swallow any errors, this is only a read operation.
+		_ = r.Close()
+	}()
+	return mon.ReadAll(ctx, ioctx.ReaderAdapter(r), mem)
+}
+
+// GetChecksum returns a 32 bit keyed-checksum for the given data.
+func GetChecksum(data []byte) ([]byte, error) {
+	hash := NewChecksumHash()
+	if _, err := hash.Write(data); err != nil {
+		return nil, errors.Wrap(err,
+			`"It never returns an error." -- https://golang.org/pkg/hash`)
+	}
+	return ChecksumFromHash(hash), nil
+}
+
+// NewChecksumHash returns a hash to compute the checksum GetChecksum returns
+// of data written incrementally.
+func NewChecksumHash() hash.Hash {
+	return sha256.New()
+}
+
+// ChecksumFromHash returns the checksum of the data written to a hash returned
+// by NewChecksumHash.
+func ChecksumFromHash(hash hash.Hash) []byte {
+	const checksumSizeBytes = 4
+	return hash.Sum(nil)[:checksumSizeBytes]
+}
+
+// ReadManifestFile reads and unmarshals a BackupManifest from filename in the
+// provided export store. If elideFiles is set, the file list of the manifest,
+// which usually makes up most of it, is left out; NewManifestFileIter can
+// stream it instead.
+func ReadManifestFile(
+	ctx context.Context,
+	mem *mon.BoundAccount,
+	exportStore cloud.ExternalStorage,
+	filename string,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	elideFiles bool,
+) (backuppb.BackupManifest, int64, error) {
+	manifestFile, checksumFile, err := openManifestFile(ctx, exportStore, filename)
+	if err != nil {
+		return backuppb.BackupManifest{}, 0, err
+	}
+	defer manifestFile.Close(ctx)
+	if checksumFile != nil {
+		defer checksumFile.Close(ctx)
+	}
+	return readManifest(ctx, mem, encryption, kmsEnv, manifestFile, checksumFile, elideFiles)
+}
+
+// openManifestFile opens filename in the provided export store, along with
+// its checksum file. The latter is nil if it is not found, as is the case for
+// older backups.
+func openManifestFile(
+	ctx context.Context, exportStore cloud.ExternalStorage, filename string,
+) (manifestFile, checksumFile ioctx.ReadCloserCtx, _ error) {
+	manifestFile, err := exportStore.ReadFile(ctx, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	checksumFile, err = exportStore.ReadFile(ctx, filename+ManifestChecksumSuffix)
+	if err != nil {
+		if !errors.Is(err, cloud.ErrFileDoesNotExist) {
+			_ = manifestFile.Close(ctx)
+			return nil, nil, err
+		}
+		return manifestFile, nil, nil
+	}
+	return manifestFile, checksumFile, nil
+}
+
+// ReadManifest reads and unmarshals a BackupManifest from manifestReader,
+// checking it against the checksum in checksumReader if it is not nil. If the
+// passed bound account is not nil, the bytes read are reserved from it as it is
+// read and then the approximate in-memory size (the total decompressed
+// serialized byte size) is reserved as well before deserialization and
+// returned so that callers can then shrink the bound acct by that amount when
+// they release the returned manifest.
+func ReadManifest(
+	ctx context.Context,
+	mem *mon.BoundAccount,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	manifestReader ioctx.ReadCloserCtx,
+	checksumReader ioctx.ReadCloserCtx,
+) (backuppb.BackupManifest, int64, error) {
+	return readManifest(ctx, mem, encryption, kmsEnv, manifestReader, checksumReader,
+		false /* elideFiles */)
+}
+
+func readManifest(
+	ctx context.Context,
+	mem *mon.BoundAccount,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	manifestReader ioctx.ReadCloserCtx,
+	checksumReader ioctx.ReadCloserCtx,
+	elideFiles bool,
+) (backuppb.BackupManifest, int64, error) {
+	ctx, sp := tracing.ChildSpan(ctx, "backupread.ReadManifest")
+	defer sp.Finish()
+
+	descBytes, err := readManifestBytes(ctx, mem, encryption, kmsEnv, manifestReader, checksumReader)
+	if err != nil {
+		return backuppb.BackupManifest{}, 0, err
+	}
+	defer func() {
+		mem.Shrink(ctx, int64(cap(descBytes)))
+	}()
+
+	manifestBytes := descBytes
+	if elideFiles {
+		if manifestBytes, err = elideManifestFiles(descBytes); err != nil {
+			return backuppb.BackupManifest{}, 0, maybeWrapEncryptedError(err, descBytes, encryption)
+		}
+	}
+
+	approxMemSize := int64(len(manifestBytes))
+	if err := mem.Grow(ctx, approxMemSize); err != nil {
+		return backuppb.BackupManifest{}, 0, err
+	}
+
+	var backupManifest backuppb.BackupManifest
+	if err := protoutil.Unmarshal(manifestBytes, &backupManifest); err != nil {
+		mem.Shrink(ctx, approxMemSize)
+		return backuppb.BackupManifest{}, 0, maybeWrapEncryptedError(err, descBytes, encryption)
+	}
+	if err := UpgradeDescriptors(&backupManifest); err != nil {
+		mem.Shrink(ctx, approxMemSize)
+		return backuppb.BackupManifest{}, 0, err
+	}
+	return backupManifest, approxMemSize, nil
+}
+
+// readManifestBytes reads the serialized manifest from manifestReader,
+// checking it against the checksum in checksumReader if it is not nil, and
+// decrypts and decompresses it. The returned bytes are reserved from the
+// passed bound account, and the caller shrinks it by their capacity once it
+// is done with them.
+func readManifestBytes(
+	ctx context.Context,
+	mem *mon.BoundAccount,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+	manifestReader ioctx.ReadCloserCtx,
+	checksumReader ioctx.ReadCloserCtx,
+) (descBytes []byte, err error) {
+	descBytes, err = mon.ReadAll(ctx, manifestReader, mem)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			mem.Shrink(ctx, int64(cap(descBytes)))
+		}
+	}()
+	if checksumReader != nil {
+		// If there is a checksum file present, check that it matches.
+		checksumFileData, err := ioctx.ReadAll(ctx, checksumReader)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading checksum file")
+		}
+		checksum, err := GetChecksum(descBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "calculating checksum of manifest")
+		}
+		if !bytes.Equal(checksumFileData, checksum) {
+			return nil, errors.Newf("checksum mismatch; expected %s, got %s",
+				hex.EncodeToString(checksumFileData), hex.EncodeToString(checksum))
+		}
+	}
+
+	if encryption != nil {
+		encryptionKey, err := backupencryption.GetEncryptionKey(ctx, encryption, kmsEnv)
+		if err != nil {
+			return nil, err
+		}
+		plaintextBytes, err := storageccl.DecryptFile(ctx, descBytes, encryptionKey, mem)
+		if err != nil {
+			return nil, err
+		}
+		mem.Shrink(ctx, int64(cap(descBytes)))
+		descBytes = plaintextBytes
+	}
+
+	if IsCompressed(descBytes) {
+		decompressedBytes, err := DecompressData(ctx, mem, descBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "decompressing backup manifest")
+		}
+		// Release the compressed bytes from the monitor before we switch descBytes
+		// to point at the decompressed bytes, which the caller will later release.
+		mem.Shrink(ctx, int64(cap(descBytes)))
+		descBytes = decompressedBytes
+	}
+	return descBytes, nil
+}
+
+// maybeWrapEncryptedError hints at the encryption options if the serialized
+// manifest in descBytes, which could not be unmarshaled, appears to be
+// encrypted but was read without them.
+func maybeWrapEncryptedError(
+	err error, descBytes []byte, encryption *jobspb.BackupEncryptionOptions,
+) error {
+	if encryption == nil && storageccl.AppearsEncrypted(descBytes) {
+		return errors.Wrapf(
+			err, "file appears encrypted -- try specifying one of \"%s\" or \"%s\"",
+			backupencryption.BackupOptEncPassphrase, backupencryption.BackupOptEncKMS)
+	}
+	return err
+}
+
+// IndexAtTime returns the index of the latest backup in
+// `backupManifests` with a StartTime >= asOf.
+func IndexAtTime(
+	backupManifests []backuppb.BackupManifest, asOf hlc.Timestamp,
+) (int, error) {
+	if len(backupManifests) == 0 {
+		return -1, errors.New("expected a nonempty backup manifest list, got an empty list")
+	}
+	backupManifestIndex := len(backupManifests) - 1
+	if asOf.IsEmpty() {
+		return backupManifestIndex, nil
+	}
+	for ind, b := range backupManifests {
+		if asOf.Less(b.StartTime) {
+			break
+		}
+		backupManifestIndex = ind
+	}
+	return backupManifestIndex, nil
+}
+
+// DescriptorsAtTime returns the Descriptors found in the last
+// (latest) backup with a StartTime >= asOf.
+func DescriptorsAtTime(
+	backupManifests []backuppb.BackupManifest, asOf hlc.Timestamp,
+) ([]catalog.Descriptor, backuppb.BackupManifest, error) {
+	lastBackupManifest := backupManifests[len(backupManifests)-1]
+
+	if asOf.IsEmpty() {
+		if lastBackupManifest.DescriptorCoverage != tree.AllDescriptors {
+			descs, err := ManifestDescriptors(&lastBackupManifest)
+			return descs, lastBackupManifest, err
+		}
+
+		// Cluster backups with revision history may have included previous database
+		// versions of database descriptors in lastBackupManifest.Descriptors. Find
+		// the correct set of descriptors by going through their revisions. See
+		// #68541.
+		asOf = lastBackupManifest.EndTime
+	}
+
+	for _, b := range backupManifests {
+		if asOf.Less(b.StartTime) {
+			break
+		}
+		lastBackupManifest = b
+	}
+	if len(lastBackupManifest.DescriptorChanges) == 0 {
+		descs, err := ManifestDescriptors(&lastBackupManifest)
+		return descs, lastBackupManifest, err
+	}
+
+	byID := make(map[descpb.ID]catalog.DescriptorBuilder, len(lastBackupManifest.Descriptors))
+	for _, rev := range lastBackupManifest.DescriptorChanges {
+		if asOf.Less(rev.Time) {
+			break
+		}
+		if rev.Desc == nil {
+			delete(byID, rev.ID)
+		} else {
+			byID[rev.ID] = newDescriptorBuilder(rev.Desc, rev.Time)
+		}
+	}
+
+	allDescs := make([]catalog.Descriptor, 0, len(byID))
+	for _, b := range byID {
+		if b == nil {
+			continue
+		}
+		// A revision may have been captured before it was in a DB that is
+		// backed up -- if the DB is missing, filter the object.
+		if err := b.RunPostDeserializationChanges(); err != nil {
+			return nil, backuppb.BackupManifest{}, err
+		}
+		desc := b.BuildCreatedMutable()
+		var isObject bool
+		switch d := desc.(type) {
+		case catalog.TableDescriptor:
+			// Filter out revisions in the dropped state.
+			if d.GetState() == descpb.DescriptorState_DROP {
+				continue
+			}
+			isObject = true
+		case catalog.TypeDescriptor, catalog.SchemaDescriptor:
+			isObject = true
+		}
+		if isObject && byID[desc.GetParentID()] == nil {
+			continue
+		}
+		allDescs = append(allDescs, desc)
+	}
+	return allDescs, lastBackupManifest, nil
+}
+
+// ManifestDescriptors returns the descriptors encoded in the manifest as
+// a slice of mutable descriptors.
+func ManifestDescriptors(
+	backupManifest *backuppb.BackupManifest,
+) ([]catalog.Descriptor, error) {
+	ret := make([]catalog.Descriptor, 0, len(backupManifest.Descriptors))
+	for i := range backupManifest.Descriptors {
+		b := newDescriptorBuilder(&backupManifest.Descriptors[i], backupManifest.EndTime)
+		if b == nil {
+			continue
+		}
+		if err := b.RunPostDeserializationChanges(); err != nil {
+			id, name := descriptorIdentity(&backupManifest.Descriptors[i])
+			return nil, errors.Wrapf(err, "upgrading descriptor %q (%d) from a backup written by %s",
+				name, id, versionString(ManifestVersion(backupManifest)))
+		}
+		ret = append(ret, b.BuildCreatedMutable())
+	}
+	return ret, nil
+}
+
+// NewDescriptorForManifest returns a descriptor instance for a protobuf
+// to be added to a backup manifest or in a backup job.
+// In these cases, we know that the ModificationTime field has already correctly
+// been set in the descriptor protobuf, because this descriptor has been read
+// from storage and therefore has been updated using the MVCC timestamp.
+func NewDescriptorForManifest(descProto *descpb.Descriptor) catalog.Descriptor {
+	b := newDescriptorBuilder(descProto, hlc.Timestamp{})
+	if b == nil {
+		return nil
+	}
+	// No need to call RunPostDeserializationChanges, because the descriptor has
+	// been read from storage and therefore this has been called already.
+	//
+	// Return a mutable descriptor because that's what the call sites assume.
+	// TODO(postamar): revisit that assumption.
+	return b.BuildCreatedMutable()
+}
+
+// newDescriptorBuilder constructs a catalog.DescriptorBuilder instance
+// initialized using the descriptor protobuf message and a fake MVCC timestamp
+// for the purpose of setting the descriptor's ModificationTime field to a
+// valid value if it's still unset.
+func newDescriptorBuilder(
+	descProto *descpb.Descriptor, fakeMVCCTimestamp hlc.Timestamp,
+) catalog.DescriptorBuilder {
+	tbl, db, typ, sc, f := descpb.GetDescriptors(descProto)
+	if tbl != nil {
+		return tabledesc.NewBuilderWithMVCCTimestamp(tbl, fakeMVCCTimestamp)
+	} else if db != nil {
+		return dbdesc.NewBuilderWithMVCCTimestamp(db, fakeMVCCTimestamp)
+	} else if typ != nil {
+		return typedesc.NewBuilderWithMVCCTimestamp(typ, fakeMVCCTimestamp)
+	} else if sc != nil {
+		return schemadesc.NewBuilderWithMVCCTimestamp(sc, fakeMVCCTimestamp)
+	} else if f != nil {
+		return funcdesc.NewBuilderWithMVCCTimestamp(f, fakeMVCCTimestamp)
+	}
+	return nil
+}
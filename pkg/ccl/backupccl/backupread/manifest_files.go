@@ -0,0 +1,163 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupread
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// manifestFilesField is the field number of BackupManifest.Files.
+const manifestFilesField = 4
+
+// Protobuf wire types, as defined by the encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// nextManifestField parses the field at the start of buf, which holds a
+// serialized BackupManifest. It returns the field number and wire type of the
+// field, its payload, which is the encoded bytes of the message for a Files
+// entry, and the length of the whole field, tag included.
+func nextManifestField(buf []byte) (field uint64, wireType int, payload []byte, n int, _ error) {
+	tag, tagLen := binary.Uvarint(buf)
+	if tagLen <= 0 {
+		return 0, 0, nil, 0, errors.New("malformed backup manifest: invalid field tag")
+	}
+	field, wireType = tag>>3, int(tag&7)
+	n = tagLen
+	switch wireType {
+	case wireVarint:
+		_, l := binary.Uvarint(buf[n:])
+		if l <= 0 {
+			return 0, 0, nil, 0, errors.Newf("malformed backup manifest: invalid varint in field %d", field)
+		}
+		payload, n = buf[n:n+l], n+l
+	case wireFixed64, wireFixed32:
+		l := 8
+		if wireType == wireFixed32 {
+			l = 4
+		}
+		if len(buf)-n < l {
+			return 0, 0, nil, 0, errors.Newf("malformed backup manifest: truncated field %d", field)
+		}
+		payload, n = buf[n:n+l], n+l
+	case wireBytes:
+		l, lenLen := binary.Uvarint(buf[n:])
+		if lenLen <= 0 || l > uint64(len(buf)-n-lenLen) {
+			return 0, 0, nil, 0, errors.Newf("malformed backup manifest: truncated field %d", field)
+		}
+		n += lenLen
+		payload, n = buf[n:n+int(l)], n+int(l)
+	default:
+		return 0, 0, nil, 0, errors.Newf(
+			"malformed backup manifest: unexpected wire type %d in field %d", wireType, field)
+	}
+	return field, wireType, payload, n, nil
+}
+
+// elideManifestFiles returns a copy of the serialized BackupManifest in buf
+// without the entries of its Files field, so that the rest of the manifest
+// can be unmarshaled without materializing its file list.
+func elideManifestFiles(buf []byte) ([]byte, error) {
+	out := make([]byte, 0, len(buf))
+	for len(buf) > 0 {
+		field, wireType, _, n, err := nextManifestField(buf)
+		if err != nil {
+			return nil, err
+		}
+		if field != manifestFilesField || wireType != wireBytes {
+			out = append(out, buf[:n]...)
+		}
+		buf = buf[n:]
+	}
+	return out, nil
+}
+
+// ManifestFileIterator iterates over the files of a serialized BackupManifest,
+// unmarshaling each of them only when it is reached.
+type ManifestFileIterator struct {
+	buf []byte
+	err error
+}
+
+// NewManifestFileIter returns an iterator over the files of the manifest of
+// the backup in store. The serialized manifest is read in full, but its files
+// are unmarshaled one at a time, so a caller that stops iterating early does
+// not pay for the remainder of the file list.
+func NewManifestFileIter(
+	ctx context.Context,
+	store cloud.ExternalStorage,
+	encryption *jobspb.BackupEncryptionOptions,
+	kmsEnv cloud.KMSEnv,
+) (*ManifestFileIterator, error) {
+	manifestFile, checksumFile, err := openManifestFile(ctx, store, backupbase.BackupManifestName)
+	if errors.Is(err, cloud.ErrFileDoesNotExist) {
+		manifestFile, checksumFile, err = openManifestFile(ctx, store, backupbase.BackupOldManifestName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer manifestFile.Close(ctx)
+	if checksumFile != nil {
+		defer checksumFile.Close(ctx)
+	}
+	descBytes, err := readManifestBytes(ctx, nil /* mem */, encryption, kmsEnv, manifestFile, checksumFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ManifestFileIterator{buf: descBytes}, nil
+}
+
+// Next retrieves the next file in the iterator.
+//
+// Next returns true if the next file was successfully unmarshalled into file,
+// and false if there are no more files or if an error was encountered. When
+// Next returns false, the user should call the Err method to verify the
+// existence of an error.
+func (mi *ManifestFileIterator) Next(file *backuppb.BackupManifest_File) bool {
+	for mi.err == nil && len(mi.buf) > 0 {
+		field, wireType, payload, n, err := nextManifestField(mi.buf)
+		if err != nil {
+			mi.err = err
+			return false
+		}
+		mi.buf = mi.buf[n:]
+		if field != manifestFilesField || wireType != wireBytes {
+			continue
+		}
+		file.Reset()
+		if err := protoutil.Unmarshal(payload, file); err != nil {
+			mi.err = err
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// Err returns the iterator's error.
+func (mi *ManifestFileIterator) Err() error {
+	return mi.err
+}
+
+// Close closes the iterator.
+func (mi *ManifestFileIterator) Close() {
+	mi.buf = nil
+}
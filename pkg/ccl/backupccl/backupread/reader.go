@@ -0,0 +1,302 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+// Package backupread reads the contents of backups: their manifests, the
+// descriptors they contain and the rows of their tables. It does not depend on
+// the SQL layer of a running cluster, so that it can be embedded in tools
+// inspecting backups outside of a cluster.
+package backupread
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// Options configure how a Chain is read.
+type Options struct {
+	// User is the user the backups are read as.
+	User username.SQLUsername
+
+	// MakeExternalStorageFromURI opens the URIs of the backups of a chain.
+	MakeExternalStorageFromURI cloud.ExternalStorageFromURIFactory
+
+	// MakeExternalStorage opens the directories the manifests of a chain
+	// recorded, from which data files are read.
+	MakeExternalStorage cloud.ExternalStorageFactory
+
+	// EncryptionPassphrase is the passphrase a chain was encrypted with, if
+	// any.
+	EncryptionPassphrase string
+
+	// KMSURIs are the URIs of the KMS a chain was encrypted with, if any, one
+	// of which must be able to decrypt it. They are used in KMSEnv.
+	KMSURIs []string
+	KMSEnv  cloud.KMSEnv
+}
+
+// Chain is a backup chain: a full backup and the incremental backups taken on
+// top of it.
+type Chain struct {
+	opts       Options
+	encryption *jobspb.BackupEncryptionOptions
+	manifests  []backuppb.BackupManifest
+}
+
+// OpenChain reads the manifests of the backup chain whose full backup is at
+// uris[0], followed by its incremental backups at the rest of uris in the
+// order they were taken.
+//
+// Locality-aware backups are not supported.
+func OpenChain(ctx context.Context, uris []string, opts Options) (*Chain, error) {
+	if len(uris) == 0 {
+		return nil, errors.New("a backup chain needs at least a full backup")
+	}
+	c := &Chain{opts: opts}
+
+	var encryptionParams jobspb.BackupEncryptionOptions
+	if opts.EncryptionPassphrase != "" {
+		encryptionParams.Mode = jobspb.EncryptionMode_Passphrase
+		encryptionParams.RawPassphrae = opts.EncryptionPassphrase
+	} else if len(opts.KMSURIs) > 0 {
+		encryptionParams.Mode = jobspb.EncryptionMode_KMS
+		encryptionParams.RawKmsUris = opts.KMSURIs
+	}
+	encryption, err := backupencryption.GetEncryptionFromBase(ctx, opts.User,
+		opts.MakeExternalStorageFromURI, uris[0], encryptionParams, opts.KMSEnv)
+	if err != nil {
+		return nil, err
+	}
+	c.encryption = encryption
+
+	c.manifests = make([]backuppb.BackupManifest, len(uris))
+	for i, uri := range uris {
+		if err := func() error {
+			store, err := opts.MakeExternalStorageFromURI(ctx, uri, opts.User)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			manifest, _, err := ReadManifestFile(ctx, nil /* mem */, store,
+				backupbase.BackupManifestName, c.encryption, opts.KMSEnv, false /* elideFiles */)
+			if errors.Is(err, cloud.ErrFileDoesNotExist) {
+				manifest, _, err = ReadManifestFile(ctx, nil /* mem */, store,
+					backupbase.BackupOldManifestName, c.encryption, opts.KMSEnv, false /* elideFiles */)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "reading manifest of backup %d of the chain", i)
+			}
+			manifest.Dir = store.Conf()
+			c.manifests[i] = manifest
+			return nil
+		}(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 1; i < len(c.manifests); i++ {
+		if !c.manifests[i].StartTime.Equal(c.manifests[i-1].EndTime) {
+			return nil, errors.Newf("backup %d of the chain starts at %s, but the previous one ends at %s",
+				i, c.manifests[i].StartTime, c.manifests[i-1].EndTime)
+		}
+	}
+	return c, nil
+}
+
+// NewChain returns the backup chain of manifests, which were already read and
+// checked by the caller. The data files of the chain are encrypted with
+// encryption if it is set.
+func NewChain(
+	manifests []backuppb.BackupManifest, encryption *jobspb.BackupEncryptionOptions, opts Options,
+) *Chain {
+	return &Chain{opts: opts, encryption: encryption, manifests: manifests}
+}
+
+// Manifests returns the manifests of the backups of the chain.
+func (c *Chain) Manifests() []backuppb.BackupManifest {
+	return c.manifests
+}
+
+// EndTime returns the time the chain is as of.
+func (c *Chain) EndTime() hlc.Timestamp {
+	return c.manifests[len(c.manifests)-1].EndTime
+}
+
+// Descriptors returns the descriptors in the chain as of asOf, or as of the
+// end time of the chain if asOf is empty. A time other than the end time of a
+// backup of the chain requires the chain to have been taken with revision
+// history.
+func (c *Chain) Descriptors(asOf hlc.Timestamp) ([]catalog.Descriptor, error) {
+	descs, _, err := DescriptorsAtTime(c.manifests, asOf)
+	return descs, err
+}
+
+// codec returns the codec of the keys in the chain, that of the tenant the
+// chain was taken in.
+func (c *Chain) codec() (keys.SQLCodec, error) {
+	latest := c.manifests[len(c.manifests)-1]
+	if len(latest.Spans) == 0 || latest.HasTenants() {
+		return keys.SystemSQLCodec, nil
+	}
+	_, tenantID, err := keys.DecodeTenantPrefix(latest.Spans[0].Key)
+	if err != nil {
+		return keys.SQLCodec{}, err
+	}
+	return keys.MakeSQLCodec(tenantID), nil
+}
+
+// ReadTable calls fn with each row of table, one of the descriptors of the
+// chain, as of asOf, or as of the end time of the chain if asOf is empty. The
+// datums passed to fn are only valid until it returns.
+func (c *Chain) ReadTable(
+	ctx context.Context,
+	table catalog.TableDescriptor,
+	asOf hlc.Timestamp,
+	fn func(tree.Datums) error,
+) error {
+	columnIDs := make([]descpb.ColumnID, 0, len(table.PublicColumns()))
+	for _, col := range table.PublicColumns() {
+		columnIDs = append(columnIDs, col.GetID())
+	}
+	return c.ReadIndex(ctx, table, table.GetPrimaryIndex(), columnIDs, asOf, fn)
+}
+
+// ReadIndex is like ReadTable, but reads the entries of index, which can be a
+// secondary index of table. The datums passed to fn are the values of the
+// columns in columnIDs, which must all be available in the index.
+func (c *Chain) ReadIndex(
+	ctx context.Context,
+	table catalog.TableDescriptor,
+	index catalog.Index,
+	columnIDs []descpb.ColumnID,
+	asOf hlc.Timestamp,
+	fn func(tree.Datums) error,
+) error {
+	if asOf.IsEmpty() {
+		asOf = c.EndTime()
+	}
+	if asOf.Less(c.manifests[0].EndTime) || c.EndTime().Less(asOf) {
+		return errors.Newf("the backup chain covers %s to %s, not %s",
+			c.manifests[0].EndTime, c.EndTime(), asOf)
+	}
+	lastIndex, err := IndexAtTime(c.manifests, asOf)
+	if err != nil {
+		return err
+	}
+	codec, err := c.codec()
+	if err != nil {
+		return err
+	}
+	span := table.IndexSpan(codec, index.GetID())
+
+	var spec descpb.IndexFetchSpec
+	if err := rowenc.InitIndexFetchSpec(&spec, codec, table, index, columnIDs); err != nil {
+		return err
+	}
+	var rf row.Fetcher
+	if err := rf.Init(ctx, row.FetcherInitArgs{
+		WillUseCustomKVBatchFetcher: true,
+		Alloc:                       &tree.DatumAlloc{},
+		Spec:                        &spec,
+	}); err != nil {
+		return err
+	}
+
+	var stores []cloud.ExternalStorage
+	defer func() {
+		for _, store := range stores {
+			_ = store.Close()
+		}
+	}()
+	var storeFiles []storageccl.StoreFile
+	var encryption *roachpb.FileEncryptionOptions
+	for _, manifest := range c.manifests[:lastIndex+1] {
+		if c.encryption != nil && !manifest.EncryptionAtRestOnly && encryption == nil {
+			key, err := backupencryption.GetEncryptionKey(ctx, c.encryption, c.opts.KMSEnv)
+			if err != nil {
+				return err
+			}
+			encryption = &roachpb.FileEncryptionOptions{Key: key}
+		}
+		var store cloud.ExternalStorage
+		seen := make(map[string]struct{})
+		for _, f := range manifest.Files {
+			if f.LocalityKV != "" {
+				return errors.New("reading locality-aware backups is not supported")
+			}
+			if _, ok := seen[f.Path]; ok || !f.Span.Overlaps(span) {
+				continue
+			}
+			seen[f.Path] = struct{}{}
+			if store == nil {
+				var err error
+				if store, err = c.opts.MakeExternalStorage(ctx, manifest.Dir); err != nil {
+					return err
+				}
+				stores = append(stores, store)
+			}
+			storeFiles = append(storeFiles, storageccl.StoreFile{
+				Store:          store,
+				FilePath:       f.Path,
+				ExpectedSize:   f.Trailer.Size,
+				ExpectedCRC32C: f.Trailer.CRC32C,
+			})
+		}
+	}
+	if len(storeFiles) == 0 {
+		return nil
+	}
+
+	iter, err := storageccl.ExternalSSTReader(ctx, storeFiles, encryption, storage.IterOptions{
+		RangeKeyMaskingBelow: asOf,
+		KeyTypes:             storage.IterKeyTypePointsAndRanges,
+		LowerBound:           span.Key,
+		UpperBound:           span.EndKey,
+	})
+	if err != nil {
+		return err
+	}
+	kvFetcher := row.MakeBackupSSTKVFetcher(
+		storage.MVCCKey{Key: span.Key}, storage.MVCCKey{Key: span.EndKey},
+		storage.NewReadAsOfIterator(iter, asOf),
+		hlc.Timestamp{}, hlc.Timestamp{}, false, /* withRev */
+	)
+	// The fetcher closes kvFetcher, and the iterator, when it is closed.
+	defer rf.Close(ctx)
+	if err := rf.StartScanFrom(ctx, &kvFetcher); err != nil {
+		return err
+	}
+	for {
+		datums, err := rf.NextRowDecoded(ctx)
+		if err != nil {
+			return err
+		}
+		if datums == nil {
+			return nil
+		}
+		if err := fn(datums); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupread_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadChain(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	tc, sqlDB, _, cleanupFn := backuputils.BackupDestinationTestSetup(t, backuputils.SingleNode,
+		numAccounts, backuputils.InitManualReplication)
+	defer cleanupFn()
+	ctx := context.Background()
+	execCfg := tc.Server(0).ExecutorConfig().(sql.ExecutorConfig)
+
+	const full, inc = "nodelocal://0/full", "nodelocal://0/inc"
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1 WITH encryption_passphrase = 'abc'`, full)
+	fullRows := sqlDB.QueryStr(t, `SELECT id, balance FROM data.bank ORDER BY id`)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1 WHERE id < 5`)
+	sqlDB.Exec(t, `DELETE FROM data.bank WHERE id = 9`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1 INCREMENTAL FROM $2 WITH encryption_passphrase = 'abc'`,
+		inc, full)
+	incRows := sqlDB.QueryStr(t, `SELECT id, balance FROM data.bank ORDER BY id`)
+
+	opts := backupread.Options{
+		User:                       username.RootUserName(),
+		MakeExternalStorageFromURI: execCfg.DistSQLSrv.ExternalStorageFromURI,
+		MakeExternalStorage:        execCfg.DistSQLSrv.ExternalStorage,
+	}
+
+	t.Run("wrong-passphrase", func(t *testing.T) {
+		badOpts := opts
+		badOpts.EncryptionPassphrase = "def"
+		_, err := backupread.OpenChain(ctx, []string{full, inc}, badOpts)
+		require.Error(t, err)
+	})
+
+	opts.EncryptionPassphrase = "abc"
+	chain, err := backupread.OpenChain(ctx, []string{full, inc}, opts)
+	require.NoError(t, err)
+	require.Len(t, chain.Manifests(), 2)
+
+	readBank := func(asOf hlc.Timestamp) [][]string {
+		descs, err := chain.Descriptors(asOf)
+		require.NoError(t, err)
+		var bank catalog.TableDescriptor
+		for _, desc := range descs {
+			if tbl, ok := desc.(catalog.TableDescriptor); ok && tbl.GetName() == "bank" {
+				bank = tbl
+			}
+		}
+		require.NotNil(t, bank)
+
+		var rows [][]string
+		require.NoError(t, chain.ReadTable(ctx, bank, asOf, func(datums tree.Datums) error {
+			rows = append(rows, []string{
+				fmt.Sprint(int64(tree.MustBeDInt(datums[0]))),
+				fmt.Sprint(int64(tree.MustBeDInt(datums[1]))),
+			})
+			return nil
+		}))
+		return rows
+	}
+	require.Equal(t, incRows, readBank(hlc.Timestamp{}))
+	require.Equal(t, fullRows, readBank(chain.Manifests()[0].EndTime))
+}
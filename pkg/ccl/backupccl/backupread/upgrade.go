@@ -0,0 +1,222 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupread
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/version"
+	"github.com/cockroachdb/errors"
+)
+
+// OldestRestorableVersion is the version of the oldest clusters whose backups
+// can be restored. The descriptors of backups written by any version since are
+// brought up to date by the registered descriptor upgrades, followed by the
+// post-deserialization changes of the descriptor builders. Retiring an upgrade
+// requires moving this forward past the version it applies to.
+var OldestRestorableVersion = roachpb.Version{Major: 1, Minor: 0}
+
+// DescriptorUpgrade is a migration of the descriptors found in backups written
+// by clusters older than Before.
+type DescriptorUpgrade struct {
+	// Name identifies the upgrade in errors.
+	Name string
+	// Before is the first version whose backups don't need the upgrade.
+	Before roachpb.Version
+	// Upgrade rewrites desc in place. It must be idempotent, as it is also
+	// applied to the descriptors of backups whose version is unknown.
+	Upgrade func(desc *descpb.Descriptor) error
+}
+
+// UnsupportedConstructCheck returns a description of each construct in desc
+// that can't be restored by this version, such as one which was removed from
+// the product after the backup was written.
+type UnsupportedConstructCheck func(desc *descpb.Descriptor) []string
+
+var (
+	descriptorUpgrades         []DescriptorUpgrade
+	unsupportedConstructChecks []UnsupportedConstructCheck
+)
+
+// RegisterDescriptorUpgrade adds an upgrade to the ones applied to the
+// descriptors of backups as their manifests are read. Upgrades are applied in
+// the order of the versions they apply before.
+func RegisterDescriptorUpgrade(u DescriptorUpgrade) {
+	descriptorUpgrades = append(descriptorUpgrades, u)
+	sort.SliceStable(descriptorUpgrades, func(i, j int) bool {
+		return descriptorUpgrades[i].Before.Less(descriptorUpgrades[j].Before)
+	})
+}
+
+// RegisterUnsupportedConstructCheck adds a check to the ones run over the
+// descriptors of backups before they are restored.
+func RegisterUnsupportedConstructCheck(check UnsupportedConstructCheck) {
+	unsupportedConstructChecks = append(unsupportedConstructChecks, check)
+}
+
+// ManifestVersion returns the version of the cluster which wrote the backup.
+// Backups written before the cluster version was recorded in the manifest fall
+// back to the release of the gateway node's binary. The returned version is
+// empty if neither is known.
+func ManifestVersion(m *backuppb.BackupManifest) roachpb.Version {
+	if m.ClusterVersion.Major != 0 {
+		return m.ClusterVersion
+	}
+	v, err := version.Parse(m.BuildInfo.Tag)
+	if err != nil {
+		return roachpb.Version{}
+	}
+	return roachpb.Version{Major: int32(v.Major()), Minor: int32(v.Minor())}
+}
+
+// UpgradeDescriptors applies the registered upgrades that the version of the
+// cluster which wrote the backup requires to its descriptors and descriptor
+// revisions, in place.
+func UpgradeDescriptors(m *backuppb.BackupManifest) error {
+	v := ManifestVersion(m)
+	for _, u := range descriptorUpgrades {
+		if v != (roachpb.Version{}) && u.Before.LessEq(v) {
+			continue
+		}
+		for i := range m.Descriptors {
+			if err := upgradeDescriptor(u, &m.Descriptors[i], v); err != nil {
+				return err
+			}
+		}
+		for i := range m.DescriptorChanges {
+			if m.DescriptorChanges[i].Desc == nil {
+				continue
+			}
+			if err := upgradeDescriptor(u, m.DescriptorChanges[i].Desc, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func upgradeDescriptor(u DescriptorUpgrade, desc *descpb.Descriptor, v roachpb.Version) error {
+	if err := u.Upgrade(desc); err != nil {
+		id, name := descriptorIdentity(desc)
+		return errors.Wrapf(err, "upgrading descriptor %q (%d) from a backup written by %s with %s",
+			name, id, versionString(v), u.Name)
+	}
+	return nil
+}
+
+// CheckRestorable returns an error if the backup was written by a cluster too
+// old for this version to restore, or if its descriptors contain constructs
+// this version can't restore. The error lists every such construct.
+func CheckRestorable(m *backuppb.BackupManifest) error {
+	v := ManifestVersion(m)
+	if v != (roachpb.Version{}) && v.Less(OldestRestorableVersion) {
+		return pgerror.Newf(pgcode.FeatureNotSupported,
+			"backup from version %s is older than the oldest restorable version %s",
+			v, OldestRestorableVersion)
+	}
+
+	var unsupported []string
+	for i := range m.Descriptors {
+		id, name := descriptorIdentity(&m.Descriptors[i])
+		for _, check := range unsupportedConstructChecks {
+			for _, construct := range check(&m.Descriptors[i]) {
+				unsupported = append(unsupported, fmt.Sprintf("%q (%d): %s", name, id, construct))
+			}
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	return errors.WithDetail(
+		pgerror.Newf(pgcode.FeatureNotSupported,
+			"backup from version %s contains %d construct(s) which cannot be restored by this version",
+			versionString(v), len(unsupported)),
+		strings.Join(unsupported, "\n"),
+	)
+}
+
+func descriptorIdentity(desc *descpb.Descriptor) (descpb.ID, string) {
+	tbl, db, typ, sc, f := descpb.GetDescriptors(desc)
+	switch {
+	case tbl != nil:
+		return tbl.ID, tbl.Name
+	case db != nil:
+		return db.ID, db.Name
+	case typ != nil:
+		return typ.ID, typ.Name
+	case sc != nil:
+		return sc.ID, sc.Name
+	case f != nil:
+		return f.ID, f.Name
+	}
+	return descpb.InvalidID, ""
+}
+
+func versionString(v roachpb.Version) string {
+	if v == (roachpb.Version{}) {
+		return "an unknown version"
+	}
+	return v.String()
+}
+
+func init() {
+	RegisterDescriptorUpgrade(DescriptorUpgrade{
+		Name:   "table modification time",
+		Before: roachpb.Version{Major: 19, Minor: 1},
+		Upgrade: func(desc *descpb.Descriptor) error {
+			// Calls to GetTable are generally frowned upon.
+			// Starting in v19.1 the ModificationTime is always written in backups
+			// for all versions of table descriptors. In earlier cockroach versions
+			// only later table descriptor versions contain a non-empty
+			// ModificationTime. Later versions of CockroachDB use the MVCC
+			// timestamp to fill in the ModificationTime for table descriptors. When
+			// performing a restore we no longer have access to that MVCC timestamp
+			// but we can set it to a value we know will be safe.
+			//
+			// nolint:descriptormarshal
+			if t := desc.GetTable(); t != nil && t.Version == 1 && t.ModificationTime.IsEmpty() {
+				t.ModificationTime = hlc.Timestamp{WallTime: 1}
+			}
+			return nil
+		},
+	})
+
+	RegisterUnsupportedConstructCheck(func(desc *descpb.Descriptor) []string {
+		if newDescriptorBuilder(desc, hlc.Timestamp{}) == nil {
+			return []string{"descriptor of an unknown type"}
+		}
+		return nil
+	})
+	RegisterUnsupportedConstructCheck(func(desc *descpb.Descriptor) []string {
+		// nolint:descriptormarshal
+		t := desc.GetTable()
+		if t == nil {
+			return nil
+		}
+		var unsupported []string
+		if t.FormatVersion > descpb.InterleavedFormatVersion {
+			unsupported = append(unsupported, fmt.Sprintf("table format version %d", t.FormatVersion))
+		}
+		for i := range t.Columns {
+			if t.Columns[i].Type == nil {
+				unsupported = append(unsupported,
+					fmt.Sprintf("column %q of an unknown type", t.Columns[i].Name))
+			}
+		}
+		return unsupported
+	})
+}
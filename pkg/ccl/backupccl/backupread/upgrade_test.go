@@ -0,0 +1,94 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupread_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/build"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgradeDescriptors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	makeManifest := func(tag string, cv roachpb.Version, tables ...*descpb.TableDescriptor) *backuppb.BackupManifest {
+		m := &backuppb.BackupManifest{
+			BuildInfo:      build.Info{Tag: tag},
+			ClusterVersion: cv,
+		}
+		for _, tbl := range tables {
+			m.Descriptors = append(m.Descriptors, descpb.Descriptor{
+				Union: &descpb.Descriptor_Table{Table: tbl},
+			})
+		}
+		return m
+	}
+	newTable := func() *descpb.TableDescriptor {
+		return &descpb.TableDescriptor{
+			ID:            52,
+			Name:          "t",
+			Version:       1,
+			FormatVersion: descpb.InterleavedFormatVersion,
+			Columns:       []descpb.ColumnDescriptor{{ID: 1, Name: "a", Type: types.Int}},
+		}
+	}
+
+	t.Run("version", func(t *testing.T) {
+		require.Equal(t, roachpb.Version{Major: 22, Minor: 1, Internal: 2},
+			backupread.ManifestVersion(makeManifest("v21.2.3", roachpb.Version{Major: 22, Minor: 1, Internal: 2})))
+		require.Equal(t, roachpb.Version{Major: 2, Minor: 1},
+			backupread.ManifestVersion(makeManifest("v2.1.8", roachpb.Version{})))
+		require.Equal(t, roachpb.Version{},
+			backupread.ManifestVersion(makeManifest("", roachpb.Version{})))
+	})
+
+	t.Run("modification-time", func(t *testing.T) {
+		// Backups written before 19.1 may lack the modification time of tables
+		// which were never changed.
+		old := makeManifest("v2.1.8", roachpb.Version{}, newTable())
+		require.NoError(t, backupread.UpgradeDescriptors(old))
+		require.False(t, old.Descriptors[0].GetTable().ModificationTime.IsEmpty())
+
+		recent := makeManifest("", roachpb.Version{Major: 22, Minor: 1}, newTable())
+		require.NoError(t, backupread.UpgradeDescriptors(recent))
+		require.True(t, recent.Descriptors[0].GetTable().ModificationTime.IsEmpty())
+	})
+
+	t.Run("too-old", func(t *testing.T) {
+		err := backupread.CheckRestorable(makeManifest("v0.9.0", roachpb.Version{}, newTable()))
+		require.ErrorContains(t, err, "backup from version 0.9 is older than the oldest restorable version")
+	})
+
+	t.Run("unsupported-constructs", func(t *testing.T) {
+		require.NoError(t, backupread.CheckRestorable(
+			makeManifest("", roachpb.Version{Major: 22, Minor: 1}, newTable())))
+
+		tbl := newTable()
+		tbl.Columns[0].Type = nil
+		m := makeManifest("", roachpb.Version{Major: 22, Minor: 1}, tbl)
+		m.Descriptors = append(m.Descriptors, descpb.Descriptor{})
+		err := backupread.CheckRestorable(m)
+		require.ErrorContains(t, err,
+			"backup from version 22.1 contains 2 construct(s) which cannot be restored by this version")
+		details := strings.Join(errors.GetAllDetails(err), "\n")
+		require.Contains(t, details, `"t" (52): column "a" of an unknown type`)
+		require.Contains(t, details, `"" (0): descriptor of an unknown type`)
+	})
+}
@@ -11,6 +11,7 @@ package backupresolver
 import (
 	"context"
 	"fmt"
+	"path"
 	"sort"
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
@@ -527,9 +528,6 @@ func DescriptorsMatchingTargets(
 			for _, id := range typeIDs {
 				maybeAddTypeDesc(id)
 			}
-			// TODO(chengxiong): get all the user-defined functions used by this
-			// table. This is needed when we start supporting udf references from
-			// other objects.
 		case *tree.AllTablesSelector:
 			// We should only back up targets in the scoped schema if the table
 			// pattern is fully qualified, i.e., `db.schema.*`, both the schema
@@ -624,9 +622,6 @@ func DescriptorsMatchingTargets(
 				for _, id := range typeIDs {
 					maybeAddTypeDesc(id)
 				}
-				// TODO(chengxiong): get all the user-defined functions used by this
-				// table. This is needed when we start supporting udf references from
-				// other objects.
 			case catalog.TypeDescriptor:
 				maybeAddTypeDesc(desc.GetID())
 			case catalog.FunctionDescriptor:
@@ -668,6 +663,72 @@ func DescriptorsMatchingTargets(
 		}
 	}
 
+	// Finally, request the user-defined functions that depend on the directly
+	// requested tables, so that they are backed up and restored along with the
+	// tables instead of having to be recreated. Functions in expanded schemas
+	// have already been requested above. A function is only requested if it can
+	// be restored with the requested descriptors: the sequences it uses are
+	// requested along with it, but it is left out if it depends on any other
+	// relation that was not requested.
+	requestedIDs := catalog.MakeDescriptorIDSet()
+	for _, desc := range ret.Descs {
+		requestedIDs.Add(desc.GetID())
+	}
+	for _, desc := range ret.Descs {
+		tableDesc, ok := desc.(catalog.TableDescriptor)
+		if !ok {
+			continue
+		}
+		if _, ok := alreadyRequestedTables[tableDesc.GetID()]; !ok {
+			continue
+		}
+	nextFunction:
+		for _, ref := range tableDesc.GetDependedOnBy() {
+			fnDesc, ok := r.DescByID[ref.ID].(catalog.FunctionDescriptor)
+			if !ok || requestedIDs.Contains(fnDesc.GetID()) ||
+				fnDesc.GetParentID() != tableDesc.GetParentID() {
+				continue
+			}
+			if err := catalog.FilterDescriptorState(
+				fnDesc, tree.CommonLookupFlags{},
+			); err != nil {
+				continue
+			}
+			var sequences []catalog.TableDescriptor
+			for _, id := range fnDesc.GetDependsOn() {
+				if requestedIDs.Contains(id) {
+					continue
+				}
+				dep, ok := r.DescByID[id].(catalog.TableDescriptor)
+				if !ok || !dep.IsSequence() || dep.GetParentID() != fnDesc.GetParentID() ||
+					catalog.FilterDescriptorState(dep, tree.CommonLookupFlags{}) != nil {
+					continue nextFunction
+				}
+				sequences = append(sequences, dep)
+			}
+			for _, seqDesc := range sequences {
+				if err := maybeAddSchemaDesc(seqDesc.GetParentSchemaID(), true /* requirePublic */); err != nil {
+					return ret, err
+				}
+				ret.Descs = append(ret.Descs, seqDesc)
+				requestedIDs.Add(seqDesc.GetID())
+			}
+			if err := maybeAddSchemaDesc(fnDesc.GetParentSchemaID(), true /* requirePublic */); err != nil {
+				return ret, err
+			}
+			// Request the array types of the types the function uses too, as
+			// restoring a type requires its array type.
+			for _, id := range fnDesc.GetDependsOnTypes() {
+				maybeAddTypeDesc(id)
+				if typDesc, err := getTypeByID(id); err == nil && typDesc.GetArrayTypeID() != descpb.InvalidID {
+					maybeAddTypeDesc(typDesc.GetArrayTypeID())
+				}
+			}
+			ret.Descs = append(ret.Descs, fnDesc)
+			requestedIDs.Add(fnDesc.GetID())
+		}
+	}
+
 	return ret, nil
 }
 
@@ -721,3 +782,100 @@ func ResolveTargetsToDescriptors(
 
 	return matched.Descs, matched.ExpandedDB, matched.RequestedDBs, matched.DescsByTablePattern, nil
 }
+
+// FilterDatabaseTables returns descs without the tables of the databases in
+// dbIDs that are filtered out by patterns: if exclude is true, tables whose
+// names match any of the patterns are removed, otherwise those whose names
+// match none of them are. Patterns are matched against the unqualified table
+// name using the syntax of path.Match. All other descriptors are kept.
+//
+// A table that is kept may not reference a table that is filtered out, be it
+// through a foreign key, as a view, or through a sequence it uses or owns or
+// that owns it, since the backup could not be restored without it.
+func FilterDatabaseTables(
+	descs []catalog.Descriptor, dbIDs []descpb.ID, patterns []string, exclude bool,
+) ([]catalog.Descriptor, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid table name pattern %q", pattern)
+		}
+	}
+	matches := func(name string) bool {
+		for _, pattern := range patterns {
+			// The pattern was validated above.
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	var filteredDBs catalog.DescriptorIDSet
+	for _, id := range dbIDs {
+		filteredDBs.Add(id)
+	}
+	filteredOut := make(map[descpb.ID]catalog.TableDescriptor)
+	ret := make([]catalog.Descriptor, 0, len(descs))
+	for _, desc := range descs {
+		if tbl, ok := desc.(catalog.TableDescriptor); ok && filteredDBs.Contains(tbl.GetParentID()) {
+			if matches(tbl.GetName()) == exclude {
+				filteredOut[tbl.GetID()] = tbl
+				continue
+			}
+		}
+		ret = append(ret, desc)
+	}
+	if len(filteredOut) == 0 {
+		return ret, nil
+	}
+	for _, desc := range ret {
+		if tbl, ok := desc.(catalog.TableDescriptor); ok {
+			if err := checkFilteredOutReferences(tbl, filteredOut); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ret, nil
+}
+
+// checkFilteredOutReferences returns an error if tbl references any of the
+// tables in filteredOut.
+func checkFilteredOutReferences(
+	tbl catalog.TableDescriptor, filteredOut map[descpb.ID]catalog.TableDescriptor,
+) error {
+	check := func(id descpb.ID, verb string) error {
+		if ref, ok := filteredOut[id]; ok {
+			return errors.Errorf("cannot filter %q out of the backup: %q %s it",
+				ref.GetName(), tbl.GetName(), verb)
+		}
+		return nil
+	}
+	if err := tbl.ForeachOutboundFK(func(fk *descpb.ForeignKeyConstraint) error {
+		return check(fk.ReferencedTableID, "references")
+	}); err != nil {
+		return err
+	}
+	for _, id := range tbl.GetDependsOn() {
+		if err := check(id, "depends on"); err != nil {
+			return err
+		}
+	}
+	for _, col := range tbl.AllColumns() {
+		for i := 0; i < col.NumUsesSequences(); i++ {
+			if err := check(col.GetUsesSequenceID(i), "uses"); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < col.NumOwnsSequences(); i++ {
+			if err := check(col.GetOwnsSequenceID(i), "owns"); err != nil {
+				return err
+			}
+		}
+	}
+	if tbl.IsSequence() && tbl.GetSequenceOpts().HasOwner() {
+		if err := check(tbl.GetSequenceOpts().SequenceOwner.OwnerTableID, "is owned by"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -297,3 +297,108 @@ func TestDescriptorsMatchingTargets(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterDatabaseTables(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	mkTable := func(id, parentID descpb.ID, name string) catalog.Descriptor {
+		return tabledesc.NewBuilder(&descpb.TableDescriptor{
+			ID: id, ParentID: parentID, UnexposedParentSchemaID: keys.PublicSchemaIDForBackup, Name: name,
+		}).BuildImmutable()
+	}
+	mkDB := func(id descpb.ID, name string) catalog.Descriptor {
+		return dbdesc.NewInitial(id, name, username.AdminRoleName(), dbdesc.WithPublicSchemaID(keys.SystemPublicSchemaID))
+	}
+	descriptors := []catalog.Descriptor{
+		mkDB(1, "a"),
+		mkTable(2, 1, "orders"),
+		mkTable(3, 1, "orders_staging"),
+		mkTable(4, 1, "tmp_load"),
+		mkDB(5, "b"),
+		mkTable(6, 5, "tmp_other"),
+	}
+
+	tests := []struct {
+		patterns []string
+		exclude  bool
+		expected []string
+		err      string
+	}{
+		{[]string{"*_staging", "tmp_*"}, true, []string{"a", "b", "orders", "tmp_other"}, ``},
+		{[]string{"orders*"}, false, []string{"a", "b", "orders", "orders_staging", "tmp_other"}, ``},
+		{[]string{"nomatch"}, true, []string{"a", "b", "orders", "orders_staging", "tmp_load", "tmp_other"}, ``},
+		{[]string{"nomatch"}, false, []string{"a", "b", "tmp_other"}, ``},
+		{[]string{"[a-"}, true, nil, `invalid table name pattern "\[a-"`},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d/%s/%t", i, test.patterns, test.exclude), func(t *testing.T) {
+			filtered, err := FilterDatabaseTables(descriptors, []descpb.ID{1}, test.patterns, test.exclude)
+			if test.err != "" {
+				if !testutils.IsError(err, test.err) {
+					t.Fatalf("expected error matching '%v', but got '%v'", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, desc := range filtered {
+				names = append(names, desc.GetName())
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(test.expected, names) {
+				t.Fatalf("expected %q got %q", test.expected, names)
+			}
+		})
+	}
+
+	// A table that is kept may not reference one that is filtered out.
+	mkRef := func(id descpb.ID, name string, ref func(*descpb.TableDescriptor)) catalog.Descriptor {
+		desc := &descpb.TableDescriptor{
+			ID: id, ParentID: 1, UnexposedParentSchemaID: keys.PublicSchemaIDForBackup, Name: name,
+			Columns: []descpb.ColumnDescriptor{{ID: 1, Name: "x"}},
+		}
+		ref(desc)
+		return tabledesc.NewBuilder(desc).BuildImmutable()
+	}
+	refTests := []struct {
+		name string
+		ref  func(*descpb.TableDescriptor)
+		err  string
+	}{
+		{"fk", func(desc *descpb.TableDescriptor) {
+			desc.OutboundFKs = []descpb.ForeignKeyConstraint{{ReferencedTableID: 4, Name: "fk"}}
+		}, `cannot filter "tmp_load" out of the backup: "ref" references it`},
+		{"view", func(desc *descpb.TableDescriptor) {
+			desc.DependsOn = []descpb.ID{4}
+		}, `cannot filter "tmp_load" out of the backup: "ref" depends on it`},
+		{"uses-sequence", func(desc *descpb.TableDescriptor) {
+			desc.Columns[0].UsesSequenceIds = []descpb.ID{4}
+		}, `cannot filter "tmp_load" out of the backup: "ref" uses it`},
+		{"owns-sequence", func(desc *descpb.TableDescriptor) {
+			desc.Columns[0].OwnsSequenceIds = []descpb.ID{4}
+		}, `cannot filter "tmp_load" out of the backup: "ref" owns it`},
+		{"owned-sequence", func(desc *descpb.TableDescriptor) {
+			desc.Columns = nil
+			desc.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+				Increment:     1,
+				SequenceOwner: descpb.TableDescriptor_SequenceOpts_SequenceOwner{OwnerTableID: 4, OwnerColumnID: 1},
+			}
+		}, `cannot filter "tmp_load" out of the backup: "ref" is owned by it`},
+	}
+	for _, test := range refTests {
+		t.Run(test.name, func(t *testing.T) {
+			withRef := append(descriptors[:len(descriptors):len(descriptors)], mkRef(7, "ref", test.ref))
+			_, err := FilterDatabaseTables(withRef, []descpb.ID{1}, []string{"tmp_*"}, true /* exclude */)
+			if !testutils.IsError(err, test.err) {
+				t.Fatalf("expected error matching '%v', but got '%v'", test.err, err)
+			}
+			// The reference does not matter if the referenced table is kept.
+			if _, err := FilterDatabaseTables(withRef, []descpb.ID{1}, []string{"*_staging"}, true /* exclude */); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -11,21 +11,139 @@ package backuputils
 import (
 	"net/url"
 	"path"
+	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/errors"
 )
 
 // URLSeparator represents the standard separator used in backup URLs.
 const URLSeparator = '/'
 
+// signedURLParams are query parameters that carry request signatures or
+// temporary credentials in pre-signed URLs. They are not registered by any
+// external storage provider, since they are never consumed as configuration,
+// but they can still find their way into a user supplied URI and must never be
+// surfaced in an error message or log line.
+var signedURLParams = []string{
+	// AWS SigV4 pre-signed URLs.
+	"X-Amz-Signature",
+	"X-Amz-Credential",
+	"X-Amz-Security-Token",
+	// GCS V4 signed URLs.
+	"X-Goog-Signature",
+	"X-Goog-Credential",
+	// Azure shared access signatures.
+	"sig",
+	// Legacy query string authentication.
+	"Signature",
+}
+
+// redactedURIPlaceholder is returned in place of a URI that could not be
+// parsed and therefore could not be safely redacted.
+const redactedURIPlaceholder = "<uri_failed_to_redact>"
+
 // RedactURIForErrorMessage redacts any storage secrets before returning a URI which is safe to
 // return to the client in an error message.
+//
+// The scheme, host (bucket), path and the COCKROACH_LOCALITY parameter are
+// preserved so that the message remains useful, while registered provider
+// secrets, signed-URL parameters and any password in the userinfo component
+// are replaced.
 func RedactURIForErrorMessage(uri string) string {
-	redactedURI, err := cloud.SanitizeExternalStorageURI(uri, []string{})
+	redactedURI, err := cloud.SanitizeExternalStorageURI(uri, signedURLParams)
 	if err != nil {
-		return "<uri_failed_to_redact>"
+		return redactedURIPlaceholder
+	}
+	parsed, err := url.Parse(redactedURI)
+	if err != nil {
+		return redactedURIPlaceholder
+	}
+	if parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			parsed.User = url.UserPassword(parsed.User.Username(), "redacted")
+		}
+	}
+	return parsed.String()
+}
+
+// RedactURIsForErrorMessage is like RedactURIForErrorMessage, but for a list of
+// URIs, such as the per-locality URIs of a partitioned backup.
+func RedactURIsForErrorMessage(uris []string) []string {
+	redacted := make([]string, len(uris))
+	for i := range uris {
+		redacted[i] = RedactURIForErrorMessage(uris[i])
+	}
+	return redacted
+}
+
+// uriInMessageRE matches the URIs in an error message, along with any
+// punctuation that follows them.
+var uriInMessageRE = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// RedactURIsInMessage returns msg with the URIs in it that hold secrets
+// replaced as by RedactURIForErrorMessage, and whether there were any.
+func RedactURIsInMessage(msg string) (string, bool) {
+	var redacted bool
+	msg = uriInMessageRE.ReplaceAllStringFunc(msg, func(match string) string {
+		uri := strings.TrimRight(match, ".,;:)")
+		if !uriHoldsSecrets(uri) {
+			return match
+		}
+		redacted = true
+		return RedactURIForErrorMessage(uri) + match[len(uri):]
+	})
+	return msg, redacted
+}
+
+// uriHoldsSecrets returns whether RedactURIForErrorMessage redacts anything
+// out of uri, rather than only normalizing it. A URI that cannot be parsed is
+// assumed to hold secrets.
+func uriHoldsSecrets(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return true
+	}
+	redacted, err := url.Parse(RedactURIForErrorMessage(uri))
+	if err != nil {
+		return true
+	}
+	return parsed.User.String() != redacted.User.String() ||
+		!reflect.DeepEqual(parsed.Query(), redacted.Query())
+}
+
+// RedactURIsInError returns err, unless its message holds URIs with secrets,
+// in which case it returns an error whose message is that of err with them
+// redacted, as by RedactURIsInMessage. The rest of the chain of err is not
+// kept, since the messages in it hold the secrets, except for its pgcode.
+func RedactURIsInError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg, redacted := RedactURIsInMessage(err.Error())
+	if !redacted {
+		return err
+	}
+	redactedErr := errors.Newf("%s", msg)
+	if code := pgerror.GetPGCode(err); code != pgcode.Uncategorized {
+		redactedErr = pgerror.WithCandidateCode(redactedErr, code)
+	}
+	return redactedErr
+}
+
+// RedactURLParseError strips the raw URI out of the *url.Error returned by
+// url.Parse, which otherwise embeds the unredacted URI in its message. Errors
+// of any other type are returned unchanged.
+func RedactURLParseError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
 	}
-	return redactedURI
+	return errors.Wrapf(urlErr.Err, "%s %s", urlErr.Op, RedactURIForErrorMessage(urlErr.URL))
 }
 
 // JoinURLPath forces a relative path join by removing any leading slash, then
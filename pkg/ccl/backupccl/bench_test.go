@@ -9,10 +9,17 @@
 package backupccl
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl/sampledataccl"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing/tracingpb"
 	"github.com/cockroachdb/cockroach/pkg/workload/bank"
 )
 
@@ -147,3 +154,67 @@ func BenchmarkDatabaseFullBackup(b *testing.B) {
 	// *skip*--i.e., the number of bytes in the full backup.
 	b.SetBytes(int64(b.N) * dataSize)
 }
+
+// BenchmarkExportStatsAggregation compares recording the stats of every
+// ExportResponse on the span of the backup processor, which serializes the
+// workers of the processor on the mutex of the span, with accumulating them
+// per worker and recording them once the workers are done.
+func BenchmarkExportStatsAggregation(b *testing.B) {
+	// Enough workers to keep every core of a 64-vCPU node busy, as a backup
+	// processor does when kv.bulk_io_write.concurrent_export_requests is raised
+	// on such nodes.
+	const numWorkers = 64
+
+	resp := &roachpb.ExportResponse{Files: []roachpb.ExportResponse_File{
+		{SST: make([]byte, 1<<10)},
+		{SST: make([]byte, 1<<10)},
+	}}
+
+	run := func(
+		b *testing.B,
+		work func(sp *tracing.Span, worker, numResponses int),
+		done func(sp *tracing.Span),
+	) {
+		tr := tracing.NewTracer()
+		sp := tr.StartSpan("backup", tracing.WithRecording(tracingpb.RecordingStructured))
+		defer sp.Finish()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		if err := ctxgroup.GroupWorkers(context.Background(), numWorkers,
+			func(_ context.Context, worker int) error {
+				work(sp, worker, b.N/numWorkers)
+				return nil
+			}); err != nil {
+			b.Fatal(err)
+		}
+		done(sp)
+		b.StopTimer()
+	}
+
+	b.Run("per-response", func(b *testing.B) {
+		run(b, func(sp *tracing.Span, _, numResponses int) {
+			for i := 0; i < numResponses; i++ {
+				exportStats := backuppb.ExportStats{Duration: time.Millisecond}
+				for _, f := range resp.Files {
+					exportStats.NumFiles++
+					exportStats.DataSize += int64(len(f.SST))
+				}
+				sp.RecordStructured(&exportStats)
+			}
+		}, func(*tracing.Span) {})
+	})
+
+	b.Run("per-worker", func(b *testing.B) {
+		workerStats := make([]backuppb.ExportStats, numWorkers)
+		run(b, func(_ *tracing.Span, worker, numResponses int) {
+			var exportStats backuppb.ExportStats
+			for i := 0; i < numResponses; i++ {
+				addExportStats(&exportStats, resp, time.Millisecond)
+			}
+			workerStats[worker] = exportStats
+		}, func(sp *tracing.Span) {
+			recordExportStats(sp, workerStats)
+		})
+	})
+}
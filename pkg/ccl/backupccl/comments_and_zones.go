@@ -0,0 +1,150 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// Non-cluster backups do not include system.comments and system.zones, so
+// when asked to, they capture the rows of those tables that belong to the
+// backed up descriptors in the manifest instead. RESTORE writes them back
+// for the restored descriptors under their new IDs, with their regions renamed
+// if the restore remaps them.
+
+func descIDsArray(descs []descpb.Descriptor) (*tree.DArray, error) {
+	ids := tree.NewDArray(types.Int)
+	for i := range descs {
+		id, _, _, _, err := descpb.GetDescriptorMetadata(&descs[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := ids.Append(tree.NewDInt(tree.DInt(id))); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// getDescriptorComments returns the comments on descs as of asOf.
+func getDescriptorComments(
+	ctx context.Context, ie *sql.InternalExecutor, descs []descpb.Descriptor, asOf hlc.Timestamp,
+) ([]backuppb.DescriptorComment, error) {
+	ids, err := descIDsArray(descs)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ie.QueryBuffered(ctx, "backup-get-comments", nil, /* txn */
+		fmt.Sprintf(`SELECT type, object_id, sub_id, comment FROM system.comments
+AS OF SYSTEM TIME %s WHERE object_id = ANY($1) ORDER BY object_id, type, sub_id`,
+			asOf.AsOfSystemTime()), ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading comments")
+	}
+	comments := make([]backuppb.DescriptorComment, len(rows))
+	for i, row := range rows {
+		comments[i] = backuppb.DescriptorComment{
+			Type:     int64(tree.MustBeDInt(row[0])),
+			ObjectID: descpb.ID(tree.MustBeDInt(row[1])),
+			SubID:    int64(tree.MustBeDInt(row[2])),
+			Comment:  string(tree.MustBeDString(row[3])),
+		}
+	}
+	return comments, nil
+}
+
+// getDescriptorZoneConfigs returns the zone configurations of descs as of
+// asOf.
+func getDescriptorZoneConfigs(
+	ctx context.Context, ie *sql.InternalExecutor, descs []descpb.Descriptor, asOf hlc.Timestamp,
+) ([]backuppb.DescriptorZoneConfig, error) {
+	ids, err := descIDsArray(descs)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ie.QueryBuffered(ctx, "backup-get-zone-configs", nil, /* txn */
+		fmt.Sprintf(`SELECT id, config FROM system.zones AS OF SYSTEM TIME %s
+WHERE id = ANY($1) ORDER BY id`, asOf.AsOfSystemTime()), ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading zone configurations")
+	}
+	zones := make([]backuppb.DescriptorZoneConfig, len(rows))
+	for i, row := range rows {
+		zones[i] = backuppb.DescriptorZoneConfig{
+			ID:     descpb.ID(tree.MustBeDInt(row[0])),
+			Config: []byte(tree.MustBeDBytes(row[1])),
+		}
+	}
+	return zones, nil
+}
+
+// restoreCommentsAndZoneConfigs writes the comments and zone configurations
+// captured in manifest for the restored descriptors, rekeyed to their new IDs.
+// Those of descriptors that were not restored are ignored.
+func restoreCommentsAndZoneConfigs(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	details jobspb.RestoreDetails,
+	manifest backuppb.BackupManifest,
+) error {
+	var comments []backuppb.DescriptorComment
+	if !details.SkipComments {
+		comments = manifest.Comments
+	}
+	var zones []backuppb.DescriptorZoneConfig
+	if !details.SkipZoneConfigs {
+		zones = manifest.ZoneConfigs
+	}
+	if len(comments) == 0 && len(zones) == 0 {
+		return nil
+	}
+
+	return execCfg.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		for _, c := range comments {
+			rewrite, ok := details.DescriptorRewrites[c.ObjectID]
+			if !ok {
+				continue
+			}
+			if _, err := execCfg.InternalExecutor.Exec(ctx, "restore-comment", txn,
+				`UPSERT INTO system.comments (type, object_id, sub_id, comment) VALUES ($1, $2, $3, $4)`,
+				c.Type, rewrite.ID, c.SubID, c.Comment,
+			); err != nil {
+				return errors.Wrap(err, "restoring comment")
+			}
+		}
+		for _, z := range zones {
+			rewrite, ok := details.DescriptorRewrites[z.ID]
+			if !ok {
+				continue
+			}
+			config, err := remapEncodedZoneConfigRegions(z.Config, details.RegionRemapping)
+			if err != nil {
+				return err
+			}
+			if _, err := execCfg.InternalExecutor.Exec(ctx, "restore-zone-config", txn,
+				`UPSERT INTO system.zones (id, config) VALUES ($1, $2)`,
+				rewrite.ID, tree.NewDBytes(tree.DBytes(config)),
+			); err != nil {
+				return errors.Wrap(err, "restoring zone configuration")
+			}
+		}
+		return nil
+	})
+}
@@ -30,11 +30,14 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log/eventpb"
 	"github.com/cockroachdb/errors"
 	pbtypes "github.com/gogo/protobuf/types"
@@ -47,6 +50,7 @@ const (
 	optOnPreviousRunning       = "on_previous_running"
 	optIgnoreExistingBackups   = "ignore_existing_backups"
 	optUpdatesLastBackupMetric = "updates_cluster_last_backup_time_metric"
+	optIncChangeThreshold      = "incremental_change_threshold"
 )
 
 var scheduledBackupOptionExpectValues = map[string]sql.KVStringOptValidate{
@@ -55,6 +59,7 @@ var scheduledBackupOptionExpectValues = map[string]sql.KVStringOptValidate{
 	optOnPreviousRunning:       sql.KVStringOptRequireValue,
 	optIgnoreExistingBackups:   sql.KVStringOptRequireNoValue,
 	optUpdatesLastBackupMetric: sql.KVStringOptRequireNoValue,
+	optIncChangeThreshold:      sql.KVStringOptRequireValue,
 }
 
 // scheduledBackupGCProtectionEnabled is used to enable and disable the chaining
@@ -169,6 +174,19 @@ func makeScheduleDetails(opts map[string]string) (jobspb.ScheduleDetails, error)
 	return details, nil
 }
 
+// parseIncChangeThreshold parses the value of the incremental_change_threshold
+// schedule option. A threshold of 0 disables it.
+func parseIncChangeThreshold(threshold string) (int64, error) {
+	size, err := humanizeutil.ParseBytes(threshold)
+	if err != nil {
+		return 0, pgerror.Wrapf(err, pgcode.InvalidParameterValue, "invalid %s", optIncChangeThreshold)
+	}
+	if size < 0 {
+		return 0, pgerror.Newf(pgcode.InvalidParameterValue, "%s cannot be negative", optIncChangeThreshold)
+	}
+	return size, nil
+}
+
 func scheduleFirstRun(evalCtx *eval.Context, opts map[string]string) (*time.Time, error) {
 	if v, ok := opts[optFirstRun]; ok {
 		firstRun, _, err := tree.ParseDTimestampTZ(evalCtx, v, time.Microsecond)
@@ -245,6 +263,11 @@ func pickFullRecurrenceFromIncremental(inc *scheduleRecurrence) *scheduleRecurre
 
 const scheduleBackupOp = "CREATE SCHEDULE FOR BACKUP"
 
+// waitingForInitialBackupStatus is the status of an incremental backup
+// schedule that is paused until the first run of its full backup schedule
+// completes.
+const waitingForInitialBackupStatus = "Waiting for initial backup to complete"
+
 // doCreateBackupSchedule creates requested schedule (or schedules).
 // It is a plan hook implementation responsible for the creating of scheduled backup.
 func doCreateBackupSchedules(
@@ -401,6 +424,18 @@ func doCreateBackupSchedules(
 		return err
 	}
 
+	var incChangeThreshold int64
+	if threshold, ok := scheduleOptions[optIncChangeThreshold]; ok {
+		if incRecurrence == nil {
+			return errors.Newf("%s requires a schedule that runs incremental backups",
+				optIncChangeThreshold)
+		}
+		incChangeThreshold, err = parseIncChangeThreshold(threshold)
+		if err != nil {
+			return err
+		}
+	}
+
 	ex := p.ExecCfg().InternalExecutor
 
 	unpauseOnSuccessID := jobs.InvalidScheduleID
@@ -425,13 +460,13 @@ func doCreateBackupSchedules(
 		}
 		inc, incScheduledBackupArgs, err = makeBackupSchedule(
 			env, p.User(), scheduleLabel, incRecurrence, details, unpauseOnSuccessID,
-			updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords)
+			updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords, incChangeThreshold)
 		if err != nil {
 			return err
 		}
 		// Incremental is paused until FULL completes.
 		inc.Pause()
-		inc.SetScheduleStatus("Waiting for initial backup to complete")
+		inc.SetScheduleStatus(waitingForInitialBackupStatus)
 
 		if err := inc.Create(ctx, ex, p.Txn()); err != nil {
 			return err
@@ -449,7 +484,7 @@ func doCreateBackupSchedules(
 	var fullScheduledBackupArgs *backuppb.ScheduledBackupExecutionArgs
 	full, fullScheduledBackupArgs, err := makeBackupSchedule(
 		env, p.User(), scheduleLabel, fullRecurrence, details, unpauseOnSuccessID,
-		updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords)
+		updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords, incChangeThreshold)
 	if err != nil {
 		return err
 	}
@@ -555,6 +590,7 @@ func makeBackupSchedule(
 	updateLastMetricOnSuccess bool,
 	backupNode *tree.Backup,
 	chainProtectedTimestampRecords bool,
+	incChangeThreshold int64,
 ) (*jobs.ScheduledJob, *backuppb.ScheduledBackupExecutionArgs, error) {
 	sj := jobs.NewScheduledJob(env)
 	sj.SetScheduleLabel(label)
@@ -565,6 +601,7 @@ func makeBackupSchedule(
 		UnpauseOnSuccess:               unpauseOnSuccess,
 		UpdatesLastBackupMetric:        updateLastMetricOnSuccess,
 		ChainProtectedTimestampRecords: chainProtectedTimestampRecords,
+		IncrementalChangeThreshold:     incChangeThreshold,
 	}
 	if backupNode.AppendToLatest {
 		args.BackupType = backuppb.ScheduledBackupExecutionArgs_INCREMENTAL
@@ -764,6 +801,9 @@ func makeScheduledBackupEval(
 	ctx context.Context, p sql.PlanHookState, schedule *tree.ScheduledBackup,
 ) (*scheduledBackupEval, error) {
 	var err error
+	if schedule.Targets != nil && schedule.Targets.AllTenants {
+		return nil, errors.New("scheduled backups of VIRTUAL CLUSTER ALL are not supported")
+	}
 	if schedule.Targets != nil && schedule.Targets.Tables.TablePatterns != nil {
 		// Table backup targets must be fully qualified during scheduled backup
 		// planning. This is because the actual execution of the backup job occurs
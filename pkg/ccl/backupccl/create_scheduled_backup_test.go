@@ -822,17 +822,151 @@ INSERT INTO t1 values (-1), (10), (-100);
 				require.NoError(t, err)
 				r, err := backupdest.FindLatestFile(ctx, store)
 				require.NoError(t, err)
-				latest, err := ioctx.ReadAll(ctx, r)
+				data, err := ioctx.ReadAll(ctx, r)
+				require.NoError(t, err)
+				latest, err := backupdest.DecodeLatestFile(data)
 				require.NoError(t, err)
 				backedUp := th.sqlDB.QueryStr(t,
 					`SELECT database_name, object_name FROM [SHOW BACKUP $1] WHERE object_type='table' ORDER BY database_name, object_name`,
-					fmt.Sprintf("%s/%s", destination, string(latest)))
+					fmt.Sprintf("%s/%s", destination, latest.Subdir))
 				require.Equal(t, tc.verifyTables, backedUp)
 			})
 		}
 	}
 }
 
+func TestScheduleBackupIncChangeThreshold(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	th, cleanup := newTestHelper(t)
+	defer cleanup()
+
+	th.sqlDB.Exec(t, `
+CREATE DATABASE db;
+USE db;
+CREATE TABLE t(a int PRIMARY KEY, b string);
+INSERT INTO t VALUES (1, 'a');
+`)
+
+	th.cfg.TestingKnobs.(*jobs.TestingKnobs).OverrideAsOfClause = func(clause *tree.AsOfClause, _ time.Time) {
+		expr, err := tree.MakeDTimestampTZ(th.cfg.DB.Clock().PhysicalTime(), time.Microsecond)
+		require.NoError(t, err)
+		clause.Expr = expr
+	}
+
+	_, err := th.createBackupSchedule(t, `CREATE SCHEDULE FOR BACKUP db.t INTO $1
+RECURRING '@hourly' FULL BACKUP ALWAYS WITH SCHEDULE OPTIONS incremental_change_threshold = '1KiB'`,
+		"nodelocal://0/backup/inc-threshold-full")
+	require.Regexp(t, "incremental_change_threshold requires a schedule that runs incremental backups", err)
+
+	schedules, err := th.createBackupSchedule(t, `CREATE SCHEDULE FOR BACKUP db.t INTO $1
+RECURRING '@hourly' FULL BACKUP '@daily' WITH SCHEDULE OPTIONS incremental_change_threshold = '16KiB'`,
+		"nodelocal://0/backup/inc-threshold")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(schedules))
+	full, inc := schedules[0], schedules[1]
+	if full.IsPaused() {
+		full, inc = inc, full
+	}
+
+	loadArgs := func(scheduleID int64) *backuppb.ScheduledBackupExecutionArgs {
+		args := &backuppb.ScheduledBackupExecutionArgs{}
+		require.NoError(t, pbtypes.UnmarshalAny(th.loadSchedule(t, scheduleID).ExecutionArgs().Args, args))
+		return args
+	}
+	numJobs := func(scheduleID int64) int {
+		var n int
+		th.sqlDB.QueryRow(t, "SELECT count(*) FROM "+th.env.SystemJobsTableName()+
+			" WHERE created_by_type=$1 AND created_by_id=$2",
+			jobs.CreatedByScheduledJobs, scheduleID).Scan(&n)
+		return n
+	}
+	runSchedule := func(scheduleID int64) {
+		th.env.SetTime(th.loadSchedule(t, scheduleID).NextRun().Add(time.Second))
+		require.NoError(t, th.executeSchedules())
+	}
+
+	// The full backup records the baseline of the incremental schedule.
+	runSchedule(full.ScheduleID())
+	th.waitForSuccessfulScheduledJob(t, full.ScheduleID())
+	incArgs := loadArgs(inc.ScheduleID())
+	require.EqualValues(t, 16<<10, incArgs.IncrementalChangeThreshold)
+	require.True(t, incArgs.LiveBytesRecorded)
+	require.Equal(t, loadArgs(full.ScheduleID()).LiveBytesAtRunningBackup, incArgs.LiveBytesAtLastBackup)
+
+	// Nothing changed, so the incremental is skipped.
+	runSchedule(inc.ScheduleID())
+	require.Equal(t, 0, numJobs(inc.ScheduleID()))
+	require.Contains(t, th.loadSchedule(t, inc.ScheduleID()).ScheduleStatus(), "skipped")
+
+	// Once enough data is written, the incremental runs and moves the baseline.
+	th.sqlDB.Exec(t, `INSERT INTO db.t SELECT i, repeat('x', 100) FROM generate_series(2, 1000) AS g(i)`)
+	runSchedule(inc.ScheduleID())
+	th.waitForSuccessfulScheduledJob(t, inc.ScheduleID())
+	require.Equal(t, 1, numJobs(inc.ScheduleID()))
+	updatedArgs := loadArgs(inc.ScheduleID())
+	require.Less(t, incArgs.LiveBytesAtLastBackup+updatedArgs.IncrementalChangeThreshold,
+		updatedArgs.LiveBytesAtLastBackup)
+}
+
+func TestScheduledBackupRunHistory(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	th, cleanup := newTestHelper(t)
+	defer cleanup()
+
+	th.sqlDB.Exec(t, `
+CREATE DATABASE db;
+USE db;
+CREATE TABLE t(a int PRIMARY KEY, b string);
+INSERT INTO t VALUES (1, 'a');
+`)
+
+	th.cfg.TestingKnobs.(*jobs.TestingKnobs).OverrideAsOfClause = func(clause *tree.AsOfClause, _ time.Time) {
+		expr, err := tree.MakeDTimestampTZ(th.cfg.DB.Clock().PhysicalTime(), time.Microsecond)
+		require.NoError(t, err)
+		clause.Expr = expr
+	}
+
+	schedules, err := th.createBackupSchedule(t, `CREATE SCHEDULE FOR BACKUP db.t INTO $1
+RECURRING '@hourly' FULL BACKUP ALWAYS`, "nodelocal://0/backup/run-history")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(schedules))
+	scheduleID := schedules[0].ScheduleID()
+
+	runSchedule := func() {
+		th.env.SetTime(th.loadSchedule(t, scheduleID).NextRun().Add(time.Second))
+		require.NoError(t, th.executeSchedules())
+	}
+	const runsQuery = `
+SELECT job_id IS NOT NULL, status, subdir IS NOT NULL, data_size > 0, row_count, error IS NOT NULL
+FROM system.scheduled_backup_runs WHERE schedule_id = $1 ORDER BY finished`
+
+	// A backup records its job, where it backed up to and what it backed up.
+	runSchedule()
+	th.waitForSuccessfulScheduledJob(t, scheduleID)
+	th.sqlDB.CheckQueryResults(t, runsQuery, [][]string{
+		{"true", "succeeded", "true", "true", "1", "false"},
+	}, scheduleID)
+
+	// A run that fails to start its backup job records the error.
+	th.sqlDB.Exec(t, `DROP TABLE db.t`)
+	runSchedule()
+	th.sqlDB.CheckQueryResults(t, runsQuery, [][]string{
+		{"true", "succeeded", "true", "true", "1", "false"},
+		{"false", "failed", "false", "NULL", "NULL", "true"},
+	}, scheduleID)
+
+	// Recording a run deletes the runs past their retention.
+	scheduledBackupRunRetention.Override(context.Background(), &th.server.ClusterSettings().SV, time.Millisecond)
+	runSchedule()
+	th.sqlDB.CheckQueryResults(t, runsQuery, [][]string{
+		{"false", "failed", "false", "NULL", "NULL", "true"},
+	}, scheduleID)
+}
+
 func TestCreateBackupScheduleRequiresAdminRole(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
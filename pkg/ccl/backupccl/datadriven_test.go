@@ -77,7 +77,10 @@ var localityCfgs = map[string]roachpb.Locality{
 }
 
 var clusterVersionKeys = map[string]clusterversion.Key{
-	"Start22_2": clusterversion.Start22_2,
+	"Start22_2":                clusterversion.Start22_2,
+	"V23_1BackupCompression":   clusterversion.V23_1BackupCompression,
+	"V23_1BackupDecryptQuorum": clusterversion.V23_1BackupDecryptQuorum,
+	"V23_1BackupKMSByLocality": clusterversion.V23_1BackupKMSByLocality,
 }
 
 type sqlDBKey struct {
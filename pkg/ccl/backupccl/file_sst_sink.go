@@ -11,20 +11,26 @@ package backupccl
 import (
 	"context"
 	"fmt"
+	"hash/crc32"
 	io "io"
 	"sort"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/admission"
 	hlc "github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
@@ -38,12 +44,61 @@ type sstSinkConf struct {
 	enc      *roachpb.FileEncryptionOptions
 	id       base.SQLInstanceID
 	settings *settings.Values
+	// perTableFiles is set if the data of each table must be written to
+	// separate files, named with a per-table prefix. codec is used to determine
+	// the table of the data.
+	perTableFiles bool
+	codec         keys.SQLCodec
+	// targetFileSize, if non-zero, overrides bulkio.backup.file_size as the
+	// size past which files are flushed.
+	targetFileSize int64
+	// jobID and destURI are the backup job the sink writes files for and the
+	// destination it writes them to, with which the spans of the writes are
+	// tagged.
+	jobID   jobspb.JobID
+	destURI string
+	// writeLimiters, if set, hold the limiter of the writes to the destination
+	// of localityKV, which is refreshed with the current rate limit of that
+	// locality whenever a file is opened.
+	writeLimiters *cloud.LocalityWriteLimiters
+	localityKV    string
+	// pacer, if set, paces the writes of the sink with elastic CPU admission
+	// control. It is owned by the sink, which closes it.
+	pacer *admission.Pacer
+	// dataCodec is the codec the blocks of the files are compressed with, or
+	// empty for the default codec of the storage engine.
+	dataCodec string
 }
 
+// sstSinkPacerUnit is the on-CPU time the sink is admitted for at a time when
+// its writes are paced.
+const sstSinkPacerUnit = 100 * time.Millisecond
+
 type fileSSTSink struct {
 	dest cloud.ExternalStorage
 	conf sstSinkConf
 
+	// failover, if set, is the destination that the sink switches to for the
+	// rest of the backup once writing a file to dest fails. The files written
+	// to it are tagged with failoverLocalityKV, which is empty if failover is
+	// the default destination.
+	failover           cloud.ExternalStorage
+	failoverLocalityKV string
+	failedOver         bool
+	// missingLocalityKV, if set, is the locality of dest, which is reported to
+	// the coordinator as missing once the sink fails over, as its remaining
+	// files are then written to the default destination.
+	missingLocalityKV string
+	reportedMissing   bool
+	// outSpans are the spans written to the open file, which are retained
+	// until it is flushed while the sink can still fail over, so that they can
+	// be written to the failover destination instead. outSpansSize is the
+	// memory reserved for them, and outSpansDropped is set if that memory could
+	// not be reserved, in which case the open file cannot fail over.
+	outSpans        []exportedSpan
+	outSpansSize    int64
+	outSpansDropped bool
+
 	queue []exportedSpan
 	// queueCap is the maximum byte size that the queue can grow to.
 	queueCap int64
@@ -55,11 +110,25 @@ type fileSSTSink struct {
 	cancel  func()
 	out     io.WriteCloser
 	outName string
+	// outCounter counts and checksums the bytes of the open file as they are
+	// written to the destination, after any encryption, and outSpan is the span
+	// covering the keys written to it. They make up the trailer of the file.
+	outCounter *countingWriteCloser
+	outSpan    roachpb.Span
+	// outTableID is the table whose data the open file holds if the sink writes
+	// per-table files, or 0 if it holds data outside of the table key space.
+	outTableID descpb.ID
 
 	flushedFiles    []backuppb.BackupManifest_File
 	flushedSize     int64
 	flushedRevStart hlc.Timestamp
-	completedSpans  int32
+	// flushedPhysicalSize is the number of bytes written to the files flushed
+	// since the last progress update.
+	flushedPhysicalSize int64
+	completedSpans      int32
+	// emptySpans are the spans pushed since the last progress update that had
+	// no data to export.
+	emptySpans []backuppb.BackupManifest_File
 
 	stats struct {
 		files       int
@@ -118,8 +187,10 @@ func (s *fileSSTSink) Close() error {
 	if s.cancel != nil {
 		s.cancel()
 	}
+	s.conf.pacer.Close()
 
 	// Release the memory reserved for the file buffer.
+	s.releaseOutSpans(s.ctx)
 	s.memAcc.ba.Shrink(s.ctx, s.memAcc.reservedBytes)
 	s.memAcc.reservedBytes = 0
 	if s.out != nil {
@@ -178,24 +249,139 @@ func (s *fileSSTSink) flush(ctx context.Context) error {
 
 func (s *fileSSTSink) flushFile(ctx context.Context) error {
 	if s.out == nil {
-		return nil
+		// Even without a file to flush, empty spans still need to be reported.
+		if len(s.emptySpans) == 0 {
+			return nil
+		}
+		return s.sendProgress(ctx)
 	}
 	s.stats.flushes++
 
+	// Most of the file has usually been uploaded by the time it is flushed, but
+	// the upload only completes once it is closed.
+	ctx, sp := startJobPhaseSpan(ctx, backupPhaseWriteSST, s.conf.jobID, s.conf.destURI)
+	defer sp.Finish()
 	if err := s.sst.Finish(); err != nil {
-		return err
+		return s.failOverAndFlush(ctx, err)
 	}
 	if err := s.out.Close(); err != nil {
 		log.Warningf(ctx, "failed to close write in fileSSTSink: % #v", pretty.Formatter(err))
-		return errors.Wrap(err, "writing SST")
+		s.out = nil
+		return s.failOverAndFlush(ctx, errors.Wrap(err, "writing SST"))
+	}
+	trailer := backuppb.BackupManifest_FileTrailer{
+		Size:   s.outCounter.n,
+		CRC32C: s.outCounter.crc,
+		Span:   s.outSpan,
 	}
+	for i := range s.flushedFiles {
+		s.flushedFiles[i].Trailer = trailer
+	}
+	s.flushedPhysicalSize += s.outCounter.n
 	s.outName = ""
+	s.outTableID = 0
+	s.out = nil
+	s.outCounter = nil
+	s.outSpan = roachpb.Span{}
+	s.releaseOutSpans(ctx)
+
+	return s.sendProgress(ctx)
+}
+
+// failOverAndFlush fails over after flushing the open file failed with err,
+// and flushes the file rewritten to the failover destination instead.
+func (s *fileSSTSink) failOverAndFlush(ctx context.Context, err error) error {
+	if err := s.failOver(ctx, err); err != nil {
+		return err
+	}
+	return s.flushFile(ctx)
+}
+
+// failOver switches the sink to its failover destination after writing the
+// open file to dest failed with err, and rewrites the spans of the open file
+// to a new file there. It returns err if the sink cannot fail over.
+func (s *fileSSTSink) failOver(ctx context.Context, err error) error {
+	if s.failover == nil || s.failedOver || s.outSpansDropped {
+		return err
+	}
+	if s.missingLocalityKV != "" {
+		log.Warningf(ctx, "failing over to the default backup destination after writing backup file %s to the destination of locality %s failed: %v",
+			s.outName, s.missingLocalityKV, err)
+	} else {
+		log.Warningf(ctx, "failing over to the secondary backup destination after writing backup file %s failed: %v",
+			s.outName, err)
+	}
+	s.failedOver = true
+	s.dest = s.failover
+
+	// Abandon the open file, and forget everything that was recorded about the
+	// spans written to it since they are all written again.
+	if s.out != nil {
+		s.cancel()
+		_ = s.out.Close()
+		s.ctx, s.cancel = context.WithCancel(ctx)
+	}
 	s.out = nil
+	s.outCounter = nil
+	s.outSpan = roachpb.Span{}
+	s.outName = ""
+	s.outTableID = 0
+	s.flushedFiles = nil
+	s.flushedSize = 0
+	spans := s.outSpans
+	for _, resp := range spans {
+		s.completedSpans -= resp.completedSpans
+	}
+	s.outSpans = nil
+	defer s.memAcc.ba.Shrink(ctx, s.outSpansSize)
+	s.outSpansSize = 0
 
+	for _, resp := range spans {
+		if err := s.write(ctx, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retainOutSpan retains a span written to the open file while the sink can
+// still fail over.
+func (s *fileSSTSink) retainOutSpan(ctx context.Context, resp exportedSpan) {
+	if s.failover == nil || s.failedOver || s.outSpansDropped {
+		return
+	}
+	if err := s.memAcc.ba.Grow(ctx, int64(len(resp.dataSST))); err != nil {
+		log.Warningf(ctx, "backup file %s cannot fail over, failed to reserve memory to retain its spans: %v",
+			s.outName, err)
+		s.releaseOutSpans(ctx)
+		s.outSpansDropped = true
+		return
+	}
+	s.outSpans = append(s.outSpans, resp)
+	s.outSpansSize += int64(len(resp.dataSST))
+}
+
+// releaseOutSpans releases the spans retained for the open file once it no
+// longer needs them.
+func (s *fileSSTSink) releaseOutSpans(ctx context.Context) {
+	s.memAcc.ba.Shrink(ctx, s.outSpansSize)
+	s.outSpans = nil
+	s.outSpansSize = 0
+	s.outSpansDropped = false
+}
+
+// sendProgress reports the files flushed and the spans completed since the
+// last progress update to the backup coordinator.
+func (s *fileSSTSink) sendProgress(ctx context.Context) error {
 	progDetails := backuppb.BackupManifest_Progress{
 		RevStartTime:   s.flushedRevStart,
 		Files:          s.flushedFiles,
 		CompletedSpans: s.completedSpans,
+		EmptySpans:     s.emptySpans,
+		PhysicalSize:   s.flushedPhysicalSize,
+	}
+	if s.failedOver && s.missingLocalityKV != "" && !s.reportedMissing {
+		progDetails.MissingLocalityKVs = []string{s.missingLocalityKV}
 	}
 	var prog execinfrapb.RemoteProducerMetadata_BulkProcessorProgress
 	details, err := gogotypes.MarshalAny(&progDetails)
@@ -208,17 +394,30 @@ func (s *fileSSTSink) flushFile(ctx context.Context) error {
 		return ctx.Err()
 	case s.conf.progCh <- prog:
 	}
+	if len(progDetails.MissingLocalityKVs) > 0 {
+		s.reportedMissing = true
+	}
 
 	s.flushedFiles = nil
 	s.flushedSize = 0
 	s.flushedRevStart.Reset()
+	s.flushedPhysicalSize = 0
 	s.completedSpans = 0
+	s.emptySpans = nil
 
 	return nil
 }
 
-func (s *fileSSTSink) open(ctx context.Context) error {
-	s.outName = generateUniqueSSTName(s.conf.id)
+func (s *fileSSTSink) open(ctx context.Context, tableID descpb.ID) error {
+	if tableID != 0 {
+		s.outName = generateUniqueTableSSTName(s.conf.id, tableID)
+	} else {
+		s.outName = generateUniqueSSTName(s.conf.id)
+	}
+	s.outTableID = tableID
+	if s.conf.writeLimiters != nil {
+		localityWriteLimiter(s.conf.settings, s.conf.writeLimiters, s.conf.localityKV)
+	}
 	if s.ctx == nil {
 		s.ctx, s.cancel = context.WithCancel(ctx)
 	}
@@ -226,6 +425,8 @@ func (s *fileSSTSink) open(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	s.outCounter = &countingWriteCloser{WriteCloser: w}
+	w = s.outCounter
 	if s.conf.enc != nil {
 		var err error
 		w, err = storageccl.EncryptingWriter(w, s.conf.enc.Key)
@@ -234,12 +435,24 @@ func (s *fileSSTSink) open(ctx context.Context) error {
 		}
 	}
 	s.out = w
-	s.sst = storage.MakeBackupSSTWriter(ctx, s.dest.Settings(), s.out)
+	s.sst = storage.MakeBackupSSTWriterWithCompression(ctx, s.dest.Settings(), s.out,
+		backupinfo.DataSSTCompression(s.conf.dataCodec))
 
 	return nil
 }
 
 func (s *fileSSTSink) write(ctx context.Context, resp exportedSpan) error {
+	// Spans with no data to export have nothing to write.
+	if len(resp.dataSST) == 0 {
+		s.emptySpans = append(s.emptySpans, resp.metadata)
+		s.completedSpans += resp.completedSpans
+		return nil
+	}
+
+	if err := s.conf.pacer.Pace(ctx); err != nil {
+		return err
+	}
+
 	s.stats.files++
 
 	span := resp.metadata.Span
@@ -259,10 +472,28 @@ func (s *fileSSTSink) write(ctx context.Context, resp exportedSpan) error {
 		}
 	}
 
+	// If the sink writes per-table files and this span belongs to a different
+	// table than the data in the open file, we need to flush to start a new one.
+	var tableID descpb.ID
+	if s.conf.perTableFiles {
+		tableID = tableIDForKey(s.conf.codec, span.Key)
+		if s.out != nil && tableID != s.outTableID {
+			log.VEventf(ctx, 1, "flushing backup file %s of size %d because span %s belongs to another table",
+				s.outName, s.flushedSize, span,
+			)
+			if err := s.flushFile(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Initialize the writer if needed.
 	if s.out == nil {
-		if err := s.open(ctx); err != nil {
-			return err
+		if err := s.open(ctx, tableID); err != nil {
+			if err := s.failOver(ctx, err); err != nil {
+				return err
+			}
+			return s.write(ctx, resp)
 		}
 	}
 
@@ -274,11 +505,22 @@ func (s *fileSSTSink) write(ctx context.Context, resp exportedSpan) error {
 	// TODO(msbutler): investigate using single a single iterator that surfaces
 	// all point keys first and then all range keys
 	if err := s.copyPointKeys(resp.dataSST); err != nil {
-		return err
+		if err := s.failOver(ctx, err); err != nil {
+			return err
+		}
+		return s.write(ctx, resp)
 	}
 	if err := s.copyRangeKeys(resp.dataSST); err != nil {
-		return err
+		if err := s.failOver(ctx, err); err != nil {
+			return err
+		}
+		return s.write(ctx, resp)
 	}
+	s.retainOutSpan(ctx, resp)
+	if s.outSpan.Key == nil {
+		s.outSpan.Key = span.Key
+	}
+	s.outSpan.EndKey = span.EndKey
 
 	// If this span extended the last span added -- that is, picked up where it
 	// ended and has the same time-bounds -- then we can simply extend that span
@@ -292,6 +534,9 @@ func (s *fileSSTSink) write(ctx context.Context, resp exportedSpan) error {
 	} else {
 		f := resp.metadata
 		f.Path = s.outName
+		if s.failedOver {
+			f.LocalityKV = s.failoverLocalityKV
+		}
 		s.flushedFiles = append(s.flushedFiles, f)
 	}
 	s.flushedRevStart.Forward(resp.revStart)
@@ -300,7 +545,11 @@ func (s *fileSSTSink) write(ctx context.Context, resp exportedSpan) error {
 
 	// If our accumulated SST is now big enough, and we are positioned at the end
 	// of a range flush it.
-	if s.flushedSize > targetFileSize.Get(s.conf.settings) && resp.atKeyBoundary {
+	fileSize := s.conf.targetFileSize
+	if fileSize == 0 {
+		fileSize = targetFileSize.Get(s.conf.settings)
+	}
+	if s.flushedSize > fileSize && resp.atKeyBoundary {
 		s.stats.sizeFlushes++
 		log.VEventf(ctx, 2, "flushing backup file %s with size %d", s.outName, s.flushedSize)
 		if err := s.flushFile(ctx); err != nil {
@@ -373,9 +622,45 @@ func (s *fileSSTSink) copyRangeKeys(dataSST []byte) error {
 	return nil
 }
 
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// countingWriteCloser counts the bytes written to the wrapped WriteCloser and
+// computes their CRC32C checksum.
+type countingWriteCloser struct {
+	io.WriteCloser
+	n   int64
+	crc uint32
+}
+
+func (w *countingWriteCloser) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.n += int64(n)
+	w.crc = crc32.Update(w.crc, crc32cTable, p[:n])
+	return n, err
+}
+
 func generateUniqueSSTName(nodeID base.SQLInstanceID) string {
 	// The data/ prefix, including a /, is intended to group SSTs in most of the
 	// common file/bucket browse UIs.
 	return fmt.Sprintf("data/%d.sst",
 		builtins.GenerateUniqueInt(builtins.ProcessUniqueID(nodeID)))
 }
+
+// generateUniqueTableSSTName is like generateUniqueSSTName, for an SST holding
+// only the data of the table with the given ID. Grouping the SSTs of each table
+// under their own prefix allows the data of a single table to be found without
+// going through the SSTs of the rest of the backup.
+func generateUniqueTableSSTName(nodeID base.SQLInstanceID, tableID descpb.ID) string {
+	return fmt.Sprintf("data/%d/%d.sst", tableID,
+		builtins.GenerateUniqueInt(builtins.ProcessUniqueID(nodeID)))
+}
+
+// tableIDForKey returns the ID of the table key belongs to, or 0 if it is
+// outside of the table key space of codec.
+func tableIDForKey(codec keys.SQLCodec, key roachpb.Key) descpb.ID {
+	_, tableID, err := codec.DecodeTablePrefix(key)
+	if err != nil {
+		return 0
+	}
+	return descpb.ID(tableID)
+}
@@ -104,6 +104,7 @@ func MakeKeyRewriterFromRekeys(
 	restoreTenantFromStream bool,
 ) (*KeyRewriter, error) {
 	descs := make(map[descpb.ID]catalog.TableDescriptor)
+	indexRewrites := make(map[descpb.ID]map[descpb.IndexID]descpb.IndexID)
 	for _, rekey := range tableRekeys {
 		// Ignore the coordinator's poison-pill, rekey, added in restore_job.go, as
 		// we will correctly handle tenant keys below.
@@ -119,9 +120,16 @@ func MakeKeyRewriterFromRekeys(
 			return nil, errors.New("expected a table descriptor")
 		}
 		descs[descpb.ID(rekey.OldID)] = tabledesc.NewBuilder(table).BuildImmutableTable()
+		if len(rekey.IndexRewrites) > 0 {
+			rewrites := make(map[descpb.IndexID]descpb.IndexID, len(rekey.IndexRewrites))
+			for oldIndexID, newIndexID := range rekey.IndexRewrites {
+				rewrites[descpb.IndexID(oldIndexID)] = descpb.IndexID(newIndexID)
+			}
+			indexRewrites[descpb.ID(rekey.OldID)] = rewrites
+		}
 	}
 
-	return makeKeyRewriter(codec, descs, tenantRekeys, restoreTenantFromStream)
+	return makeKeyRewriter(codec, descs, indexRewrites, tenantRekeys, restoreTenantFromStream)
 }
 
 var (
@@ -135,9 +143,12 @@ var (
 )
 
 // makeKeyRewriter makes a KeyRewriter from a map of descs keyed by original ID.
+// The data of the indexes in indexRewrites, keyed by original table ID, is
+// rewritten into the indexes of the descs they map to.
 func makeKeyRewriter(
 	codec keys.SQLCodec,
 	descs map[descpb.ID]catalog.TableDescriptor,
+	indexRewrites map[descpb.ID]map[descpb.IndexID]descpb.IndexID,
 	tenants []execinfrapb.TenantRekey,
 	restoreTenantFromStream bool,
 ) (*KeyRewriter, error) {
@@ -150,9 +161,23 @@ func makeKeyRewriter(
 		// The PrefixEnd() of index 1 is the same as the prefix of index 2, so use a
 		// map to avoid duplicating entries.
 
+		type indexRewrite struct{ oldID, newID descpb.IndexID }
+		var indexes []indexRewrite
+		rewrittenInto := make(map[descpb.IndexID]bool)
+		for oldIndexID, newIndexID := range indexRewrites[oldID] {
+			indexes = append(indexes, indexRewrite{oldIndexID, newIndexID})
+			rewrittenInto[newIndexID] = true
+		}
 		for _, index := range desc.NonDropIndexes() {
-			oldPrefix := roachpb.Key(MakeKeyRewriterPrefixIgnoringInterleaved(oldID, index.GetID()))
-			newPrefix := roachpb.Key(MakeKeyRewriterPrefixIgnoringInterleaved(desc.GetID(), index.GetID()))
+			// An index which the data of another is rewritten into has no data of
+			// its own in the backup.
+			if !rewrittenInto[index.GetID()] {
+				indexes = append(indexes, indexRewrite{index.GetID(), index.GetID()})
+			}
+		}
+		for _, index := range indexes {
+			oldPrefix := roachpb.Key(MakeKeyRewriterPrefixIgnoringInterleaved(oldID, index.oldID))
+			newPrefix := roachpb.Key(MakeKeyRewriterPrefixIgnoringInterleaved(desc.GetID(), index.newID))
 			if !seenPrefixes[string(oldPrefix)] {
 				seenPrefixes[string(oldPrefix)] = true
 				prefixes.rewrites = append(prefixes.rewrites, prefixRewrite{
@@ -148,6 +148,32 @@ func TestKeyRewriter(t *testing.T) {
 		}
 	})
 
+	t.Run("index rewrite", func(t *testing.T) {
+		desc.ID = oldID + 15
+		const newIndexID = 5
+		newKr, err := MakeKeyRewriterFromRekeys(keys.SystemSQLCodec, []execinfrapb.TableRekey{{
+			OldID:         uint32(oldID),
+			NewDesc:       mustMarshalDesc(t, desc.TableDesc()),
+			IndexRewrites: map[uint32]uint32{uint32(desc.GetPrimaryIndexID()): newIndexID},
+		}}, nil /* tenantRekeys */, false /* restoreTenantFromStream */)
+		require.NoError(t, err)
+
+		// The data of the index, and the end of its span, are rewritten into the
+		// index it maps to.
+		key := roachpb.Key(rowenc.MakeIndexKeyPrefix(keys.SystemSQLCodec,
+			systemschema.NamespaceTable.GetID(), desc.GetPrimaryIndexID()))
+		expected := keys.SystemSQLCodec.IndexPrefix(uint32(oldID+15), newIndexID)
+		for _, tc := range []struct{ key, expected roachpb.Key }{
+			{key, expected},
+			{key.PrefixEnd(), expected.PrefixEnd()},
+		} {
+			newKey, ok, err := newKr.RewriteKey(tc.key, 0)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, tc.expected, roachpb.Key(newKey))
+		}
+	})
+
 	t.Run("importing", func(t *testing.T) {
 
 		// Create a new key rewriter with a table descriptor undergoing an in-progress import.
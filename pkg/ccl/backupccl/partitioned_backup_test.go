@@ -9,12 +9,17 @@
 package backupccl
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetURIsByLocalityKV(t *testing.T) {
@@ -124,3 +129,123 @@ func TestGetURIsByLocalityKV(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLocalityRateLimits(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	for _, tc := range []struct {
+		name   string
+		input  string
+		limits map[string]int64
+		error  string
+	}{
+		{name: "empty", input: "", limits: map[string]int64{}},
+		{
+			name:   "multiple",
+			input:  "region=eu-west=100MiB/s, dc=dc1=1GiB,default=512KiB",
+			limits: map[string]int64{"region=eu-west": 100 << 20, "dc=dc1": 1 << 30, "default": 512 << 10},
+		},
+		{name: "noLimit", input: "region=eu-west", error: `invalid locality "region"`},
+		{name: "invalidLimit", input: "region=eu-west=fast", error: `invalid rate limit for locality "region=eu-west"`},
+		{name: "noTier", input: "100MiB", error: `invalid locality rate limit "100MiB"`},
+		{name: "invalidTier", input: "eu-west=100MiB", error: `invalid locality "eu-west"`},
+		{name: "zero", input: "default=0", error: `rate limit for locality "default" must be positive`},
+		{
+			name:  "duplicate",
+			input: "region=eu-west=1MiB,region=eu-west=2MiB",
+			error: `locality "region=eu-west" specified multiple times`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			limits, err := parseLocalityRateLimits(tc.input)
+			if tc.error != "" {
+				if !testutils.IsError(err, tc.error) {
+					t.Fatalf("expected error matching %q, got %v", tc.error, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.limits, limits)
+		})
+	}
+}
+
+// TestBackupFailover verifies that the data files of a BACKUP INTO FAILOVER
+// that cannot be written to the primary collection are written to the
+// secondary collection instead, and restored from there.
+func TestBackupFailover(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 1000
+	_, sqlDB, dir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	const primary, secondary = "nodelocal://1/primary", "nodelocal://1/secondary"
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.deprecated_full_backup_with_subdir.enabled = true`)
+
+	// A file in place of the data directory of the primary makes every write of
+	// a data file to it fail, while its metadata can still be written.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "primary", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "primary", "sub", "data"), nil, 0644))
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'sub' IN FAILOVER ($1, $2)`, primary, secondary)
+
+	var primaryFiles, failoverFiles int
+	sqlDB.QueryRow(t, `SELECT count(*) FILTER (WHERE locality = 'default'),
+count(*) FILTER (WHERE locality = $3)
+FROM [SHOW BACKUP FILES FROM 'sub' IN ($1, $2)]`,
+		primary, secondary, backupdest.FailoverLocalityValue).Scan(&primaryFiles, &failoverFiles)
+	require.Equal(t, 0, primaryFiles)
+	require.Greater(t, failoverFiles, 0)
+
+	sqlDB.Exec(t, `CREATE DATABASE data2`)
+	sqlDB.Exec(t, `RESTORE data.bank FROM 'sub' IN ($1, $2) WITH into_db = 'data2'`, primary, secondary)
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM data2.bank`, [][]string{{"1000"}})
+
+	sqlDB.ExpectErr(t, "incremental_location option not supported with FAILOVER destinations",
+		`BACKUP DATABASE data INTO LATEST IN FAILOVER ($1, $2) WITH incremental_location = $3`,
+		primary, secondary, "nodelocal://1/inc")
+}
+
+func TestBackupAllowMissingLocalities(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 1000
+	args := base.TestClusterArgs{ServerArgs: base.TestServerArgs{
+		Locality: roachpb.Locality{Tiers: []roachpb.Tier{{Key: "region", Value: "east"}}},
+	}}
+	_, sqlDB, dir, cleanupFn := backupRestoreTestSetupWithParams(t, singleNode, numAccounts, InitManualReplication, args)
+	defer cleanupFn()
+
+	const def, east = "nodelocal://1/default?COCKROACH_LOCALITY=default",
+		"nodelocal://1/east?COCKROACH_LOCALITY=region%3Deast"
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.deprecated_full_backup_with_subdir.enabled = true`)
+
+	sqlDB.ExpectErr(t, "allow_missing_localities can only be used with locality-aware destinations",
+		`BACKUP DATABASE data INTO 'nodelocal://1/default' WITH allow_missing_localities`)
+
+	// Without the option, a destination of a locality that can't be written to
+	// fails the backup.
+	for _, subdir := range []string{"failed", "sub"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "east", subdir), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "east", subdir, "data"), nil, 0644))
+	}
+	sqlDB.ExpectErr(t, "", `BACKUP DATABASE data INTO 'failed' IN ($1, $2)`, def, east)
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'sub' IN ($1, $2) WITH allow_missing_localities`, def, east)
+
+	var defaultFiles, eastFiles int
+	sqlDB.QueryRow(t, `SELECT count(*) FILTER (WHERE locality = 'default'),
+count(*) FILTER (WHERE locality = 'region=east')
+FROM [SHOW BACKUP FILES FROM 'sub' IN ($1, $2)]`, def, east).Scan(&defaultFiles, &eastFiles)
+	require.Greater(t, defaultFiles, 0)
+	require.Equal(t, 0, eastFiles)
+
+	sqlDB.Exec(t, `CREATE DATABASE data2`)
+	sqlDB.Exec(t, `RESTORE data.bank FROM 'sub' IN ($1, $2) WITH into_db = 'data2'`, def, east)
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM data2.bank`, [][]string{{"1000"}})
+}
@@ -0,0 +1,256 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+var restoreArchiveRetrievalEnabled = settings.RegisterBoolSetting(
+	settings.TenantWritable,
+	"bulkio.restore.archive_retrieval.enabled",
+	"if set, a restore checks whether the backup files it reads are in an archive storage tier, "+
+		"such as S3 Glacier Deep Archive or the Azure Archive tier, and waits for them to be "+
+		"retrieved before it ingests any data",
+	false,
+).WithPublic()
+
+var restoreArchiveRetrievalPollInterval = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.restore.archive_retrieval.poll_interval",
+	"how often a restore that waits for backup files to be retrieved from an archive storage "+
+		"tier checks whether they are readable",
+	5*time.Minute,
+	settings.PositiveDuration,
+)
+
+// archiveRetrievalConcurrency is the number of files whose retrieval state is
+// checked concurrently. Each check is a request to the storage provider.
+const archiveRetrievalConcurrency = 32
+
+// archivedFile is a backup file in a store that supports archive tiers.
+type archivedFile struct {
+	store cloud.ArchiveStorage
+	path  string
+}
+
+// waitForArchivedFiles requests the retrieval of the files read by importSpans
+// that are in an archive storage tier, and waits until they are all readable.
+// While it waits, the running status of the job reports how many files are
+// still being retrieved and when the retrieval is expected to complete.
+func waitForArchivedFiles(
+	ctx context.Context,
+	execCtx sql.JobExecContext,
+	job *jobs.Job,
+	importSpans []execinfrapb.RestoreSpanEntry,
+) error {
+	sv := &execCtx.ExecCfg().Settings.SV
+	if !restoreArchiveRetrievalEnabled.Get(sv) {
+		return nil
+	}
+
+	files, closeStores, err := archiveStorageFiles(ctx, execCtx, importSpans)
+	if err != nil {
+		return err
+	}
+	defer closeStores()
+	if len(files) == 0 {
+		return nil
+	}
+
+	pending, eta, err := requestArchivedFileRetrievals(ctx, files, timeutil.Now())
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	log.Infof(ctx, "restore is waiting for %d archived files to be retrieved, expected by %s",
+		len(pending), eta)
+
+	var timer timeutil.Timer
+	defer timer.Stop()
+	for len(pending) > 0 {
+		status := jobs.RunningStatus(fmt.Sprintf("awaiting rehydration of %d archived files, expected by %s",
+			len(pending), eta.Format(time.RFC3339)))
+		if err := job.RunningStatus(ctx, nil /* txn */, func(_ context.Context, _ jobspb.Details) (jobs.RunningStatus, error) {
+			return status, nil
+		}); err != nil {
+			return errors.Wrapf(err, "failed to update running status of job %d", job.ID())
+		}
+
+		timer.Reset(restoreArchiveRetrievalPollInterval.Get(sv))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			timer.Read = true
+		}
+		if pending, err = pendingArchivedFiles(ctx, pending); err != nil {
+			return err
+		}
+	}
+	log.Infof(ctx, "all archived files of the restore were retrieved")
+	return job.RunningStatus(ctx, nil /* txn */, func(_ context.Context, _ jobspb.Details) (jobs.RunningStatus, error) {
+		return "", nil
+	})
+}
+
+// archiveStorageFiles returns the files read by importSpans whose store
+// supports archive tiers, along with a function that closes those stores.
+func archiveStorageFiles(
+	ctx context.Context, execCtx sql.JobExecContext, importSpans []execinfrapb.RestoreSpanEntry,
+) ([]archivedFile, func(), error) {
+	var stores []cloud.ExternalStorage
+	closeStores := func() {
+		for _, s := range stores {
+			if err := s.Close(); err != nil {
+				log.Warningf(ctx, "failed to close store: %v", err)
+			}
+		}
+	}
+
+	// Stores are keyed by their configuration, since the files of a backup
+	// share a handful of them.
+	archiveStores := make(map[string]cloud.ArchiveStorage)
+	seen := make(map[string]map[string]struct{})
+	var files []archivedFile
+	for i := range importSpans {
+		for _, f := range importSpans[i].Files {
+			key := f.Dir.String()
+			paths, ok := seen[key]
+			if !ok {
+				store, err := execCtx.ExecCfg().DistSQLSrv.ExternalStorage(ctx, f.Dir)
+				if err != nil {
+					closeStores()
+					return nil, nil, err
+				}
+				stores = append(stores, store)
+				if a, ok := cloud.AsArchiveStorage(store); ok {
+					archiveStores[key] = a
+				}
+				paths = make(map[string]struct{})
+				seen[key] = paths
+			}
+			a, ok := archiveStores[key]
+			if !ok {
+				continue
+			}
+			if _, ok := paths[f.Path]; ok {
+				continue
+			}
+			paths[f.Path] = struct{}{}
+			files = append(files, archivedFile{store: a, path: f.Path})
+		}
+	}
+	return files, closeStores, nil
+}
+
+// requestArchivedFileRetrievals requests the retrieval of the archived files
+// among files, and returns the files that are not readable yet along with the
+// time by which they are expected to be.
+func requestArchivedFileRetrievals(
+	ctx context.Context, files []archivedFile, now time.Time,
+) ([]archivedFile, time.Time, error) {
+	states := make([]cloud.RetrievalState, len(files))
+	estimates := make([]time.Duration, len(files))
+	if err := forEachArchivedFile(ctx, files, func(ctx context.Context, i int) error {
+		state, err := files[i].store.RetrievalState(ctx, files[i].path)
+		if err != nil {
+			return errors.Wrapf(err, "checking retrieval state of %s", files[i].path)
+		}
+		states[i] = state
+		if state == cloud.RetrievalReadable {
+			return nil
+		}
+		estimates[i], err = files[i].store.RequestRetrieval(ctx, files[i].path)
+		return errors.Wrapf(err, "requesting retrieval of %s", files[i].path)
+	}); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var pending []archivedFile
+	var longest time.Duration
+	for i := range files {
+		if states[i] == cloud.RetrievalReadable {
+			continue
+		}
+		pending = append(pending, files[i])
+		if estimates[i] > longest {
+			longest = estimates[i]
+		}
+	}
+	return pending, now.Add(longest), nil
+}
+
+// pendingArchivedFiles returns the files that are not readable yet.
+func pendingArchivedFiles(ctx context.Context, files []archivedFile) ([]archivedFile, error) {
+	readable := make([]bool, len(files))
+	if err := forEachArchivedFile(ctx, files, func(ctx context.Context, i int) error {
+		state, err := files[i].store.RetrievalState(ctx, files[i].path)
+		if err != nil {
+			return errors.Wrapf(err, "checking retrieval state of %s", files[i].path)
+		}
+		readable[i] = state == cloud.RetrievalReadable
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	pending := files[:0]
+	for i := range files {
+		if !readable[i] {
+			pending = append(pending, files[i])
+		}
+	}
+	return pending, nil
+}
+
+// forEachArchivedFile calls fn with the index of each file, from
+// archiveRetrievalConcurrency goroutines.
+func forEachArchivedFile(
+	ctx context.Context, files []archivedFile, fn func(ctx context.Context, i int) error,
+) error {
+	idxCh := make(chan int)
+	g := ctxgroup.WithContext(ctx)
+	g.GoCtx(func(ctx context.Context) error {
+		defer close(idxCh)
+		for i := range files {
+			select {
+			case idxCh <- i:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	for w := 0; w < archiveRetrievalConcurrency; w++ {
+		g.GoCtx(func(ctx context.Context) error {
+			for i := range idxCh {
+				if err := fn(ctx, i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
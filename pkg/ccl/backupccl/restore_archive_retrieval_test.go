@@ -0,0 +1,105 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeArchiveStorage is a cloud.ArchiveStorage whose retrievals complete when
+// the test marks them done.
+type fakeArchiveStorage struct {
+	estimate time.Duration
+	mu       struct {
+		syncutil.Mutex
+		states   map[string]cloud.RetrievalState
+		requests int
+	}
+}
+
+func (f *fakeArchiveStorage) RetrievalState(
+	_ context.Context, basename string,
+) (cloud.RetrievalState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mu.states[basename], nil
+}
+
+func (f *fakeArchiveStorage) RequestRetrieval(
+	_ context.Context, basename string,
+) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.requests++
+	f.mu.states[basename] = cloud.RetrievalInProgress
+	return f.estimate, nil
+}
+
+func (f *fakeArchiveStorage) setState(basename string, state cloud.RetrievalState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.states[basename] = state
+}
+
+func TestArchivedFileRetrievals(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	glacier := &fakeArchiveStorage{estimate: 12 * time.Hour}
+	glacier.mu.states = map[string]cloud.RetrievalState{
+		"1.sst": cloud.RetrievalReadable,
+		"2.sst": cloud.RetrievalArchived,
+		"3.sst": cloud.RetrievalInProgress,
+	}
+	azure := &fakeArchiveStorage{estimate: 15 * time.Hour}
+	azure.mu.states = map[string]cloud.RetrievalState{
+		"4.sst": cloud.RetrievalArchived,
+	}
+	files := []archivedFile{
+		{store: glacier, path: "1.sst"},
+		{store: glacier, path: "2.sst"},
+		{store: glacier, path: "3.sst"},
+		{store: azure, path: "4.sst"},
+	}
+
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	pending, eta, err := requestArchivedFileRetrievals(ctx, files, now)
+	require.NoError(t, err)
+	require.Equal(t, files[1:], pending)
+	require.Equal(t, now.Add(15*time.Hour), eta)
+	// The file that was already being retrieved is requested again, which the
+	// providers accept, to learn when its retrieval is expected to complete.
+	require.Equal(t, 2, glacier.mu.requests)
+	require.Equal(t, 1, azure.mu.requests)
+
+	glacier.setState("2.sst", cloud.RetrievalReadable)
+	azure.setState("4.sst", cloud.RetrievalReadable)
+	pending, err = pendingArchivedFiles(ctx, pending)
+	require.NoError(t, err)
+	require.Equal(t, []archivedFile{{store: glacier, path: "3.sst"}}, pending)
+
+	glacier.setState("3.sst", cloud.RetrievalReadable)
+	pending, err = pendingArchivedFiles(ctx, pending)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	// Nothing is pending if every file is readable.
+	pending, _, err = requestArchivedFileRetrievals(ctx, files, now)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
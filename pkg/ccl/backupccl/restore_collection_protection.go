@@ -0,0 +1,98 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// protectCollectionLayers registers the protection of the layers of the
+// collection the restore reads, if it restores from a collection.
+func (r *restoreResumer) protectCollectionLayers(
+	ctx context.Context, p sql.JobExecContext, details jobspb.RestoreDetails,
+) error {
+	if details.CollectionURI == "" {
+		return nil
+	}
+	protection := backuppb.CollectionProtection{JobID: int64(r.job.ID())}
+	for _, uri := range details.URIs {
+		// Incremental backups in a custom incremental_location are not in the
+		// collection, and are left to whatever manages that location.
+		if layer, ok := backupdest.CollectionRelativePath(details.CollectionURI, uri); ok {
+			protection.Layers = append(protection.Layers, layer)
+		}
+	}
+	store, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, details.CollectionURI, p.User())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return backupdest.WriteCollectionProtection(ctx, store, protection)
+}
+
+// unprotectCollectionLayers removes the protection the restore registered, if
+// any. A protection that cannot be removed is left behind to be ignored once
+// the job is done, so that this does not fail the job.
+func (r *restoreResumer) unprotectCollectionLayers(
+	ctx context.Context, p sql.JobExecContext, details jobspb.RestoreDetails,
+) {
+	if details.CollectionURI == "" {
+		return
+	}
+	if err := func() error {
+		store, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, details.CollectionURI, p.User())
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		return backupdest.RemoveCollectionProtection(ctx, store, r.job.ID())
+	}(); err != nil {
+		log.Warningf(ctx, "failed to remove the protection of the layers of the collection read by restore job %d: %v",
+			r.job.ID(), err)
+	}
+}
+
+// protectedCollectionLayers returns the layers of the collection in store that
+// are protected by restore jobs that are still running, keyed by the subdirectory
+// of the layer relative to the collection and mapped to the ID of the job.
+func protectedCollectionLayers(
+	ctx context.Context, execCfg *sql.ExecutorConfig, store cloud.ExternalStorage,
+) (map[string]jobspb.JobID, error) {
+	protections, err := backupdest.ReadCollectionProtections(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	layers := make(map[string]jobspb.JobID)
+	for _, protection := range protections {
+		jobID := jobspb.JobID(protection.JobID)
+		job, err := execCfg.JobRegistry.LoadJob(ctx, jobID)
+		if err != nil {
+			if jobs.HasJobNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+		if job.Status().Terminal() {
+			continue
+		}
+		for _, layer := range protection.Layers {
+			layers[strings.Trim(layer, "/")] = jobID
+		}
+	}
+	return layers, nil
+}
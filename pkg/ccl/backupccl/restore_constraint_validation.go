@@ -0,0 +1,61 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+)
+
+// A restore run with defer_constraint_validation does not leave the restored
+// tables offline while it checks that their rows satisfy their CHECK and
+// foreign key constraints. Instead, it publishes the constraints in the
+// validating state, in which they are enforced on new writes but not relied
+// on by the optimizer and shown as not validated, along with an ADD mutation
+// for each of them. The mutations are turned into schema change jobs like
+// those of the mutations the tables were backed up with, so that the jobs
+// validate the restored rows against the constraints after the restore
+// completes, and mark them validated. A constraint the restored rows violate
+// fails its job, which drops it, as a failed ALTER TABLE ... ADD CONSTRAINT
+// would.
+//
+// The mutations are given a mutation ID each, so that each constraint has a
+// job of its own. Since the schema changer processes the mutations of a table
+// in order, the jobs validating the constraints of the same table run one
+// after the other.
+
+// deferConstraintValidation marks the validated CHECK and foreign key
+// constraints of table as validating, and adds the mutations that validate
+// them. The NOT NULL constraints of the table and the checks of its
+// hash-sharded columns, which belong to its columns rather than to its users,
+// are left validated, and the constraints which were not validated are left
+// as they are.
+func deferConstraintValidation(table *tabledesc.Mutable) {
+	addMutation := func(add func()) {
+		id := table.NextMutationID
+		add()
+		table.Mutations[len(table.Mutations)-1].MutationID = id
+		table.NextMutationID = id + 1
+	}
+	for _, ck := range table.Checks {
+		if ck.Validity != descpb.ConstraintValidity_Validated || ck.IsNonNullConstraint || ck.FromHashShardedColumn {
+			continue
+		}
+		ck.Validity = descpb.ConstraintValidity_Validating
+		addMutation(func() { table.AddCheckMutation(ck, descpb.DescriptorMutation_ADD) })
+	}
+	for i := range table.OutboundFKs {
+		fk := &table.OutboundFKs[i]
+		if fk.Validity != descpb.ConstraintValidity_Validated {
+			continue
+		}
+		fk.Validity = descpb.ConstraintValidity_Validating
+		addMutation(func() { table.AddForeignKeyMutation(fk, descpb.DescriptorMutation_ADD) })
+	}
+}
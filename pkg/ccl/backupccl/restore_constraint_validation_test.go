@@ -0,0 +1,90 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+func TestRestoreDeferConstraintValidation(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, 0, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE TABLE data.parent (id INT PRIMARY KEY)`)
+	sqlDB.Exec(t, `CREATE TABLE data.child (
+	id INT PRIMARY KEY,
+	parent_id INT REFERENCES data.parent (id),
+	v INT NOT NULL CONSTRAINT v_positive CHECK (v > 0)
+)`)
+	sqlDB.Exec(t, `INSERT INTO data.parent VALUES (1), (2)`)
+	sqlDB.Exec(t, `INSERT INTO data.child VALUES (1, 1, 10), (2, 2, 20)`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+
+	sqlDB.ExpectErr(t, "cannot be used with schema_only",
+		`RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = 'data2', schema_only, `+
+			`defer_constraint_validation`, localFoo)
+
+	// Pause the jobs validating the constraints before they run, so that the
+	// state the restore leaves the constraints in can be seen.
+	sqlDB.Exec(t, `SET CLUSTER SETTING jobs.debug.pausepoints = 'schemachanger.before.exec'`)
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = 'data2', `+
+		`defer_constraint_validation`, localFoo)
+
+	constraintsQuery := `SELECT constraint_name, validated FROM [SHOW CONSTRAINTS FROM data2.child]
+WHERE constraint_type != 'PRIMARY KEY' ORDER BY constraint_name`
+	sqlDB.CheckQueryResults(t, constraintsQuery, [][]string{
+		{"child_parent_id_fkey", "false"}, {"v_positive", "false"},
+	})
+	sqlDB.CheckQueryResults(t, `SELECT * FROM data2.child ORDER BY id`, [][]string{
+		{"1", "1", "10"}, {"2", "2", "20"},
+	})
+	// The constraints are enforced on writes while they are validated.
+	sqlDB.ExpectErr(t, "v_positive", `INSERT INTO data2.child VALUES (3, 1, -1)`)
+	sqlDB.ExpectErr(t, "child_parent_id_fkey", `INSERT INTO data2.child VALUES (3, 3, 30)`)
+
+	var jobIDs []jobspb.JobID
+	rows := sqlDB.Query(t, `SELECT job_id FROM crdb_internal.jobs
+WHERE job_type = 'SCHEMA CHANGE' AND description LIKE 'RESTORING: schema change on child adding constraint %'
+ORDER BY description`)
+	for rows.Next() {
+		var id jobspb.JobID
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		jobIDs = append(jobIDs, id)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobIDs) != 2 {
+		t.Fatalf("expected a job validating each of the 2 constraints, found %d", len(jobIDs))
+	}
+
+	for _, id := range jobIDs {
+		jobutils.WaitForJobToPause(t, sqlDB, id)
+	}
+	sqlDB.Exec(t, `RESET CLUSTER SETTING jobs.debug.pausepoints`)
+	for _, id := range jobIDs {
+		sqlDB.Exec(t, `RESUME JOB $1`, id)
+	}
+	for _, id := range jobIDs {
+		jobutils.WaitForJobToSucceed(t, sqlDB, id)
+	}
+	sqlDB.CheckQueryResults(t, constraintsQuery, [][]string{
+		{"child_parent_id_fkey", "true"}, {"v_positive", "true"},
+	})
+}
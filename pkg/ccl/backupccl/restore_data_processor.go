@@ -17,6 +17,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalioaudit"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv/bulk"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -123,6 +125,16 @@ var numRestoreWorkers = settings.RegisterIntSetting(
 	settings.PositiveInt,
 )
 
+// restoreAdaptivePacing controls whether the stores ingesting the data of a
+// restore pace it according to their L0 sublevel count and compaction debt.
+var restoreAdaptivePacing = settings.RegisterBoolSetting(
+	settings.TenantWritable,
+	"bulkio.restore.adaptive_pacing.enabled",
+	"if set, stores slow down the ingestion of restored data while their L0 sublevel count or "+
+		"compaction debt are elevated (see kv.bulk_io_write.adaptive_pacing)",
+	true,
+)
+
 func newRestoreDataProcessor(
 	ctx context.Context,
 	flowCtx *execinfra.FlowCtx,
@@ -160,6 +172,7 @@ func newRestoreDataProcessor(
 // Start is part of the RowSource interface.
 func (rd *restoreDataProcessor) Start(ctx context.Context) {
 	ctx = logtags.AddTag(ctx, "job", rd.spec.JobID)
+	ctx = externalioaudit.WithPrincipal(ctx, rd.spec.User(), jobspb.JobID(rd.spec.JobID))
 	ctx = rd.StartInternal(ctx, restoreDataProcName)
 	rd.input.Start(ctx)
 
@@ -327,7 +340,13 @@ func (rd *restoreDataProcessor) openSSTs(
 			return err
 		}
 		dirs = append(dirs, dir)
-		storeFiles = append(storeFiles, storageccl.StoreFile{Store: dir, FilePath: file.Path})
+		storeFiles = append(storeFiles, storageccl.StoreFile{
+			Store:          dir,
+			FilePath:       file.Path,
+			ExpectedSize:   file.Size,
+			ExpectedCRC32C: file.CRC32C,
+			Encryption:     rd.spec.EncryptionByLocalityKV[file.LocalityKV],
+		})
 		// TODO(pbardea): When memory monitoring is added, send the currently
 		// accumulated iterators on the channel if we run into memory pressure.
 	}
@@ -351,6 +370,10 @@ func (rd *restoreDataProcessor) runRestoreWorkers(ctx context.Context, ssts chan
 		if err != nil {
 			return err
 		}
+		rp, err := makeRestoreProjection(rd.FlowCtx.Codec(), rd.spec.TableRekeys)
+		if err != nil {
+			return err
+		}
 
 		ctx, agg := bulkutil.MakeTracingAggregatorWithSpan(ctx,
 			fmt.Sprintf("%s-worker-%d-aggregator", restoreDataProcName, worker), rd.EvalCtx.Tracer)
@@ -364,7 +387,7 @@ func (rd *restoreDataProcessor) runRestoreWorkers(ctx context.Context, ssts chan
 					return done, nil
 				}
 
-				summary, err := rd.processRestoreSpanEntry(ctx, kr, sstIter)
+				summary, err := rd.processRestoreSpanEntry(ctx, kr, rp, sstIter)
 				if err != nil {
 					return done, err
 				}
@@ -388,8 +411,10 @@ func (rd *restoreDataProcessor) runRestoreWorkers(ctx context.Context, ssts chan
 	})
 }
 
+// processRestoreSpanEntry ingests the data of sst, rekeyed by kr and, if the
+// restore projects any of its tables, projected by rp.
 func (rd *restoreDataProcessor) processRestoreSpanEntry(
-	ctx context.Context, kr *KeyRewriter, sst mergedSST,
+	ctx context.Context, kr *KeyRewriter, rp *restoreProjection, sst mergedSST,
 ) (roachpb.BulkOpSummary, error) {
 	db := rd.flowCtx.Cfg.DB
 	evalCtx := rd.EvalCtx
@@ -431,6 +456,7 @@ func (rd *restoreDataProcessor) processRestoreSpanEntry(
 			disallowShadowingBelow,
 			writeAtBatchTS,
 			false, /* scatterSplitRanges */
+			restoreAdaptivePacing.Get(&evalCtx.Settings.SV),
 			rd.flowCtx.Cfg.BackupMonitor.MakeBoundAccount(),
 			rd.flowCtx.Cfg.BulkSenderLimiter,
 		)
@@ -481,6 +507,13 @@ func (rd *restoreDataProcessor) processRestoreSpanEntry(
 			}
 			continue
 		}
+		if rp != nil {
+			if ok, err = rp.project(key.Key, &value); err != nil {
+				return summary, err
+			} else if !ok {
+				continue
+			}
+		}
 
 		// Rewriting the key means the checksum needs to be updated.
 		value.ClearChecksum()
@@ -533,6 +566,7 @@ func (rd *restoreDataProcessor) Next() (rowenc.EncDatumRow, *execinfrapb.Produce
 			return nil, rd.DrainHelper()
 		}
 
+		progDetails.SQLInstanceID = rd.flowCtx.NodeID.SQLInstanceID()
 		details, err := gogotypes.MarshalAny(&progDetails)
 		if err != nil {
 			rd.MoveToDraining(err)
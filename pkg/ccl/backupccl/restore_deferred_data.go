@@ -0,0 +1,242 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+)
+
+// A RESTORE run with schema_only and deferred_data creates the restored
+// objects and publishes them empty, like any schema_only restore, so that
+// applications can start using them right away. In the transaction publishing
+// them, it creates a second restore job which then loads the data of the
+// backup, as of the end time of the restored chain, into the online tables.
+//
+// The data is ingested underneath any rows applications write in the meantime:
+// a restored key that an application has already written fails the job, as
+// does a schema change of a restored table, rather than leaving the table with
+// rows inconsistent with their indexes. A failed or canceled data job leaves
+// the restored objects, and any data loaded until then, in place.
+
+// deferredDataJobDetails returns the details of the job loading the data of
+// the schema_only restore with the given details, which have just been
+// published.
+func deferredDataJobDetails(
+	jobID jobspb.JobID, details jobspb.RestoreDetails,
+) jobspb.RestoreDetails {
+	details.SchemaOnly = false
+	details.DeferredData = false
+	details.DeferredDataOf = jobID
+	// The comments and zone configurations are restored by the schema_only
+	// restore.
+	details.SkipComments = true
+	details.SkipZoneConfigs = true
+	details.ReplacedDescriptors = nil
+	return details
+}
+
+// createDeferredDataJob creates the job loading the data of the schema_only
+// restore being run by r, whose descriptors are published in txn.
+func (r *restoreResumer) createDeferredDataJob(
+	ctx context.Context, txn *kv.Txn, details jobspb.RestoreDetails,
+) error {
+	payload := r.job.Payload()
+	record := jobs.Record{
+		Description: fmt.Sprintf("loading the data of restore job %d: %s", r.job.ID(), payload.Description),
+		Username:    payload.UsernameProto.Decode(),
+		Details:     deferredDataJobDetails(r.job.ID(), details),
+		Progress:    jobspb.RestoreProgress{},
+	}
+	for _, tbl := range details.TableDescs {
+		record.DescriptorIDs = append(record.DescriptorIDs, tbl.ID)
+	}
+	jobID := r.execCfg.JobRegistry.MakeJobID()
+	if _, err := r.execCfg.JobRegistry.CreateAdoptableJobWithTxn(ctx, record, jobID, txn); err != nil {
+		return errors.Wrap(err, "creating the job loading the restored data")
+	}
+	log.Infof(ctx, "created job %d to load the data of restore job %d", jobID, r.job.ID())
+	return nil
+}
+
+// deferredDataRestorationData returns the data of the backup to load into the
+// published tables of a deferred data job.
+func deferredDataRestorationData(
+	p sql.JobExecContext,
+	backupCodec keys.SQLCodec,
+	sqlDescs []catalog.Descriptor,
+	details jobspb.RestoreDetails,
+) (*mainRestorationData, error) {
+	oldIDs := make(map[descpb.ID]descpb.ID, len(details.DescriptorRewrites))
+	for oldID, rw := range details.DescriptorRewrites {
+		oldIDs[rw.ID] = oldID
+	}
+	backupTables := make(map[descpb.ID]catalog.TableDescriptor)
+	for _, desc := range sqlDescs {
+		if tbl, ok := desc.(catalog.TableDescriptor); ok {
+			backupTables[tbl.GetID()] = tbl
+		}
+	}
+
+	tables := make([]catalog.TableDescriptor, 0, len(details.TableDescs))
+	oldTableIDs := make([]descpb.ID, 0, len(details.TableDescs))
+	spanTables := make([]catalog.TableDescriptor, 0, len(details.TableDescs))
+	for _, tbl := range details.TableDescs {
+		oldID, ok := oldIDs[tbl.ID]
+		if !ok {
+			return nil, errors.AssertionFailedf("no rewrite for restored table %d", tbl.ID)
+		}
+		backupTable, ok := backupTables[oldID]
+		if !ok {
+			return nil, errors.AssertionFailedf("table %d not found in the backup", oldID)
+		}
+		tables = append(tables, tabledesc.NewBuilder(tbl).BuildImmutableTable())
+		oldTableIDs = append(oldTableIDs, oldID)
+		spanTables = append(spanTables, backupTable)
+	}
+
+	rekeys, tenantRekeys, err := makeRestoreRekeys(p, backupCodec, tables, oldTableIDs)
+	if err != nil {
+		return nil, err
+	}
+	pkIDs := make(map[uint64]bool)
+	for _, tbl := range tables {
+		pkIDs[roachpb.BulkOpSummaryID(uint64(tbl.GetID()), uint64(tbl.GetPrimaryIndexID()))] = true
+	}
+	spans := spansForAllRestoreTableIndexes(backupCodec, spanTables, nil, false /* schemaOnly */)
+	if details.RowFilter != "" {
+		spans = restrictSpansToRowFilter(spans, details.RowFilterSpans)
+	}
+	return &mainRestorationData{
+		restorationDataBase{
+			spans:        spans,
+			tableRekeys:  rekeys,
+			tenantRekeys: tenantRekeys,
+			pkIDs:        pkIDs,
+		},
+	}, nil
+}
+
+// checkDeferredDataTables checks that the tables of a deferred data job are
+// still public and have the indexes they were published with, so that the
+// data being loaded into them is consistent with their schema.
+func checkDeferredDataTables(
+	ctx context.Context, execCfg *sql.ExecutorConfig, details jobspb.RestoreDetails,
+) error {
+	return sql.DescsTxn(ctx, execCfg, func(ctx context.Context, txn *kv.Txn, col *descs.Collection) error {
+		for _, published := range details.TableDescs {
+			tbl, err := col.GetImmutableTableByID(ctx, txn, published.ID, tree.ObjectLookupFlags{
+				CommonLookupFlags: tree.CommonLookupFlags{
+					Required:       true,
+					AvoidLeased:    true,
+					IncludeOffline: true,
+					IncludeDropped: true,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			changed := !tbl.Public() || len(tbl.AllMutations()) > 0
+			if !changed {
+				publishedIndexes := make(map[descpb.IndexID]struct{})
+				for _, idx := range tabledesc.NewBuilder(published).BuildImmutableTable().ActiveIndexes() {
+					publishedIndexes[idx.GetID()] = struct{}{}
+				}
+				indexes := tbl.ActiveIndexes()
+				changed = len(indexes) != len(publishedIndexes)
+				for _, idx := range indexes {
+					if _, ok := publishedIndexes[idx.GetID()]; !ok {
+						changed = true
+					}
+				}
+			}
+			if changed {
+				return errors.WithHint(
+					errors.Newf("table %q was dropped or altered while its data was being restored",
+						tbl.GetName()),
+					"schema changes to restored tables must wait for the data of the restore to be loaded")
+			}
+		}
+		return nil
+	})
+}
+
+// restoreDeferredData loads the data of the backup into the published tables
+// of a deferred data job.
+func (r *restoreResumer) restoreDeferredData(
+	ctx context.Context,
+	p sql.JobExecContext,
+	backupCodec keys.SQLCodec,
+	backupManifests []backuppb.BackupManifest,
+	latestBackupManifest backuppb.BackupManifest,
+	sqlDescs []catalog.Descriptor,
+	defaultStore cloud.ExternalStorage,
+	kmsEnv cloud.KMSEnv,
+) error {
+	details := r.job.Details().(jobspb.RestoreDetails)
+	if err := checkDeferredDataTables(ctx, p.ExecCfg(), details); err != nil {
+		return err
+	}
+	data, err := deferredDataRestorationData(p, backupCodec, sqlDescs, details)
+	if err != nil {
+		return err
+	}
+
+	numNodes, err := clusterNodeCount(p.ExecCfg().Gossip)
+	if err != nil {
+		log.Warningf(ctx, "unable to determine cluster node count: %v", err)
+		numNodes = 1
+	}
+	res, err := restoreWithRetry(ctx, p, numNodes, backupManifests, details.BackupLocalityInfo,
+		details.EndTime, data, r.job, details.Encryption, kmsEnv)
+	if err != nil {
+		return err
+	}
+	if err := checkDeferredDataTables(ctx, p.ExecCfg(), details); err != nil {
+		return err
+	}
+
+	backupStats, err := backupinfo.GetStatisticsFromBackup(ctx, defaultStore, details.Encryption,
+		kmsEnv, latestBackupManifest)
+	if err == nil {
+		remappedStats := remapRelevantStatistics(ctx, backupStats, details.DescriptorRewrites,
+			details.TableDescs)
+		if err := insertStats(ctx, r.job, p.ExecCfg(), remappedStats); err != nil {
+			return errors.Wrap(err, "inserting table statistics")
+		}
+	} else {
+		log.Warningf(ctx, "failed to resolve table statistics from backup during restore: %+v",
+			err.Error())
+	}
+
+	r.notifyStatsRefresherOfNewTables()
+	r.restoreStats = res
+	emitRestoreJobEvent(ctx, p, jobs.StatusSucceeded, r.job)
+	telemetry.Count("restore.deferred-data.succeeded")
+	logJobCompletion(ctx, restoreJobEventType, r.job.ID(), true, nil)
+	return nil
+}
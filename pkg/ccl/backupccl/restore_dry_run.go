@@ -0,0 +1,116 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+)
+
+// A RESTORE with dry_run plans the restore like any other, but rather than
+// creating a job it reports the descriptors it would restore and the outcome
+// of the checks that would fail the restore: whether the user has the
+// privileges to restore them, whether they conflict with the descriptors of
+// the cluster, and whether the cluster has the regions they need. It reads
+// the manifests of the backups, but not their data, and writes nothing.
+
+// The checks reported by a RESTORE with dry_run.
+const (
+	restoreDryRunCheckPrivileges  = "privileges"
+	restoreDryRunCheckDescriptors = "descriptors"
+	restoreDryRunCheckRegions     = "regions"
+)
+
+// restoreDryRunHeader is the header of the results of a RESTORE with dry_run.
+var restoreDryRunHeader = colinfo.ResultColumns{
+	{Name: "check", Typ: types.String},
+	{Name: "object", Typ: types.String},
+	{Name: "passed", Typ: types.Bool},
+	{Name: "detail", Typ: types.String},
+}
+
+// restoreDryRunCheck is the outcome of a check reported by a RESTORE with
+// dry_run.
+type restoreDryRunCheck struct {
+	name string
+	// err is the error the restore would fail the check with, if any.
+	err error
+	// skipped is set if the check was not run, either because the options of
+	// the restore skip it or because an earlier check failed.
+	skipped bool
+}
+
+func (c restoreDryRunCheck) datums() tree.Datums {
+	switch {
+	case c.skipped:
+		return tree.Datums{tree.NewDString(c.name), tree.DNull, tree.DNull, tree.NewDString("not checked")}
+	case c.err != nil:
+		return tree.Datums{tree.NewDString(c.name), tree.DNull, tree.DBoolFalse, tree.NewDString(c.err.Error())}
+	default:
+		return tree.Datums{tree.NewDString(c.name), tree.DNull, tree.DBoolTrue, tree.DNull}
+	}
+}
+
+// emitRestoreDryRunReport emits the report of a RESTORE with dry_run: a row
+// for each descriptor it would restore, in the order of their IDs in the
+// backup, followed by a row for each of the checks.
+func emitRestoreDryRunReport(
+	resultsCh chan<- tree.Datums,
+	databasesByID map[descpb.ID]*dbdesc.Mutable,
+	schemasByID map[descpb.ID]*schemadesc.Mutable,
+	tablesByID map[descpb.ID]*tabledesc.Mutable,
+	typesByID map[descpb.ID]*typedesc.Mutable,
+	functionsByID map[descpb.ID]*funcdesc.Mutable,
+	checks ...restoreDryRunCheck,
+) {
+	var restored []catalog.Descriptor
+	for _, desc := range databasesByID {
+		restored = append(restored, desc)
+	}
+	for _, desc := range schemasByID {
+		restored = append(restored, desc)
+	}
+	for _, desc := range tablesByID {
+		restored = append(restored, desc)
+	}
+	for _, desc := range typesByID {
+		restored = append(restored, desc)
+	}
+	for _, desc := range functionsByID {
+		restored = append(restored, desc)
+	}
+	sort.Slice(restored, func(i, j int) bool { return restored[i].GetID() < restored[j].GetID() })
+
+	for _, desc := range restored {
+		name := desc.GetName()
+		if db, ok := databasesByID[desc.GetParentID()]; ok {
+			name = db.GetName() + "." + name
+		}
+		resultsCh <- tree.Datums{
+			tree.NewDString("restore"),
+			tree.NewDString(fmt.Sprintf("%s %s", desc.DescriptorType(), name)),
+			tree.DBoolTrue,
+			tree.NewDString(fmt.Sprintf("ID %d in the backup", desc.GetID())),
+		}
+	}
+	for _, c := range checks {
+		resultsCh <- c.datums()
+	}
+}
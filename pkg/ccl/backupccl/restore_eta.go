@@ -0,0 +1,133 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+)
+
+const (
+	// restoreIngestSmoothingWindow is the time constant of the exponentially
+	// weighted moving average of the ingest throughput of each SQL instance: a
+	// sample taken that long ago weighs 1/e as much as a current one.
+	restoreIngestSmoothingWindow = 5 * time.Minute
+
+	// restoreETAStatusInterval is how often a restore refreshes the ETA in its
+	// running status.
+	restoreETAStatusInterval = 30 * time.Second
+)
+
+// restoreIngestTracker tracks the data each SQL instance ingests for a restore
+// and the smoothed rate at which it does, from which it estimates when the
+// restore completes. Its state is persisted in the progress of the job, so that
+// the estimate of a resumed restore starts from the throughput of its earlier
+// attempts. It is not safe for concurrent use.
+type restoreIngestTracker struct {
+	progress jobspb.RestoreProgress
+	// pending is the data each instance ingested since the last sample.
+	pending    map[base.SQLInstanceID]int64
+	lastSample time.Time
+	// eta is when the restore was estimated to complete at the last sample, or
+	// zero if there was not enough data to estimate it.
+	eta            time.Time
+	bytesPerSecond float64
+}
+
+func makeRestoreIngestTracker(
+	progress *jobspb.RestoreProgress, now time.Time,
+) restoreIngestTracker {
+	t := restoreIngestTracker{
+		pending:    make(map[base.SQLInstanceID]int64),
+		lastSample: now,
+	}
+	t.progress.IngestedBytes = progress.IngestedBytes
+	t.progress.IngestedSpans = progress.IngestedSpans
+	t.progress.InstanceIngest = append(t.progress.InstanceIngest, progress.InstanceIngest...)
+	return t
+}
+
+// spanIngested records that the instance ingested a span of the given size.
+func (t *restoreIngestTracker) spanIngested(instance base.SQLInstanceID, dataSize int64) {
+	t.pending[instance] += dataSize
+	t.progress.IngestedBytes += dataSize
+	t.progress.IngestedSpans++
+}
+
+// sample folds the data ingested since the last sample into the throughput of
+// each instance, estimates when the remaining spans will have been ingested,
+// and records the result in progress.
+func (t *restoreIngestTracker) sample(
+	now time.Time, remainingSpans int, progress *jobspb.RestoreProgress,
+) {
+	elapsed := now.Sub(t.lastSample)
+	if elapsed >= time.Second {
+		t.lastSample = now
+		// The weight of the new sample grows with the time it covers, since
+		// samples are not taken at regular intervals.
+		alpha := 1 - math.Exp(-elapsed.Seconds()/restoreIngestSmoothingWindow.Seconds())
+		for i := range t.progress.InstanceIngest {
+			ingest := &t.progress.InstanceIngest[i]
+			bytes := t.pending[ingest.SQLInstanceID]
+			delete(t.pending, ingest.SQLInstanceID)
+			ingest.IngestedBytes += bytes
+			ingest.BytesPerSecond += alpha * (float64(bytes)/elapsed.Seconds() - ingest.BytesPerSecond)
+		}
+		// The first sample of an instance is its throughput.
+		for instance, bytes := range t.pending {
+			t.progress.InstanceIngest = append(t.progress.InstanceIngest, jobspb.RestoreProgress_InstanceIngest{
+				SQLInstanceID:  instance,
+				IngestedBytes:  bytes,
+				BytesPerSecond: float64(bytes) / elapsed.Seconds(),
+			})
+			delete(t.pending, instance)
+		}
+		sort.Slice(t.progress.InstanceIngest, func(i, j int) bool {
+			return t.progress.InstanceIngest[i].SQLInstanceID < t.progress.InstanceIngest[j].SQLInstanceID
+		})
+
+		t.bytesPerSecond = 0
+		for _, ingest := range t.progress.InstanceIngest {
+			t.bytesPerSecond += ingest.BytesPerSecond
+		}
+		t.eta = time.Time{}
+		if t.bytesPerSecond > 0 && t.progress.IngestedSpans > 0 {
+			// Spans are cut to a target size, so the data left is estimated from
+			// the average size of the spans ingested so far.
+			remainingBytes := float64(remainingSpans) *
+				float64(t.progress.IngestedBytes) / float64(t.progress.IngestedSpans)
+			t.eta = now.Add(time.Duration(remainingBytes / t.bytesPerSecond * float64(time.Second)))
+		}
+	}
+
+	progress.IngestedBytes = t.progress.IngestedBytes
+	progress.IngestedSpans = t.progress.IngestedSpans
+	progress.InstanceIngest = append(progress.InstanceIngest[:0], t.progress.InstanceIngest...)
+}
+
+// runningStatus returns the running status of the restore at now, which
+// reports the time it has left and its throughput, or the empty string if there
+// is no estimate yet.
+func (t *restoreIngestTracker) runningStatus(now time.Time) string {
+	if t.eta.IsZero() {
+		return ""
+	}
+	remaining := t.eta.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return fmt.Sprintf("restoring data: about %s left at %s/s",
+		remaining.Round(time.Minute), humanizeutil.IBytes(int64(t.bytesPerSecond)))
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreIngestTracker(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const mb = 1 << 20
+	start := time.Unix(1600000000, 0)
+	tracker := makeRestoreIngestTracker(&jobspb.RestoreProgress{}, start)
+	var progress jobspb.RestoreProgress
+
+	// Without any data ingested there is no estimate.
+	tracker.sample(start.Add(time.Minute), 10, &progress)
+	require.Equal(t, "", tracker.runningStatus(start.Add(time.Minute)))
+
+	// Two instances each ingest 60 MiB in a minute, so that the 8 remaining
+	// spans of 10 MiB take 40s at 2 MiB/s.
+	for i := 0; i < 6; i++ {
+		tracker.spanIngested(1, 10*mb)
+		tracker.spanIngested(2, 10*mb)
+	}
+	now := start.Add(2 * time.Minute)
+	tracker.sample(now, 8, &progress)
+	require.Equal(t, int64(120*mb), progress.IngestedBytes)
+	require.Equal(t, int64(12), progress.IngestedSpans)
+	require.Len(t, progress.InstanceIngest, 2)
+	require.InDelta(t, float64(mb), progress.InstanceIngest[0].BytesPerSecond, 1)
+	require.Equal(t, 40*time.Second, tracker.eta.Sub(now))
+	require.Equal(t, "restoring data: about 1m0s left at 2.0 MiB/s", tracker.runningStatus(now))
+
+	// An instance that stops ingesting lowers the throughput gradually.
+	tracker.spanIngested(1, 10*mb)
+	now = now.Add(10 * time.Second)
+	tracker.sample(now, 7, &progress)
+	require.Less(t, progress.InstanceIngest[1].BytesPerSecond, float64(mb))
+	require.Greater(t, progress.InstanceIngest[1].BytesPerSecond, 0.9*mb)
+
+	// A resumed restore starts from the persisted throughput.
+	resumed := makeRestoreIngestTracker(&progress, now)
+	var resumedProgress jobspb.RestoreProgress
+	resumed.sample(now.Add(time.Second), 7, &resumedProgress)
+	require.Equal(t, progress.IngestedBytes, resumedProgress.IngestedBytes)
+	require.Equal(t, progress.IngestedSpans, resumedProgress.IngestedSpans)
+	require.NotEqual(t, "", resumed.runningStatus(now))
+}
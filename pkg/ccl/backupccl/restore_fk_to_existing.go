@@ -0,0 +1,304 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
+)
+
+// The restore_fk_to_existing option keeps the foreign keys of restored tables
+// that reference tables which are not restored, by pointing them at the
+// existing tables with the same schema and name in the database the
+// referencing table is restored into. The referenced tables must be in the
+// backup, as it is their descriptors that record those names. The referenced
+// tables are added to the descriptor rewrites as rewrites to existing
+// descriptors, like the existing types restored tables use, and the restored
+// foreign keys are left unvalidated, since the existing tables hold different
+// rows than the tables they referenced when backed up.
+
+// resolveFKsToExistingTables points the foreign keys of the tables in
+// tablesByID that reference tables which are not restored at the matching
+// existing tables, adding the rewrites of the referenced tables to
+// descriptorRewrites. Foreign keys whose referenced table has no match are left
+// to be dropped if skipMissing is set, and are an error otherwise.
+func resolveFKsToExistingTables(
+	ctx context.Context,
+	p sql.PlanHookState,
+	backupDescs []catalog.Descriptor,
+	tablesByID map[descpb.ID]*tabledesc.Mutable,
+	descriptorRewrites jobspb.DescRewriteMap,
+	skipMissing bool,
+) error {
+	backupDescsByID := make(map[descpb.ID]catalog.Descriptor, len(backupDescs))
+	for _, desc := range backupDescs {
+		backupDescsByID[desc.GetID()] = desc
+	}
+
+	for _, table := range tablesByID {
+		for i := range table.OutboundFKs {
+			fk := &table.OutboundFKs[i]
+			if _, ok := tablesByID[fk.ReferencedTableID]; ok {
+				continue
+			}
+			referenced, ok := backupDescsByID[fk.ReferencedTableID].(catalog.TableDescriptor)
+			if !ok {
+				if skipMissing {
+					continue
+				}
+				return errors.Errorf(
+					"cannot restore table %q: referenced table %d is not in the backup (or %q option)",
+					table.Name, fk.ReferencedTableID, restoreOptSkipMissingFKs,
+				)
+			}
+			existing, err := lookupExistingReferencedTable(
+				ctx, p, backupDescsByID, referenced, descriptorRewrites[table.ID].ParentID,
+			)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				if skipMissing {
+					continue
+				}
+				return errors.Errorf(
+					"cannot restore table %q without referenced table %q (or %q option)",
+					table.Name, referenced.GetName(), restoreOptSkipMissingFKs,
+				)
+			}
+			if err := remapFKToExistingTable(table, fk, referenced, existing); err != nil {
+				return err
+			}
+			if rw, ok := descriptorRewrites[referenced.GetID()]; ok && rw.ID != existing.GetID() {
+				return pgerror.Newf(pgcode.FeatureNotSupported,
+					"cannot point the foreign keys referencing table %q at tables in different databases",
+					referenced.GetName())
+			}
+			descriptorRewrites[referenced.GetID()] = &jobspb.DescriptorRewrite{
+				ID:             existing.GetID(),
+				ParentID:       existing.GetParentID(),
+				ParentSchemaID: existing.GetParentSchemaID(),
+				ToExisting:     true,
+			}
+			p.BufferClientNotice(ctx, pgnotice.Newf(
+				"foreign key %q of table %q now references existing table %q and is not validated; "+
+					"run ALTER TABLE ... VALIDATE CONSTRAINT to validate it",
+				fk.Name, table.Name, existing.GetName()))
+		}
+	}
+	return nil
+}
+
+// lookupExistingReferencedTable returns the table with the schema and name of
+// the backed up table referenced in the database parentID, or nil if there is
+// none.
+func lookupExistingReferencedTable(
+	ctx context.Context,
+	p sql.PlanHookState,
+	backupDescsByID map[descpb.ID]catalog.Descriptor,
+	referenced catalog.TableDescriptor,
+	parentID descpb.ID,
+) (catalog.TableDescriptor, error) {
+	found, db, err := p.Descriptors().GetImmutableDatabaseByID(ctx, p.Txn(), parentID,
+		tree.DatabaseLookupFlags{AvoidLeased: true})
+	if err != nil || !found {
+		// The database is restored too, so it holds no existing tables.
+		return nil, err
+	}
+	scName := tree.PublicSchema
+	if scID := referenced.GetParentSchemaID(); scID != keys.PublicSchemaIDForBackup {
+		sc, ok := backupDescsByID[scID]
+		if !ok {
+			return nil, errors.AssertionFailedf(
+				"schema %d of table %q not found in the backup", scID, referenced.GetName())
+		}
+		scName = sc.GetName()
+	}
+	tn := tree.MakeTableNameWithSchema(
+		tree.Name(db.GetName()), tree.Name(scName), tree.Name(referenced.GetName()),
+	)
+	found, existing, err := p.Descriptors().GetImmutableTableByName(ctx, p.Txn(), &tn,
+		tree.ObjectLookupFlags{CommonLookupFlags: tree.CommonLookupFlags{AvoidLeased: true}})
+	if err != nil || !found {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// remapFKToExistingTable points fk, which references the backed up table
+// referenced, at the columns of existing with the same names.
+func remapFKToExistingTable(
+	table *tabledesc.Mutable,
+	fk *descpb.ForeignKeyConstraint,
+	referenced catalog.TableDescriptor,
+	existing catalog.TableDescriptor,
+) error {
+	colIDs := make(descpb.ColumnIDs, len(fk.ReferencedColumnIDs))
+	for i, id := range fk.ReferencedColumnIDs {
+		col, err := referenced.FindColumnWithID(id)
+		if err != nil {
+			return err
+		}
+		existingCol, err := existing.FindColumnWithName(col.ColName())
+		if err != nil || !existingCol.Public() {
+			return pgerror.Newf(pgcode.InvalidTableDefinition,
+				"cannot restore foreign key %q of table %q: table %q has no column %q",
+				fk.Name, table.Name, existing.GetName(), col.GetName())
+		}
+		if !existingCol.GetType().Identical(col.GetType()) {
+			return pgerror.Newf(pgcode.DatatypeMismatch,
+				"cannot restore foreign key %q of table %q: column %q of table %q has type %s, not %s",
+				fk.Name, table.Name, col.GetName(), existing.GetName(),
+				existingCol.GetType().SQLString(), col.GetType().SQLString())
+		}
+		colIDs[i] = existingCol.GetID()
+	}
+	if _, err := tabledesc.FindFKReferencedUniqueConstraint(existing, colIDs); err != nil {
+		return errors.Wrapf(err, "cannot restore foreign key %q of table %q", fk.Name, table.Name)
+	}
+	fk.ReferencedColumnIDs = colIDs
+	fk.Validity = descpb.ConstraintValidity_Unvalidated
+	return nil
+}
+
+// addExistingTableFKBackReferences adds the back references of the foreign keys
+// of the restored tables that reference existing tables to those tables. The
+// foreign keys of the tables loaded from the backup reference the columns of
+// the backed up tables, so they're first replaced by the foreign keys resolved
+// when the restore was planned.
+func addExistingTableFKBackReferences(
+	ctx context.Context,
+	txn *kv.Txn,
+	descsCol *descs.Collection,
+	restoredTables []*tabledesc.Mutable,
+	details *jobspb.RestoreDetails,
+) error {
+	restoredTableIDs := make(map[descpb.ID]struct{}, len(restoredTables))
+	for _, tbl := range restoredTables {
+		restoredTableIDs[tbl.GetID()] = struct{}{}
+	}
+	plannedTables := make(map[descpb.ID]*descpb.TableDescriptor, len(details.TableDescs))
+	for _, tbl := range details.TableDescs {
+		plannedTables[tbl.ID] = tbl
+	}
+
+	existingTables := make(map[descpb.ID]*tabledesc.Mutable)
+	for _, tbl := range restoredTables {
+		for i := range tbl.OutboundFKs {
+			fk := &tbl.OutboundFKs[i]
+			if _, ok := restoredTableIDs[fk.ReferencedTableID]; ok {
+				continue
+			}
+			planned, err := findPlannedFK(plannedTables[tbl.GetID()], fk.Name)
+			if err != nil {
+				return err
+			}
+			fk.ReferencedColumnIDs = planned.ReferencedColumnIDs
+			fk.Validity = planned.Validity
+
+			existing, ok := existingTables[fk.ReferencedTableID]
+			if !ok {
+				existing, err = descsCol.GetMutableTableVersionByID(ctx, fk.ReferencedTableID, txn)
+				if err != nil {
+					return err
+				}
+				existing.MaybeIncrementVersion()
+				existingTables[existing.GetID()] = existing
+			}
+			existing.InboundFKs = append(existing.InboundFKs, *fk)
+		}
+	}
+	if len(existingTables) == 0 {
+		return nil
+	}
+
+	b := txn.NewBatch()
+	for _, tbl := range existingTables {
+		if err := descsCol.WriteDescToBatch(ctx, false /* kvTrace */, tbl, b); err != nil {
+			return err
+		}
+	}
+	return txn.Run(ctx, b)
+}
+
+func findPlannedFK(
+	planned *descpb.TableDescriptor, name string,
+) (*descpb.ForeignKeyConstraint, error) {
+	if planned != nil {
+		for i := range planned.OutboundFKs {
+			if planned.OutboundFKs[i].Name == name {
+				return &planned.OutboundFKs[i], nil
+			}
+		}
+	}
+	return nil, errors.AssertionFailedf("foreign key %q to an existing table was not planned", name)
+}
+
+// removeExistingTableFKBackReferences removes the back references that
+// addExistingTableFKBackReferences added to existing tables. It is used when
+// rolling back from a failed restore.
+func removeExistingTableFKBackReferences(
+	ctx context.Context,
+	txn *kv.Txn,
+	descsCol *descs.Collection,
+	b *kv.Batch,
+	restoredTables []*tabledesc.Mutable,
+) error {
+	restoredTableIDs := make(map[descpb.ID]struct{}, len(restoredTables))
+	for _, tbl := range restoredTables {
+		restoredTableIDs[tbl.GetID()] = struct{}{}
+	}
+
+	existingTables := make(map[descpb.ID]*tabledesc.Mutable)
+	for _, tbl := range restoredTables {
+		for i := range tbl.OutboundFKs {
+			refID := tbl.OutboundFKs[i].ReferencedTableID
+			if _, ok := restoredTableIDs[refID]; ok {
+				continue
+			}
+			existing, ok := existingTables[refID]
+			if !ok {
+				var err error
+				existing, err = descsCol.GetMutableTableVersionByID(ctx, refID, txn)
+				if err != nil {
+					return err
+				}
+				existing.MaybeIncrementVersion()
+				existingTables[refID] = existing
+			}
+			inbound := existing.InboundFKs[:0]
+			for _, backref := range existing.InboundFKs {
+				if backref.OriginTableID != tbl.GetID() {
+					inbound = append(inbound, backref)
+				}
+			}
+			existing.InboundFKs = inbound
+		}
+	}
+
+	for _, tbl := range existingTables {
+		if err := descsCol.WriteDescToBatch(ctx, false /* kvTrace */, tbl, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
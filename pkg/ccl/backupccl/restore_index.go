@@ -0,0 +1,554 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// A RESTORE INDEX rebuilds a secondary index of an existing table from the
+// data of the index in a backup of the table, rather than backfilling it from
+// the rows of the table, and without restoring the rest of the table.
+//
+// The job takes the table offline, ingests the data of the index under an
+// index ID the table has not used before, and validates the rebuilt index
+// against the rows of the table. Only then is the index added to the table,
+// replacing the index of the same name if the table has one, and the table
+// brought back online. A restore which fails, because for instance the table
+// was written to after the backup was taken, leaves the table as it was.
+
+// indexRestoreOfflineReason is the reason the table of a RESTORE INDEX is
+// offline while the job runs.
+const indexRestoreOfflineReason = "restoring index"
+
+// checkIndexRestoreOptions returns an error if restoreStmt, a RESTORE INDEX,
+// has options which only apply to the restore of whole descriptors.
+func checkIndexRestoreOptions(restoreStmt *tree.Restore) error {
+	if restoreStmt.Options.DryRun {
+		return errors.New("RESTORE INDEX cannot use dry_run")
+	}
+	opts := restoreStmt.Options
+	// These options pick the backup to restore the index from, and how the job
+	// runs.
+	opts.EncryptionPassphrase = nil
+	opts.DecryptionKMSURI = nil
+	opts.KMSURIByLocality = nil
+	opts.IncrementalStorage = nil
+	opts.MetadataURI = nil
+	opts.LatestValue = nil
+	opts.LatestAsOf = nil
+	opts.MinLatestTime = nil
+	opts.Detached = false
+	opts.DebugPauseOn = nil
+	opts.ExecutionLocality = nil
+	if !opts.IsDefault() {
+		return errors.Newf("RESTORE INDEX does not support the options %s", tree.AsString(&opts))
+	}
+	return nil
+}
+
+// planIndexRestore resolves the existing table and the index of the backup
+// which a RESTORE INDEX rebuilds, and checks that the index, as it is in the
+// backup, can be added to the table. backupTables holds the table of the index
+// as it is in the backup, and rowFilter is the row filter of the backup, if
+// any. It returns the existing table, the rewrite of the table of the backup
+// to it, and the index restore of the job.
+func planIndexRestore(
+	ctx context.Context,
+	p sql.PlanHookState,
+	index *tree.TableIndexName,
+	backupTables map[descpb.ID]*tabledesc.Mutable,
+	rowFilter string,
+) (*tabledesc.Mutable, jobspb.DescRewriteMap, *jobspb.RestoreDetails_IndexRestore, error) {
+	if !p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.V23_1RestoreIndex) {
+		return nil, nil, nil, errors.New(
+			"cannot run RESTORE INDEX until the cluster has fully upgraded to 23.1")
+	}
+	if len(backupTables) != 1 {
+		return nil, nil, nil, errors.AssertionFailedf(
+			"expected the backup to hold one table of index %s, found %d", index, len(backupTables))
+	}
+	var backupTable *tabledesc.Mutable
+	for _, tbl := range backupTables {
+		backupTable = tbl
+	}
+	if rowFilter != "" {
+		return nil, nil, nil, errors.Newf(
+			"cannot restore an index from a backup which only contains the rows matching %s", rowFilter)
+	}
+
+	tn := index.Table
+	_, table, err := p.ResolveMutableTableDescriptor(ctx, &tn, true /* required */, tree.ResolveRequireTableDesc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := p.CheckPrivilege(ctx, table, privilege.CREATE); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(table.AllMutations()) > 0 || table.GetDeclarativeSchemaChangerState() != nil {
+		return nil, nil, nil, pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+			"cannot restore an index of table %q while it has schema changes in progress -- try again later",
+			table.GetName())
+	}
+
+	name := string(index.Index)
+	backupIndex, err := backupTable.FindIndexWithName(name)
+	if err != nil || !backupIndex.Public() {
+		return nil, nil, nil, pgerror.Newf(pgcode.UndefinedObject,
+			"index %q not found in table %q of the backup", name, backupTable.GetName())
+	}
+	if backupIndex.Primary() {
+		return nil, nil, nil, errors.WithHint(
+			pgerror.Newf(pgcode.FeatureNotSupported, "cannot restore the primary index %q", name),
+			"restore the whole table with RESTORE TABLE")
+	}
+	if backupIndex.GetType() != descpb.IndexDescriptor_FORWARD {
+		return nil, nil, nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"restoring the inverted index %q is not supported", name)
+	}
+	if backupIndex.GetPartitioning().NumColumns() > 0 {
+		return nil, nil, nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"restoring the partitioned index %q is not supported", name)
+	}
+	if !sameIndexKeyColumns(backupTable.GetPrimaryIndex(), table.GetPrimaryIndex()) {
+		return nil, nil, nil, errors.Newf(
+			"cannot restore index %q: the primary key of table %q differs from the one in the backup",
+			name, table.GetName())
+	}
+
+	// The index is decoded with the columns of the existing table, which have to
+	// be those the index was encoded with.
+	restored := backupIndex.IndexDescDeepCopy()
+	liveColumnName := func(id descpb.ColumnID, stored bool) (string, error) {
+		backupCol, err := backupTable.FindColumnWithID(id)
+		if err != nil {
+			return "", err
+		}
+		col, err := table.FindColumnWithID(id)
+		if err != nil || !col.Public() || !col.GetType().Identical(backupCol.GetType()) ||
+			col.IsVirtual() != backupCol.IsVirtual() || col.GetComputeExpr() != backupCol.GetComputeExpr() ||
+			(stored && columnFamilyID(table, id) != columnFamilyID(backupTable, id)) {
+			return "", errors.Newf(
+				"cannot restore index %q: column %q of the index differs in table %q from the backup",
+				name, backupCol.GetName(), table.GetName())
+		}
+		return col.GetName(), nil
+	}
+	for i, id := range restored.KeyColumnIDs {
+		if restored.KeyColumnNames[i], err = liveColumnName(id, false /* stored */); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	for i, id := range restored.StoreColumnIDs {
+		if restored.StoreColumnNames[i], err = liveColumnName(id, true /* stored */); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	// The index ID and constraint ID are allocated by the job.
+	restored.ID = 0
+	restored.ConstraintID = 0
+
+	indexRestore := &jobspb.RestoreDetails_IndexRestore{
+		TableID:       table.GetID(),
+		BackupIndexID: backupIndex.GetID(),
+		Index:         restored,
+	}
+	if existing, err := table.FindIndexWithName(name); err == nil {
+		if existing.Primary() {
+			return nil, nil, nil, errors.Newf(
+				"cannot restore index %q: it is the primary index of table %q", name, table.GetName())
+		}
+		indexRestore.ReplacedIndexID = existing.GetID()
+	}
+	descriptorRewrites := jobspb.DescRewriteMap{
+		backupTable.GetID(): {
+			ID:             table.GetID(),
+			ParentID:       table.GetParentID(),
+			ParentSchemaID: table.GetParentSchemaID(),
+		},
+	}
+	return table, descriptorRewrites, indexRestore, nil
+}
+
+// sameIndexKeyColumns returns true if the key columns of a and b are the same
+// columns, in the same directions.
+func sameIndexKeyColumns(a, b catalog.Index) bool {
+	if a.NumKeyColumns() != b.NumKeyColumns() {
+		return false
+	}
+	for i := 0; i < a.NumKeyColumns(); i++ {
+		if a.GetKeyColumnID(i) != b.GetKeyColumnID(i) ||
+			a.GetKeyColumnDirection(i) != b.GetKeyColumnDirection(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnFamilyID returns the ID of the family of the column of desc with the
+// given ID, which the values of the column are encoded in.
+func columnFamilyID(desc catalog.TableDescriptor, id descpb.ColumnID) descpb.FamilyID {
+	for _, fam := range desc.GetFamilies() {
+		for _, colID := range fam.ColumnIDs {
+			if colID == id {
+				return fam.ID
+			}
+		}
+	}
+	return 0
+}
+
+// prepareIndexRestore takes the table of the index restore of r offline and
+// allocates the ID of the restored index, unless an earlier run of the job did
+// so already. It returns the offline table, and true if an earlier run already
+// added the restored index to the table.
+func (r *restoreResumer) prepareIndexRestore(
+	ctx context.Context,
+) (catalog.TableDescriptor, bool, error) {
+	details := r.job.Details().(jobspb.RestoreDetails)
+	var table catalog.TableDescriptor
+	var published bool
+	if err := sql.DescsTxn(ctx, r.execCfg, func(
+		ctx context.Context, txn *kv.Txn, col *descs.Collection,
+	) error {
+		indexRestore := *details.IndexRestore
+		mut, err := col.GetMutableTableVersionByID(ctx, indexRestore.TableID, txn)
+		if err != nil {
+			return err
+		}
+		if indexRestore.Index.ID != 0 {
+			_, err := mut.FindIndexWithID(indexRestore.Index.ID)
+			published = err == nil
+			table = mut.ImmutableCopy().(catalog.TableDescriptor)
+			return nil
+		}
+		if mut.GetVersion() != details.TableDescs[0].Version || !mut.Public() {
+			return errors.Errorf("another operation is currently operating on table %q", mut.GetName())
+		}
+
+		indexRestore.Index.ID = mut.GetNextIndexID()
+		mut.NextIndexID++
+		if indexRestore.Index.Unique {
+			indexRestore.Index.ConstraintID = mut.GetNextConstraintID()
+			mut.NextConstraintID++
+		}
+		mut.SetOffline(indexRestoreOfflineReason)
+		if err := col.WriteDesc(ctx, false /* kvTrace */, mut, txn); err != nil {
+			return err
+		}
+		table = mut.ImmutableCopy().(catalog.TableDescriptor)
+
+		updated := details
+		updated.IndexRestore = &indexRestore
+		updated.TableDescs = []*descpb.TableDescriptor{mut.TableDesc()}
+		return r.job.SetDetails(ctx, txn, updated)
+	}); err != nil {
+		return nil, false, err
+	}
+	return table, published, nil
+}
+
+// indexRestorationData returns the data of the backup to restore into the index
+// of indexRestore, which is that of the index in backupTable, rewritten into
+// the index of table.
+func indexRestorationData(
+	p sql.JobExecContext,
+	backupCodec keys.SQLCodec,
+	backupTable catalog.TableDescriptor,
+	table catalog.TableDescriptor,
+	indexRestore jobspb.RestoreDetails_IndexRestore,
+) (*mainRestorationData, error) {
+	rekeys, tenantRekeys, err := makeRestoreRekeys(p, backupCodec,
+		[]catalog.TableDescriptor{table}, []descpb.ID{backupTable.GetID()})
+	if err != nil {
+		return nil, err
+	}
+	rekeys[0].IndexRewrites = map[uint32]uint32{
+		uint32(indexRestore.BackupIndexID): uint32(indexRestore.Index.ID),
+	}
+	return &mainRestorationData{
+		restorationDataBase{
+			spans:        []roachpb.Span{backupTable.IndexSpan(backupCodec, indexRestore.BackupIndexID)},
+			tableRekeys:  rekeys,
+			tenantRekeys: tenantRekeys,
+			// No rows of the table are restored, only the entries of the index.
+			pkIDs: map[uint64]bool{},
+		},
+	}, nil
+}
+
+// withRestoredIndex returns table, which is offline, as a public table which
+// has the restored index of indexRestore in place of the one it replaces.
+func withRestoredIndex(
+	table catalog.TableDescriptor, indexRestore jobspb.RestoreDetails_IndexRestore,
+) (catalog.TableDescriptor, catalog.Index, error) {
+	mut := tabledesc.NewBuilder(table.TableDesc()).BuildExistingMutableTable()
+	if indexRestore.ReplacedIndexID != 0 {
+		replaced, err := mut.FindIndexWithID(indexRestore.ReplacedIndexID)
+		if err != nil {
+			return nil, nil, err
+		}
+		mut.RemovePublicNonPrimaryIndex(replaced.Ordinal())
+	}
+	mut.AddPublicNonPrimaryIndex(indexRestore.Index)
+	withIndex := mut.MakePublic()
+	index, err := withIndex.FindIndexWithID(indexRestore.Index.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return withIndex, index, nil
+}
+
+// validateRestoredIndex checks that the entries of the restored index of
+// indexRestore are exactly those the rows of table, which is offline, map to.
+func validateRestoredIndex(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	job *jobs.Job,
+	table catalog.TableDescriptor,
+	indexRestore jobspb.RestoreDetails_IndexRestore,
+) error {
+	withIndex, index, err := withRestoredIndex(table, indexRestore)
+	if err != nil {
+		return err
+	}
+	mismatch := errors.WithHint(
+		errors.Newf("restored index %q does not match the rows of table %q",
+			index.GetName(), table.GetName()),
+		"the table may have been written to after the backup was taken; restore the index "+
+			"from a more recent backup, or recreate it with CREATE INDEX")
+
+	// The table is offline, so it is read as of now rather than as of a fixed
+	// timestamp.
+	var runner descs.HistoricalInternalExecTxnRunner = func(ctx context.Context, fn descs.InternalExecFn) error {
+		return execCfg.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+			ie := job.MakeSessionBoundInternalExecutor(sql.NewFakeSessionData(execCfg.SV())).(*sql.InternalExecutor)
+			return fn(ctx, txn, ie, nil /* descriptors */)
+		})
+	}
+	// The index has as many entries as the table has rows it covers, and its
+	// unique constraint, if any, holds.
+	if err := sql.ValidateForwardIndexes(
+		ctx,
+		withIndex,
+		[]catalog.Index{index},
+		runner,
+		false, /* withFirstMutationPublic */
+		true,  /* gatherAllInvalid */
+		sessiondata.InternalExecutorOverride{},
+	); err != nil {
+		if invalid := (sql.InvalidIndexesError{}); errors.As(err, &invalid) {
+			return mismatch
+		}
+		return err
+	}
+
+	// Each row covered by the index has an entry in it holding the values of the
+	// row. As the counts match, so do the entries and the rows.
+	colIDs := index.CollectKeyColumnIDs()
+	colIDs.UnionWith(index.CollectKeySuffixColumnIDs())
+	colIDs.UnionWith(index.CollectSecondaryStoredColumnIDs())
+	cols := make([]string, 0, colIDs.Len())
+	for _, id := range colIDs.Ordered() {
+		col, err := withIndex.FindColumnWithID(id)
+		if err != nil {
+			return err
+		}
+		cols = append(cols, tree.NameString(col.GetName()))
+	}
+	var where string
+	if index.IsPartial() {
+		where = " WHERE " + index.GetPredicate()
+	}
+	query := fmt.Sprintf(
+		`SELECT count(1) FROM (SELECT %[1]s FROM [%[2]d AS t]@[%[3]d]%[5]s EXCEPT ALL SELECT %[1]s FROM [%[2]d AS t]@[%[4]d]%[5]s)`,
+		strings.Join(cols, ", "), withIndex.GetID(), withIndex.GetPrimaryIndexID(), index.GetID(), where,
+	)
+	return runner(ctx, func(
+		ctx context.Context, txn *kv.Txn, ie sqlutil.InternalExecutor, _ *descs.Collection,
+	) error {
+		return ie.WithSyntheticDescriptors([]catalog.Descriptor{withIndex}, func() error {
+			row, err := ie.QueryRowEx(ctx, "validate-restored-index", txn,
+				sessiondata.InternalExecutorOverride{}, query)
+			if err != nil {
+				return err
+			}
+			if row == nil {
+				return errors.New("failed to validate restored index")
+			}
+			if tree.MustBeDInt(row[0]) != 0 {
+				return mismatch
+			}
+			return nil
+		})
+	})
+}
+
+// createIndexGCJob creates, in txn, the job removing the data of the index of
+// the table with the given ID, which is not or no longer in the table.
+func (r *restoreResumer) createIndexGCJob(
+	ctx context.Context, txn *kv.Txn, tableID descpb.ID, indexID descpb.IndexID,
+) error {
+	payload := r.job.Payload()
+	record := sql.CreateGCJobRecord(
+		fmt.Sprintf("GC for %s", payload.Description),
+		payload.UsernameProto.Decode(),
+		jobspb.SchemaChangeGCDetails{
+			Indexes: []jobspb.SchemaChangeGCDetails_DroppedIndex{{
+				IndexID:  indexID,
+				DropTime: timeutil.Now().UnixNano(),
+			}},
+			ParentID: tableID,
+		},
+		!r.execCfg.Settings.Version.IsActive(ctx, clusterversion.UseDelRangeInGCJob),
+	)
+	_, err := r.execCfg.JobRegistry.CreateAdoptableJobWithTxn(ctx, record,
+		r.execCfg.JobRegistry.MakeJobID(), txn)
+	return err
+}
+
+// publishRestoredIndex adds the validated restored index of indexRestore to its
+// table, in place of the index it replaces, and brings the table back online.
+func (r *restoreResumer) publishRestoredIndex(
+	ctx context.Context, indexRestore jobspb.RestoreDetails_IndexRestore,
+) error {
+	return sql.DescsTxn(ctx, r.execCfg, func(
+		ctx context.Context, txn *kv.Txn, col *descs.Collection,
+	) error {
+		mut, err := col.GetMutableTableVersionByID(ctx, indexRestore.TableID, txn)
+		if err != nil {
+			return err
+		}
+		if indexRestore.ReplacedIndexID != 0 {
+			replaced, err := mut.FindIndexWithID(indexRestore.ReplacedIndexID)
+			if err != nil {
+				return err
+			}
+			mut.RemovePublicNonPrimaryIndex(replaced.Ordinal())
+			if err := r.createIndexGCJob(ctx, txn, mut.GetID(), indexRestore.ReplacedIndexID); err != nil {
+				return err
+			}
+		}
+		mut.AddPublicNonPrimaryIndex(indexRestore.Index)
+		mut.SetPublic()
+		return col.WriteDesc(ctx, false /* kvTrace */, mut, txn)
+	})
+}
+
+// restoreIndex rebuilds the index of the index restore of r from the data of
+// the index in the backup.
+func (r *restoreResumer) restoreIndex(
+	ctx context.Context,
+	p sql.JobExecContext,
+	backupCodec keys.SQLCodec,
+	backupManifests []backuppb.BackupManifest,
+	sqlDescs []catalog.Descriptor,
+	kmsEnv cloud.KMSEnv,
+) error {
+	table, published, err := r.prepareIndexRestore(ctx)
+	if err != nil {
+		return err
+	}
+	details := r.job.Details().(jobspb.RestoreDetails)
+	indexRestore := *details.IndexRestore
+	if !published {
+		var backupTable catalog.TableDescriptor
+		for _, desc := range sqlDescs {
+			if tbl, ok := desc.(catalog.TableDescriptor); ok {
+				backupTable = tbl
+			}
+		}
+		if backupTable == nil {
+			return errors.AssertionFailedf("table of index %q not found in the backup",
+				indexRestore.Index.Name)
+		}
+		data, err := indexRestorationData(p, backupCodec, backupTable, table, indexRestore)
+		if err != nil {
+			return err
+		}
+
+		numNodes, err := clusterNodeCount(p.ExecCfg().Gossip)
+		if err != nil {
+			log.Warningf(ctx, "unable to determine cluster node count: %v", err)
+			numNodes = 1
+		}
+		res, err := restoreWithRetry(ctx, p, numNodes, backupManifests, details.BackupLocalityInfo,
+			details.EndTime, data, r.job, details.Encryption, kmsEnv)
+		if err != nil {
+			return err
+		}
+		if err := validateRestoredIndex(ctx, p.ExecCfg(), r.job, table, indexRestore); err != nil {
+			return err
+		}
+		if err := r.publishRestoredIndex(ctx, indexRestore); err != nil {
+			return errors.Wrap(err, "publishing restored index")
+		}
+		r.restoreStats = res
+	}
+
+	emitRestoreJobEvent(ctx, p, jobs.StatusSucceeded, r.job)
+	telemetry.Count("restore.index.succeeded")
+	logJobCompletion(ctx, restoreJobEventType, r.job.ID(), true, nil)
+	return nil
+}
+
+// rollbackIndexRestore brings the table of a failed or canceled index restore
+// back online, and removes any data restored into the index the job allocated.
+func (r *restoreResumer) rollbackIndexRestore(
+	ctx context.Context, indexRestore jobspb.RestoreDetails_IndexRestore,
+) error {
+	return sql.DescsTxn(ctx, r.execCfg, func(
+		ctx context.Context, txn *kv.Txn, col *descs.Collection,
+	) error {
+		mut, err := col.GetMutableTableVersionByID(ctx, indexRestore.TableID, txn)
+		if err != nil {
+			return err
+		}
+		if indexRestore.Index.ID != 0 {
+			if _, err := mut.FindIndexWithID(indexRestore.Index.ID); err == nil {
+				// The restored index was published.
+				return nil
+			}
+			if err := r.createIndexGCJob(ctx, txn, mut.GetID(), indexRestore.Index.ID); err != nil {
+				return err
+			}
+		}
+		if !mut.Offline() || mut.GetOfflineReason() != indexRestoreOfflineReason {
+			return nil
+		}
+		mut.SetPublic()
+		return col.WriteDesc(ctx, false /* kvTrace */, mut, txn)
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreIndex(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 100
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE INDEX balance_idx ON data.bank (balance) STORING (payload)`)
+	sqlDB.Exec(t, `CREATE UNIQUE INDEX id_balance_idx ON data.bank (id, balance) WHERE id > 10`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+
+	const byBalance = `SELECT id, balance, payload FROM data.bank@balance_idx WHERE balance >= 0 ORDER BY id`
+	const byID = `SELECT id, balance FROM data.bank@id_balance_idx WHERE id > 10 ORDER BY id`
+	expBalance := sqlDB.QueryStr(t, byBalance)
+	expID := sqlDB.QueryStr(t, byID)
+
+	sqlDB.ExpectErr(t, "index \"missing_idx\" not found",
+		`RESTORE INDEX data.bank@missing_idx FROM LATEST IN $1`, localFoo)
+	sqlDB.ExpectErr(t, "cannot restore the primary index",
+		`RESTORE INDEX data.bank@bank_pkey FROM LATEST IN $1`, localFoo)
+	sqlDB.ExpectErr(t, "does not support the options",
+		`RESTORE INDEX data.bank@balance_idx FROM LATEST IN $1 WITH into_db = 'data2'`, localFoo)
+
+	// A dropped index is rebuilt from the backup.
+	sqlDB.Exec(t, `DROP INDEX data.bank@balance_idx`)
+	sqlDB.Exec(t, `RESTORE INDEX data.bank@balance_idx FROM LATEST IN $1`, localFoo)
+	sqlDB.CheckQueryResults(t, byBalance, expBalance)
+
+	// An existing index is replaced by the one rebuilt from the backup.
+	const indexID = `SELECT index_id FROM crdb_internal.table_indexes
+WHERE descriptor_name = 'bank' AND index_name = 'id_balance_idx'`
+	var oldIndexID, newIndexID int
+	sqlDB.QueryRow(t, indexID).Scan(&oldIndexID)
+	sqlDB.Exec(t, `RESTORE INDEX data.bank@id_balance_idx FROM LATEST IN $1`, localFoo)
+	sqlDB.CheckQueryResults(t, byID, expID)
+	sqlDB.QueryRow(t, indexID).Scan(&newIndexID)
+	require.NotEqual(t, oldIndexID, newIndexID)
+
+	// An index which does not match the rows of the table fails to restore, and
+	// the table is left as it was.
+	sqlDB.Exec(t, `DROP INDEX data.bank@balance_idx`)
+	sqlDB.Exec(t, `INSERT INTO data.bank VALUES (1000, 1, 'after')`)
+	sqlDB.ExpectErr(t, "does not match the rows of table",
+		`RESTORE INDEX data.bank@balance_idx FROM LATEST IN $1`, localFoo)
+	sqlDB.CheckQueryResults(t, `SELECT count(1) FROM data.bank`, [][]string{{"101"}})
+	sqlDB.ExpectErr(t, "index \"balance_idx\" not found",
+		`SELECT * FROM data.bank@balance_idx`)
+}
@@ -46,6 +46,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/nstree"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/rewrite"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/systemschema"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
@@ -54,6 +55,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
 	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/interval"
@@ -258,6 +260,8 @@ func restore(
 		highWaterMark     int
 		res               roachpb.RowCount
 		requestsCompleted []bool
+		numCompleted      int
+		ingest            restoreIngestTracker
 	}{
 		highWaterMark: -1,
 	}
@@ -278,10 +282,13 @@ func restore(
 
 	// Pivot the backups, which are grouped by time, into requests for import,
 	// which are grouped by keyrange.
-	highWaterMark := job.Progress().Details.(*jobspb.Progress_Restore).Restore.HighWater
+	restoreProgress := job.Progress().Details.(*jobspb.Progress_Restore).Restore
+	highWaterMark := restoreProgress.HighWater
+	priorCompletedSpans := restoreProgress.CompletedSpans
 
 	importSpans := makeSimpleImportSpans(dataToRestore.getSpans(), backupManifests,
 		backupLocalityMap, introducedSpanFrontier, highWaterMark, targetRestoreSpanSize.Get(execCtx.ExecCfg().SV()))
+	importSpans = skipCompletedImportSpans(importSpans, priorCompletedSpans)
 
 	if len(importSpans) == 0 {
 		// There are no files to restore.
@@ -292,6 +299,13 @@ func restore(
 		importSpans[i].ProgressIdx = int64(i)
 	}
 	mu.requestsCompleted = make([]bool, len(importSpans))
+	mu.ingest = makeRestoreIngestTracker(restoreProgress, timeutil.Now())
+
+	// Files in an archive storage tier cannot be read until they are retrieved,
+	// which can take hours, so wait for that before any data is ingested.
+	if err := waitForArchivedFiles(restoreCtx, execCtx, job, importSpans); err != nil {
+		return emptyRowCount, err
+	}
 
 	// TODO(pbardea): This not super principled. I just wanted something that
 	// wasn't a constant and grew slower than linear with the length of
@@ -320,13 +334,14 @@ func restore(
 
 	// tasks are the concurrent tasks that are run during the restore.
 	var tasks []func(ctx context.Context) error
+	var progressLogger *jobs.ChunkProgressLogger
 	if dataToRestore.isMainBundle() {
 		// Only update the job progress on the main data bundle. This should account
 		// for the bulk of the data to restore. Other data (e.g. zone configs in
 		// cluster restores) may be restored first. When restoring that data, we
 		// don't want to update the high-water mark key, so instead progress is just
 		// defined on the main data bundle (of which there should only be one).
-		progressLogger := jobs.NewChunkProgressLogger(job, len(importSpans), job.FractionCompleted(),
+		progressLogger = jobs.NewChunkProgressLogger(job, len(importSpans), job.FractionCompleted(),
 			func(progressedCtx context.Context, details jobspb.ProgressDetails) {
 				switch d := details.(type) {
 				case *jobspb.Progress_Restore:
@@ -334,18 +349,55 @@ func restore(
 					if mu.highWaterMark >= 0 {
 						d.Restore.HighWater = importSpans[mu.highWaterMark].Span.Key
 					}
+					d.Restore.CompletedSpans, d.Restore.NumRemainingSpans = importSpansAboveHighWater(
+						importSpans, mu.requestsCompleted, mu.highWaterMark, d.Restore.HighWater,
+						priorCompletedSpans)
+					mu.ingest.sample(timeutil.Now(), len(importSpans)-mu.numCompleted, d.Restore)
 					mu.Unlock()
 				default:
 					log.Errorf(progressedCtx, "job payload had unexpected type %T", d)
 				}
 			})
 
+		progressDone := make(chan struct{})
 		jobProgressLoop := func(ctx context.Context) error {
 			ctx, progressSpan := tracing.ChildSpan(ctx, "progress-log")
 			defer progressSpan.Finish()
+			defer close(progressDone)
 			return progressLogger.Loop(ctx, requestFinishedCh)
 		}
 		tasks = append(tasks, jobProgressLoop)
+
+		// The ETA estimated when progress is recorded is reported in the running
+		// status of the job, which SHOW JOBS and crdb_internal.jobs show.
+		etaStatusLoop := func(ctx context.Context) error {
+			ticker := time.NewTicker(restoreETAStatusInterval)
+			defer ticker.Stop()
+			var reported string
+			for {
+				select {
+				case <-progressDone:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-ticker.C:
+				}
+				mu.Lock()
+				status := mu.ingest.runningStatus(timeutil.Now())
+				mu.Unlock()
+				if status == "" || status == reported {
+					continue
+				}
+				if err := job.RunningStatus(ctx, nil /* txn */, func(_ context.Context, _ jobspb.Details) (jobs.RunningStatus, error) {
+					return jobs.RunningStatus(status), nil
+				}); err != nil {
+					log.Warningf(ctx, "failed to update the running status of the restore: %v", err)
+					continue
+				}
+				reported = status
+			}
+		}
+		tasks = append(tasks, etaStatusLoop)
 	}
 
 	jobCheckpointLoop := func(ctx context.Context) error {
@@ -369,7 +421,11 @@ func restore(
 					idx, progDetails.DataSpan, importSpans[idx],
 				)
 			}
+			if !mu.requestsCompleted[idx] {
+				mu.numCompleted++
+			}
 			mu.requestsCompleted[idx] = true
+			mu.ingest.spanIngested(progDetails.SQLInstanceID, progDetails.Summary.DataSize)
 			for j := mu.highWaterMark + 1; j < len(mu.requestsCompleted) && mu.requestsCompleted[j]; j++ {
 				mu.highWaterMark = j
 			}
@@ -383,25 +439,42 @@ func restore(
 	}
 	tasks = append(tasks, jobCheckpointLoop)
 
+	// The data files of backups relying on encryption-at-rest are not
+	// encrypted. Every backup in a chain agrees on it.
+	dataEncryption := encryption
+	if backupManifests[len(backupManifests)-1].EncryptionAtRestOnly {
+		dataEncryption = nil
+	}
+
 	runRestore := func(ctx context.Context) error {
+		ctx, sp := startJobPhaseSpan(ctx, restorePhaseIngest, job.ID(), details.URIs[0])
+		defer sp.Finish()
 		return distRestore(
 			ctx,
 			execCtx,
 			int64(job.ID()),
 			importSpanChunks,
 			dataToRestore.getPKIDs(),
-			encryption,
+			dataEncryption,
 			kmsEnv,
 			dataToRestore.getRekeys(),
 			dataToRestore.getTenantRekeys(),
 			endTime,
 			dataToRestore.isValidateOnly(),
+			details.ExecutionLocality,
 			progCh,
 		)
 	}
 	tasks = append(tasks, runRestore)
 
 	if err := ctxgroup.GoAndWait(restoreCtx, tasks...); err != nil {
+		if errors.Is(err, sql.ErrPlanChanged) && progressLogger != nil {
+			// Record the spans imported so far before the flow is re-planned, so
+			// that the next one does not import them again.
+			if err := progressLogger.Flush(restoreCtx); err != nil {
+				log.Warningf(restoreCtx, "unable to record restore progress before re-planning: %+v", err)
+			}
+		}
 		// This leaves the data that did get imported in case the user wants to
 		// retry.
 		// TODO(dan): Build tooling to allow a user to restart a failed restore.
@@ -411,6 +484,63 @@ func restore(
 	return mu.res, nil
 }
 
+// skipCompletedImportSpans removes the import spans that are enclosed by the
+// spans an earlier attempt of the restore recorded as completed.
+func skipCompletedImportSpans(
+	importSpans []execinfrapb.RestoreSpanEntry, completed []roachpb.Span,
+) []execinfrapb.RestoreSpanEntry {
+	if len(completed) == 0 {
+		return importSpans
+	}
+	var g roachpb.SpanGroup
+	g.Add(completed...)
+	remaining := importSpans[:0]
+	for _, entry := range importSpans {
+		if !g.Encloses(entry.Span) {
+			remaining = append(remaining, entry)
+		}
+	}
+	return remaining
+}
+
+// importSpansAboveHighWater returns the spans above highWater that have been
+// restored, either by this attempt of the restore or by an earlier one, and the
+// number of import spans above it that have not.
+func importSpansAboveHighWater(
+	importSpans []execinfrapb.RestoreSpanEntry,
+	requestsCompleted []bool,
+	highWaterMark int,
+	highWater roachpb.Key,
+	priorCompleted []roachpb.Span,
+) ([]roachpb.Span, int64) {
+	var completed []roachpb.Span
+	for _, sp := range priorCompleted {
+		if highWater.Compare(sp.EndKey) < 0 {
+			completed = append(completed, sp)
+		}
+	}
+	var remaining int64
+	for i := highWaterMark + 1; i < len(importSpans); i++ {
+		if requestsCompleted[i] {
+			completed = append(completed, importSpans[i].Span)
+		} else {
+			remaining++
+		}
+	}
+	completed, _ = roachpb.MergeSpans(&completed)
+	return completed, remaining
+}
+
+// manifestURIs returns the locations of the manifests of the backups being
+// restored, which are those of their data unless they were resolved in a
+// metadata collection.
+func manifestURIs(details jobspb.RestoreDetails) []string {
+	if len(details.MetadataURIs) > 0 {
+		return details.MetadataURIs
+	}
+	return details.URIs
+}
+
 // loadBackupSQLDescs extracts the backup descriptors, the latest backup
 // descriptor, and all the Descriptors for a backup to be restored. It upgrades
 // the table descriptors to the new FK representation if necessary. FKs that
@@ -428,7 +558,7 @@ func loadBackupSQLDescs(
 	encryption *jobspb.BackupEncryptionOptions,
 	kmsEnv cloud.KMSEnv,
 ) ([]backuppb.BackupManifest, backuppb.BackupManifest, []catalog.Descriptor, int64, error) {
-	backupManifests, sz, err := backupinfo.LoadBackupManifestsAtTime(ctx, mem, details.URIs,
+	backupManifests, sz, err := backupinfo.LoadBackupManifestsAtTime(ctx, mem, manifestURIs(details),
 		p.User(), p.ExecCfg().DistSQLSrv.ExternalStorageFromURI, encryption, kmsEnv, details.EndTime)
 	if err != nil {
 		return nil, backuppb.BackupManifest{}, nil, 0, err
@@ -454,7 +584,12 @@ func loadBackupSQLDescs(
 				desc.SetRegionConfig(m.RegionConfig)
 			}
 		}
-		if _, ok := details.DescriptorRewrites[id]; ok {
+		if rw, ok := details.DescriptorRewrites[id]; ok {
+			// Tables rewritten to existing tables are only referenced by the foreign
+			// keys of the restored tables.
+			if _, isTable := desc.(catalog.TableDescriptor); isTable && rw.ToExisting {
+				continue
+			}
 			sqlDescs = append(sqlDescs, desc)
 		}
 	}
@@ -464,6 +599,11 @@ func loadBackupSQLDescs(
 		return nil, backuppb.BackupManifest{}, nil, 0, err
 	}
 
+	if err := remapRegions(sqlDescs, details.RegionRemapping); err != nil {
+		mem.Shrink(ctx, sz)
+		return nil, backuppb.BackupManifest{}, nil, 0, err
+	}
+
 	return backupManifests, latestBackupManifest, sqlDescs, sz, nil
 }
 
@@ -592,6 +732,66 @@ func isSchemaEmpty(
 	return true, nil
 }
 
+// makeRestoreRekeys returns the rekeys with which the data of the backup,
+// encoded with backupCodec, is rewritten into tables, whose IDs in the backup
+// are oldTableIDs.
+func makeRestoreRekeys(
+	p sql.JobExecContext,
+	backupCodec keys.SQLCodec,
+	tables []catalog.TableDescriptor,
+	oldTableIDs []descpb.ID,
+) ([]execinfrapb.TableRekey, []execinfrapb.TenantRekey, error) {
+	// Get TableRekeys to use when importing raw data.
+	var rekeys []execinfrapb.TableRekey
+	for i := range tables {
+		tableToSerialize := tables[i]
+		newDescBytes, err := protoutil.Marshal(tableToSerialize.DescriptorProto())
+		if err != nil {
+			return nil, nil, errors.NewAssertionErrorWithWrappedErrf(err,
+				"marshaling descriptor")
+		}
+		rekeys = append(rekeys, execinfrapb.TableRekey{
+			OldID:   uint32(oldTableIDs[i]),
+			NewDesc: newDescBytes,
+		})
+	}
+
+	_, backupTenantID, err := keys.DecodeTenantPrefix(backupCodec.TenantPrefix())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !backupCodec.TenantPrefix().Equal(p.ExecCfg().Codec.TenantPrefix()) {
+		// Ensure old processors fail if this is a previously unsupported restore of
+		// a tenant backup by the system tenant, which the old rekey processor would
+		// mishandle since it assumed the system tenant always restored tenant keys
+		// to tenant prefixes, i.e. as tenant restore.
+		if backupTenantID != roachpb.SystemTenantID && p.ExecCfg().Codec.ForSystemTenant() {
+			// This empty table rekey acts as a poison-pill, which will be ignored by
+			// a current processor but reliably cause an older processor, which would
+			// otherwise mishandle tenant-made backup keys, to fail as it will be
+			// unable to decode the zero ID table desc.
+			rekeys = append(rekeys, execinfrapb.TableRekey{})
+		}
+	}
+
+	// If, and only if, the backup was made by a system tenant, can it contain
+	// backed up tenants, which the processor needs to know when is rekeying -- if
+	// the backup contains tenants, then a key with a tenant prefix should be
+	// restored if, and only if, we're restoring that tenant, and restored to a
+	// tenant. Otherwise, if this backup was not made by a system tenant, it does
+	// not contain tenants, so the rekey will assume if a key has a tenant prefix,
+	// it is because the tenant produced the backup, and it should be removed to
+	// then decode the remainder of the key. We communicate this distinction to
+	// the processor with a special tenant rekey _into_ the system tenant, which
+	// would never otherwise be valid. It will discard this rekey but it signals
+	// to it that we're rekeying a system-made backup.
+	var tenantRekeys []execinfrapb.TenantRekey
+	if backupTenantID == roachpb.SystemTenantID {
+		tenantRekeys = append(tenantRekeys, isBackupFromSystemTenantRekey)
+	}
+	return rekeys, tenantRekeys, nil
+}
+
 // spansForAllRestoreTableIndexes returns non-overlapping spans for every index
 // and table passed in. They would normally overlap if any of them are
 // interleaved.
@@ -747,6 +947,10 @@ func createImportingDescriptors(
 
 	preRestoreTables := make([]catalog.TableDescriptor, 0)
 
+	// droppedColumns holds the columns of the table restored with the columns
+	// option which are not restored, by its ID in the backup.
+	droppedColumns := make(map[descpb.ID]catalog.TableColSet)
+
 	for _, desc := range sqlDescs {
 		// Decide which offline tables to include in the restore:
 		//
@@ -771,6 +975,15 @@ func createImportingDescriptors(
 		switch desc := desc.(type) {
 		case catalog.TableDescriptor:
 			mut := tabledesc.NewBuilder(desc.TableDesc()).BuildCreatedMutableTable()
+			if len(details.ProjectedColumns) > 0 {
+				// The table is projected before the spans of its indexes are computed
+				// so that the data of the indexes it loses is not read.
+				dropped, err := projectRestoredTable(mut, details.ProjectedColumns)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				droppedColumns[mut.GetID()] = dropped
+			}
 			if shouldPreRestore(mut) {
 				preRestoreTables = append(preRestoreTables, mut)
 			} else {
@@ -819,6 +1032,12 @@ func createImportingDescriptors(
 		// verify_backup_table_data RESTORE
 		verifySpans = spansForAllRestoreTableIndexes(backupCodec, postRestoreTables, nil, false)
 	}
+	if details.RowFilter != "" {
+		// A backup taken with a row filter only holds the parts of the spans of
+		// its table that the filter selects.
+		postRestoreSpans = restrictSpansToRowFilter(postRestoreSpans, details.RowFilterSpans)
+		verifySpans = restrictSpansToRowFilter(verifySpans, details.RowFilterSpans)
+	}
 
 	log.Eventf(ctx, "starting restore for %d tables", len(mutableTables))
 
@@ -900,14 +1119,10 @@ func createImportingDescriptors(
 		return nil, nil, nil, err
 	}
 
-	// TODO(chengxiong): for now, we know that functions are not referenced by any
-	// other objects, so that function descriptors are only restored when
-	// restoring databases. This means that all function descriptors are not
-	// remaps. Which means that we don't need resolve collisions between functions
-	// being restored and existing functions in target DB However, this won't be
-	// true when we start supporting udf references from other objects. For
-	// example, we need extra logic to handle remaps for udfs used by a table when
-	// backup/restore is on table level.
+	// Functions are always written from the backup: unlike types, they are
+	// never remapped to existing functions. A function restored along with the
+	// tables it depends on into an existing schema has its signature added to
+	// that schema once the descriptors are written.
 	functionsToWrite := make([]*funcdesc.Mutable, len(functions))
 	writtenFunctions := make([]catalog.FunctionDescriptor, len(functions))
 	for i, fn := range functions {
@@ -1044,6 +1259,14 @@ func createImportingDescriptors(
 				}
 			}
 
+			// Restored tables could have foreign keys that reference existing tables,
+			// which need back references to them.
+			if err := addExistingTableFKBackReferences(
+				ctx, txn, descsCol, mutableTables, &details,
+			); err != nil {
+				return err
+			}
+
 			// Write the new descriptors which are set in the OFFLINE state.
 			if err := ingesting.WriteDescriptors(
 				ctx, p.ExecCfg().Codec, txn, p.User(), descsCol,
@@ -1126,6 +1349,48 @@ func createImportingDescriptors(
 					}
 				}
 			}
+
+			// Functions restored into existing schemas need their signatures added
+			// to those schemas, and the existing types they use need back references
+			// to them, as the schemas and types written from the backup already
+			// have them.
+			writtenSchemaIDs := make(map[descpb.ID]struct{}, len(writtenSchemas))
+			for _, sc := range writtenSchemas {
+				writtenSchemaIDs[sc.GetID()] = struct{}{}
+			}
+			for _, fn := range functionsToWrite {
+				if _, ok := writtenSchemaIDs[fn.GetParentSchemaID()]; !ok {
+					sc, err := descsCol.GetMutableSchemaByID(ctx, txn, fn.GetParentSchemaID(), tree.SchemaLookupFlags{
+						Required:       true,
+						AvoidLeased:    true,
+						IncludeOffline: true,
+					})
+					if err != nil {
+						return err
+					}
+					sc.AddFunction(fn.GetName(), functionOverloadSignature(fn))
+					if err := descsCol.WriteDescToBatch(
+						ctx, false /* kvTrace */, sc, b,
+					); err != nil {
+						return err
+					}
+				}
+				for _, id := range fn.GetDependsOnTypes() {
+					if _, ok := existingTypeIDs[id]; !ok {
+						continue
+					}
+					typDesc, err := descsCol.GetMutableTypeVersionByID(ctx, txn, id)
+					if err != nil {
+						return err
+					}
+					typDesc.AddReferencingDescriptorID(fn.GetID())
+					if err := descsCol.WriteDescToBatch(
+						ctx, false /* kvTrace */, typDesc, b,
+					); err != nil {
+						return err
+					}
+				}
+			}
 			if err := txn.Run(ctx, b); err != nil {
 				return err
 			}
@@ -1240,55 +1505,16 @@ func createImportingDescriptors(
 		}
 	}
 
-	// Get TableRekeys to use when importing raw data.
-	var rekeys []execinfrapb.TableRekey
-	for i := range tables {
-		tableToSerialize := tables[i]
-		newDescBytes, err := protoutil.Marshal(tableToSerialize.DescriptorProto())
-		if err != nil {
-			return nil, nil, nil, errors.NewAssertionErrorWithWrappedErrf(err,
-				"marshaling descriptor")
-		}
-		rekeys = append(rekeys, execinfrapb.TableRekey{
-			OldID:   uint32(oldTableIDs[i]),
-			NewDesc: newDescBytes,
-		})
-	}
-
-	_, backupTenantID, err := keys.DecodeTenantPrefix(backupCodec.TenantPrefix())
+	rekeys, tenantRekeys, err := makeRestoreRekeys(p, backupCodec, tables, oldTableIDs)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	if !backupCodec.TenantPrefix().Equal(p.ExecCfg().Codec.TenantPrefix()) {
-		// Ensure old processors fail if this is a previously unsupported restore of
-		// a tenant backup by the system tenant, which the old rekey processor would
-		// mishandle since it assumed the system tenant always restored tenant keys
-		// to tenant prefixes, i.e. as tenant restore.
-		if backupTenantID != roachpb.SystemTenantID && p.ExecCfg().Codec.ForSystemTenant() {
-			// This empty table rekey acts as a poison-pill, which will be ignored by
-			// a current processor but reliably cause an older processor, which would
-			// otherwise mishandle tenant-made backup keys, to fail as it will be
-			// unable to decode the zero ID table desc.
-			rekeys = append(rekeys, execinfrapb.TableRekey{})
+	for i := range rekeys {
+		if dropped, ok := droppedColumns[descpb.ID(rekeys[i].OldID)]; ok {
+			rekeys[i].DroppedColumnIDs = dropped.Ordered()
 		}
 	}
 
-	// If, and only if, the backup was made by a system tenant, can it contain
-	// backed up tenants, which the processor needs to know when is rekeying -- if
-	// the backup contains tenants, then a key with a tenant prefix should be
-	// restored if, and only if, we're restoring that tenant, and restored to a
-	// tenant. Otherwise, if this backup was not made by a system tenant, it does
-	// not contain tenants, so the rekey will assume if a key has a tenant prefix,
-	// it is because the tenant produced the backup, and it should be removed to
-	// then decode the remainder of the key. We communicate this distinction to
-	// the processor with a special tenant rekey _into_ the system tenant, which
-	// would never otherwise be valid. It will discard this rekey but it signals
-	// to it that we're rekeying a system-made backup.
-	var tenantRekeys []execinfrapb.TenantRekey
-	if backupTenantID == roachpb.SystemTenantID {
-		tenantRekeys = append(tenantRekeys, isBackupFromSystemTenantRekey)
-	}
-
 	pkIDs := make(map[uint64]bool)
 	for _, tbl := range tables {
 		pkIDs[roachpb.BulkOpSummaryID(uint64(tbl.GetID()), uint64(tbl.GetPrimaryIndexID()))] = true
@@ -1417,6 +1643,7 @@ func remapPublicSchemas(
 // Resume is part of the jobs.Resumer interface.
 func (r *restoreResumer) Resume(ctx context.Context, execCtx interface{}) error {
 	if err := r.doResume(ctx, execCtx); err != nil {
+		err = redactJobError(err)
 		details := r.job.Details().(jobspb.RestoreDetails)
 		if details.DebugPauseOn == "error" {
 			const errorFmt = "job failed with error (%v) but is being paused due to the %s=%s setting"
@@ -1428,6 +1655,7 @@ func (r *restoreResumer) Resume(ctx context.Context, execCtx interface{}) error
 		}
 		return err
 	}
+	r.unprotectCollectionLayers(ctx, execCtx.(sql.JobExecContext), r.job.Details().(jobspb.RestoreDetails))
 
 	return nil
 }
@@ -1450,9 +1678,22 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 
 	kmsEnv := backupencryption.MakeBackupKMSEnv(p.ExecCfg().Settings, &p.ExecCfg().ExternalIODirConfig,
 		p.ExecCfg().DB, p.User(), p.ExecCfg().InternalExecutor)
+	resolveCtx, resolveSpan := startJobPhaseSpan(ctx, restorePhaseResolveBackups, r.job.ID(), details.URIs[0])
+	if details.DeferredLayerResolution != nil {
+		if err := r.resolveDeferredLayers(resolveCtx, p, &mem, &kmsEnv); err != nil {
+			resolveSpan.Finish()
+			return err
+		}
+		details = r.job.Details().(jobspb.RestoreDetails)
+	}
+	if err := r.protectCollectionLayers(resolveCtx, p, details); err != nil {
+		resolveSpan.Finish()
+		return err
+	}
 	backupManifests, latestBackupManifest, sqlDescs, memSize, err := loadBackupSQLDescs(
-		ctx, &mem, p, details, details.Encryption, &kmsEnv,
+		resolveCtx, &mem, p, details, details.Encryption, &kmsEnv,
 	)
+	resolveSpan.Finish()
 	if err != nil {
 		return err
 	}
@@ -1478,7 +1719,7 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 	if err != nil {
 		return err
 	}
-	defaultConf, err := cloud.ExternalStorageConfFromURI(details.URIs[lastBackupIndex], p.User())
+	defaultConf, err := cloud.ExternalStorageConfFromURI(manifestURIs(details)[lastBackupIndex], p.User())
 	if err != nil {
 		return errors.Wrapf(err, "creating external store configuration")
 	}
@@ -1486,6 +1727,17 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 	if err != nil {
 		return err
 	}
+	if details.DeferredDataOf != 0 {
+		// The descriptors were created and published by the schema_only restore
+		// which created this job, which only loads their data.
+		return r.restoreDeferredData(ctx, p, backupCodec, backupManifests, latestBackupManifest,
+			sqlDescs, defaultStore, &kmsEnv)
+	}
+	if details.IndexRestore != nil {
+		// The data of the index is restored into an existing table, which the job
+		// takes offline while it runs.
+		return r.restoreIndex(ctx, p, backupCodec, backupManifests, sqlDescs, &kmsEnv)
+	}
 	preData, preValidateData, mainData, err := createImportingDescriptors(ctx, p, backupCodec, sqlDescs, r)
 	if err != nil {
 		return err
@@ -1506,6 +1758,9 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 	if err == nil {
 		remappedStats = remapRelevantStatistics(ctx, backupStats, details.DescriptorRewrites,
 			details.TableDescs)
+		if len(details.ProjectedColumns) > 0 {
+			remappedStats = filterProjectedStatistics(remappedStats, details.TableDescs)
+		}
 	} else {
 		// We don't want to fail the restore if we are unable to resolve statistics
 		// from the backup, since they can be recomputed after the restore has
@@ -1523,10 +1778,20 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 		// public.
 		// TODO (lucy): Ideally we'd just create the database in the public state in
 		// the first place, as a special case.
-		publishDescriptors := func(ctx context.Context, txn *kv.Txn, descsCol *descs.Collection) (err error) {
-			return r.publishDescriptors(ctx, txn, p.ExecCfg(), p.User(), descsCol, details, nil)
+		publishDescriptors := func(
+			ctx context.Context, txn *kv.Txn, descsCol *descs.Collection, ie sqlutil.InternalExecutor,
+		) (err error) {
+			return r.publishDescriptors(ctx, txn, ie, p.ExecCfg(), p.User(), descsCol, details, nil)
 		}
-		if err := sql.DescsTxn(ctx, r.execCfg, publishDescriptors); err != nil {
+		if err := r.execCfg.InternalExecutorFactory.DescsTxnWithExecutor(
+			ctx, r.execCfg.DB, p.SessionData(), publishDescriptors,
+		); err != nil {
+			return err
+		}
+		if err := restoreCommentsAndZoneConfigs(ctx, p.ExecCfg(), details, latestBackupManifest); err != nil {
+			return err
+		}
+		if err := commentRowFilteredTables(ctx, p.ExecCfg(), details); err != nil {
 			return err
 		}
 
@@ -1656,11 +1921,15 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 		devalidateIndexes = bad
 	}
 
-	publishDescriptors := func(ctx context.Context, txn *kv.Txn, descsCol *descs.Collection) (err error) {
-		err = r.publishDescriptors(ctx, txn, p.ExecCfg(), p.User(), descsCol, details, devalidateIndexes)
+	publishDescriptors := func(
+		ctx context.Context, txn *kv.Txn, descsCol *descs.Collection, ie sqlutil.InternalExecutor,
+	) (err error) {
+		err = r.publishDescriptors(ctx, txn, ie, p.ExecCfg(), p.User(), descsCol, details, devalidateIndexes)
 		return err
 	}
-	if err := sql.DescsTxn(ctx, p.ExecCfg(), publishDescriptors); err != nil {
+	if err := p.ExecCfg().InternalExecutorFactory.DescsTxnWithExecutor(
+		ctx, p.ExecCfg().DB, p.SessionData(), publishDescriptors,
+	); err != nil {
 		return err
 	}
 
@@ -1678,6 +1947,16 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 	details = r.job.Details().(jobspb.RestoreDetails)
 	p.ExecCfg().JobRegistry.NotifyToAdoptJobs()
 
+	if err := restoreCommentsAndZoneConfigs(ctx, p.ExecCfg(), details, latestBackupManifest); err != nil {
+		return err
+	}
+	if err := restoreRowLevelTTLSchedules(ctx, p.ExecCfg(), details, latestBackupManifest); err != nil {
+		return err
+	}
+	if err := commentRowFilteredTables(ctx, p.ExecCfg(), details); err != nil {
+		return err
+	}
+
 	if details.DescriptorCoverage == tree.AllDescriptors {
 		// We restore the system tables from the main data bundle so late because it
 		// includes the jobs that are being restored. As soon as we restore these
@@ -1686,6 +1965,9 @@ func (r *restoreResumer) doResume(ctx context.Context, execCtx interface{}) erro
 		if err := r.restoreSystemTables(ctx, p.ExecCfg().DB, mainData.systemTables); err != nil {
 			return err
 		}
+		if err := r.restoreJobs(ctx, p.ExecCfg(), latestBackupManifest); err != nil {
+			return err
+		}
 		// Reload the details as we may have updated the job.
 		details = r.job.Details().(jobspb.RestoreDetails)
 
@@ -1915,6 +2197,9 @@ func insertStats(
 		// Only insert table stats from the backup manifest if actual data was restored.
 		return nil
 	}
+	if details.SkipStatistics {
+		return nil
+	}
 	if details.StatsInserted {
 		return nil
 	}
@@ -1956,13 +2241,15 @@ func insertStats(
 }
 
 // publishDescriptors updates the RESTORED descriptors' status from OFFLINE to
-// PUBLIC. The schema change jobs are returned to be started after the
-// transaction commits. The details struct is passed in rather than loaded
-// from r.job as the call to r.job.SetDetails will overwrite the job details
-// with a new value even if this transaction does not commit.
+// PUBLIC, and swaps in those that replace existing descriptors. The schema
+// change jobs are returned to be started after the transaction commits. The
+// details struct is passed in rather than loaded from r.job as the call to
+// r.job.SetDetails will overwrite the job details with a new value even if
+// this transaction does not commit.
 func (r *restoreResumer) publishDescriptors(
 	ctx context.Context,
 	txn *kv.Txn,
+	ie sqlutil.InternalExecutor,
 	execCfg *sql.ExecutorConfig,
 	user username.SQLUsername,
 	descsCol *descs.Collection,
@@ -2034,6 +2321,9 @@ func (r *restoreResumer) publishDescriptors(
 				return err
 			}
 		}
+		if details.DeferConstraintValidation {
+			deferConstraintValidation(mutTable)
+		}
 		version := r.settings.Version.ActiveVersion(ctx)
 		if err := mutTable.AllocateIDs(ctx, version); err != nil {
 			return err
@@ -2104,6 +2394,10 @@ func (r *restoreResumer) publishDescriptors(
 		return errors.Wrap(err, "publishing tables")
 	}
 
+	if err := replaceExistingDescriptors(ctx, txn, descsCol, ie, details.ReplacedDescriptors); err != nil {
+		return err
+	}
+
 	for _, tenant := range details.Tenants {
 		switch tenant.State {
 		case descpb.TenantInfo_ACTIVE:
@@ -2128,6 +2422,11 @@ func (r *restoreResumer) publishDescriptors(
 	details.SchemaDescs = newSchemas
 	details.DatabaseDescs = newDBs
 	details.FunctionDescs = newFunctions
+	if details.DeferredData && len(newTables) > 0 {
+		if err := r.createDeferredDataJob(ctx, txn, details); err != nil {
+			return err
+		}
+	}
 	if err := r.job.SetDetails(ctx, txn, details); err != nil {
 		return errors.Wrap(err,
 			"updating job details after publishing tables")
@@ -2209,7 +2508,8 @@ func emitRestoreJobEvent(
 // change stuff to delete the keys in the background.
 func (r *restoreResumer) OnFailOrCancel(
 	ctx context.Context, execCtx interface{}, jobErr error,
-) error {
+) (retErr error) {
+	defer func() { retErr = redactJobError(retErr) }()
 	p := execCtx.(sql.JobExecContext)
 	r.execCfg = p.ExecCfg()
 
@@ -2222,6 +2522,23 @@ func (r *restoreResumer) OnFailOrCancel(
 
 	details := r.job.Details().(jobspb.RestoreDetails)
 	logJobCompletion(ctx, restoreJobEventType, r.job.ID(), false, jobErr)
+	r.unprotectCollectionLayers(ctx, p, details)
+
+	if details.DeferredDataOf != 0 {
+		// The restored descriptors were published by the schema_only restore and
+		// may be in use, so they are left in place.
+		emitRestoreJobEvent(ctx, p, jobs.StatusFailed, r.job)
+		return nil
+	}
+	if details.IndexRestore != nil {
+		// The table of the index existed before the job, so it is only brought
+		// back online.
+		if err := r.rollbackIndexRestore(ctx, *details.IndexRestore); err != nil {
+			return err
+		}
+		emitRestoreJobEvent(ctx, p, jobs.StatusFailed, r.job)
+		return nil
+	}
 
 	execCfg := execCtx.(sql.JobExecContext).ExecCfg()
 	if err := execCfg.InternalExecutorFactory.DescsTxnWithExecutor(ctx, execCfg.DB, p.SessionData(), func(
@@ -2319,6 +2636,14 @@ func (r *restoreResumer) dropDescriptors(
 		return err
 	}
 
+	// Remove any foreign key back references installed from existing tables to
+	// tables being restored.
+	if err := removeExistingTableFKBackReferences(
+		ctx, txn, descsCol, b, mutableTables,
+	); err != nil {
+		return err
+	}
+
 	// Drop the table descriptors that were created at the start of the restore.
 	tablesToGC := make([]descpb.ID, 0, len(details.TableDescs))
 	// Set the drop time as 1 (ns in Unix time), so that the table gets GC'd
@@ -2374,6 +2699,14 @@ func (r *restoreResumer) dropDescriptors(
 		descsCol.NotifyOfDeletedDescriptor(mutType.GetID())
 	}
 
+	restoredSchemaIDs := make(map[descpb.ID]struct{}, len(details.SchemaDescs))
+	for _, sc := range details.SchemaDescs {
+		restoredSchemaIDs[sc.ID] = struct{}{}
+	}
+	restoredTypeIDs := make(map[descpb.ID]struct{}, len(details.TypeDescs))
+	for _, typ := range details.TypeDescs {
+		restoredTypeIDs[typ.ID] = struct{}{}
+	}
 	for i := range details.FunctionDescs {
 		fnDesc := details.FunctionDescs[i]
 		mutFn, err := descsCol.GetMutableFunctionByID(ctx, txn, fnDesc.ID, tree.ObjectLookupFlags{
@@ -2388,6 +2721,36 @@ func (r *restoreResumer) dropDescriptors(
 		mutFn.SetDropped()
 		b.Del(catalogkeys.MakeDescMetadataKey(codec, fnDesc.ID))
 		descsCol.NotifyOfDeletedDescriptor(fnDesc.ID)
+
+		// Remove the signature and back references the function was given in
+		// the existing schema and types it was restored into.
+		if _, ok := restoredSchemaIDs[fnDesc.ParentSchemaID]; !ok {
+			sc, err := descsCol.GetMutableSchemaByID(ctx, txn, fnDesc.ParentSchemaID, tree.SchemaLookupFlags{
+				Required:       true,
+				AvoidLeased:    true,
+				IncludeOffline: true,
+			})
+			if err != nil {
+				return err
+			}
+			sc.RemoveFunction(fnDesc.Name, fnDesc.ID)
+			if err := descsCol.WriteDescToBatch(ctx, false /* kvTrace */, sc, b); err != nil {
+				return err
+			}
+		}
+		for _, id := range fnDesc.DependsOnTypes {
+			if _, ok := restoredTypeIDs[id]; ok {
+				continue
+			}
+			typDesc, err := descsCol.GetMutableTypeVersionByID(ctx, txn, id)
+			if err != nil {
+				return err
+			}
+			typDesc.RemoveReferencingDescriptorID(fnDesc.ID)
+			if err := descsCol.WriteDescToBatch(ctx, false /* kvTrace */, typDesc, b); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Queue a GC job.
@@ -2571,6 +2934,21 @@ func (r *restoreResumer) dropDescriptors(
 	return nil
 }
 
+// functionOverloadSignature returns the signature of a function that is
+// stored in its parent schema.
+func functionOverloadSignature(fn *funcdesc.Mutable) descpb.SchemaDescriptor_FunctionOverload {
+	ret := descpb.SchemaDescriptor_FunctionOverload{
+		ID:         fn.GetID(),
+		ArgTypes:   make([]*types.T, len(fn.Args)),
+		ReturnType: fn.ReturnType.Type,
+		ReturnSet:  fn.ReturnType.ReturnSet,
+	}
+	for i := range fn.Args {
+		ret.ArgTypes[i] = fn.Args[i].Type
+	}
+	return ret
+}
+
 // removeExistingTypeBackReferences removes back references from types that
 // exist in the cluster to tables restored. It is used when rolling back from
 // a failed restore.
@@ -2740,6 +3118,11 @@ func (r *restoreResumer) restoreSystemTables(
 		systemTableName := table.GetName()
 		stagingTableName := restoreTempSystemDB + "." + systemTableName
 
+		if (details.SkipComments && systemTableName == systemschema.CommentsTable.GetName()) ||
+			(details.SkipZoneConfigs && systemTableName == systemschema.ZonesTable.GetName()) {
+			continue
+		}
+
 		config, ok := systemTableBackupConfiguration[systemTableName]
 		if !ok {
 			log.Warningf(ctx, "no configuration specified for table %s... skipping restoration",
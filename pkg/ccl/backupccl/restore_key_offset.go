@@ -0,0 +1,81 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descidgen"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/errors"
+)
+
+// A restore run with the key_offset option restores the descriptors of the
+// backup under their IDs shifted by the offset, rather than under newly
+// generated ones, and a tenant under its ID shifted by the offset. Since the
+// keys of a table or tenant are prefixed by its ID, the same backup can be
+// restored side by side into predictable, distinct key spans of one cluster,
+// e.g. to compare them with each other or with the spans the backup was taken
+// of. It is meant for testing, and requires the admin role.
+
+// parseRestoreKeyOffset parses the value of the key_offset option.
+func parseRestoreKeyOffset(s string) (descpb.ID, error) {
+	offset, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || offset == 0 {
+		return descpb.InvalidID, errors.Newf("%q must be a positive integer, got %q", restoreOptKeyOffset, s)
+	}
+	return descpb.ID(offset), nil
+}
+
+// reserveOffsetDescIDs reserves the IDs the descriptors with the given IDs are
+// restored under with a key_offset of offset. Shifted, the IDs must not have
+// been generated yet, and the descriptor ID generator is advanced past them so
+// that they never will be.
+func reserveOffsetDescIDs(
+	ctx context.Context, execCfg *sql.ExecutorConfig, offset descpb.ID, ids []descpb.ID,
+) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	minID, maxID := ids[0], ids[0]
+	for _, id := range ids[1:] {
+		if id < minID {
+			minID = id
+		}
+		if id > maxID {
+			maxID = id
+		}
+	}
+
+	next, err := descidgen.PeekNextUniqueDescID(ctx, execCfg.DB, execCfg.Codec)
+	if err != nil {
+		return err
+	}
+	if minID+offset < next {
+		return errors.WithHintf(
+			errors.Newf("%s of %d would restore descriptor %d under ID %d, which may already be in use",
+				restoreOptKeyOffset, offset, minID, minID+offset),
+			"use an offset of at least %d", next-minID)
+	}
+	delta := int64(maxID + offset + 1 - next)
+	newVal, err := kv.IncrementValRetryable(ctx, execCfg.DB, execCfg.Codec.DescIDSequenceKey(), delta)
+	if err != nil {
+		return err
+	}
+	// IDs may have been generated since the generator was peeked at, in which
+	// case the reserved ones may overlap them.
+	if descpb.ID(newVal-delta) > minID+offset {
+		return errors.Newf("descriptor IDs were generated concurrently with reserving those of %s",
+			restoreOptKeyOffset)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreKeyOffset(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+	var bankID int
+	sqlDB.QueryRow(t, `SELECT 'data.bank'::REGCLASS::INT`).Scan(&bankID)
+
+	sqlDB.ExpectErr(t, "must be a positive integer",
+		`RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = 'data2', key_offset = 'x'`, localFoo)
+	sqlDB.ExpectErr(t, "cannot be used with a cluster restore",
+		`RESTORE FROM LATEST IN $1 WITH key_offset = '1000'`, localFoo)
+	sqlDB.ExpectErr(t, "may already be in use",
+		`RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = 'data2', key_offset = '1'`, localFoo)
+
+	// The same backup is restored side by side under different offsets, into
+	// the key spans of the tables with the shifted IDs.
+	fingerprint := sqlDB.QueryStr(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE data.bank`)
+	for _, offset := range []int{1000, 2000} {
+		db := fmt.Sprintf("data_%d", offset)
+		sqlDB.Exec(t, fmt.Sprintf(`RESTORE DATABASE data FROM LATEST IN $1 WITH new_db_name = '%s', key_offset = '%d'`,
+			db, offset), localFoo)
+		sqlDB.CheckQueryResults(t, fmt.Sprintf(`SELECT '%s.bank'::REGCLASS::INT`, db),
+			[][]string{{fmt.Sprint(bankID + offset)}})
+		sqlDB.CheckQueryResults(t, fmt.Sprintf(`SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE %s.bank`, db), fingerprint)
+	}
+
+	// Descriptors created after the restores are given IDs past the ones they
+	// reserved.
+	sqlDB.Exec(t, `CREATE TABLE data.after (k INT PRIMARY KEY)`)
+	var afterID int
+	sqlDB.QueryRow(t, `SELECT 'data.after'::REGCLASS::INT`).Scan(&afterID)
+	require.Greater(t, afterID, bankID+2000)
+}
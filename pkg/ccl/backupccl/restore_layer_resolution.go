@@ -0,0 +1,242 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+var deferredLayerResolutionMinLayers = settings.RegisterIntSetting(
+	settings.TenantWritable,
+	"bulkio.restore.deferred_layer_resolution.min_layers",
+	"the number of layers of a backup chain from which a detached restore of it leaves reading "+
+		"the manifests of its layers to the job, rather than the statement; 0 disables this",
+	100,
+	settings.NonNegativeInt,
+).WithPublic()
+
+// deferredLayerResolutionCheckpointInterval is how often a restore resolving
+// the layers of its backup chain records the layers it has resolved so far.
+const deferredLayerResolutionCheckpointInterval = 10 * time.Second
+
+// checkBackupVersion returns an error if the backup was written by a cluster
+// more migrated than this one, which then isn't ready to restore it.
+func checkBackupVersion(
+	currentVersion clusterversion.ClusterVersion, m *backuppb.BackupManifest,
+) error {
+	// This is the "cluster" version that does not change between patches but
+	// rather just tracks migrations run.
+	if v := m.ClusterVersion; v.Major != 0 && currentVersion.Less(v) {
+		return errors.Errorf("backup from version %s is newer than current version %s", v, currentVersion)
+	}
+	return nil
+}
+
+// forEachBackfilledIndex calls fn with each index of the tables in the backup
+// that was being added when the backup exported its span. The span was seeing
+// non-transactional bulk-writes, which can fail to be caught by backups, so the
+// index can't be trusted once restored.
+func forEachBackfilledIndex(
+	codec keys.SQLCodec, m *backuppb.BackupManifest, fn func(tableAndIndex),
+) error {
+	spans := roachpb.Spans(m.Spans)
+	for i := range m.Descriptors {
+		table, _, _, _, _ := descpb.GetDescriptors(&m.Descriptors[i])
+		if table == nil {
+			continue
+		}
+		index := table.GetPrimaryIndex()
+		if len(index.Interleave.Ancestors) > 0 || len(index.InterleavedBy) > 0 {
+			return errors.Errorf("restoring interleaved tables is no longer allowed. table %s was found to be interleaved", table.Name)
+		}
+		if err := catalog.ForEachNonDropIndex(
+			tabledesc.NewBuilder(table).BuildImmutable().(catalog.TableDescriptor),
+			func(index catalog.Index) error {
+				if index.Adding() && spans.ContainsKey(codec.IndexPrefix(uint32(table.ID), uint32(index.GetID()))) {
+					fn(tableAndIndex{tableID: table.ID, indexID: index.GetID()})
+				}
+				return nil
+			}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveDeferredLayers reads the manifests of the layers of the backup chain
+// of a restore with deferred layer resolution that it hasn't read yet. It runs
+// the checks the statement would have run on them, resolves their locality
+// info and finds the indexes that have to be revalidated, checkpointing the
+// layers it has resolved in the progress of the job. Once it has resolved
+// every layer it sets the details of the job that depend on them.
+func (r *restoreResumer) resolveDeferredLayers(
+	ctx context.Context, p sql.JobExecContext, mem *mon.BoundAccount, kmsEnv cloud.KMSEnv,
+) error {
+	details := r.job.Details().(jobspb.RestoreDetails)
+	deferred := details.DeferredLayerResolution
+	progress := *r.job.Progress().Details.(*jobspb.Progress_Restore).Restore
+	uris := manifestURIs(details)
+	if len(deferred.IncrementalSubdirs)+1 != len(uris) {
+		return errors.AssertionFailedf("restore of a backup chain of %d layers found %d incremental backups",
+			len(uris), len(deferred.IncrementalSubdirs))
+	}
+
+	mkStore := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI
+	baseStores, cleanupBase, err := backupdest.MakeBackupDestinationStores(ctx, p.User(), mkStore,
+		deferred.BaseDirs)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cleanupBase(); err != nil {
+			log.Warningf(ctx, "failed to close base store: %+v", err)
+		}
+	}()
+	incStores, cleanupInc, err := backupdest.MakeBackupDestinationStores(ctx, p.User(), mkStore,
+		deferred.IncrementalDirs)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cleanupInc(); err != nil {
+			log.Warningf(ctx, "failed to close incremental store: %+v", err)
+		}
+	}()
+
+	backfilled := make(map[tableAndIndex]struct{}, len(progress.ResolvedRevalidateIndexes))
+	for _, ri := range progress.ResolvedRevalidateIndexes {
+		backfilled[tableAndIndex{tableID: ri.TableID, indexID: ri.IndexID}] = struct{}{}
+	}
+	checkpoint := func() error {
+		return r.job.SetProgress(ctx, nil /* txn */, progress)
+	}
+	if len(progress.ResolvedLayers) > 0 {
+		log.Infof(ctx, "resuming the resolution of backup chain of %d layers at layer %d",
+			len(uris), len(progress.ResolvedLayers))
+	}
+
+	currentVersion := p.ExecCfg().Settings.Version.ActiveVersion(ctx)
+	lastCheckpoint := timeutil.Now()
+	for i := len(progress.ResolvedLayers); i < len(uris); i++ {
+		m, memSize, err := backupinfo.ReadBackupManifestFromURI(ctx, mem, uris[i], p.User(), mkStore,
+			details.Encryption, kmsEnv, false /* elideFiles */)
+		if err != nil {
+			return err
+		}
+		if err := func() error {
+			defer mem.Shrink(ctx, memSize)
+			if err := backupread.CheckReadable(&m, p.ExecCfg().Settings.Version.BinaryVersion()); err != nil {
+				return err
+			}
+			if err := checkBackupVersion(currentVersion, &m); err != nil {
+				return err
+			}
+			if len(details.MetadataURIs) == 0 && m.DataURI != "" {
+				return errors.Newf("the data of backup %s was written apart from its metadata, to %s",
+					backuputils.RedactURIForErrorMessage(uris[i]), m.DataURI)
+			}
+			if err := backupread.CheckRestorable(&m); err != nil {
+				return err
+			}
+			if err := forEachBackfilledIndex(p.ExecCfg().Codec, &m, func(k tableAndIndex) {
+				if _, ok := backfilled[k]; !ok {
+					backfilled[k] = struct{}{}
+					progress.ResolvedRevalidateIndexes = append(progress.ResolvedRevalidateIndexes,
+						jobspb.RestoreDetails_RevalidateIndex{TableID: k.tableID, IndexID: k.indexID})
+				}
+			}); err != nil {
+				return err
+			}
+
+			var localityInfo jobspb.RestoreDetails_BackupLocalityInfo
+			if i == 0 {
+				localityInfo, err = backupinfo.GetLocalityInfo(ctx, baseStores, deferred.BaseDirs, m,
+					details.Encryption, kmsEnv, "" /* prefix */)
+			} else {
+				incSubdir := deferred.IncrementalSubdirs[i-1]
+				var partitionURIs []string
+				partitionURIs, err = backuputils.AppendPaths(deferred.IncrementalDirs, incSubdir)
+				if err != nil {
+					return err
+				}
+				localityInfo, err = backupinfo.GetLocalityInfo(ctx, incStores, partitionURIs, m,
+					details.Encryption, kmsEnv, incSubdir)
+			}
+			if err != nil {
+				return err
+			}
+			progress.ResolvedLayers = append(progress.ResolvedLayers, localityInfo)
+			return nil
+		}(); err != nil {
+			return errors.Wrapf(err, "resolving layer %d of the backup chain", i)
+		}
+
+		if timeutil.Since(lastCheckpoint) > deferredLayerResolutionCheckpointInterval {
+			if err := checkpoint(); err != nil {
+				return err
+			}
+			lastCheckpoint = timeutil.Now()
+		}
+		if err := p.ExecCfg().JobRegistry.CheckPausepoint("restore.after_resolving_layer"); err != nil {
+			if cpErr := checkpoint(); cpErr != nil {
+				return errors.CombineErrors(err, cpErr)
+			}
+			return err
+		}
+	}
+
+	// Only the indexes of the restored tables which are active in the
+	// descriptors being restored need to be revalidated, under their new IDs.
+	restoredIndexes := make(map[tableAndIndex]struct{})
+	for _, t := range details.TableDescs {
+		for _, idx := range tabledesc.NewBuilder(t).BuildImmutableTable().ActiveIndexes() {
+			restoredIndexes[tableAndIndex{tableID: t.ID, indexID: idx.GetID()}] = struct{}{}
+		}
+	}
+	var revalidateIndexes []jobspb.RestoreDetails_RevalidateIndex
+	for _, ri := range progress.ResolvedRevalidateIndexes {
+		rw, ok := details.DescriptorRewrites[ri.TableID]
+		if !ok || rw.ToExisting {
+			continue
+		}
+		if _, ok := restoredIndexes[tableAndIndex{tableID: rw.ID, indexID: ri.IndexID}]; ok {
+			revalidateIndexes = append(revalidateIndexes,
+				jobspb.RestoreDetails_RevalidateIndex{TableID: rw.ID, IndexID: ri.IndexID})
+		}
+	}
+
+	if err := checkpoint(); err != nil {
+		return err
+	}
+	details.BackupLocalityInfo = progress.ResolvedLayers
+	details.RevalidateIndexes = revalidateIndexes
+	details.DeferredLayerResolution = nil
+	return r.job.SetDetails(ctx, nil /* txn */, details)
+}
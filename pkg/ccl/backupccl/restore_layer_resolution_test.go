@@ -0,0 +1,72 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreDeferredLayerResolution(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	const numAccounts = 10
+	params := base.TestClusterArgs{ServerArgs: base.TestServerArgs{
+		Knobs: base.TestingKnobs{JobsTestingKnobs: jobs.NewTestingKnobsWithShortIntervals()},
+	}}
+	tc, sqlDB, _, cleanupFn := backupRestoreTestSetupWithParams(t, singleNode, numAccounts,
+		InitManualReplication, params)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+	for i := 0; i < 3; i++ {
+		sqlDB.Exec(t, `INSERT INTO data.bank VALUES ($1, 0, 'inc')`, numAccounts+i)
+		sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, localFoo)
+	}
+
+	// Pause the restore after it has resolved the first layer of the chain.
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.restore.deferred_layer_resolution.min_layers = 2`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING jobs.debug.pausepoints = 'restore.after_resolving_layer'`)
+	var jobID jobspb.JobID
+	sqlDB.QueryRow(t,
+		`RESTORE DATABASE data FROM LATEST IN $1 WITH detached, new_db_name = 'data2'`, localFoo,
+	).Scan(&jobID)
+	jobutils.WaitForJobToPause(t, sqlDB, jobID)
+
+	registry := tc.Server(0).JobRegistry().(*jobs.Registry)
+	job, err := registry.LoadJob(ctx, jobID)
+	require.NoError(t, err)
+	details := job.Details().(jobspb.RestoreDetails)
+	require.NotNil(t, details.DeferredLayerResolution)
+	require.Len(t, details.DeferredLayerResolution.IncrementalSubdirs, 3)
+	require.Len(t, details.URIs, 4)
+	require.Len(t, job.Progress().Details.(*jobspb.Progress_Restore).Restore.ResolvedLayers, 1)
+
+	// The job resumes from the layers it has resolved and completes.
+	sqlDB.Exec(t, `SET CLUSTER SETTING jobs.debug.pausepoints = ''`)
+	sqlDB.Exec(t, `RESUME JOB $1`, jobID)
+	jobutils.WaitForJobToSucceed(t, sqlDB, jobID)
+
+	job, err = registry.LoadJob(ctx, jobID)
+	require.NoError(t, err)
+	details = job.Details().(jobspb.RestoreDetails)
+	require.Nil(t, details.DeferredLayerResolution)
+	require.Len(t, details.BackupLocalityInfo, 4)
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM data2.bank`, [][]string{{"13"}})
+}
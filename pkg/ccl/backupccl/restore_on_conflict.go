@@ -0,0 +1,232 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/errors"
+)
+
+// The values of the on_conflict option, which determines what RESTORE does
+// with the tables and databases it restores that already exist.
+const (
+	// restoreOnConflictError fails the restore. This is the default.
+	restoreOnConflictError = "error"
+	// restoreOnConflictSkip leaves the existing objects in place and does not
+	// restore them.
+	restoreOnConflictSkip = "skip"
+	// restoreOnConflictReplace restores the objects under temporary names and,
+	// once all their data is restored, drops the existing objects and renames
+	// the restored ones to their names in a single transaction.
+	restoreOnConflictReplace = "replace"
+)
+
+// resolveOnConflict validates the value of the on_conflict option, returning
+// the default if it is unset.
+func resolveOnConflict(onConflict string, descriptorCoverage tree.DescriptorCoverage) (string, error) {
+	switch onConflict {
+	case "":
+		return restoreOnConflictError, nil
+	case restoreOnConflictError:
+		return onConflict, nil
+	case restoreOnConflictSkip, restoreOnConflictReplace:
+		if descriptorCoverage != tree.RequestedDescriptors {
+			return "", errors.Errorf(
+				"%s can only be used when restoring tables or databases", restoreOptOnConflict)
+		}
+		return onConflict, nil
+	default:
+		return "", errors.Errorf("%s must be one of '%s', '%s' or '%s', got %q",
+			restoreOptOnConflict, restoreOnConflictError, restoreOnConflictSkip,
+			restoreOnConflictReplace, onConflict)
+	}
+}
+
+// restoreTempName returns the temporary name a restored descriptor that
+// replaces the existing one named name is restored under.
+func restoreTempName(name string, id descpb.ID) string {
+	return fmt.Sprintf("%s_crdb_restore_%d", name, id)
+}
+
+// getTableCollidingWithRestore returns the existing table that table collides
+// with when restored into parentDB, or nil if there is none. If the object it
+// collides with is not a table, it cannot be skipped or replaced, so an error
+// is returned.
+func getTableCollidingWithRestore(
+	ctx context.Context,
+	txn *kv.Txn,
+	col *descs.Collection,
+	parentDB catalog.DatabaseDescriptor,
+	table *tabledesc.Mutable,
+	descriptorRewrites jobspb.DescRewriteMap,
+) (catalog.TableDescriptor, error) {
+	parentSchemaID := table.GetParentSchemaID()
+	if parentSchemaID == keys.PublicSchemaIDForBackup || parentSchemaID == descpb.InvalidID {
+		parentSchemaID = parentDB.GetSchemaID(tree.PublicSchema)
+	} else if rw, ok := descriptorRewrites[parentSchemaID]; ok && rw.ToExisting {
+		parentSchemaID = rw.ID
+	}
+	desc, err := col.Direct().GetDescriptorCollidingWithObject(
+		ctx, txn, parentDB.GetID(), parentSchemaID, table.GetName(),
+	)
+	if err != nil || desc == nil {
+		return nil, err
+	}
+	existing, ok := desc.(catalog.TableDescriptor)
+	if !ok {
+		return nil, pgerror.Newf(pgcode.DuplicateObject,
+			"cannot restore table %q: a %s with the same name already exists",
+			table.GetName(), desc.DescriptorType())
+	}
+	return existing, nil
+}
+
+// checkTableReplaceable returns an error if the existing table cannot be
+// replaced by a restored one: the user must be able to drop it, and nothing
+// else may depend on it since replacing it would break those dependencies.
+func checkTableReplaceable(
+	ctx context.Context, p sql.PlanHookState, existing catalog.TableDescriptor,
+) error {
+	if existing.Offline() {
+		return pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+			"cannot replace table %q because it is offline", existing.GetName())
+	}
+	if err := p.CheckPrivilege(ctx, existing, privilege.DROP); err != nil {
+		return err
+	}
+	if len(existing.GetDependedOnBy()) > 0 || len(existing.TableDesc().InboundFKs) > 0 {
+		return pgerror.Newf(pgcode.DependentObjectsStillExist,
+			"cannot replace table %q because other objects depend on it", existing.GetName())
+	}
+	return nil
+}
+
+// checkNoDependenciesOnSkippedTables returns an error if any of the tables to
+// restore references one of the skipped ones, since the reference could not
+// be restored.
+func checkNoDependenciesOnSkippedTables(
+	tablesByID map[descpb.ID]*tabledesc.Mutable, skipped map[descpb.ID]*tabledesc.Mutable,
+) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+	for _, table := range tablesByID {
+		var refs []descpb.ID
+		for i := range table.OutboundFKs {
+			refs = append(refs, table.OutboundFKs[i].ReferencedTableID)
+		}
+		refs = append(refs, table.DependsOn...)
+		for i := range table.Columns {
+			refs = append(refs, table.Columns[i].UsesSequenceIds...)
+			refs = append(refs, table.Columns[i].OwnsSequenceIds...)
+		}
+		if table.IsSequence() && table.SequenceOpts.HasOwner() {
+			refs = append(refs, table.SequenceOpts.SequenceOwner.OwnerTableID)
+		}
+		for _, id := range refs {
+			if dep, ok := skipped[id]; ok {
+				return errors.Errorf(
+					"cannot restore table %q because it depends on table %q, which is skipped as it already exists",
+					table.GetName(), dep.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+// replaceExistingDescriptors drops the existing tables and databases that are
+// replaced by restored ones and renames the restored ones, which were
+// published under temporary names, to their names.
+func replaceExistingDescriptors(
+	ctx context.Context,
+	txn *kv.Txn,
+	descsCol *descs.Collection,
+	ie sqlutil.InternalExecutor,
+	replaced []jobspb.RestoreDetails_ReplacedDescriptor,
+) error {
+	for _, r := range replaced {
+		restored, err := descsCol.GetImmutableDescriptorByID(ctx, txn, r.RestoredID, tree.CommonLookupFlags{
+			Required:    true,
+			AvoidLeased: true,
+		})
+		if err != nil {
+			return err
+		}
+		// The existing descriptor may have been dropped while the restore was
+		// running, in which case there is nothing left to drop.
+		existing, err := descsCol.GetImmutableDescriptorByID(ctx, txn, r.ID, tree.CommonLookupFlags{
+			AvoidLeased: true,
+		})
+		if errors.Is(err, catalog.ErrDescriptorNotFound) || errors.Is(err, catalog.ErrDescriptorDropped) {
+			existing, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch restored := restored.(type) {
+		case catalog.DatabaseDescriptor:
+			if existing != nil {
+				if _, err := ie.Exec(ctx, "restore-drop-replaced-database", txn,
+					fmt.Sprintf("DROP DATABASE %s CASCADE", tree.NameString(existing.GetName())),
+				); err != nil {
+					return errors.Wrapf(err, "dropping replaced database %q", existing.GetName())
+				}
+			}
+			if _, err := ie.Exec(ctx, "restore-rename-replacing-database", txn,
+				fmt.Sprintf("ALTER DATABASE %s RENAME TO %s",
+					tree.NameString(restored.GetName()), tree.NameString(r.Name)),
+			); err != nil {
+				return errors.Wrapf(err, "renaming restored database %q", restored.GetName())
+			}
+		case catalog.TableDescriptor:
+			if existing != nil {
+				existingName, err := descs.GetTableNameByID(ctx, txn, descsCol, existing.GetID())
+				if err != nil {
+					return err
+				}
+				if _, err := ie.Exec(ctx, "restore-drop-replaced-table", txn,
+					fmt.Sprintf("DROP TABLE %s", existingName.FQString()),
+				); err != nil {
+					return errors.Wrapf(err, "dropping replaced table %s", existingName)
+				}
+			}
+			restoredName, err := descs.GetTableNameByDesc(ctx, txn, descsCol, restored)
+			if err != nil {
+				return err
+			}
+			newName := *restoredName
+			newName.ObjectName = tree.Name(r.Name)
+			if _, err := ie.Exec(ctx, "restore-rename-replacing-table", txn,
+				fmt.Sprintf("ALTER TABLE %s RENAME TO %s", restoredName.FQString(), newName.FQString()),
+			); err != nil {
+				return errors.Wrapf(err, "renaming restored table %s", restoredName)
+			}
+		default:
+			return errors.AssertionFailedf("unexpected replacing descriptor %d of type %s",
+				restored.GetID(), restored.DescriptorType())
+		}
+	}
+	return nil
+}
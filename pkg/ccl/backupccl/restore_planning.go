@@ -14,17 +14,20 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/ccl/multiregionccl"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
 	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/featureflag"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
@@ -56,12 +59,15 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
 	"github.com/cockroachdb/cockroach/pkg/sql/roleoption"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/catconstants"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/catid"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
 	"github.com/cockroachdb/cockroach/pkg/sql/syntheticprivilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/errors"
 )
@@ -75,12 +81,31 @@ const (
 	restoreOptSkipLocalitiesCheck       = "skip_localities_check"
 	restoreOptDebugPauseOn              = "debug_pause_on"
 	restoreOptAsTenant                  = "tenant"
+	restoreOptLatestValue               = "latest_value"
+	restoreOptLatestAsOf                = "latest_as_of"
+	restoreOptMinLatestTime             = "min_latest_time"
+	restoreOptOnConflict                = "on_conflict"
+	restoreOptRemapRegions              = "remap_regions"
+	restoreOptRestoreFKToExisting       = "restore_fk_to_existing"
+	restoreOptKeyOffset                 = "key_offset"
+	restoreOptColumns                   = "columns"
 
 	// The temporary database system tables will be restored into for full
 	// cluster backups.
 	restoreTempSystemDB = "crdb_temp_system"
 )
 
+// minLatestTimeWaitTimeout bounds how long a restore with min_latest_time
+// waits for LATEST to name a recent enough backup.
+var minLatestTimeWaitTimeout = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.restore.min_latest_time_wait_timeout",
+	"the maximum amount of time a restore with min_latest_time waits for the LATEST file "+
+		"of its collection to name a backup ending at or after that time",
+	time.Minute,
+	settings.PositiveDuration,
+)
+
 var allowedDebugPauseOnValues = map[string]struct{}{
 	"error": {},
 }
@@ -144,6 +169,11 @@ func maybeFilterMissingViews(
 // DescriptorRewrite. It first validates that the provided sqlDescs can be restored
 // into their original database (or the database specified in opts) to avoid
 // leaking table IDs if we can be sure the restore would fail.
+//
+// Tables and databases that already exist are handled according to
+// onConflict: skipped ones are removed, along with their contents, from the
+// passed maps, and replaced ones are restored under temporary names and
+// returned so that the job can swap them in.
 func allocateDescriptorRewrites(
 	ctx context.Context,
 	p sql.PlanHookState,
@@ -157,7 +187,9 @@ func allocateDescriptorRewrites(
 	opts tree.RestoreOptions,
 	intoDB string,
 	newDBName string,
-) (jobspb.DescRewriteMap, error) {
+	onConflict string,
+	keyOffset descpb.ID,
+) (jobspb.DescRewriteMap, []jobspb.RestoreDetails_ReplacedDescriptor, error) {
 	descriptorRewrites := make(jobspb.DescRewriteMap)
 
 	restoreDBNames := make(map[string]catalog.DatabaseDescriptor, len(restoreDBs))
@@ -166,7 +198,7 @@ func allocateDescriptorRewrites(
 	}
 
 	if len(restoreDBNames) > 0 && intoDB != "" {
-		return nil, errors.Errorf("cannot use %q option when restoring database(s)", restoreOptIntoDB)
+		return nil, nil, errors.Errorf("cannot use %q option when restoring database(s)", restoreOptIntoDB)
 	}
 
 	// The logic at the end of this function leaks table IDs, so fail fast if
@@ -175,12 +207,13 @@ func allocateDescriptorRewrites(
 	// Fail fast if the tables to restore are incompatible with the specified
 	// options.
 	for _, table := range tablesByID {
-		// Check that foreign key targets exist.
+		// Check that foreign key targets exist. Those of the restore_fk_to_existing
+		// option are checked when they're resolved to existing tables.
 		for i := range table.OutboundFKs {
 			fk := &table.OutboundFKs[i]
 			if _, ok := tablesByID[fk.ReferencedTableID]; !ok {
-				if !opts.SkipMissingFKs {
-					return nil, errors.Errorf(
+				if !opts.SkipMissingFKs && !opts.RestoreFKToExisting {
+					return nil, nil, errors.Errorf(
 						"cannot restore table %q without referenced table %d (or %q option)",
 						table.Name, fk.ReferencedTableID, restoreOptSkipMissingFKs,
 					)
@@ -196,10 +229,10 @@ func allocateDescriptorRewrites(
 				// TODO (rohany): This can be turned into an option later.
 				id, err := typedesc.GetUserDefinedTypeDescID(col.Type)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				if _, ok := typesByID[id]; !ok {
-					return nil, errors.Errorf(
+					return nil, nil, errors.Errorf(
 						"cannot restore table %q without referenced type %d",
 						table.Name,
 						id,
@@ -209,7 +242,7 @@ func allocateDescriptorRewrites(
 			for _, seqID := range col.UsesSequenceIds {
 				if _, ok := tablesByID[seqID]; !ok {
 					if !opts.SkipMissingSequences {
-						return nil, errors.Errorf(
+						return nil, nil, errors.Errorf(
 							"cannot restore table %q without referenced sequence %d (or %q option)",
 							table.Name, seqID, restoreOptSkipMissingSequences,
 						)
@@ -219,7 +252,7 @@ func allocateDescriptorRewrites(
 			for _, seqID := range col.OwnsSequenceIds {
 				if _, ok := tablesByID[seqID]; !ok {
 					if !opts.SkipMissingSequenceOwners {
-						return nil, errors.Errorf(
+						return nil, nil, errors.Errorf(
 							"cannot restore table %q without referenced sequence %d (or %q option)",
 							table.Name, seqID, restoreOptSkipMissingSequenceOwners)
 					}
@@ -231,7 +264,7 @@ func allocateDescriptorRewrites(
 		if table.IsSequence() && table.SequenceOpts.HasOwner() {
 			if _, ok := tablesByID[table.SequenceOpts.SequenceOwner.OwnerTableID]; !ok {
 				if !opts.SkipMissingSequenceOwners {
-					return nil, errors.Errorf(
+					return nil, nil, errors.Errorf(
 						"cannot restore sequence %q without referenced owner table %d (or %q option)",
 						table.Name,
 						table.SequenceOpts.SequenceOwner.OwnerTableID,
@@ -242,6 +275,23 @@ func allocateDescriptorRewrites(
 		}
 	}
 
+	// Check that the relations and types the functions to restore depend on are
+	// restored with them.
+	for _, fn := range functionsByID {
+		for _, id := range fn.DependsOn {
+			if _, ok := tablesByID[id]; !ok {
+				return nil, nil, errors.Errorf(
+					"cannot restore function %q without referenced table %d", fn.Name, id)
+			}
+		}
+		for _, id := range fn.DependsOnTypes {
+			if _, ok := typesByID[id]; !ok {
+				return nil, nil, errors.Errorf(
+					"cannot restore function %q without referenced type %d", fn.Name, id)
+			}
+		}
+	}
+
 	needsNewParentIDs := make(map[string][]descpb.ID)
 
 	// Increment the DescIDSequenceKey so that it is higher than both the max desc ID
@@ -250,7 +300,7 @@ func allocateDescriptorRewrites(
 	if descriptorCoverage == tree.AllDescriptors || descriptorCoverage == tree.SystemUsers {
 		tempSysDBID, err := p.ExecCfg().DescIDGenerator.GenerateUniqueDescID(ctx)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// Remap all of the descriptor belonging to system tables to the temp system
@@ -281,6 +331,14 @@ func allocateDescriptorRewrites(
 	var shouldBufferDeprecatedPrivilegeNotice bool
 	databasesWithDeprecatedPrivileges := make(map[string]struct{})
 
+	// The databases and tables that already exist and are skipped or replaced
+	// because of the on_conflict option. The replaced ones map to the IDs of
+	// the existing descriptors.
+	skippedDBs := make(map[string]struct{})
+	replacedDBs := make(map[string]descpb.ID)
+	var skippedTables catalog.DescriptorIDSet
+	replacedTables := make(map[descpb.ID]descpb.ID)
+
 	// Fail fast if the necessary databases don't exist or are otherwise
 	// incompatible with this restore.
 	if err := sql.DescsTxn(ctx, p.ExecCfg(), func(ctx context.Context, txn *kv.Txn, col *descs.Collection) error {
@@ -290,7 +348,22 @@ func allocateDescriptorRewrites(
 			if err != nil {
 				return err
 			}
-			if dbID != descpb.InvalidID {
+			if dbID == descpb.InvalidID {
+				continue
+			}
+			switch onConflict {
+			case restoreOnConflictSkip:
+				skippedDBs[name] = struct{}{}
+			case restoreOnConflictReplace:
+				existing, err := col.Direct().MustGetDatabaseDescByID(ctx, txn, dbID)
+				if err != nil {
+					return err
+				}
+				if err := p.CheckPrivilege(ctx, existing, privilege.DROP); err != nil {
+					return err
+				}
+				replacedDBs[name] = dbID
+			default:
 				return errors.Errorf("database %q already exists", name)
 			}
 		}
@@ -373,20 +446,43 @@ func allocateDescriptorRewrites(
 					}
 					parentID = newParentID
 				}
-				// Check that the table name is _not_ in use.
-				// This would fail the CPut later anyway, but this yields a prettier error.
-				tableName := tree.NewUnqualifiedTableName(tree.Name(table.GetName()))
-				err := col.Direct().CheckObjectCollision(ctx, txn, parentID, table.GetParentSchemaID(), tableName)
-				if err != nil {
-					return err
-				}
-
-				// Check privileges.
 				parentDB, err := col.Direct().MustGetDatabaseDescByID(ctx, txn, parentID)
 				if err != nil {
 					return errors.Wrapf(err,
 						"failed to lookup parent DB %d", errors.Safe(parentID))
 				}
+
+				if onConflict != restoreOnConflictError {
+					existing, err := getTableCollidingWithRestore(
+						ctx, txn, col, parentDB, table, descriptorRewrites)
+					if err != nil {
+						return err
+					}
+					if existing != nil && onConflict == restoreOnConflictSkip {
+						skippedTables.Add(table.GetID())
+						continue
+					}
+					if existing != nil {
+						if err := checkTableReplaceable(ctx, p, existing); err != nil {
+							return err
+						}
+						replacedTables[table.GetID()] = existing.GetID()
+					}
+				}
+
+				// Check that the table name is _not_ in use, unless the table
+				// replaces an existing one and will be restored under a temporary
+				// name. This would fail the CPut later anyway, but this yields a
+				// prettier error.
+				if _, ok := replacedTables[table.GetID()]; !ok {
+					tableName := tree.NewUnqualifiedTableName(tree.Name(table.GetName()))
+					err := col.Direct().CheckObjectCollision(ctx, txn, parentID, table.GetParentSchemaID(), tableName)
+					if err != nil {
+						return err
+					}
+				}
+
+				// Check privileges.
 				if usesDeprecatedPrivileges, err := checkRestorePrivilegesOnDatabase(ctx, p, parentDB); err != nil {
 					return err
 				} else if usesDeprecatedPrivileges {
@@ -541,16 +637,12 @@ func allocateDescriptorRewrites(
 			}
 		}
 
-		// TODO(chengxiong): we need to handle the cases of restoring tables when we
-		// start supporting udf references from other objects. Namely, we need to do
-		// collision checks similar to tables and types. However, there would be a
-		// bit shift since there is not namespace entry for functions. That means we
-		// need some function resolution for it.
+		// Iterate through functionsByID to construct a remapping entry for each
+		// function. Functions are restored into existing databases when they are
+		// restored along with the tables they depend on.
 		for _, function := range functionsByID {
-			// User-defined functions are not allowed in tables, so restoring specific
-			// tables shouldn't match any udf descriptors.
 			if _, ok := descriptorRewrites[function.ID]; ok {
-				return errors.AssertionFailedf("function descriptors seen when restoring tables")
+				return errors.AssertionFailedf("function %q already has a rewrite", function.Name)
 			}
 
 			targetDB, err := resolveTargetDB(ctx, txn, p, databasesByID, intoDB, descriptorCoverage, function)
@@ -560,13 +652,51 @@ func allocateDescriptorRewrites(
 
 			if _, ok := restoreDBNames[targetDB]; ok {
 				needsNewParentIDs[targetDB] = append(needsNewParentIDs[targetDB], function.ID)
-			} else {
-				return errors.AssertionFailedf("function descriptor seen when restoring tables")
+				continue
+			}
+
+			parentID, parentDB, err := getDatabaseIDAndDesc(ctx, txn, col, targetDB)
+			if err != nil {
+				return err
+			}
+
+			// Functions have no namespace entries, so rather than checking for a
+			// name collision, check that no function in the schema the function is
+			// restored into has the same signature.
+			var parentSchemaID descpb.ID
+			if function.GetParentSchemaID() == keys.PublicSchemaIDForBackup ||
+				function.GetParentSchemaID() == descpb.InvalidID {
+				parentSchemaID = parentDB.GetSchemaID(tree.PublicSchema)
+			} else if rw, ok := descriptorRewrites[function.GetParentSchemaID()]; ok && rw.ToExisting {
+				parentSchemaID = rw.ID
+			}
+			if parentSchemaID != descpb.InvalidID {
+				sc, err := col.Direct().MustGetSchemaDescByID(ctx, txn, parentSchemaID)
+				if err != nil {
+					return err
+				}
+				if err := checkRestoredFunctionCollision(function, sc, descriptorRewrites); err != nil {
+					return err
+				}
+			}
+
+			// Check privileges.
+			if usesDeprecatedPrivileges, err := checkRestorePrivilegesOnDatabase(ctx, p, parentDB); err != nil {
+				return err
+			} else if usesDeprecatedPrivileges {
+				shouldBufferDeprecatedPrivilegeNotice = true
+				databasesWithDeprecatedPrivileges[parentDB.GetName()] = struct{}{}
+			}
+
+			descriptorRewrites[function.ID] = &jobspb.DescriptorRewrite{ParentID: parentID}
+			if function.GetParentSchemaID() == keys.PublicSchemaIDForBackup ||
+				function.GetParentSchemaID() == descpb.InvalidID {
+				descriptorRewrites[function.ID].ParentSchemaID = parentSchemaID
 			}
 		}
 		return nil
 	}); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if shouldBufferDeprecatedPrivilegeNotice {
@@ -578,6 +708,91 @@ func allocateDescriptorRewrites(
 			deprecatedPrivilegesPreamble, p.User(), strings.Join(dbNames, ", ")))
 	}
 
+	// Drop the skipped databases and tables, and everything in the skipped
+	// databases, from the restore.
+	skipped := make(map[descpb.ID]*tabledesc.Mutable)
+	for _, id := range skippedTables.Ordered() {
+		skipped[id] = tablesByID[id]
+		delete(tablesByID, id)
+	}
+	for _, db := range restoreDBs {
+		if _, ok := skippedDBs[db.GetName()]; !ok {
+			continue
+		}
+		p.BufferClientNotice(ctx, pgnotice.Newf(
+			"skipping database %q because it already exists", db.GetName()))
+		delete(databasesByID, db.GetID())
+		for _, id := range needsNewParentIDs[db.GetName()] {
+			if table, ok := tablesByID[id]; ok {
+				skipped[id] = table
+			}
+			delete(tablesByID, id)
+			delete(schemasByID, id)
+			delete(typesByID, id)
+			delete(functionsByID, id)
+		}
+	}
+	for _, id := range skippedTables.Ordered() {
+		p.BufferClientNotice(ctx, pgnotice.Newf(
+			"skipping table %q because it already exists", skipped[id].GetName()))
+	}
+	if err := checkNoDependenciesOnSkippedTables(tablesByID, skipped); err != nil {
+		return nil, nil, err
+	}
+	// The functions that depend on a skipped table are skipped with it, as the
+	// existing table has no back-references to them.
+	for id, fn := range functionsByID {
+		for _, depID := range fn.DependsOn {
+			if dep, ok := skipped[depID]; ok {
+				p.BufferClientNotice(ctx, pgnotice.Newf(
+					"skipping function %q because table %q is skipped", fn.GetName(), dep.GetName()))
+				delete(functionsByID, id)
+				delete(descriptorRewrites, id)
+				break
+			}
+		}
+	}
+	if opts.DryRun {
+		// A dry run only checks the descriptors it would restore.
+		return descriptorRewrites, nil, nil
+	}
+
+	// With key_offset, the descriptors are restored under their IDs shifted by
+	// the offset, which are reserved up front, rather than under generated ones.
+	newDescID := func(desc catalog.Descriptor) (descpb.ID, error) {
+		if keyOffset != descpb.InvalidID {
+			return desc.GetID() + keyOffset, nil
+		}
+		return p.ExecCfg().DescIDGenerator.GenerateUniqueDescID(ctx)
+	}
+	if keyOffset != descpb.InvalidID {
+		var ids []descpb.ID
+		for _, db := range restoreDBs {
+			if _, ok := skippedDBs[db.GetName()]; !ok {
+				ids = append(ids, db.GetID())
+			}
+		}
+		for id := range tablesByID {
+			ids = append(ids, id)
+		}
+		for id := range typesByID {
+			if !descriptorRewrites[id].ToExisting {
+				ids = append(ids, id)
+			}
+		}
+		for id := range schemasByID {
+			if !descriptorRewrites[id].ToExisting {
+				ids = append(ids, id)
+			}
+		}
+		for id := range functionsByID {
+			ids = append(ids, id)
+		}
+		if err := reserveOffsetDescIDs(ctx, p.ExecCfg(), keyOffset, ids); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Allocate new IDs for each database and table.
 	//
 	// NB: we do this in a standalone transaction, not one that covers the
@@ -591,12 +806,14 @@ func allocateDescriptorRewrites(
 	// handle this by chunking the AddSSTable calls more finely in Import, but
 	// it would be a big performance hit.
 
+	var replaced []jobspb.RestoreDetails_ReplacedDescriptor
 	for _, db := range restoreDBs {
-		var newID descpb.ID
-		var err error
-		newID, err = p.ExecCfg().DescIDGenerator.GenerateUniqueDescID(ctx)
+		if _, ok := skippedDBs[db.GetName()]; ok {
+			continue
+		}
+		newID, err := newDescID(db)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		descriptorRewrites[db.GetID()] = &jobspb.DescriptorRewrite{ID: newID}
@@ -607,6 +824,17 @@ func allocateDescriptorRewrites(
 			descriptorRewrites[db.GetID()].NewDBName = newDBName
 		}
 
+		// A database that replaces an existing one is restored under a temporary
+		// name until it is swapped in.
+		if existingID, ok := replacedDBs[db.GetName()]; ok {
+			descriptorRewrites[db.GetID()].NewDBName = restoreTempName(db.GetName(), newID)
+			replaced = append(replaced, jobspb.RestoreDetails_ReplacedDescriptor{
+				ID:         existingID,
+				RestoredID: newID,
+				Name:       db.GetName(),
+			})
+		}
+
 		for _, objectID := range needsNewParentIDs[db.GetName()] {
 			descriptorRewrites[objectID] = &jobspb.DescriptorRewrite{ParentID: newID}
 		}
@@ -647,13 +875,29 @@ func allocateDescriptorRewrites(
 	// Generate new IDs for the schemas, tables, and types that need to be
 	// remapped.
 	for _, desc := range descriptorsToRemap {
-		id, err := p.ExecCfg().DescIDGenerator.GenerateUniqueDescID(ctx)
+		id, err := newDescID(desc)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		descriptorRewrites[desc.GetID()].ID = id
 	}
 
+	// A table that replaces an existing one is restored under a temporary name
+	// until it is swapped in.
+	for _, table := range tablesByID {
+		existingID, ok := replacedTables[table.GetID()]
+		if !ok {
+			continue
+		}
+		newID := descriptorRewrites[table.GetID()].ID
+		replaced = append(replaced, jobspb.RestoreDetails_ReplacedDescriptor{
+			ID:         existingID,
+			RestoredID: newID,
+			Name:       table.GetName(),
+		})
+		table.SetName(restoreTempName(table.GetName(), newID))
+	}
+
 	// Now that the descriptorRewrites contains a complete rewrite entry for every
 	// schema that is being restored, we can correctly populate the ParentSchemaID
 	// of all tables and types.
@@ -680,7 +924,7 @@ func allocateDescriptorRewrites(
 		rewriteObject(fn)
 	}
 
-	return descriptorRewrites, nil
+	return descriptorRewrites, replaced, nil
 }
 
 func getDatabaseIDAndDesc(
@@ -702,6 +946,51 @@ func getDatabaseIDAndDesc(
 	return dbID, dbDesc, nil
 }
 
+// checkRestoredFunctionCollision returns an error if the existing schema a
+// function is restored into already has a function with the same name and
+// argument types.
+func checkRestoredFunctionCollision(
+	function *funcdesc.Mutable, sc catalog.SchemaDescriptor, descriptorRewrites jobspb.DescRewriteMap,
+) error {
+	existing, ok := sc.GetFunction(function.GetName())
+	if !ok {
+		return nil
+	}
+	for _, overload := range existing.Overloads {
+		if len(overload.ArgTypes) != len(function.Args) {
+			continue
+		}
+		same := true
+		for i, argType := range overload.ArgTypes {
+			oid := function.Args[i].Type.Oid()
+			if function.Args[i].Type.UserDefined() {
+				// A user-defined type can only match an existing type if it is
+				// remapped to it.
+				id, err := typedesc.GetUserDefinedTypeDescID(function.Args[i].Type)
+				if err != nil {
+					return err
+				}
+				rw, ok := descriptorRewrites[id]
+				if !ok || !rw.ToExisting {
+					same = false
+					break
+				}
+				oid = catid.TypeIDToOID(rw.ID)
+			}
+			if argType.Oid() != oid {
+				same = false
+				break
+			}
+		}
+		if same {
+			return pgerror.Newf(pgcode.DuplicateFunction,
+				"function %q already exists with the same argument types in schema %q",
+				function.GetName(), sc.GetName())
+		}
+	}
+	return nil
+}
+
 // If we're doing a full cluster restore - to treat defaultdb and postgres
 // as regular databases, we drop them before restoring them again in the
 // restore.
@@ -833,7 +1122,13 @@ func maybeUpgradeDescriptorsInBackupManifests(
 // them to be suitable for displaying in the jobs' description.
 // This includes redacting secrets from external storage URIs.
 func resolveOptionsForRestoreJobDescription(
-	opts tree.RestoreOptions, intoDB string, newDBName string, kmsURIs []string, incFrom []string,
+	opts tree.RestoreOptions,
+	intoDB string,
+	newDBName string,
+	kmsURIs []string,
+	kmsURIsByLocality map[string]string,
+	incFrom []string,
+	metadataURI string,
 ) (tree.RestoreOptions, error) {
 	if opts.IsDefault() {
 		return opts, nil
@@ -844,9 +1139,22 @@ func resolveOptionsForRestoreJobDescription(
 		SkipMissingSequences:      opts.SkipMissingSequences,
 		SkipMissingSequenceOwners: opts.SkipMissingSequenceOwners,
 		SkipMissingViews:          opts.SkipMissingViews,
+		RestoreFKToExisting:       opts.RestoreFKToExisting,
 		Detached:                  opts.Detached,
 		SchemaOnly:                opts.SchemaOnly,
 		VerifyData:                opts.VerifyData,
+		DeferredData:              opts.DeferredData,
+		SkipStatistics:            opts.SkipStatistics,
+		SkipComments:              opts.SkipComments,
+		SkipZoneConfigs:           opts.SkipZoneConfigs,
+		SkipJobs:                  opts.SkipJobs,
+		PauseTTLSchedules:         opts.PauseTTLSchedules,
+		RemapRegions:              opts.RemapRegions,
+		OnConflict:                opts.OnConflict,
+		ExecutionLocality:         opts.ExecutionLocality,
+		KeyOffset:                 opts.KeyOffset,
+		Columns:                   opts.Columns,
+		DeferConstraintValidation: opts.DeferConstraintValidation,
 	}
 
 	if opts.EncryptionPassphrase != nil {
@@ -869,6 +1177,14 @@ func resolveOptionsForRestoreJobDescription(
 		newOpts.DecryptionKMSURI = append(newOpts.DecryptionKMSURI, tree.NewDString(redactedURI))
 	}
 
+	if opts.KMSURIByLocality != nil {
+		var err error
+		newOpts.KMSURIByLocality, err = redactKMSURIsByLocality(kmsURIsByLocality)
+		if err != nil {
+			return tree.RestoreOptions{}, err
+		}
+	}
+
 	if opts.IncrementalStorage != nil {
 		var err error
 		newOpts.IncrementalStorage, err = sanitizeURIList(incFrom)
@@ -877,6 +1193,14 @@ func resolveOptionsForRestoreJobDescription(
 		}
 	}
 
+	if opts.MetadataURI != nil {
+		sanitizedURI, err := cloud.SanitizeExternalStorageURI(metadataURI, nil /* extraParams */)
+		if err != nil {
+			return tree.RestoreOptions{}, err
+		}
+		newOpts.MetadataURI = tree.NewDString(sanitizedURI)
+	}
+
 	return newOpts, nil
 }
 
@@ -885,22 +1209,26 @@ func restoreJobDescription(
 	restore *tree.Restore,
 	from [][]string,
 	incFrom []string,
+	metadataURI string,
 	opts tree.RestoreOptions,
 	intoDB string,
 	newDBName string,
 	kmsURIs []string,
+	kmsURIsByLocality map[string]string,
 ) (string, error) {
 	r := &tree.Restore{
 		DescriptorCoverage: restore.DescriptorCoverage,
 		AsOf:               restore.AsOf,
 		Targets:            restore.Targets,
+		TableRenames:       restore.TableRenames,
+		Index:              restore.Index,
 		From:               make([]tree.StringOrPlaceholderOptList, len(restore.From)),
 	}
 
 	var options tree.RestoreOptions
 	var err error
 	if options, err = resolveOptionsForRestoreJobDescription(opts, intoDB, newDBName,
-		kmsURIs, incFrom); err != nil {
+		kmsURIs, kmsURIsByLocality, incFrom, metadataURI); err != nil {
 		return "", err
 	}
 	r.Options = options
@@ -944,6 +1272,68 @@ func restorePlanHook(
 		return nil, nil, nil, false,
 			errors.New("to set the verify_backup_table_data option, the schema_only option must be set")
 	}
+	if restoreStmt.Options.DeferredData {
+		if !restoreStmt.Options.SchemaOnly {
+			return nil, nil, nil, false,
+				errors.New("to set the deferred_data option, the schema_only option must be set")
+		}
+		if restoreStmt.Options.VerifyData {
+			return nil, nil, nil, false,
+				errors.New("the deferred_data option cannot be used with verify_backup_table_data")
+		}
+		if restoreStmt.DescriptorCoverage != tree.RequestedDescriptors || restoreStmt.Targets.TenantID.IsSet() {
+			return nil, nil, nil, false,
+				errors.New("the deferred_data option can only be used to restore tables and databases")
+		}
+	}
+
+	if restoreStmt.Options.DeferConstraintValidation && restoreStmt.Options.SchemaOnly {
+		return nil, nil, nil, false,
+			errors.New("the defer_constraint_validation option cannot be used with schema_only, " +
+				"which restores no rows to validate the constraints against")
+	}
+
+	if restoreStmt.PrepareOnly && restoreStmt.Options.Detached {
+		return nil, nil, nil, false, errors.New("PREPARE RESTORE does not run a job and cannot be DETACHED")
+	}
+	if restoreStmt.Options.DryRun && (restoreStmt.PrepareOnly || restoreStmt.Options.Detached) {
+		return nil, nil, nil, false, errors.New("a RESTORE with dry_run does not run a job and " +
+			"cannot be PREPARE RESTORE or DETACHED")
+	}
+	if restoreStmt.ExplainOnly && (restoreStmt.Options.DryRun || restoreStmt.Options.Detached) {
+		return nil, nil, nil, false, errors.New("EXPLAIN RESTORE does not run a job and " +
+			"cannot use dry_run or be DETACHED")
+	}
+
+	if restoreStmt.Targets.TableFilter != nil {
+		// The tables a backup contains are fixed when it is taken; to restore only
+		// some of them, name them with RESTORE TABLE.
+		return nil, nil, nil, false, errors.New(
+			"EXCLUDE TABLES and INCLUDE TABLES can only be used with BACKUP")
+	}
+
+	if restoreStmt.Targets.RowFilter != nil {
+		// The rows a backup contains are fixed when it is taken.
+		return nil, nil, nil, false, errors.New("a row filter can only be used with BACKUP")
+	}
+
+	if restoreStmt.Index != nil {
+		if err := checkIndexRestoreOptions(restoreStmt); err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	if restoreStmt.Options.Columns != nil {
+		if restoreStmt.DescriptorCoverage != tree.RequestedDescriptors || restoreStmt.Index != nil ||
+			restoreStmt.Targets.Tables.TablePatterns == nil {
+			return nil, nil, nil, false,
+				errors.Errorf("the %s option can only be used with RESTORE TABLE", restoreOptColumns)
+		}
+		if restoreStmt.Options.DeferredData {
+			return nil, nil, nil, false,
+				errors.Errorf("the %s option cannot be used with deferred_data", restoreOptColumns)
+		}
+	}
 
 	fromFns := make([]func() ([]string, error), len(restoreStmt.From))
 	for i := range restoreStmt.From {
@@ -975,6 +1365,19 @@ func restorePlanHook(
 		}
 	}
 
+	var kmsByLocalityFn func() (map[string]string, error)
+	if restoreStmt.Options.KMSURIByLocality != nil {
+		if restoreStmt.Options.DecryptionKMSURI == nil {
+			return nil, nil, nil, false, errors.Newf("%s must be used with the kms option",
+				backupOptKMSByLocality)
+		}
+		kmsByLocalityFn, err = kmsURIsByLocalityFn(ctx, p, restoreStmt.Options.KMSURIByLocality,
+			"RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	var intoDBFn func() (string, error)
 	if restoreStmt.Options.IntoDB != nil {
 		if restoreStmt.DescriptorCoverage == tree.SystemUsers {
@@ -1015,6 +1418,44 @@ func restorePlanHook(
 		}
 	}
 
+	var metadataURIFn func() (string, error)
+	if restoreStmt.Options.MetadataURI != nil {
+		if restoreStmt.Subdir == nil {
+			err = errors.Errorf("%q can only be used with the following syntax:"+
+				" 'RESTORE [target] FROM [subdirectory] IN [destination]'", backupOptMetadataURI)
+			return nil, nil, nil, false, err
+		}
+		if restoreStmt.Options.IncrementalStorage != nil {
+			err = errors.Errorf("%q cannot be used with %q", backupOptMetadataURI, backupOptIncStorage)
+			return nil, nil, nil, false, err
+		}
+		metadataURIFn, err = p.TypeAsString(ctx, restoreStmt.Options.MetadataURI, "RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	var remapRegionsFn func() ([]string, error)
+	if restoreStmt.Options.RemapRegions != nil {
+		if restoreStmt.DescriptorCoverage == tree.AllDescriptors {
+			return nil, nil, nil, false, errors.Errorf("%q cannot be used with a cluster restore",
+				restoreOptRemapRegions)
+		}
+		remapRegionsFn, err = p.TypeAsStringArray(ctx, tree.Exprs(restoreStmt.Options.RemapRegions),
+			"RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	var executionLocalityFn func() (string, error)
+	if restoreStmt.Options.ExecutionLocality != nil {
+		executionLocalityFn, err = p.TypeAsString(ctx, restoreStmt.Options.ExecutionLocality, "RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	var newDBNameFn func() (string, error)
 	if restoreStmt.Options.NewDBName != nil {
 		if restoreStmt.DescriptorCoverage == tree.AllDescriptors ||
@@ -1032,6 +1473,45 @@ func restorePlanHook(
 		}
 	}
 
+	var latestValueFn, latestAsOfFn func() (string, error)
+	if restoreStmt.Options.LatestValue != nil || restoreStmt.Options.LatestAsOf != nil {
+		if restoreStmt.Options.LatestValue != nil && restoreStmt.Options.LatestAsOf != nil {
+			err := errors.Errorf("cannot set both %q and %q options", restoreOptLatestValue, restoreOptLatestAsOf)
+			return nil, nil, nil, false, err
+		}
+		if restoreStmt.Subdir == nil {
+			err := errors.Errorf("%q and %q can only be used with the following syntax:"+
+				" 'RESTORE [target] FROM LATEST IN [destination]'", restoreOptLatestValue, restoreOptLatestAsOf)
+			return nil, nil, nil, false, err
+		}
+		if restoreStmt.Options.LatestValue != nil {
+			latestValueFn, err = p.TypeAsString(ctx, restoreStmt.Options.LatestValue, "RESTORE")
+		} else {
+			latestAsOfFn, err = p.TypeAsString(ctx, restoreStmt.Options.LatestAsOf, "RESTORE")
+		}
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	var minLatestTimeFn func() (string, error)
+	if restoreStmt.Options.MinLatestTime != nil {
+		if restoreStmt.Options.LatestValue != nil || restoreStmt.Options.LatestAsOf != nil {
+			err := errors.Errorf("%q cannot be used with %q or %q", restoreOptMinLatestTime,
+				restoreOptLatestValue, restoreOptLatestAsOf)
+			return nil, nil, nil, false, err
+		}
+		if restoreStmt.Subdir == nil {
+			err := errors.Errorf("%q can only be used with the following syntax:"+
+				" 'RESTORE [target] FROM LATEST IN [destination]'", restoreOptMinLatestTime)
+			return nil, nil, nil, false, err
+		}
+		minLatestTimeFn, err = p.TypeAsString(ctx, restoreStmt.Options.MinLatestTime, "RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	var newTenantIDFn func() (*roachpb.TenantID, error)
 	if restoreStmt.Options.AsTenant != nil {
 		if restoreStmt.DescriptorCoverage == tree.AllDescriptors || !restoreStmt.Targets.TenantID.IsSet() {
@@ -1062,12 +1542,37 @@ func restorePlanHook(
 		}
 	}
 
+	var keyOffsetFn func() (descpb.ID, error)
+	if restoreStmt.Options.KeyOffset != nil {
+		if restoreStmt.DescriptorCoverage == tree.AllDescriptors ||
+			restoreStmt.DescriptorCoverage == tree.SystemUsers {
+			err := errors.Errorf("%q cannot be used with a cluster restore", restoreOptKeyOffset)
+			return nil, nil, nil, false, err
+		}
+		if restoreStmt.Options.AsTenant != nil {
+			err := errors.Errorf("%q cannot be used with %q", restoreOptKeyOffset, restoreOptAsTenant)
+			return nil, nil, nil, false, err
+		}
+		fn, err := p.TypeAsString(ctx, restoreStmt.Options.KeyOffset, "RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+		keyOffsetFn = func() (descpb.ID, error) {
+			s, err := fn()
+			if err != nil {
+				return descpb.InvalidID, err
+			}
+			return parseRestoreKeyOffset(s)
+		}
+	}
+
 	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
 		// TODO(dan): Move this span into sql.
 		ctx, span := tracing.ChildSpan(ctx, stmt.StatementTag())
 		defer span.Finish()
 
-		if !(p.ExtendedEvalContext().TxnIsSingleStmt || restoreStmt.Options.Detached) {
+		if !(p.ExtendedEvalContext().TxnIsSingleStmt || restoreStmt.Options.Detached ||
+			restoreStmt.PrepareOnly || restoreStmt.Options.DryRun || restoreStmt.ExplainOnly) {
 			return errors.Errorf("RESTORE cannot be used inside a multi-statement transaction without DETACHED option")
 		}
 
@@ -1085,7 +1590,52 @@ func restorePlanHook(
 		}
 
 		if err := checkPrivilegesForRestore(ctx, restoreStmt, p, from); err != nil {
-			return err
+			if !restoreStmt.Options.DryRun {
+				return err
+			}
+			// A user who may not restore from the backups may not read them either,
+			// so none of the other checks can be run.
+			resultsCh <- restoreDryRunCheck{name: restoreDryRunCheckPrivileges, err: err}.datums()
+			return nil
+		}
+
+		// The backups are resolved in the metadata replica of the collection, if
+		// one is given, and their data is read from the collection.
+		var metadataURI string
+		metadataCollection := from[0][0]
+		if metadataURIFn != nil {
+			if len(from) != 1 || len(from[0]) != 1 {
+				return errors.Errorf("%q cannot be used with locality aware backups", backupOptMetadataURI)
+			}
+			metadataURI, err = metadataURIFn()
+			if err != nil {
+				return err
+			}
+			if err := cloudprivilege.CheckBackupSourcePrivileges(ctx, p, []string{metadataURI}); err != nil {
+				return err
+			}
+			metadataCollection = metadataURI
+		}
+
+		if latestValueFn != nil || latestAsOfFn != nil {
+			if !strings.EqualFold(subdir, backupbase.LatestFileName) {
+				return errors.Errorf("%q and %q can only be used when restoring from LATEST",
+					restoreOptLatestValue, restoreOptLatestAsOf)
+			}
+			subdir, err = pinLatestSubdir(ctx, p, metadataCollection, latestValueFn, latestAsOfFn)
+			if err != nil {
+				return err
+			}
+		}
+		if minLatestTimeFn != nil {
+			if !strings.EqualFold(subdir, backupbase.LatestFileName) {
+				return errors.Errorf("%q can only be used when restoring from LATEST",
+					restoreOptMinLatestTime)
+			}
+			subdir, err = waitForLatestSubdir(ctx, p, metadataCollection, minLatestTimeFn)
+			if err != nil {
+				return err
+			}
 		}
 
 		var endTime hlc.Timestamp
@@ -1113,6 +1663,14 @@ func restorePlanHook(
 			}
 		}
 
+		var kmsByLocality map[string]string
+		if kmsByLocalityFn != nil {
+			kmsByLocality, err = kmsByLocalityFn()
+			if err != nil {
+				return err
+			}
+		}
+
 		var intoDB string
 		if intoDBFn != nil {
 			intoDB, err = intoDBFn()
@@ -1135,6 +1693,45 @@ func restorePlanHook(
 				return err
 			}
 		}
+		var keyOffset descpb.ID
+		if keyOffsetFn != nil {
+			keyOffset, err = keyOffsetFn()
+			if err != nil {
+				return err
+			}
+			hasAdmin, err := p.HasAdminRole(ctx)
+			if err != nil {
+				return err
+			}
+			if !hasAdmin {
+				return pgerror.Newf(pgcode.InsufficientPrivilege,
+					"only users with the admin role are allowed to RESTORE with %q", restoreOptKeyOffset)
+			}
+		}
+
+		var executionLocality roachpb.Locality
+		if executionLocalityFn != nil {
+			filter, err := executionLocalityFn()
+			if err != nil {
+				return err
+			}
+			executionLocality, err = parseExecutionLocality(filter)
+			if err != nil {
+				return err
+			}
+		}
+
+		var regionRemapping map[string]string
+		if remapRegionsFn != nil {
+			entries, err := remapRegionsFn()
+			if err != nil {
+				return err
+			}
+			regionRemapping, err = parseRegionRemapping(entries)
+			if err != nil {
+				return err
+			}
+		}
 
 		// incFrom will contain the directory URIs for incremental backups (i.e.
 		// <prefix>/<subdir>) iff len(From)==1, regardless of the
@@ -1150,16 +1747,152 @@ func restorePlanHook(
 			}
 		}
 
-		return doRestorePlan(ctx, restoreStmt, p, from, incFrom, passphrase, kms, intoDB,
-			newDBName, newTenantID, endTime, resultsCh, subdir)
+		readURIs := append([]string{metadataURI}, incFrom...)
+		for _, uris := range from {
+			readURIs = append(readURIs, uris...)
+		}
+		if err := backupdest.CheckConnectionCapability(ctx, p.ExecCfg(), "RESTORE",
+			connectionpb.CapabilityRestoreRead, readURIs...); err != nil {
+			return err
+		}
+
+		return doRestorePlan(ctx, restoreStmt, p, from, incFrom, metadataURI, passphrase, kms,
+			kmsByLocality, intoDB, newDBName, newTenantID, keyOffset, executionLocality, regionRemapping, endTime, resultsCh,
+			subdir)
 	}
 
+	if restoreStmt.PrepareOnly {
+		return fn, prepareRestoreHeader, nil, false, nil
+	}
+	if restoreStmt.Options.DryRun {
+		return fn, restoreDryRunHeader, nil, false, nil
+	}
+	if restoreStmt.ExplainOnly {
+		return fn, explainHeader, nil, false, nil
+	}
 	if restoreStmt.Options.Detached {
 		return fn, jobs.DetachedJobExecutionResultHeader, nil, false, nil
 	}
 	return fn, jobs.BulkJobExecutionResultHeader, nil, false, nil
 }
 
+// prepareRestoreHeader is the header for PREPARE RESTORE stmt results.
+var prepareRestoreHeader = colinfo.ResultColumns{
+	{Name: "path", Typ: types.String},
+	{Name: "end_time", Typ: types.Timestamp},
+	{Name: "files", Typ: types.Int},
+	{Name: "cached", Typ: types.Bool},
+}
+
+// prepareRestore caches the manifests of the backup layers in uris on this
+// node, so that a later RESTORE of the same backups does not need to read them
+// from external storage again, and emits a row describing each layer.
+func prepareRestore(
+	ctx context.Context,
+	p sql.PlanHookState,
+	uris []string,
+	manifests []backuppb.BackupManifest,
+	encryption *jobspb.BackupEncryptionOptions,
+	resultsCh chan<- tree.Datums,
+) error {
+	mkStore := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI
+	for i, uri := range uris {
+		path, err := cloud.SanitizeExternalStorageURI(uri, nil /* extraParams */)
+		if err != nil {
+			return err
+		}
+		cached, err := func() (bool, error) {
+			store, err := mkStore(ctx, uri, p.User())
+			if err != nil {
+				return false, err
+			}
+			defer store.Close()
+			return backupinfo.PreparedManifests.Add(store, backupbase.BackupManifestName, encryption,
+				&manifests[i])
+		}()
+		if err != nil {
+			return errors.Wrapf(err, "caching manifest of backup %s", path)
+		}
+		endTime, err := tree.MakeDTimestamp(timeutil.Unix(0, manifests[i].EndTime.WallTime), time.Nanosecond)
+		if err != nil {
+			return err
+		}
+		resultsCh <- tree.Datums{
+			tree.NewDString(path),
+			endTime,
+			tree.NewDInt(tree.DInt(len(manifests[i].Files))),
+			tree.MakeDBool(tree.DBool(cached)),
+		}
+	}
+	return nil
+}
+
+// pinLatestSubdir returns the subdirectory of the collection that LATEST should
+// refer to, as specified by the latest_value or latest_as_of options, without
+// consulting the collection's LATEST file. This allows a RESTORE to proceed
+// even if the most recent LATEST file is not yet visible to this node, e.g. on
+// an eventually consistent store.
+func pinLatestSubdir(
+	ctx context.Context,
+	p sql.PlanHookState,
+	collectionURI string,
+	latestValueFn, latestAsOfFn func() (string, error),
+) (string, error) {
+	mkStore := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI
+	if latestValueFn != nil {
+		subdir, err := latestValueFn()
+		if err != nil {
+			return "", err
+		}
+		if subdir == "" || strings.EqualFold(subdir, backupbase.LatestFileName) {
+			return "", errors.Errorf("%q must name a backup subdirectory", restoreOptLatestValue)
+		}
+		if err := backupdest.ValidateLatestSubdir(ctx, collectionURI, subdir, mkStore, p.User()); err != nil {
+			return "", err
+		}
+		return subdir, nil
+	}
+
+	s, err := latestAsOfFn()
+	if err != nil {
+		return "", err
+	}
+	asOf, _, err := tree.ParseDTimestamp(nil /* ctx */, s, time.Microsecond)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing %q", restoreOptLatestAsOf)
+	}
+	return backupdest.FindLatestSubdirAsOf(ctx, collectionURI, asOf.Time, mkStore, p.User())
+}
+
+// waitForLatestSubdir returns the subdirectory named by the LATEST file of the
+// collection once it names a backup ending at or after the HLC timestamp given
+// by the min_latest_time option, so that a restore which follows a backup
+// reads its own writes even through a stale cache or an eventually consistent
+// store.
+func waitForLatestSubdir(
+	ctx context.Context,
+	p sql.PlanHookState,
+	collectionURI string,
+	minLatestTimeFn func() (string, error),
+) (string, error) {
+	s, err := minLatestTimeFn()
+	if err != nil {
+		return "", err
+	}
+	minLatestTime, err := hlc.ParseHLC(s)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing %q as an HLC timestamp", restoreOptMinLatestTime)
+	}
+	ctx, cancel := context.WithTimeout(ctx, minLatestTimeWaitTimeout.Get(&p.ExecCfg().Settings.SV))
+	defer cancel()
+	latest, err := backupdest.WaitForLatestFile(ctx, collectionURI, minLatestTime,
+		p.ExecCfg().DistSQLSrv.ExternalStorageFromURI, p.User())
+	if err != nil {
+		return "", err
+	}
+	return latest.Subdir, nil
+}
+
 // checkRestoreDestinationPrivileges iterates over the External Storage URIs and
 // ensures the user has adequate privileges to use each of them.
 func checkRestoreDestinationPrivileges(
@@ -1168,7 +1901,7 @@ func checkRestoreDestinationPrivileges(
 	// Check destination specific privileges.
 	for _, uris := range from {
 		uris := uris
-		if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, uris); err != nil {
+		if err := cloudprivilege.CheckBackupSourcePrivileges(ctx, p, uris); err != nil {
 			return err
 		}
 	}
@@ -1178,13 +1911,19 @@ func checkRestoreDestinationPrivileges(
 
 // checkRestorePrivilegesOnDatabase check that the user has adequate privileges
 // on the parent database to restore schema objects into the database. This is
-// used to check the privileges required for a `RESTORE TABLE`.
+// used to check the privileges required for a `RESTORE TABLE`, which either the
+// RESTORE or the RESTOREINTO privilege on the database allow.
 func checkRestorePrivilegesOnDatabase(
 	ctx context.Context, p sql.PlanHookState, parentDB catalog.DatabaseDescriptor,
 ) (shouldBufferNotice bool, err error) {
 	if err := p.CheckPrivilege(ctx, parentDB, privilege.RESTORE); err == nil {
 		return false, nil
 	}
+	if p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.V23_1FineGrainedRestorePrivileges) {
+		if err := p.CheckPrivilege(ctx, parentDB, privilege.RESTOREINTO); err == nil {
+			return false, nil
+		}
+	}
 
 	if err := p.CheckPrivilege(ctx, parentDB, privilege.CREATE); err != nil {
 		notice := fmt.Sprintf("%s RESTORE TABLE, user %s will exclusively require the "+
@@ -1340,11 +2079,16 @@ func doRestorePlan(
 	p sql.PlanHookState,
 	from [][]string,
 	incFrom []string,
+	metadataURI string,
 	passphrase string,
 	kms []string,
+	kmsByLocality map[string]string,
 	intoDB string,
 	newDBName string,
 	newTenantID *roachpb.TenantID,
+	keyOffset descpb.ID,
+	executionLocality roachpb.Locality,
+	regionRemapping map[string]string,
 	endTime hlc.Timestamp,
 	resultsCh chan<- tree.Datums,
 	subdir string,
@@ -1357,24 +2101,44 @@ func doRestorePlan(
 		return errors.Errorf("RESTORE FROM ... IN can only by used against a single collection path (per-locality)")
 	}
 
+	// A dry run reports the conflicts and region mismatches that would fail the
+	// restore rather than failing.
+	var conflictsErr, regionsErr error
+	failOrReport := func(err error, reported *error) error {
+		if err == nil || !restoreStmt.Options.DryRun {
+			return err
+		}
+		if *reported == nil {
+			*reported = err
+		}
+		return nil
+	}
+
 	if restoreStmt.DescriptorCoverage == tree.AllDescriptors {
 		// We do this before resolving the backup manifest since resolving the
 		// backup manifest can take a while.
-		if err := checkForConflictingDescriptors(ctx, p.ExecCfg()); err != nil {
+		if err := failOrReport(checkForConflictingDescriptors(ctx, p.ExecCfg()), &conflictsErr); err != nil {
 			return err
 		}
 	}
 
+	// LATEST, the manifests and the incremental backups are read from the
+	// metadata replica of the collection, if one is given.
+	metadataFrom := from[0]
+	if metadataURI != "" {
+		metadataFrom = []string{metadataURI}
+	}
+
 	var fullyResolvedSubdir string
 
 	if strings.EqualFold(subdir, backupbase.LatestFileName) {
 		// set subdir to content of latest file
-		latest, err := backupdest.ReadLatestFile(ctx, from[0][0],
+		latest, err := backupdest.ReadLatestFile(ctx, metadataFrom[0],
 			p.ExecCfg().DistSQLSrv.ExternalStorageFromURI, p.User())
 		if err != nil {
 			return err
 		}
-		fullyResolvedSubdir = latest
+		fullyResolvedSubdir = latest.Subdir
 	} else {
 		fullyResolvedSubdir = subdir
 	}
@@ -1383,13 +2147,17 @@ func doRestorePlan(
 	if err != nil {
 		return err
 	}
+	metadataBaseDirectory, err := backuputils.AppendPaths(metadataFrom, fullyResolvedSubdir)
+	if err != nil {
+		return err
+	}
 
 	fullyResolvedIncrementalsDirectory, err := backupdest.ResolveIncrementalsBackupLocation(
 		ctx,
 		p.User(),
 		p.ExecCfg(),
 		incFrom,
-		from[0],
+		metadataFrom,
 		fullyResolvedSubdir,
 	)
 	if err != nil {
@@ -1408,7 +2176,7 @@ func doRestorePlan(
 	// Note that incremental _backup_ requests to this location will fail loudly instead.
 	mkStore := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI
 	baseStores, cleanupFn, err := backupdest.MakeBackupDestinationStores(ctx, p.User(), mkStore,
-		fullyResolvedBaseDirectory)
+		metadataBaseDirectory)
 	if err != nil {
 		return err
 	}
@@ -1449,26 +2217,13 @@ func doRestorePlan(
 		if err != nil {
 			return err
 		}
-
-		// A backup could have been encrypted with multiple KMS keys that
-		// are stored across ENCRYPTION-INFO files. Iterate over all
-		// ENCRYPTION-INFO files to check if the KMS passed in during
-		// restore has been used to encrypt the backup at least once.
-		var defaultKMSInfo *jobspb.BackupEncryptionOptions_KMSInfo
-		for _, encFile := range opts {
-			defaultKMSInfo, err = backupencryption.ValidateKMSURIsAgainstFullBackup(ctx, kms,
-				backupencryption.NewEncryptedDataKeyMapFromProtoMap(encFile.EncryptedDataKeyByKMSMasterKeyID),
-				&kmsEnv)
-			if err == nil {
-				break
-			}
-		}
+		encryption, err = backupencryption.MakeKMSEncryptionOptions(ctx, kms, opts, &kmsEnv)
 		if err != nil {
 			return err
 		}
-		encryption = &jobspb.BackupEncryptionOptions{
-			Mode:    jobspb.EncryptionMode_KMS,
-			KMSInfo: defaultKMSInfo,
+		if err := backupencryption.MakeLocalityKMSEncryptionOptions(ctx, encryption, kmsByLocality,
+			opts, &kmsEnv); err != nil {
+			return err
 		}
 	}
 
@@ -1482,12 +2237,36 @@ func doRestorePlan(
 	var mainBackupManifests []backuppb.BackupManifest
 	var localityInfo []jobspb.RestoreDetails_BackupLocalityInfo
 	var memReserved int64
-	if len(from) <= 1 {
+	var deferredLayers *jobspb.RestoreDetails_DeferredLayerResolution
+	if minLayers := deferredLayerResolutionMinLayers.Get(&p.ExecCfg().Settings.SV); minLayers > 0 &&
+		restoreStmt.Options.Detached && endTime.IsEmpty() && len(from) <= 1 && len(incStores) > 0 {
+		// Reading the manifest of every layer of a long chain can take a while,
+		// so a detached restore only reads those that planning needs and leaves
+		// the others to the job.
+		incSubdirs, err := backupdest.FindPriorBackups(ctx, incStores[0], false /* includeManifest */)
+		if err != nil {
+			return err
+		}
+		if int64(len(incSubdirs)+1) >= minLayers {
+			deferredLayers = &jobspb.RestoreDetails_DeferredLayerResolution{
+				BaseDirs:           metadataBaseDirectory,
+				IncrementalDirs:    fullyResolvedIncrementalsDirectory,
+				IncrementalSubdirs: incSubdirs,
+			}
+		}
+	}
+	if deferredLayers != nil {
+		defaultURIs, mainBackupManifests, memReserved, err = backupdest.ResolveBackupChainEnds(
+			ctx, &mem, baseStores, mkStore, metadataBaseDirectory, fullyResolvedIncrementalsDirectory,
+			deferredLayers.IncrementalSubdirs, encryption, &kmsEnv, p.User(),
+		)
+	} else if len(from) <= 1 {
 		// Incremental layers are not specified explicitly. They will be searched for automatically.
 		// This could be either INTO-syntax, OR TO-syntax.
 		defaultURIs, mainBackupManifests, localityInfo, memReserved, err = backupdest.ResolveBackupManifests(
-			ctx, &mem, baseStores, incStores, mkStore, fullyResolvedBaseDirectory,
+			ctx, &mem, baseStores, incStores, mkStore, metadataBaseDirectory,
 			fullyResolvedIncrementalsDirectory, endTime, encryption, &kmsEnv, p.User(),
+			false, /* elideFiles */
 		)
 	} else {
 		// Incremental layers are specified explicitly.
@@ -1504,15 +2283,42 @@ func doRestorePlan(
 		mem.Shrink(ctx, memReserved)
 	}()
 
+	var metadataURIs []string
+	if metadataURI != "" {
+		// The data of the backups is read from the collection itself, which the
+		// replica may lag behind, or which is the only place that has it if the
+		// backups were written with metadata_uri.
+		metadataURIs = defaultURIs
+		defaultURIs, err = backupdest.RebaseURIs(defaultURIs, metadataURI, from[0][0])
+		if err != nil {
+			return err
+		}
+	} else {
+		for i := range mainBackupManifests {
+			if dataURI := mainBackupManifests[i].DataURI; dataURI != "" {
+				uri := defaultURIs[i]
+				// Only the manifests of the first and last layers of a chain whose
+				// layers are resolved by the job were read.
+				if deferredLayers != nil && i > 0 {
+					uri = defaultURIs[len(defaultURIs)-1]
+				}
+				return errors.WithHintf(
+					errors.Newf("the data of backup %s was written apart from its metadata, to %s",
+						backuputils.RedactURIForErrorMessage(uri), dataURI),
+					"restore it from the collection holding its data, passing the collection"+
+						" holding its metadata with the %q option", backupOptMetadataURI)
+			}
+		}
+	}
+
 	currentVersion := p.ExecCfg().Settings.Version.ActiveVersion(ctx)
 	for i := range mainBackupManifests {
-		if v := mainBackupManifests[i].ClusterVersion; v.Major != 0 {
-			// This is the "cluster" version that does not change between patches but
-			// rather just tracks migrations run. If the backup is more migrated than
-			// this cluster, then this cluster isn't ready to restore this backup.
-			if currentVersion.Less(v) {
-				return errors.Errorf("backup from version %s is newer than current version %s", v, currentVersion)
-			}
+		if err := backupread.CheckReadable(&mainBackupManifests[i],
+			p.ExecCfg().Settings.Version.BinaryVersion()); err != nil {
+			return err
+		}
+		if err := checkBackupVersion(currentVersion, &mainBackupManifests[i]); err != nil {
+			return err
 		}
 	}
 
@@ -1539,30 +2345,20 @@ func doRestorePlan(
 	// be caught by backups.
 	wasOffline := make(map[tableAndIndex]hlc.Timestamp)
 
-	for _, m := range mainBackupManifests {
-		spans := roachpb.Spans(m.Spans)
-		for i := range m.Descriptors {
-			table, _, _, _, _ := descpb.GetDescriptors(&m.Descriptors[i])
-			if table == nil {
-				continue
-			}
-			index := table.GetPrimaryIndex()
-			if len(index.Interleave.Ancestors) > 0 || len(index.InterleavedBy) > 0 {
-				return errors.Errorf("restoring interleaved tables is no longer allowed. table %s was found to be interleaved", table.Name)
-			}
-			if err := catalog.ForEachNonDropIndex(
-				tabledesc.NewBuilder(table).BuildImmutable().(catalog.TableDescriptor),
-				func(index catalog.Index) error {
-					if index.Adding() && spans.ContainsKey(p.ExecCfg().Codec.IndexPrefix(uint32(table.ID), uint32(index.GetID()))) {
-						k := tableAndIndex{tableID: table.ID, indexID: index.GetID()}
-						if _, ok := wasOffline[k]; !ok {
-							wasOffline[k] = m.EndTime
-						}
-					}
-					return nil
-				}); err != nil {
-				return err
+	for i := range mainBackupManifests {
+		m := &mainBackupManifests[i]
+		if err := forEachBackfilledIndex(p.ExecCfg().Codec, m, func(k tableAndIndex) {
+			if _, ok := wasOffline[k]; !ok {
+				wasOffline[k] = m.EndTime
 			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	for i := range mainBackupManifests {
+		if err := backupread.CheckRestorable(&mainBackupManifests[i]); err != nil {
+			return err
 		}
 	}
 
@@ -1612,11 +2408,31 @@ func doRestorePlan(
 		}
 	}
 
+	var renamedTableSchemas map[descpb.ID]string
+	if len(restoreStmt.TableRenames) > 0 {
+		renamedTableSchemas, err = renameTargetTableDescriptors(sqlDescs, descsByTablePattern,
+			restoreStmt.Targets.Tables.TablePatterns, restoreStmt.TableRenames)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := remapRegions(sqlDescs, regionRemapping); err != nil {
+		return err
+	}
+
 	var oldTenantID *roachpb.TenantID
 	if len(tenants) > 0 {
 		if !p.ExecCfg().Codec.ForSystemTenant() {
 			return pgerror.Newf(pgcode.InsufficientPrivilege, "only the system tenant can restore other tenants")
 		}
+		if keyOffset != descpb.InvalidID {
+			if len(tenants) != 1 {
+				return errors.Errorf("%q option can only be used when restoring a single tenant", restoreOptKeyOffset)
+			}
+			id := roachpb.MakeTenantID(tenants[0].ID + uint64(keyOffset))
+			newTenantID = &id
+		}
 		if newTenantID != nil {
 			if len(tenants) != 1 {
 				return errors.Errorf("%q option can only be used when restoring a single tenant", restoreOptAsTenant)
@@ -1629,7 +2445,10 @@ func doRestorePlan(
 				return err
 			}
 			if res != nil {
-				return errors.Errorf("tenant %s already exists", newTenantID)
+				if err := failOrReport(errors.Errorf("tenant %s already exists", newTenantID),
+					&conflictsErr); err != nil {
+					return err
+				}
 			}
 			old := roachpb.MakeTenantID(tenants[0].ID)
 			tenants[0].ID = newTenantID.ToUint64()
@@ -1644,7 +2463,10 @@ func doRestorePlan(
 					return err
 				}
 				if res != nil {
-					return errors.Errorf("tenant %d already exists", i.ID)
+					if err := failOrReport(errors.Errorf("tenant %d already exists", i.ID),
+						&conflictsErr); err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -1672,7 +2494,7 @@ func doRestorePlan(
 	}
 
 	if !restoreStmt.Options.SkipLocalitiesCheck {
-		if err := checkClusterRegions(ctx, p, typesByID); err != nil {
+		if err := failOrReport(checkClusterRegions(ctx, p, typesByID), &regionsErr); err != nil {
 			return err
 		}
 	}
@@ -1694,6 +2516,22 @@ func doRestorePlan(
 		}
 	}
 
+	var onConflict string
+	if restoreStmt.Options.OnConflict != nil {
+		onConflictFn, err := p.TypeAsString(ctx, restoreStmt.Options.OnConflict, "RESTORE")
+		if err != nil {
+			return err
+		}
+		onConflict, err = onConflictFn()
+		if err != nil {
+			return err
+		}
+	}
+	onConflict, err = resolveOnConflict(onConflict, restoreStmt.DescriptorCoverage)
+	if err != nil {
+		return err
+	}
+
 	var asOfInterval int64
 	if !endTime.IsEmpty() {
 		asOfInterval = endTime.WallTime - p.ExtendedEvalContext().StmtTimestamp.UnixNano()
@@ -1707,6 +2545,70 @@ func doRestorePlan(
 		return err
 	}
 
+	var projectedColumns []string
+	if restoreStmt.Options.Columns != nil {
+		// The columns are projected here to report any problem with them before
+		// the job is created, and again by the job from the backup.
+		projectedColumns = restoreStmt.Options.Columns.ToStrings()
+		if len(filteredTablesByID) != 1 {
+			return errors.Errorf("the %s option can only be used when restoring a single table",
+				restoreOptColumns)
+		}
+		for _, table := range filteredTablesByID {
+			if !table.IsTable() {
+				return errors.Errorf("the %s option cannot be used to restore %q, which is not a table",
+					restoreOptColumns, table.GetName())
+			}
+			if _, err := projectRestoredTable(table, projectedColumns); err != nil {
+				return err
+			}
+		}
+	}
+
+	if restoreStmt.PrepareOnly {
+		// Everything the RESTORE would need to plan has been resolved and checked,
+		// so the backups are ready to be restored.
+		return prepareRestore(ctx, p, defaultURIs, mainBackupManifests, encryption, resultsCh)
+	}
+
+	if restoreStmt.ExplainOnly {
+		return explainRestore(ctx, p, from[0][0], fullyResolvedSubdir, fullyResolvedIncrementalsDirectory,
+			defaultURIs, mainBackupManifests, filteredTablesByID, tenants, oldTenantID,
+			restoreStmt.Options.SchemaOnly, executionLocality, resultsCh)
+	}
+
+	if restoreStmt.Options.DryRun {
+		privilegesCheck := restoreDryRunCheck{name: restoreDryRunCheckPrivileges}
+		descriptorsCheck := restoreDryRunCheck{name: restoreDryRunCheckDescriptors, err: conflictsErr}
+		// A cluster restore drops the default databases of the cluster, which
+		// checkForConflictingDescriptors checked are its only ones, before
+		// restoring its own, so only the descriptors of other restores are
+		// checked against those of the cluster.
+		if restoreStmt.DescriptorCoverage != tree.AllDescriptors && conflictsErr == nil {
+			if _, _, err := allocateDescriptorRewrites(ctx, p, databasesByID, schemasByID,
+				filteredTablesByID, typesByID, functionsByID, restoreDBs, restoreStmt.DescriptorCoverage,
+				restoreStmt.Options, intoDB, newDBName, onConflict, keyOffset); err != nil {
+				if pgerror.GetPGCode(err) == pgcode.InsufficientPrivilege {
+					privilegesCheck.err = err
+					descriptorsCheck.skipped = true
+				} else {
+					descriptorsCheck.err = err
+				}
+			}
+		}
+		emitRestoreDryRunReport(resultsCh, databasesByID, schemasByID, filteredTablesByID, typesByID,
+			functionsByID,
+			privilegesCheck,
+			descriptorsCheck,
+			restoreDryRunCheck{
+				name:    restoreDryRunCheckRegions,
+				err:     regionsErr,
+				skipped: restoreStmt.Options.SkipLocalitiesCheck,
+			},
+		)
+		return nil
+	}
+
 	// When running a full cluster restore, we drop the defaultdb and postgres
 	// databases that are present in a new cluster.
 	// This is done so that they can be restored the same way any other user
@@ -1717,21 +2619,59 @@ func doRestorePlan(
 		}
 	}
 
-	descriptorRewrites, err := allocateDescriptorRewrites(
-		ctx,
-		p,
-		databasesByID,
-		schemasByID,
-		filteredTablesByID,
-		typesByID,
-		functionsByID,
-		restoreDBs,
-		restoreStmt.DescriptorCoverage,
-		restoreStmt.Options,
-		intoDB,
-		newDBName)
-	if err != nil {
-		return err
+	var descriptorRewrites jobspb.DescRewriteMap
+	var replacedDescs []jobspb.RestoreDetails_ReplacedDescriptor
+	var indexRestore *jobspb.RestoreDetails_IndexRestore
+	if restoreStmt.Index != nil {
+		// A RESTORE INDEX restores into an existing table, which is the only
+		// descriptor of its job, rather than creating any descriptors.
+		var table *tabledesc.Mutable
+		table, descriptorRewrites, indexRestore, err = planIndexRestore(ctx, p, restoreStmt.Index,
+			filteredTablesByID, mainBackupManifests[len(mainBackupManifests)-1].RowFilter)
+		if err != nil {
+			return err
+		}
+		databasesByID, schemasByID, typesByID, functionsByID = nil, nil, nil, nil
+		filteredTablesByID = map[descpb.ID]*tabledesc.Mutable{table.GetID(): table}
+		revalidateIndexes = nil
+	} else {
+		descriptorRewrites, replacedDescs, err = allocateDescriptorRewrites(
+			ctx,
+			p,
+			databasesByID,
+			schemasByID,
+			filteredTablesByID,
+			typesByID,
+			functionsByID,
+			restoreDBs,
+			restoreStmt.DescriptorCoverage,
+			restoreStmt.Options,
+			intoDB,
+			newDBName,
+			onConflict,
+			keyOffset)
+		if err != nil {
+			return err
+		}
+	}
+	if len(renamedTableSchemas) > 0 {
+		if err := remapRenamedTableSchemas(ctx, p, renamedTableSchemas, filteredTablesByID,
+			descriptorRewrites); err != nil {
+			return err
+		}
+	}
+	if restoreStmt.Options.RestoreFKToExisting {
+		// The tables referenced by foreign keys are resolved by the names they
+		// had when backed up, which are recorded in the backup even if they are
+		// not restored.
+		backupDescs, _, err := backupinfo.LoadSQLDescsFromBackupsAtTime(mainBackupManifests, endTime)
+		if err != nil {
+			return err
+		}
+		if err := resolveFKsToExistingTables(ctx, p, backupDescs, filteredTablesByID,
+			descriptorRewrites, restoreStmt.Options.SkipMissingFKs); err != nil {
+			return err
+		}
 	}
 	var fromDescription [][]string
 	if len(from) == 1 {
@@ -1744,10 +2684,12 @@ func doRestorePlan(
 		restoreStmt,
 		fromDescription,
 		fullyResolvedIncrementalsDirectory,
+		metadataURI,
 		restoreStmt.Options,
 		intoDB,
 		newDBName,
-		kms)
+		kms,
+		kmsByLocality)
 	if err != nil {
 		return err
 	}
@@ -1781,8 +2723,12 @@ func doRestorePlan(
 	if newDBName != "" {
 		overrideDBName = newDBName
 	}
-	if err := rewrite.TableDescs(tables, descriptorRewrites, overrideDBName); err != nil {
-		return err
+	if indexRestore == nil {
+		// The table of a RESTORE INDEX is the existing one, which needs no
+		// rewriting.
+		if err := rewrite.TableDescs(tables, descriptorRewrites, overrideDBName); err != nil {
+			return err
+		}
 	}
 	if err := rewrite.DatabaseDescs(databases, descriptorRewrites, map[descpb.ID]struct{}{}); err != nil {
 		return err
@@ -1809,6 +2755,7 @@ func doRestorePlan(
 		EndTime:            endTime,
 		DescriptorRewrites: descriptorRewrites,
 		URIs:               defaultURIs,
+		MetadataURIs:       metadataURIs,
 		BackupLocalityInfo: localityInfo,
 		TableDescs:         encodedTables,
 		Tenants:            tenants,
@@ -1825,10 +2772,35 @@ func doRestorePlan(
 		// compatability.
 		//
 		// TODO(msbutler): Delete in 23.1
-		RestoreSystemUsers: restoreStmt.DescriptorCoverage == tree.SystemUsers,
-		PreRewriteTenantId: oldTenantID,
-		SchemaOnly:         restoreStmt.Options.SchemaOnly,
-		VerifyData:         restoreStmt.Options.VerifyData,
+		RestoreSystemUsers:        restoreStmt.DescriptorCoverage == tree.SystemUsers,
+		PreRewriteTenantId:        oldTenantID,
+		SchemaOnly:                restoreStmt.Options.SchemaOnly,
+		VerifyData:                restoreStmt.Options.VerifyData,
+		DeferredData:              restoreStmt.Options.DeferredData,
+		SkipStatistics:            restoreStmt.Options.SkipStatistics,
+		SkipComments:              restoreStmt.Options.SkipComments,
+		SkipZoneConfigs:           restoreStmt.Options.SkipZoneConfigs,
+		SkipJobs:                  restoreStmt.Options.SkipJobs,
+		ReplacedDescriptors:       replacedDescs,
+		PauseRowLevelTTLSchedules: restoreStmt.Options.PauseTTLSchedules,
+		ExecutionLocality:         executionLocality,
+		RegionRemapping:           regionRemapping,
+		IndexRestore:              indexRestore,
+		ProjectedColumns:          projectedColumns,
+		DeferConstraintValidation: restoreStmt.Options.DeferConstraintValidation,
+
+		DeferredLayerResolution: deferredLayers,
+	}
+	if subdir != "" {
+		// The backups were resolved in a collection, in which the job protects
+		// the layers it reads while it runs.
+		restoreDetails.CollectionURI = from[0][0]
+	}
+	if latest := mainBackupManifests[len(mainBackupManifests)-1]; latest.RowFilter != "" {
+		restoreDetails.RowFilter = latest.RowFilter
+		restoreDetails.RowFilterSpans = latest.Spans
+		p.BufferClientNotice(ctx, pgnotice.Newf(
+			"the backup being restored only contains the rows matching %s", latest.RowFilter))
 	}
 
 	jr := jobs.Record{
@@ -2035,6 +3007,128 @@ func renameTargetDatabaseDescriptor(
 	return nil
 }
 
+// renameTargetTableDescriptors applies the renames of a `RESTORE TABLE a AS x,
+// ...` statement to the table descriptors being restored. renames[i] applies to
+// the table matched by patterns[i].
+//
+// Only the object name is updated here. If a rename also names a schema, the
+// schema is returned keyed by the ID of the table, to be resolved by
+// remapRenamedTableSchemas once the table's target database is known.
+func renameTargetTableDescriptors(
+	sqlDescs []catalog.Descriptor,
+	descsByTablePattern map[tree.TablePattern]catalog.Descriptor,
+	patterns tree.TablePatterns,
+	renames tree.RestoreTableRenames,
+) (map[descpb.ID]string, error) {
+	if len(patterns) != len(renames) {
+		return nil, errors.AssertionFailedf(
+			"expected %d table patterns for %d table renames", len(patterns), len(renames))
+	}
+
+	tablesByID := make(map[descpb.ID]*tabledesc.Mutable)
+	for _, desc := range sqlDescs {
+		if tbl, ok := desc.(*tabledesc.Mutable); ok {
+			tablesByID[tbl.GetID()] = tbl
+		}
+	}
+
+	newNames := make(map[descpb.ID]*tree.UnresolvedObjectName, len(renames))
+	// Renamed tables are identified by their schema qualifier, if any, and their
+	// new name so that two tables cannot be restored under the same name.
+	seen := make(map[string]descpb.ID, len(renames))
+	for i := range renames {
+		rename := &renames[i]
+		desc, ok := descsByTablePattern[patterns[i]]
+		if !ok {
+			return nil, errors.AssertionFailedf("no descriptor resolved for table %s", rename.Table)
+		}
+		tbl, ok := tablesByID[desc.GetID()]
+		if !ok {
+			return nil, pgerror.Newf(pgcode.WrongObjectType, "%s is not a table", rename.Table)
+		}
+		if rename.NewName.HasExplicitCatalog() {
+			return nil, errors.WithHintf(
+				pgerror.Newf(pgcode.FeatureNotSupported,
+					"cannot restore table %s as %s: the new name may not specify a database",
+					rename.Table, rename.NewName),
+				"use the %q option to restore into a different database", restoreOptIntoDB)
+		}
+		if _, ok := newNames[tbl.GetID()]; ok {
+			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+				"table %s is renamed more than once", rename.Table)
+		}
+		key := rename.NewName.String()
+		if _, ok := seen[key]; ok {
+			return nil, pgerror.Newf(pgcode.DuplicateRelation,
+				"more than one table would be restored as %s", rename.NewName)
+		}
+		seen[key] = tbl.GetID()
+		newNames[tbl.GetID()] = rename.NewName
+	}
+
+	// Views store their query as text, so the names of the tables they
+	// reference cannot be changed without invalidating them.
+	for _, tbl := range tablesByID {
+		if !tbl.IsView() {
+			continue
+		}
+		for _, id := range tbl.DependsOn {
+			if _, ok := newNames[id]; ok && id != tbl.GetID() {
+				return nil, pgerror.Newf(pgcode.DependentObjectsStillExist,
+					"cannot rename table %q since view %q being restored depends on it",
+					tablesByID[id].GetName(), tbl.GetName())
+			}
+		}
+	}
+
+	schemas := make(map[descpb.ID]string)
+	for id, newName := range newNames {
+		tablesByID[id].SetName(newName.Object())
+		if newName.HasExplicitSchema() {
+			schemas[id] = newName.Schema()
+		}
+	}
+	return schemas, nil
+}
+
+// remapRenamedTableSchemas points each table in schemasByTableID at the named
+// schema in the table's target database, which must already exist.
+func remapRenamedTableSchemas(
+	ctx context.Context,
+	p sql.PlanHookState,
+	schemasByTableID map[descpb.ID]string,
+	tablesByID map[descpb.ID]*tabledesc.Mutable,
+	descriptorRewrites jobspb.DescRewriteMap,
+) error {
+	return sql.DescsTxn(ctx, p.ExecCfg(), func(ctx context.Context, txn *kv.Txn, col *descs.Collection) error {
+		for id, scName := range schemasByTableID {
+			table, ok := tablesByID[id]
+			if !ok {
+				// The table was filtered out of the restore.
+				continue
+			}
+			rw, ok := descriptorRewrites[id]
+			if !ok {
+				return errors.AssertionFailedf("missing rewrite for table %d", id)
+			}
+			schemaID, err := col.Direct().LookupSchemaID(ctx, txn, rw.ParentID, scName)
+			if err != nil {
+				return err
+			}
+			if schemaID == descpb.InvalidID {
+				return pgerror.Newf(pgcode.InvalidSchemaName,
+					"a schema named %q needs to exist to restore table %q", scName, table.GetName())
+			}
+			tableName := tree.NewUnqualifiedTableName(tree.Name(table.GetName()))
+			if err := col.Direct().CheckObjectCollision(ctx, txn, rw.ParentID, schemaID, tableName); err != nil {
+				return err
+			}
+			rw.ParentSchemaID = schemaID
+		}
+		return nil
+	})
+}
+
 // ensureMultiRegionDatabaseRestoreIsAllowed returns an error if restoring a
 // multi-region database is not allowed.
 func ensureMultiRegionDatabaseRestoreIsAllowed(
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
@@ -28,7 +29,6 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
-	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/errors"
 )
 
@@ -47,6 +47,14 @@ var replanRestoreFrequency = settings.RegisterDurationSetting(
 	settings.PositiveDuration,
 )
 
+var replanRestoreOnDrainFrequency = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.restore.drain_replan_frequency",
+	"frequency at which RESTORE checks whether a node running part of its flow has begun draining, to restart the flow from the last checkpoint without it (0=disabled)",
+	10*time.Second,
+	settings.NonNegativeDuration,
+)
+
 // distRestore plans a 2 stage distSQL flow for a distributed restore. It
 // streams back progress updates over the given progCh. The first stage is a
 // splitAndScatter processor on every node that is running a compatible version.
@@ -68,24 +76,15 @@ func distRestore(
 	tenantRekeys []execinfrapb.TenantRekey,
 	restoreTime hlc.Timestamp,
 	validateOnly bool,
+	executionLocality roachpb.Locality,
 	progCh chan *execinfrapb.RemoteProducerMetadata_BulkProcessorProgress,
 ) error {
 	defer close(progCh)
 	var noTxn *kv.Txn
 
 	if encryption != nil && encryption.Mode == jobspb.EncryptionMode_KMS {
-		kms, err := cloud.KMSFromURI(ctx, encryption.KMSInfo.Uri, kmsEnv)
-		if err != nil {
-			return err
-		}
-		defer func() {
-			err := kms.Close()
-			if err != nil {
-				log.Infof(ctx, "failed to close KMS: %+v", err)
-			}
-		}()
-
-		encryption.Key, err = kms.Decrypt(ctx, encryption.KMSInfo.EncryptedDataKey)
+		var err error
+		encryption.Key, err = backupencryption.GetEncryptionKey(ctx, encryption, kmsEnv)
 		if err != nil {
 			return errors.Wrap(err,
 				"failed to decrypt data key before starting BackupDataProcessor")
@@ -97,10 +96,17 @@ func distRestore(
 	if encryption != nil {
 		fileEncryption = &roachpb.FileEncryptionOptions{Key: encryption.Key}
 	}
+	fileEncryptionByLocalityKV, err := backupencryption.GetLocalityEncryptionKeys(ctx, encryption, kmsEnv)
+	if err != nil {
+		return errors.Wrap(err,
+			"failed to decrypt locality data keys before starting RestoreDataProcessor")
+	}
 
 	makePlan := func(ctx context.Context, dsp *sql.DistSQLPlanner) (*sql.PhysicalPlan, *sql.PlanningCtx, error) {
 
-		planCtx, sqlInstanceIDs, err := dsp.SetupAllNodesPlanning(ctx, execCtx.ExtendedEvalContext(), execCtx.ExecCfg())
+		planCtx, sqlInstanceIDs, err := dsp.SetupAllNodesPlanningWithLocalityFilter(
+			ctx, execCtx.ExtendedEvalContext(), execCtx.ExecCfg(), executionLocality,
+		)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -121,7 +127,9 @@ func distRestore(
 			TenantRekeys: tenantRekeys,
 			PKIDs:        pkIDs,
 			ValidateOnly: validateOnly,
+			UserProto:    execCtx.User().EncodeProto(),
 		}
+		restoreDataSpec.EncryptionByLocalityKV = fileEncryptionByLocalityKV
 
 		if len(splitAndScatterSpecs) == 0 {
 			// We should return an error here as there are no nodes that are compatible,
@@ -248,10 +256,14 @@ func distRestore(
 		sql.ReplanOnChangedFraction(func() float64 { return replanRestoreThreshold.Get(execCtx.ExecCfg().SV()) }),
 		func() time.Duration { return replanRestoreFrequency.Get(execCtx.ExecCfg().SV()) },
 	)
+	drainChecker, stopDrainChecker := sql.DrainingInstanceChecker(p, execCtx,
+		func() time.Duration { return replanRestoreOnDrainFrequency.Get(execCtx.ExecCfg().SV()) },
+	)
 
 	g := ctxgroup.WithContext(ctx)
 	g.GoCtx(func(ctx context.Context) error {
 		defer stopReplanner()
+		defer stopDrainChecker()
 
 		metaFn := func(_ context.Context, meta *execinfrapb.ProducerMetadata) error {
 			if meta.BulkProcessorProgress != nil {
@@ -283,6 +295,7 @@ func distRestore(
 	})
 
 	g.GoCtx(replanner)
+	g.GoCtx(drainChecker)
 
 	return g.Wait()
 }
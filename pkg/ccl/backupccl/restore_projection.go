@@ -0,0 +1,383 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemaexpr"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// A RESTORE of a single table with the columns option restores only some of
+// the columns of the table: the others are removed from the restored table
+// before its data is ingested, along with the secondary indexes and
+// constraints that use them, so that the data of those indexes is never read,
+// and their values are dropped from the rows of the primary index as they are
+// ingested.
+
+// projectRestoredTable removes the columns of table that are not among
+// columns from it, along with the indexes, constraints and column families
+// that use them, and returns the IDs of the removed columns.
+//
+// The hidden and inaccessible columns of table, such as rowid, are kept
+// whether or not they are listed, unless they are computed from a removed
+// column. The visible columns of the primary key must be listed, as must the
+// columns that the kept computed columns and the TTL expiration expression are
+// computed from.
+func projectRestoredTable(
+	table *tabledesc.Mutable, columns []string,
+) (catalog.TableColSet, error) {
+	var dropped catalog.TableColSet
+	if len(table.Mutations) > 0 {
+		return dropped, pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+			"cannot restore a subset of the columns of table %q, which has schema changes in progress",
+			table.GetName())
+	}
+	listed := make(map[string]bool, len(columns))
+	for _, name := range columns {
+		if _, err := table.FindColumnWithName(tree.Name(name)); err != nil {
+			return dropped, err
+		}
+		listed[name] = true
+	}
+
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if !col.Hidden && !col.Inaccessible && !listed[col.Name] {
+			dropped.Add(col.ID)
+		}
+	}
+	for i, id := range table.PrimaryIndex.KeyColumnIDs {
+		if dropped.Contains(id) {
+			return dropped, pgerror.Newf(pgcode.InvalidParameterValue,
+				"%s must include the primary key column %q of table %q",
+				restoreOptColumns, table.PrimaryIndex.KeyColumnNames[i], table.GetName())
+		}
+	}
+	primaryKey := catalog.MakeTableColSet(table.PrimaryIndex.KeyColumnIDs...)
+	// Computed columns cannot reference other computed columns, so a single
+	// pass finds every column computed from a removed one.
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if col.ComputeExpr == nil || dropped.Contains(col.ID) {
+			continue
+		}
+		refs, err := exprColumnIDs(table, *col.ComputeExpr)
+		if err != nil {
+			return dropped, err
+		}
+		if !refs.Intersects(dropped) {
+			continue
+		}
+		if listed[col.Name] || primaryKey.Contains(col.ID) {
+			return dropped, pgerror.Newf(pgcode.InvalidParameterValue,
+				"%s must include the columns that column %q of table %q is computed from",
+				restoreOptColumns, col.Name, table.GetName())
+		}
+		dropped.Add(col.ID)
+	}
+	if ttl := table.RowLevelTTL; ttl != nil && ttl.HasExpirationExpr() {
+		refs, err := exprColumnIDs(table, string(ttl.ExpirationExpr))
+		if err != nil {
+			return dropped, err
+		}
+		if refs.Intersects(dropped) {
+			return dropped, pgerror.Newf(pgcode.InvalidParameterValue,
+				"%s must include the columns of the TTL expiration expression of table %q",
+				restoreOptColumns, table.GetName())
+		}
+	}
+	if dropped.Empty() {
+		return dropped, nil
+	}
+
+	// The predicates of partial indexes and constraints are resolved against
+	// the columns of the table, so they are all checked before any column is
+	// removed.
+	indexes := make([]descpb.IndexDescriptor, 0, len(table.Indexes))
+	for _, idx := range table.Indexes {
+		uses := catalog.MakeTableColSet(idx.KeyColumnIDs...)
+		for _, id := range idx.StoreColumnIDs {
+			uses.Add(id)
+		}
+		if idx.IsPartial() {
+			refs, err := exprColumnIDs(table, idx.Predicate)
+			if err != nil {
+				return dropped, err
+			}
+			uses.UnionWith(refs)
+		}
+		if !uses.Intersects(dropped) {
+			indexes = append(indexes, idx)
+		}
+	}
+	uniques := make([]descpb.UniqueWithoutIndexConstraint, 0, len(table.UniqueWithoutIndexConstraints))
+	for _, u := range table.UniqueWithoutIndexConstraints {
+		uses := catalog.MakeTableColSet(u.ColumnIDs...)
+		if u.IsPartial() {
+			refs, err := exprColumnIDs(table, u.Predicate)
+			if err != nil {
+				return dropped, err
+			}
+			uses.UnionWith(refs)
+		}
+		if !uses.Intersects(dropped) {
+			uniques = append(uniques, u)
+		}
+	}
+	table.Indexes = indexes
+	table.UniqueWithoutIndexConstraints = uniques
+
+	checks := table.Checks[:0]
+	for _, check := range table.Checks {
+		if !catalog.MakeTableColSet(check.ColumnIDs...).Intersects(dropped) {
+			checks = append(checks, check)
+		}
+	}
+	table.Checks = checks
+
+	// A foreign key references columns of the table either from it or, if it
+	// references the table itself, to it.
+	fkUsesDropped := func(fk *descpb.ForeignKeyConstraint) bool {
+		uses := func(tableID descpb.ID, ids []descpb.ColumnID) bool {
+			return tableID == table.GetID() && catalog.MakeTableColSet(ids...).Intersects(dropped)
+		}
+		return uses(fk.OriginTableID, fk.OriginColumnIDs) ||
+			uses(fk.ReferencedTableID, fk.ReferencedColumnIDs)
+	}
+	outbound := table.OutboundFKs[:0]
+	for i := range table.OutboundFKs {
+		if !fkUsesDropped(&table.OutboundFKs[i]) {
+			outbound = append(outbound, table.OutboundFKs[i])
+		}
+	}
+	table.OutboundFKs = outbound
+	inbound := table.InboundFKs[:0]
+	for i := range table.InboundFKs {
+		if !fkUsesDropped(&table.InboundFKs[i]) {
+			inbound = append(inbound, table.InboundFKs[i])
+		}
+	}
+	table.InboundFKs = inbound
+
+	// A family left without columns is removed, other than the first, which
+	// every table has. Values are decoded by their tags, so the rows of a
+	// family that lost columns stay tuples even if it is left with one. The
+	// bare values of a family whose default column is removed are dropped along
+	// with it, or replaced with empty tuples in the first family.
+	families := table.Families[:0]
+	for _, family := range table.Families {
+		ids, names := family.ColumnIDs[:0], family.ColumnNames[:0]
+		for i, id := range family.ColumnIDs {
+			if !dropped.Contains(id) {
+				ids = append(ids, id)
+				names = append(names, family.ColumnNames[i])
+			}
+		}
+		family.ColumnIDs, family.ColumnNames = ids, names
+		if dropped.Contains(family.DefaultColumnID) {
+			family.DefaultColumnID = 0
+		}
+		if len(ids) > 0 || family.ID == 0 {
+			families = append(families, family)
+		}
+	}
+	table.Families = families
+
+	storeIDs, storeNames := table.PrimaryIndex.StoreColumnIDs[:0], table.PrimaryIndex.StoreColumnNames[:0]
+	for i, id := range table.PrimaryIndex.StoreColumnIDs {
+		if !dropped.Contains(id) {
+			storeIDs = append(storeIDs, id)
+			storeNames = append(storeNames, table.PrimaryIndex.StoreColumnNames[i])
+		}
+	}
+	table.PrimaryIndex.StoreColumnIDs, table.PrimaryIndex.StoreColumnNames = storeIDs, storeNames
+
+	cols := table.Columns[:0]
+	for _, col := range table.Columns {
+		if !dropped.Contains(col.ID) {
+			cols = append(cols, col)
+		}
+	}
+	table.Columns = cols
+	return dropped, nil
+}
+
+// exprColumnIDs returns the IDs of the columns of table referenced by expr, a
+// serialized expression of the table's descriptor.
+func exprColumnIDs(table catalog.TableDescriptor, expr string) (catalog.TableColSet, error) {
+	parsed, err := parser.ParseExpr(expr)
+	if err != nil {
+		return catalog.TableColSet{}, err
+	}
+	return schemaexpr.ExtractColumnIDs(table, parsed)
+}
+
+// filterProjectedStatistics returns the statistics among tableStats, of the
+// restored tables tableDescs, which are only on columns that were restored.
+func filterProjectedStatistics(
+	tableStats []*stats.TableStatisticProto, tableDescs []*descpb.TableDescriptor,
+) []*stats.TableStatisticProto {
+	columns := make(map[descpb.ID]catalog.TableColSet, len(tableDescs))
+	for _, desc := range tableDescs {
+		var cols catalog.TableColSet
+		for i := range desc.Columns {
+			cols.Add(desc.Columns[i].ID)
+		}
+		columns[desc.ID] = cols
+	}
+	filtered := tableStats[:0]
+	for _, stat := range tableStats {
+		if catalog.MakeTableColSet(stat.ColumnIDs...).SubsetOf(columns[stat.TableID]) {
+			filtered = append(filtered, stat)
+		}
+	}
+	return filtered
+}
+
+// projectedTable describes a table restored with the columns option to the
+// restore data processors.
+type projectedTable struct {
+	primaryIndexID descpb.IndexID
+	// families maps the IDs of the restored families to their default columns.
+	families map[descpb.FamilyID]descpb.ColumnID
+	dropped  catalog.TableColSet
+}
+
+// restoreProjection drops the values of the columns that are not restored
+// from the rows of the tables restored with the columns option.
+type restoreProjection struct {
+	codec   keys.SQLCodec
+	tables  map[descpb.ID]projectedTable
+	scratch []byte
+}
+
+// makeRestoreProjection returns the projection of the tables of rekeys which
+// are restored with the columns option, or nil if there are none.
+func makeRestoreProjection(
+	codec keys.SQLCodec, rekeys []execinfrapb.TableRekey,
+) (*restoreProjection, error) {
+	var rp *restoreProjection
+	for _, rekey := range rekeys {
+		if len(rekey.DroppedColumnIDs) == 0 {
+			continue
+		}
+		var desc descpb.Descriptor
+		if err := protoutil.Unmarshal(rekey.NewDesc, &desc); err != nil {
+			return nil, errors.Wrapf(err, "unmarshalling rekey descriptor for old table id %d", rekey.OldID)
+		}
+		table, _, _, _, _ := descpb.GetDescriptors(&desc)
+		if table == nil {
+			return nil, errors.New("expected a table descriptor")
+		}
+		t := projectedTable{
+			primaryIndexID: table.PrimaryIndex.ID,
+			families:       make(map[descpb.FamilyID]descpb.ColumnID, len(table.Families)),
+			dropped:        catalog.MakeTableColSet(rekey.DroppedColumnIDs...),
+		}
+		for _, family := range table.Families {
+			t.families[family.ID] = family.DefaultColumnID
+		}
+		if rp == nil {
+			rp = &restoreProjection{codec: codec, tables: make(map[descpb.ID]projectedTable)}
+		}
+		rp.tables[table.ID] = t
+	}
+	return rp, nil
+}
+
+// project drops the values of the columns that are not restored from value,
+// the value of the restored key. It returns false if nothing of the value is
+// restored, in which case the key is not restored either.
+func (rp *restoreProjection) project(key roachpb.Key, value *roachpb.Value) (bool, error) {
+	_, tableID, indexID, err := rp.codec.DecodeIndexPrefix(key)
+	if err != nil {
+		return false, err
+	}
+	t, ok := rp.tables[descpb.ID(tableID)]
+	if !ok || descpb.IndexID(indexID) != t.primaryIndexID {
+		// The secondary indexes of the table that are restored only use columns
+		// that are.
+		return true, nil
+	}
+	familyID, err := keys.DecodeFamilyKey(key)
+	if err != nil {
+		return false, err
+	}
+	defaultColumnID, ok := t.families[descpb.FamilyID(familyID)]
+	if !ok {
+		return false, nil
+	}
+	if len(value.RawBytes) == 0 {
+		// A deletion.
+		return true, nil
+	}
+	if value.GetTag() != roachpb.ValueType_TUPLE {
+		if defaultColumnID != 0 {
+			// The bare value of the default column of a family, which is kept
+			// since the column is.
+			return true, nil
+		}
+		// The bare value of a default column that is not restored, which leaves
+		// the family with no columns outside of the primary key. The rows of the
+		// first family are what marks that the row exists, so they are kept as
+		// the empty tuples the restored table expects.
+		if familyID != 0 {
+			return false, nil
+		}
+		value.SetTuple(nil)
+		return true, nil
+	}
+	tuple, err := value.GetTuple()
+	if err != nil {
+		return false, err
+	}
+
+	// The columns of a tuple are each tagged with the difference between their
+	// ID and that of the previous column, so the tags of the columns that
+	// follow a dropped one are re-encoded.
+	rp.scratch = rp.scratch[:0]
+	var colID, lastKeptID descpb.ColumnID
+	for len(tuple) > 0 {
+		_, dataOffset, delta, typ, err := encoding.DecodeValueTag(tuple)
+		if err != nil {
+			return false, err
+		}
+		length, err := encoding.PeekValueLengthWithOffsetsAndType(tuple, dataOffset, typ)
+		if err != nil {
+			return false, err
+		}
+		colID += descpb.ColumnID(delta)
+		if !t.dropped.Contains(colID) {
+			rp.scratch = encoding.EncodeValueTag(rp.scratch, uint32(colID-lastKeptID), typ)
+			rp.scratch = append(rp.scratch, tuple[dataOffset:length]...)
+			lastKeptID = colID
+		}
+		tuple = tuple[length:]
+	}
+	if len(rp.scratch) == 0 && familyID != 0 {
+		// Only the rows of the first family of a row are kept if they are empty,
+		// as they are what marks that the row exists.
+		return false, nil
+	}
+	value.SetTuple(rp.scratch)
+	return true, nil
+}
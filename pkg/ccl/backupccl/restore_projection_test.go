@@ -0,0 +1,91 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+func TestRestoreProjection(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, 0, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE TABLE data.t (
+	id INT PRIMARY KEY,
+	a INT,
+	b STRING,
+	c INT,
+	d INT AS (c * 2) STORED,
+	e INT NOT NULL DEFAULT 7,
+	FAMILY f1 (id, a, b),
+	FAMILY f2 (c, d),
+	FAMILY f3 (e),
+	INDEX a_idx (a) STORING (b),
+	INDEX e_idx (e),
+	CONSTRAINT c_positive CHECK (c > 0)
+)`)
+	sqlDB.Exec(t, `INSERT INTO data.t (id, a, b, c, e) VALUES
+	(1, 10, 'one', 1, 100), (2, 20, 'two', 2, 200), (3, NULL, 'three', 3, 300)`)
+	sqlDB.Exec(t, `BACKUP TABLE data.t TO $1`, localFoo)
+	sqlDB.Exec(t, `CREATE DATABASE slim`)
+
+	sqlDB.ExpectErr(t, `must include the primary key column "id"`,
+		`RESTORE TABLE data.t FROM $1 WITH into_db = 'slim', columns = (a, b)`, localFoo)
+	sqlDB.ExpectErr(t, `must include the columns that column "d" of table "t" is computed from`,
+		`RESTORE TABLE data.t FROM $1 WITH into_db = 'slim', columns = (id, d)`, localFoo)
+	sqlDB.ExpectErr(t, `column "z" does not exist`,
+		`RESTORE TABLE data.t FROM $1 WITH into_db = 'slim', columns = (id, z)`, localFoo)
+	sqlDB.ExpectErr(t, "can only be used with RESTORE TABLE",
+		`RESTORE DATABASE data FROM $1 WITH new_db_name = 'data2', columns = (id)`, localFoo)
+
+	sqlDB.Exec(t, `RESTORE TABLE data.t FROM $1 WITH into_db = 'slim', columns = (id, b, e)`, localFoo)
+	sqlDB.CheckQueryResults(t, `SELECT * FROM slim.t ORDER BY id`, [][]string{
+		{"1", "one", "100"}, {"2", "two", "200"}, {"3", "three", "300"},
+	})
+	// The indexes and constraints that used the columns which were not restored
+	// are removed with them, along with the family left without columns.
+	sqlDB.CheckQueryResults(t,
+		`SELECT DISTINCT index_name FROM [SHOW INDEXES FROM slim.t] ORDER BY index_name`,
+		[][]string{{"e_idx"}, {"t_pkey"}})
+	sqlDB.CheckQueryResults(t,
+		`SELECT constraint_name FROM [SHOW CONSTRAINTS FROM slim.t] ORDER BY constraint_name`,
+		[][]string{{"t_pkey"}})
+	sqlDB.CheckQueryResults(t,
+		`SELECT column_name FROM [SHOW COLUMNS FROM slim.t] ORDER BY column_name`,
+		[][]string{{"b"}, {"e"}, {"id"}})
+	sqlDB.CheckQueryResults(t, `SELECT id FROM slim.t@e_idx WHERE e = 200`, [][]string{{"2"}})
+
+	// The restored table can be written to and read back like any other.
+	sqlDB.Exec(t, `INSERT INTO slim.t VALUES (4, 'four', 400)`)
+	sqlDB.Exec(t, `UPDATE slim.t SET b = 'uno' WHERE id = 1`)
+	sqlDB.CheckQueryResults(t, `SELECT * FROM slim.t ORDER BY id`, [][]string{
+		{"1", "uno", "100"}, {"2", "two", "200"}, {"3", "three", "300"}, {"4", "four", "400"},
+	})
+
+	// The values of the first family of data.u are those of its only column
+	// outside of the primary key, a, rather than tuples. Without a, the rows of
+	// the family are restored as empty tuples, which still mark that the rows
+	// exist when b is NULL.
+	sqlDB.Exec(t, `CREATE TABLE data.u (k INT PRIMARY KEY, a INT, b INT, FAMILY f0 (k, a), FAMILY f1 (b))`)
+	sqlDB.Exec(t, `INSERT INTO data.u VALUES (1, 10, 100), (2, 20, NULL)`)
+	sqlDB.Exec(t, `BACKUP TABLE data.u TO $1`, localFoo+"/u")
+	sqlDB.Exec(t, `RESTORE TABLE data.u FROM $1 WITH into_db = 'slim', columns = (k, b)`, localFoo+"/u")
+	sqlDB.CheckQueryResults(t, `SELECT * FROM slim.u ORDER BY k`, [][]string{
+		{"1", "100"}, {"2", "NULL"},
+	})
+	sqlDB.Exec(t, `UPDATE slim.u SET b = 200 WHERE k = 2`)
+	sqlDB.Exec(t, `DELETE FROM slim.u WHERE k = 1`)
+	sqlDB.CheckQueryResults(t, `SELECT * FROM slim.u ORDER BY k`, [][]string{{"2", "200"}})
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// The remap_regions option restores multi-region databases and tables into a
+// cluster whose regions are named differently from those of the backed up
+// cluster. The regions are renamed in the restored descriptors, which keep the
+// physical representation of the region enum members so the restored rows of
+// REGIONAL BY ROW tables map to the renamed regions, and in the zone
+// configurations captured with the zone_configs option. The zone
+// configurations of multi-region objects are otherwise regenerated from the
+// renamed descriptors once they are published.
+
+// parseRegionRemapping parses the 'old=new' entries of the remap_regions
+// option.
+func parseRegionRemapping(entries []string) (map[string]string, error) {
+	remapping := make(map[string]string, len(entries))
+	targets := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+				"invalid %s entry %q: expected 'old_region=new_region'", restoreOptRemapRegions, entry)
+		}
+		from, to := parts[0], parts[1]
+		if _, ok := remapping[from]; ok {
+			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+				"region %q is remapped more than once", from)
+		}
+		if other, ok := targets[to]; ok {
+			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+				"regions %q and %q are both remapped to %q", other, from, to)
+		}
+		remapping[from] = to
+		targets[to] = from
+	}
+	return remapping, nil
+}
+
+// remapRegions renames the regions of the multi-region databases, region
+// enums and tables in descs according to remapping.
+func remapRegions(descs []catalog.Descriptor, remapping map[string]string) error {
+	if len(remapping) == 0 {
+		return nil
+	}
+	remap := func(region catpb.RegionName) catpb.RegionName {
+		if to, ok := remapping[string(region)]; ok {
+			return catpb.RegionName(to)
+		}
+		return region
+	}
+	for _, desc := range descs {
+		switch desc := desc.(type) {
+		case *dbdesc.Mutable:
+			if desc.RegionConfig == nil {
+				continue
+			}
+			desc.RegionConfig.PrimaryRegion = remap(desc.RegionConfig.PrimaryRegion)
+			desc.RegionConfig.SecondaryRegion = remap(desc.RegionConfig.SecondaryRegion)
+
+		case *typedesc.Mutable:
+			if desc.Kind != descpb.TypeDescriptor_MULTIREGION_ENUM {
+				continue
+			}
+			members := make(map[string]struct{}, len(desc.EnumMembers))
+			for i := range desc.EnumMembers {
+				m := &desc.EnumMembers[i]
+				m.LogicalRepresentation = string(remap(catpb.RegionName(m.LogicalRepresentation)))
+				if _, ok := members[m.LogicalRepresentation]; ok {
+					return pgerror.Newf(pgcode.InvalidParameterValue,
+						"remapping the regions of %q would add region %q to it twice",
+						desc.GetName(), m.LogicalRepresentation)
+				}
+				members[m.LogicalRepresentation] = struct{}{}
+			}
+			if desc.RegionConfig == nil {
+				continue
+			}
+			rc := desc.RegionConfig
+			rc.PrimaryRegion = remap(rc.PrimaryRegion)
+			rc.SecondaryRegion = remap(rc.SecondaryRegion)
+			for i := range rc.SuperRegions {
+				for j, region := range rc.SuperRegions[i].Regions {
+					rc.SuperRegions[i].Regions[j] = remap(region)
+				}
+			}
+			ext := &rc.ZoneConfigExtensions
+			remapZoneConfigRegions(ext.Global, remapping)
+			remapZoneConfigRegions(ext.Regional, remapping)
+			if ext.RegionalIn != nil {
+				regionalIn := make(map[catpb.RegionName]zonepb.ZoneConfig, len(ext.RegionalIn))
+				for region, zone := range ext.RegionalIn {
+					zone := zone
+					remapZoneConfigRegions(&zone, remapping)
+					regionalIn[remap(region)] = zone
+				}
+				ext.RegionalIn = regionalIn
+			}
+
+		case *tabledesc.Mutable:
+			if desc.LocalityConfig == nil {
+				continue
+			}
+			if rbt := desc.LocalityConfig.GetRegionalByTable(); rbt != nil && rbt.Region != nil {
+				region := remap(*rbt.Region)
+				rbt.Region = &region
+			}
+			if desc.LocalityConfig.GetRegionalByRow() == nil {
+				continue
+			}
+			// The implicit partitions of a REGIONAL BY ROW table are named after
+			// the regions.
+			remapPartitions := func(idx *descpb.IndexDescriptor) {
+				for i := range idx.Partitioning.List {
+					p := &idx.Partitioning.List[i]
+					p.Name = string(remap(catpb.RegionName(p.Name)))
+				}
+			}
+			remapPartitions(&desc.PrimaryIndex)
+			for i := range desc.Indexes {
+				remapPartitions(&desc.Indexes[i])
+			}
+			for _, m := range desc.Mutations {
+				if idx := m.GetIndex(); idx != nil {
+					remapPartitions(idx)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// remapZoneConfigRegions renames the regions in the constraints and lease
+// preferences of zone, and in the partitions its subzones apply to.
+func remapZoneConfigRegions(zone *zonepb.ZoneConfig, remapping map[string]string) {
+	if zone == nil {
+		return
+	}
+	remapConstraints := func(constraints []zonepb.Constraint) {
+		for i := range constraints {
+			c := &constraints[i]
+			if c.Key != "region" {
+				continue
+			}
+			if to, ok := remapping[c.Value]; ok {
+				c.Value = to
+			}
+		}
+	}
+	for i := range zone.Constraints {
+		remapConstraints(zone.Constraints[i].Constraints)
+	}
+	for i := range zone.VoterConstraints {
+		remapConstraints(zone.VoterConstraints[i].Constraints)
+	}
+	for i := range zone.LeasePreferences {
+		remapConstraints(zone.LeasePreferences[i].Constraints)
+	}
+	for i := range zone.Subzones {
+		s := &zone.Subzones[i]
+		if to, ok := remapping[s.PartitionName]; ok {
+			s.PartitionName = to
+		}
+		remapZoneConfigRegions(&s.Config, remapping)
+	}
+}
+
+// remapEncodedZoneConfigRegions is remapZoneConfigRegions for a zone
+// configuration as stored in system.zones.
+func remapEncodedZoneConfigRegions(config []byte, remapping map[string]string) ([]byte, error) {
+	if len(remapping) == 0 {
+		return config, nil
+	}
+	var zone zonepb.ZoneConfig
+	if err := protoutil.Unmarshal(config, &zone); err != nil {
+		return nil, errors.Wrap(err, "decoding zone configuration")
+	}
+	remapZoneConfigRegions(&zone, remapping)
+	return protoutil.Marshal(&zone)
+}
@@ -0,0 +1,192 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRegionRemapping(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	remapping, err := parseRegionRemapping([]string{"us-east1=europe-west1", "us-west1=europe-west2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"us-east1": "europe-west1",
+		"us-west1": "europe-west2",
+	}, remapping)
+
+	for _, tc := range []struct {
+		entries []string
+		err     string
+	}{
+		{[]string{"us-east1"}, "expected 'old_region=new_region'"},
+		{[]string{"=europe-west1"}, "expected 'old_region=new_region'"},
+		{[]string{"us-east1=a", "us-east1=b"}, `region "us-east1" is remapped more than once`},
+		{[]string{"us-east1=a", "us-west1=a"}, `are both remapped to "a"`},
+	} {
+		_, err := parseRegionRemapping(tc.entries)
+		require.ErrorContains(t, err, tc.err)
+	}
+}
+
+func TestRemapRegions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	regionConstraint := func(region string) zonepb.Constraint {
+		return zonepb.Constraint{Type: zonepb.Constraint_REQUIRED, Key: "region", Value: region}
+	}
+	remapping := map[string]string{"us-east1": "europe-west1", "us-west1": "europe-west2"}
+
+	db := dbdesc.NewBuilder(&descpb.DatabaseDescriptor{
+		ID:   100,
+		Name: "db",
+		RegionConfig: &descpb.DatabaseDescriptor_RegionConfig{
+			PrimaryRegion:   "us-east1",
+			SecondaryRegion: "us-west1",
+		},
+	}).BuildCreatedMutableDatabase()
+	enum := typedesc.NewBuilder(&descpb.TypeDescriptor{
+		ID:   101,
+		Name: "crdb_internal_region",
+		Kind: descpb.TypeDescriptor_MULTIREGION_ENUM,
+		EnumMembers: []descpb.TypeDescriptor_EnumMember{
+			{LogicalRepresentation: "asia-east1", PhysicalRepresentation: []byte{0x40}},
+			{LogicalRepresentation: "us-east1", PhysicalRepresentation: []byte{0x80}},
+			{LogicalRepresentation: "us-west1", PhysicalRepresentation: []byte{0xc0}},
+		},
+		RegionConfig: &descpb.TypeDescriptor_RegionConfig{
+			PrimaryRegion: "us-east1",
+			SuperRegions: []descpb.SuperRegion{
+				{SuperRegionName: "us", Regions: []catpb.RegionName{"us-east1", "us-west1"}},
+			},
+			ZoneConfigExtensions: descpb.ZoneConfigExtensions{
+				RegionalIn: map[catpb.RegionName]zonepb.ZoneConfig{
+					"us-east1": {LeasePreferences: []zonepb.LeasePreference{
+						{Constraints: []zonepb.Constraint{regionConstraint("us-east1")}},
+					}},
+				},
+			},
+		},
+	}).BuildCreatedMutableType()
+	rbtRegion := catpb.RegionName("us-west1")
+	rbt := tabledesc.NewBuilder(&descpb.TableDescriptor{
+		ID:   102,
+		Name: "rbt",
+		LocalityConfig: &catpb.LocalityConfig{
+			Locality: &catpb.LocalityConfig_RegionalByTable_{
+				RegionalByTable: &catpb.LocalityConfig_RegionalByTable{Region: &rbtRegion},
+			},
+		},
+	}).BuildCreatedMutableTable()
+	rbr := tabledesc.NewBuilder(&descpb.TableDescriptor{
+		ID:   103,
+		Name: "rbr",
+		LocalityConfig: &catpb.LocalityConfig{
+			Locality: &catpb.LocalityConfig_RegionalByRow_{
+				RegionalByRow: &catpb.LocalityConfig_RegionalByRow{},
+			},
+		},
+		PrimaryIndex: descpb.IndexDescriptor{
+			Partitioning: catpb.PartitioningDescriptor{
+				List: []catpb.PartitioningDescriptor_List{{Name: "asia-east1"}, {Name: "us-east1"}},
+			},
+		},
+		Indexes: []descpb.IndexDescriptor{{
+			Partitioning: catpb.PartitioningDescriptor{
+				List: []catpb.PartitioningDescriptor_List{{Name: "us-west1"}},
+			},
+		}},
+	}).BuildCreatedMutableTable()
+
+	require.NoError(t, remapRegions([]catalog.Descriptor{db, enum, rbt, rbr}, remapping))
+
+	require.Equal(t, catpb.RegionName("europe-west1"), db.RegionConfig.PrimaryRegion)
+	require.Equal(t, catpb.RegionName("europe-west2"), db.RegionConfig.SecondaryRegion)
+
+	var members []string
+	for _, m := range enum.EnumMembers {
+		members = append(members, m.LogicalRepresentation)
+	}
+	require.Equal(t, []string{"asia-east1", "europe-west1", "europe-west2"}, members)
+	require.Equal(t, []byte{0x80}, enum.EnumMembers[1].PhysicalRepresentation)
+	require.Equal(t, catpb.RegionName("europe-west1"), enum.RegionConfig.PrimaryRegion)
+	require.Equal(t, []catpb.RegionName{"europe-west1", "europe-west2"},
+		enum.RegionConfig.SuperRegions[0].Regions)
+	regionalIn := enum.RegionConfig.ZoneConfigExtensions.RegionalIn
+	require.Contains(t, regionalIn, catpb.RegionName("europe-west1"))
+	require.Equal(t, regionConstraint("europe-west1"),
+		regionalIn["europe-west1"].LeasePreferences[0].Constraints[0])
+
+	require.Equal(t, catpb.RegionName("europe-west2"), *rbt.LocalityConfig.GetRegionalByTable().Region)
+	require.Equal(t, "asia-east1", rbr.PrimaryIndex.Partitioning.List[0].Name)
+	require.Equal(t, "europe-west1", rbr.PrimaryIndex.Partitioning.List[1].Name)
+	require.Equal(t, "europe-west2", rbr.Indexes[0].Partitioning.List[0].Name)
+
+	// Remapping a region onto one the enum already has is an error.
+	enum = typedesc.NewBuilder(&descpb.TypeDescriptor{
+		ID:   101,
+		Name: "crdb_internal_region",
+		Kind: descpb.TypeDescriptor_MULTIREGION_ENUM,
+		EnumMembers: []descpb.TypeDescriptor_EnumMember{
+			{LogicalRepresentation: "europe-west1"},
+			{LogicalRepresentation: "us-east1"},
+		},
+	}).BuildCreatedMutableType()
+	require.ErrorContains(t, remapRegions([]catalog.Descriptor{enum}, remapping),
+		`would add region "europe-west1" to it twice`)
+}
+
+func TestRemapZoneConfigRegions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	zone := zonepb.ZoneConfig{
+		Constraints: []zonepb.ConstraintsConjunction{{
+			NumReplicas: 1,
+			Constraints: []zonepb.Constraint{
+				{Type: zonepb.Constraint_REQUIRED, Key: "region", Value: "us-east1"},
+				{Type: zonepb.Constraint_REQUIRED, Key: "zone", Value: "us-east1"},
+			},
+		}},
+		VoterConstraints: []zonepb.ConstraintsConjunction{{
+			Constraints: []zonepb.Constraint{{Type: zonepb.Constraint_REQUIRED, Key: "region", Value: "us-west1"}},
+		}},
+		Subzones: []zonepb.Subzone{{
+			IndexID:       1,
+			PartitionName: "us-east1",
+			Config: zonepb.ZoneConfig{
+				LeasePreferences: []zonepb.LeasePreference{{
+					Constraints: []zonepb.Constraint{{Type: zonepb.Constraint_REQUIRED, Key: "region", Value: "us-east1"}},
+				}},
+			},
+		}},
+	}
+	remapZoneConfigRegions(&zone, map[string]string{"us-east1": "europe-west1", "us-west1": "europe-west2"})
+
+	require.Equal(t, "europe-west1", zone.Constraints[0].Constraints[0].Value)
+	// Only region constraints are remapped.
+	require.Equal(t, "us-east1", zone.Constraints[0].Constraints[1].Value)
+	require.Equal(t, "europe-west2", zone.VoterConstraints[0].Constraints[0].Value)
+	require.Equal(t, "europe-west1", zone.Subzones[0].PartitionName)
+	require.Equal(t, "europe-west1", zone.Subzones[0].Config.LeasePreferences[0].Constraints[0].Value)
+}
@@ -145,8 +145,9 @@ func makeSimpleImportSpans(
 				if sp := span.Intersect(f.Span); sp.Valid() {
 					fileSpec := execinfrapb.RestoreFileSpec{Path: f.Path, Dir: backups[layer].Dir}
 					if dir, ok := backupLocalityMap[layer][f.LocalityKV]; ok {
-						fileSpec = execinfrapb.RestoreFileSpec{Path: f.Path, Dir: dir}
+						fileSpec = execinfrapb.RestoreFileSpec{Path: f.Path, Dir: dir, LocalityKV: f.LocalityKV}
 					}
+					fileSpec.Size, fileSpec.CRC32C = f.Trailer.Size, f.Trailer.CRC32C
 
 					// Lookup the size of the file being added; if the backup didn't
 					// record a file size, just assume it is 16mb for estimating.
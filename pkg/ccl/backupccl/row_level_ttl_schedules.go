@@ -0,0 +1,149 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// The row-level TTL of a table is part of its descriptor, but its schedule is
+// a row of system.scheduled_jobs that RESTORE cannot restore as is, since it
+// holds the ID of the table. RESTORE creates a new schedule for each restored
+// table with a row-level TTL instead, and resumes it in the state the schedule
+// of the backed up table was in, which backups capture in their manifest.
+
+// getRowLevelTTLSchedules returns the state of the schedules of the row-level
+// TTL of the tables among descs as of asOf.
+func getRowLevelTTLSchedules(
+	ctx context.Context, ie *sql.InternalExecutor, descs []descpb.Descriptor, asOf hlc.Timestamp,
+) ([]backuppb.RowLevelTTLSchedule, error) {
+	tableBySchedule := make(map[int64]descpb.ID)
+	scheduleIDs := tree.NewDArray(types.Int)
+	for i := range descs {
+		table, _, _, _, _ := descpb.GetDescriptors(&descs[i])
+		if table == nil || table.RowLevelTTL == nil || table.RowLevelTTL.ScheduleID == 0 {
+			continue
+		}
+		tableBySchedule[table.RowLevelTTL.ScheduleID] = table.ID
+		if err := scheduleIDs.Append(tree.NewDInt(tree.DInt(table.RowLevelTTL.ScheduleID))); err != nil {
+			return nil, err
+		}
+	}
+	if len(tableBySchedule) == 0 {
+		return nil, nil
+	}
+	rows, err := ie.QueryBuffered(ctx, "backup-get-row-level-ttl-schedules", nil, /* txn */
+		fmt.Sprintf(`SELECT schedule_id, next_run FROM system.scheduled_jobs
+AS OF SYSTEM TIME %s WHERE schedule_id = ANY($1) ORDER BY schedule_id`,
+			asOf.AsOfSystemTime()), scheduleIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading row-level TTL schedules")
+	}
+	schedules := make([]backuppb.RowLevelTTLSchedule, len(rows))
+	for i, row := range rows {
+		schedules[i].TableID = tableBySchedule[int64(tree.MustBeDInt(row[0]))]
+		if row[1] == tree.DNull {
+			schedules[i].Paused = true
+		} else {
+			schedules[i].NextRun = tree.MustBeDTimestampTZ(row[1]).Time
+		}
+	}
+	return schedules, nil
+}
+
+// restoreRowLevelTTLSchedules brings the schedules of the row-level TTL of the
+// restored tables, created when they were published, to the state captured
+// in manifest, or pauses them all if the restore was run with
+// pause_ttl_schedules. Schedules whose state was not captured are left as
+// they were created.
+func restoreRowLevelTTLSchedules(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	details jobspb.RestoreDetails,
+	manifest backuppb.BackupManifest,
+) error {
+	backedUp := make(map[descpb.ID]backuppb.RowLevelTTLSchedule, len(manifest.RowLevelTTLSchedules))
+	for _, s := range manifest.RowLevelTTLSchedules {
+		if rewrite, ok := details.DescriptorRewrites[s.TableID]; ok {
+			backedUp[rewrite.ID] = s
+		}
+	}
+
+	return execCfg.DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		for _, table := range details.TableDescs {
+			if table.RowLevelTTL == nil || table.RowLevelTTL.ScheduleID == 0 {
+				continue
+			}
+			nextRun := tree.DNull
+			if !details.PauseRowLevelTTLSchedules {
+				s, ok := backedUp[table.ID]
+				if !ok {
+					continue
+				}
+				if !s.Paused {
+					nextRun = tree.MustMakeDTimestampTZ(s.NextRun, time.Microsecond)
+				}
+			}
+			if _, err := execCfg.InternalExecutor.Exec(ctx, "restore-row-level-ttl-schedule", txn,
+				`UPDATE system.scheduled_jobs SET next_run = $2 WHERE schedule_id = $1`,
+				table.RowLevelTTL.ScheduleID, nextRun,
+			); err != nil {
+				return errors.Wrapf(err, "restoring the row-level TTL schedule of table %q", table.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// showRowLevelTTL returns the row-level TTL storage parameters of table, the
+// status of its schedule captured in manifest, and its next run, for SHOW
+// BACKUP SCHEMAS. The status and next run are NULL if the schedule was not
+// captured, and the next run also if the schedule was paused.
+func showRowLevelTTL(
+	table catalog.TableDescriptor, manifest *backuppb.BackupManifest,
+) (params, status, nextRun tree.Datum) {
+	params, status, nextRun = tree.DNull, tree.DNull, tree.DNull
+	if !table.HasRowLevelTTL() {
+		return params, status, nextRun
+	}
+	var ttlParams []string
+	for _, p := range table.GetStorageParams(true /* spaceBetweenEqual */) {
+		if strings.HasPrefix(p, "ttl") {
+			ttlParams = append(ttlParams, p)
+		}
+	}
+	params = tree.NewDString(strings.Join(ttlParams, ", "))
+	for _, s := range manifest.RowLevelTTLSchedules {
+		if s.TableID != table.GetID() {
+			continue
+		}
+		if s.Paused {
+			status = tree.NewDString("PAUSED")
+		} else {
+			status = tree.NewDString("ACTIVE")
+			nextRun = tree.MustMakeDTimestampTZ(s.NextRun, time.Microsecond)
+		}
+		break
+	}
+	return params, status, nextRun
+}
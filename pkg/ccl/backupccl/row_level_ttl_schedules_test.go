@@ -0,0 +1,60 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestoreRowLevelTTLSchedules(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 1
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	// nextRun returns the next run of the row-level TTL schedule of table, or
+	// "paused".
+	nextRun := func(table string) string {
+		var res string
+		sqlDB.QueryRow(t, `SELECT COALESCE(next_run::STRING, 'paused') FROM system.scheduled_jobs
+WHERE schedule_name = 'row-level-ttl-' || ($1::REGCLASS::INT)::STRING`, table).Scan(&res)
+		return res
+	}
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.active (id INT PRIMARY KEY) WITH (ttl_expire_after = '10 days')`)
+	sqlDB.Exec(t, `CREATE TABLE d.paused (id INT PRIMARY KEY) WITH (ttl_expire_after = '1 day', ttl_job_cron = '@daily')`)
+	activeNextRun := nextRun("d.active")
+	sqlDB.Exec(t, `PAUSE SCHEDULES SELECT schedule_id FROM system.scheduled_jobs
+WHERE schedule_name = 'row-level-ttl-' || ('d.paused'::REGCLASS::INT)::STRING`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO $1`, localFoo)
+
+	sqlDB.CheckQueryResults(t, `SELECT object_name, row_level_ttl, ttl_schedule_status
+FROM [SHOW BACKUP SCHEMAS FROM LATEST IN $1] WHERE object_type = 'table' ORDER BY object_name`,
+		[][]string{
+			{"active", "ttl = 'on', ttl_expire_after = '10 days':::INTERVAL, ttl_job_cron = '@hourly'", "ACTIVE"},
+			{"paused", "ttl = 'on', ttl_expire_after = '1 day':::INTERVAL, ttl_job_cron = '@daily'", "PAUSED"},
+		})
+
+	// The schedules of the restored tables resume in the state they were backed
+	// up in.
+	sqlDB.Exec(t, `RESTORE DATABASE d FROM LATEST IN $1 WITH new_db_name = 'd2'`, localFoo)
+	require.Equal(t, activeNextRun, nextRun("d2.active"))
+	require.Equal(t, "paused", nextRun("d2.paused"))
+
+	// Or paused, if asked to.
+	sqlDB.Exec(t, `RESTORE DATABASE d FROM LATEST IN $1 WITH new_db_name = 'd3', pause_ttl_schedules`, localFoo)
+	require.Equal(t, "paused", nextRun("d3.active"))
+	require.Equal(t, "paused", nextRun("d3.paused"))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/log/eventpb"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
@@ -54,8 +55,22 @@ func (e *scheduledBackupExecutor) ExecuteJob(
 	sj *jobs.ScheduledJob,
 	txn *kv.Txn,
 ) error {
+	skip, err := checkIncChangeThreshold(ctx, cfg, sj, txn)
+	if err != nil {
+		e.metrics.NumFailed.Inc(1)
+		recordScheduledBackupRun(ctx, cfg.Settings, cfg.InternalExecutor, env.Now(),
+			scheduledBackupRun{scheduleID: sj.ScheduleID(), status: scheduledBackupRunFailed, err: err})
+		return err
+	}
+	if skip {
+		recordScheduledBackupRun(ctx, cfg.Settings, cfg.InternalExecutor, env.Now(),
+			scheduledBackupRun{scheduleID: sj.ScheduleID(), status: scheduledBackupRunSkipped})
+		return nil
+	}
 	if err := e.executeBackup(ctx, cfg, sj, txn); err != nil {
 		e.metrics.NumFailed.Inc(1)
+		recordScheduledBackupRun(ctx, cfg.Settings, cfg.InternalExecutor, env.Now(),
+			scheduledBackupRun{scheduleID: sj.ScheduleID(), status: scheduledBackupRunFailed, err: err})
 		return err
 	}
 	e.metrics.NumStarted.Inc(1)
@@ -282,6 +297,12 @@ func (e *scheduledBackupExecutor) GetCreateScheduleStatement(
 			Value: tree.NewDString(wait),
 		},
 	}
+	if args.IncrementalChangeThreshold > 0 {
+		scheduleOptions = append(scheduleOptions, tree.KVOption{
+			Key:   optIncChangeThreshold,
+			Value: tree.NewDString(string(humanizeutil.IBytes(args.IncrementalChangeThreshold))),
+		})
+	}
 
 	var destinations []string
 	for i := range backupNode.To {
@@ -347,6 +368,12 @@ func (e *scheduledBackupExecutor) backupSucceeded(
 		e.metrics.RpoMetric.Update(details.(jobspb.BackupDetails).EndTime.GoTime().Unix())
 	}
 
+	if args.IncrementalChangeThreshold > 0 {
+		if err := recordLiveBytesAtLastBackup(ctx, env, ex, txn, schedule, args); err != nil {
+			return err
+		}
+	}
+
 	if args.UnpauseOnSuccess == jobs.InvalidScheduleID {
 		return nil
 	}
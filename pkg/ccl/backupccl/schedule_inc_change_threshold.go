@@ -0,0 +1,245 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupresolver"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvclient/kvcoord"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/scheduledjobs"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	pbtypes "github.com/gogo/protobuf/types"
+)
+
+// A backup schedule created with the incremental_change_threshold option
+// adapts the cadence of its incremental backups to the write volume of the
+// data it backs up: every time the incremental schedule fires, it compares the
+// live bytes (as tracked by MVCCStats) of the backed up data to the live bytes
+// when the last backup in the chain started, and only backs up if they changed
+// by at least the threshold. Quiet clusters thus write fewer, larger
+// incremental layers, while busy clusters can use a frequent RECURRING
+// expression without writing near-empty ones.
+//
+// The live bytes are summed over the ranges overlapping the backed up spans,
+// so they are an approximation: ranges that straddle the edge of a backed up
+// span are counted in full. Writes that do not change the live bytes, such as
+// overwriting a row with one of the same size, are not noticed; the full
+// backup schedule bounds how long such changes go without being backed up.
+//
+// 1. Before any scheduled backup starts, the schedule records the current live
+//    bytes in its LiveBytesAtRunningBackup.
+//
+// 2. When a backup succeeds, its LiveBytesAtRunningBackup becomes the
+//    LiveBytesAtLastBackup of the incremental schedule. For a full backup this
+//    is done on the dependent incremental schedule.
+//
+// 3. When the incremental schedule fires, it skips the run if the live bytes
+//    differ from its LiveBytesAtLastBackup by less than the threshold.
+
+// checkIncChangeThreshold is called before a backup schedule starts a backup.
+// If the schedule has an incremental_change_threshold, it records the current
+// live bytes of the backed up data on the schedule, and returns true if the
+// schedule is incremental and they have not changed by the threshold since the
+// last backup in the chain, in which case no backup should be started.
+func checkIncChangeThreshold(
+	ctx context.Context, cfg *scheduledjobs.JobExecutionConfig, sj *jobs.ScheduledJob, txn *kv.Txn,
+) (bool, error) {
+	args := &backuppb.ScheduledBackupExecutionArgs{}
+	if err := pbtypes.UnmarshalAny(sj.ExecutionArgs().Args, args); err != nil {
+		return false, errors.Wrap(err, "un-marshaling args")
+	}
+	if args.IncrementalChangeThreshold <= 0 {
+		return false, nil
+	}
+
+	backupStmt, err := extractBackupStatement(sj)
+	if err != nil {
+		return false, err
+	}
+	hook, cleanup := cfg.PlanHookMaker("backup-live-bytes", txn, sj.Owner())
+	defer cleanup()
+	liveBytes, err := backupTargetsLiveBytes(ctx, hook.(sql.PlanHookState), backupStmt.Backup)
+	if err != nil {
+		return false, errors.Wrap(err, "computing live bytes of backup targets")
+	}
+
+	if args.BackupType == backuppb.ScheduledBackupExecutionArgs_INCREMENTAL && args.LiveBytesRecorded {
+		changed := liveBytes - args.LiveBytesAtLastBackup
+		if changed < 0 {
+			changed = -changed
+		}
+		if changed < args.IncrementalChangeThreshold {
+			log.Infof(ctx, "skipping scheduled backup %d: live bytes changed by %s, below %s of %s",
+				sj.ScheduleID(), humanizeutil.IBytes(changed), optIncChangeThreshold,
+				humanizeutil.IBytes(args.IncrementalChangeThreshold))
+			sj.SetScheduleStatus("skipped: live bytes changed by %s since the last backup, below %s of %s",
+				humanizeutil.IBytes(changed), optIncChangeThreshold,
+				humanizeutil.IBytes(args.IncrementalChangeThreshold))
+			return true, nil
+		}
+	}
+
+	// Record the live bytes; the scheduler updates the schedule.
+	args.LiveBytesAtRunningBackup = liveBytes
+	any, err := pbtypes.MarshalAny(args)
+	if err != nil {
+		return false, errors.Wrap(err, "marshaling args")
+	}
+	sj.SetExecutionDetails(sj.ExecutorType(), jobspb.ExecutionArguments{Args: any})
+	return false, nil
+}
+
+// recordLiveBytesAtLastBackup is called when a backup started by a schedule
+// with an incremental_change_threshold succeeds. It sets the live bytes the
+// backup started with as the baseline for the next run of the incremental
+// schedule. args are the arguments of schedule, which the caller updates.
+func recordLiveBytesAtLastBackup(
+	ctx context.Context,
+	env scheduledjobs.JobSchedulerEnv,
+	ex sqlutil.InternalExecutor,
+	txn *kv.Txn,
+	schedule *jobs.ScheduledJob,
+	args *backuppb.ScheduledBackupExecutionArgs,
+) error {
+	if args.BackupType == backuppb.ScheduledBackupExecutionArgs_INCREMENTAL {
+		args.LiveBytesAtLastBackup = args.LiveBytesAtRunningBackup
+		args.LiveBytesRecorded = true
+		any, err := pbtypes.MarshalAny(args)
+		if err != nil {
+			return errors.Wrap(err, "marshaling args")
+		}
+		schedule.SetExecutionDetails(schedule.ExecutorType(), jobspb.ExecutionArguments{Args: any})
+		return nil
+	}
+
+	if args.DependentScheduleID == 0 {
+		return nil
+	}
+	incSj, err := jobs.LoadScheduledJob(ctx, env, args.DependentScheduleID, ex, txn)
+	if err != nil {
+		if jobs.HasScheduledJobNotFoundError(err) {
+			log.Warningf(ctx, "could not find dependent schedule with id %d",
+				args.DependentScheduleID)
+			return nil
+		}
+		return err
+	}
+	incArgs := &backuppb.ScheduledBackupExecutionArgs{}
+	if err := pbtypes.UnmarshalAny(incSj.ExecutionArgs().Args, incArgs); err != nil {
+		return errors.Wrap(err, "un-marshaling args")
+	}
+	incArgs.LiveBytesAtLastBackup = args.LiveBytesAtRunningBackup
+	incArgs.LiveBytesRecorded = true
+	any, err := pbtypes.MarshalAny(incArgs)
+	if err != nil {
+		return errors.Wrap(err, "marshaling args")
+	}
+	incSj.SetExecutionDetails(incSj.ExecutorType(), jobspb.ExecutionArguments{Args: any})
+	return incSj.Update(ctx, ex, txn)
+}
+
+// backupTargetsLiveBytes returns the live bytes of the ranges that overlap the
+// data backed up by backupStmt.
+func backupTargetsLiveBytes(
+	ctx context.Context, p sql.PlanHookState, backupStmt *tree.Backup,
+) (int64, error) {
+	execCfg := p.ExecCfg()
+	spans, err := backupTargetsSpans(ctx, p, backupStmt)
+	if err != nil {
+		return 0, err
+	}
+
+	// Collect a key in each range overlapping the spans, which RangeStats
+	// requests are addressed by.
+	var rangeKeys []roachpb.Key
+	seen := make(map[roachpb.RangeID]struct{})
+	ri := kvcoord.MakeRangeIterator(execCfg.DistSender)
+	for _, span := range spans {
+		rs, err := keys.SpanAddr(span)
+		if err != nil {
+			return 0, err
+		}
+		for ri.Seek(ctx, rs.Key, kvcoord.Ascending); ; ri.Next(ctx) {
+			if !ri.Valid() {
+				return 0, ri.Error()
+			}
+			desc := ri.Desc()
+			if _, ok := seen[desc.RangeID]; !ok {
+				seen[desc.RangeID] = struct{}{}
+				key := desc.StartKey
+				if key.Less(rs.Key) {
+					key = rs.Key
+				}
+				rangeKeys = append(rangeKeys, key.AsRawKey())
+			}
+			if !ri.NeedAnother(rs) {
+				break
+			}
+		}
+	}
+	if len(rangeKeys) == 0 {
+		return 0, nil
+	}
+
+	stats, err := execCfg.RangeStatsFetcher.RangeStats(ctx, rangeKeys...)
+	if err != nil {
+		return 0, err
+	}
+	var liveBytes int64
+	for _, s := range stats {
+		liveBytes += s.MVCCStats.LiveBytes
+	}
+	return liveBytes, nil
+}
+
+// backupTargetsSpans returns the spans that contain the data backed up by
+// backupStmt.
+func backupTargetsSpans(
+	ctx context.Context, p sql.PlanHookState, backupStmt *tree.Backup,
+) ([]roachpb.Span, error) {
+	codec := p.ExecCfg().Codec
+	if backupStmt.Coverage() == tree.AllDescriptors {
+		if codec.ForSystemTenant() {
+			return []roachpb.Span{{Key: keys.TableDataMin, EndKey: keys.TableDataMax}}, nil
+		}
+		prefix := codec.TenantPrefix()
+		return []roachpb.Span{{Key: prefix, EndKey: prefix.PrefixEnd()}}, nil
+	}
+	if backupStmt.Targets.TenantID.IsSet() {
+		prefix := keys.MakeTenantPrefix(roachpb.MakeTenantID(backupStmt.Targets.TenantID.ID))
+		return []roachpb.Span{{Key: prefix, EndKey: prefix.PrefixEnd()}}, nil
+	}
+
+	descs, _, _, _, err := backupresolver.ResolveTargetsToDescriptors(
+		ctx, p, p.ExecCfg().Clock.Now(), backupStmt.Targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve targets specified in the BACKUP stmt")
+	}
+	var spans []roachpb.Span
+	for _, desc := range descs {
+		if table, ok := desc.(catalog.TableDescriptor); ok && table.IsPhysicalTable() {
+			prefix := codec.TablePrefix(uint32(table.GetID()))
+			spans = append(spans, roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()})
+		}
+	}
+	return spans, nil
+}
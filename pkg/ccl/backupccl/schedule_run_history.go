@@ -0,0 +1,151 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// Each run of a backup schedule, whether it backs up, is skipped, or fails to
+// start its job, is recorded in system.scheduled_backup_runs, so that the
+// history of a schedule outlives the single status its row keeps. A run is
+// recorded outside of the transaction of the schedule or of its job, and a
+// failure to record it is only logged.
+
+var scheduledBackupRunRetention = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.backup.schedule_run_history.retention",
+	"how long the runs of backup schedules recorded in system.scheduled_backup_runs are kept; "+
+		"0 keeps them indefinitely",
+	30*24*time.Hour,
+	settings.NonNegativeDuration,
+).WithPublic()
+
+// scheduledBackupRunCleanupBatchSize is the maximum number of expired runs
+// deleted each time a run is recorded.
+const scheduledBackupRunCleanupBatchSize = 1000
+
+// The statuses of a run that did not run a backup job to completion.
+const (
+	scheduledBackupRunSkipped = "skipped"
+	scheduledBackupRunFailed  = "failed"
+)
+
+// scheduledBackupRun is the outcome of a run of a backup schedule.
+type scheduledBackupRun struct {
+	scheduleID int64
+	// jobID is the backup job of the run, or 0 if the run did not start one.
+	jobID  jobspb.JobID
+	status string
+	// started is when the job of the run started, if it did.
+	started time.Time
+	// destination is the URI the job backed up to, and subdir the subdirectory
+	// of the collection it backed up into.
+	destination string
+	subdir      string
+	// stats is what the job backed up, if it succeeded.
+	stats *roachpb.RowCount
+	err   error
+}
+
+// makeScheduledBackupRun returns the run of the job with the given details,
+// which started at started.
+func makeScheduledBackupRun(
+	scheduleID int64,
+	jobID jobspb.JobID,
+	status string,
+	started time.Time,
+	details jobspb.BackupDetails,
+	stats *roachpb.RowCount,
+	err error,
+) scheduledBackupRun {
+	run := scheduledBackupRun{
+		scheduleID: scheduleID,
+		jobID:      jobID,
+		status:     status,
+		started:    started,
+		subdir:     details.Destination.Subdir,
+		stats:      stats,
+		err:        err,
+	}
+	if details.URI != "" {
+		run.destination = backuputils.RedactURIForErrorMessage(details.URI)
+	}
+	return run
+}
+
+// recordScheduledBackupRun records run, which finished at finished, and
+// deletes the runs that are past their retention.
+func recordScheduledBackupRun(
+	ctx context.Context,
+	st *cluster.Settings,
+	ex sqlutil.InternalExecutor,
+	finished time.Time,
+	run scheduledBackupRun,
+) {
+	if !st.Version.IsActive(ctx, clusterversion.V23_1ScheduledBackupRunsTable) {
+		return
+	}
+
+	orNull := func(ok bool, v interface{}) interface{} {
+		if !ok {
+			return nil
+		}
+		return v
+	}
+	var dataSize, rowCount interface{}
+	if run.stats != nil {
+		dataSize, rowCount = run.stats.DataSize, run.stats.Rows
+	}
+	var errMsg interface{}
+	if run.err != nil {
+		errMsg = run.err.Error()
+	}
+	if _, err := ex.ExecEx(ctx, "record-scheduled-backup-run", nil, /* txn */
+		sessiondata.NodeUserSessionDataOverride,
+		`INSERT INTO system.scheduled_backup_runs
+  (schedule_id, finished, job_id, status, started, destination, subdir, data_size, row_count, error)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		run.scheduleID, finished,
+		orNull(run.jobID != 0, int64(run.jobID)),
+		run.status,
+		orNull(!run.started.IsZero(), run.started),
+		orNull(run.destination != "", run.destination),
+		orNull(run.subdir != "", run.subdir),
+		dataSize, rowCount, errMsg,
+	); err != nil {
+		log.Warningf(ctx, "failed to record run of backup schedule %d: %v", run.scheduleID, err)
+		return
+	}
+
+	retention := scheduledBackupRunRetention.Get(&st.SV)
+	if retention == 0 {
+		return
+	}
+	if _, err := ex.ExecEx(ctx, "cleanup-scheduled-backup-runs", nil, /* txn */
+		sessiondata.NodeUserSessionDataOverride,
+		fmt.Sprintf(`DELETE FROM system.scheduled_backup_runs WHERE finished < $1 LIMIT %d`,
+			scheduledBackupRunCleanupBatchSize),
+		finished.Add(-retention),
+	); err != nil {
+		log.Warningf(ctx, "failed to delete expired runs of backup schedules: %v", err)
+	}
+}
@@ -21,10 +21,12 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
 	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
@@ -44,6 +46,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
 	"github.com/cockroachdb/cockroach/pkg/util/json"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
@@ -82,9 +85,9 @@ func (m manifestInfoReader) header() colinfo.ResultColumns {
 func (m manifestInfoReader) showBackup(
 	ctx context.Context,
 	mem *mon.BoundAccount,
-	_ cloud.ExternalStorageFromURIFactory,
+	mkStore cloud.ExternalStorageFromURIFactory,
 	info backupInfo,
-	_ username.SQLUsername,
+	user username.SQLUsername,
 	kmsEnv cloud.KMSEnv,
 	resultsCh chan<- tree.Datums,
 ) error {
@@ -110,16 +113,27 @@ func (m manifestInfoReader) showBackup(
 		return err
 	}
 
+	push := func(row tree.Datums) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case resultsCh <- row:
+			return nil
+		}
+	}
+
+	if m.shower.iterFn != nil {
+		return m.shower.iterFn(ctx, info, mkStore, user, kmsEnv, push)
+	}
+
 	datums, err := m.shower.fn(ctx, info)
 	if err != nil {
 		return err
 	}
 
 	for _, row := range datums {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case resultsCh <- row:
+		if err := push(row); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -185,6 +199,9 @@ func showBackupPlanHook(
 	if backup.Path == nil && backup.InCollection != nil {
 		return showBackupsInCollectionPlanHook(ctx, backup, p)
 	}
+	if backup.Details == tree.BackupDiffDetails {
+		return showBackupDiffPlanHook(ctx, backup, p)
+	}
 
 	toFn, err := p.TypeAsString(ctx, backup.Path, "SHOW BACKUP")
 	if err != nil {
@@ -201,7 +218,6 @@ func showBackupPlanHook(
 
 	expected := map[string]sql.KVStringOptValidate{
 		backupencryption.BackupOptEncPassphrase: sql.KVStringOptRequireValue,
-		backupencryption.BackupOptEncKMS:        sql.KVStringOptRequireValue,
 		backupOptWithPrivileges:                 sql.KVStringOptRequireNoValue,
 		backupOptAsJSON:                         sql.KVStringOptRequireNoValue,
 		backupOptWithDebugIDs:                   sql.KVStringOptRequireNoValue,
@@ -209,8 +225,15 @@ func showBackupPlanHook(
 		backupOptDebugMetadataSST:               sql.KVStringOptRequireNoValue,
 		backupOptEncDir:                         sql.KVStringOptRequireValue,
 		backupOptCheckFiles:                     sql.KVStringOptRequireNoValue,
+		backupOptCheckKMS:                       sql.KVStringOptRequireNoValue,
+		backupOptMetadataURI:                    sql.KVStringOptRequireValue,
+		backupOptFingerprint:                    sql.KVStringOptRequireNoValue,
 	}
-	optsFn, err := p.TypeAsStringOpts(ctx, backup.Options, expected)
+	options, kmsURIs, err := showBackupKMSURIs(ctx, p, backup.Options)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	optsFn, err := p.TypeAsStringOpts(ctx, options, expected)
 	if err != nil {
 		return nil, nil, nil, false, err
 	}
@@ -218,12 +241,39 @@ func showBackupPlanHook(
 	if err != nil {
 		return nil, nil, nil, false, err
 	}
+	if _, ok := opts[backupOptMetadataURI]; ok {
+		if backup.InCollection == nil {
+			return nil, nil, nil, false, errors.Errorf("%q can only be used with the following syntax:"+
+				" 'SHOW BACKUP [subdirectory] IN [collection]'", backupOptMetadataURI)
+		}
+		if _, ok := opts[backupOptIncStorage]; ok {
+			return nil, nil, nil, false, errors.Errorf("%q cannot be used with %q",
+				backupOptMetadataURI, backupOptIncStorage)
+		}
+	}
 
 	var infoReader backupInfoReader
+	// elideFiles is set when the file lists of the manifests are streamed by
+	// the shower rather than read along with the manifests.
+	var elideFiles bool
 	if _, dumpSST := opts[backupOptDebugMetadataSST]; dumpSST {
 		infoReader = metadataSSTInfoReader{}
 	} else if _, asJSON := opts[backupOptAsJSON]; asJSON {
 		infoReader = manifestInfoReader{shower: jsonShower}
+	} else if _, checkKMS := opts[backupOptCheckKMS]; checkKMS {
+		if len(kmsURIs) != 1 {
+			return nil, nil, nil, false, errors.Newf(
+				"SHOW BACKUP option %s requires the %s option, passed once", backupOptCheckKMS,
+				backupencryption.BackupOptEncKMS)
+		}
+		infoReader = manifestInfoReader{shower: backupShowerCheckKMS(kmsURIs[0], opts[backupOptEncDir])}
+	} else if _, fingerprint := opts[backupOptFingerprint]; fingerprint {
+		if backup.Details != tree.BackupDefaultDetails {
+			return nil, nil, nil, false, errors.Newf(
+				"SHOW BACKUP option %s cannot be used with SCHEMAS, FILES, RANGES or VALIDATE",
+				backupOptFingerprint)
+		}
+		infoReader = manifestInfoReader{shower: backupShowerFingerprints(p)}
 	} else {
 		var shower backupShower
 		switch backup.Details {
@@ -231,6 +281,9 @@ func showBackupPlanHook(
 			shower = backupShowerRanges
 		case tree.BackupFileDetails:
 			shower = backupShowerFileSetup(backup.InCollection)
+			// Checking the files needs the file lists up front.
+			_, checkFiles := opts[backupOptCheckFiles]
+			elideFiles = !checkFiles
 		case tree.BackupSchemaDetails:
 			shower = backupShowerDefault(p, true, opts)
 		case tree.BackupValidateDetails:
@@ -276,21 +329,40 @@ func showBackupPlanHook(
 					"https://www.cockroachlabs.com/docs/stable/show-backup.html"))
 		}
 
-		if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, dest); err != nil {
+		if err := cloudprivilege.CheckBackupSourcePrivileges(ctx, p, dest); err != nil {
+			return err
+		}
+		if err := backupdest.CheckConnectionCapability(ctx, p.ExecCfg(), "SHOW BACKUP",
+			connectionpb.CapabilityRestoreRead, dest...); err != nil {
 			return err
 		}
 
-		fullyResolvedDest := dest
+		// The backup is resolved in the metadata replica of the collection, if
+		// one is given, while its data files are in the collection itself.
+		metadataURI, hasMetadataURI := opts[backupOptMetadataURI]
+		metadataDest := dest
+		if hasMetadataURI {
+			if len(dest) > 1 {
+				return errors.Errorf("%q cannot be used with locality aware backups", backupOptMetadataURI)
+			}
+			if err := cloudprivilege.CheckBackupSourcePrivileges(ctx, p, []string{metadataURI}); err != nil {
+				return err
+			}
+			metadataDest = []string{metadataURI}
+		}
+
+		fullyResolvedDest := metadataDest
 		if subdir != "" {
 			if strings.EqualFold(subdir, backupbase.LatestFileName) {
-				subdir, err = backupdest.ReadLatestFile(ctx, dest[0],
+				latest, err := backupdest.ReadLatestFile(ctx, metadataDest[0],
 					p.ExecCfg().DistSQLSrv.ExternalStorageFromURI,
 					p.User())
 				if err != nil {
 					return errors.Wrap(err, "read LATEST path")
 				}
+				subdir = latest.Subdir
 			}
-			fullyResolvedDest, err = backuputils.AppendPaths(dest, subdir)
+			fullyResolvedDest, err = backuputils.AppendPaths(metadataDest, subdir)
 			if err != nil {
 				return err
 			}
@@ -317,52 +389,11 @@ func showBackupPlanHook(
 			}
 			defer encStore.Close()
 		}
-		var encryption *jobspb.BackupEncryptionOptions
 		kmsEnv := backupencryption.MakeBackupKMSEnv(p.ExecCfg().Settings,
 			&p.ExecCfg().ExternalIODirConfig, p.ExecCfg().DB, p.User(), p.ExecCfg().InternalExecutor)
-		showEncErr := `If you are running SHOW BACKUP exclusively on an incremental backup, 
-you must pass the 'encryption_info_dir' parameter that points to the directory of your full backup`
-		if passphrase, ok := opts[backupencryption.BackupOptEncPassphrase]; ok {
-			opts, err := backupencryption.ReadEncryptionOptions(ctx, encStore)
-			if errors.Is(err, backupencryption.ErrEncryptionInfoRead) {
-				return errors.WithHint(err, showEncErr)
-			}
-			if err != nil {
-				return err
-			}
-			encryptionKey := storageccl.GenerateKey([]byte(passphrase), opts[0].Salt)
-			encryption = &jobspb.BackupEncryptionOptions{
-				Mode: jobspb.EncryptionMode_Passphrase,
-				Key:  encryptionKey,
-			}
-		} else if kms, ok := opts[backupencryption.BackupOptEncKMS]; ok {
-			opts, err := backupencryption.ReadEncryptionOptions(ctx, encStore)
-			if errors.Is(err, backupencryption.ErrEncryptionInfoRead) {
-				return errors.WithHint(err, showEncErr)
-			}
-			if err != nil {
-				return err
-			}
-
-			var defaultKMSInfo *jobspb.BackupEncryptionOptions_KMSInfo
-			for _, encFile := range opts {
-				defaultKMSInfo, err = backupencryption.ValidateKMSURIsAgainstFullBackup(
-					ctx,
-					[]string{kms},
-					backupencryption.NewEncryptedDataKeyMapFromProtoMap(encFile.EncryptedDataKeyByKMSMasterKeyID),
-					&kmsEnv,
-				)
-				if err == nil {
-					break
-				}
-			}
-			if err != nil {
-				return err
-			}
-			encryption = &jobspb.BackupEncryptionOptions{
-				Mode:    jobspb.EncryptionMode_KMS,
-				KMSInfo: defaultKMSInfo,
-			}
+		encryption, err := showBackupEncryption(ctx, encStore, opts, kmsURIs, &kmsEnv)
+		if err != nil {
+			return err
 		}
 		explicitIncPaths := make([]string, 0)
 		explicitIncPath := opts[backupOptIncStorage]
@@ -374,7 +405,7 @@ you must pass the 'encryption_info_dir' parameter that points to the directory o
 			}
 		}
 
-		collection, computedSubdir := backupdest.CollectionAndSubdir(dest[0], subdir)
+		collection, computedSubdir := backupdest.CollectionAndSubdir(metadataDest[0], subdir)
 		fullyResolvedIncrementalsDirectory, err := backupdest.ResolveIncrementalsBackupLocation(
 			ctx,
 			p.User(),
@@ -402,6 +433,7 @@ you must pass the 'encryption_info_dir' parameter that points to the directory o
 		)
 		info.collectionURI = dest[0]
 		info.subdir = computedSubdir
+		info.enc = encryption
 
 		mkStore := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI
 		incStores, cleanupFn, err := backupdest.MakeBackupDestinationStores(ctx, p.User(), mkStore,
@@ -418,7 +450,8 @@ you must pass the 'encryption_info_dir' parameter that points to the directory o
 		info.defaultURIs, info.manifests, info.localityInfo, memReserved,
 			err = backupdest.ResolveBackupManifests(
 			ctx, &mem, baseStores, incStores, mkStore, fullyResolvedDest,
-			fullyResolvedIncrementalsDirectory, hlc.Timestamp{}, encryption, &kmsEnv, p.User())
+			fullyResolvedIncrementalsDirectory, hlc.Timestamp{}, encryption, &kmsEnv, p.User(),
+			elideFiles)
 		defer func() {
 			mem.Shrink(ctx, memReserved)
 		}()
@@ -444,6 +477,12 @@ you must pass the 'encryption_info_dir' parameter that points to the directory o
 				return err
 			}
 		}
+		for i := range info.manifests {
+			if err := backupread.CheckReadable(&info.manifests[i],
+				p.ExecCfg().Settings.Version.BinaryVersion()); err != nil {
+				return err
+			}
+		}
 		// If backup is locality aware, check that user passed at least some localities.
 
 		// TODO (msbutler): this is an extremely crude check that the user is
@@ -459,8 +498,25 @@ you must pass the 'encryption_info_dir' parameter that points to the directory o
 						len(dest)))
 			}
 		}
+		// Flag the backups run with allow_missing_localities whose files of some
+		// localities were written to the default destination instead.
+		for i := range info.manifests {
+			if missing := info.manifests[i].MissingLocalityKVs; len(missing) > 0 {
+				p.BufferClientNotice(ctx,
+					pgnotice.Newf("The backup ending at %s could not write to the destination of "+
+						"localities %s; their files were written to the default destination instead",
+						timeutil.Unix(0, info.manifests[i].EndTime.WallTime).UTC(), strings.Join(missing, ", ")))
+			}
+		}
 		if _, ok := opts[backupOptCheckFiles]; ok {
-			fileSizes, err := checkBackupFiles(ctx, info,
+			dataInfo := info
+			if hasMetadataURI {
+				dataInfo.defaultURIs, err = backupdest.RebaseURIs(info.defaultURIs, metadataURI, dest[0])
+				if err != nil {
+					return err
+				}
+			}
+			fileSizes, err := checkBackupFiles(ctx, dataInfo,
 				p.ExecCfg().DistSQLSrv.ExternalStorageFromURI,
 				p.User())
 			if err != nil {
@@ -544,8 +600,10 @@ func checkBackupFiles(
 			localityStores[locality] = store
 		}
 
-		// Check all backup SSTs.
+		// Check all backup SSTs. Many entries can refer to the same file, whose
+		// trailer only needs to be checked once.
 		fileSizes := make([]int64, len(info.manifests[layer].Files))
+		checkedTrailers := make(map[string]struct{})
 		for i, f := range info.manifests[layer].Files {
 			store := defaultStore
 			uri := info.defaultURIs[layer]
@@ -566,6 +624,14 @@ func checkBackupFiles(
 				continue
 			}
 			fileSizes[i] = sz
+			if _, ok := checkedTrailers[f.LocalityKV+"/"+f.Path]; ok {
+				continue
+			}
+			if err := checkFileTrailer(ctx, store, f, sz); err != nil {
+				uriNoLocality := strings.Split(uri, "?")[0]
+				return nil, errors.Wrapf(err, "checking backup file in %s", uriNoLocality)
+			}
+			checkedTrailers[f.LocalityKV+"/"+f.Path] = struct{}{}
 		}
 
 		return fileSizes, nil
@@ -597,6 +663,111 @@ func checkBackupFiles(
 	return manifestFileSizes, nil
 }
 
+// checkFileTrailer verifies that the backup file of entry f, which is sz bytes
+// in store, matches the trailer recorded for it when it was written.
+func checkFileTrailer(
+	ctx context.Context, store cloud.ExternalStorage, f backuppb.BackupManifest_File, sz int64,
+) error {
+	if f.Trailer.Size == 0 {
+		// The backup did not record a trailer for this file.
+		return nil
+	}
+	if !f.Trailer.Span.Contains(f.Span) {
+		return errors.Newf("file %s has an entry for span %s outside of the span %s written to it",
+			f.Path, f.Span, f.Trailer.Span)
+	}
+	sf := storageccl.StoreFile{
+		Store:          store,
+		FilePath:       f.Path,
+		ExpectedSize:   f.Trailer.Size,
+		ExpectedCRC32C: f.Trailer.CRC32C,
+	}
+	if err := sf.VerifySize(sz); err != nil {
+		return err
+	}
+	r, _, err := store.ReadFileAt(ctx, f.Path, 0)
+	if err != nil {
+		return err
+	}
+	defer r.Close(ctx)
+	content, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return err
+	}
+	return sf.VerifyContent(content)
+}
+
+// showBackupKMSURIs splits the kms options out of opts, returning the other
+// options and the KMS URIs the kms options name. Unlike the other options of
+// SHOW BACKUP, kms can be passed more than once, since a backup encrypted with
+// decrypt_quorum can only be decrypted with a quorum of its KMSs.
+func showBackupKMSURIs(
+	ctx context.Context, p sql.PlanHookState, opts tree.KVOptions,
+) (tree.KVOptions, []string, error) {
+	var rest tree.KVOptions
+	var kmsExprs tree.Exprs
+	for _, opt := range opts {
+		if string(opt.Key) != backupencryption.BackupOptEncKMS {
+			rest = append(rest, opt)
+			continue
+		}
+		if opt.Value == nil {
+			return nil, nil, errors.Errorf("option %q requires a value", opt.Key)
+		}
+		kmsExprs = append(kmsExprs, opt.Value)
+	}
+	if len(kmsExprs) == 0 {
+		return rest, nil, nil
+	}
+	kmsFn, err := p.TypeAsStringArray(ctx, kmsExprs, "SHOW BACKUP")
+	if err != nil {
+		return nil, nil, err
+	}
+	kmsURIs, err := kmsFn()
+	if err != nil {
+		return nil, nil, err
+	}
+	return rest, kmsURIs, nil
+}
+
+// showBackupEncryption returns the options to decrypt the backups whose
+// ENCRYPTION-INFO is in encStore with the passphrase or KMSs passed to SHOW
+// BACKUP, or nil if neither was passed.
+func showBackupEncryption(
+	ctx context.Context,
+	encStore cloud.ExternalStorage,
+	opts map[string]string,
+	kmsURIs []string,
+	kmsEnv cloud.KMSEnv,
+) (*jobspb.BackupEncryptionOptions, error) {
+	showEncErr := `If you are running SHOW BACKUP exclusively on an incremental backup, 
+you must pass the 'encryption_info_dir' parameter that points to the directory of your full backup`
+	if passphrase, ok := opts[backupencryption.BackupOptEncPassphrase]; ok {
+		encInfo, err := backupencryption.ReadEncryptionOptions(ctx, encStore)
+		if errors.Is(err, backupencryption.ErrEncryptionInfoRead) {
+			return nil, errors.WithHint(err, showEncErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		encryptionKey := storageccl.GenerateKey([]byte(passphrase), encInfo[0].Salt)
+		return &jobspb.BackupEncryptionOptions{
+			Mode: jobspb.EncryptionMode_Passphrase,
+			Key:  encryptionKey,
+		}, nil
+	} else if len(kmsURIs) > 0 {
+		encInfo, err := backupencryption.ReadEncryptionOptions(ctx, encStore)
+		if errors.Is(err, backupencryption.ErrEncryptionInfoRead) {
+			return nil, errors.WithHint(err, showEncErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return backupencryption.MakeKMSEncryptionOptions(ctx, kmsURIs, encInfo, kmsEnv)
+	}
+	return nil, nil
+}
+
 type backupInfo struct {
 	collectionURI string
 	defaultURIs   []string
@@ -614,6 +785,19 @@ type backupShower struct {
 	// fn is the specific implementation of the shower that can either be a default, ranges, files,
 	// or JSON shower.
 	fn func(ctx context.Context, info backupInfo) ([]tree.Datums, error)
+
+	// iterFn, if set, is used in place of fn. Rather than materializing every
+	// row up front, it hands rows to push one at a time, so that a consumer
+	// which stops reading early, e.g. because of a LIMIT, also stops the shower
+	// from reading any more of the backup's metadata.
+	iterFn func(
+		ctx context.Context,
+		info backupInfo,
+		mkStore cloud.ExternalStorageFromURIFactory,
+		user username.SQLUsername,
+		kmsEnv cloud.KMSEnv,
+		push func(tree.Datums) error,
+	) error
 }
 
 // backupShowerHeaders defines the schema for the table presented to the user.
@@ -630,9 +814,15 @@ func backupShowerHeaders(showSchemas bool, opts map[string]string) colinfo.Resul
 		{Name: "rows", Typ: types.Int},
 		{Name: "is_full_cluster", Typ: types.Bool},
 		{Name: "regions", Typ: types.String},
+		{Name: "app_token", Typ: types.String},
 	}
 	if showSchemas {
-		baseHeaders = append(baseHeaders, colinfo.ResultColumn{Name: "create_statement", Typ: types.String})
+		baseHeaders = append(baseHeaders,
+			colinfo.ResultColumn{Name: "create_statement", Typ: types.String},
+			colinfo.ResultColumn{Name: "row_level_ttl", Typ: types.String},
+			colinfo.ResultColumn{Name: "ttl_schedule_status", Typ: types.String},
+			colinfo.ResultColumn{Name: "ttl_next_run", Typ: types.TimestampTZ},
+		)
 	}
 	if _, shouldShowPrivleges := opts[backupOptWithPrivileges]; shouldShowPrivleges {
 		baseHeaders = append(baseHeaders, colinfo.ResultColumn{Name: "privileges", Typ: types.String})
@@ -666,6 +856,11 @@ func backupShowerDefault(
 			ctx, sp := tracing.ChildSpan(ctx, "backupccl.backupShowerDefault.fn")
 			defer sp.Finish()
 
+			chainTableSizes, err := getTableSizes(ctx, info.manifests, info.fileSizes)
+			if err != nil {
+				return nil, err
+			}
+
 			var rows []tree.Datums
 			for layer, manifest := range info.manifests {
 				ctx, sp := tracing.ChildSpan(ctx, "backupccl.backupShowerDefault.fn.layer")
@@ -719,14 +914,7 @@ func backupShowerDefault(
 					}
 				}
 
-				var fileSizes []int64
-				if len(info.fileSizes) > 0 {
-					fileSizes = info.fileSizes[layer]
-				}
-				tableSizes, err := getTableSizes(ctx, manifest.Files, fileSizes)
-				if err != nil {
-					return nil, err
-				}
+				tableSizes := chainTableSizes[layer]
 				backupType := tree.NewDString("full")
 				if manifest.IsIncremental() {
 					backupType = tree.NewDString("incremental")
@@ -753,6 +941,7 @@ func backupShowerDefault(
 					var parentSchemaID descpb.ID
 
 					createStmtDatum := tree.DNull
+					ttlDatum, ttlStatusDatum, ttlNextRunDatum := tree.DNull, tree.DNull, tree.DNull
 					dataSizeDatum := tree.DNull
 					rowCountDatum := tree.DNull
 					fileSizeDatum := tree.DNull
@@ -807,6 +996,7 @@ func backupShowerDefault(
 								log.Errorf(ctx, "error while generating create statement: %+v", err)
 							}
 							createStmtDatum = nullIfEmpty(createStmt)
+							ttlDatum, ttlStatusDatum, ttlNextRunDatum = showRowLevelTTL(desc, &manifest)
 						}
 					default:
 						descriptorType = "unknown"
@@ -824,9 +1014,10 @@ func backupShowerDefault(
 						rowCountDatum,
 						tree.MakeDBool(manifest.DescriptorCoverage == tree.AllDescriptors),
 						regionsDatum,
+						nullIfEmpty(manifest.AppToken),
 					}
 					if showSchemas {
-						row = append(row, createStmtDatum)
+						row = append(row, createStmtDatum, ttlDatum, ttlStatusDatum, ttlNextRunDatum)
 					}
 					if _, shouldShowPrivileges := opts[backupOptWithPrivileges]; shouldShowPrivileges {
 						row = append(row, tree.NewDString(showPrivileges(ctx, desc)))
@@ -865,9 +1056,10 @@ func backupShowerDefault(
 						tree.DNull, // RowCount
 						tree.DNull, // Descriptor Coverage
 						tree.DNull, // Regions
+						nullIfEmpty(manifest.AppToken),
 					}
 					if showSchemas {
-						row = append(row, tree.DNull)
+						row = append(row, tree.DNull, tree.DNull, tree.DNull, tree.DNull)
 					}
 					if _, shouldShowPrivileges := opts[backupOptWithPrivileges]; shouldShowPrivileges {
 						row = append(row, tree.DNull)
@@ -925,26 +1117,54 @@ func approximateSpanPhysicalSize(
 	return int64(float64(physicalSSTSize) * (float64(logicalSpanSize) / float64(logicalSSTSize)))
 }
 
-// getTableSizes gathers row and size count for each table in the manifest
+// getTableSizes gathers row and size count for each table in each layer of
+// the chain described by manifests, in a single pass over the files of the
+// chain rather than one over the file list of each layer. fileSizes, if set,
+// holds the size of each file of each layer.
 func getTableSizes(
-	ctx context.Context, files []backuppb.BackupManifest_File, fileSizes []int64,
-) (map[descpb.ID]descriptorSize, error) {
+	ctx context.Context, manifests []backuppb.BackupManifest, fileSizes [][]int64,
+) ([]map[descpb.ID]descriptorSize, error) {
 	ctx, span := tracing.ChildSpan(ctx, "backupccl.getTableSizes")
 	defer span.Finish()
 
-	tableSizes := make(map[descpb.ID]descriptorSize)
-	if len(files) == 0 {
-		return tableSizes, nil
+	tableSizes := make([]map[descpb.ID]descriptorSize, len(manifests))
+	// The physical size approximation needs the logical size of every SST in
+	// a layer, so it is only computed when the file sizes were requested.
+	logicalSSTSizes := make([]map[string]int64, len(manifests))
+	for layer := range manifests {
+		tableSizes[layer] = make(map[descpb.ID]descriptorSize)
+		if len(fileSizes) > 0 {
+			logicalSSTSizes[layer] = getLogicalSSTSize(ctx, manifests[layer].Files)
+		}
 	}
-	_, tenantID, err := keys.DecodeTenantPrefix(files[0].Span.Key)
+
+	it, err := backupinfo.NewChainFileIter(ctx, nil /* stores */, manifests, nil /* encryption */, nil /* kmsEnv */)
 	if err != nil {
 		return nil, err
 	}
-	showCodec := keys.MakeSQLCodec(tenantID)
-
-	logicalSSTSize := getLogicalSSTSize(ctx, files)
+	defer it.Close()
+
+	// The codec of each layer is that of the tenant of its first file, and the
+	// position of each file within its layer indexes its size in fileSizes.
+	codecs := make([]*keys.SQLCodec, len(manifests))
+	positions := make([]int, len(manifests))
+	var file backuppb.BackupManifest_File
+	for {
+		layer, ok := it.Next(&file)
+		if !ok {
+			break
+		}
+		i := positions[layer]
+		positions[layer]++
+		if codecs[layer] == nil {
+			_, tenantID, err := keys.DecodeTenantPrefix(file.Span.Key)
+			if err != nil {
+				return nil, err
+			}
+			codec := keys.MakeSQLCodec(tenantID)
+			codecs[layer] = &codec
+		}
 
-	for i, file := range files {
 		// TODO(dan): This assumes each file in the backup only
 		// contains data from a single table, which is usually but
 		// not always correct. It does not account for a BACKUP that
@@ -954,17 +1174,20 @@ func getTableSizes(
 		// TODO(msbutler): after handling the todo above, understand whether
 		// we should return an error if a key does not have tableId. The lack
 		// of error handling let #77705 sneak by our unit tests.
-		_, tableID, err := showCodec.DecodeTablePrefix(file.Span.Key)
+		_, tableID, err := codecs[layer].DecodeTablePrefix(file.Span.Key)
 		if err != nil {
 			continue
 		}
-		s := tableSizes[descpb.ID(tableID)]
+		s := tableSizes[layer][descpb.ID(tableID)]
 		s.rowCount.Add(file.EntryCounts)
-		if len(fileSizes) > 0 {
-			s.fileSize += approximateSpanPhysicalSize(file.EntryCounts.DataSize, logicalSSTSize[file.Path],
-				fileSizes[i])
+		if len(fileSizes) > 0 && i < len(fileSizes[layer]) {
+			s.fileSize += approximateSpanPhysicalSize(file.EntryCounts.DataSize,
+				logicalSSTSizes[layer][file.Path], fileSizes[layer][i])
 		}
-		tableSizes[descpb.ID(tableID)] = s
+		tableSizes[layer][descpb.ID(tableID)] = s
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
 	}
 	return tableSizes, nil
 }
@@ -1164,6 +1387,122 @@ var backupShowerDoctor = backupShower{
 	},
 }
 
+// backupShowerCheckKMS returns a shower that reports, for each layer of the
+// backup, whether the given KMS URI can decrypt the data key stored in each of
+// the ENCRYPTION-INFO files that the layer is encrypted with. Incremental
+// layers do not write their own ENCRYPTION-INFO file and so report on the
+// files of the full backup, which are read from encDir if it is set.
+func backupShowerCheckKMS(kmsURI string, encDir string) backupShower {
+	return backupShower{header: colinfo.ResultColumns{
+		{Name: "backup_type", Typ: types.String},
+		{Name: "start_time", Typ: types.Timestamp},
+		{Name: "end_time", Typ: types.Timestamp},
+		{Name: "encryption_info_file", Typ: types.String},
+		{Name: "num_kms_keys", Typ: types.Int},
+		{Name: "key_found", Typ: types.Bool},
+		{Name: "key_decrypts", Typ: types.Bool},
+		{Name: "error", Typ: types.String},
+	},
+
+		iterFn: func(
+			ctx context.Context,
+			info backupInfo,
+			mkStore cloud.ExternalStorageFromURIFactory,
+			user username.SQLUsername,
+			kmsEnv cloud.KMSEnv,
+			push func(tree.Datums) error,
+		) error {
+			// checkStore reads every ENCRYPTION-INFO file in the store at uri
+			// and checks the KMS against each of them. A store without any
+			// ENCRYPTION-INFO files returns no rows.
+			checkStore := func(uri string) ([]tree.Datums, error) {
+				store, err := mkStore(ctx, uri, user)
+				if err != nil {
+					return nil, errors.Wrapf(err, "make storage")
+				}
+				defer store.Close()
+
+				files, err := backupencryption.GetEncryptionInfoFiles(ctx, store)
+				if err != nil {
+					return nil, nil //nolint:returnerrcheck
+				}
+				rows := make([]tree.Datums, 0, len(files))
+				for _, file := range files {
+					encInfo, err := backupencryption.ReadEncryptionInfoFile(ctx, store, file)
+					if err != nil {
+						return nil, err
+					}
+					errDatum := tree.DNull
+					found, err := backupencryption.CheckKMSURIAgainstEncryptionInfo(
+						ctx, kmsURI, encInfo, kmsEnv)
+					if err != nil {
+						errDatum = tree.NewDString(err.Error())
+					} else if !found {
+						errDatum = tree.NewDString(
+							"the KMS master key is not one of the keys this file was encrypted with")
+					}
+					rows = append(rows, tree.Datums{
+						tree.NewDString(file),
+						tree.NewDInt(tree.DInt(len(encInfo.EncryptedDataKeyByKMSMasterKeyID))),
+						tree.MakeDBool(tree.DBool(found)),
+						tree.MakeDBool(tree.DBool(found && err == nil)),
+						errDatum,
+					})
+				}
+				return rows, nil
+			}
+
+			fullURI := info.defaultURIs[0]
+			if encDir != "" {
+				fullURI = encDir
+			}
+			fullRows, err := checkStore(fullURI)
+			if err != nil {
+				return err
+			}
+			if len(fullRows) == 0 {
+				return errors.WithHint(backupencryption.ErrEncryptionInfoRead,
+					"SHOW BACKUP with check_kms can only be used on a KMS encrypted backup")
+			}
+
+			for layer, manifest := range info.manifests {
+				rows := fullRows
+				if layer > 0 && encDir == "" {
+					layerRows, err := checkStore(info.defaultURIs[layer])
+					if err != nil {
+						return err
+					}
+					if len(layerRows) > 0 {
+						rows = layerRows
+					}
+				}
+
+				backupType := tree.NewDString("full")
+				if manifest.IsIncremental() {
+					backupType = tree.NewDString("incremental")
+				}
+				start := tree.DNull
+				end, err := tree.MakeDTimestamp(timeutil.Unix(0, manifest.EndTime.WallTime), time.Nanosecond)
+				if err != nil {
+					return err
+				}
+				if manifest.StartTime.WallTime != 0 {
+					start, err = tree.MakeDTimestamp(timeutil.Unix(0, manifest.StartTime.WallTime), time.Nanosecond)
+					if err != nil {
+						return err
+					}
+				}
+				for _, row := range rows {
+					if err := push(append(tree.Datums{backupType, start, end}, row...)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
 func backupShowerFileSetup(inCol tree.StringOrPlaceholderOptList) backupShower {
 	return backupShower{header: colinfo.ResultColumns{
 		{Name: "path", Typ: types.String},
@@ -1178,63 +1517,122 @@ func backupShowerFileSetup(inCol tree.StringOrPlaceholderOptList) backupShower {
 		{Name: "file_bytes", Typ: types.Int},
 	},
 
-		fn: func(ctx context.Context, info backupInfo) (rows []tree.Datums, err error) {
-
+		iterFn: func(
+			ctx context.Context,
+			info backupInfo,
+			mkStore cloud.ExternalStorageFromURIFactory,
+			user username.SQLUsername,
+			kmsEnv cloud.KMSEnv,
+			push func(tree.Datums) error,
+		) error {
 			var manifestDirs []string
 			var localityAware bool
 			if len(inCol) > 0 {
+				var err error
 				manifestDirs, err = getManifestDirs(info.subdir, info.defaultURIs)
 				if err != nil {
-					return nil, err
+					return err
 				}
 
 				if len(info.localityInfo[0].URIsByOriginalLocalityKV) > 0 {
 					localityAware = true
 				}
 			}
-			for i, manifest := range info.manifests {
-				backupType := "full"
-				if manifest.IsIncremental() {
-					backupType = "incremental"
+			for i := range info.manifests {
+				var manifestDir string
+				if inCol != nil {
+					manifestDir = manifestDirs[i]
 				}
-
-				logicalSSTSize := getLogicalSSTSize(ctx, manifest.Files)
-				for j, file := range manifest.Files {
-					filePath := file.Path
-					if inCol != nil {
-						filePath = path.Join(manifestDirs[i], filePath)
-					}
-					locality := "NULL"
-					if localityAware {
-						locality = "default"
-						if _, ok := info.localityInfo[i].URIsByOriginalLocalityKV[file.LocalityKV]; ok {
-							locality = file.LocalityKV
-						}
-					}
-					sz := int64(-1)
-					if len(info.fileSizes) > 0 {
-						sz = approximateSpanPhysicalSize(file.EntryCounts.DataSize,
-							logicalSSTSize[file.Path], info.fileSizes[i][j])
-					}
-					rows = append(rows, tree.Datums{
-						tree.NewDString(filePath),
-						tree.NewDString(backupType),
-						tree.NewDString(file.Span.Key.String()),
-						tree.NewDString(file.Span.EndKey.String()),
-						tree.NewDBytes(tree.DBytes(file.Span.Key)),
-						tree.NewDBytes(tree.DBytes(file.Span.EndKey)),
-						tree.NewDInt(tree.DInt(file.EntryCounts.DataSize)),
-						tree.NewDInt(tree.DInt(file.EntryCounts.Rows)),
-						tree.NewDString(locality),
-						tree.NewDInt(tree.DInt(sz)),
-					})
+				if err := showBackupLayerFiles(ctx, info, i, manifestDir, localityAware,
+					mkStore, user, kmsEnv, push); err != nil {
+					return err
 				}
 			}
-			return rows, nil
+			return nil
 		},
 	}
 }
 
+// showBackupLayerFiles pushes a SHOW BACKUP FILES row for each file in the
+// given layer of the backup. Files are read through a
+// backupinfo.LayerFileIterator, so if push returns an error, e.g. because the
+// consumer has stopped reading, no more of the layer's file list is read.
+func showBackupLayerFiles(
+	ctx context.Context,
+	info backupInfo,
+	layer int,
+	manifestDir string,
+	localityAware bool,
+	mkStore cloud.ExternalStorageFromURIFactory,
+	user username.SQLUsername,
+	kmsEnv cloud.KMSEnv,
+	push func(tree.Datums) error,
+) error {
+	manifest := &info.manifests[layer]
+	backupType := "full"
+	if manifest.IsIncremental() {
+		backupType = "incremental"
+	}
+
+	store, err := mkStore(ctx, info.defaultURIs[layer], user)
+	if err != nil {
+		return errors.Wrapf(err, "make storage")
+	}
+	defer store.Close()
+
+	it, err := backupinfo.NewLayerFileIter(ctx, store, manifest, info.enc, kmsEnv)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	// The physical size approximation needs the logical size of every SST in
+	// the layer, which requires a pass over all of its files; it is only
+	// computed when the file sizes were requested, in which case the manifest
+	// was read with its file list.
+	var logicalSSTSize map[string]int64
+	var fileSizes []int64
+	if len(info.fileSizes) > 0 {
+		logicalSSTSize = getLogicalSSTSize(ctx, manifest.Files)
+		fileSizes = info.fileSizes[layer]
+	}
+
+	var file backuppb.BackupManifest_File
+	for j := 0; it.Next(&file); j++ {
+		filePath := file.Path
+		if manifestDir != "" {
+			filePath = path.Join(manifestDir, filePath)
+		}
+		locality := "NULL"
+		if localityAware {
+			locality = "default"
+			if _, ok := info.localityInfo[layer].URIsByOriginalLocalityKV[file.LocalityKV]; ok {
+				locality = file.LocalityKV
+			}
+		}
+		sz := int64(-1)
+		if j < len(fileSizes) {
+			sz = approximateSpanPhysicalSize(file.EntryCounts.DataSize,
+				logicalSSTSize[file.Path], fileSizes[j])
+		}
+		if err := push(tree.Datums{
+			tree.NewDString(filePath),
+			tree.NewDString(backupType),
+			tree.NewDString(file.Span.Key.String()),
+			tree.NewDString(file.Span.EndKey.String()),
+			tree.NewDBytes(tree.DBytes(file.Span.Key)),
+			tree.NewDBytes(tree.DBytes(file.Span.EndKey)),
+			tree.NewDInt(tree.DInt(file.EntryCounts.DataSize)),
+			tree.NewDInt(tree.DInt(file.EntryCounts.Rows)),
+			tree.NewDString(locality),
+			tree.NewDInt(tree.DInt(sz)),
+		}); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
 // getRootURI splits a fully resolved backup URI at the backup's subdirectory
 // and returns the path to that subdirectory. e.g. for a full backup URI,
 // getRootURI returns the collectionURI.
@@ -1345,7 +1743,11 @@ func showBackupsInCollectionPlanHook(
 			return err
 		}
 
-		if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, collection); err != nil {
+		if err := cloudprivilege.CheckBackupSourcePrivileges(ctx, p, collection); err != nil {
+			return err
+		}
+		if err := backupdest.CheckConnectionCapability(ctx, p.ExecCfg(), "SHOW BACKUPS",
+			connectionpb.CapabilityList, collection...); err != nil {
 			return err
 		}
 
@@ -1359,11 +1761,31 @@ func showBackupsInCollectionPlanHook(
 			return err
 		}
 		for _, i := range res {
-			resultsCh <- tree.Datums{tree.NewDString(i)}
+			row := tree.Datums{tree.NewDString(i), tree.DNull, tree.DNull, tree.DNull}
+			size, found, err := backupdest.ReadChainSize(ctx, store, i)
+			if err != nil {
+				return err
+			}
+			if found {
+				row[1] = tree.NewDInt(tree.DInt(size.NumLayers))
+				row[2] = tree.NewDInt(tree.DInt(size.LogicalSize))
+				row[3] = tree.NewDInt(tree.DInt(size.PhysicalSize))
+			}
+			resultsCh <- row
 		}
 		return nil
 	}
-	return fn, colinfo.ResultColumns{{Name: "path", Typ: types.String}}, nil, false, nil
+	return fn, showBackupsHeader, nil, false, nil
+}
+
+// showBackupsHeader is the header of SHOW BACKUPS IN. The sizes are NULL for
+// backup chains that do not record their size, such as those started before
+// sizes were recorded.
+var showBackupsHeader = colinfo.ResultColumns{
+	{Name: "path", Typ: types.String},
+	{Name: "layers", Typ: types.Int},
+	{Name: "logical_size", Typ: types.Int},
+	{Name: "physical_size", Typ: types.Int},
 }
 
 func init() {
@@ -0,0 +1,288 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/catconstants"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+)
+
+// showBackupDiffHeader is the header of SHOW BACKUP DIFF. The deltas are
+// approximate: they are computed from the row and byte counts the manifests of
+// each backup chain record for its files, which for incremental backups count
+// the rows that changed rather than the rows in the table.
+var showBackupDiffHeader = colinfo.ResultColumns{
+	{Name: "database_name", Typ: types.String},
+	{Name: "parent_schema_name", Typ: types.String},
+	{Name: "object_name", Typ: types.String},
+	{Name: "change", Typ: types.String},
+	{Name: "rows_delta", Typ: types.Int},
+	{Name: "size_bytes_delta", Typ: types.Int},
+}
+
+// The changes SHOW BACKUP DIFF reports for a table. A table is altered if its
+// descriptor changed between the backups, e.g. because of a schema change.
+const (
+	backupDiffAdded     = "added"
+	backupDiffDropped   = "dropped"
+	backupDiffAltered   = "altered"
+	backupDiffUnchanged = "unchanged"
+)
+
+// backupChainTables is the state of the tables of a backup chain as of the end
+// time of its last backup.
+type backupChainTables struct {
+	tables map[descpb.ID]catalog.TableDescriptor
+	// names maps the IDs of databases and schemas to their names.
+	names map[descpb.ID]string
+	sizes map[descpb.ID]roachpb.RowCount
+}
+
+// showBackupDiffPlanHook implements SHOW BACKUP DIFF, which compares the
+// tables of two backup chains in the same collection.
+func showBackupDiffPlanHook(
+	ctx context.Context, backup *tree.ShowBackup, p sql.PlanHookState,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	const stmtName = "SHOW BACKUP DIFF"
+	fromFn, err := p.TypeAsString(ctx, backup.Path, stmtName)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	toFn, err := p.TypeAsString(ctx, backup.DiffPath, stmtName)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	inColFn, err := p.TypeAsStringArray(ctx, tree.Exprs(backup.InCollection), stmtName)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	options, kmsURIs, err := showBackupKMSURIs(ctx, p, backup.Options)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	optsFn, err := p.TypeAsStringOpts(ctx, options, map[string]sql.KVStringOptValidate{
+		backupencryption.BackupOptEncPassphrase: sql.KVStringOptRequireValue,
+	})
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		ctx, span := tracing.ChildSpan(ctx, backup.StatementTag())
+		defer span.Finish()
+
+		from, err := fromFn()
+		if err != nil {
+			return err
+		}
+		to, err := toFn()
+		if err != nil {
+			return err
+		}
+		collection, err := inColFn()
+		if err != nil {
+			return err
+		}
+		opts, err := optsFn()
+		if err != nil {
+			return err
+		}
+
+		if err := cloudprivilege.CheckBackupSourcePrivileges(ctx, p, collection); err != nil {
+			return err
+		}
+		if err := backupdest.CheckConnectionCapability(ctx, p.ExecCfg(), stmtName,
+			connectionpb.CapabilityRestoreRead, collection...); err != nil {
+			return err
+		}
+
+		mem := p.ExecCfg().RootMemoryMonitor.MakeBoundAccount()
+		defer mem.Close(ctx)
+		kmsEnv := backupencryption.MakeBackupKMSEnv(p.ExecCfg().Settings,
+			&p.ExecCfg().ExternalIODirConfig, p.ExecCfg().DB, p.User(), p.ExecCfg().InternalExecutor)
+
+		fromTables, err := readBackupChainTables(ctx, p, &mem, collection, from, opts, kmsURIs, &kmsEnv)
+		if err != nil {
+			return errors.Wrapf(err, "reading backup %s", from)
+		}
+		toTables, err := readBackupChainTables(ctx, p, &mem, collection, to, opts, kmsURIs, &kmsEnv)
+		if err != nil {
+			return errors.Wrapf(err, "reading backup %s", to)
+		}
+		for _, row := range diffBackupChainTables(fromTables, toTables) {
+			resultsCh <- row
+		}
+		return nil
+	}
+	return fn, showBackupDiffHeader, nil, false, nil
+}
+
+// readBackupChainTables reads the manifests of the backup chain in subdir of
+// the collection and returns its tables.
+func readBackupChainTables(
+	ctx context.Context,
+	p sql.PlanHookState,
+	mem *mon.BoundAccount,
+	collection []string,
+	subdir string,
+	opts map[string]string,
+	kmsURIs []string,
+	kmsEnv cloud.KMSEnv,
+) (backupChainTables, error) {
+	mkStore := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI
+	if strings.EqualFold(subdir, backupbase.LatestFileName) {
+		latest, err := backupdest.ReadLatestFile(ctx, collection[0], mkStore, p.User())
+		if err != nil {
+			return backupChainTables{}, errors.Wrap(err, "read LATEST path")
+		}
+		subdir = latest.Subdir
+	}
+	fullyResolvedDest, err := backuputils.AppendPaths(collection, subdir)
+	if err != nil {
+		return backupChainTables{}, err
+	}
+	baseStores := make([]cloud.ExternalStorage, len(fullyResolvedDest))
+	for i := range fullyResolvedDest {
+		baseStores[i], err = mkStore(ctx, fullyResolvedDest[i], p.User())
+		if err != nil {
+			return backupChainTables{}, errors.Wrapf(err, "make storage")
+		}
+		defer baseStores[i].Close()
+	}
+	encryption, err := showBackupEncryption(ctx, baseStores[0], opts, kmsURIs, kmsEnv)
+	if err != nil {
+		return backupChainTables{}, err
+	}
+
+	incDirs, err := backupdest.ResolveIncrementalsBackupLocation(
+		ctx, p.User(), p.ExecCfg(), nil /* explicitIncrementalCollections */, collection[:1], subdir,
+	)
+	if err != nil {
+		if !errors.Is(err, cloud.ErrListingUnsupported) {
+			return backupChainTables{}, err
+		}
+		log.Warningf(ctx, "storage sink %v does not support listing, only diffing the base backup",
+			collection[0])
+	}
+	incStores, cleanupFn, err := backupdest.MakeBackupDestinationStores(ctx, p.User(), mkStore, incDirs)
+	if err != nil {
+		return backupChainTables{}, err
+	}
+	defer func() {
+		if err := cleanupFn(); err != nil {
+			log.Warningf(ctx, "failed to close incremental store: %+v", err)
+		}
+	}()
+
+	_, manifests, _, memReserved, err := backupdest.ResolveBackupManifests(
+		ctx, mem, baseStores, incStores, mkStore, fullyResolvedDest, incDirs, hlc.Timestamp{},
+		encryption, kmsEnv, p.User(), false, /* elideFiles */
+	)
+	if err != nil {
+		return backupChainTables{}, err
+	}
+	defer mem.Shrink(ctx, memReserved)
+
+	descriptors, _, err := backupinfo.LoadSQLDescsFromBackupsAtTime(manifests, hlc.Timestamp{})
+	if err != nil {
+		return backupChainTables{}, err
+	}
+	res := backupChainTables{
+		tables: make(map[descpb.ID]catalog.TableDescriptor),
+		names: map[descpb.ID]string{
+			keys.PublicSchemaIDForBackup: catconstants.PublicSchemaName,
+		},
+		sizes: make(map[descpb.ID]roachpb.RowCount),
+	}
+	for _, desc := range descriptors {
+		switch d := desc.(type) {
+		case catalog.TableDescriptor:
+			if !d.Dropped() {
+				res.tables[d.GetID()] = d
+			}
+		case catalog.DatabaseDescriptor, catalog.SchemaDescriptor:
+			res.names[d.GetID()] = d.GetName()
+		}
+	}
+	chainTableSizes, err := getTableSizes(ctx, manifests, nil /* fileSizes */)
+	if err != nil {
+		return backupChainTables{}, err
+	}
+	for _, tableSizes := range chainTableSizes {
+		for id, size := range tableSizes {
+			rowCount := res.sizes[id]
+			rowCount.Add(size.rowCount)
+			res.sizes[id] = rowCount
+		}
+	}
+	return res, nil
+}
+
+// diffBackupChainTables returns a row for each table in either of the backup
+// chains, ordered by table ID.
+func diffBackupChainTables(from, to backupChainTables) []tree.Datums {
+	ids := make([]descpb.ID, 0, len(to.tables))
+	for id := range to.tables {
+		ids = append(ids, id)
+	}
+	for id := range from.tables {
+		if _, ok := to.tables[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	rows := make([]tree.Datums, 0, len(ids))
+	for _, id := range ids {
+		fromTable, inFrom := from.tables[id]
+		toTable, inTo := to.tables[id]
+		table, names, change := toTable, to.names, backupDiffUnchanged
+		switch {
+		case !inFrom:
+			change = backupDiffAdded
+		case !inTo:
+			table, names, change = fromTable, from.names, backupDiffDropped
+		case fromTable.GetVersion() != toTable.GetVersion():
+			change = backupDiffAltered
+		}
+		rows = append(rows, tree.Datums{
+			nullIfEmpty(names[table.GetParentID()]),
+			nullIfEmpty(names[table.GetParentSchemaID()]),
+			tree.NewDString(table.GetName()),
+			tree.NewDString(change),
+			tree.NewDInt(tree.DInt(to.sizes[id].Rows - from.sizes[id].Rows)),
+			tree.NewDInt(tree.DInt(to.sizes[id].DataSize - from.sizes[id].DataSize)),
+		})
+	}
+	return rows
+}
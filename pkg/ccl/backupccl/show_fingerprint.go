@@ -0,0 +1,218 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupread"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/catconstants"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// backupShowerFingerprints returns a row for each index of the tables in the
+// backup with the fingerprint SHOW EXPERIMENTAL_FINGERPRINTS computes for it,
+// computed over the data files of the backup's chain as of its end time. A
+// backup matches the tables it was taken of if their fingerprints AS OF SYSTEM
+// TIME the end time of the backup are the same.
+func backupShowerFingerprints(p sql.PlanHookState) backupShower {
+	return backupShower{header: colinfo.ResultColumns{
+		{Name: "database_name", Typ: types.String},
+		{Name: "parent_schema_name", Typ: types.String},
+		{Name: "object_name", Typ: types.String},
+		{Name: "index_name", Typ: types.String},
+		{Name: "fingerprint", Typ: types.String},
+	},
+
+		iterFn: func(
+			ctx context.Context,
+			info backupInfo,
+			mkStore cloud.ExternalStorageFromURIFactory,
+			user username.SQLUsername,
+			kmsEnv cloud.KMSEnv,
+			push func(tree.Datums) error,
+		) error {
+			chain := backupread.NewChain(info.manifests, info.enc, backupread.Options{
+				User:                       user,
+				MakeExternalStorageFromURI: mkStore,
+				MakeExternalStorage:        p.ExecCfg().DistSQLSrv.ExternalStorage,
+				KMSEnv:                     kmsEnv,
+			})
+			descs, err := chain.Descriptors(hlc.Timestamp{})
+			if err != nil {
+				return err
+			}
+
+			dbIDToName := make(map[descpb.ID]string)
+			schemaIDToName := make(map[descpb.ID]string)
+			schemaIDToName[keys.PublicSchemaIDForBackup] = catconstants.PublicSchemaName
+			for _, desc := range descs {
+				switch d := desc.(type) {
+				case catalog.DatabaseDescriptor:
+					dbIDToName[d.GetID()] = d.GetName()
+				case catalog.SchemaDescriptor:
+					schemaIDToName[d.GetID()] = d.GetName()
+				}
+			}
+
+			// SHOW EXPERIMENTAL_FINGERPRINTS casts datums to strings in an
+			// internal session, so its session data is used here as well, e.g.
+			// to format timestamps in UTC.
+			evalCtx := p.ExtendedEvalContext().Context.Copy()
+			evalCtx.SessionDataStack = sessiondata.NewStack(sql.NewFakeSessionData(&p.ExecCfg().Settings.SV))
+
+			for _, desc := range descs {
+				table, ok := desc.(catalog.TableDescriptor)
+				if !ok || !table.IsTable() || !table.Public() {
+					continue
+				}
+				for _, index := range table.ActiveIndexes() {
+					cols, err := backupFingerprintColumns(table, index)
+					if err != nil {
+						return err
+					}
+					if cols == nil {
+						p.BufferClientNotice(ctx, pgnotice.Newf(
+							"skipping index %s of table %s: its fingerprint can't be computed from a backup",
+							index.GetName(), table.GetName()))
+						continue
+					}
+					fingerprint, err := fingerprintBackupIndex(ctx, evalCtx, chain, table, index, cols)
+					if err != nil {
+						return errors.Wrapf(err, "fingerprinting index %s of table %s",
+							index.GetName(), table.GetName())
+					}
+					if err := push(tree.Datums{
+						tree.NewDString(dbIDToName[table.GetParentID()]),
+						tree.NewDString(schemaIDToName[table.GetParentSchemaID()]),
+						tree.NewDString(table.GetName()),
+						tree.NewDString(index.GetName()),
+						fingerprint,
+					}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// backupFingerprintColumns returns the columns SHOW EXPERIMENTAL_FINGERPRINTS
+// hashes for each entry of index, or nil if some of them are not stored in the
+// index and so can't be read from a backup: the virtual columns of a table
+// are only computed when its primary index is read, and an inverted index
+// only stores the tokens of the column it indexes.
+func backupFingerprintColumns(
+	table catalog.TableDescriptor, index catalog.Index,
+) ([]catalog.Column, error) {
+	if index.GetType() != descpb.IndexDescriptor_FORWARD {
+		return nil, nil
+	}
+	if index.Primary() {
+		for _, col := range table.PublicColumns() {
+			if col.IsVirtual() {
+				return nil, nil
+			}
+		}
+		return table.PublicColumns(), nil
+	}
+
+	var cols []catalog.Column
+	addColumns := func(n int, columnID func(int) descpb.ColumnID) error {
+		for i := 0; i < n; i++ {
+			col, err := table.FindColumnWithID(columnID(i))
+			if err != nil {
+				return err
+			}
+			cols = append(cols, col)
+		}
+		return nil
+	}
+	if err := addColumns(index.NumKeyColumns(), index.GetKeyColumnID); err != nil {
+		return nil, err
+	}
+	if err := addColumns(index.NumKeySuffixColumns(), index.GetKeySuffixColumnID); err != nil {
+		return nil, err
+	}
+	if err := addColumns(index.NumSecondaryStoredColumns(), index.GetStoredColumnID); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// fingerprintBackupIndex computes the fingerprint of index over the entries
+// of the backup chain: the xor of the fnv64 hash of the columns of each entry,
+// where columns that are not bytes are hashed as strings, or NULL if there are
+// no entries. A partial index only has entries for the rows matching its
+// predicate, which SHOW EXPERIMENTAL_FINGERPRINTS filters the rows by.
+func fingerprintBackupIndex(
+	ctx context.Context,
+	evalCtx *eval.Context,
+	chain *backupread.Chain,
+	table catalog.TableDescriptor,
+	index catalog.Index,
+	cols []catalog.Column,
+) (tree.Datum, error) {
+	columnIDs := make([]descpb.ColumnID, len(cols))
+	for i, col := range cols {
+		columnIDs[i] = col.GetID()
+	}
+
+	var fingerprint uint64
+	var seen bool
+	h := fnv.New64()
+	if err := chain.ReadIndex(ctx, table, index, columnIDs, hlc.Timestamp{}, func(row tree.Datums) error {
+		h.Reset()
+		var nonNull bool
+		for i, d := range row {
+			if d == tree.DNull {
+				continue
+			}
+			nonNull = true
+			if cols[i].GetType().Family() == types.BytesFamily {
+				_, _ = h.Write([]byte(tree.MustBeDBytes(d)))
+				continue
+			}
+			s, err := eval.PerformCast(ctx, evalCtx, d, types.String)
+			if err != nil {
+				return err
+			}
+			_, _ = h.Write([]byte(tree.MustBeDString(s)))
+		}
+		// fnv64 is NULL for an entry whose columns are all NULL, which xor_agg
+		// skips.
+		if nonNull {
+			fingerprint ^= h.Sum64()
+			seen = true
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if !seen {
+		return tree.DNull, nil
+	}
+	return tree.NewDString(strconv.FormatInt(int64(fingerprint), 10)), nil
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/skip"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/stretchr/testify/require"
@@ -527,6 +528,51 @@ func TestShowBackups(t *testing.T) {
 
 }
 
+func TestShowBackupsChainSizes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 11
+	_, sqlDB, tempDir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	const collection = localFoo + "/sizes"
+	sqlDB.Exec(t, `BACKUP data.bank INTO $1`, collection)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1`)
+	sqlDB.Exec(t, `BACKUP data.bank INTO LATEST IN $1`, collection)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1 WHERE id < 5`)
+	sqlDB.Exec(t, `BACKUP data.bank INTO LATEST IN $1`, collection)
+
+	var path string
+	var layers, logicalSize, physicalSize int64
+	sqlDB.QueryRow(t, `SHOW BACKUPS IN $1`, collection).Scan(&path, &layers, &logicalSize, &physicalSize)
+	require.Equal(t, int64(3), layers)
+
+	var expectedLogicalSize int64
+	sqlDB.QueryRow(t, `SELECT sum(size_bytes) FROM [SHOW BACKUP $1 IN $2]`,
+		path, collection).Scan(&expectedLogicalSize)
+	require.Equal(t, expectedLogicalSize, logicalSize)
+
+	// The physical size is the size of the data files of all the layers.
+	var expectedPhysicalSize int64
+	require.NoError(t, filepath.Walk(filepath.Join(tempDir, "foo", "sizes"),
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if strings.HasSuffix(path, ".sst") && strings.Contains(path, "/data/") {
+				expectedPhysicalSize += info.Size()
+			}
+			return nil
+		}))
+	require.Equal(t, expectedPhysicalSize, physicalSize)
+
+	// A chain that did not record its size has no sizes.
+	require.NoError(t, os.RemoveAll(filepath.Join(tempDir, "foo", "sizes", backupbase.ChainSizesDirectory)))
+	require.Equal(t, [][]string{{path, "NULL", "NULL", "NULL"}},
+		sqlDB.QueryStr(t, `SHOW BACKUPS IN $1`, collection))
+}
+
 func TestShowNonDefaultBackups(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -1018,3 +1064,125 @@ func TestShowBackupCheckFiles(t *testing.T) {
 		}
 	}
 }
+
+// TestShowBackupCheckFilesTruncated verifies that a backup file that was
+// truncated after it was written is detected by SHOW BACKUP with check_files
+// and by RESTORE, using the size recorded in the file's trailer.
+func TestShowBackupCheckFilesTruncated(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, tempDir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts,
+		InitManualReplication)
+	defer cleanupFn()
+
+	const dest = `'nodelocal://1/trailer'`
+	sqlDB.Exec(t, `BACKUP TABLE data.bank INTO `+dest)
+
+	checkQuery := `SHOW BACKUP FROM LATEST IN ` + dest + ` WITH check_files`
+	sqlDB.Exec(t, checkQuery)
+
+	files := sqlDB.QueryStr(t, `SELECT path FROM [SHOW BACKUP FILES FROM LATEST IN `+dest+`]`)
+	require.NotEmpty(t, files)
+	fullPath := filepath.Join(tempDir, "trailer", files[0][0])
+	info, err := os.Stat(fullPath)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(fullPath, info.Size()-1))
+
+	sqlDB.ExpectErr(t, "may have been truncated", checkQuery)
+	sqlDB.Exec(t, `CREATE DATABASE restoredb`)
+	sqlDB.ExpectErr(t, "may have been truncated",
+		`RESTORE TABLE data.bank FROM LATEST IN `+dest+` WITH into_db = 'restoredb'`)
+}
+
+// TestShowBackupCheckKMS verifies that SHOW BACKUP with check_kms reports, for
+// each layer of an encrypted backup chain, whether the KMS can decrypt the data
+// key in the full backup's ENCRYPTION-INFO file.
+func TestShowBackupCheckKMS(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 1
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	kmsURIs := constructMockKMSURIsWithKeyID([]string{"abc", "def"})
+	sqlDB.Exec(t, `BACKUP data.bank INTO $1 WITH kms = ($2, $3)`, localFoo, kmsURIs[0], kmsURIs[1])
+	sqlDB.Exec(t, `BACKUP data.bank INTO LATEST IN $1 WITH kms = ($2, $3)`, localFoo, kmsURIs[0], kmsURIs[1])
+
+	for _, kmsURI := range kmsURIs {
+		sqlDB.CheckQueryResults(t,
+			fmt.Sprintf(`SELECT backup_type, encryption_info_file, num_kms_keys, key_found, key_decrypts, error
+FROM [SHOW BACKUP FROM LATEST IN '%s' WITH kms = '%s', check_kms]`, localFoo, kmsURI),
+			[][]string{
+				{"full", "ENCRYPTION-INFO", "2", "true", "true", "NULL"},
+				{"incremental", "ENCRYPTION-INFO", "2", "true", "true", "NULL"},
+			})
+	}
+
+	sqlDB.ExpectErr(t, "SHOW BACKUP option check_kms requires the kms option",
+		`SHOW BACKUP FROM LATEST IN $1 WITH check_kms`, localFoo)
+}
+
+func TestShowBackupFingerprint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE TABLE data.t (
+		id INT PRIMARY KEY, s STRING, b BYTES, ts TIMESTAMPTZ, d DECIMAL,
+		INDEX (s) STORING (b), INDEX (d) WHERE d > 1
+	)`)
+	sqlDB.Exec(t, `INSERT INTO data.t VALUES
+		(1, 'a', 'x', '2022-01-01 10:00:00+02', 1.5), (2, NULL, NULL, NULL, NULL)`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, localFoo)
+	sqlDB.Exec(t, `INSERT INTO data.t VALUES (3, 'c', '\x00', now(), 2.25)`)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1 WHERE id < 5`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, localFoo)
+
+	var endTime time.Time
+	sqlDB.QueryRow(t, `SELECT max(end_time) FROM [SHOW BACKUP LATEST IN $1]`, localFoo).Scan(&endTime)
+	// Writes after the backup don't change its fingerprints.
+	sqlDB.Exec(t, `DELETE FROM data.t WHERE id = 1`)
+
+	aost := hlc.Timestamp{WallTime: endTime.UnixNano()}.AsOfSystemTime()
+	for _, table := range []string{"bank", "t"} {
+		expected := sqlDB.QueryStr(t, fmt.Sprintf(
+			`SELECT index_name, fingerprint FROM [SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE data.%s]
+			AS OF SYSTEM TIME %s ORDER BY index_name`, table, aost))
+		sqlDB.CheckQueryResults(t, fmt.Sprintf(
+			`SELECT index_name, fingerprint FROM [SHOW BACKUP LATEST IN '%s' WITH fingerprint]
+			WHERE object_name = '%s' ORDER BY index_name`, localFoo, table), expected)
+	}
+
+	sqlDB.ExpectErr(t, "cannot be used with SCHEMAS",
+		`SHOW BACKUP SCHEMAS FROM LATEST IN $1 WITH fingerprint`, localFoo)
+}
+
+func TestShowBackupAppToken(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, 0, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1 WITH app_token = 'schema-v41'`, localFoo)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, localFoo)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1 WITH app_token = 'schema-v42'`, localFoo)
+
+	// Each layer shows the token of the backup that wrote it.
+	sqlDB.CheckQueryResults(t, `SELECT backup_type, app_token FROM [SHOW BACKUP LATEST IN $1]
+		WHERE object_name = 'bank' ORDER BY end_time`, [][]string{
+		{"full", "schema-v41"}, {"incremental", "NULL"}, {"incremental", "schema-v42"},
+	})
+
+	sqlDB.ExpectErr(t, "app_token must be at most 1024 bytes",
+		`BACKUP DATABASE data INTO LATEST IN $1 WITH app_token = $2`, localFoo,
+		strings.Repeat("x", 1025))
+	sqlDB.ExpectErr(t, "app_token cannot be used with skip_if_unchanged",
+		`BACKUP DATABASE data INTO LATEST IN $1 WITH app_token = 'v43', skip_if_unchanged`, localFoo)
+}
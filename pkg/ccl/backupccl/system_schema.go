@@ -550,6 +550,15 @@ var systemTableBackupConfiguration = map[string]systemBackupConfiguration{
 		customRestoreFunc:            roleIDSeqRestoreFunc,
 		restoreInOrder:               roleIDSequenceRestoreOrder,
 	},
+	systemschema.SystemExternalIOAuditTable.GetName(): {
+		shouldIncludeInClusterBackup: optOutOfClusterBackup,
+	},
+	systemschema.SystemScheduledBackupRunsTable.GetName(): {
+		shouldIncludeInClusterBackup: optOutOfClusterBackup,
+	},
+	systemschema.SystemTableBackupCheckpointsTable.GetName(): {
+		shouldIncludeInClusterBackup: optOutOfClusterBackup,
+	},
 }
 
 func rekeySystemTable(
@@ -173,7 +173,9 @@ var changefeedResultTypes = []*types.T{
 }
 
 // fetchSpansForTable returns the set of spans for the specified table.
-// Usually, this is just the primary index span.
+// Usually, this is just the primary index span, but a changefeed with
+// format=backup_kv watches the spans of all the indexes of the table, which a
+// backup consolidated from its KVs must cover.
 // However, if details.Select is not empty, the set of spans returned may be
 // restricted to satisfy predicate in the select clause.  In that case,
 // possibly updated select clause returned representing the remaining expression
@@ -185,6 +187,12 @@ func fetchSpansForTables(
 	details jobspb.ChangefeedDetails,
 ) (_ []roachpb.Span, updatedExpression string, _ error) {
 	var trackedSpans []roachpb.Span
+	if details.Opts[changefeedbase.OptFormat] == string(changefeedbase.OptFormatBackupKV) {
+		for _, d := range tableDescs {
+			trackedSpans = append(trackedSpans, d.TableSpan(execCtx.ExecCfg().Codec))
+		}
+		return trackedSpans, "", nil
+	}
 	if details.Select == "" {
 		for _, d := range tableDescs {
 			trackedSpans = append(trackedSpans, d.PrimaryIndexSpan(execCtx.ExecCfg().Codec))
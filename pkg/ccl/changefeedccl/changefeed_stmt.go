@@ -493,6 +493,16 @@ func createChangefeedJobRecord(
 	if _, err := getEncoder(encodingOpts, AllTargets(details)); err != nil {
 		return nil, err
 	}
+	if encodingOpts.Format == changefeedbase.OptFormatBackupKV {
+		if !isCloudStorageSink(parsedSink) {
+			return nil, errors.Errorf(`%s=%s requires a cloud storage sink`,
+				changefeedbase.OptFormat, changefeedbase.OptFormatBackupKV)
+		}
+		if details.Select != "" {
+			return nil, errors.Errorf(`%s=%s cannot be used with CREATE CHANGEFEED ... AS SELECT`,
+				changefeedbase.OptFormat, changefeedbase.OptFormatBackupKV)
+		}
+	}
 
 	//	 The changefeed is opted in to `OptKeyInValue` for any cloud
 	//   storage sink or webhook sink. Kafka etc have a key and value field in
@@ -510,7 +520,8 @@ func createChangefeedJobRecord(
 	//   explicitly set. Fortunately we know the only way to cause this is to
 	//   set envelope.
 	if (isCloudStorageSink(parsedSink) || isWebhookSink(parsedSink)) &&
-		encodingOpts.Envelope != changefeedbase.OptEnvelopeBare {
+		encodingOpts.Envelope != changefeedbase.OptEnvelopeBare &&
+		encodingOpts.Format != changefeedbase.OptFormatBackupKV {
 		if err = opts.ForceKeyInValue(); err != nil {
 			return nil, errors.Errorf(`this sink is incompatible with envelope=%s`, encodingOpts.Envelope)
 		}
@@ -4198,6 +4198,38 @@ func TestChangefeedErrors(t *testing.T) {
 		`CREATE CHANGEFEED FOR foo INTO $1 WITH topic_in_value, format='experimental_avro'`,
 		`kafka://nope`,
 	)
+	// The backup_kv format continues a backup chain, so it must start where the
+	// chain ends and must resolve timestamps to consolidate the chain up to.
+	sqlDB.ExpectErr(
+		t, `format=backup_kv requires cursor`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='backup_kv', resolved, schema_change_policy='stop'`,
+		`nodelocal://0/changes`,
+	)
+	sqlDB.ExpectErr(
+		t, `format=backup_kv cannot be used with an initial scan`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='backup_kv', cursor=$2, initial_scan='yes', resolved, schema_change_policy='stop'`,
+		`nodelocal://0/changes`, s.Clock().Now().AsOfSystemTime(),
+	)
+	sqlDB.ExpectErr(
+		t, `format=backup_kv requires resolved`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='backup_kv', cursor=$2, schema_change_policy='stop'`,
+		`nodelocal://0/changes`, s.Clock().Now().AsOfSystemTime(),
+	)
+	sqlDB.ExpectErr(
+		t, `format=backup_kv requires schema_change_policy='stop'`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='backup_kv', cursor=$2, resolved`,
+		`nodelocal://0/changes`, s.Clock().Now().AsOfSystemTime(),
+	)
+	sqlDB.ExpectErr(
+		t, `diff cannot be used with format=backup_kv`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='backup_kv', cursor=$2, resolved, schema_change_policy='stop', diff`,
+		`nodelocal://0/changes`, s.Clock().Now().AsOfSystemTime(),
+	)
+	sqlDB.ExpectErr(
+		t, `format=backup_kv requires a cloud storage sink`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='backup_kv', cursor=$2, resolved, schema_change_policy='stop'`,
+		`kafka://nope`, s.Clock().Now().AsOfSystemTime(),
+	)
 
 	// The topics option should not be exposed to users since it is used
 	// internally to display topics in the show changefeed jobs query
@@ -144,6 +144,9 @@ const (
 	OptFormatJSON FormatType = `json`
 	OptFormatAvro FormatType = `avro`
 	OptFormatCSV  FormatType = `csv`
+	// OptFormatBackupKV emits the raw KVs of the watched tables, to be
+	// consolidated into the incremental backups of a backup collection.
+	OptFormatBackupKV FormatType = `backup_kv`
 
 	OptOnErrorFail  OnErrorType = `fail`
 	OptOnErrorPause OnErrorType = `pause`
@@ -289,7 +292,7 @@ var ChangefeedOptionExpectValues = map[string]OptionPermittedValues{
 	OptCursor:                   timestampOption,
 	OptEndTime:                  timestampOption,
 	OptEnvelope:                 enum("row", "key_only", "wrapped", "deprecated_row", "bare"),
-	OptFormat:                   enum("json", "avro", "csv", "experimental_avro"),
+	OptFormat:                   enum("json", "avro", "csv", "experimental_avro", "backup_kv"),
 	OptFullTableName:            flagOption,
 	OptKeyInValue:               flagOption,
 	OptTopicInValue:             flagOption,
@@ -710,6 +713,26 @@ func (e EncodingOptions) Validate() error {
 			OptEnvelope, OptEnvelopeRow, OptFormat, OptFormatAvro,
 		)
 	}
+	if e.Format == OptFormatBackupKV {
+		// The raw KVs are not decoded into rows, so none of the options shaping
+		// the encoded rows apply to them.
+		notApplicable := []struct {
+			k string
+			b bool
+		}{
+			{OptEnvelope, e.Envelope != OptEnvelopeWrapped},
+			{OptKeyInValue, e.KeyInValue},
+			{OptTopicInValue, e.TopicInValue},
+			{OptUpdatedTimestamps, e.UpdatedTimestamps},
+			{OptMVCCTimestamps, e.MVCCTimestamps},
+			{OptDiff, e.Diff},
+		}
+		for _, v := range notApplicable {
+			if v.b {
+				return errors.Errorf(`%s cannot be used with %s=%s`, v.k, OptFormat, OptFormatBackupKV)
+			}
+		}
+	}
 	if e.Envelope != OptEnvelopeWrapped && e.Format != OptFormatJSON {
 		requiresWrap := []struct {
 			k string
@@ -922,6 +945,37 @@ func (s StatementOptions) ValidateForCreateChangefeed() error {
 			return errors.Newf(`%s=%s is only usable with %s`, OptFormat, OptFormatCSV, OptInitialScanOnly)
 		}
 	}
+	if s.m[OptFormat] == string(OptFormatBackupKV) {
+		return s.validateBackupKV(scanType)
+	}
+	return nil
+}
+
+// validateBackupKV checks that the options of a changefeed with
+// format=backup_kv let its output be consolidated into incremental backups: the
+// changefeed must emit every change since the end time of the backup it
+// continues, emit resolved timestamps up to which a backup can be consolidated,
+// and stop at schema changes, which only a regular backup can capture.
+func (s StatementOptions) validateBackupKV(scanType InitialScanType) error {
+	if !s.HasStartCursor() {
+		return errors.Newf(`%s=%s requires %s, set to the end time of the backup to continue`,
+			OptFormat, OptFormatBackupKV, OptCursor)
+	}
+	if scanType != NoInitialScan {
+		return errors.Newf(`%s=%s cannot be used with an initial scan`, OptFormat, OptFormatBackupKV)
+	}
+	if _, emit, err := s.GetResolvedTimestampInterval(); err != nil {
+		return err
+	} else if !emit {
+		return errors.Newf(`%s=%s requires %s`, OptFormat, OptFormatBackupKV, OptResolvedTimestamps)
+	}
+	if s.m[OptSchemaChangePolicy] != string(OptSchemaChangePolicyStop) {
+		return errors.Newf(`%s=%s requires %s='%s'`,
+			OptFormat, OptFormatBackupKV, OptSchemaChangePolicy, OptSchemaChangePolicyStop)
+	}
+	if _, ok := s.m[OptCompression]; ok {
+		return errors.Newf(`%s=%s cannot be used with %s`, OptFormat, OptFormatBackupKV, OptCompression)
+	}
 	return nil
 }
 
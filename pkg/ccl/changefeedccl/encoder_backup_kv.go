@@ -0,0 +1,62 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"context"
+	gojson "encoding/json"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// backupKVEncoder encodes the raw KVs of the watched tables, rather than their
+// rows, for a changefeed with format=backup_kv writing the changes that BACKUP
+// ... WITH consolidate_changes turns into incremental backups. Each KV is a
+// line of JSON holding a backupbase.ChangefeedKV.
+//
+// The KVs are not decoded, so the changefeed emits them with EncodeKV instead
+// of EncodeKey and EncodeValue.
+type backupKVEncoder struct{}
+
+var _ Encoder = &backupKVEncoder{}
+
+// EncodeKV encodes a KV of a watched table.
+func (e *backupKVEncoder) EncodeKV(kv roachpb.KeyValue) ([]byte, error) {
+	return gojson.Marshal(backupbase.ChangefeedKV{
+		Key:       kv.Key,
+		Value:     kv.Value.RawBytes,
+		Timestamp: kv.Value.Timestamp,
+	})
+}
+
+// EncodeKey implements the Encoder interface.
+func (e *backupKVEncoder) EncodeKey(context.Context, cdcevent.Row) ([]byte, error) {
+	return nil, errors.AssertionFailedf("rows are not encoded by a backup_kv changefeed")
+}
+
+// EncodeValue implements the Encoder interface.
+func (e *backupKVEncoder) EncodeValue(
+	context.Context, eventContext, cdcevent.Row, cdcevent.Row,
+) ([]byte, error) {
+	return nil, errors.AssertionFailedf("rows are not encoded by a backup_kv changefeed")
+}
+
+// EncodeResolvedTimestamp implements the Encoder interface.
+func (e *backupKVEncoder) EncodeResolvedTimestamp(
+	_ context.Context, _ string, resolved hlc.Timestamp,
+) ([]byte, error) {
+	return gojson.Marshal(map[string]interface{}{
+		`resolved`: eval.TimestampToDecimalDatum(resolved).Decimal.String(),
+	})
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
 	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/kvevent"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
@@ -58,6 +60,11 @@ type kvEventToRowConsumer struct {
 	details   ChangefeedConfig
 	evaluator *cdceval.Evaluator
 	safeExpr  string
+	codec     keys.SQLCodec
+
+	// backupKV is set for a changefeed with format=backup_kv, which emits the
+	// raw KVs of the watched tables instead of decoding them into rows.
+	backupKV *backupKVEncoder
 
 	topicDescriptorCache map[TopicIdentifier]TopicDescriptor
 	topicNamer           *TopicNamer
@@ -188,6 +195,8 @@ func newKVEventToRowConsumer(
 		}
 	}
 
+	backupKV, _ := encoder.(*backupKVEncoder)
+
 	return &kvEventToRowConsumer{
 		frontier:             frontier,
 		encoder:              encoder,
@@ -200,6 +209,8 @@ func newKVEventToRowConsumer(
 		topicNamer:           topicNamer,
 		evaluator:            evaluator,
 		safeExpr:             safeExpr,
+		codec:                cfg.Codec,
+		backupKV:             backupKV,
 	}, nil
 }
 
@@ -226,6 +237,9 @@ func (c *kvEventToRowConsumer) ConsumeEvent(ctx context.Context, ev kvevent.Even
 	if ev.Type() != kvevent.TypeKV {
 		return errors.AssertionFailedf("expected kv ev, got %v", ev.Type())
 	}
+	if c.backupKV != nil {
+		return c.consumeBackupKV(ctx, ev)
+	}
 
 	schemaTimestamp := ev.KV().Value.Timestamp
 	prevSchemaTimestamp := schemaTimestamp
@@ -352,6 +366,61 @@ func (c *kvEventToRowConsumer) ConsumeEvent(ctx context.Context, ev kvevent.Even
 	return nil
 }
 
+// consumeBackupKV emits the raw KV of ev, for a changefeed with
+// format=backup_kv.
+func (c *kvEventToRowConsumer) consumeBackupKV(ctx context.Context, ev kvevent.Event) error {
+	kv := ev.KV()
+	_, tableID, err := c.codec.DecodeTablePrefix(kv.Key)
+	if err != nil {
+		return err
+	}
+	topic, err := c.backupKVTopic(descpb.ID(tableID))
+	if err != nil {
+		return err
+	}
+
+	// As for rows, KVs at or below the local frontier have already been emitted.
+	if kv.Value.Timestamp.LessEq(c.frontier.Frontier()) && !kv.Value.Timestamp.Equal(c.cursor) {
+		log.Errorf(ctx, "cdc ux violation: detected timestamp %s that is less than "+
+			"or equal to the local frontier %s.", kv.Value.Timestamp, c.frontier.Frontier())
+		return nil
+	}
+
+	encoded, err := c.backupKV.EncodeKV(kv)
+	if err != nil {
+		return err
+	}
+	var valueCopy []byte
+	c.scratch, valueCopy = c.scratch.Copy(encoded, 0 /* extraCap */)
+
+	a := ev.DetachAlloc()
+	a.AdjustBytesToTarget(ctx, int64(len(valueCopy)))
+	return c.sink.EmitRow(
+		ctx, topic, nil /* key */, valueCopy, kv.Value.Timestamp, ev.MVCCTimestamp(), a,
+	)
+}
+
+// backupKVTopic returns the topic of the KVs of the table with the given ID.
+func (c *kvEventToRowConsumer) backupKVTopic(tableID descpb.ID) (TopicDescriptor, error) {
+	if topic, ok := c.topicDescriptorCache[TopicIdentifier{TableID: tableID}]; ok {
+		return topic, nil
+	}
+	var spec changefeedbase.Target
+	found, err := c.details.Targets.EachHavingTableID(tableID, func(t changefeedbase.Target) error {
+		spec = t
+		return nil
+	})
+	if err != nil {
+		return noTopic{}, err
+	}
+	if !found {
+		return noTopic{}, errors.AssertionFailedf("no TargetSpecification for table %d", tableID)
+	}
+	topic := &backupKVTopic{spec: spec}
+	c.topicDescriptorCache[topic.GetTopicIdentifier()] = topic
+	return topic, nil
+}
+
 // Close is a noop for the kvEventToRowConsumer because it
 // has no goroutines in flight.
 func (c *kvEventToRowConsumer) Close() error {
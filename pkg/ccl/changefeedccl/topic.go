@@ -291,6 +291,35 @@ func (cft *columnFamilyTopic) GetTargetSpecification() changefeedbase.Target {
 
 var _ TopicDescriptor = &columnFamilyTopic{}
 
+// backupKVTopic is the topic of the raw KVs of a table emitted by a changefeed
+// with format=backup_kv. The KVs are not decoded, so the topic is that of the
+// whole table and has no version.
+type backupKVTopic struct {
+	spec changefeedbase.Target
+}
+
+// GetNameComponents implements the TopicDescriptor interface
+func (bt *backupKVTopic) GetNameComponents() (changefeedbase.StatementTimeName, []string) {
+	return bt.spec.StatementTimeName, []string{}
+}
+
+// GetTopicIdentifier implements the TopicDescriptor interface
+func (bt *backupKVTopic) GetTopicIdentifier() TopicIdentifier {
+	return TopicIdentifier{TableID: bt.spec.TableID}
+}
+
+// GetVersion implements the TopicDescriptor interface
+func (bt *backupKVTopic) GetVersion() descpb.DescriptorVersion {
+	return 0
+}
+
+// GetTargetSpecification implements the TopicDescriptor interface
+func (bt *backupKVTopic) GetTargetSpecification() changefeedbase.Target {
+	return bt.spec
+}
+
+var _ TopicDescriptor = &backupKVTopic{}
+
 type noTopic struct{}
 
 var noStatementTimeName changefeedbase.StatementTimeName = ""
@@ -0,0 +1,89 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package cliccl
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/blobs"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/cli"
+	"github.com/cockroachdb/cockroach/pkg/cli/clierrorplus"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	checkStoreCompatCmd := &cobra.Command{
+		Use:   "check-store-compat <uri>",
+		Short: "check whether an external storage URI can hold backups",
+		Long: `
+Writes, lists, overwrites and deletes a handful of small objects in a scratch
+directory under 'uri' and reports which of the storage behaviors that backup
+destinations rely upon are supported, and in turn which BACKUP features can be
+used with the storage. The scratch directory is removed once the checks finish.
+
+This is intended for S3-compatible object stores whose listing, delimiter, or
+overwrite semantics may differ from the major cloud providers.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: clierrorplus.MaybeDecorateError(runCheckStoreCompat),
+	}
+	cli.DebugCmd.AddCommand(checkStoreCompatCmd)
+}
+
+// errNoBlobClient is returned for nodelocal URIs, which require a running
+// node to be served.
+var errNoBlobClient = errors.New("nodelocal storage is not supported by this command")
+
+func runCheckStoreCompat(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	blobClientFactory := func(ctx context.Context, dialing roachpb.NodeID) (blobs.BlobClient, error) {
+		return nil, errNoBlobClient
+	}
+	settings := cluster.MakeClusterSettings()
+	makeCloudStorage := func(
+		ctx context.Context, uri string, user username.SQLUsername, opts ...cloud.ExternalStorageOption,
+	) (cloud.ExternalStorage, error) {
+		return cloud.ExternalStorageFromURI(ctx, uri, base.ExternalIODirConfig{}, settings,
+			blobClientFactory, user, nil /* ie */, nil /* ief */, nil /* kvDB */, nil /* limiters */, opts...)
+	}
+
+	report, err := backupdest.CheckStoreCompatibility(ctx, args[0], makeCloudStorage, username.RootUserName())
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	fmt.Fprintln(w, "checks:")
+	for _, res := range report.Results {
+		if res.Passed() {
+			fmt.Fprintf(w, "  %-20s ok\n", res.Check)
+		} else {
+			fmt.Fprintf(w, "  %-20s FAILED: %v\n", res.Check, res.Err)
+		}
+	}
+	fmt.Fprintln(w, "features:")
+	for _, f := range backupdest.StoreCompatFeatures {
+		status := "supported"
+		if !report.Supports(f) {
+			status = "NOT supported"
+		}
+		fmt.Fprintf(w, "  %-35s %s\n", f.Name, status)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ package storageccl
 
 import (
 	"context"
+	"hash/crc32"
 	"io"
 	"os"
 	"time"
@@ -63,6 +64,53 @@ func getFileWithRetry(
 type StoreFile struct {
 	Store    cloud.ExternalStorage
 	FilePath string
+	// ExpectedSize and ExpectedCRC32C, if non-zero, are the size and CRC32C
+	// checksum that the file had when it was written. The size is verified
+	// whenever the file is opened; the checksum only when the whole file is
+	// read into memory.
+	ExpectedSize   int64
+	ExpectedCRC32C uint32
+	// Encryption, if set, overrides the encryption the file is read with, for
+	// files encrypted with a key other than that of the rest of the files.
+	Encryption *roachpb.FileEncryptionOptions
+}
+
+// encryption returns the encryption to read the file with, given the
+// encryption of the files it is read with.
+func (sf StoreFile) encryption(
+	encryption *roachpb.FileEncryptionOptions,
+) *roachpb.FileEncryptionOptions {
+	if sf.Encryption != nil {
+		return sf.Encryption
+	}
+	return encryption
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// VerifySize returns an error if the file has a recorded size that differs from
+// sz, which indicates that it was truncated.
+func (sf StoreFile) VerifySize(sz int64) error {
+	if sf.ExpectedSize != 0 && sz != sf.ExpectedSize {
+		return errors.Newf("file %s is %d bytes but %d bytes were written to it; it may have been truncated",
+			sf.FilePath, sz, sf.ExpectedSize)
+	}
+	return nil
+}
+
+// VerifyContent returns an error if content, the whole file, does not match the
+// recorded size and checksum of the file.
+func (sf StoreFile) VerifyContent(content []byte) error {
+	if err := sf.VerifySize(int64(len(content))); err != nil {
+		return err
+	}
+	if sf.ExpectedSize != 0 {
+		if crc := crc32.Checksum(content, crc32cTable); crc != sf.ExpectedCRC32C {
+			return errors.Newf("file %s has checksum %08x but %08x was written to it; it may be corrupt",
+				sf.FilePath, crc, sf.ExpectedCRC32C)
+		}
+	}
+	return nil
 }
 
 // newMemPebbleSSTReader returns a PebbleSSTIterator for in-memory SSTs from
@@ -89,8 +137,11 @@ func newMemPebbleSSTReader(
 		if err != nil {
 			return nil, err
 		}
-		if encryption != nil {
-			content, err = DecryptFile(ctx, content, encryption.Key, nil /* mm */)
+		if err := sf.VerifyContent(content); err != nil {
+			return nil, err
+		}
+		if enc := sf.encryption(encryption); enc != nil {
+			content, err = DecryptFile(ctx, content, enc.Key, nil /* mm */)
 			if err != nil {
 				return nil, err
 			}
@@ -134,6 +185,10 @@ func ExternalSSTReader(
 		if err != nil {
 			return nil, err
 		}
+		if err := sf.VerifySize(sz); err != nil {
+			f.Close(ctx)
+			return nil, err
+		}
 
 		raw := &sstReader{
 			ctx:  ctx,
@@ -147,8 +202,8 @@ func ExternalSSTReader(
 
 		var reader sstable.ReadableFile
 
-		if encryption != nil {
-			r, err := decryptingReader(raw, encryption.Key)
+		if enc := sf.encryption(encryption); enc != nil {
+			r, err := decryptingReader(raw, enc.Key)
 			if err != nil {
 				f.Close(ctx)
 				return nil, err
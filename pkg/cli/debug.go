@@ -1305,6 +1305,7 @@ var debugCmds = []*cobra.Command{
 	debugListFilesCmd,
 	debugResetQuorumCmd,
 	debugSendKVBatchCmd,
+	debugRangeStatsLogCmd,
 	debugRecoverCmd,
 }
 
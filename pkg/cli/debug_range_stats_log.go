@@ -0,0 +1,98 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/pkg/cli/clierrorplus"
+	"github.com/cockroachdb/cockroach/pkg/cli/clisqlexec"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/spf13/cobra"
+)
+
+var debugRangeStatsLogCmd = &cobra.Command{
+	Use:   "range-stats-log [range ID]",
+	Short: "display the recent MVCC stats deltas applied to a range",
+	Long: `
+Displays the MVCC stats deltas most recently applied by each replica of the
+given range, along with the ID of the command that applied each delta and the
+replica that proposed it, oldest first. This can be used to pinpoint the
+command that introduced an MVCC stats inconsistency reported by the
+consistency checker.
+
+The deltas are only recorded while the kv.replica.stats_delta_log.size cluster
+setting is nonzero; it must be set before the commands of interest apply.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: clierrorplus.MaybeDecorateError(runDebugRangeStatsLog),
+}
+
+func runDebugRangeStatsLog(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rangeID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	c, finish, err := getStatusClient(ctx, serverCfg)
+	if err != nil {
+		return err
+	}
+	defer finish()
+
+	resp, err := c.Range(ctx, &serverpb.RangeRequest{RangeId: rangeID})
+	if err != nil {
+		return err
+	}
+
+	nodeIDs := make([]roachpb.NodeID, 0, len(resp.ResponsesByNodeID))
+	for nodeID := range resp.ResponsesByNodeID {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Slice(nodeIDs, func(i, j int) bool { return nodeIDs[i] < nodeIDs[j] })
+
+	headers := []string{
+		"node_id", "store_id", "raft_applied_index", "lease_applied_index", "cmd_id", "proposer", "delta",
+	}
+	alignment := "rrrrlll"
+	var rows [][]string
+	for _, nodeID := range nodeIDs {
+		nodeResp := resp.ResponsesByNodeID[nodeID]
+		if nodeResp.ErrorMessage != "" {
+			log.Warningf(ctx, "cannot retrieve range %d from node %d: %s", rangeID, nodeID, nodeResp.ErrorMessage)
+			continue
+		}
+		for _, info := range nodeResp.Infos {
+			for _, e := range info.State.StatsDeltaLog {
+				rows = append(rows, []string{
+					fmt.Sprintf("%d", info.SourceNodeID),
+					fmt.Sprintf("%d", info.SourceStoreID),
+					fmt.Sprintf("%d", e.RaftAppliedIndex),
+					fmt.Sprintf("%d", e.LeaseAppliedIndex),
+					fmt.Sprintf("%x", e.CmdID),
+					e.Proposer.String(),
+					e.Delta.String(),
+				})
+			}
+		}
+	}
+
+	return sqlExecCtx.PrintQueryOutput(os.Stdout, stderr, headers, clisqlexec.NewRowSliceIter(rows, alignment))
+}
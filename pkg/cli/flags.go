@@ -579,6 +579,7 @@ func init() {
 		debugZipCmd,
 		debugListFilesCmd,
 		debugSendKVBatchCmd,
+		debugRangeStatsLogCmd,
 		doctorExamineClusterCmd,
 		doctorExamineFallbackClusterCmd,
 		doctorRecreateClusterCmd,
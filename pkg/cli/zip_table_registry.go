@@ -828,6 +828,17 @@ var zipSystemTables = DebugZipTableRegistry{
 			"connection_type",
 		},
 	},
+	"system.external_io_audit": {
+		// `uri` column may contain customer bucket names and paths.
+		nonSensitiveCols: NonSensitiveColumns{
+			"ts",
+			"id",
+			"principal",
+			"job_id",
+			"operation",
+			"bytes",
+		},
+	},
 	"system.jobs": {
 		// `payload` column may contain customer info, such as URI params
 		// containing access keys, encryption salts, etc.
@@ -979,6 +990,21 @@ var zipSystemTables = DebugZipTableRegistry{
 			"value",
 		},
 	},
+	"system.scheduled_backup_runs": {
+		// `destination` and `error` columns may contain customer bucket names
+		// and paths.
+		nonSensitiveCols: NonSensitiveColumns{
+			"schedule_id",
+			"finished",
+			"id",
+			"job_id",
+			"status",
+			"started",
+			"subdir",
+			"data_size",
+			"row_count",
+		},
+	},
 	"system.scheduled_jobs": {
 		// `execution_args` column contains BACKUP statements which can contain
 		// sensitive URI params, such as AWS keys.
@@ -1054,6 +1080,14 @@ var zipSystemTables = DebugZipTableRegistry{
 			"sampling_probability",
 		},
 	},
+	"system.table_backup_checkpoints": {
+		nonSensitiveCols: NonSensitiveColumns{
+			"table_id",
+			"database_id",
+			"end_time",
+			"job_id",
+		},
+	},
 	"system.table_statistics": {
 		// `histogram` may contain sensitive information, such as keys and non-key column data.
 		nonSensitiveCols: NonSensitiveColumns{
@@ -67,6 +67,7 @@ FROM [ SELECT table_name FROM [ SHOW TABLES FROM crdb_internal ] ]
 WHERE
 table_name NOT IN (
 	-- allowlisted tables that don't need to be in debug zip
+	'backup_remaining_spans',
 	'backward_dependencies',
 	'builtin_functions',
 	'cluster_contended_keys',
@@ -74,7 +75,9 @@ table_name NOT IN (
 	'cluster_contended_tables',
 	'cluster_inflight_traces',
 	'cross_db_references',
+	'database_storage_stats',
 	'databases',
+	'external_io_audit',
 	'forward_dependencies',
 	'index_columns',
 	'lost_descriptors_with_data',
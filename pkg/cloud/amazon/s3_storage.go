@@ -18,6 +18,7 @@ import (
 	"net/url"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -94,6 +95,7 @@ type s3Storage struct {
 
 	opts   s3ClientConfig
 	cached *s3Client
+	upload cloudpb.UploadOptions
 }
 
 var _ request.Retryer = &customRetryer{}
@@ -184,6 +186,7 @@ var s3ClientCache struct {
 }
 
 var _ cloud.ExternalStorage = &s3Storage{}
+var _ cloud.ArchiveStorage = &s3Storage{}
 
 type serverSideEncMode string
 
@@ -376,6 +379,7 @@ func MakeS3Storage(
 		prefix:   conf.Prefix,
 		settings: args.Settings,
 		opts:     clientConfig(conf),
+		upload:   args.UploadOptions(),
 	}
 
 	reuse := reuseSession.Get(&args.Settings.SV)
@@ -620,12 +624,17 @@ func (s *s3Storage) Writer(ctx context.Context, basename string) (io.WriteCloser
 		return nil, err
 	}
 
+	parallelism, partSize := s.upload.Resolve(
+		s3manager.DefaultUploadConcurrency, cloud.WriteChunkSize.Get(&s.settings.SV))
+	if partSize < s3manager.MinUploadPartSize {
+		partSize = s3manager.MinUploadPartSize
+	}
+
 	ctx, sp := tracing.ChildSpan(ctx, "s3.Writer")
 	sp.RecordStructured(&types.StringValue{Value: fmt.Sprintf("s3.Writer: %s", path.Join(s.prefix, basename))})
 	return cloud.BackgroundPipe(ctx, func(ctx context.Context, r io.Reader) error {
 		defer sp.Finish()
 		// Upload the file to S3.
-		// TODO(dt): test and tune the uploader parameters.
 		_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 			Bucket:               s.bucket,
 			Key:                  aws.String(path.Join(s.prefix, basename)),
@@ -633,6 +642,9 @@ func (s *s3Storage) Writer(ctx context.Context, basename string) (io.WriteCloser
 			ServerSideEncryption: nilIfEmpty(s.conf.ServerEncMode),
 			SSEKMSKeyId:          nilIfEmpty(s.conf.ServerKMSID),
 			StorageClass:         nilIfEmpty(s.conf.StorageClass),
+		}, func(u *s3manager.Uploader) {
+			u.Concurrency = parallelism
+			u.PartSize = partSize
 		})
 		return errors.Wrap(err, "upload failed")
 	}), nil
@@ -723,6 +735,28 @@ func (s *s3Storage) ReadFileAt(
 }
 
 func (s *s3Storage) List(ctx context.Context, prefix, delim string, fn cloud.ListingFn) error {
+	return s.list(ctx, prefix, delim, "" /* startAfter */, fn)
+}
+
+// ListWithOptions implements the ExternalStorage interface. S3 lists keys in
+// ascending order and can start listing after a given key, so only reverse
+// listings have to be buffered.
+func (s *s3Storage) ListWithOptions(
+	ctx context.Context, prefix string, opts cloud.ListOptions, fn cloud.ListingFn,
+) error {
+	if opts.Reverse {
+		return cloud.ListByBuffering(ctx, s, prefix, opts, fn)
+	}
+	return cloud.ListOrdered(opts, fn, func(fn cloud.ListingFn) error {
+		return s.list(ctx, prefix, opts.Delimiter, opts.StartAfter, fn)
+	})
+}
+
+// list lists the keys, and the common prefixes if delim is set, under prefix
+// that come after startAfter, in ascending order.
+func (s *s3Storage) list(
+	ctx context.Context, prefix, delim, startAfter string, fn cloud.ListingFn,
+) error {
 	ctx, sp := tracing.ChildSpan(ctx, "s3.List")
 	defer sp.Finish()
 
@@ -736,13 +770,18 @@ func (s *s3Storage) List(ctx context.Context, prefix, delim string, fn cloud.Lis
 
 	var fnErr error
 	pageFn := func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		// A page covers a contiguous range of the keys, but lists the common
+		// prefixes in it before the keys, so they are merged back in order.
+		names := make([]string, 0, len(page.CommonPrefixes)+len(page.Contents))
 		for _, x := range page.CommonPrefixes {
-			if fnErr = fn(strings.TrimPrefix(*x.Prefix, dest)); fnErr != nil {
-				return false
-			}
+			names = append(names, strings.TrimPrefix(*x.Prefix, dest))
 		}
 		for _, fileObject := range page.Contents {
-			if fnErr = fn(strings.TrimPrefix(*fileObject.Key, dest)); fnErr != nil {
+			names = append(names, strings.TrimPrefix(*fileObject.Key, dest))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if fnErr = fn(name); fnErr != nil {
 				return false
 			}
 		}
@@ -750,15 +789,15 @@ func (s *s3Storage) List(ctx context.Context, prefix, delim string, fn cloud.Lis
 		return true
 	}
 
-	var s3Input *s3.ListObjectsInput
-	// Add an environment variable toggle for s3 storage to list prefixes with a
-	// paging marker that's the prefix with an additional /. This allows certain
-	// s3 clones which return s3://<prefix>/ as the first result of listing
-	// s3://<prefix> to exclude that result.
-	if envutil.EnvOrDefaultBool("COCKROACH_S3_LIST_WITH_PREFIX_SLASH_MARKER", false) {
-		s3Input = &s3.ListObjectsInput{Bucket: s.bucket, Prefix: aws.String(dest), Delimiter: nilIfEmpty(delim), Marker: aws.String(dest + "/")}
-	} else {
-		s3Input = &s3.ListObjectsInput{Bucket: s.bucket, Prefix: aws.String(dest), Delimiter: nilIfEmpty(delim)}
+	s3Input := &s3.ListObjectsInput{Bucket: s.bucket, Prefix: aws.String(dest), Delimiter: nilIfEmpty(delim)}
+	if startAfter != "" {
+		s3Input.Marker = aws.String(dest + startAfter)
+	} else if envutil.EnvOrDefaultBool("COCKROACH_S3_LIST_WITH_PREFIX_SLASH_MARKER", false) {
+		// Add an environment variable toggle for s3 storage to list prefixes
+		// with a paging marker that's the prefix with an additional /. This
+		// allows certain s3 clones which return s3://<prefix>/ as the first
+		// result of listing s3://<prefix> to exclude that result.
+		s3Input.Marker = aws.String(dest + "/")
 	}
 
 	if err := client.ListObjectsPagesWithContext(
@@ -786,11 +825,43 @@ func (s *s3Storage) Delete(ctx context.Context, basename string) error {
 		})
 }
 
-func (s *s3Storage) Size(ctx context.Context, basename string) (int64, error) {
+// Copy implements the ExternalStorage interface using CopyObject, which copies
+// the object within S3. A single CopyObject is limited to objects of 5 GiB,
+// which is well above the size of the files written by BACKUP.
+func (s *s3Storage) Copy(ctx context.Context, src, dst string) error {
 	client, err := s.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	source := path.Join(*s.bucket, s.prefix, src)
+	return contextutil.RunWithTimeout(ctx, "copy s3 object",
+		cloud.Timeout.Get(&s.settings.SV),
+		func(ctx context.Context) error {
+			_, err := client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+				Bucket:               s.bucket,
+				Key:                  aws.String(path.Join(s.prefix, dst)),
+				CopySource:           aws.String(url.PathEscape(source)),
+				ServerSideEncryption: nilIfEmpty(s.conf.ServerEncMode),
+				SSEKMSKeyId:          nilIfEmpty(s.conf.ServerKMSID),
+				StorageClass:         nilIfEmpty(s.conf.StorageClass),
+			})
+			return errors.Wrap(err, "failed to copy s3 object")
+		})
+}
+
+func (s *s3Storage) Size(ctx context.Context, basename string) (int64, error) {
+	out, err := s.headObject(ctx, basename)
 	if err != nil {
 		return 0, err
 	}
+	return *out.ContentLength, nil
+}
+
+func (s *s3Storage) headObject(ctx context.Context, basename string) (*s3.HeadObjectOutput, error) {
+	client, err := s.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var out *s3.HeadObjectOutput
 	err = contextutil.RunWithTimeout(ctx, "get s3 object header",
 		cloud.Timeout.Get(&s.settings.SV),
@@ -803,9 +874,103 @@ func (s *s3Storage) Size(ctx context.Context, basename string) (int64, error) {
 			return err
 		})
 	if err != nil {
-		return 0, errors.Wrap(err, "failed to get s3 object headers")
+		return nil, errors.Wrap(err, "failed to get s3 object headers")
 	}
-	return *out.ContentLength, nil
+	return out, nil
+}
+
+// s3RetrievalTimes are the typical times it takes S3 to restore an object in
+// each archive storage class with each retrieval tier. Deep Archive has no
+// expedited tier.
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/restoring-objects-retrieval-options.html
+var s3RetrievalTimes = map[string]map[string]time.Duration{
+	s3.StorageClassGlacier: {
+		s3.TierExpedited: 5 * time.Minute,
+		s3.TierStandard:  5 * time.Hour,
+		s3.TierBulk:      12 * time.Hour,
+	},
+	s3.StorageClassDeepArchive: {
+		s3.TierStandard: 12 * time.Hour,
+		s3.TierBulk:     48 * time.Hour,
+	},
+}
+
+// s3RestoredCopyDays is the number of days S3 keeps the readable copy of a
+// restored object before it is only available from its archive storage class
+// again. It only needs to outlast the job that requested the restore.
+const s3RestoredCopyDays = 7
+
+// RetrievalState implements the cloud.ArchiveStorage interface. The Restore
+// header of an object in an archive storage class is only set once a restore
+// of it was requested, and says whether that restore is still ongoing.
+func (s *s3Storage) RetrievalState(
+	ctx context.Context, basename string,
+) (cloud.RetrievalState, error) {
+	out, err := s.headObject(ctx, basename)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := s3RetrievalTimes[aws.StringValue(out.StorageClass)]; !ok {
+		return cloud.RetrievalReadable, nil
+	}
+	restore := aws.StringValue(out.Restore)
+	switch {
+	case restore == "":
+		return cloud.RetrievalArchived, nil
+	case strings.Contains(restore, `ongoing-request="true"`):
+		return cloud.RetrievalInProgress, nil
+	default:
+		return cloud.RetrievalReadable, nil
+	}
+}
+
+// RequestRetrieval implements the cloud.ArchiveStorage interface using
+// RestoreObject, which makes a temporary readable copy of the object.
+func (s *s3Storage) RequestRetrieval(ctx context.Context, basename string) (time.Duration, error) {
+	out, err := s.headObject(ctx, basename)
+	if err != nil {
+		return 0, err
+	}
+	times, ok := s3RetrievalTimes[aws.StringValue(out.StorageClass)]
+	if !ok {
+		return 0, nil
+	}
+	tier := s3.TierStandard
+	switch cloud.ArchiveRetrievalPriority.Get(&s.settings.SV) {
+	case cloud.ArchiveRetrievalBulk:
+		tier = s3.TierBulk
+	case cloud.ArchiveRetrievalExpedited:
+		tier = s3.TierExpedited
+	}
+	estimate, ok := times[tier]
+	if !ok {
+		tier, estimate = s3.TierStandard, times[s3.TierStandard]
+	}
+
+	client, err := s.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	err = contextutil.RunWithTimeout(ctx, "restore s3 object",
+		cloud.Timeout.Get(&s.settings.SV),
+		func(ctx context.Context) error {
+			_, err := client.RestoreObjectWithContext(ctx, &s3.RestoreObjectInput{
+				Bucket: s.bucket,
+				Key:    aws.String(path.Join(s.prefix, basename)),
+				RestoreRequest: &s3.RestoreRequest{
+					Days:                 aws.Int64(s3RestoredCopyDays),
+					GlacierJobParameters: &s3.GlacierJobParameters{Tier: aws.String(tier)},
+				},
+			})
+			return err
+		})
+	if aerr := (awserr.Error)(nil); errors.As(err, &aerr) && aerr.Code() == "RestoreAlreadyInProgress" {
+		return estimate, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to restore s3 object")
+	}
+	return estimate, nil
 }
 
 func (s *s3Storage) Close() error {
@@ -0,0 +1,137 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package azure
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+)
+
+// The environment variables the AKS workload identity webhook injects into
+// the pods that are allowed to authenticate as a workload identity.
+const (
+	workloadIdentityClientIDEnvVar      = "AZURE_CLIENT_ID"
+	workloadIdentityTenantIDEnvVar      = "AZURE_TENANT_ID"
+	workloadIdentityTokenFileEnvVar     = "AZURE_FEDERATED_TOKEN_FILE"
+	workloadIdentityAuthorityHostEnvVar = "AZURE_AUTHORITY_HOST"
+)
+
+const (
+	// tokenRefreshMargin is how long before its expiry a token is refreshed.
+	tokenRefreshMargin = 5 * time.Minute
+	// tokenRefreshRetryInterval is how long to wait before retrying a failed
+	// token refresh.
+	tokenRefreshRetryInterval = 10 * time.Second
+)
+
+// newImplicitTokenCredential returns a credential that authenticates to Azure
+// storage with OAuth tokens obtained for the identity of the node: the
+// federated workload identity if the node runs in an AKS pod set up for it,
+// and its managed identity otherwise. clientID, if non-empty, selects the
+// identity to use when there are several. The tokens are refreshed in the
+// background before they expire.
+func newImplicitTokenCredential(
+	ctx context.Context, env azure.Environment, clientID string,
+) (azblob.TokenCredential, error) {
+	spt, err := newImplicitServicePrincipalToken(env, clientID)
+	if err != nil {
+		return nil, err
+	}
+	spt.SetRefreshWithin(tokenRefreshMargin)
+	if err := spt.EnsureFreshWithContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "fetching token")
+	}
+	return azblob.NewTokenCredential(spt.OAuthToken(), func(credential azblob.TokenCredential) time.Duration {
+		// The refresher runs in the background, outside of any operation.
+		ctx := context.Background()
+		if err := spt.EnsureFreshWithContext(ctx); err != nil {
+			log.Warningf(ctx, "failed to refresh azure token: %v", err)
+			return tokenRefreshRetryInterval
+		}
+		credential.SetToken(spt.OAuthToken())
+		if wait := time.Until(spt.Token().Expires()) - tokenRefreshMargin; wait > tokenRefreshRetryInterval {
+			return wait
+		}
+		return tokenRefreshRetryInterval
+	}), nil
+}
+
+// newImplicitServicePrincipalToken returns a token source for Azure storage
+// for the workload identity of the node if its environment is set up for one,
+// or for its managed identity otherwise.
+func newImplicitServicePrincipalToken(
+	env azure.Environment, clientID string,
+) (*adal.ServicePrincipalToken, error) {
+	resource := env.ResourceIdentifiers.Storage
+	tokenFile := os.Getenv(workloadIdentityTokenFileEnvVar)
+	if tokenFile == "" {
+		spt, err := adal.NewServicePrincipalTokenFromManagedIdentity(
+			resource, &adal.ManagedIdentityOptions{ClientID: clientID})
+		return spt, errors.Wrap(err, "managed identity")
+	}
+
+	if clientID == "" {
+		clientID = os.Getenv(workloadIdentityClientIDEnvVar)
+	}
+	tenantID := os.Getenv(workloadIdentityTenantIDEnvVar)
+	if clientID == "" || tenantID == "" {
+		return nil, errors.Errorf("workload identity requires %s and %s to be set",
+			workloadIdentityClientIDEnvVar, workloadIdentityTenantIDEnvVar)
+	}
+	authority := env.ActiveDirectoryEndpoint
+	if host := os.Getenv(workloadIdentityAuthorityHostEnvVar); host != "" {
+		authority = host
+	}
+	oauthConfig, err := adal.NewOAuthConfig(authority, tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "workload identity")
+	}
+	spt, err := adal.NewServicePrincipalTokenWithSecret(
+		*oauthConfig, clientID, resource, &federatedTokenSecret{tokenFile: tokenFile})
+	return spt, errors.Wrap(err, "workload identity")
+}
+
+// federatedTokenSecret authenticates a service principal with the federated
+// token in tokenFile as a client assertion. The file is re-read whenever a
+// token is requested since it is rotated by the kubelet.
+type federatedTokenSecret struct {
+	tokenFile string
+}
+
+var _ adal.ServicePrincipalSecret = &federatedTokenSecret{}
+
+// SetAuthenticationValues is part of the adal.ServicePrincipalSecret
+// interface.
+func (s *federatedTokenSecret) SetAuthenticationValues(
+	_ *adal.ServicePrincipalToken, v *url.Values,
+) error {
+	token, err := os.ReadFile(s.tokenFile)
+	if err != nil {
+		return errors.Wrap(err, "reading federated token")
+	}
+	v.Set("client_assertion", strings.TrimSpace(string(token)))
+	v.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	return nil
+}
+
+// MarshalJSON is part of the adal.ServicePrincipalSecret interface.
+func (s *federatedTokenSecret) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("marshalling a federated token secret is not supported")
+}
@@ -16,7 +16,9 @@ import (
 	"io"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -39,6 +41,9 @@ const (
 	AzureAccountKeyParam = "AZURE_ACCOUNT_KEY"
 	// AzureEnvironmentKeyParam is the query parameter for the environment name in an azure URI.
 	AzureEnvironmentKeyParam = "AZURE_ENVIRONMENT"
+	// AzureClientIDParam is the query parameter for the client ID of the
+	// managed or workload identity to use for implicit auth in an azure URI.
+	AzureClientIDParam = "AZURE_CLIENT_ID"
 
 	scheme                   = "azure"
 	externalConnectionScheme = "azure-storage"
@@ -56,6 +61,8 @@ func parseAzureURL(
 		AccountName: azureURL.ConsumeParam(AzureAccountNameParam),
 		AccountKey:  azureURL.ConsumeParam(AzureAccountKeyParam),
 		Environment: azureURL.ConsumeParam(AzureEnvironmentKeyParam),
+		Auth:        azureURL.ConsumeParam(cloud.AuthParam),
+		ClientID:    azureURL.ConsumeParam(AzureClientIDParam),
 	}
 
 	// Validate that all the passed in parameters are supported.
@@ -67,8 +74,23 @@ func parseAzureURL(
 	if conf.AzureConfig.AccountName == "" {
 		return conf, errors.Errorf("azure uri missing %q parameter", AzureAccountNameParam)
 	}
-	if conf.AzureConfig.AccountKey == "" {
-		return conf, errors.Errorf("azure uri missing %q parameter", AzureAccountKeyParam)
+	switch conf.AzureConfig.Auth {
+	case "", cloud.AuthParamSpecified:
+		if conf.AzureConfig.AccountKey == "" {
+			return conf, errors.Errorf("azure uri missing %q parameter", AzureAccountKeyParam)
+		}
+		if conf.AzureConfig.ClientID != "" {
+			return conf, errors.Errorf("%s can only be used if %s is '%s'",
+				AzureClientIDParam, cloud.AuthParam, cloud.AuthParamImplicit)
+		}
+	case cloud.AuthParamImplicit:
+		if conf.AzureConfig.AccountKey != "" {
+			return conf, errors.Errorf("%s cannot be used if %s is '%s'",
+				AzureAccountKeyParam, cloud.AuthParam, cloud.AuthParamImplicit)
+		}
+	default:
+		return conf, errors.Errorf("unsupported value %s for %s",
+			conf.AzureConfig.Auth, cloud.AuthParam)
 	}
 	if conf.AzureConfig.Environment == "" {
 		// Default to AzurePublicCloud if not specified for backwards compatibility
@@ -84,26 +106,41 @@ type azureStorage struct {
 	container azblob.ContainerURL
 	prefix    string
 	settings  *cluster.Settings
+	upload    cloudpb.UploadOptions
 }
 
 var _ cloud.ExternalStorage = &azureStorage{}
+var _ cloud.ArchiveStorage = &azureStorage{}
 
 func makeAzureStorage(
-	_ context.Context, args cloud.ExternalStorageContext, dest cloudpb.ExternalStorage,
+	ctx context.Context, args cloud.ExternalStorageContext, dest cloudpb.ExternalStorage,
 ) (cloud.ExternalStorage, error) {
 	telemetry.Count("external-io.azure")
 	conf := dest.AzureConfig
 	if conf == nil {
 		return nil, errors.Errorf("azure upload requested but info missing")
 	}
-	credential, err := azblob.NewSharedKeyCredential(conf.AccountName, conf.AccountKey)
-	if err != nil {
-		return nil, errors.Wrap(err, "azure credential")
-	}
 	env, err := azure.EnvironmentFromName(conf.Environment)
 	if err != nil {
 		return nil, errors.Wrap(err, "azure environment")
 	}
+	var credential azblob.Credential
+	switch conf.Auth {
+	case cloud.AuthParamImplicit:
+		if args.IOConf.DisableImplicitCredentials {
+			return nil, errors.New(
+				"implicit credentials disallowed for azure due to --external-io-disable-implicit-credentials flag")
+		}
+		credential, err = newImplicitTokenCredential(ctx, env, conf.ClientID)
+		if err != nil {
+			return nil, errors.Wrap(err, "azure implicit credential")
+		}
+	default:
+		credential, err = azblob.NewSharedKeyCredential(conf.AccountName, conf.AccountKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "azure credential")
+		}
+	}
 	p := azblob.NewPipeline(credential, azblob.PipelineOptions{})
 	u, err := url.Parse(fmt.Sprintf("https://%s.blob.%s", conf.AccountName, env.StorageEndpointSuffix))
 	if err != nil {
@@ -116,6 +153,7 @@ func makeAzureStorage(
 		container: serviceURL.NewContainerURL(conf.Container),
 		prefix:    conf.Prefix,
 		settings:  args.Settings,
+		upload:    args.UploadOptions(),
 	}, nil
 }
 
@@ -146,11 +184,13 @@ func (s *azureStorage) Writer(ctx context.Context, basename string) (io.WriteClo
 	sp.RecordStructured(&types.StringValue{Value: fmt.Sprintf("azure.Writer: %s",
 		path.Join(s.prefix, basename))})
 	blob := s.getBlob(basename)
+	parallelism, partSize := s.upload.Resolve(1, cloud.WriteChunkSize.Get(&s.settings.SV))
 	return cloud.BackgroundPipe(ctx, func(ctx context.Context, r io.Reader) error {
 		defer sp.Finish()
 		_, err := azblob.UploadStreamToBlockBlob(
 			ctx, r, blob, azblob.UploadStreamToBlockBlobOptions{
-				BufferSize: int(cloud.WriteChunkSize.Get(&s.settings.SV)),
+				BufferSize: int(partSize),
+				MaxBuffers: parallelism,
 			},
 		)
 		return err
@@ -220,13 +260,18 @@ func (s *azureStorage) List(ctx context.Context, prefix, delim string, fn cloud.
 		if err != nil {
 			return errors.Wrap(err, "unable to list files for specified blob")
 		}
+		// A segment covers a contiguous range of the blobs, but lists the
+		// prefixes in it before the blobs, so they are merged back in order.
+		names := make([]string, 0, len(response.Segment.BlobPrefixes)+len(response.Segment.BlobItems))
 		for _, blob := range response.Segment.BlobPrefixes {
-			if err := fn(strings.TrimPrefix(blob.Name, dest)); err != nil {
-				return err
-			}
+			names = append(names, strings.TrimPrefix(blob.Name, dest))
 		}
 		for _, blob := range response.Segment.BlobItems {
-			if err := fn(strings.TrimPrefix(blob.Name, dest)); err != nil {
+			names = append(names, strings.TrimPrefix(blob.Name, dest))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := fn(name); err != nil {
 				return err
 			}
 		}
@@ -235,6 +280,19 @@ func (s *azureStorage) List(ctx context.Context, prefix, delim string, fn cloud.
 	return nil
 }
 
+// ListWithOptions implements the ExternalStorage interface. Azure lists blobs
+// in ascending order, so only reverse listings have to be buffered.
+func (s *azureStorage) ListWithOptions(
+	ctx context.Context, prefix string, opts cloud.ListOptions, fn cloud.ListingFn,
+) error {
+	if opts.Reverse {
+		return cloud.ListByBuffering(ctx, s, prefix, opts, fn)
+	}
+	return cloud.ListOrdered(opts, fn, func(fn cloud.ListingFn) error {
+		return s.List(ctx, prefix, opts.Delimiter, fn)
+	})
+}
+
 func (s *azureStorage) Delete(ctx context.Context, basename string) error {
 	err := contextutil.RunWithTimeout(ctx, "delete azure file", cloud.Timeout.Get(&s.settings.SV),
 		func(ctx context.Context) error {
@@ -245,9 +303,23 @@ func (s *azureStorage) Delete(ctx context.Context, basename string) error {
 	return errors.Wrap(err, "delete file")
 }
 
+func (s *azureStorage) Copy(ctx context.Context, src, dst string) error {
+	return cloud.CopyFileByStreaming(ctx, s, src, dst)
+}
+
 func (s *azureStorage) Size(ctx context.Context, basename string) (int64, error) {
+	props, err := s.getProperties(ctx, basename)
+	if err != nil {
+		return 0, err
+	}
+	return props.ContentLength(), nil
+}
+
+func (s *azureStorage) getProperties(
+	ctx context.Context, basename string,
+) (*azblob.BlobGetPropertiesResponse, error) {
 	var props *azblob.BlobGetPropertiesResponse
-	err := contextutil.RunWithTimeout(ctx, "size azure file", cloud.Timeout.Get(&s.settings.SV),
+	err := contextutil.RunWithTimeout(ctx, "get azure file properties", cloud.Timeout.Get(&s.settings.SV),
 		func(ctx context.Context) error {
 			blob := s.getBlob(basename)
 			var err error
@@ -255,9 +327,54 @@ func (s *azureStorage) Size(ctx context.Context, basename string) (int64, error)
 			return err
 		})
 	if err != nil {
-		return 0, errors.Wrap(err, "get file properties")
+		return nil, errors.Wrap(err, "get file properties")
 	}
-	return props.ContentLength(), nil
+	return props, nil
+}
+
+// azureRehydrationTime is the time it can take Azure to rehydrate a blob from
+// the Archive tier with standard priority. The SDK does not expose the high
+// rehydration priority, so cloudstorage.archive_retrieval.priority does not
+// apply to Azure.
+// See https://learn.microsoft.com/en-us/azure/storage/blobs/archive-rehydrate-overview
+const azureRehydrationTime = 15 * time.Hour
+
+// RetrievalState implements the cloud.ArchiveStorage interface.
+func (s *azureStorage) RetrievalState(
+	ctx context.Context, basename string,
+) (cloud.RetrievalState, error) {
+	props, err := s.getProperties(ctx, basename)
+	if err != nil {
+		return 0, err
+	}
+	if azblob.AccessTierType(props.AccessTier()) != azblob.AccessTierArchive {
+		return cloud.RetrievalReadable, nil
+	}
+	switch azblob.ArchiveStatusType(props.ArchiveStatus()) {
+	case azblob.ArchiveStatusRehydratePendingToHot, azblob.ArchiveStatusRehydratePendingToCool:
+		return cloud.RetrievalInProgress, nil
+	default:
+		return cloud.RetrievalArchived, nil
+	}
+}
+
+// RequestRetrieval implements the cloud.ArchiveStorage interface by
+// rehydrating the blob to the Hot tier.
+func (s *azureStorage) RequestRetrieval(ctx context.Context, basename string) (time.Duration, error) {
+	err := contextutil.RunWithTimeout(ctx, "rehydrate azure file", cloud.Timeout.Get(&s.settings.SV),
+		func(ctx context.Context) error {
+			blob := s.getBlob(basename)
+			_, err := blob.SetTier(ctx, azblob.AccessTierHot, azblob.LeaseAccessConditions{})
+			return err
+		})
+	if azerr := (azblob.StorageError)(nil); errors.As(err, &azerr) &&
+		azerr.ServiceCode() == azblob.ServiceCodeBlobBeingRehydrated {
+		return azureRehydrationTime, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "rehydrate file")
+	}
+	return azureRehydrationTime, nil
 }
 
 // Close is part of the cloud.ExternalStorage interface.
@@ -16,9 +16,11 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudtestutils"
@@ -118,6 +120,85 @@ func TestParseAzureURL(t *testing.T) {
 
 		require.Equal(t, azure.USGovernmentCloud.Name, sut.AzureConfig.Environment)
 	})
+
+	t.Run("Implicit auth does not require AZURE_ACCOUNT_KEY", func(t *testing.T) {
+		u, err := url.Parse("azure://container/path?AZURE_ACCOUNT_NAME=account&AUTH=implicit&AZURE_CLIENT_ID=client")
+		require.NoError(t, err)
+
+		sut, err := parseAzureURL(cloud.ExternalStorageURIContext{}, u)
+		require.NoError(t, err)
+
+		require.Equal(t, cloud.AuthParamImplicit, sut.AzureConfig.Auth)
+		require.Equal(t, "client", sut.AzureConfig.ClientID)
+		require.False(t, sut.AccessIsWithExplicitAuth())
+	})
+
+	for _, tc := range []struct {
+		name, uri, err string
+	}{
+		{
+			name: "Specified auth requires AZURE_ACCOUNT_KEY",
+			uri:  "azure://container/path?AZURE_ACCOUNT_NAME=account&AUTH=specified",
+			err:  `azure uri missing "AZURE_ACCOUNT_KEY" parameter`,
+		},
+		{
+			name: "AZURE_CLIENT_ID requires implicit auth",
+			uri:  "azure://container/path?AZURE_ACCOUNT_NAME=account&AZURE_ACCOUNT_KEY=key&AZURE_CLIENT_ID=client",
+			err:  "AZURE_CLIENT_ID can only be used if AUTH is 'implicit'",
+		},
+		{
+			name: "Implicit auth rejects AZURE_ACCOUNT_KEY",
+			uri:  "azure://container/path?AZURE_ACCOUNT_NAME=account&AZURE_ACCOUNT_KEY=key&AUTH=implicit",
+			err:  "AZURE_ACCOUNT_KEY cannot be used if AUTH is 'implicit'",
+		},
+		{
+			name: "Unknown auth",
+			uri:  "azure://container/path?AZURE_ACCOUNT_NAME=account&AUTH=magic",
+			err:  "unsupported value magic for AUTH",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.uri)
+			require.NoError(t, err)
+
+			_, err = parseAzureURL(cloud.ExternalStorageURIContext{}, u)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestAzureImplicitAuthDisallowed(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	_, err := makeAzureStorage(context.Background(), cloud.ExternalStorageContext{
+		IOConf: base.ExternalIODirConfig{DisableImplicitCredentials: true},
+	}, cloudpb.ExternalStorage{
+		AzureConfig: &cloudpb.ExternalStorage_Azure{
+			Container:   "container",
+			AccountName: "account",
+			Environment: azure.PublicCloud.Name,
+			Auth:        cloud.AuthParamImplicit,
+		},
+	})
+	require.ErrorContains(t, err, "implicit credentials disallowed for azure")
+}
+
+func TestFederatedTokenSecret(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("first\n"), 0600))
+	s := &federatedTokenSecret{tokenFile: tokenFile}
+
+	v := url.Values{}
+	require.NoError(t, s.SetAuthenticationValues(nil, &v))
+	require.Equal(t, "first", v.Get("client_assertion"))
+	require.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", v.Get("client_assertion_type"))
+
+	// The token is re-read as it is rotated.
+	require.NoError(t, os.WriteFile(tokenFile, []byte("second"), 0600))
+	require.NoError(t, s.SetAuthenticationValues(nil, &v))
+	require.Equal(t, "second", v.Get("client_assertion"))
 }
 
 func TestMakeAzureStorageURLFromEnvironment(t *testing.T) {
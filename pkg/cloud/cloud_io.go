@@ -17,11 +17,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
@@ -58,6 +62,28 @@ var WriteChunkSize = settings.RegisterByteSizeSetting(
 	8<<20,
 )
 
+// ArchiveRetrievalPriority is the priority of the requests made by
+// ArchiveStorage.RequestRetrieval.
+var ArchiveRetrievalPriority = settings.RegisterEnumSetting(
+	settings.TenantWritable,
+	"cloudstorage.archive_retrieval.priority",
+	"the priority of requests to retrieve files from an archive storage tier, which trades the cost "+
+		"of a retrieval for its speed (providers without a matching priority use the closest one)",
+	"standard",
+	map[int64]string{
+		int64(ArchiveRetrievalBulk):      "bulk",
+		int64(ArchiveRetrievalStandard):  "standard",
+		int64(ArchiveRetrievalExpedited): "expedited",
+	},
+).WithPublic()
+
+// The values of ArchiveRetrievalPriority.
+const (
+	ArchiveRetrievalBulk = iota
+	ArchiveRetrievalStandard
+	ArchiveRetrievalExpedited
+)
+
 // HTTPRetryOptions defines the tunable settings which control the retry of HTTP
 // operations.
 var HTTPRetryOptions = retry.Options{
@@ -360,3 +386,157 @@ func WriteFile(ctx context.Context, dest ExternalStorage, basename string, src i
 	}
 	return errors.Wrap(w.Close(), "closing object")
 }
+
+// CopyFileByStreaming copies src to dst within an ExternalStorage by reading
+// src through this node and writing it back out. It is used to implement
+// Copy by stores that cannot copy files server-side.
+func CopyFileByStreaming(ctx context.Context, s ExternalStorage, src, dst string) error {
+	return copyFileBetween(ctx, s, src, s, dst)
+}
+
+// errListedMaxResults stops a listing once ListOptions.MaxResults results were
+// listed.
+var errListedMaxResults = errors.New("listed the maximum number of results")
+
+// ListOrdered applies the bounds of opts to a listing, run by list, that passes
+// its results to the function it is given in the order opts asks for. It stops
+// the listing as soon as opts.MaxResults results were passed to fn. It is used
+// to implement ListWithOptions by stores whose provider lists in order.
+func ListOrdered(opts ListOptions, fn ListingFn, list func(ListingFn) error) error {
+	var n int
+	err := list(func(name string) error {
+		if opts.StartAfter != "" {
+			if opts.Reverse && name >= opts.StartAfter || !opts.Reverse && name <= opts.StartAfter {
+				return nil
+			}
+		}
+		if err := fn(name); err != nil {
+			return err
+		}
+		n++
+		if opts.MaxResults > 0 && n >= opts.MaxResults {
+			return errListedMaxResults
+		}
+		return nil
+	})
+	if errors.Is(err, errListedMaxResults) {
+		return nil
+	}
+	return err
+}
+
+// ListByBuffering implements ListWithOptions for a store whose provider cannot
+// list in the order opts asks for, by listing all of prefix and sorting the
+// results before passing them to fn.
+func ListByBuffering(
+	ctx context.Context, s ExternalStorage, prefix string, opts ListOptions, fn ListingFn,
+) error {
+	var names []string
+	if err := s.List(ctx, prefix, opts.Delimiter, func(name string) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if opts.Reverse {
+		sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	} else {
+		sort.Strings(names)
+	}
+	return ListOrdered(opts, fn, func(fn ListingFn) error {
+		for _, name := range names {
+			if err := fn(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CopyFile copies srcName in src to dstName in dst. If both stores are in the
+// same bucket of a provider that copies objects server-side, and access it with
+// the same credentials, the copy is delegated to the Copy method of dst, with
+// srcName resolved against the prefix of src, so that the bytes are not pulled
+// through this node even if the stores have different prefixes, such as two
+// collections in the same bucket. Otherwise the file is streamed from src to
+// dst.
+func CopyFile(
+	ctx context.Context, src ExternalStorage, srcName string, dst ExternalStorage, dstName string,
+) error {
+	if source, ok := copySourceInBucket(src.Conf(), srcName, dst.Conf()); ok {
+		return dst.Copy(ctx, source, dstName)
+	}
+	return copyFileBetween(ctx, src, srcName, dst, dstName)
+}
+
+// copySourceInBucket returns the path, relative to the prefix of the store
+// configured by dst, of srcName in the store configured by src, if both stores
+// are in the same bucket of a provider that copies objects server-side, and
+// access it with the same credentials. Only the provider, bucket and
+// credentials of the stores are compared: the other parameters of dst, such as
+// its storage class, apply to the copy as they apply to any file it writes.
+func copySourceInBucket(
+	src cloudpb.ExternalStorage, srcName string, dst cloudpb.ExternalStorage,
+) (string, bool) {
+	if src.Provider != dst.Provider {
+		return "", false
+	}
+	var srcPrefix, dstPrefix string
+	switch src.Provider {
+	case cloudpb.ExternalStorageProvider_s3:
+		s, d := src.S3Config, dst.S3Config
+		if s == nil || d == nil || s.Bucket != d.Bucket || s.Endpoint != d.Endpoint ||
+			s.Region != d.Region || s.Auth != d.Auth || s.AccessKey != d.AccessKey ||
+			s.Secret != d.Secret || s.TempToken != d.TempToken || s.RoleARN != d.RoleARN ||
+			!reflect.DeepEqual(s.DelegateRoleARNs, d.DelegateRoleARNs) {
+			return "", false
+		}
+		srcPrefix, dstPrefix = s.Prefix, d.Prefix
+	case cloudpb.ExternalStorageProvider_gs:
+		s, d := src.GoogleCloudConfig, dst.GoogleCloudConfig
+		if s == nil || d == nil || s.Bucket != d.Bucket || s.Auth != d.Auth ||
+			s.Credentials != d.Credentials || s.BearerToken != d.BearerToken ||
+			s.AssumeRole != d.AssumeRole ||
+			!reflect.DeepEqual(s.AssumeRoleDelegates, d.AssumeRoleDelegates) {
+			return "", false
+		}
+		srcPrefix, dstPrefix = s.Prefix, d.Prefix
+	default:
+		return "", false
+	}
+	return relativePath(dstPrefix, path.Join(srcPrefix, srcName)), true
+}
+
+// relativePath returns the path of target relative to base, both relative to
+// the root of a bucket, such that path.Join(base, relativePath(base, target))
+// is target.
+func relativePath(base, target string) string {
+	split := func(p string) []string {
+		p = strings.Trim(path.Clean("/"+p), "/")
+		if p == "" {
+			return nil
+		}
+		return strings.Split(p, "/")
+	}
+	b, t := split(base), split(target)
+	i := 0
+	for i < len(b) && i < len(t) && b[i] == t[i] {
+		i++
+	}
+	elems := make([]string, 0, len(b)-i+len(t)-i)
+	for range b[i:] {
+		elems = append(elems, "..")
+	}
+	return path.Join(append(elems, t[i:]...)...)
+}
+
+func copyFileBetween(
+	ctx context.Context, src ExternalStorage, srcName string, dst ExternalStorage, dstName string,
+) error {
+	r, err := src.ReadFile(ctx, srcName)
+	if err != nil {
+		return errors.Wrapf(err, "opening %s for copy", srcName)
+	}
+	defer r.Close(ctx)
+	return WriteFile(ctx, dst, dstName, ioctx.ReaderCtxAdapter(ctx, r))
+}
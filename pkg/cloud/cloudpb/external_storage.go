@@ -36,8 +36,7 @@ func (m *ExternalStorage) AccessIsWithExplicitAuth() bool {
 	case ExternalStorageProvider_gs:
 		return m.GoogleCloudConfig.Auth == ExternalStorageAuthSpecified
 	case ExternalStorageProvider_azure:
-		// Azure storage only uses explicitly supplied credentials.
-		return true
+		return m.AzureConfig.Auth != ExternalStorageAuthImplicit
 	case ExternalStorageProvider_userfile:
 		// userfile always checks the user performing the action has grants on the
 		// table used.
@@ -55,7 +54,36 @@ func (m *ExternalStorage) AccessIsWithExplicitAuth() bool {
 		// External Connections have a `USAGE` privilege that determines if a user
 		// has the appropriate privileges to use the underlying resource.
 		return true
+	case ExternalStorageProvider_plugin:
+		// Plugins access their storage with credentials of their own, which any
+		// user of the node could otherwise borrow.
+		return false
 	default:
 		return false
 	}
 }
+
+// Resolve returns the parallelism and part size an upload should use, taking
+// the provider's defaults for those that are unset. If BufferMemory is set,
+// the part size and then the parallelism are reduced so that the parts being
+// uploaded at once fit within it.
+func (m UploadOptions) Resolve(
+	defaultParallelism int, defaultPartSize int64,
+) (parallelism int, partSize int64) {
+	parallelism, partSize = int(m.Parallelism), m.PartSize
+	if parallelism <= 0 {
+		parallelism = defaultParallelism
+	}
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+	if m.BufferMemory > 0 && partSize > 0 {
+		if partSize > m.BufferMemory {
+			partSize = m.BufferMemory
+		}
+		if max := int(m.BufferMemory / partSize); parallelism > max {
+			parallelism = max
+		}
+	}
+	return parallelism, partSize
+}
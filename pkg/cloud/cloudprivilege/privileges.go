@@ -26,6 +26,20 @@ import (
 // CheckDestinationPrivileges iterates over the External Storage URIs and
 // ensures the user has adequate privileges to use each of them.
 func CheckDestinationPrivileges(ctx context.Context, p sql.PlanHookState, to []string) error {
+	return checkPrivileges(ctx, p, to, false /* backupSource */)
+}
+
+// CheckBackupSourcePrivileges is like CheckDestinationPrivileges, for URIs
+// that backups are only read from, such as by RESTORE and SHOW BACKUP. The
+// BACKUPFROM privilege on an External Connection then suffices in place of
+// USAGE, which also allows writing to it.
+func CheckBackupSourcePrivileges(ctx context.Context, p sql.PlanHookState, from []string) error {
+	return checkPrivileges(ctx, p, from, true /* backupSource */)
+}
+
+func checkPrivileges(
+	ctx context.Context, p sql.PlanHookState, uris []string, backupSource bool,
+) error {
 	isAdmin, err := p.UserHasAdminRole(ctx, p.User())
 	if err != nil {
 		return err
@@ -35,7 +49,7 @@ func CheckDestinationPrivileges(ctx context.Context, p sql.PlanHookState, to []s
 	}
 
 	// Check destination specific privileges.
-	for _, uri := range to {
+	for _, uri := range uris {
 		conf, err := cloud.ExternalStorageConfFromURI(uri, p.User())
 		if err != nil {
 			return err
@@ -65,7 +79,11 @@ func CheckDestinationPrivileges(ctx context.Context, p sql.PlanHookState, to []s
 				ConnectionName: conf.ExternalConnectionConfig.Name,
 			}
 			if err := p.CheckPrivilege(ctx, ecPrivilege, privilege.USAGE); err != nil {
-				return err
+				if !backupSource ||
+					!p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.V23_1FineGrainedRestorePrivileges) ||
+					p.CheckPrivilege(ctx, ecPrivilege, privilege.BACKUPFROM) != nil {
+					return err
+				}
 			}
 		}
 	}
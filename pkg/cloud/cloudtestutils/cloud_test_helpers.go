@@ -194,6 +194,50 @@ func CheckExportStore(
 		}
 	})
 
+	t.Run("copy", func(t *testing.T) {
+		const src, dst = "copy-src", "copy-dst"
+		payload := randutil.RandBytes(rng, 1024)
+		require.NoError(t, cloud.WriteFile(ctx, s, src, bytes.NewReader(payload)))
+		require.NoError(t, s.Copy(ctx, src, dst))
+
+		r, err := s.ReadFile(ctx, dst)
+		require.NoError(t, err)
+		defer r.Close(ctx)
+		res, err := ioctx.ReadAll(ctx, r)
+		require.NoError(t, err)
+		require.Equal(t, payload, res)
+
+		// The source is left in place.
+		sz, err := s.Size(ctx, src)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(payload)), sz)
+
+		require.NoError(t, s.Delete(ctx, src))
+		require.NoError(t, s.Delete(ctx, dst))
+	})
+
+	t.Run("copy-between-prefixes", func(t *testing.T) {
+		// The source is resolved against the prefix of its own store, whether
+		// the copy is done server-side or streamed.
+		const name = "copy-between-prefixes"
+		other := storeFromURI(ctx, t, appendPath(t, storeURI, "other-prefix"), clientFactory,
+			user, ie, ief, kvDB, testSettings)
+		defer other.Close()
+		payload := randutil.RandBytes(rng, 1024)
+		require.NoError(t, cloud.WriteFile(ctx, s, name, bytes.NewReader(payload)))
+		require.NoError(t, cloud.CopyFile(ctx, s, name, other, name))
+
+		r, err := other.ReadFile(ctx, name)
+		require.NoError(t, err)
+		defer r.Close(ctx)
+		res, err := ioctx.ReadAll(ctx, r)
+		require.NoError(t, err)
+		require.Equal(t, payload, res)
+
+		require.NoError(t, s.Delete(ctx, name))
+		require.NoError(t, other.Delete(ctx, name))
+	})
+
 	// The azure driver makes us chunk files that are greater than 4mb, so make
 	// sure that files larger than that work on all the providers.
 	t.Run("exceeds-4mb-chunk", func(t *testing.T) {
@@ -479,6 +523,81 @@ func CheckListFilesCanonical(
 		}
 	})
 
+	t.Run("ListWithOptions", func(t *testing.T) {
+		reversed := func(in []string) []string {
+			out := make([]string, len(in))
+			for i := range in {
+				out[len(in)-1-i] = in[i]
+			}
+			return out
+		}
+		for _, tc := range []struct {
+			name     string
+			prefix   string
+			opts     cloud.ListOptions
+			expected []string
+		}{
+			{
+				"ascending",
+				"file/",
+				cloud.ListOptions{},
+				foreach(fileNames, func(s string) string { return strings.TrimPrefix(s, "file/") }),
+			},
+			{
+				"descending",
+				"file/",
+				cloud.ListOptions{Reverse: true},
+				reversed(foreach(fileNames, func(s string) string { return strings.TrimPrefix(s, "file/") })),
+			},
+			{
+				"max-results",
+				"file/",
+				cloud.ListOptions{MaxResults: 2},
+				[]string{"abc/A.csv", "abc/B.csv"},
+			},
+			{
+				"descending-max-results",
+				"file/",
+				cloud.ListOptions{Reverse: true, MaxResults: 1},
+				[]string{"numbers/data3.csv"},
+			},
+			{
+				"start-after",
+				"file/",
+				cloud.ListOptions{StartAfter: "letters/dataC.csv"},
+				[]string{"numbers/data1.csv", "numbers/data2.csv", "numbers/data3.csv"},
+			},
+			{
+				"descending-start-after",
+				"file/",
+				cloud.ListOptions{Reverse: true, StartAfter: "abc/C.csv", MaxResults: 2},
+				[]string{"abc/B.csv", "abc/A.csv"},
+			},
+			{
+				"delimiter",
+				"file/",
+				cloud.ListOptions{Delimiter: "/", Reverse: true},
+				[]string{"numbers/", "letters/", "abc/"},
+			},
+			{
+				"delimiter-start-after",
+				"file/",
+				cloud.ListOptions{Delimiter: "/", StartAfter: "abc/"},
+				[]string{"letters/", "numbers/"},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				s := storeFromURI(ctx, t, storeURI, clientFactory, user, ie, ief, kvDB, testSettings)
+				var actual []string
+				require.NoError(t, s.ListWithOptions(ctx, tc.prefix, tc.opts, func(f string) error {
+					actual = append(actual, f)
+					return nil
+				}))
+				require.Equal(t, tc.expected, actual)
+			})
+		}
+	})
+
 	for _, fileName := range fileNames {
 		file := storeFromURI(ctx, t, storeURI, clientFactory, user, ie, ief, kvDB, testSettings)
 		if err := file.Delete(ctx, fileName); err != nil {
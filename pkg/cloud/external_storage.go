@@ -15,6 +15,7 @@ import (
 	"database/sql/driver"
 	"io"
 	"net/url"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/blobs"
@@ -24,6 +25,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
 	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
 	"github.com/cockroachdb/errors"
 )
 
@@ -89,16 +91,83 @@ type ExternalStorage interface {
 	// passed to the callback is undefined.
 	List(ctx context.Context, prefix, delimiter string, fn ListingFn) error
 
+	// ListWithOptions is like List, except that it passes the results to fn in
+	// lexicographic order, ascending unless opts.Reverse is set, and only those
+	// within the bounds set by opts. Callers that only need the first results
+	// in that order can set opts.MaxResults to stop listing once they were
+	// found. Implementations whose provider cannot list in the requested order
+	// emulate it with ListByBuffering, which lists all of prefix.
+	ListWithOptions(ctx context.Context, prefix string, opts ListOptions, fn ListingFn) error
+
 	// Delete removes the named file from the store.
 	Delete(ctx context.Context, basename string) error
 
+	// Copy copies the file src to dst within the store, replacing dst if it
+	// exists. Implementations whose provider can copy objects server-side do so,
+	// so that the bytes do not flow through this node; the others fall back to
+	// CopyFileByStreaming.
+	Copy(ctx context.Context, src, dst string) error
+
 	// Size returns the length of the named file in bytes.
 	Size(ctx context.Context, basename string) (int64, error)
 }
 
+// ArchiveStorage is implemented by the ExternalStorage implementations whose
+// provider can keep files in an archive tier, such as S3 Glacier Deep Archive
+// or the Azure Archive tier, from which a file has to be retrieved before it
+// can be read. Use AsArchiveStorage to get it from an ExternalStorage.
+type ArchiveStorage interface {
+	// RetrievalState returns whether the named file is readable, archived, or
+	// being retrieved from the archive.
+	RetrievalState(ctx context.Context, basename string) (RetrievalState, error)
+
+	// RequestRetrieval asks the provider to make the named archived file
+	// readable, with the priority set by ArchiveRetrievalPriority, and returns
+	// an estimate of how long the retrieval takes. Requesting the retrieval of
+	// a file that is already being retrieved is not an error.
+	RequestRetrieval(ctx context.Context, basename string) (time.Duration, error)
+}
+
+// ListingStorage is implemented by the ExternalStorage implementations that
+// only know whether their provider can list files once they are connected to
+// it. Use SupportsListing to ask any ExternalStorage.
+type ListingStorage interface {
+	// SupportsListing returns whether List and ListWithOptions can be used.
+	SupportsListing() bool
+}
+
+// RetrievalState is the state of a file in an ArchiveStorage.
+type RetrievalState int
+
+const (
+	// RetrievalReadable is the state of a file that can be read, either because
+	// it is not archived or because it was retrieved from the archive.
+	RetrievalReadable RetrievalState = iota
+	// RetrievalArchived is the state of an archived file whose retrieval has not
+	// been requested.
+	RetrievalArchived
+	// RetrievalInProgress is the state of an archived file that is being
+	// retrieved.
+	RetrievalInProgress
+)
+
 // ListingFn describes functions passed to ExternalStorage.ListFiles.
 type ListingFn func(string) error
 
+// ListOptions configures ExternalStorage.ListWithOptions.
+type ListOptions struct {
+	// Delimiter groups the names that share a prefix, as in
+	// ExternalStorage.List.
+	Delimiter string
+	// Reverse lists the results in descending rather than ascending order.
+	Reverse bool
+	// StartAfter, if set, skips the results that do not come strictly after it
+	// in the order of the listing.
+	StartAfter string
+	// MaxResults, if positive, stops the listing after that many results.
+	MaxResults int
+}
+
 // ExternalStorageFactory describes a factory function for ExternalStorage.
 type ExternalStorageFactory func(ctx context.Context, dest cloudpb.ExternalStorage, opts ...ExternalStorageOption) (ExternalStorage, error)
 
@@ -164,6 +233,10 @@ type ExternalStorageContext struct {
 // ExternalStorageOption.
 type ExternalStorageOptions struct {
 	ioAccountingInterceptor ReadWriterInterceptor
+	accessAuditor           AccessAuditor
+	uploadOptions           cloudpb.UploadOptions
+	writeLimiter            *quotapool.RateLimiter
+	faultInjector           *FaultInjector
 }
 
 // ExternalStorageConstructor is a function registered to create instances
@@ -0,0 +1,59 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package externalconn
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+)
+
+// CapabilitiesOption is the CREATE EXTERNAL CONNECTION option that limits the
+// operations the connection can be used for.
+const CapabilitiesOption = "capabilities"
+
+// CheckCapability returns an error if uri refers to an External Connection
+// which can't be used for the operations of capability. op names the operation
+// in the error. Operations that none of the capabilities cover, such as IMPORT
+// and EXPORT, pass CapabilityUnspecified, which only connections created
+// without capabilities have.
+func CheckCapability(
+	ctx context.Context,
+	ie sqlutil.InternalExecutor,
+	txn *kv.Txn,
+	uri string,
+	op string,
+	capability connectionpb.ConnectionCapability,
+) error {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != scheme {
+		return nil
+	}
+	ec, err := LoadExternalConnection(ctx, parsed.Host, ie, txn)
+	if err != nil {
+		return err
+	}
+	details := ec.ConnectionProto()
+	if details.HasCapability(capability) {
+		return nil
+	}
+	return pgerror.Newf(pgcode.InsufficientPrivilege,
+		"External Connection %q cannot be used for %s as its capabilities are limited to %s",
+		parsed.Host, op, connectionpb.FormatCapabilities(details.Capabilities))
+}
@@ -10,7 +10,11 @@
 
 package connectionpb
 
-import "github.com/cockroachdb/errors"
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
 
 // Type returns the ConnectionType of the receiver.
 func (d *ConnectionDetails) Type() ConnectionType {
@@ -37,3 +41,63 @@ func (d *ConnectionDetails) UnredactedURI() string {
 		panic(errors.AssertionFailedf("ConnectionDetails.UnredactedURI called on details with an unknown type: %s", d.Provider.String()))
 	}
 }
+
+// capabilityNames are the names the capabilities of an External Connection
+// are specified with in CREATE EXTERNAL CONNECTION.
+var capabilityNames = map[ConnectionCapability]string{
+	CapabilityBackupWrite: "backup-write",
+	CapabilityRestoreRead: "restore-read",
+	CapabilityList:        "list",
+}
+
+// SQLName returns the name the capability is specified with.
+func (c ConnectionCapability) SQLName() string {
+	if name, ok := capabilityNames[c]; ok {
+		return name
+	}
+	return c.String()
+}
+
+// ParseCapabilities parses a comma-separated list of capability names.
+func ParseCapabilities(s string) ([]ConnectionCapability, error) {
+	var capabilities []ConnectionCapability
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		var found bool
+		for c, cName := range capabilityNames {
+			if cName == name {
+				capabilities = append(capabilities, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Newf("unknown External Connection capability %q; "+
+				"valid capabilities are backup-write, restore-read and list", name)
+		}
+	}
+	return capabilities, nil
+}
+
+// FormatCapabilities formats capabilities as ParseCapabilities parses them.
+func FormatCapabilities(capabilities []ConnectionCapability) string {
+	names := make([]string, len(capabilities))
+	for i, c := range capabilities {
+		names[i] = c.SQLName()
+	}
+	return strings.Join(names, ",")
+}
+
+// HasCapability returns whether the External Connection can be used for the
+// operations of capability.
+func (d *ConnectionDetails) HasCapability(capability ConnectionCapability) bool {
+	if len(d.Capabilities) == 0 {
+		return true
+	}
+	for _, c := range d.Capabilities {
+		if c == capability && c != CapabilityUnspecified {
+			return true
+		}
+	}
+	return false
+}
@@ -165,6 +165,12 @@ func (e *MutableExternalConnection) UnredactedConnectionStatement() string {
 		},
 		As: tree.NewDString(e.rec.ConnectionDetails.UnredactedURI()),
 	}
+	if capabilities := e.rec.ConnectionDetails.Capabilities; len(capabilities) > 0 {
+		ecNode.Options = tree.KVOptions{{
+			Key:   CapabilitiesOption,
+			Value: tree.NewDString(connectionpb.FormatCapabilities(capabilities)),
+		}}
+	}
 	return tree.AsString(ecNode)
 }
 
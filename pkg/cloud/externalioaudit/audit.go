@@ -0,0 +1,301 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// Package externalioaudit records the accesses to external storage made on
+// behalf of principals, such as by the jobs they run, in the
+// system.external_io_audit table.
+package externalioaudit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// Enabled controls whether accesses to external storage are recorded.
+var Enabled = settings.RegisterBoolSetting(
+	settings.TenantWritable,
+	"cloud.external_io_audit.enabled",
+	"if enabled, accesses to external storage made by jobs and on behalf of users "+
+		"are recorded in system.external_io_audit",
+	false,
+).WithPublic()
+
+// Retention is how long recorded accesses are kept.
+var Retention = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"cloud.external_io_audit.retention",
+	"how long the accesses recorded in system.external_io_audit are kept; 0 keeps them indefinitely",
+	7*24*time.Hour,
+	settings.NonNegativeDuration,
+).WithPublic()
+
+// FlushInterval is how often an Auditor writes the accesses it buffered.
+var FlushInterval = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"cloud.external_io_audit.flush_interval",
+	"how often the accesses to external storage buffered by a node are written to "+
+		"system.external_io_audit",
+	10*time.Second,
+	settings.PositiveDuration,
+)
+
+// flushBatchSize is the maximum number of accesses written at a time. An
+// Auditor flushes as soon as it buffered that many.
+const flushBatchSize = 100
+
+// maxBufferedAccesses is the maximum number of accesses an Auditor buffers.
+// Accesses recorded while its buffer is full, such as while the table cannot
+// be written to, are dropped.
+const maxBufferedAccesses = 10000
+
+// cleanupInterval is how often an Auditor deletes the accesses that are past
+// their retention.
+const cleanupInterval = 10 * time.Minute
+
+// cleanupBatchSize is the maximum number of accesses deleted at a time.
+const cleanupBatchSize = 10000
+
+type principalKey struct{}
+
+type principal struct {
+	user  username.SQLUsername
+	jobID jobspb.JobID
+}
+
+// WithPrincipal returns a context in which the accesses to external storage
+// are attributed to user and, if it is non-zero, to the job jobID. Accesses
+// made in a context without a principal are not recorded.
+func WithPrincipal(ctx context.Context, user username.SQLUsername, jobID jobspb.JobID) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal{user: user, jobID: jobID})
+}
+
+// access is an access to external storage that is yet to be written.
+type access struct {
+	ts    time.Time
+	p     principal
+	op    cloud.AccessOp
+	uri   string
+	bytes int64
+}
+
+// Auditor is a cloud.AccessAuditor that records accesses in the
+// system.external_io_audit table, and deletes them once they are past their
+// retention. Accesses are buffered in memory and written in batches by the
+// task that Start starts, so that closing a file does not wait on a write to
+// the table. Accesses that are still buffered when the server stops are not
+// recorded.
+type Auditor struct {
+	settings *cluster.Settings
+	ie       sqlutil.InternalExecutor
+
+	// flushCh is signalled when flushBatchSize accesses are buffered.
+	flushCh chan struct{}
+
+	mu struct {
+		syncutil.Mutex
+		buffered []access
+		// dropped counts the accesses dropped since the buffer last had room.
+		dropped int
+	}
+}
+
+var _ cloud.AccessAuditor = &Auditor{}
+
+// NewAuditor returns an Auditor that records accesses using ie. It only writes
+// them once it is started.
+func NewAuditor(settings *cluster.Settings, ie sqlutil.InternalExecutor) *Auditor {
+	return &Auditor{settings: settings, ie: ie, flushCh: make(chan struct{}, 1)}
+}
+
+// Start starts the task that writes the buffered accesses, and deletes the
+// accesses that are past their retention, until stopper quiesces.
+func (a *Auditor) Start(ctx context.Context, stopper *stop.Stopper) error {
+	return stopper.RunAsyncTask(ctx, "external-io-audit", func(ctx context.Context) {
+		ctx, cancel := stopper.WithCancelOnQuiesce(ctx)
+		defer cancel()
+
+		var flushTimer, cleanupTimer timeutil.Timer
+		defer flushTimer.Stop()
+		defer cleanupTimer.Stop()
+		flushTimer.Reset(FlushInterval.Get(&a.settings.SV))
+		cleanupTimer.Reset(cleanupInterval)
+		for {
+			select {
+			case <-a.flushCh:
+				a.flush(ctx)
+			case <-flushTimer.C:
+				flushTimer.Read = true
+				a.flush(ctx)
+				flushTimer.Reset(FlushInterval.Get(&a.settings.SV))
+			case <-cleanupTimer.C:
+				cleanupTimer.Read = true
+				a.cleanup(ctx)
+				cleanupTimer.Reset(cleanupInterval)
+			case <-stopper.ShouldQuiesce():
+				return
+			}
+		}
+	})
+}
+
+// RecordAccess implements the cloud.AccessAuditor interface. The access is
+// buffered, to be written by the task that Start starts.
+func (a *Auditor) RecordAccess(
+	ctx context.Context, es cloud.ExternalStorage, op cloud.AccessOp, bytes int64,
+) {
+	if !Enabled.Get(&a.settings.SV) ||
+		!a.settings.Version.IsActive(ctx, clusterversion.V23_1ExternalIOAuditTable) {
+		return
+	}
+	p, ok := ctx.Value(principalKey{}).(principal)
+	if !ok {
+		return
+	}
+	acc := access{ts: timeutil.Now(), p: p, op: op, uri: RedactedURI(es.Conf()), bytes: bytes}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.mu.buffered) >= maxBufferedAccesses {
+		a.mu.dropped++
+		return
+	}
+	a.mu.buffered = append(a.mu.buffered, acc)
+	if len(a.mu.buffered) >= flushBatchSize {
+		select {
+		case a.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush writes the buffered accesses. Failures to write them are logged, and
+// the accesses are dropped rather than retried.
+func (a *Auditor) flush(ctx context.Context) {
+	a.mu.Lock()
+	buffered, dropped := a.mu.buffered, a.mu.dropped
+	a.mu.buffered, a.mu.dropped = nil, 0
+	a.mu.Unlock()
+
+	if dropped > 0 {
+		log.Warningf(ctx, "dropped %d accesses to external storage while the audit buffer was full", dropped)
+	}
+	for len(buffered) > 0 {
+		batch := buffered
+		if len(batch) > flushBatchSize {
+			batch = batch[:flushBatchSize]
+		}
+		buffered = buffered[len(batch):]
+		if err := a.write(ctx, batch); err != nil {
+			if ctx.Err() == nil {
+				log.Warningf(ctx, "failed to record %d accesses to external storage: %v", len(batch), err)
+			}
+			return
+		}
+	}
+}
+
+// write inserts the given accesses into system.external_io_audit.
+func (a *Auditor) write(ctx context.Context, batch []access) error {
+	const cols = 6
+	var stmt strings.Builder
+	stmt.WriteString(`INSERT INTO system.external_io_audit (ts, principal, job_id, operation, uri, bytes) VALUES `)
+	args := make([]interface{}, 0, len(batch)*cols)
+	for i, acc := range batch {
+		if i > 0 {
+			stmt.WriteString(", ")
+		}
+		fmt.Fprintf(&stmt, "($%d, $%d, $%d, $%d, $%d, $%d)",
+			i*cols+1, i*cols+2, i*cols+3, i*cols+4, i*cols+5, i*cols+6)
+		var jobID interface{}
+		if acc.p.jobID != 0 {
+			jobID = int64(acc.p.jobID)
+		}
+		args = append(args, acc.ts, acc.p.user.Normalized(), jobID, string(acc.op), acc.uri, acc.bytes)
+	}
+	_, err := a.ie.ExecEx(ctx, "record-external-io", nil, /* txn */
+		sessiondata.NodeUserSessionDataOverride, stmt.String(), args...)
+	return err
+}
+
+// cleanup deletes the accesses that are past their retention.
+func (a *Auditor) cleanup(ctx context.Context) {
+	retention := Retention.Get(&a.settings.SV)
+	if retention == 0 ||
+		!a.settings.Version.IsActive(ctx, clusterversion.V23_1ExternalIOAuditTable) {
+		return
+	}
+	if _, err := a.ie.ExecEx(ctx, "cleanup-external-io-audit", nil, /* txn */
+		sessiondata.NodeUserSessionDataOverride,
+		fmt.Sprintf(`DELETE FROM system.external_io_audit WHERE ts < $1 LIMIT %d`, cleanupBatchSize),
+		timeutil.Now().Add(-retention),
+	); err != nil && ctx.Err() == nil {
+		log.Warningf(ctx, "failed to delete expired external storage accesses: %v", err)
+	}
+}
+
+// RedactedURI returns the URI of the location of an external storage, without
+// any of the credentials or other parameters that its configuration holds.
+func RedactedURI(conf cloudpb.ExternalStorage) string {
+	switch conf.Provider {
+	case cloudpb.ExternalStorageProvider_nodelocal:
+		return fmt.Sprintf("nodelocal://%d/%s", conf.LocalFileConfig.NodeID,
+			strings.TrimPrefix(conf.LocalFileConfig.Path, "/"))
+	case cloudpb.ExternalStorageProvider_plugin:
+		return "plugin://" + conf.PluginConfig.Name + "/" + strings.TrimPrefix(conf.PluginConfig.Path, "/")
+	case cloudpb.ExternalStorageProvider_http:
+		u, err := url.Parse(conf.HttpPath.BaseUri)
+		if err != nil {
+			return "http://<redacted>"
+		}
+		u.User = nil
+		u.RawQuery = ""
+		return u.String()
+	case cloudpb.ExternalStorageProvider_s3:
+		if conf.S3Config != nil {
+			return "s3://" + conf.S3Config.Bucket + "/" + strings.TrimPrefix(conf.S3Config.Prefix, "/")
+		}
+	case cloudpb.ExternalStorageProvider_gs:
+		if conf.GoogleCloudConfig != nil {
+			return "gs://" + conf.GoogleCloudConfig.Bucket + "/" +
+				strings.TrimPrefix(conf.GoogleCloudConfig.Prefix, "/")
+		}
+	case cloudpb.ExternalStorageProvider_azure:
+		if conf.AzureConfig != nil {
+			return "azure://" + conf.AzureConfig.Container + "/" +
+				strings.TrimPrefix(conf.AzureConfig.Prefix, "/")
+		}
+	case cloudpb.ExternalStorageProvider_userfile:
+		return "userfile://" + conf.FileTableConfig.QualifiedTableName + "/" +
+			strings.TrimPrefix(conf.FileTableConfig.Path, "/")
+	case cloudpb.ExternalStorageProvider_external:
+		return "external://" + conf.ExternalConnectionConfig.Name + "/" +
+			strings.TrimPrefix(conf.ExternalConnectionConfig.Path, "/")
+	case cloudpb.ExternalStorageProvider_null:
+		return "null://"
+	}
+	return conf.Provider.String() + "://"
+}
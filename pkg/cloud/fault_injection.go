@@ -0,0 +1,258 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cloud
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// ErrInjectedFault is the error that the operations on external storage fail
+// with when a FaultInjector fails them without being given an error of its
+// own.
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultInjection sets faults to inject into the operations on external
+// storage, for reproducing the failures of a destination in a running
+// cluster, such as one started by cockroach demo. Tests can set the same
+// faults, and any others, with the FaultInjector of the TestingKnobs.
+var FaultInjection = settings.RegisterValidatedStringSetting(
+	settings.TenantWritable,
+	"cloud.debug.fault_injection",
+	"for testing, a comma separated list of faults to inject into the operations on external "+
+		"storage, each of the form op:pattern[:n[:action]], where op is read, write (which "+
+		"includes copies to the file), list, delete or size, pattern matches the trailing elements of the path of the file, n is the index, "+
+		"from 1, of the matching operation to inject the fault into, or 0 for all of them, and "+
+		"action is error (the default), notfound, or a duration to delay the operation by",
+	"",
+	func(_ *settings.Values, s string) error {
+		_, err := ParseFaultInjectionRules(s)
+		return err
+	},
+)
+
+// FaultInjectionRule describes a fault to inject into the operations on
+// external storage.
+type FaultInjectionRule struct {
+	// Op is the operation the fault is injected into.
+	Op AccessOp
+	// Pattern is matched, as by path.Match, against the trailing elements of
+	// the path of the file operated on, relative to the external storage, or
+	// of the prefix listed, with as many elements as the pattern has. For
+	// example, metadata/latest/* matches the LATEST files written into the
+	// latest directory of a collection.
+	Pattern string
+	// N is the index, counted from 1, of the matching operation that the fault
+	// is injected into, or 0 if it is injected into all of them.
+	N int
+	// Latency delays the operation.
+	Latency time.Duration
+	// Err fails the operation. If neither Err nor Latency is set, the operation
+	// fails with ErrInjectedFault.
+	Err error
+}
+
+// ParseFaultInjectionRules parses the rules of the FaultInjection setting.
+func ParseFaultInjectionRules(s string) ([]FaultInjectionRule, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var rules []FaultInjectionRule
+	for _, spec := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(spec), ":")
+		if len(parts) < 2 || len(parts) > 4 {
+			return nil, errors.Newf("invalid fault %q: expected op:pattern[:n[:action]]", spec)
+		}
+		r := FaultInjectionRule{Op: AccessOp(parts[0]), Pattern: parts[1]}
+		switch r.Op {
+		case AccessRead, AccessWrite, AccessList, AccessDelete, AccessSize:
+		default:
+			return nil, errors.Newf("invalid fault %q: unknown operation %q", spec, parts[0])
+		}
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid fault %q", spec)
+		}
+		if len(parts) > 2 {
+			n, err := strconv.Atoi(parts[2])
+			if err != nil || n < 0 {
+				return nil, errors.Newf("invalid fault %q: %q is not an operation index", spec, parts[2])
+			}
+			r.N = n
+		}
+		if len(parts) > 3 {
+			switch action := parts[3]; action {
+			case "error":
+			case "notfound":
+				r.Err = errors.Mark(ErrInjectedFault, ErrFileDoesNotExist)
+			default:
+				d, err := time.ParseDuration(action)
+				if err != nil {
+					return nil, errors.Newf("invalid fault %q: unknown action %q", spec, action)
+				}
+				r.Latency = d
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+type faultRule struct {
+	FaultInjectionRule
+	// seen is the number of operations that matched the rule.
+	seen int
+}
+
+func (r *faultRule) matches(op AccessOp, p string) bool {
+	if r.Op != op {
+		return false
+	}
+	p = strings.Trim(p, "/")
+	if n := strings.Count(r.Pattern, "/") + 1; strings.Count(p, "/")+1 > n {
+		elems := strings.Split(p, "/")
+		p = strings.Join(elems[len(elems)-n:], "/")
+	}
+	ok, _ := path.Match(r.Pattern, p)
+	return ok
+}
+
+// FaultInjector injects faults into the operations on the external storage
+// that it is set on with WithFaultInjector. Each rule counts the operations
+// that match it, so that tests can fail, say, the third write to a path, and
+// reproduce the failures of a destination deterministically.
+type FaultInjector struct {
+	sv       atomic.Value // *settings.Values
+	numRules int32        // accessed atomically
+
+	mu struct {
+		syncutil.Mutex
+		rules []*faultRule
+		// setting and settingRules are the value of the FaultInjection setting
+		// that the rules were last parsed from, and the rules. The operations
+		// the latter match are counted from when the setting was changed.
+		setting      string
+		settingRules []*faultRule
+	}
+}
+
+// NewFaultInjector returns a FaultInjector without rules.
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{}
+}
+
+// WatchSetting makes f inject the faults set by the FaultInjection setting
+// in sv too.
+func (f *FaultInjector) WatchSetting(sv *settings.Values) {
+	f.sv.Store(sv)
+}
+
+// AddRule adds a fault for f to inject.
+func (f *FaultInjector) AddRule(r FaultInjectionRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.rules = append(f.mu.rules, &faultRule{FaultInjectionRule: r})
+	atomic.StoreInt32(&f.numRules, int32(len(f.mu.rules)))
+}
+
+// ClearRules removes the faults added with AddRule.
+func (f *FaultInjector) ClearRules() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.rules = nil
+	atomic.StoreInt32(&f.numRules, 0)
+}
+
+// inject injects the faults of the first rule matching op on the file or
+// prefix p whose turn it is, if any.
+func (f *FaultInjector) inject(ctx context.Context, op AccessOp, p string) error {
+	if f == nil {
+		return nil
+	}
+	var setting string
+	if sv, ok := f.sv.Load().(*settings.Values); ok {
+		setting = FaultInjection.Get(sv)
+	}
+	if setting == "" && atomic.LoadInt32(&f.numRules) == 0 {
+		return nil
+	}
+
+	var fault *FaultInjectionRule
+	func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if setting != f.mu.setting {
+			// The setting was validated when it was set.
+			rules, _ := ParseFaultInjectionRules(setting)
+			f.mu.setting, f.mu.settingRules = setting, nil
+			for _, r := range rules {
+				f.mu.settingRules = append(f.mu.settingRules, &faultRule{FaultInjectionRule: r})
+			}
+		}
+		for _, rules := range [][]*faultRule{f.mu.rules, f.mu.settingRules} {
+			for _, r := range rules {
+				if !r.matches(op, p) {
+					continue
+				}
+				r.seen++
+				if fault == nil && (r.N == 0 || r.N == r.seen) {
+					fault = &r.FaultInjectionRule
+				}
+			}
+		}
+	}()
+	if fault == nil {
+		return nil
+	}
+
+	if fault.Latency > 0 {
+		t := timeutil.NewTimer()
+		defer t.Stop()
+		t.Reset(fault.Latency)
+		select {
+		case <-t.C:
+			t.Read = true
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if fault.Err == nil {
+			return nil
+		}
+	}
+	err := fault.Err
+	if err == nil {
+		err = ErrInjectedFault
+	}
+	return errors.Wrapf(err, "%s of %s", op, p)
+}
+
+// TestingKnobs provide fine-grained control over external storage for
+// testing.
+type TestingKnobs struct {
+	// FaultInjector, if set, injects faults into the operations on the external
+	// storage opened by the server, along with those set by the FaultInjection
+	// setting.
+	FaultInjector *FaultInjector
+}
+
+// ModuleTestingKnobs is part of the base.ModuleTestingKnobs interface.
+func (t *TestingKnobs) ModuleTestingKnobs() {}
+
+var _ base.ModuleTestingKnobs = (*TestingKnobs)(nil)
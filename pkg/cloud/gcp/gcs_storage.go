@@ -110,6 +110,7 @@ type gcsStorage struct {
 	ioConf   base.ExternalIODirConfig
 	prefix   string
 	settings *cluster.Settings
+	upload   cloudpb.UploadOptions
 }
 
 var _ cloud.ExternalStorage = &gcsStorage{}
@@ -210,6 +211,7 @@ func makeGCSStorage(
 		ioConf:   args.IOConf,
 		prefix:   conf.Prefix,
 		settings: args.Settings,
+		upload:   args.UploadOptions(),
 	}, nil
 }
 
@@ -264,7 +266,10 @@ func (g *gcsStorage) Writer(ctx context.Context, basename string) (io.WriteClose
 		path.Join(g.prefix, basename))})
 
 	w := g.bucket.Object(path.Join(g.prefix, basename)).NewWriter(ctx)
-	w.ChunkSize = int(cloud.WriteChunkSize.Get(&g.settings.SV))
+	// GCS uploads the chunks of an object one at a time, so only the part size
+	// of the upload options applies.
+	_, chunkSize := g.upload.Resolve(1, cloud.WriteChunkSize.Get(&g.settings.SV))
+	w.ChunkSize = int(chunkSize)
 	if !gcsChunkingEnabled.Get(&g.settings.SV) {
 		w.ChunkSize = 0
 	}
@@ -317,12 +322,41 @@ func (g *gcsStorage) ReadFileAt(
 }
 
 func (g *gcsStorage) List(ctx context.Context, prefix, delim string, fn cloud.ListingFn) error {
+	return g.list(ctx, prefix, delim, "" /* startAfter */, fn)
+}
+
+// ListWithOptions implements the ExternalStorage interface. GCS lists objects
+// in ascending order and can start listing at a given name, but the client
+// lists the prefixes of each page after its objects, so listings that are
+// reversed or grouped by a delimiter have to be buffered.
+func (g *gcsStorage) ListWithOptions(
+	ctx context.Context, prefix string, opts cloud.ListOptions, fn cloud.ListingFn,
+) error {
+	if opts.Reverse || opts.Delimiter != "" {
+		return cloud.ListByBuffering(ctx, g, prefix, opts, fn)
+	}
+	return cloud.ListOrdered(opts, fn, func(fn cloud.ListingFn) error {
+		return g.list(ctx, prefix, "" /* delim */, opts.StartAfter, fn)
+	})
+}
+
+// list lists the objects, and the prefixes if delim is set, under prefix,
+// starting at those that come after startAfter.
+func (g *gcsStorage) list(
+	ctx context.Context, prefix, delim, startAfter string, fn cloud.ListingFn,
+) error {
 	dest := cloud.JoinPathPreservingTrailingSlash(g.prefix, prefix)
 	ctx, sp := tracing.ChildSpan(ctx, "gcs.List")
 	defer sp.Finish()
 	sp.RecordStructured(&types.StringValue{Value: fmt.Sprintf("gcs.List: %s", dest)})
 
-	it := g.bucket.Objects(ctx, &gcs.Query{Prefix: dest, Delimiter: delim})
+	query := &gcs.Query{Prefix: dest, Delimiter: delim}
+	if startAfter != "" {
+		// StartOffset is inclusive, so startAfter itself is listed, and skipped
+		// by the caller.
+		query.StartOffset = dest + startAfter
+	}
+	it := g.bucket.Objects(ctx, query)
 	for {
 		attrs, err := it.Next()
 		if errors.Is(err, iterator.Done) {
@@ -349,6 +383,18 @@ func (g *gcsStorage) Delete(ctx context.Context, basename string) error {
 		})
 }
 
+// Copy implements the ExternalStorage interface using a GCS rewrite, which
+// copies the object within GCS.
+func (g *gcsStorage) Copy(ctx context.Context, src, dst string) error {
+	return contextutil.RunWithTimeout(ctx, "copy gcs file",
+		cloud.Timeout.Get(&g.settings.SV),
+		func(ctx context.Context) error {
+			srcObj := g.bucket.Object(path.Join(g.prefix, src))
+			_, err := g.bucket.Object(path.Join(g.prefix, dst)).CopierFrom(srcObj).Run(ctx)
+			return err
+		})
+}
+
 func (g *gcsStorage) Size(ctx context.Context, basename string) (int64, error) {
 	var r *gcs.Reader
 	if err := contextutil.RunWithTimeout(ctx, "size gcs file",
@@ -185,6 +185,12 @@ func (h *httpStorage) List(_ context.Context, _, _ string, _ cloud.ListingFn) er
 	return errors.Mark(errors.New("http storage does not support listing"), cloud.ErrListingUnsupported)
 }
 
+func (h *httpStorage) ListWithOptions(
+	_ context.Context, _ string, _ cloud.ListOptions, _ cloud.ListingFn,
+) error {
+	return errors.Mark(errors.New("http storage does not support listing"), cloud.ErrListingUnsupported)
+}
+
 func (h *httpStorage) Delete(ctx context.Context, basename string) error {
 	return contextutil.RunWithTimeout(ctx, fmt.Sprintf("DELETE %s", basename),
 		cloud.Timeout.Get(&h.settings.SV), func(ctx context.Context) error {
@@ -193,6 +199,10 @@ func (h *httpStorage) Delete(ctx context.Context, basename string) error {
 		})
 }
 
+func (h *httpStorage) Copy(ctx context.Context, src, dst string) error {
+	return cloud.CopyFileByStreaming(ctx, h, src, dst)
+}
+
 func (h *httpStorage) Size(ctx context.Context, basename string) (int64, error) {
 	var resp *http.Response
 	if err := contextutil.RunWithTimeout(ctx, fmt.Sprintf("HEAD %s", basename),
@@ -24,5 +24,6 @@ import (
 	_ "github.com/cockroachdb/cockroach/pkg/cloud/httpsink"
 	_ "github.com/cockroachdb/cockroach/pkg/cloud/nodelocal"
 	_ "github.com/cockroachdb/cockroach/pkg/cloud/nullsink"
+	_ "github.com/cockroachdb/cockroach/pkg/cloud/storageplugin"
 	_ "github.com/cockroachdb/cockroach/pkg/cloud/userfile"
 )
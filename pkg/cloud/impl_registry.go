@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/errors"
 )
 
@@ -244,7 +245,10 @@ func MakeExternalStorage(
 		return &esWrapper{
 			ExternalStorage: e,
 			lim:             limiters[dest.Provider],
+			writeLim:        options.writeLimiter,
 			ioRecorder:      options.ioAccountingInterceptor,
+			auditor:         options.accessAuditor,
+			faults:          options.faultInjector,
 		}, nil
 	}
 
@@ -292,11 +296,77 @@ func MakeLimiters(ctx context.Context, sv *settings.Values) Limiters {
 	return m
 }
 
+// LocalityWriteLimiters are write rate limiters, by locality tier, that are
+// shared by the operations of a server writing to the external storage
+// destinations specific to those localities.
+type LocalityWriteLimiters struct {
+	mu struct {
+		syncutil.Mutex
+		limiters map[string]*quotapool.RateLimiter
+	}
+}
+
+// MakeLocalityWriteLimiters makes an empty set of LocalityWriteLimiters. It
+// should be called only once per server at creation.
+func MakeLocalityWriteLimiters() *LocalityWriteLimiters {
+	l := &LocalityWriteLimiters{}
+	l.mu.limiters = make(map[string]*quotapool.RateLimiter)
+	return l
+}
+
+// Get returns the limiter for the given locality tier, after updating it to
+// allow bytesPerSecond bytes per second, or any number of bytes if zero.
+func (l *LocalityWriteLimiters) Get(tier string, bytesPerSecond int64) *quotapool.RateLimiter {
+	rate, burst := quotapool.Limit(bytesPerSecond), bytesPerSecond
+	if bytesPerSecond == 0 {
+		rate, burst = quotapool.Limit(math.Inf(1)), math.MaxInt64
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim, ok := l.mu.limiters[tier]
+	if !ok {
+		lim = quotapool.NewRateLimiter(fmt.Sprintf("locality-write-%s", tier), rate, burst)
+		l.mu.limiters[tier] = lim
+	} else {
+		lim.UpdateLimit(rate, burst)
+	}
+	return lim
+}
+
 type esWrapper struct {
 	ExternalStorage
 
 	lim        rwLimiter
+	writeLim   *quotapool.RateLimiter
 	ioRecorder ReadWriterInterceptor
+	auditor    AccessAuditor
+	faults     *FaultInjector
+}
+
+// AsArchiveStorage returns the ArchiveStorage implemented by es, if any. The
+// stores returned by this package are wrapped to limit and account for their
+// IO, so they cannot be type-asserted to ArchiveStorage directly.
+func AsArchiveStorage(es ExternalStorage) (ArchiveStorage, bool) {
+	if w, ok := es.(*esWrapper); ok {
+		es = w.ExternalStorage
+	}
+	a, ok := es.(ArchiveStorage)
+	return a, ok
+}
+
+// SupportsListing returns whether es can list the files it stores. The List
+// and ListWithOptions of a store that cannot return errors marked with
+// ErrListingUnsupported, and callers that need to find files without listing
+// them have to know their names.
+func SupportsListing(es ExternalStorage) bool {
+	if w, ok := es.(*esWrapper); ok {
+		es = w.ExternalStorage
+	}
+	if l, ok := es.(ListingStorage); ok {
+		return l.SupportsListing()
+	}
+	return es.Conf().Provider != cloudpb.ExternalStorageProvider_http
 }
 
 func (e *esWrapper) wrapReader(ctx context.Context, r ioctx.ReadCloserCtx) ioctx.ReadCloserCtx {
@@ -306,6 +376,9 @@ func (e *esWrapper) wrapReader(ctx context.Context, r ioctx.ReadCloserCtx) ioctx
 	if e.ioRecorder != nil {
 		r = e.ioRecorder.Reader(ctx, e.ExternalStorage, r)
 	}
+	if e.auditor != nil {
+		r = &auditedReader{r: r, es: e.ExternalStorage, auditor: e.auditor}
+	}
 	return r
 }
 
@@ -313,13 +386,22 @@ func (e *esWrapper) wrapWriter(ctx context.Context, w io.WriteCloser) io.WriteCl
 	if e.lim.write != nil {
 		w = &limitedWriter{w: w, ctx: ctx, lim: e.lim.write}
 	}
+	if e.writeLim != nil {
+		w = &limitedWriter{w: w, ctx: ctx, lim: e.writeLim}
+	}
 	if e.ioRecorder != nil {
 		w = e.ioRecorder.Writer(ctx, e.ExternalStorage, w)
 	}
+	if e.auditor != nil {
+		w = &auditedWriter{w: w, ctx: ctx, es: e.ExternalStorage, auditor: e.auditor}
+	}
 	return w
 }
 
 func (e *esWrapper) ReadFile(ctx context.Context, basename string) (ioctx.ReadCloserCtx, error) {
+	if err := e.faults.inject(ctx, AccessRead, basename); err != nil {
+		return nil, err
+	}
 	r, err := e.ExternalStorage.ReadFile(ctx, basename)
 	if err != nil {
 		return r, err
@@ -331,6 +413,9 @@ func (e *esWrapper) ReadFile(ctx context.Context, basename string) (ioctx.ReadCl
 func (e *esWrapper) ReadFileAt(
 	ctx context.Context, basename string, offset int64,
 ) (ioctx.ReadCloserCtx, int64, error) {
+	if err := e.faults.inject(ctx, AccessRead, basename); err != nil {
+		return nil, 0, err
+	}
 	r, s, err := e.ExternalStorage.ReadFileAt(ctx, basename, offset)
 	if err != nil {
 		return r, s, err
@@ -340,6 +425,9 @@ func (e *esWrapper) ReadFileAt(
 }
 
 func (e *esWrapper) Writer(ctx context.Context, basename string) (io.WriteCloser, error) {
+	if err := e.faults.inject(ctx, AccessWrite, basename); err != nil {
+		return nil, err
+	}
 	w, err := e.ExternalStorage.Writer(ctx, basename)
 	if err != nil {
 		return nil, err
@@ -348,6 +436,74 @@ func (e *esWrapper) Writer(ctx context.Context, basename string) (io.WriteCloser
 	return e.wrapWriter(ctx, w), nil
 }
 
+func (e *esWrapper) Delete(ctx context.Context, basename string) error {
+	if err := e.faults.inject(ctx, AccessDelete, basename); err != nil {
+		return err
+	}
+	if err := e.ExternalStorage.Delete(ctx, basename); err != nil {
+		return err
+	}
+	if e.auditor != nil {
+		e.auditor.RecordAccess(ctx, e.ExternalStorage, AccessDelete, 0)
+	}
+	return nil
+}
+
+func (e *esWrapper) List(ctx context.Context, prefix, delimiter string, fn ListingFn) error {
+	if err := e.faults.inject(ctx, AccessList, prefix); err != nil {
+		return err
+	}
+	return e.ExternalStorage.List(ctx, prefix, delimiter, fn)
+}
+
+func (e *esWrapper) ListWithOptions(
+	ctx context.Context, prefix string, opts ListOptions, fn ListingFn,
+) error {
+	if err := e.faults.inject(ctx, AccessList, prefix); err != nil {
+		return err
+	}
+	return e.ExternalStorage.ListWithOptions(ctx, prefix, opts, fn)
+}
+
+// Copy is part of the ExternalStorage interface. Whether the store copies the
+// file server-side or streams it through this node, its bytes do not pass
+// through the readers and writers of the wrapper, so the copy waits on the
+// write limiters for the size of src, and is audited as a read of src and a
+// write of dst of that size. Faults are injected into it as into a write of
+// dst.
+func (e *esWrapper) Copy(ctx context.Context, src, dst string) error {
+	if err := e.faults.inject(ctx, AccessWrite, dst); err != nil {
+		return err
+	}
+	size, err := e.ExternalStorage.Size(ctx, src)
+	if err != nil {
+		return err
+	}
+	for _, lim := range []*quotapool.RateLimiter{e.lim.write, e.writeLim} {
+		if lim == nil {
+			continue
+		}
+		if err := lim.WaitN(ctx, size); err != nil {
+			return err
+		}
+	}
+	if err := e.ExternalStorage.Copy(ctx, src, dst); err != nil {
+		return err
+	}
+	if e.auditor != nil {
+		e.auditor.RecordAccess(ctx, e.ExternalStorage, AccessRead, size)
+		e.auditor.RecordAccess(ctx, e.ExternalStorage, AccessWrite, size)
+	}
+	return nil
+}
+
+func (e *esWrapper) Size(ctx context.Context, basename string) (int64, error) {
+	if err := e.faults.inject(ctx, AccessSize, basename); err != nil {
+		return 0, err
+	}
+	return e.ExternalStorage.Size(ctx, basename)
+}
+
 type limitedReader struct {
 	r    ioctx.ReadCloserCtx
 	lim  *quotapool.RateLimiter
@@ -409,6 +565,69 @@ func (l *limitedWriter) Close() error {
 	return l.w.Close()
 }
 
+// AccessOp is the kind of access made to a file in external storage.
+type AccessOp string
+
+const (
+	// AccessRead is a read of a file.
+	AccessRead AccessOp = "read"
+	// AccessWrite is a write of a file.
+	AccessWrite AccessOp = "write"
+	// AccessDelete is a deletion of a file.
+	AccessDelete AccessOp = "delete"
+	// AccessList is a listing of files, and AccessSize a lookup of the size of
+	// a file. AccessAuditors are not told of them, but faults can be injected
+	// into them.
+	AccessList AccessOp = "list"
+	AccessSize AccessOp = "size"
+)
+
+// An AccessAuditor records the accesses made to the files in an external
+// storage. Reads and writes are recorded once the file is closed, with the
+// number of bytes read or written.
+type AccessAuditor interface {
+	RecordAccess(ctx context.Context, es ExternalStorage, op AccessOp, bytes int64)
+}
+
+type auditedReader struct {
+	r       ioctx.ReadCloserCtx
+	es      ExternalStorage
+	auditor AccessAuditor
+	n       int64
+}
+
+func (a *auditedReader) Read(ctx context.Context, p []byte) (int, error) {
+	n, err := a.r.Read(ctx, p)
+	a.n += int64(n)
+	return n, err
+}
+
+func (a *auditedReader) Close(ctx context.Context) error {
+	err := a.r.Close(ctx)
+	a.auditor.RecordAccess(ctx, a.es, AccessRead, a.n)
+	return err
+}
+
+type auditedWriter struct {
+	w       io.WriteCloser
+	ctx     context.Context
+	es      ExternalStorage
+	auditor AccessAuditor
+	n       int64
+}
+
+func (a *auditedWriter) Write(p []byte) (int, error) {
+	n, err := a.w.Write(p)
+	a.n += int64(n)
+	return n, err
+}
+
+func (a *auditedWriter) Close() error {
+	err := a.w.Close()
+	a.auditor.RecordAccess(a.ctx, a.es, AccessWrite, a.n)
+	return err
+}
+
 // A ReadWriterInterceptor providers methods that construct Readers and Writers from given Readers
 // and Writers.
 type ReadWriterInterceptor interface {
@@ -216,10 +216,28 @@ func (l *localFileStorage) List(
 	return nil
 }
 
+// ListWithOptions implements the ExternalStorage interface. The files matched
+// by the blob client are sorted by List, so only reverse listings need another
+// buffer.
+func (l *localFileStorage) ListWithOptions(
+	ctx context.Context, prefix string, opts cloud.ListOptions, fn cloud.ListingFn,
+) error {
+	if opts.Reverse {
+		return cloud.ListByBuffering(ctx, l, prefix, opts, fn)
+	}
+	return cloud.ListOrdered(opts, fn, func(fn cloud.ListingFn) error {
+		return l.List(ctx, prefix, opts.Delimiter, fn)
+	})
+}
+
 func (l *localFileStorage) Delete(ctx context.Context, basename string) error {
 	return l.blobClient.Delete(ctx, joinRelativePath(l.base, basename))
 }
 
+func (l *localFileStorage) Copy(ctx context.Context, src, dst string) error {
+	return cloud.CopyFileByStreaming(ctx, l, src, dst)
+}
+
 func (l *localFileStorage) Size(ctx context.Context, basename string) (int64, error) {
 	stat, err := l.blobClient.Stat(ctx, joinRelativePath(l.base, basename))
 	if err != nil {
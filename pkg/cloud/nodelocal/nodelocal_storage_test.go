@@ -11,13 +11,24 @@
 package nodelocal
 
 import (
+	"bytes"
+	"context"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/blobs"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudtestutils"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPutLocal(t *testing.T) {
@@ -38,3 +49,114 @@ func TestPutLocal(t *testing.T) {
 		testSettings,
 	)
 }
+
+type recordedAccess struct {
+	op    cloud.AccessOp
+	bytes int64
+}
+
+type recordingAuditor struct {
+	syncutil.Mutex
+	accesses []recordedAccess
+}
+
+func (r *recordingAuditor) RecordAccess(
+	_ context.Context, _ cloud.ExternalStorage, op cloud.AccessOp, bytes int64,
+) {
+	r.Lock()
+	defer r.Unlock()
+	r.accesses = append(r.accesses, recordedAccess{op: op, bytes: bytes})
+}
+
+// TestCopyLocal checks that copies within a store wait on its write limiter
+// and are audited, even though their bytes are not read and written through
+// the store.
+func TestCopyLocal(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	p, cleanupFn := testutils.TempDir(t)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	testSettings := cluster.MakeTestingClusterSettings()
+	testSettings.ExternalIODir = p
+	conf, err := cloud.ExternalStorageConfFromURI("nodelocal://0/copy", username.RootUserName())
+	require.NoError(t, err)
+	makeStore := func(opts ...cloud.ExternalStorageOption) cloud.ExternalStorage {
+		s, err := cloud.MakeExternalStorage(ctx, conf, base.ExternalIODirConfig{}, testSettings,
+			blobs.TestBlobServiceClient(p), nil, nil, nil, nil, opts...)
+		require.NoError(t, err)
+		return s
+	}
+
+	contents := bytes.Repeat([]byte("a"), 64)
+	unlimited := makeStore()
+	defer unlimited.Close()
+	require.NoError(t, cloud.WriteFile(ctx, unlimited, "src", bytes.NewReader(contents)))
+
+	// The limiter does not refill, as its time source does not advance.
+	lim := quotapool.NewRateLimiter("test", 10, 100,
+		quotapool.WithTimeSource(timeutil.NewManualTime(timeutil.Unix(0, 0))))
+	auditor := &recordingAuditor{}
+	s := makeStore(cloud.WithWriteLimiter(lim), cloud.WithAccessAuditor(auditor))
+	defer s.Close()
+
+	require.NoError(t, s.Copy(ctx, "src", "dst"))
+	r, err := unlimited.ReadFile(ctx, "dst")
+	require.NoError(t, err)
+	copied, err := ioctx.ReadAll(ctx, r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close(ctx))
+	require.Equal(t, contents, copied)
+
+	require.Equal(t, []recordedAccess{
+		{op: cloud.AccessRead, bytes: 64},
+		{op: cloud.AccessWrite, bytes: 64},
+	}, auditor.accesses)
+	require.False(t, lim.AdmitN(100-64+1))
+	require.True(t, lim.AdmitN(100-64))
+
+	// With the limiter exhausted, the next copy waits until it is cancelled.
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	require.Error(t, s.Copy(ctx, "src", "dst2"))
+	_, err = unlimited.Size(context.Background(), "dst2")
+	require.Error(t, err)
+}
+
+// TestCopyLocalInjectedFaults checks that faults are injected into copies as
+// into writes of their destination.
+func TestCopyLocalInjectedFaults(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	p, cleanupFn := testutils.TempDir(t)
+	defer cleanupFn()
+
+	ctx := context.Background()
+	testSettings := cluster.MakeTestingClusterSettings()
+	testSettings.ExternalIODir = p
+	conf, err := cloud.ExternalStorageConfFromURI("nodelocal://0/copy", username.RootUserName())
+	require.NoError(t, err)
+	faults := cloud.NewFaultInjector()
+	auditor := &recordingAuditor{}
+	s, err := cloud.MakeExternalStorage(ctx, conf, base.ExternalIODirConfig{}, testSettings,
+		blobs.TestBlobServiceClient(p), nil, nil, nil, nil,
+		cloud.WithFaultInjector(faults), cloud.WithAccessAuditor(auditor))
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, cloud.WriteFile(ctx, s, "src", bytes.NewReader([]byte("contents"))))
+	auditor.accesses = nil
+
+	faults.AddRule(cloud.FaultInjectionRule{Op: cloud.AccessWrite, Pattern: "dst", N: 1})
+	require.ErrorIs(t, s.Copy(ctx, "src", "dst"), cloud.ErrInjectedFault)
+	_, err = s.Size(ctx, "dst")
+	require.Error(t, err)
+	require.Empty(t, auditor.accesses)
+
+	// The rule only fails the first copy to dst.
+	require.NoError(t, s.Copy(ctx, "src", "dst"))
+	size, err := s.Size(ctx, "dst")
+	require.NoError(t, err)
+	require.Equal(t, int64(len("contents")), size)
+}
@@ -95,10 +95,20 @@ func (n *nullSinkStorage) List(_ context.Context, _, _ string, _ cloud.ListingFn
 	return nil
 }
 
+func (n *nullSinkStorage) ListWithOptions(
+	_ context.Context, _ string, _ cloud.ListOptions, _ cloud.ListingFn,
+) error {
+	return nil
+}
+
 func (n *nullSinkStorage) Delete(_ context.Context, _ string) error {
 	return nil
 }
 
+func (n *nullSinkStorage) Copy(_ context.Context, _, _ string) error {
+	return nil
+}
+
 func (n *nullSinkStorage) Size(_ context.Context, _ string) (int64, error) {
 	return 0, nil
 }
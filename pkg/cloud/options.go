@@ -10,6 +10,11 @@
 
 package cloud
 
+import (
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+)
+
 // ExternalStorageOption is an option passed during the construction
 // of an external storage.
 type ExternalStorageOption func(opts *ExternalStorageOptions)
@@ -20,3 +25,45 @@ func WithIOAccountingInterceptor(i ReadWriterInterceptor) ExternalStorageOption
 		opts.ioAccountingInterceptor = i
 	}
 }
+
+// WithAccessAuditor sets the AccessAuditor that records the accesses made to
+// the files of the external storage.
+func WithAccessAuditor(a AccessAuditor) ExternalStorageOption {
+	return func(opts *ExternalStorageOptions) {
+		opts.accessAuditor = a
+	}
+}
+
+// WithUploadOptions sets the options that tune how the Writer of the external
+// storage uploads files, for providers that upload them in parts.
+func WithUploadOptions(o cloudpb.UploadOptions) ExternalStorageOption {
+	return func(opts *ExternalStorageOptions) {
+		opts.uploadOptions = o
+	}
+}
+
+// WithWriteLimiter sets a rate limiter that the bytes written to the external
+// storage are subject to, in addition to the limiter of its provider.
+func WithWriteLimiter(lim *quotapool.RateLimiter) ExternalStorageOption {
+	return func(opts *ExternalStorageOptions) {
+		opts.writeLimiter = lim
+	}
+}
+
+// WithFaultInjector sets the FaultInjector that injects faults into the
+// operations on the external storage.
+func WithFaultInjector(f *FaultInjector) ExternalStorageOption {
+	return func(opts *ExternalStorageOptions) {
+		opts.faultInjector = f
+	}
+}
+
+// UploadOptions returns the upload options set by the ExternalStorageOptions
+// that the external storage is being constructed with.
+func (e ExternalStorageContext) UploadOptions() cloudpb.UploadOptions {
+	var options ExternalStorageOptions
+	for _, o := range e.Options {
+		o(&options)
+	}
+	return options.uploadOptions
+}
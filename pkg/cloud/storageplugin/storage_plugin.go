@@ -0,0 +1,427 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// Package storageplugin implements the `plugin` ExternalStorage provider,
+// which lets backups and other external files be kept in storage that
+// CockroachDB has no provider for, such as proprietary or internal object
+// stores. The files are stored by a plugin, a sidecar of each node that serves
+// the StoragePlugin gRPC service of storagepluginpb, and which a URI names as
+// in plugin://<name>/<path>. The address each plugin is served at is set by the
+// cloudstorage.plugins cluster setting.
+//
+// Plugins only have to serve reads, writes, deletes and sizes. Whether they
+// can also list files, list them in order, or copy them is negotiated when
+// the storage is opened: the node emulates ordered listings and copies, and
+// reports storage that cannot list like HTTP storage, so that backups find
+// their files by well-known names instead.
+package storageplugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/cloud/storageplugin/storagepluginpb"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/contextutil"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const scheme = "plugin"
+
+// Plugins is the cluster setting mapping the names of storage plugins to the
+// addresses they are served at.
+var Plugins = settings.RegisterValidatedStringSetting(
+	settings.TenantWritable,
+	"cloudstorage.plugins",
+	"comma-separated list of name=address pairs of the storage plugins that plugin://<name>/ "+
+		"URIs refer to; each plugin must be served at an address only reachable from the node, "+
+		"such as a loopback address or a unix:// socket, on every node",
+	"",
+	func(_ *settings.Values, s string) error {
+		_, err := parsePlugins(s)
+		return err
+	},
+)
+
+// capabilitiesTimeout bounds the time the capabilities of a plugin are waited
+// for when a storage of it is opened.
+const capabilitiesTimeout = 10 * time.Second
+
+// parsePlugins parses the value of the Plugins setting.
+func parsePlugins(s string) (map[string]string, error) {
+	plugins := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, addr, ok := strings.Cut(entry, "=")
+		name, addr = strings.TrimSpace(name), strings.TrimSpace(addr)
+		if !ok || name == "" || addr == "" {
+			return nil, errors.Newf("invalid storage plugin %q: expected name=address", entry)
+		}
+		if _, ok := plugins[name]; ok {
+			return nil, errors.Newf("storage plugin %q is listed more than once", name)
+		}
+		plugins[name] = addr
+	}
+	return plugins, nil
+}
+
+func parsePluginURL(
+	_ cloud.ExternalStorageURIContext, uri *url.URL,
+) (cloudpb.ExternalStorage, error) {
+	conf := cloudpb.ExternalStorage{}
+	if uri.Host == "" {
+		return conf, errors.Newf("host component of plugin URI must be the name of a storage plugin: %s",
+			uri.String())
+	}
+	// Plugins are configured apart from the URIs that refer to them, so that
+	// their credentials are never part of one.
+	if uri.RawQuery != "" {
+		return conf, errors.Newf("plugin URIs do not take parameters: %s", uri.String())
+	}
+	conf.Provider = cloudpb.ExternalStorageProvider_plugin
+	conf.PluginConfig.Name = uri.Host
+	conf.PluginConfig.Path = uri.Path
+	return conf, nil
+}
+
+type pluginStorage struct {
+	conf     cloudpb.ExternalStorage_Plugin
+	ioConf   base.ExternalIODirConfig
+	settings *cluster.Settings
+	conn     *grpc.ClientConn
+	client   storagepluginpb.StoragePluginClient
+	caps     storagepluginpb.Capabilities
+}
+
+var _ cloud.ExternalStorage = &pluginStorage{}
+var _ cloud.ListingStorage = &pluginStorage{}
+
+func makePluginStorage(
+	ctx context.Context, args cloud.ExternalStorageContext, dest cloudpb.ExternalStorage,
+) (cloud.ExternalStorage, error) {
+	telemetry.Count("external-io.plugin")
+	conf := dest.PluginConfig
+	plugins, err := parsePlugins(Plugins.Get(&args.Settings.SV))
+	if err != nil {
+		return nil, err
+	}
+	addr, ok := plugins[conf.Name]
+	if !ok {
+		return nil, errors.Newf("storage plugin %q is not configured in %s", conf.Name, Plugins.Key())
+	}
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "connecting to storage plugin %q", conf.Name)
+	}
+	s := &pluginStorage{
+		conf:     conf,
+		ioConf:   args.IOConf,
+		settings: args.Settings,
+		conn:     conn,
+		client:   storagepluginpb.NewStoragePluginClient(conn),
+	}
+	// A plugin that does not serve Capabilities supports none of the optional
+	// operations.
+	if err := contextutil.RunWithTimeout(ctx, "storage plugin capabilities", capabilitiesTimeout,
+		func(ctx context.Context) error {
+			caps, err := s.client.Capabilities(ctx, &storagepluginpb.CapabilitiesRequest{})
+			if err != nil {
+				return err
+			}
+			s.caps = *caps
+			return nil
+		}); err != nil && status.Code(err) != codes.Unimplemented {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "fetching the capabilities of storage plugin %q", conf.Name)
+	}
+	return s, nil
+}
+
+func (s *pluginStorage) Conf() cloudpb.ExternalStorage {
+	return cloudpb.ExternalStorage{
+		Provider:     cloudpb.ExternalStorageProvider_plugin,
+		PluginConfig: s.conf,
+	}
+}
+
+func (s *pluginStorage) ExternalIOConf() base.ExternalIODirConfig {
+	return s.ioConf
+}
+
+func (s *pluginStorage) RequiresExternalIOAccounting() bool {
+	return true
+}
+
+func (s *pluginStorage) Settings() *cluster.Settings {
+	return s.settings
+}
+
+// SupportsListing implements the cloud.ListingStorage interface.
+func (s *pluginStorage) SupportsListing() bool {
+	return s.caps.List
+}
+
+func (s *pluginStorage) path(basename string) string {
+	return cloud.JoinPathPreservingTrailingSlash(s.conf.Path, basename)
+}
+
+// wrapErr marks the errors of requests for files that do not exist with
+// cloud.ErrFileDoesNotExist.
+func (s *pluginStorage) wrapErr(err error, basename string) error {
+	if status.Code(err) == codes.NotFound {
+		// nolint:errwrap
+		return errors.WithMessagef(
+			errors.Wrapf(cloud.ErrFileDoesNotExist, "storage plugin %q file does not exist", s.conf.Name),
+			"%s", err.Error())
+	}
+	return errors.Wrapf(err, "storage plugin %q: %s", s.conf.Name, basename)
+}
+
+// ReadFile is shorthand for ReadFileAt with offset 0.
+func (s *pluginStorage) ReadFile(
+	ctx context.Context, basename string,
+) (ioctx.ReadCloserCtx, error) {
+	r, _, err := s.ReadFileAt(ctx, basename, 0)
+	return r, err
+}
+
+func (s *pluginStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (ioctx.ReadCloserCtx, int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := s.client.Read(ctx, &storagepluginpb.ReadRequest{Path: s.path(basename), Offset: offset})
+	if err != nil {
+		cancel()
+		return nil, 0, s.wrapErr(err, basename)
+	}
+	// The first chunk holds the size of the file, and is the first to tell
+	// whether it exists.
+	first, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		cancel()
+		return nil, 0, s.wrapErr(err, basename)
+	}
+	r := &readStream{stream: stream, cancel: cancel}
+	if first == nil {
+		r.eof = true
+		return r, 0, nil
+	}
+	r.buf = first.Payload
+	return r, first.Size, nil
+}
+
+// readStream reads the chunks of a Read stream.
+type readStream struct {
+	stream storagepluginpb.StoragePlugin_ReadClient
+	cancel context.CancelFunc
+	buf    []byte
+	eof    bool
+}
+
+var _ ioctx.ReadCloserCtx = &readStream{}
+
+func (r *readStream) Read(_ context.Context, p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		chunk, err := r.stream.Recv()
+		if err == io.EOF {
+			r.eof = true
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.buf = chunk.Payload
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *readStream) Close(_ context.Context) error {
+	r.cancel()
+	return nil
+}
+
+// chunkSize is the size of the chunks files are written in.
+const chunkSize = 128 << 10
+
+func (s *pluginStorage) Writer(ctx context.Context, basename string) (io.WriteCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := s.client.Write(ctx)
+	if err != nil {
+		cancel()
+		return nil, s.wrapErr(err, basename)
+	}
+	return &writeStream{
+		stream: stream,
+		cancel: cancel,
+		req:    storagepluginpb.WriteRequest{Path: s.path(basename), Payload: make([]byte, 0, chunkSize)},
+	}, nil
+}
+
+// writeStream writes a file as the chunks of a Write stream, the first of
+// which holds its path.
+type writeStream struct {
+	stream storagepluginpb.StoragePlugin_WriteClient
+	cancel context.CancelFunc
+	req    storagepluginpb.WriteRequest
+	sent   bool
+}
+
+func (w *writeStream) send() error {
+	if err := w.stream.Send(&w.req); err != nil {
+		return err
+	}
+	w.req.Path = ""
+	w.req.Payload = w.req.Payload[:0]
+	w.sent = true
+	return nil
+}
+
+func (w *writeStream) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		l := copy(w.req.Payload[len(w.req.Payload):cap(w.req.Payload)], p)
+		w.req.Payload = w.req.Payload[:len(w.req.Payload)+l]
+		p = p[l:]
+		n += l
+		if len(w.req.Payload) == cap(w.req.Payload) {
+			if err := w.send(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *writeStream) Close() error {
+	defer w.cancel()
+	if len(w.req.Payload) > 0 || !w.sent {
+		if err := w.send(); err != nil {
+			return err
+		}
+	}
+	_, err := w.stream.CloseAndRecv()
+	return err
+}
+
+func (s *pluginStorage) List(ctx context.Context, prefix, delim string, fn cloud.ListingFn) error {
+	return s.list(ctx, &storagepluginpb.ListRequest{Prefix: s.path(prefix), Delimiter: delim}, fn)
+}
+
+// ListWithOptions implements the ExternalStorage interface. Plugins that do not
+// list in order are listed in full and their results sorted.
+func (s *pluginStorage) ListWithOptions(
+	ctx context.Context, prefix string, opts cloud.ListOptions, fn cloud.ListingFn,
+) error {
+	if !s.caps.OrderedList {
+		return cloud.ListByBuffering(ctx, s, prefix, opts, fn)
+	}
+	return cloud.ListOrdered(opts, fn, func(fn cloud.ListingFn) error {
+		return s.list(ctx, &storagepluginpb.ListRequest{
+			Prefix:     s.path(prefix),
+			Delimiter:  opts.Delimiter,
+			Reverse:    opts.Reverse,
+			StartAfter: opts.StartAfter,
+			MaxResults: int64(opts.MaxResults),
+		}, fn)
+	})
+}
+
+func (s *pluginStorage) list(
+	ctx context.Context, req *storagepluginpb.ListRequest, fn cloud.ListingFn,
+) error {
+	if !s.caps.List {
+		return errors.Mark(errors.Newf("storage plugin %q does not support listing", s.conf.Name),
+			cloud.ErrListingUnsupported)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := s.client.List(ctx, req)
+	if err != nil {
+		return s.wrapErr(err, req.Prefix)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return s.wrapErr(err, req.Prefix)
+		}
+		for _, name := range resp.Names {
+			if err := fn(name); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *pluginStorage) Delete(ctx context.Context, basename string) error {
+	_, err := s.client.Delete(ctx, &storagepluginpb.DeleteRequest{Path: s.path(basename)})
+	if err != nil {
+		return s.wrapErr(err, basename)
+	}
+	return nil
+}
+
+func (s *pluginStorage) Copy(ctx context.Context, src, dst string) error {
+	if !s.caps.Copy {
+		return cloud.CopyFileByStreaming(ctx, s, src, dst)
+	}
+	_, err := s.client.Copy(ctx, &storagepluginpb.CopyRequest{Src: s.path(src), Dst: s.path(dst)})
+	if err != nil {
+		return s.wrapErr(err, src)
+	}
+	return nil
+}
+
+func (s *pluginStorage) Size(ctx context.Context, basename string) (int64, error) {
+	resp, err := s.client.Size(ctx, &storagepluginpb.SizeRequest{Path: s.path(basename)})
+	if err != nil {
+		return 0, s.wrapErr(err, basename)
+	}
+	return resp.Size, nil
+}
+
+func (s *pluginStorage) Close() error {
+	return s.conn.Close()
+}
+
+// MakePluginStorageURI returns the URI of path in the storage of the named
+// plugin.
+func MakePluginStorageURI(name, path string) string {
+	return fmt.Sprintf("%s://%s/%s", scheme, name, strings.TrimPrefix(path, "/"))
+}
+
+func init() {
+	cloud.RegisterExternalStorageProvider(cloudpb.ExternalStorageProvider_plugin,
+		parsePluginURL, makePluginStorage, cloud.RedactedParams(), scheme)
+}
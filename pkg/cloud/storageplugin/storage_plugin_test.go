@@ -0,0 +1,274 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package storageplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/storageplugin/storagepluginpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memPlugin is a storage plugin keeping its files in memory, which lists them
+// in order if it has the ordered_list capability and in reverse otherwise.
+type memPlugin struct {
+	caps storagepluginpb.Capabilities
+	mu   struct {
+		sync.Mutex
+		files map[string][]byte
+	}
+}
+
+var _ storagepluginpb.StoragePluginServer = &memPlugin{}
+
+func (m *memPlugin) Capabilities(
+	context.Context, *storagepluginpb.CapabilitiesRequest,
+) (*storagepluginpb.Capabilities, error) {
+	caps := m.caps
+	return &caps, nil
+}
+
+func (m *memPlugin) get(path string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.mu.files[path]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s not found", path)
+	}
+	return data, nil
+}
+
+func (m *memPlugin) Read(
+	req *storagepluginpb.ReadRequest, stream storagepluginpb.StoragePlugin_ReadServer,
+) error {
+	data, err := m.get(req.Path)
+	if err != nil {
+		return err
+	}
+	size := int64(len(data))
+	// Send the file in chunks of a byte to exercise the reassembly of chunks.
+	for i := req.Offset; i < size; i++ {
+		if err := stream.Send(&storagepluginpb.ReadResponse{Size: size, Payload: data[i : i+1]}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&storagepluginpb.ReadResponse{Size: size})
+}
+
+func (m *memPlugin) Write(stream storagepluginpb.StoragePlugin_WriteServer) error {
+	var path string
+	var data []byte
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.Path != "" {
+			path = req.Path
+		}
+		data = append(data, req.Payload...)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mu.files[path] = data
+	return stream.SendAndClose(&storagepluginpb.WriteResponse{})
+}
+
+func (m *memPlugin) List(
+	req *storagepluginpb.ListRequest, stream storagepluginpb.StoragePlugin_ListServer,
+) error {
+	if !m.caps.List {
+		return status.Error(codes.Unimplemented, "listing is not supported")
+	}
+	m.mu.Lock()
+	var names []string
+	seen := make(map[string]bool)
+	for path := range m.mu.files {
+		if !strings.HasPrefix(path, req.Prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(path, req.Prefix)
+		if req.Delimiter != "" {
+			if i := strings.Index(name, req.Delimiter); i >= 0 {
+				name = name[:i+len(req.Delimiter)]
+			}
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	m.mu.Unlock()
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	if m.caps.OrderedList && !req.Reverse {
+		sort.Strings(names)
+	}
+	return stream.Send(&storagepluginpb.ListResponse{Names: names})
+}
+
+func (m *memPlugin) Delete(
+	_ context.Context, req *storagepluginpb.DeleteRequest,
+) (*storagepluginpb.DeleteResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.mu.files, req.Path)
+	return &storagepluginpb.DeleteResponse{}, nil
+}
+
+func (m *memPlugin) Size(
+	_ context.Context, req *storagepluginpb.SizeRequest,
+) (*storagepluginpb.SizeResponse, error) {
+	data, err := m.get(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &storagepluginpb.SizeResponse{Size: int64(len(data))}, nil
+}
+
+func (m *memPlugin) Copy(
+	_ context.Context, req *storagepluginpb.CopyRequest,
+) (*storagepluginpb.CopyResponse, error) {
+	if !m.caps.Copy {
+		return nil, status.Error(codes.Unimplemented, "copying is not supported")
+	}
+	data, err := m.get(req.Src)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mu.files[req.Dst] = data
+	return &storagepluginpb.CopyResponse{}, nil
+}
+
+// startMemPlugin serves a memPlugin with the given capabilities, and returns
+// settings in which it is configured as the plugin named "mem".
+func startMemPlugin(
+	t *testing.T, caps storagepluginpb.Capabilities,
+) (*cluster.Settings, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	plugin := &memPlugin{caps: caps}
+	plugin.mu.files = make(map[string][]byte)
+	srv := grpc.NewServer()
+	storagepluginpb.RegisterStoragePluginServer(srv, plugin)
+	go func() { _ = srv.Serve(ln) }()
+
+	st := cluster.MakeTestingClusterSettings()
+	Plugins.Override(context.Background(), &st.SV, "mem="+ln.Addr().String())
+	return st, srv.Stop
+}
+
+func makeMemPluginStorage(
+	ctx context.Context, t *testing.T, st *cluster.Settings, uri string,
+) cloud.ExternalStorage {
+	conf, err := cloud.ExternalStorageConfFromURI(uri, username.RootUserName())
+	require.NoError(t, err)
+	s, err := cloud.MakeExternalStorage(ctx, conf, base.ExternalIODirConfig{}, st,
+		nil, /* blobClientFactory */
+		nil, /* ie */
+		nil, /* ief */
+		nil, /* kvDB */
+		nil, /* limiters */
+	)
+	require.NoError(t, err)
+	return s
+}
+
+func TestPluginStorage(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		caps storagepluginpb.Capabilities
+	}{
+		{name: "all", caps: storagepluginpb.Capabilities{List: true, OrderedList: true, Copy: true}},
+		{name: "unordered", caps: storagepluginpb.Capabilities{List: true}},
+		{name: "none"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			st, stop := startMemPlugin(t, tc.caps)
+			defer stop()
+			s := makeMemPluginStorage(ctx, t, st, MakePluginStorageURI("mem", "backups"))
+			defer s.Close()
+
+			for _, name := range []string{"b", "a/1", "a/2", "c"} {
+				require.NoError(t, cloud.WriteFile(ctx, s, name, bytes.NewReader([]byte("data "+name))))
+			}
+			r, size, err := s.ReadFileAt(ctx, "a/2", 2)
+			require.NoError(t, err)
+			require.Equal(t, int64(8), size)
+			data, err := ioctx.ReadAll(ctx, r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close(ctx))
+			require.Equal(t, "ta a/2", string(data))
+
+			_, err = s.ReadFile(ctx, "missing")
+			require.True(t, errors.Is(err, cloud.ErrFileDoesNotExist), "%v", err)
+
+			require.NoError(t, s.Copy(ctx, "b", "d"))
+			size, err = s.Size(ctx, "d")
+			require.NoError(t, err)
+			require.Equal(t, int64(6), size)
+			require.NoError(t, s.Delete(ctx, "d"))
+			_, err = s.Size(ctx, "d")
+			require.True(t, errors.Is(err, cloud.ErrFileDoesNotExist), "%v", err)
+
+			require.Equal(t, tc.caps.List, cloud.SupportsListing(s))
+			var names []string
+			err = s.ListWithOptions(ctx, "/", cloud.ListOptions{Delimiter: "/", StartAfter: "a/"},
+				func(name string) error {
+					names = append(names, name)
+					return nil
+				})
+			if !tc.caps.List {
+				require.True(t, errors.Is(err, cloud.ErrListingUnsupported), "%v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []string{"b", "c"}, names)
+		})
+	}
+}
+
+func TestParsePlugins(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	plugins, err := parsePlugins(" a=localhost:1, b = unix:///tmp/b.sock ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "localhost:1", "b": "unix:///tmp/b.sock"}, plugins)
+
+	for _, s := range []string{"a", "a=", "=b", "a=b,a=c"} {
+		_, err := parsePlugins(s)
+		require.Error(t, err, s)
+	}
+}
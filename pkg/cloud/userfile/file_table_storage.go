@@ -294,6 +294,20 @@ func (f *fileTableStorage) List(
 	return nil
 }
 
+// ListWithOptions implements the ExternalStorage interface. List sorts the
+// files of the user scoped FileToTableSystem, so only reverse listings are
+// buffered again.
+func (f *fileTableStorage) ListWithOptions(
+	ctx context.Context, prefix string, opts cloud.ListOptions, fn cloud.ListingFn,
+) error {
+	if opts.Reverse {
+		return cloud.ListByBuffering(ctx, f, prefix, opts, fn)
+	}
+	return cloud.ListOrdered(opts, fn, func(fn cloud.ListingFn) error {
+		return f.List(ctx, prefix, opts.Delimiter, fn)
+	})
+}
+
 // Delete implements the ExternalStorage interface and deletes the file from the
 // user scoped FileToTableSystem.
 func (f *fileTableStorage) Delete(ctx context.Context, basename string) error {
@@ -304,6 +318,12 @@ func (f *fileTableStorage) Delete(ctx context.Context, basename string) error {
 	return f.fs.DeleteFile(ctx, filepath)
 }
 
+// Copy implements the ExternalStorage interface and copies the file within the
+// user scoped FileToTableSystem.
+func (f *fileTableStorage) Copy(ctx context.Context, src, dst string) error {
+	return cloud.CopyFileByStreaming(ctx, f, src, dst)
+}
+
 // Size implements the ExternalStorage interface and returns the size of the
 // file stored in the user scoped FileToTableSystem.
 func (f *fileTableStorage) Size(ctx context.Context, basename string) (int64, error) {
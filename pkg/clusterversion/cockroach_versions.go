@@ -314,6 +314,55 @@ const (
 	// the process of upgrading from 22.2 to 23.1.
 	V23_1Start
 
+	// V23_1ExternalIOAuditTable adds the system.external_io_audit table.
+	V23_1ExternalIOAuditTable
+
+	// V23_1ScheduledBackupRunsTable adds the system.scheduled_backup_runs
+	// table.
+	V23_1ScheduledBackupRunsTable
+
+	// V23_1TableBackupCheckpointsTable adds the system.table_backup_checkpoints
+	// table.
+	V23_1TableBackupCheckpointsTable
+
+	// V23_1FineGrainedRestorePrivileges adds the RESTOREINTO database privilege
+	// and the BACKUPFROM external connection privilege.
+	V23_1FineGrainedRestorePrivileges
+
+	// V23_1StructuredLatestFiles writes the LATEST files of backup collections
+	// as LatestFile protos summarizing the chain they name, rather than as the
+	// bare subdir of that chain.
+	V23_1StructuredLatestFiles
+
+	// V23_1RestoreIndex is the version from which RESTORE INDEX can rebuild
+	// an index under an index ID other than the one it has in the backup.
+	V23_1RestoreIndex
+
+	// V23_1BackupManifestMinReaderVersion is the version from which backup
+	// manifests record the oldest version that can read them, and readers
+	// refuse the manifests that require a newer one.
+	V23_1BackupManifestMinReaderVersion
+
+	// V23_1BackupCompression is the version from which backups can compress
+	// their data and metadata with the codecs of the compression option, and
+	// backups to cloud storage default to zstd.
+	V23_1BackupCompression
+
+	// V23_1BackupDecryptQuorum is the version from which backups can be
+	// encrypted with decrypt_quorum, splitting their data key into shares that a
+	// quorum of their KMSs is needed to decrypt.
+	V23_1BackupDecryptQuorum
+
+	// V23_1BackupKMSByLocality is the version from which the files of each
+	// locality of a backup can be encrypted with a data key of their own, with the
+	// kms_by_locality option.
+	V23_1BackupKMSByLocality
+
+	// V23_1MVCCStatsEstimatedFields is the version from which MVCC stats record
+	// which of their fields contain estimates, in their estimated_fields, instead
+	// of all of them being treated as estimates when any is.
+	V23_1MVCCStatsEstimatedFields
+
 	// *************************************************
 	// Step (1): Add new versions here.
 	// Do not add new versions to a patch release.
@@ -512,6 +561,50 @@ var rawVersionsSingleton = keyedVersions{
 		Key:     V23_1Start,
 		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 2},
 	},
+	{
+		Key:     V23_1ExternalIOAuditTable,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 4},
+	},
+	{
+		Key:     V23_1ScheduledBackupRunsTable,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 6},
+	},
+	{
+		Key:     V23_1TableBackupCheckpointsTable,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 8},
+	},
+	{
+		Key:     V23_1FineGrainedRestorePrivileges,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 10},
+	},
+	{
+		Key:     V23_1StructuredLatestFiles,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 12},
+	},
+	{
+		Key:     V23_1RestoreIndex,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 14},
+	},
+	{
+		Key:     V23_1BackupManifestMinReaderVersion,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 16},
+	},
+	{
+		Key:     V23_1BackupCompression,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 18},
+	},
+	{
+		Key:     V23_1BackupDecryptQuorum,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 20},
+	},
+	{
+		Key:     V23_1BackupKMSByLocality,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 22},
+	},
+	{
+		Key:     V23_1MVCCStatsEstimatedFields,
+		Version: roachpb.Version{Major: 22, Minor: 2, Internal: 24},
+	},
 	// *************************************************
 	// Step (2): Add new versions here.
 	// Do not add new versions to a patch release.
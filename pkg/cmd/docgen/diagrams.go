@@ -373,6 +373,18 @@ var specs = []stmtSpec{
 		},
 		unlink: []string{"subdirectory", "collectionURI", "kmsURI"},
 	},
+	{
+		name:    "alter_backup_collection",
+		stmt:    "alter_backup_collection_stmt",
+		replace: map[string]string{"sconst_or_placeholder": "collectionURI"},
+		unlink:  []string{"collectionURI"},
+	},
+	{
+		name:    "alter_backup_schedules",
+		stmt:    "alter_backup_schedules_stmt",
+		replace: map[string]string{"sconst_or_placeholder": "collectionURI"},
+		unlink:  []string{"collectionURI"},
+	},
 	{
 		name:    "alter_backup_schedule",
 		replace: map[string]string{"iconst64": "schedule_id", "alter_backup_schedule_cmds": "options ( ',' options )*", "options": "'SET' ( 'LABEL' schedule_label | 'INTO' collectionURI | 'WITH' option | 'RECURRING' crontab | 'FULL BACKUP' ( crontab | 'ALWAYS' ) | 'SCHEDULE OPTION' schedule_option )"},
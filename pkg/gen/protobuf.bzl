@@ -17,6 +17,7 @@ PROTOBUF_SRCS = [
   "//pkg/ccl/utilccl/licenseccl:licenseccl_go_proto",
   "//pkg/cloud/cloudpb:cloudpb_go_proto",
   "//pkg/cloud/externalconn/connectionpb:connectionpb_go_proto",
+  "//pkg/cloud/storageplugin/storagepluginpb:storagepluginpb_go_proto",
   "//pkg/clusterversion:clusterversion_go_proto",
   "//pkg/config/zonepb:zonepb_go_proto",
   "//pkg/config:config_go_proto",
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"sync"
 
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalioaudit"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/multitenant"
@@ -394,6 +395,8 @@ func (r *Registry) runJob(
 		fmt.Sprintf("%s-%d", typ.String(), job.ID()), spanOptions...)
 	span.SetTag("job-id", attribute.Int64Value(int64(job.ID())))
 	defer span.Finish()
+	// Attribute the accesses to external storage made by the job to it.
+	ctx = externalioaudit.WithPrincipal(ctx, username, job.ID())
 	if span.TraceID() != 0 {
 		if err := job.Update(ctx, nil /* txn */, func(txn *kv.Txn, md JobMetadata,
 			ju *JobUpdater) error {
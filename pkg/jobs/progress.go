@@ -118,6 +118,18 @@ func (jpl *ChunkProgressLogger) Loop(ctx context.Context, chunkCh <-chan struct{
 	}
 }
 
+// Flush reports the progress made so far, however little or recently progress
+// was last reported, for example before the work is stopped to be restarted.
+// It must not be called concurrently with Loop.
+func (jpl *ChunkProgressLogger) Flush(ctx context.Context) error {
+	jpl.batcher.Lock()
+	completed := jpl.batcher.completed
+	jpl.batcher.reported = completed
+	jpl.batcher.lastReported = timeutil.Now()
+	jpl.batcher.Unlock()
+	return jpl.batcher.Report(ctx, completed)
+}
+
 // ProgressUpdateBatcher is a helper for tracking progress as it is made and
 // calling a progress update function when it has meaningfully advanced (e.g. by
 // more than 5%), while ensuring updates also are not done too often (by default
@@ -137,6 +137,9 @@ type SSTBatcher struct {
 	// disableScatters controls scatters of the as-we-fill split ranges.
 	disableScatters bool
 
+	// adaptivePacing is passed to AddSSTableRequest.AdaptivePacing.
+	adaptivePacing bool
+
 	// The rest of the fields accumulated state as opposed to configuration. Some,
 	// like totalRows, are accumulated _across_ batches and are not reset between
 	// batches when Reset() is called.
@@ -200,6 +203,7 @@ func MakeSSTBatcher(
 	disallowShadowingBelow hlc.Timestamp,
 	writeAtBatchTs bool,
 	scatterSplitRanges bool,
+	adaptivePacing bool,
 	mem mon.BoundAccount,
 	sendLimiter limit.ConcurrentRequestLimiter,
 ) (*SSTBatcher, error) {
@@ -210,6 +214,7 @@ func MakeSSTBatcher(
 		disallowShadowingBelow: disallowShadowingBelow,
 		writeAtBatchTS:         writeAtBatchTs,
 		disableScatters:        !scatterSplitRanges,
+		adaptivePacing:         adaptivePacing,
 		mem:                    mem,
 		limiter:                sendLimiter,
 	}
@@ -749,6 +754,7 @@ func (b *SSTBatcher) addSSTable(
 					MVCCStats:                              &item.stats,
 					IngestAsWrites:                         ingestAsWriteBatch,
 					ReturnFollowingLikelyNonEmptySpanStart: true,
+					AdaptivePacing:                         b.adaptivePacing,
 				}
 				if b.writeAtBatchTS {
 					req.SSTTimestampToRequestTimestamp = batchTS
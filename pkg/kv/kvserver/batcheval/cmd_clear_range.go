@@ -33,6 +33,10 @@ import (
 // and clearing them individually with engine.Clear.
 const ClearRangeBytesThreshold = 512 << 10 // 512KiB
 
+// totalStatsFields are the fields of MVCCStats.Total.
+const totalStatsFields = enginepb.MVCCStatsKeyBytes | enginepb.MVCCStatsValBytes |
+	enginepb.MVCCStatsRangeKeyBytes | enginepb.MVCCStatsRangeValBytes
+
 func init() {
 	RegisterReadWriteCommand(roachpb.ClearRange, declareKeysClearRange, ClearRange)
 }
@@ -128,12 +132,13 @@ func ClearRange(
 	// clearRangeBytesThreshold, clear the individual values with an iterator,
 	// instead of using a range tombstone (inefficient for small ranges).
 	//
-	// However, don't do this if the stats contain estimates -- this can only
-	// happen when we're clearing an entire range and we're using the existing
-	// range stats. We've seen cases where these estimates are wildly inaccurate
-	// (even negative), and it's better to drop an unnecessary range tombstone
-	// than to submit a huge write batch that'll get rejected by Raft.
-	if statsDelta.ContainsEstimates == 0 && statsDelta.Total() < ClearRangeBytesThreshold {
+	// However, don't do this if the size of the data to be cleared contains
+	// estimates -- this can only happen when we're clearing an entire range and
+	// we're using the existing range stats. We've seen cases where these
+	// estimates are wildly inaccurate (even negative), and it's better to drop
+	// an unnecessary range tombstone than to submit a huge write batch that'll
+	// get rejected by Raft.
+	if !statsDelta.HasEstimates(totalStatsFields) && statsDelta.Total() < ClearRangeBytesThreshold {
 		log.VEventf(ctx, 2, "delta=%d < threshold=%d; using non-range clear",
 			statsDelta.Total(), ClearRangeBytesThreshold)
 		err = readWriter.ClearMVCCIteratorRange(from, to, true /* pointKeys */, true /* rangeKeys */)
@@ -191,7 +196,8 @@ func computeStatsDelta(
 		}
 		// If we took the fast path but race is enabled, assert stats were correctly computed.
 		if entireRange {
-			computed.ContainsEstimates = delta.ContainsEstimates // retained for tests under race
+			// Retained for tests under race.
+			computed.ContainsEstimates, computed.EstimatedFields = delta.ContainsEstimates, delta.EstimatedFields
 			if !delta.Equal(computed) {
 				log.Fatalf(ctx, "fast-path MVCCStats computation gave wrong result: diff(fast, computed) = %s",
 					pretty.Diff(delta, computed))
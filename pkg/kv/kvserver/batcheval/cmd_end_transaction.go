@@ -18,6 +18,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/abortspan"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/batcheval/result"
@@ -29,6 +30,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/spanset"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/stateloader"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/util"
@@ -771,6 +773,8 @@ func RunCommitTrigger(
 // infer the other side's stats is only possible if the stats are fully accurate
 // (ContainsEstimates = 0). If they contain estimates, stats for both the LHS
 // and RHS are computed.
+// Splits of large ranges can instead estimate the stats of both sides from the
+// on-disk size of their keyspace, see splitStatsEstimationMode.
 //
 // Splits are complicated. A split is initiated when a replica receives an
 // AdminSplit request. Note that this request (and other "admin" requests)
@@ -970,9 +974,92 @@ func splitTrigger(
 		AbsPostSplitRightFn:      makeScanStatsFn(ctx, batch, ts, &split.RightDesc, "right hand side"),
 		ScanRightFirst:           splitScansRightForStatsFirst || emptyRHS,
 	}
+	// Scanning an empty right hand side is cheap, so only estimate the stats
+	// of splits that would otherwise scan a large left hand side.
+	sv := &rec.ClusterSettings().SV
+	if mode := splitStatsEstimationMode.Get(sv); mode != splitStatsEstimationOff && !h.ScanRightFirst &&
+		h.AbsPreSplitBothEstimated.Total() >= splitStatsEstimationMinBytes.Get(sv) {
+		h.EstimateFn = makeEstimateStatsFn(ctx, rec, batch, ts, split)
+		h.VerifyEstimates = mode == splitStatsEstimationVerify
+	}
 	return splitTriggerHelper(ctx, rec, batch, h, split, ts)
 }
 
+// The values of splitStatsEstimationMode.
+const (
+	splitStatsEstimationOff = iota
+	splitStatsEstimationOn
+	splitStatsEstimationVerify
+)
+
+// splitStatsEstimationMode controls whether split triggers estimate the stats
+// of the two sides of the split with storage.EstimateSplitStats instead of
+// scanning one of them.
+var splitStatsEstimationMode = settings.RegisterEnumSetting(
+	settings.SystemOnly,
+	"kv.split.mvcc_stats_estimation.mode",
+	"whether splits of large ranges estimate the MVCC stats of the resulting ranges from the "+
+		"on-disk size of either side instead of scanning one of them (on), or scan as usual and log "+
+		"the error of the estimates (verify); estimated stats are later recomputed by the "+
+		"consistency checker",
+	"off",
+	map[int64]string{
+		splitStatsEstimationOff:    "off",
+		splitStatsEstimationOn:     "on",
+		splitStatsEstimationVerify: "verify",
+	},
+)
+
+// splitStatsEstimationMinBytes is the size of a range below which its splits
+// scan to compute stats regardless of splitStatsEstimationMode.
+var splitStatsEstimationMinBytes = settings.RegisterByteSizeSetting(
+	settings.SystemOnly,
+	"kv.split.mvcc_stats_estimation.min_range_size",
+	"the size of a range below which its splits compute MVCC stats by scanning even if "+
+		"kv.split.mvcc_stats_estimation.mode is set",
+	512<<20, /* 512 MiB */
+)
+
+// makeEstimateStatsFn constructs a splitStatsEstimateFn which estimates the
+// stats of the user data of both sides of the split from the on-disk size of
+// their keyspace. The system stats of either side, which only account for its
+// few range-local and range-ID-local keys, are computed by scanning them.
+func makeEstimateStatsFn(
+	ctx context.Context,
+	rec EvalContext,
+	reader storage.Reader,
+	ts hlc.Timestamp,
+	split *roachpb.SplitTrigger,
+) splitStatsEstimateFn {
+	return func(ms enginepb.MVCCStats) (enginepb.MVCCStats, enginepb.MVCCStats, bool, error) {
+		left, right, ok, err := storage.EstimateSplitStats(rec.GetApproximateDiskBytes, ms,
+			split.LeftDesc.StartKey.AsRawKey(), split.RightDesc.StartKey.AsRawKey(),
+			split.RightDesc.EndKey.AsRawKey())
+		if err != nil {
+			return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, errors.Wrap(err,
+				"unable to estimate stats for ranges after split")
+		}
+		if !ok {
+			return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, nil
+		}
+		for _, side := range []struct {
+			ms   *enginepb.MVCCStats
+			desc *roachpb.RangeDescriptor
+		}{{&left, &split.LeftDesc}, {&right, &split.RightDesc}} {
+			for _, span := range rditer.MakeReplicatedKeySpansExcludingUserAndLockTable(side.desc) {
+				sysMS, err := storage.ComputeStats(reader, span.Key, span.EndKey, ts.WallTime)
+				if err != nil {
+					return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, errors.Wrap(err,
+						"unable to compute system stats for ranges after split")
+				}
+				side.ms.Add(sysMS)
+			}
+		}
+		log.Eventf(ctx, "estimated stats for both ranges")
+		return left, right, true, nil
+	}
+}
+
 // splitScansRightForStatsFirst controls whether the left hand side or the right
 // hand side of the split is scanned first on the leaseholder when evaluating
 // the split trigger. In practice, the splitQueue wants to scan the left hand
@@ -1034,7 +1121,7 @@ func splitTriggerHelper(
 	// modifications to the left hand side are allowed after this line and any
 	// modifications to the right hand side are accounted for by updating the
 	// helper's AbsPostSplitRight() reference.
-	h, err := makeSplitStatsHelper(statsInput)
+	h, err := makeSplitStatsHelper(ctx, statsInput)
 	if err != nil {
 		return enginepb.MVCCStats{}, result.Result{}, err
 	}
@@ -1139,6 +1226,11 @@ func splitTriggerHelper(
 		if err != nil {
 			return enginepb.MVCCStats{}, result.Result{}, errors.Wrap(err, "unable to load replica version")
 		}
+		// Like the stats delta of a proposal, the stats of the right hand side
+		// only record their estimated fields once all nodes know about them.
+		if !rec.ClusterSettings().Version.IsActive(ctx, clusterversion.V23_1MVCCStatsEstimatedFields) {
+			h.AbsPostSplitRight().EstimatedFields = 0
+		}
 		*h.AbsPostSplitRight(), err = stateloader.WriteInitialReplicaState(
 			ctx, batch, *h.AbsPostSplitRight(), split.RightDesc, rightLease,
 			*gcThreshold, *gcHint, replicaVersion, split.WriteGCHint,
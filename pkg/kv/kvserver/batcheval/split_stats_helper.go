@@ -10,7 +10,12 @@
 
 package batcheval
 
-import "github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
 
 // splitStatsHelper codifies and explains the stats computations related to a
 // split. The quantities known during a split (i.e. while the split trigger
@@ -106,6 +111,13 @@ import "github.com/cockroachdb/cockroach/pkg/storage/enginepb"
 // nonzero, we effectively have one more unknown in our linear system and we
 // need to recompute AbsPostSplitRight from scratch. (As fallout, we can in
 // principle compute CombinedError, but we don't care).
+//
+// Scanning either side is expensive for huge ranges, so the helper can instead
+// be given an EstimateFn, which apportions the left hand side of (1) between
+// AbsPostSplitLeft and AbsPostSplitRight without scanning. Both then contain
+// estimates, which the consistency checker eventually recomputes. With
+// VerifyEstimates, the stats are computed as if there were no EstimateFn and
+// the estimates are only checked against them.
 type splitStatsHelper struct {
 	in splitStatsHelperInput
 
@@ -117,6 +129,13 @@ type splitStatsHelper struct {
 // computed stats should not contain estimates.
 type splitStatsScanFn func() (enginepb.MVCCStats, error)
 
+// splitStatsEstimateFn estimates the stats of the left and right hand sides of
+// the split from the stats of the whole range, including the writes in the
+// batch. It returns ok=false if it cannot make an estimate.
+type splitStatsEstimateFn func(
+	ms enginepb.MVCCStats,
+) (left, right enginepb.MVCCStats, ok bool, err error)
+
 // splitStatsHelperInput is passed to makeSplitStatsHelper.
 type splitStatsHelperInput struct {
 	AbsPreSplitBothEstimated enginepb.MVCCStats
@@ -133,6 +152,12 @@ type splitStatsHelperInput struct {
 	// input stats contain estimates, this is the only side that needs to
 	// be scanned.
 	ScanRightFirst bool
+	// EstimateFn, if set, estimates the stats of both sides of the split
+	// instead of scanning either of them.
+	EstimateFn splitStatsEstimateFn
+	// VerifyEstimates, if set, computes the stats of both sides as if
+	// EstimateFn was unset, and checks the estimates against them.
+	VerifyEstimates bool
 }
 
 // makeSplitStatsHelper initializes a splitStatsHelper. The values in the input
@@ -140,7 +165,46 @@ type splitStatsHelperInput struct {
 // The provided AbsPostSplitLeftFn and AbsPostSplitRightFn recompute the left
 // and right hand sides of the split after accounting for the split trigger
 // batch. Each are only invoked at most once, and only when necessary.
-func makeSplitStatsHelper(input splitStatsHelperInput) (splitStatsHelper, error) {
+func makeSplitStatsHelper(
+	ctx context.Context, input splitStatsHelperInput,
+) (splitStatsHelper, error) {
+	if input.EstimateFn == nil {
+		return makeComputedSplitStatsHelper(input)
+	}
+
+	ms := input.AbsPreSplitBothEstimated
+	ms.Add(input.DeltaBatchEstimated)
+	ms.Add(input.DeltaRangeKey)
+	estimatedLeft, estimatedRight, ok, err := input.EstimateFn(ms)
+	if err != nil {
+		return splitStatsHelper{}, err
+	}
+	if !ok {
+		return makeComputedSplitStatsHelper(input)
+	}
+	// The left hand side is the pre-split range, so carry over its estimates
+	// such that DeltaPostSplitLeft() adds estimates rather than removing them.
+	estimatedLeft.ContainsEstimates += input.AbsPreSplitBothEstimated.ContainsEstimates
+	if !input.VerifyEstimates {
+		return splitStatsHelper{
+			in:                input,
+			absPostSplitLeft:  &estimatedLeft,
+			absPostSplitRight: &estimatedRight,
+		}, nil
+	}
+
+	h, err := makeComputedSplitStatsHelper(input)
+	if err != nil {
+		return splitStatsHelper{}, err
+	}
+	verifySplitStatsEstimates(ctx, input, estimatedLeft, estimatedRight,
+		*h.absPostSplitLeft, *h.absPostSplitRight)
+	return h, nil
+}
+
+// makeComputedSplitStatsHelper initializes a splitStatsHelper whose stats are
+// computed by scanning one or both sides of the split.
+func makeComputedSplitStatsHelper(input splitStatsHelperInput) (splitStatsHelper, error) {
 	h := splitStatsHelper{
 		in: input,
 	}
@@ -194,6 +258,45 @@ func makeSplitStatsHelper(input splitStatsHelperInput) (splitStatsHelper, error)
 	return h, nil
 }
 
+// verifySplitStatsEstimates checks the estimated stats of the two sides of a
+// split against their computed stats. Estimates are expected to be off, so it
+// only logs by how much, but the estimates have to add up to the computed
+// stats unless the pre-split stats contained estimates, and it logs an error
+// if they don't. The split goes ahead with the computed stats either way.
+func verifySplitStatsEstimates(
+	ctx context.Context,
+	input splitStatsHelperInput,
+	estimatedLeft, estimatedRight, computedLeft, computedRight enginepb.MVCCStats,
+) {
+	log.Infof(ctx, "estimated split stats: left %d live bytes, %d keys (computed %d, %d); "+
+		"right %d live bytes, %d keys (computed %d, %d)",
+		estimatedLeft.LiveBytes, estimatedLeft.KeyCount,
+		computedLeft.LiveBytes, computedLeft.KeyCount,
+		estimatedRight.LiveBytes, estimatedRight.KeyCount,
+		computedRight.LiveBytes, computedRight.KeyCount)
+
+	if input.AbsPreSplitBothEstimated.ContainsEstimates != 0 ||
+		input.DeltaBatchEstimated.ContainsEstimates != 0 {
+		return
+	}
+	estimated := estimatedLeft
+	estimated.Add(estimatedRight)
+	computed := computedLeft
+	computed.Add(computedRight)
+	nowNanos := estimated.LastUpdateNanos
+	if computed.LastUpdateNanos > nowNanos {
+		nowNanos = computed.LastUpdateNanos
+	}
+	estimated.AgeTo(nowNanos)
+	computed.AgeTo(nowNanos)
+	estimated.ContainsEstimates, computed.ContainsEstimates = 0, 0
+	estimated.EstimatedFields, computed.EstimatedFields = 0, 0
+	if !estimated.Equal(computed) {
+		log.Errorf(ctx, "estimated split stats %+v do not add up to computed stats %+v",
+			estimated, computed)
+	}
+}
+
 // AbsPostSplitRight returns the stats of the right hand side created by the
 // split. The result is returned as a pointer because the caller can freely
 // modify it, assuming they're adding only stats corresponding to mutations that
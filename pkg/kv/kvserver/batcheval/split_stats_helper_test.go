@@ -0,0 +1,108 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package batcheval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatsHelperEstimates(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	computedLeft := enginepb.MVCCStats{
+		LiveBytes: 300, KeyBytes: 100, ValBytes: 200, LiveCount: 3, KeyCount: 3, ValCount: 3,
+	}
+	computedRight := enginepb.MVCCStats{
+		LiveBytes: 700, KeyBytes: 300, ValBytes: 400, LiveCount: 7, KeyCount: 7, ValCount: 7,
+	}
+	pre := computedLeft
+	pre.Add(computedRight)
+
+	var scans int
+	scanFn := func(ms enginepb.MVCCStats) splitStatsScanFn {
+		return func() (enginepb.MVCCStats, error) {
+			scans++
+			return ms, nil
+		}
+	}
+	estimateFn := func(ms enginepb.MVCCStats) (enginepb.MVCCStats, enginepb.MVCCStats, bool, error) {
+		left, right := ms.EstimateSplit(0.5)
+		return left, right, true, nil
+	}
+	makeInput := func() splitStatsHelperInput {
+		scans = 0
+		return splitStatsHelperInput{
+			AbsPreSplitBothEstimated: pre,
+			AbsPostSplitLeftFn:       scanFn(computedLeft),
+			AbsPostSplitRightFn:      scanFn(computedRight),
+			EstimateFn:               estimateFn,
+		}
+	}
+
+	// With an estimate, neither side is scanned.
+	h, err := makeSplitStatsHelper(ctx, makeInput())
+	require.NoError(t, err)
+	require.Zero(t, scans)
+	require.EqualValues(t, 500, h.AbsPostSplitRight().LiveBytes)
+	require.EqualValues(t, 1, h.AbsPostSplitRight().ContainsEstimates)
+	delta := h.DeltaPostSplitLeft()
+	require.EqualValues(t, -500, delta.LiveBytes)
+	require.EqualValues(t, 1, delta.ContainsEstimates)
+
+	// Without one, the helper falls back to scanning.
+	input := makeInput()
+	input.EstimateFn = func(enginepb.MVCCStats) (enginepb.MVCCStats, enginepb.MVCCStats, bool, error) {
+		return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, nil
+	}
+	h, err = makeSplitStatsHelper(ctx, input)
+	require.NoError(t, err)
+	require.Equal(t, 1, scans)
+	require.Equal(t, computedRight, *h.AbsPostSplitRight())
+
+	// Verification computes the stats and uses them.
+	input = makeInput()
+	input.VerifyEstimates = true
+	h, err = makeSplitStatsHelper(ctx, input)
+	require.NoError(t, err)
+	require.Equal(t, 1, scans)
+	require.Equal(t, computedRight, *h.AbsPostSplitRight())
+	require.Zero(t, h.DeltaPostSplitLeft().ContainsEstimates)
+
+	// Estimates which do not add up don't fail the split either, which uses the
+	// computed stats.
+	input = makeInput()
+	input.VerifyEstimates = true
+	input.EstimateFn = func(ms enginepb.MVCCStats) (enginepb.MVCCStats, enginepb.MVCCStats, bool, error) {
+		left, right := ms.EstimateSplit(0.5)
+		right.LiveBytes++
+		return left, right, true, nil
+	}
+	h, err = makeSplitStatsHelper(ctx, input)
+	require.NoError(t, err)
+	require.Equal(t, computedRight, *h.AbsPostSplitRight())
+
+	// Estimation errors are returned.
+	input = makeInput()
+	input.EstimateFn = func(enginepb.MVCCStats) (enginepb.MVCCStats, enginepb.MVCCStats, bool, error) {
+		return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, errors.New("boom")
+	}
+	_, err = makeSplitStatsHelper(ctx, input)
+	require.EqualError(t, err, "boom")
+}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv"
@@ -47,6 +48,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/bootstrap"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/kvclientutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
@@ -5005,6 +5007,68 @@ func TestRangeMigration(t *testing.T) {
 	assertVersion(endV)
 }
 
+// TestMVCCStatsEstimatedFieldsVersionGate checks that the stats of a range
+// only record which of their fields contain estimates once
+// V23_1MVCCStatsEstimatedFields is active, since the replicas on nodes which
+// don't know about the estimated fields would drop them.
+func TestMVCCStatsEstimatedFieldsVersionGate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	beforeV := clusterversion.ByKey(clusterversion.V23_1MVCCStatsEstimatedFields - 1)
+	tc := testcluster.StartTestCluster(t, 1, base.TestClusterArgs{
+		ReplicationMode: base.ReplicationManual,
+		ServerArgs: base.TestServerArgs{
+			Settings: cluster.MakeTestingClusterSettingsWithVersions(
+				clusterversion.TestingBinaryVersion, beforeV, false /* initializeVersion */),
+			Knobs: base.TestingKnobs{
+				Server: &server.TestingKnobs{
+					BinaryVersionOverride:          beforeV,
+					DisableAutomaticVersionUpgrade: make(chan struct{}),
+				},
+			},
+		},
+	})
+	defer tc.Stopper().Stop(ctx)
+
+	key := tc.ScratchRange(t)
+	repl := tc.GetFirstStoreFromServer(t, 0).LookupReplica(roachpb.RKey(key))
+	sender := tc.Servers[0].DB().NonTransactionalSender()
+	// A merge into a user key estimates its live and value bytes.
+	merge := func() enginepb.MVCCStats {
+		_, pErr := kv.SendWrapped(ctx, sender, &roachpb.MergeRequest{
+			RequestHeader: roachpb.RequestHeader{Key: key},
+			Value:         roachpb.MakeValueFromString("a"),
+		})
+		require.NoError(t, pErr.GoError())
+		return repl.GetMVCCStats()
+	}
+	recompute := func() {
+		_, pErr := kv.SendWrapped(ctx, sender, &roachpb.RecomputeStatsRequest{
+			RequestHeader: roachpb.RequestHeader{Key: key},
+		})
+		require.NoError(t, pErr.GoError())
+		require.Zero(t, repl.GetMVCCStats().ContainsEstimates)
+	}
+
+	recompute()
+	ms := merge()
+	require.NotZero(t, ms.ContainsEstimates)
+	require.Zero(t, ms.EstimatedFields)
+	require.Equal(t, enginepb.AllMVCCStatsFields, ms.Estimates())
+
+	_, err := tc.Conns[0].ExecContext(ctx, `SET CLUSTER SETTING version = $1`,
+		clusterversion.ByKey(clusterversion.V23_1MVCCStatsEstimatedFields).String())
+	require.NoError(t, err)
+
+	recompute()
+	ms = merge()
+	require.NotZero(t, ms.ContainsEstimates)
+	require.Equal(t, enginepb.MVCCStatsLiveBytes|enginepb.MVCCStatsValBytes, ms.EstimatedFields)
+	require.False(t, ms.HasEstimates(enginepb.MVCCStatsKeyCount))
+}
+
 func TestRaftSchedulerPrioritizesNodeLiveness(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -1527,6 +1527,18 @@ throttled they do count towards 'delay.total' and 'delay.enginebackpressure'.
 		Measurement: "Nanoseconds",
 		Unit:        metric.Unit_NANOSECONDS,
 	}
+	metaAddSSTableEvalPacingDelay = metric.Metadata{
+		Name:        "addsstable.delay.adaptivepacing",
+		Help:        "Amount by which evaluation of adaptively paced AddSSTable requests was delayed by L0 sublevels or compaction debt",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaAddSSTablePaced = metric.Metadata{
+		Name:        "addsstable.paced",
+		Help:        "Number of adaptively paced AddSSTable requests that were delayed by L0 sublevels or compaction debt",
+		Measurement: "Ingestions",
+		Unit:        metric.Unit_COUNT,
+	}
 
 	// Export request counter.
 	metaExportEvalTotalDelay = metric.Metadata{
@@ -1933,6 +1945,8 @@ type StoreMetrics struct {
 	AddSSTableAsWrites            *metric.Counter
 	AddSSTableProposalTotalDelay  *metric.Counter
 	AddSSTableProposalEngineDelay *metric.Counter
+	AddSSTableProposalPacingDelay *metric.Counter
+	AddSSTablePaced               *metric.Counter
 
 	// Export request stats.
 	ExportRequestProposalTotalDelay *metric.Counter
@@ -2469,6 +2483,8 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		AddSSTableApplicationCopies:   metric.NewCounter(metaAddSSTableApplicationCopies),
 		AddSSTableProposalTotalDelay:  metric.NewCounter(metaAddSSTableEvalTotalDelay),
 		AddSSTableProposalEngineDelay: metric.NewCounter(metaAddSSTableEvalEngineDelay),
+		AddSSTableProposalPacingDelay: metric.NewCounter(metaAddSSTableEvalPacingDelay),
+		AddSSTablePaced:               metric.NewCounter(metaAddSSTablePaced),
 
 		// ExportRequest proposal.
 		ExportRequestProposalTotalDelay: metric.NewCounter(metaExportEvalTotalDelay),
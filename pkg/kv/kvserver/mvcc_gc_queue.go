@@ -400,8 +400,8 @@ func makeMVCCGCQueueScoreImpl(
 	// If we GC'ed now, we can expect to delete at least this much GCByteAge.
 	// GCByteAge - TTL*GCBytes = ExpMinGCByteAgeReduction & algebra.
 	//
-	// Note that for ranges with ContainsEstimates > 0, the value here may not
-	// reflect reality, and may even be nonsensical (though that's unlikely).
+	// Note that for ranges whose GC bytes contain estimates, the value here may
+	// not reflect reality, and may even be nonsensical (though that's unlikely).
 	r.ExpMinGCByteAgeReduction = r.GCByteAge - r.GCBytes*int64(r.TTL.Seconds())
 
 	// DeadFraction is close to 1 when most values are dead, and close to zero
@@ -467,10 +467,10 @@ func makeMVCCGCQueueScoreImpl(
 	maybeRangeDel := suspectedFullRangeDeletion(ms)
 	hasActiveGCHint := gcHintedRangeDelete(hint, gcTTL, now)
 
-	if hasActiveGCHint && (maybeRangeDel || ms.ContainsEstimates > 0) {
+	if hasActiveGCHint && (maybeRangeDel || ms.HasEstimates(rangeDelHeuristicFields)) {
 		// We have GC hint allowing us to collect range and we either satisfy
-		// heuristic that indicate no live data or we have estimates and we assume
-		// hint is correct.
+		// heuristic that indicate no live data or the heuristic relies on
+		// estimates and we assume hint is correct.
 		r.ShouldQueue = canAdvanceGCThreshold
 		r.FinalScore = deleteRangePriority
 	}
@@ -492,6 +492,10 @@ func gcHintedRangeDelete(hint roachpb.GCHint, ttl time.Duration, now hlc.Timesta
 	return deleteTimestamp.Add(ttl.Nanoseconds(), 0).Less(now)
 }
 
+// rangeDelHeuristicFields are the stats suspectedFullRangeDeletion relies on.
+const rangeDelHeuristicFields = enginepb.MVCCStatsLiveCount | enginepb.MVCCStatsIntentCount |
+	enginepb.MVCCStatsRangeKeyCount
+
 // suspectedFullRangeDeletion checks for ranges where there's no live data and
 // range tombstones are present. This is an indication that range is likely
 // removed by bulk operations and its garbage collection should be done faster
@@ -332,6 +332,10 @@ type Replica struct {
 	// Contains the lease history when enabled.
 	leaseHistory *leaseHistory
 
+	// Contains the most recent MVCC stats deltas applied to the replica when
+	// kv.replica.stats_delta_log.size is set.
+	statsDeltaLog statsDeltaLog
+
 	// concMgr sequences incoming requests and provides isolation between
 	// requests that intend to perform conflicting operations. It is the
 	// centerpiece of transaction contention handling.
@@ -1301,6 +1305,12 @@ func (r *Replica) State(ctx context.Context) kvserverpb.RangeInfo {
 		})
 		ri.PausedReplicas = sl
 	}
+	if statsDeltaLogSize.Get(&r.store.cfg.Settings.SV) > 0 {
+		ri.StatsDeltaLog = r.statsDeltaLog.get()
+	} else {
+		// Free the memory held by the log once recording is disabled.
+		r.statsDeltaLog.reset()
+	}
 	return ri
 }
 
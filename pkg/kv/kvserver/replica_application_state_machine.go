@@ -945,6 +945,19 @@ func (b *replicaAppBatch) stageTrivialReplicatedEvalResult(
 	// serialize on the stats key.
 	deltaStats := res.Delta.ToStats()
 	b.state.Stats.Add(deltaStats)
+	if n := statsDeltaLogSize.Get(&b.r.store.cfg.Settings.SV); n > 0 &&
+		deltaStats != (enginepb.MVCCStats{}) {
+		entry := kvserverpb.StatsDeltaLogEntry{
+			CmdID:             []byte(cmd.idKey),
+			RaftAppliedIndex:  cmd.ent.Index,
+			LeaseAppliedIndex: cmd.leaseIndex,
+			Delta:             deltaStats,
+		}
+		if b.state.Lease != nil && b.state.Lease.Sequence == cmd.raftCmd.ProposerLeaseSequence {
+			entry.Proposer = b.state.Lease.Replica
+		}
+		b.r.statsDeltaLog.add(int(n), entry)
+	}
 
 	if res.State != nil && res.State.GCHint != nil {
 		b.r.handleGCHintResult(ctx, res.State.GCHint)
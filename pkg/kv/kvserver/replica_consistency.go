@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/batcheval"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
@@ -48,7 +49,8 @@ import (
 //     (i.e. byte-by-byte identical)
 //   - the (identical) stats tracked in them do not correspond to a recomputation
 //     via the data, i.e. the stats were incorrect
-//   - ContainsEstimates==false, i.e. the stats claimed they were correct.
+//   - the stats claimed the mismatching fields were correct, i.e. they didn't
+//     mark them as estimates (see MVCCStats.Estimates).
 //
 // Before issuing the fatal error, the cluster bootstrap version is verified.
 // Note that on clusters that originally got bootstrapped on older releases
@@ -175,18 +177,19 @@ func (r *Replica) checkConsistencyImpl(
 		d2.AgeTo(0)
 		haveDelta = d2 != enginepb.MVCCStats{}
 	}
+	estimatedFields := r.ClusterSettings().Version.IsActive(ctx, clusterversion.V23_1MVCCStatsEstimatedFields)
 
 	res.StartKey = []byte(args.Key)
 	res.Status = roachpb.CheckConsistencyResponse_RANGE_CONSISTENT
 	if minoritySHA != "" {
 		res.Status = roachpb.CheckConsistencyResponse_RANGE_INCONSISTENT
 	} else if args.Mode != roachpb.ChecksumMode_CHECK_STATS && haveDelta {
-		if delta.ContainsEstimates > 0 {
-			// When ContainsEstimates is set, it's generally expected that we'll get a different
-			// result when we recompute from scratch.
+		if statsDeltaIsEstimated(delta, estimatedFields) {
+			// When the fields that differ are marked as estimates, it's generally expected that
+			// we'll get a different result when we recompute from scratch.
 			res.Status = roachpb.CheckConsistencyResponse_RANGE_CONSISTENT_STATS_ESTIMATED
 		} else {
-			// When ContainsEstimates is unset, we expect the recomputation to agree with the stored stats.
+			// Fields which aren't marked as estimates are expected to agree with the recomputation.
 			// If that's not the case, that's a problem: it could be a bug in the stats computation
 			// or stats maintenance, but it could also hint at the replica having diverged from its peers.
 			res.Status = roachpb.CheckConsistencyResponse_RANGE_CONSISTENT_STATS_INCORRECT
@@ -217,9 +220,9 @@ func (r *Replica) checkConsistencyImpl(
 		if !haveDelta {
 			return resp, nil
 		}
-		if delta.ContainsEstimates <= 0 && fatalOnStatsMismatch {
+		if !statsDeltaIsEstimated(delta, estimatedFields) && fatalOnStatsMismatch {
 			// We just found out that the recomputation doesn't match the persisted stats,
-			// so ContainsEstimates should have been strictly positive.
+			// so the fields that differ should have been marked as estimates.
 			log.Fatalf(ctx, "found a delta of %+v", redact.Safe(delta))
 		}
 
@@ -457,6 +460,18 @@ func (*Replica) checksumInitialWait(ctx context.Context) time.Duration {
 	return wait
 }
 
+// statsDeltaIsEstimated returns whether the delta between the persisted and the
+// recomputed stats of a range is confined to the fields which the persisted
+// stats marked as estimates. Other fields are expected to be exact. Until
+// estimatedFields, i.e. until V23_1MVCCStatsEstimatedFields is active, stats
+// with estimates may be off in any field.
+func statsDeltaIsEstimated(delta enginepb.MVCCStats, estimatedFields bool) bool {
+	if !estimatedFields {
+		return delta.ContainsEstimates > 0
+	}
+	return delta.ContainsEstimates > 0 && delta.NonZeroFields()&^delta.Estimates() == 0
+}
+
 // computeChecksumDone sends the checksum computation result to the receiver.
 func (*Replica) computeChecksumDone(rc *replicaChecksum, result *replicaHash) {
 	var c CollectChecksumResponse
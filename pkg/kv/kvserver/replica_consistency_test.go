@@ -21,6 +21,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/echotest"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -229,3 +230,34 @@ func TestReplicaChecksumSHA512(t *testing.T) {
 
 	echotest.Require(t, sb.String(), testutils.TestDataPath(t, "replica_consistency_sha512"))
 }
+
+func TestStatsDeltaIsEstimated(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	persisted := enginepb.MVCCStats{
+		ContainsEstimates: 2,
+		EstimatedFields:   enginepb.MVCCStatsSysBytes,
+		LiveBytes:         100,
+		SysBytes:          50,
+	}
+	delta := func(recomputed enginepb.MVCCStats) enginepb.MVCCStats {
+		d := persisted
+		d.Subtract(recomputed)
+		return d
+	}
+
+	// A mismatch in the estimated fields is expected.
+	require.True(t, statsDeltaIsEstimated(delta(enginepb.MVCCStats{LiveBytes: 100, SysBytes: 40}), true))
+	// A mismatch in the exact fields is not, even if others are estimated.
+	require.False(t, statsDeltaIsEstimated(delta(enginepb.MVCCStats{LiveBytes: 90, SysBytes: 50}), true))
+	// Unless the estimated fields aren't relied upon yet.
+	require.True(t, statsDeltaIsEstimated(delta(enginepb.MVCCStats{LiveBytes: 90, SysBytes: 50}), false))
+	// Neither is any mismatch when the stats contain no estimates.
+	persisted.ContainsEstimates, persisted.EstimatedFields = 0, 0
+	require.False(t, statsDeltaIsEstimated(delta(enginepb.MVCCStats{LiveBytes: 100, SysBytes: 40}), true))
+	require.False(t, statsDeltaIsEstimated(delta(enginepb.MVCCStats{LiveBytes: 100, SysBytes: 40}), false))
+	// Stats which don't say which fields are estimated may be off in any.
+	persisted.ContainsEstimates = 2
+	require.True(t, statsDeltaIsEstimated(delta(enginepb.MVCCStats{LiveBytes: 90, SysBytes: 40}), true))
+}
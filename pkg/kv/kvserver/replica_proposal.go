@@ -16,6 +16,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/batcheval/result"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/concurrency"
@@ -722,6 +723,13 @@ func (r *Replica) evaluateProposal(
 			res.Replicated.WriteTimestamp = ba.WriteTimestamp()
 		}
 		res.Replicated.Delta = ms.ToStatsDelta()
+		// Nodes which don't know about the estimated fields drop them from the
+		// stats they apply the delta to, and so would diverge from those that
+		// don't. Until all nodes know about them, every field of a delta with
+		// estimates is treated as estimated.
+		if !r.ClusterSettings().Version.IsActive(ctx, clusterversion.V23_1MVCCStatsEstimatedFields) {
+			res.Replicated.Delta.EstimatedFields = 0
+		}
 
 		// This is the result of a migration. See the field for more details.
 		if res.Replicated.Delta.ContainsEstimates > 0 {
@@ -0,0 +1,84 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// statsDeltaLogSize controls if replicas record the MVCC stats deltas of the
+// commands they apply, and how many of them each replica retains. It is meant
+// to be enabled temporarily to track down the command responsible for stats
+// drift detected by the consistency checker; see `cockroach debug
+// range-stats-log`.
+var statsDeltaLogSize = settings.RegisterIntSetting(
+	settings.SystemOnly,
+	"kv.replica.stats_delta_log.size",
+	"the number of most recent MVCC stats deltas recorded by each replica for "+
+		"debugging stats inconsistencies; 0 disables recording",
+	0,
+	settings.NonNegativeInt,
+)
+
+// statsDeltaLog is a circular buffer of the most recent MVCC stats deltas
+// applied to a replica.
+type statsDeltaLog struct {
+	syncutil.Mutex
+	index   int
+	entries []kvserverpb.StatsDeltaLogEntry // A circular buffer with index.
+}
+
+// add records entry, retaining at most maxEntries entries. The buffer is reset
+// whenever maxEntries changes.
+func (l *statsDeltaLog) add(maxEntries int, entry kvserverpb.StatsDeltaLogEntry) {
+	l.Lock()
+	defer l.Unlock()
+
+	if cap(l.entries) != maxEntries {
+		l.entries = make([]kvserverpb.StatsDeltaLogEntry, 0, maxEntries)
+		l.index = 0
+	}
+	// Not through the first pass through the buffer.
+	if l.index == len(l.entries) {
+		l.entries = append(l.entries, entry)
+	} else {
+		l.entries[l.index] = entry
+	}
+	l.index++
+	if l.index >= maxEntries {
+		l.index = 0
+	}
+}
+
+// get returns the recorded entries, oldest first.
+func (l *statsDeltaLog) get() []kvserverpb.StatsDeltaLogEntry {
+	l.Lock()
+	defer l.Unlock()
+	if len(l.entries) == 0 {
+		return nil
+	}
+	first := l.entries[l.index:]
+	second := l.entries[:l.index]
+	result := make([]kvserverpb.StatsDeltaLogEntry, len(first)+len(second))
+	copy(result, first)
+	copy(result[len(first):], second)
+	return result
+}
+
+// reset discards the recorded entries.
+func (l *statsDeltaLog) reset() {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = nil
+	l.index = 0
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsDeltaLog(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const maxEntries = 3
+	var l statsDeltaLog
+	require.Nil(t, l.get())
+
+	indexes := func(entries []kvserverpb.StatsDeltaLogEntry) []uint64 {
+		var res []uint64
+		for _, e := range entries {
+			res = append(res, e.RaftAppliedIndex)
+		}
+		return res
+	}
+	add := func(maxEntries int, index uint64) {
+		l.add(maxEntries, kvserverpb.StatsDeltaLogEntry{RaftAppliedIndex: index})
+	}
+
+	add(maxEntries, 1)
+	add(maxEntries, 2)
+	require.Equal(t, []uint64{1, 2}, indexes(l.get()))
+
+	// Overflow the circular buffer.
+	add(maxEntries, 3)
+	add(maxEntries, 4)
+	add(maxEntries, 5)
+	entries := l.get()
+	require.Equal(t, []uint64{3, 4, 5}, indexes(entries))
+	require.NotSame(t, &l.entries[0], &entries[0], "expected slice copy")
+
+	// Changing the size starts over.
+	add(maxEntries+1, 6)
+	require.Equal(t, []uint64{6}, indexes(l.get()))
+
+	l.reset()
+	require.Nil(t, l.get())
+}
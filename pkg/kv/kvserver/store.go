@@ -3992,9 +3992,12 @@ func (n *KVAdmissionControllerImpl) AdmitKVWork(
 		}
 	}
 	if admissionEnabled {
-		if ba.IsSingleExportRequest() {
+		if ba.IsSingleExportRequest() &&
+			(bypassAdmission || admissionInfo.Priority < admissionpb.NormalPri) {
 			// Backups generate batches with single export requests, which we
-			// admit through the elastic CPU work queue. We grant this
+			// admit through the elastic CPU work queue unless the backup asked
+			// to run at high priority, in which case they are admitted like
+			// any other foreground work. We grant this
 			// CPU-intensive work a set amount of CPU time and expect it to
 			// terminate (cooperatively) once it exceeds its grant. The amount
 			// disbursed is 100ms, which we've experimentally found to be long
@@ -0,0 +1,116 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/pebble"
+)
+
+// ingestPacingL0SublevelThreshold is the L0 sublevel count at which an
+// AddSSTable request with AdaptivePacing set is held until compactions catch
+// up. Pacing starts at half of it.
+var ingestPacingL0SublevelThreshold = settings.RegisterIntSetting(
+	settings.TenantWritable,
+	"kv.bulk_io_write.adaptive_pacing.l0_sublevel_threshold",
+	"number of L0 sublevels at which adaptively paced SST ingestions, such as those of RESTORE, "+
+		"are held until compactions catch up; they are slowed down from half of it",
+	10,
+	settings.PositiveInt,
+)
+
+// ingestPacingCompactionDebtThreshold is the estimated compaction debt at
+// which an AddSSTable request with AdaptivePacing set is held until
+// compactions catch up. Pacing starts at half of it.
+var ingestPacingCompactionDebtThreshold = settings.RegisterByteSizeSetting(
+	settings.TenantWritable,
+	"kv.bulk_io_write.adaptive_pacing.compaction_debt_threshold",
+	"estimated compaction debt at which adaptively paced SST ingestions, such as those of RESTORE, "+
+		"are held until compactions catch up; they are slowed down from half of it",
+	16<<30, // 16 GiB
+	settings.PositiveInt,
+)
+
+// ingestPacingMaxDelay bounds the delay of a single AddSSTable request with
+// AdaptivePacing set.
+var ingestPacingMaxDelay = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"kv.bulk_io_write.adaptive_pacing.max_delay",
+	"maximum amount of time an adaptively paced SST ingestion is delayed",
+	30*time.Second,
+	settings.NonNegativeDuration,
+)
+
+// ingestPacingPollInterval is how often the engine metrics are re-read while
+// an ingestion is held, so that it proceeds soon after compactions catch up.
+const ingestPacingPollInterval = time.Second
+
+// calculateIngestPacingDelay returns how long to delay an adaptively paced
+// ingestion given the current metrics of the engine. The store's pressure is
+// the larger of its L0 sublevel count and its compaction debt, relative to
+// their thresholds: there is no delay below half of the thresholds, and the
+// delay grows linearly to maxDelay as the pressure approaches them.
+func calculateIngestPacingDelay(
+	l0Threshold int64, debtThreshold int64, maxDelay time.Duration, metrics *pebble.Metrics,
+) time.Duration {
+	l0ReadAmp := metrics.Levels[0].NumFiles
+	if metrics.Levels[0].Sublevels >= 0 {
+		l0ReadAmp = int64(metrics.Levels[0].Sublevels)
+	}
+	pressure := float64(l0ReadAmp) / float64(l0Threshold)
+	if debt := float64(metrics.Compact.EstimatedDebt) / float64(debtThreshold); debt > pressure {
+		pressure = debt
+	}
+	if pressure <= 0.5 {
+		return 0
+	}
+	if pressure >= 1 {
+		return maxDelay
+	}
+	return time.Duration((pressure - 0.5) * 2 * float64(maxDelay))
+}
+
+// paceIngestion delays an AddSSTable request with AdaptivePacing set for as
+// long as the engine's L0 sublevel count and compaction debt call for, up to
+// kv.bulk_io_write.adaptive_pacing.max_delay, and returns how long it waited.
+func (s *Store) paceIngestion(ctx context.Context) time.Duration {
+	sv := &s.cfg.Settings.SV
+	maxDelay := ingestPacingMaxDelay.Get(sv)
+	start := timeutil.Now()
+	for {
+		metrics := s.engine.GetMetrics()
+		delay := calculateIngestPacingDelay(
+			ingestPacingL0SublevelThreshold.Get(sv),
+			ingestPacingCompactionDebtThreshold.Get(sv),
+			maxDelay, metrics.Metrics,
+		)
+		waited := timeutil.Since(start)
+		if delay <= waited {
+			return waited
+		}
+		log.VEventf(ctx, 2, "pacing SST ingestion for %s: %d L0 sublevels, %d bytes of compaction debt",
+			delay-waited, metrics.Levels[0].Sublevels, metrics.Compact.EstimatedDebt)
+		wait := delay - waited
+		if wait > ingestPacingPollInterval {
+			wait = ingestPacingPollInterval
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return timeutil.Since(start)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestPacingDelay(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const l0Threshold, debtThreshold, max = 10, 1000, 10 * time.Second
+
+	for _, tc := range []struct {
+		exp           time.Duration
+		fileCount     int64
+		sublevelCount int32
+		debt          uint64
+	}{
+		{0, 0, 0, 0},
+		{0, 5, -1, 0},
+		{2 * time.Second, 6, -1, 0},
+		{2 * time.Second, 20, 6, 0},
+		{6 * time.Second, 0, 0, 800},
+		{6 * time.Second, 0, 6, 800},
+		{max, 10, -1, 0},
+		{max, 0, 0, 2000},
+	} {
+		var m pebble.Metrics
+		m.Levels[0].NumFiles = tc.fileCount
+		m.Levels[0].Sublevels = tc.sublevelCount
+		m.Compact.EstimatedDebt = tc.debt
+		require.Equal(t, tc.exp, calculateIngestPacingDelay(l0Threshold, debtThreshold, max, &m))
+	}
+}
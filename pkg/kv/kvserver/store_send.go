@@ -349,14 +349,22 @@ func (s *Store) maybeThrottleBatch(
 
 		beforeEngineDelay := timeutil.Now()
 		s.engine.PreIngestDelay(ctx)
+		var waitedPacing time.Duration
+		if t.AdaptivePacing {
+			waitedPacing = s.paceIngestion(ctx)
+			if waitedPacing > 0 {
+				s.metrics.AddSSTablePaced.Inc(1)
+				s.metrics.AddSSTableProposalPacingDelay.Inc(waitedPacing.Nanoseconds())
+			}
+		}
 		after := timeutil.Now()
 
-		waited, waitedEngine := after.Sub(before), after.Sub(beforeEngineDelay)
+		waited, waitedEngine := after.Sub(before), after.Sub(beforeEngineDelay)-waitedPacing
 		s.metrics.AddSSTableProposalTotalDelay.Inc(waited.Nanoseconds())
 		s.metrics.AddSSTableProposalEngineDelay.Inc(waitedEngine.Nanoseconds())
 		if waited > time.Second {
-			log.Infof(ctx, "SST ingestion was delayed by %v (%v for storage engine back-pressure)",
-				waited, waitedEngine)
+			log.Infof(ctx, "SST ingestion was delayed by %v (%v for storage engine back-pressure, %v for adaptive pacing)",
+				waited, waitedEngine, waitedPacing)
 		}
 		return res, nil
 
@@ -723,6 +723,18 @@ func (l *Locality) Find(key string) (value string, ok bool) {
 	return "", false
 }
 
+// Matches checks whether the locality has a tier with the same key and value
+// for every tier of the filter. If it does not, it returns false along with the
+// first tier of the filter that does not match.
+func (l Locality) Matches(filter Locality) (bool, Tier) {
+	for _, t := range filter.Tiers {
+		if v, ok := l.Find(t.Key); !ok || v != t.Value {
+			return false, t
+		}
+	}
+	return true, Tier{}
+}
+
 // DefaultLocationInformation is used to populate the system.locations
 // table. The region values here are specific to GCP.
 var DefaultLocationInformation = []struct {
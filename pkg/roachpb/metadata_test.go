@@ -257,3 +257,32 @@ func TestAddTier(t *testing.T) {
 	require.Equal(t, l2, l1.AddTier(Tier{Key: "foo", Value: "bar"}))
 	require.Equal(t, l3, l2.AddTier(Tier{Key: "bar", Value: "foo"}))
 }
+
+func TestLocalityMatches(t *testing.T) {
+	var l Locality
+	require.NoError(t, l.Set("region=us-east1,zone=us-east1-b,rack=12"))
+
+	for _, tc := range []struct {
+		filter  string
+		matches bool
+		failing Tier
+	}{
+		{filter: "", matches: true},
+		{filter: "region=us-east1", matches: true},
+		{filter: "zone=us-east1-b", matches: true},
+		{filter: "region=us-east1,rack=12", matches: true},
+		{filter: "region=us-west1", failing: Tier{Key: "region", Value: "us-west1"}},
+		{filter: "region=us-east1,zone=us-east1-c", failing: Tier{Key: "zone", Value: "us-east1-c"}},
+		{filter: "dc=dc1", failing: Tier{Key: "dc", Value: "dc1"}},
+	} {
+		t.Run(tc.filter, func(t *testing.T) {
+			var filter Locality
+			if tc.filter != "" {
+				require.NoError(t, filter.Set(tc.filter))
+			}
+			matches, failing := l.Matches(filter)
+			require.Equal(t, tc.matches, matches)
+			require.Equal(t, tc.failing, failing)
+		})
+	}
+}
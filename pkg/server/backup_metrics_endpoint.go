@@ -0,0 +1,52 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/util/httputil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+)
+
+// backupMetricsPath exposes the metrics of backups in the prometheus format,
+// so that the health of backups can be scraped apart from /_status/vars.
+const backupMetricsPath = "/metrics/backup"
+
+// Construct a handler responsible for serving the metrics of backup jobs,
+// including those labeled by backup collection and schedule. The metrics also
+// show in /_status/vars.
+func makeBackupMetricsHandler(jobRegistry *jobs.Registry) func(http.ResponseWriter, *http.Request) {
+	exporter := metric.MakePrometheusExporter()
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The job registry is only initialized once the SQL server is, so its
+		// metrics are looked up on each scrape.
+		jobMetrics := jobRegistry.MetricsStruct()
+		registry := metric.NewRegistry()
+		if m := jobMetrics.JobMetrics[jobspb.TypeBackup]; m != nil {
+			registry.AddMetricStruct(m)
+		}
+		if jobMetrics.Backup != nil {
+			registry.AddMetricStruct(jobMetrics.Backup)
+		}
+
+		w.Header().Set(httputil.ContentTypeHeader, httputil.PlaintextContentType)
+		if err := exporter.ScrapeAndPrintAsText(w, func(pm *metric.PrometheusExporter) {
+			pm.ScrapeRegistry(registry, true /* includeChildMetrics */)
+		}); err != nil {
+			log.Errorf(r.Context(), "%v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
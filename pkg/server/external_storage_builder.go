@@ -17,6 +17,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/blobs"
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalioaudit"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/multitenant"
 	"github.com/cockroachdb/cockroach/pkg/multitenant/multitenantio"
@@ -25,7 +26,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/logtags"
 )
 
 // externalStorageBuilder is a wrapper around the ExternalStorage factory
@@ -43,10 +46,13 @@ type externalStorageBuilder struct {
 	db                *kv.DB
 	limiters          cloud.Limiters
 	recorder          multitenant.TenantSideExternalIORecorder
+	auditor           *externalioaudit.Auditor
+	faults            *cloud.FaultInjector
 }
 
 func (e *externalStorageBuilder) init(
 	ctx context.Context,
+	stopper *stop.Stopper,
 	conf base.ExternalIODirConfig,
 	settings *cluster.Settings,
 	nodeIDContainer *base.NodeIDContainer,
@@ -56,7 +62,7 @@ func (e *externalStorageBuilder) init(
 	ief sqlutil.InternalExecutorFactory,
 	db *kv.DB,
 	recorder multitenant.TenantSideExternalIORecorder,
-) {
+) error {
 	var blobClientFactory blobs.BlobClientFactory
 	if p, ok := testingKnobs.Server.(*TestingKnobs); ok && p.BlobClientFactory != nil {
 		blobClientFactory = p.BlobClientFactory
@@ -73,6 +79,16 @@ func (e *externalStorageBuilder) init(
 	e.db = db
 	e.limiters = cloud.MakeLimiters(ctx, &settings.SV)
 	e.recorder = recorder
+	e.auditor = externalioaudit.NewAuditor(settings, ie)
+	e.faults = cloud.NewFaultInjector()
+	if k, ok := testingKnobs.Cloud.(*cloud.TestingKnobs); ok && k.FaultInjector != nil {
+		e.faults = k.FaultInjector
+	}
+	e.faults.WatchSetting(&settings.SV)
+	// The auditor writes the accesses it buffers for as long as the server
+	// runs, beyond the context that init is called with.
+	auditCtx := logtags.WithTags(context.Background(), logtags.FromContext(ctx))
+	return e.auditor.Start(auditCtx, stopper)
 }
 
 func (e *externalStorageBuilder) makeExternalStorage(
@@ -99,5 +115,7 @@ func (e *externalStorageBuilder) defaultOptions() []cloud.ExternalStorageOption
 	bytesAllowedBeforeAccounting := multitenantio.DefaultBytesAllowedBeforeAccounting.Get(&e.settings.SV)
 	return []cloud.ExternalStorageOption{
 		cloud.WithIOAccountingInterceptor(multitenantio.NewReadWriteAccounter(e.recorder, bytesAllowedBeforeAccounting)),
+		cloud.WithAccessAuditor(e.auditor),
+		cloud.WithFaultInjector(e.faults),
 	}
 }
@@ -822,6 +822,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 			externalStorageFromURI:   externalStorageFromURI,
 			isMeta1Leaseholder:       node.stores.IsMeta1Leaseholder,
 			sqlSQLResponseAdmissionQ: gcoords.Regular.GetWorkQueue(admission.SQLSQLResponseWork),
+			admissionPacerFactory:    gcoords.Elastic,
 			spanConfigKVAccessor:     spanConfig.kvAccessorForTenantRecords,
 			kvStoresIterator:         kvserver.MakeStoresIterator(node.stores),
 		},
@@ -1082,8 +1083,9 @@ func (s *Server) PreStart(ctx context.Context) error {
 	ieMon.StartNoReserved(ctx, s.PGServer().SQLServer.GetBytesMonitor())
 	s.stopper.AddCloser(stop.CloserFn(func() { ieMon.Stop(ctx) }))
 	fileTableInternalExecutor := sql.MakeInternalExecutor(s.PGServer().SQLServer, sql.MemoryMetrics{}, ieMon)
-	s.externalStorageBuilder.init(
+	if err := s.externalStorageBuilder.init(
 		ctx,
+		s.stopper,
 		s.cfg.ExternalIODirConfig,
 		s.st,
 		s.nodeIDContainer,
@@ -1093,7 +1095,9 @@ func (s *Server) PreStart(ctx context.Context) error {
 		s.sqlServer.execCfg.InternalExecutorFactory,
 		s.db,
 		nil, /* TenantExternalIORecorder */
-	)
+	); err != nil {
+		return err
+	}
 
 	// Filter out self from the gossip bootstrap addresses.
 	filtered := s.cfg.FilterGossipBootstrapAddresses(ctx)
@@ -1594,13 +1598,14 @@ func (s *Server) PreStart(ctx context.Context) error {
 	// endpoints served by gwMux by the HTTP cookie authentication
 	// check.
 	if err := s.http.setupRoutes(ctx,
-		s.authentication,       /* authnServer */
-		s.adminAuthzCheck,      /* adminAuthzCheck */
-		s.recorder,             /* metricSource */
-		s.runtime,              /* runtimeStatsSampler */
-		gwMux,                  /* handleRequestsUnauthenticated */
-		s.debug,                /* handleDebugUnauthenticated */
-		newAPIV2Server(ctx, s), /* apiServer */
+		s.authentication,        /* authnServer */
+		s.adminAuthzCheck,       /* adminAuthzCheck */
+		s.recorder,              /* metricSource */
+		s.runtime,               /* runtimeStatsSampler */
+		gwMux,                   /* handleRequestsUnauthenticated */
+		s.debug,                 /* handleDebugUnauthenticated */
+		newAPIV2Server(ctx, s),  /* apiServer */
+		s.sqlServer.jobRegistry, /* jobRegistry */
 	); err != nil {
 		return err
 	}
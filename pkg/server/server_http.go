@@ -17,6 +17,7 @@ import (
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/cockroachdb/cmux"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/rpc"
 	"github.com/cockroachdb/cockroach/pkg/server/debug"
 	"github.com/cockroachdb/cockroach/pkg/server/status"
@@ -94,6 +95,7 @@ func (s *httpServer) setupRoutes(
 	handleRequestsUnauthenticated http.Handler,
 	handleDebugUnauthenticated http.Handler,
 	apiServer *apiV2Server,
+	jobRegistry *jobs.Registry,
 ) error {
 	// OIDC Configuration must happen prior to the UI Handler being defined below so that we have
 	// the system settings initialized for it to pick up from the oidcAuthenticationServer.
@@ -158,6 +160,8 @@ func (s *httpServer) setupRoutes(
 	s.mux.Handle(statusVars, http.HandlerFunc(varsHandler{metricSource, s.cfg.Settings}.handleVars))
 	// Same for /_status/load.
 	s.mux.Handle(loadStatusVars, http.HandlerFunc(makeStatusLoadHandler(ctx, runtimeStatSampler, metricSource)))
+	// Same for the metrics of backups.
+	s.mux.Handle(backupMetricsPath, http.HandlerFunc(makeBackupMetricsHandler(jobRegistry)))
 
 	if apiServer != nil {
 		// The new "v2" HTTP API tree.
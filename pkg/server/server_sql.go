@@ -222,6 +222,9 @@ type sqlServerOptionalKVArgs struct {
 
 	// The admission queue to use for SQLSQLResponseWork.
 	sqlSQLResponseAdmissionQ *admission.WorkQueue
+	// Used to pace elastic work done by SQL processors, like backups, with
+	// the elastic CPU work queue of the KV node.
+	admissionPacerFactory admission.PacerFactory
 
 	// Used when creating and deleting tenant records.
 	spanConfigKVAccessor spanconfig.KVAccessor
@@ -645,6 +648,8 @@ func newSQLServer(ctx context.Context, cfg sqlServerArgs) (*SQLServer, error) {
 		BackupMonitor:     backupMemoryMonitor,
 		BulkSenderLimiter: bulkSenderLimiter,
 
+		BackupLocalityWriteLimiters: cloud.MakeLocalityWriteLimiters(),
+
 		ParentMemoryMonitor: rootSQLMemoryMonitor,
 		BulkAdder: func(
 			ctx context.Context, db *kv.DB, ts hlc.Timestamp, opts kvserverbase.BulkAdderOptions,
@@ -671,6 +676,7 @@ func newSQLServer(ctx context.Context, cfg sqlServerArgs) (*SQLServer, error) {
 		DistSender:               cfg.distSender,
 		RangeCache:               cfg.distSender.RangeDescriptorCache(),
 		SQLSQLResponseAdmissionQ: cfg.sqlSQLResponseAdmissionQ,
+		AdmissionPacerFactory:    cfg.admissionPacerFactory,
 		CollectionFactory:        collectionFactory,
 		ExternalIORecorder:       cfg.costController,
 		RangeStatsFetcher:        rangeStatsFetcher,
@@ -834,6 +840,7 @@ func newSQLServer(ctx context.Context, cfg sqlServerArgs) (*SQLServer, error) {
 		RangeProber:                rangeprober.NewRangeProber(cfg.db),
 		DescIDGenerator:            descidgen.NewGenerator(codec, cfg.db),
 		RangeStatsFetcher:          rangeStatsFetcher,
+		StorageStatsCache:          sql.NewStorageStatsCache(cfg.db, codec, cfg.Settings, rangeStatsFetcher),
 		EventsExporter:             cfg.eventsServer,
 	}
 
@@ -1253,6 +1260,7 @@ func (s *SQLServer) preStart(
 		return err
 	}
 	s.stmtDiagnosticsRegistry.Start(ctx, stopper)
+	s.execCfg.StorageStatsCache.Start(ctx, stopper)
 	if err := s.execCfg.TableStatsCache.Start(ctx, s.execCfg.Codec, s.execCfg.RangeFeedFactory); err != nil {
 		return err
 	}
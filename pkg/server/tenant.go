@@ -287,13 +287,14 @@ func startTenantInternal(
 
 	// TODO(knz): Add support for the APIv2 tree here.
 	if err := httpServer.setupRoutes(ctx,
-		authServer,      /* authnServer */
-		adminAuthzCheck, /* adminAuthzCheck */
-		args.recorder,   /* metricSource */
-		args.runtime,    /* runtimeStatSampler */
-		gwMux,           /* handleRequestsUnauthenticated */
-		debugServer,     /* handleDebugUnauthenticated */
-		nil,             /* apiServer */
+		authServer,               /* authnServer */
+		adminAuthzCheck,          /* adminAuthzCheck */
+		args.recorder,            /* metricSource */
+		args.runtime,             /* runtimeStatSampler */
+		gwMux,                    /* handleRequestsUnauthenticated */
+		debugServer,              /* handleDebugUnauthenticated */
+		nil,                      /* apiServer */
+		args.circularJobRegistry, /* jobRegistry */
 	); err != nil {
 		return nil, nil, nil, "", "", err
 	}
@@ -532,8 +533,9 @@ func makeTenantSQLServerArgs(
 	externalStorage := esb.makeExternalStorage
 	externalStorageFromURI := esb.makeExternalStorageFromURI
 
-	esb.init(
+	if err := esb.init(
 		startupCtx,
+		stopper,
 		sqlCfg.ExternalIODirConfig,
 		baseCfg.Settings,
 		baseCfg.IDContainer,
@@ -543,7 +545,9 @@ func makeTenantSQLServerArgs(
 		internalExecutorFactory,
 		db,
 		costController,
-	)
+	); err != nil {
+		return sqlServerArgs{}, err
+	}
 
 	grpcServer := newGRPCServer(rpcContext)
 	// In a SQL-only server, there is no separate node initialization
@@ -37,6 +37,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/storageparam"
+	"github.com/cockroachdb/cockroach/pkg/sql/storageparam/databasestorageparam"
 	"github.com/cockroachdb/cockroach/pkg/util/log/eventpb"
 	"github.com/cockroachdb/cockroach/pkg/util/log/logpb"
 	"github.com/cockroachdb/errors"
@@ -2267,3 +2269,98 @@ func (n *alterDatabaseSetZoneConfigExtensionNode) startExec(params runParams) er
 func (n *alterDatabaseSetZoneConfigExtensionNode) Next(runParams) (bool, error) { return false, nil }
 func (n *alterDatabaseSetZoneConfigExtensionNode) Values() tree.Datums          { return tree.Datums{} }
 func (n *alterDatabaseSetZoneConfigExtensionNode) Close(context.Context)        {}
+
+type alterDatabaseStorageParamsNode struct {
+	desc *dbdesc.Mutable
+	// Exactly one of set and reset is set.
+	set   *tree.AlterDatabaseSetStorageParams
+	reset *tree.AlterDatabaseResetStorageParams
+}
+
+// AlterDatabaseSetStorageParams transforms a
+// tree.AlterDatabaseSetStorageParams into a plan node.
+func (p *planner) AlterDatabaseSetStorageParams(
+	ctx context.Context, n *tree.AlterDatabaseSetStorageParams,
+) (planNode, error) {
+	dbDesc, err := p.prepareAlterDatabaseStorageParams(ctx, n.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &alterDatabaseStorageParamsNode{desc: dbDesc, set: n}, nil
+}
+
+// AlterDatabaseResetStorageParams transforms a
+// tree.AlterDatabaseResetStorageParams into a plan node.
+func (p *planner) AlterDatabaseResetStorageParams(
+	ctx context.Context, n *tree.AlterDatabaseResetStorageParams,
+) (planNode, error) {
+	dbDesc, err := p.prepareAlterDatabaseStorageParams(ctx, n.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &alterDatabaseStorageParamsNode{desc: dbDesc, reset: n}, nil
+}
+
+func (p *planner) prepareAlterDatabaseStorageParams(
+	ctx context.Context, name tree.Name,
+) (*dbdesc.Mutable, error) {
+	if err := checkSchemaChangeEnabled(
+		ctx,
+		p.ExecCfg(),
+		"ALTER DATABASE",
+	); err != nil {
+		return nil, err
+	}
+
+	dbDesc, err := p.Descriptors().GetMutableDatabaseByName(ctx, p.txn, string(name),
+		tree.DatabaseLookupFlags{Required: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+	hasOwnership, err := p.HasOwnership(ctx, dbDesc)
+	if err != nil {
+		return nil, err
+	}
+	if !hasOwnership {
+		return nil, pgerror.Newf(pgcode.InsufficientPrivilege,
+			"must be owner of database %s", dbDesc.GetName())
+	}
+	return dbDesc, nil
+}
+
+func (n *alterDatabaseStorageParamsNode) startExec(params runParams) error {
+	setter := &databasestorageparam.Setter{DatabaseDesc: n.desc}
+	var stmt tree.Statement
+	if n.set != nil {
+		stmt = n.set
+		if err := storageparam.Set(
+			params.ctx,
+			params.p.SemaCtx(),
+			params.EvalContext(),
+			n.set.StorageParams,
+			setter,
+		); err != nil {
+			return err
+		}
+	} else {
+		stmt = n.reset
+		if err := storageparam.Reset(
+			params.ctx,
+			params.EvalContext(),
+			n.reset.Params,
+			setter,
+		); err != nil {
+			return err
+		}
+	}
+	return params.p.writeNonDropDatabaseChange(
+		params.ctx,
+		n.desc,
+		tree.AsStringWithFQNames(stmt, params.Ann()),
+	)
+}
+
+func (n *alterDatabaseStorageParamsNode) Next(runParams) (bool, error) { return false, nil }
+func (n *alterDatabaseStorageParamsNode) Values() tree.Datums          { return tree.Datums{} }
+func (n *alterDatabaseStorageParamsNode) Close(context.Context)        {}
@@ -0,0 +1,175 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+var backupProtectionWindow = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"sql.backup_protection.window",
+	"how recently a backup must have covered a table with backup_protection set "+
+		"for the table to be dropped or truncated",
+	24*time.Hour,
+	settings.PositiveDuration,
+).WithPublic()
+
+// checkBackupProtection returns an error if stmt drops or truncates a table
+// which has backup_protection set, or is in a database which has it set, and
+// which no backup has covered within sql.backup_protection.window, as recorded
+// in system.table_backup_checkpoints.
+//
+// The check runs before the statement is planned by either schema changer.
+// Names which do not resolve are skipped, so that the statement reports them.
+func (p *planner) checkBackupProtection(ctx context.Context, stmt tree.Statement) error {
+	var op string
+	var tables []catalog.TableDescriptor
+	var err error
+	switch n := stmt.(type) {
+	case *tree.DropTable:
+		op = "drop"
+		tables, err = p.resolveBackupProtectedTables(ctx, n.Names)
+	case *tree.Truncate:
+		op = "truncate"
+		tables, err = p.resolveBackupProtectedTables(ctx, n.Tables)
+	case *tree.DropDatabase:
+		op = "drop"
+		tables, err = p.backupProtectedTablesInDatabase(ctx, n.Name)
+	default:
+		return nil
+	}
+	if err != nil || len(tables) == 0 {
+		return err
+	}
+
+	window := backupProtectionWindow.Get(&p.ExecCfg().Settings.SV)
+	since := timeutil.Now().Add(-window)
+	covered, err := p.tablesBackedUpSince(ctx, tables, since)
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if covered[t.GetID()] {
+			continue
+		}
+		return errors.WithHintf(
+			pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+				"cannot %s table %q: it has backup_protection set and no backup has covered it in the last %s",
+				op, t.GetName(), window),
+			"back up the table first, or RESET (backup_protection) on the table and its database",
+		)
+	}
+	return nil
+}
+
+// resolveBackupProtectedTables returns the tables among names which are
+// protected, either directly or through their database.
+func (p *planner) resolveBackupProtectedTables(
+	ctx context.Context, names tree.TableNames,
+) ([]catalog.TableDescriptor, error) {
+	flags := tree.ObjectLookupFlagsWithRequiredTableKind(tree.ResolveRequireTableDesc)
+	flags.Required = false
+	var tables []catalog.TableDescriptor
+	for i := range names {
+		tn := names[i]
+		_, table, err := resolver.ResolveExistingTableObject(ctx, p, &tn, flags)
+		if err != nil || table == nil {
+			continue
+		}
+		if !isBackedUpTable(table) {
+			continue
+		}
+		protected := table.GetBackupProtection()
+		if !protected {
+			_, db, err := p.Descriptors().GetImmutableDatabaseByID(ctx, p.txn, table.GetParentID(),
+				tree.DatabaseLookupFlags{Required: true})
+			if err != nil {
+				return nil, err
+			}
+			protected = db.GetBackupProtection()
+		}
+		if protected {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// backupProtectedTablesInDatabase returns the protected tables of the named
+// database, which are all of them if the database itself is protected.
+func (p *planner) backupProtectedTablesInDatabase(
+	ctx context.Context, name tree.Name,
+) ([]catalog.TableDescriptor, error) {
+	db, err := p.Descriptors().GetImmutableDatabaseByName(ctx, p.txn, string(name),
+		tree.DatabaseLookupFlags{})
+	if err != nil || db == nil {
+		return nil, err
+	}
+	all, err := p.Descriptors().GetAllTableDescriptorsInDatabase(ctx, p.txn, db)
+	if err != nil {
+		return nil, err
+	}
+	var tables []catalog.TableDescriptor
+	for _, t := range all {
+		if isBackedUpTable(t) && (db.GetBackupProtection() || t.GetBackupProtection()) {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+// isBackedUpTable returns true if backups record a checkpoint for the table.
+func isBackedUpTable(t catalog.TableDescriptor) bool {
+	return t.IsTable() && !t.IsVirtualTable() && !t.IsTemporary() && !t.Dropped()
+}
+
+// tablesBackedUpSince returns the IDs of those tables which a backup ending
+// after since has covered.
+func (p *planner) tablesBackedUpSince(
+	ctx context.Context, tables []catalog.TableDescriptor, since time.Time,
+) (map[descpb.ID]bool, error) {
+	var ids strings.Builder
+	for i, t := range tables {
+		if i > 0 {
+			ids.WriteString(", ")
+		}
+		fmt.Fprintf(&ids, "%d", t.GetID())
+	}
+	rows, err := p.ExecCfg().InternalExecutor.QueryBufferedEx(ctx, "check-backup-protection",
+		nil /* txn */, sessiondata.NodeUserSessionDataOverride,
+		fmt.Sprintf(`SELECT table_id FROM system.table_backup_checkpoints
+WHERE table_id IN (%s) AND end_time >= $1`, ids.String()),
+		since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	covered := make(map[descpb.ID]bool, len(rows))
+	for _, row := range rows {
+		covered[descpb.ID(tree.MustBeDInt(row[0]))] = true
+	}
+	return covered, nil
+}
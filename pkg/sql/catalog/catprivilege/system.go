@@ -64,6 +64,9 @@ var (
 		catconstants.SpanCountTableName,
 		catconstants.SystemPrivilegeTableName,
 		catconstants.SystemExternalConnectionsTableName,
+		catconstants.SystemExternalIOAuditTableName,
+		catconstants.SystemScheduledBackupRunsTableName,
+		catconstants.SystemTableBackupCheckpointsTableName,
 	}
 
 	readWriteSystemSequences = []catconstants.SystemTableName{
@@ -277,6 +277,9 @@ type DatabaseDescriptor interface {
 	// HasPublicSchemaWithDescriptor returns true iff the database has a public
 	// schema which itself has a descriptor.
 	HasPublicSchemaWithDescriptor() bool
+	// GetBackupProtection returns true if the tables of the database may only
+	// be dropped or truncated once a recent backup covers them.
+	GetBackupProtection() bool
 }
 
 // TableDescriptor is an interface around the table descriptor types.
@@ -701,6 +704,9 @@ type TableDescriptor interface {
 	// GetExcludeDataFromBackup returns true if the table's row data is configured
 	// to be excluded during backup.
 	GetExcludeDataFromBackup() bool
+	// GetBackupProtection returns true if the table may only be dropped or
+	// truncated once a recent backup covers it.
+	GetBackupProtection() bool
 	// GetStorageParams returns a list of storage parameters for the table.
 	GetStorageParams(spaceBetweenEqual bool) []string
 	// NoAutoStatsSettingsOverrides is true if no auto stats related settings are
@@ -115,9 +115,9 @@ func TableDescs(
 				continue
 			}
 
-			// TODO(dt): if there is an existing (i.e. non-restoring) table with
-			// a db and name matching the one the FK pointed to at backup, should
-			// we update the FK to point to it?
+			// The rewrite may be to an existing table of the same name if the user
+			// specified the restore_fk_to_existing option, in which case the
+			// referenced columns were already remapped when the restore was planned.
 			table.OutboundFKs = append(table.OutboundFKs, *fk)
 		}
 
@@ -524,11 +524,16 @@ func SchemaDescs(schemas []*schemadesc.Mutable, descriptorRewrites jobspb.DescRe
 		sc.ID = rewrite.ID
 		sc.ParentID = rewrite.ParentID
 
-		// Rewrite function ID and types ID in function signatures.
-		for _, fn := range sc.GetFunctions() {
-			for i := range fn.Overloads {
-				overload := &fn.Overloads[i]
-				overload.ID = descriptorRewrites[overload.ID].ID
+		// Rewrite function ID and types ID in function signatures. The signatures
+		// of functions that are not restored along with the schema are dropped.
+		for name, fn := range sc.GetFunctions() {
+			overloads := fn.Overloads[:0]
+			for _, overload := range fn.Overloads {
+				fnRewrite, ok := descriptorRewrites[overload.ID]
+				if !ok {
+					continue
+				}
+				overload.ID = fnRewrite.ID
 				for _, typ := range overload.ArgTypes {
 					if err := rewriteIDsInTypesT(typ, descriptorRewrites); err != nil {
 						return err
@@ -537,7 +542,14 @@ func SchemaDescs(schemas []*schemadesc.Mutable, descriptorRewrites jobspb.DescRe
 				if err := rewriteIDsInTypesT(overload.ReturnType, descriptorRewrites); err != nil {
 					return err
 				}
+				overloads = append(overloads, overload)
+			}
+			if len(overloads) == 0 {
+				delete(sc.Functions, name)
+				continue
 			}
+			fn.Overloads = overloads
+			sc.Functions[name] = fn
 		}
 
 		if err := rewriteSchemaChangerState(sc, descriptorRewrites); err != nil {
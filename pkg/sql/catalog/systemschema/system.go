@@ -698,6 +698,52 @@ CREATE TABLE system.external_connections (
 	CONSTRAINT "primary" PRIMARY KEY (connection_name),
 	FAMILY "primary" (connection_name, created, updated, connection_type, connection_details, owner)
 );`
+
+	// external_io_audit records the accesses to external storage made on behalf
+	// of principals, such as by jobs, for as long as its retention setting.
+	SystemExternalIOAuditTableSchema = `
+CREATE TABLE system.external_io_audit (
+	ts TIMESTAMP NOT NULL DEFAULT now(),
+	id INT8 NOT NULL DEFAULT unique_rowid(),
+	principal STRING NOT NULL,
+	job_id INT8,
+	operation STRING NOT NULL,
+	uri STRING NOT NULL,
+	bytes INT8 NOT NULL,
+	CONSTRAINT "primary" PRIMARY KEY (ts, id),
+	FAMILY "primary" (ts, id, principal, job_id, operation, uri, bytes)
+);`
+
+	// scheduled_backup_runs records the outcome of each run of a backup
+	// schedule, for as long as its retention setting.
+	SystemScheduledBackupRunsTableSchema = `
+CREATE TABLE system.scheduled_backup_runs (
+	schedule_id INT8 NOT NULL,
+	finished TIMESTAMP NOT NULL DEFAULT now(),
+	id INT8 NOT NULL DEFAULT unique_rowid(),
+	job_id INT8,
+	status STRING NOT NULL,
+	started TIMESTAMP,
+	destination STRING,
+	subdir STRING,
+	data_size INT8,
+	row_count INT8,
+	error STRING,
+	CONSTRAINT "primary" PRIMARY KEY (schedule_id, finished, id),
+	FAMILY "primary" (schedule_id, finished, id, job_id, status, started, destination, subdir, data_size, row_count, error)
+);`
+
+	// table_backup_checkpoints records, for each table that has been backed
+	// up, the end time of the most recent backup which covered it.
+	SystemTableBackupCheckpointsTableSchema = `
+CREATE TABLE system.table_backup_checkpoints (
+	table_id INT8 NOT NULL,
+	database_id INT8 NOT NULL,
+	end_time TIMESTAMP NOT NULL,
+	job_id INT8 NOT NULL,
+	CONSTRAINT "primary" PRIMARY KEY (table_id),
+	FAMILY "primary" (table_id, database_id, end_time, job_id)
+);`
 )
 
 func pk(name string) descpb.IndexDescriptor {
@@ -2562,6 +2608,102 @@ var (
 			},
 		),
 	)
+
+	SystemExternalIOAuditTable = registerSystemTable(
+		SystemExternalIOAuditTableSchema,
+		systemTable(
+			catconstants.SystemExternalIOAuditTableName,
+			descpb.InvalidID, // dynamically assigned
+			[]descpb.ColumnDescriptor{
+				{Name: "ts", ID: 1, Type: types.Timestamp, DefaultExpr: &nowString},
+				{Name: "id", ID: 2, Type: types.Int, DefaultExpr: &uniqueRowIDString},
+				{Name: "principal", ID: 3, Type: types.String},
+				{Name: "job_id", ID: 4, Type: types.Int, Nullable: true},
+				{Name: "operation", ID: 5, Type: types.String},
+				{Name: "uri", ID: 6, Type: types.String},
+				{Name: "bytes", ID: 7, Type: types.Int},
+			},
+			[]descpb.ColumnFamilyDescriptor{
+				{
+					Name:        "primary",
+					ID:          0,
+					ColumnNames: []string{"ts", "id", "principal", "job_id", "operation", "uri", "bytes"},
+					ColumnIDs:   []descpb.ColumnID{1, 2, 3, 4, 5, 6, 7},
+				},
+			},
+			descpb.IndexDescriptor{
+				Name:                "primary",
+				ID:                  1,
+				Unique:              true,
+				KeyColumnNames:      []string{"ts", "id"},
+				KeyColumnDirections: []catpb.IndexColumn_Direction{catpb.IndexColumn_ASC, catpb.IndexColumn_ASC},
+				KeyColumnIDs:        []descpb.ColumnID{1, 2},
+			},
+		),
+	)
+
+	SystemScheduledBackupRunsTable = registerSystemTable(
+		SystemScheduledBackupRunsTableSchema,
+		systemTable(
+			catconstants.SystemScheduledBackupRunsTableName,
+			descpb.InvalidID, // dynamically assigned
+			[]descpb.ColumnDescriptor{
+				{Name: "schedule_id", ID: 1, Type: types.Int},
+				{Name: "finished", ID: 2, Type: types.Timestamp, DefaultExpr: &nowString},
+				{Name: "id", ID: 3, Type: types.Int, DefaultExpr: &uniqueRowIDString},
+				{Name: "job_id", ID: 4, Type: types.Int, Nullable: true},
+				{Name: "status", ID: 5, Type: types.String},
+				{Name: "started", ID: 6, Type: types.Timestamp, Nullable: true},
+				{Name: "destination", ID: 7, Type: types.String, Nullable: true},
+				{Name: "subdir", ID: 8, Type: types.String, Nullable: true},
+				{Name: "data_size", ID: 9, Type: types.Int, Nullable: true},
+				{Name: "row_count", ID: 10, Type: types.Int, Nullable: true},
+				{Name: "error", ID: 11, Type: types.String, Nullable: true},
+			},
+			[]descpb.ColumnFamilyDescriptor{
+				{
+					Name: "primary",
+					ID:   0,
+					ColumnNames: []string{"schedule_id", "finished", "id", "job_id", "status", "started",
+						"destination", "subdir", "data_size", "row_count", "error"},
+					ColumnIDs: []descpb.ColumnID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+				},
+			},
+			descpb.IndexDescriptor{
+				Name:           "primary",
+				ID:             1,
+				Unique:         true,
+				KeyColumnNames: []string{"schedule_id", "finished", "id"},
+				KeyColumnDirections: []catpb.IndexColumn_Direction{
+					catpb.IndexColumn_ASC, catpb.IndexColumn_ASC, catpb.IndexColumn_ASC,
+				},
+				KeyColumnIDs: []descpb.ColumnID{1, 2, 3},
+			},
+		),
+	)
+
+	SystemTableBackupCheckpointsTable = registerSystemTable(
+		SystemTableBackupCheckpointsTableSchema,
+		systemTable(
+			catconstants.SystemTableBackupCheckpointsTableName,
+			descpb.InvalidID, // dynamically assigned
+			[]descpb.ColumnDescriptor{
+				{Name: "table_id", ID: 1, Type: types.Int},
+				{Name: "database_id", ID: 2, Type: types.Int},
+				{Name: "end_time", ID: 3, Type: types.Timestamp},
+				{Name: "job_id", ID: 4, Type: types.Int},
+			},
+			[]descpb.ColumnFamilyDescriptor{
+				{
+					Name:        "primary",
+					ID:          0,
+					ColumnNames: []string{"table_id", "database_id", "end_time", "job_id"},
+					ColumnIDs:   []descpb.ColumnID{1, 2, 3, 4},
+				},
+			},
+			pk("table_id"),
+		),
+	)
 )
 
 type descRefByName struct {
@@ -2600,6 +2600,11 @@ func (desc *wrapper) GetExcludeDataFromBackup() bool {
 	return desc.ExcludeDataFromBackup
 }
 
+// GetBackupProtection implements the TableDescriptor interface.
+func (desc *wrapper) GetBackupProtection() bool {
+	return desc.BackupProtection
+}
+
 // GetStorageParams implements the TableDescriptor interface.
 func (desc *wrapper) GetStorageParams(spaceBetweenEqual bool) []string {
 	var storageParams []string
@@ -2643,6 +2648,9 @@ func (desc *wrapper) GetStorageParams(spaceBetweenEqual bool) []string {
 	if exclude := desc.GetExcludeDataFromBackup(); exclude {
 		appendStorageParam(`exclude_data_from_backup`, `true`)
 	}
+	if desc.GetBackupProtection() {
+		appendStorageParam(`backup_protection`, `true`)
+	}
 	if settings := desc.AutoStatsSettings; settings != nil {
 		if settings.Enabled != nil {
 			value := *settings.Enabled
@@ -175,6 +175,9 @@ var crdbInternal = virtualSchema{
 		catconstants.CrdbInternalActiveRangeFeedsTable:              crdbInternalActiveRangeFeedsTable,
 		catconstants.CrdbInternalTenantUsageDetailsViewID:           crdbInternalTenantUsageDetailsView,
 		catconstants.CrdbInternalPgCatalogTableIsImplementedTableID: crdbInternalPgCatalogTableIsImplementedTable,
+		catconstants.CrdbInternalBackupRemainingSpansTableID:        crdbInternalBackupRemainingSpansTable,
+		catconstants.CrdbInternalExternalIOAuditTableID:             crdbInternalExternalIOAuditTable,
+		catconstants.CrdbInternalDatabaseStorageStatsTableID:        crdbInternalDatabaseStorageStatsTable,
 	},
 	validWithNoDatabaseContext: true,
 }
@@ -1036,6 +1039,173 @@ CREATE TABLE crdb_internal.jobs (
 	},
 }
 
+var crdbInternalBackupRemainingSpansTable = virtualSchemaTable{
+	comment: `spans that running backup jobs have yet to export, as of their last checkpoint`,
+	schema: `
+CREATE TABLE crdb_internal.backup_remaining_spans (
+  job_id       INT NOT NULL,
+  start_key    BYTES NOT NULL,
+  start_pretty STRING NOT NULL,
+  end_key      BYTES NOT NULL,
+  end_pretty   STRING NOT NULL
+)`,
+	populate: func(ctx context.Context, p *planner, _ catalog.DatabaseDescriptor, addRow func(...tree.Datum) error) error {
+		currentUser := p.SessionData().User()
+		isAdmin, err := p.HasAdminRole(ctx)
+		if err != nil {
+			return err
+		}
+		hasControlJob, err := p.HasRoleOption(ctx, roleoption.CONTROLJOB)
+		if err != nil {
+			return err
+		}
+
+		// Beware: we're querying system.jobs as root; we need to be careful to filter
+		// out results that the current user is not able to see.
+		rows, err := p.ExtendedEvalContext().ExecCfg.InternalExecutor.QueryBufferedEx(
+			ctx, "crdb-internal-backup-remaining-spans", p.txn,
+			sessiondata.InternalExecutorOverride{User: username.RootUserName()},
+			`SELECT id, payload, progress FROM system.jobs WHERE status = $1`, jobs.StatusRunning)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			payload, err := jobs.UnmarshalPayload(r[1])
+			if err != nil {
+				return err
+			}
+			if payload.Type() != jobspb.TypeBackup {
+				continue
+			}
+			// Apply the same visibility rules as crdb_internal.jobs.
+			owner := payload.UsernameProto.Decode()
+			ownedByAdmin, err := p.UserHasAdminRole(ctx, owner)
+			if err != nil {
+				return err
+			}
+			if canAccess := isAdmin || !ownedByAdmin && hasControlJob || owner == currentUser; !canAccess {
+				continue
+			}
+			progress, err := jobs.UnmarshalProgress(r[2])
+			if err != nil {
+				return err
+			}
+			backup := progress.GetBackup()
+			if backup == nil {
+				continue
+			}
+			for _, sp := range backup.RemainingSpans {
+				if err := addRow(
+					r[0],
+					tree.NewDBytes(tree.DBytes(sp.Key)),
+					tree.NewDString(sp.Key.String()),
+					tree.NewDBytes(tree.DBytes(sp.EndKey)),
+					tree.NewDString(sp.EndKey.String()),
+				); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var crdbInternalExternalIOAuditTable = virtualSchemaTable{
+	comment: `accesses to external storage made by jobs and on behalf of users (KV scan)`,
+	schema: `
+CREATE TABLE crdb_internal.external_io_audit (
+  ts        TIMESTAMP NOT NULL,
+  principal STRING NOT NULL,
+  job_id    INT,
+  operation STRING NOT NULL,
+  uri       STRING NOT NULL,
+  bytes     INT NOT NULL
+)`,
+	populate: func(ctx context.Context, p *planner, _ catalog.DatabaseDescriptor, addRow func(...tree.Datum) error) error {
+		if err := p.RequireAdminRole(ctx, "read crdb_internal.external_io_audit"); err != nil {
+			return err
+		}
+		if !p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.V23_1ExternalIOAuditTable) {
+			return nil
+		}
+		it, err := p.ExtendedEvalContext().ExecCfg.InternalExecutor.QueryIteratorEx(
+			ctx, "crdb-internal-external-io-audit", p.txn,
+			sessiondata.NodeUserSessionDataOverride,
+			`SELECT ts, principal, job_id, operation, uri, bytes FROM system.external_io_audit ORDER BY ts`)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = it.Close() }()
+		for {
+			ok, err := it.Next(ctx)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+			if err := addRow(it.Cur()...); err != nil {
+				return err
+			}
+		}
+	},
+}
+
+var crdbInternalDatabaseStorageStatsTable = virtualSchemaTable{
+	comment: `MVCC statistics of the ranges of every table (cached KV scan)`,
+	schema: `
+CREATE TABLE crdb_internal.database_storage_stats (
+  database_id   INT NOT NULL,
+  database_name STRING NOT NULL,
+  schema_name   STRING NOT NULL,
+  table_id      INT NOT NULL,
+  table_name    STRING NOT NULL,
+  range_count   INT NOT NULL,
+  live_bytes    INT NOT NULL,
+  total_bytes   INT NOT NULL,
+  key_bytes     INT NOT NULL,
+  val_bytes     INT NOT NULL,
+  intent_bytes  INT NOT NULL,
+  live_count    INT NOT NULL,
+  key_count     INT NOT NULL,
+  as_of         TIMESTAMPTZ NOT NULL
+)`,
+	populate: func(ctx context.Context, p *planner, dbContext catalog.DatabaseDescriptor, addRow func(...tree.Datum) error) error {
+		snapshot, err := p.ExecCfg().StorageStatsCache.Get(ctx)
+		if err != nil {
+			return err
+		}
+		asOf, err := tree.MakeDTimestampTZ(snapshot.AsOf, time.Microsecond)
+		if err != nil {
+			return err
+		}
+		return forEachTableDesc(ctx, p, dbContext, hideVirtual,
+			func(db catalog.DatabaseDescriptor, scName string, table catalog.TableDescriptor) error {
+				stats, ok := snapshot.Tables[table.GetID()]
+				if !ok {
+					// The table was created after the statistics were collected.
+					return nil
+				}
+				return addRow(
+					tree.NewDInt(tree.DInt(db.GetID())),
+					tree.NewDString(db.GetName()),
+					tree.NewDString(scName),
+					tree.NewDInt(tree.DInt(table.GetID())),
+					tree.NewDString(table.GetName()),
+					tree.NewDInt(tree.DInt(stats.RangeCount)),
+					tree.NewDInt(tree.DInt(stats.LiveBytes)),
+					tree.NewDInt(tree.DInt(stats.Total())),
+					tree.NewDInt(tree.DInt(stats.KeyBytes)),
+					tree.NewDInt(tree.DInt(stats.ValBytes)),
+					tree.NewDInt(tree.DInt(stats.IntentBytes)),
+					tree.NewDInt(tree.DInt(stats.LiveCount)),
+					tree.NewDInt(tree.DInt(stats.KeyCount)),
+					asOf,
+				)
+			})
+	},
+}
+
 // execStatAvg is a helper for execution stats shown in virtual tables. Returns
 // NULL when the count is 0, or the mean of the given NumericStat.
 func execStatAvg(count int64, n roachpb.NumericStat) tree.Datum {
@@ -15,6 +15,7 @@ import (
 	"fmt"
 
 	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
 	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
@@ -30,6 +31,10 @@ import (
 
 const externalConnectionOp = "CREATE EXTERNAL CONNECTION"
 
+var externalConnectionOptionValidate = map[string]KVStringOptValidate{
+	externalconn.CapabilitiesOption: KVStringOptRequireValue,
+}
+
 type createExternalConectionNode struct {
 	n *tree.CreateExternalConnection
 }
@@ -48,6 +53,7 @@ func (c *createExternalConectionNode) startExec(params runParams) error {
 type externalConnectionEval struct {
 	externalConnectionName     func() (string, error)
 	externalConnectionEndpoint func() (string, error)
+	externalConnectionOptions  func() (map[string]string, error)
 }
 
 func (p *planner) makeExternalConnectionEval(
@@ -61,6 +67,12 @@ func (p *planner) makeExternalConnectionEval(
 	}
 
 	eval.externalConnectionEndpoint, err = p.TypeAsString(ctx, n.As, externalConnectionOp)
+	if err != nil {
+		return nil, err
+	}
+
+	eval.externalConnectionOptions, err = p.TypeAsStringOpts(ctx, n.Options,
+		externalConnectionOptionValidate)
 	return eval, err
 }
 
@@ -110,7 +122,22 @@ func (p *planner) createExternalConnection(
 	if err != nil {
 		return errors.Wrap(err, "failed to construct External Connection details")
 	}
-	ex.SetConnectionDetails(*exConn.ConnectionProto())
+	opts, err := eval.externalConnectionOptions()
+	if err != nil {
+		return err
+	}
+	details := *exConn.ConnectionProto()
+	if capabilities, ok := opts[externalconn.CapabilitiesOption]; ok {
+		if exConn.ConnectionType() != connectionpb.TypeStorage {
+			return pgerror.Newf(pgcode.InvalidParameterValue,
+				"%q can only be specified for External Connections to storage", externalconn.CapabilitiesOption)
+		}
+		details.Capabilities, err = connectionpb.ParseCapabilities(capabilities)
+		if err != nil {
+			return pgerror.WithCandidateCode(err, pgcode.InvalidParameterValue)
+		}
+	}
+	ex.SetConnectionDetails(details)
 	ex.SetConnectionType(exConn.ConnectionType())
 	ex.SetOwner(p.User())
 
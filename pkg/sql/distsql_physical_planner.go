@@ -789,6 +789,43 @@ type PlanningCtx struct {
 	// release the resources that are acquired during the physical planning and
 	// are being hold onto throughout the whole flow lifecycle.
 	onFlowCleanup []func()
+
+	// localityFilter, if set, restricts the SQL instances that spans are
+	// assigned to to those whose locality matches it. It is only set up by
+	// SetupAllNodesPlanningWithLocalityFilter, which also populates
+	// localityFilteredInstances with the healthy instances that match it.
+	localityFilter            roachpb.Locality
+	localityFilteredInstances []base.SQLInstanceID
+	// nextLocalityFilteredInstance is the index of the instance that the spans
+	// of the next instance not matching localityFilter are assigned to.
+	nextLocalityFilteredInstance int
+}
+
+// matchesLocalityFilter returns whether spans can be assigned to the SQL
+// instance according to the locality filter of the plan, if any.
+func (p *PlanningCtx) matchesLocalityFilter(sqlInstanceID base.SQLInstanceID) bool {
+	if len(p.localityFilter.Tiers) == 0 {
+		return true
+	}
+	for _, id := range p.localityFilteredInstances {
+		if id == sqlInstanceID {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackSQLInstanceID returns the SQL instance that spans are assigned to
+// when the instance that would process them can't be used. That is the
+// gateway, unless the plan has a locality filter, in which case the instances
+// matching the filter are used in turn.
+func (p *PlanningCtx) fallbackSQLInstanceID(gateway base.SQLInstanceID) base.SQLInstanceID {
+	if len(p.localityFilter.Tiers) == 0 {
+		return gateway
+	}
+	id := p.localityFilteredInstances[p.nextLocalityFilteredInstance%len(p.localityFilteredInstances)]
+	p.nextLocalityFilteredInstance++
+	return id
 }
 
 var _ physicalplan.ExprContext = &PlanningCtx{}
@@ -1006,9 +1043,25 @@ func (h *distSQLNodeHealth) checkSystem(
 	}
 
 	// Check that the node is not draining.
+	draining, err := h.isDrainingSystem(sqlInstanceID)
+	if err != nil {
+		return err
+	}
+	if draining {
+		err := errors.Newf("not using n%d because it is draining", sqlInstanceID)
+		log.VEventf(ctx, 1, "%v", err)
+		return err
+	}
+
+	return nil
+}
+
+// isDrainingSystem returns whether the node has gossiped that it is draining.
+// It should only be used by the system tenant.
+func (h *distSQLNodeHealth) isDrainingSystem(sqlInstanceID base.SQLInstanceID) (bool, error) {
 	g, ok := h.gossip.Optional(distsql.MultiTenancyIssueNo)
 	if !ok {
-		return errors.AssertionFailedf("gossip is expected to be available for the system tenant")
+		return false, errors.AssertionFailedf("gossip is expected to be available for the system tenant")
 	}
 	drainingInfo := &execinfrapb.DistSQLDrainingInfo{}
 	if err := g.GetInfoProto(gossip.MakeDistSQLDrainingKey(sqlInstanceID), drainingInfo); err != nil {
@@ -1019,16 +1072,9 @@ func (h *distSQLNodeHealth) checkSystem(
 		// that the node is ready. We therefore return no
 		// error.
 		// TODO(ajwerner): Determine the expected error types and only filter those.
-		return nil //nolint:returnerrcheck
-	}
-
-	if drainingInfo.Draining {
-		err := errors.Newf("not using n%d because it is draining", sqlInstanceID)
-		log.VEventf(ctx, 1, "%v", err)
-		return err
+		return false, nil //nolint:returnerrcheck
 	}
-
-	return nil
+	return drainingInfo.Draining, nil
 }
 
 // nodeVersionIsCompatibleSystem decides whether a particular node's DistSQL
@@ -1230,7 +1276,7 @@ func (dsp *DistSQLPlanner) partitionSpansSystem(
 func (dsp *DistSQLPlanner) partitionSpansTenant(
 	ctx context.Context, planCtx *PlanningCtx, spans roachpb.Spans,
 ) (partitions []SpanPartition, _ error) {
-	resolver, instances, hasLocalitySet, err := dsp.makeSQLInstanceIDForKVNodeIDTenantResolver(ctx)
+	resolver, instances, hasLocalitySet, err := dsp.makeSQLInstanceIDForKVNodeIDTenantResolver(ctx, planCtx)
 	if err != nil {
 		return nil, err
 	}
@@ -1259,8 +1305,11 @@ func (dsp *DistSQLPlanner) partitionSpansTenant(
 			return nil, err
 		}
 	}
-	if err = dsp.maybeReassignToGatewaySQLInstance(partitions, instances, hasLocalitySet); err != nil {
-		return nil, err
+	// The gateway may not match the locality filter of the plan, if any.
+	if len(planCtx.localityFilter.Tiers) == 0 {
+		if err = dsp.maybeReassignToGatewaySQLInstance(partitions, instances, hasLocalitySet); err != nil {
+			return nil, err
+		}
 	}
 	return partitions, nil
 }
@@ -1279,7 +1328,12 @@ func (dsp *DistSQLPlanner) getSQLInstanceIDForKVNodeIDSystem(
 	// address indicates an unhealthy host.
 	if status != NodeOK {
 		log.Eventf(ctx, "not planning on node %d: %s", sqlInstanceID, status)
-		sqlInstanceID = dsp.gatewaySQLInstanceID
+		return planCtx.fallbackSQLInstanceID(dsp.gatewaySQLInstanceID)
+	}
+	if !planCtx.matchesLocalityFilter(sqlInstanceID) {
+		log.Eventf(ctx, "not planning on node %d: locality does not match %s",
+			sqlInstanceID, planCtx.localityFilter)
+		return planCtx.fallbackSQLInstanceID(dsp.gatewaySQLInstanceID)
 	}
 	return sqlInstanceID
 }
@@ -1290,7 +1344,7 @@ func (dsp *DistSQLPlanner) getSQLInstanceIDForKVNodeIDSystem(
 // boolean indicating whether the locality information is available for at least
 // some of those instances.
 func (dsp *DistSQLPlanner) makeSQLInstanceIDForKVNodeIDTenantResolver(
-	ctx context.Context,
+	ctx context.Context, planCtx *PlanningCtx,
 ) (
 	resolver func(roachpb.NodeID) base.SQLInstanceID,
 	_ []sqlinstance.InstanceInfo,
@@ -1306,6 +1360,15 @@ func (dsp *DistSQLPlanner) makeSQLInstanceIDForKVNodeIDTenantResolver(
 	if err != nil {
 		return nil, nil, false, err
 	}
+	if len(planCtx.localityFilter.Tiers) > 0 {
+		matching := instances[:0]
+		for _, instance := range instances {
+			if planCtx.matchesLocalityFilter(instance.InstanceID) {
+				matching = append(matching, instance)
+			}
+		}
+		instances = matching
+	}
 	if len(instances) == 0 {
 		return nil, nil, false, errors.New("no healthy sql instances available for planning")
 	}
@@ -1335,12 +1398,12 @@ func (dsp *DistSQLPlanner) makeSQLInstanceIDForKVNodeIDTenantResolver(
 			nodeDesc, err := dsp.nodeDescs.GetNodeDescriptor(nodeID)
 			if err != nil {
 				log.Eventf(ctx, "unable to get node descriptor for KV node %s", nodeID)
-				return dsp.gatewaySQLInstanceID
+				return planCtx.fallbackSQLInstanceID(dsp.gatewaySQLInstanceID)
 			}
 			region, ok := nodeDesc.Locality.Find("region")
 			if !ok {
 				log.Eventf(ctx, "could not find region for KV node %s", nodeDesc)
-				return dsp.gatewaySQLInstanceID
+				return planCtx.fallbackSQLInstanceID(dsp.gatewaySQLInstanceID)
 			}
 			instancesInRegion, ok := regionToSQLInstanceIDs[region]
 			if !ok {
@@ -1348,7 +1411,7 @@ func (dsp *DistSQLPlanner) makeSQLInstanceIDForKVNodeIDTenantResolver(
 				// gateway.
 				// TODO(yuzefovich): we should instead pick the closest instance
 				// in a different region.
-				return dsp.gatewaySQLInstanceID
+				return planCtx.fallbackSQLInstanceID(dsp.gatewaySQLInstanceID)
 			}
 			// Pick a random instance in this region in order to spread the
 			// load.
@@ -1459,7 +1522,7 @@ func (dsp *DistSQLPlanner) getInstanceIDForScan(
 	if dsp.codec.ForSystemTenant() {
 		return dsp.getSQLInstanceIDForKVNodeIDSystem(ctx, planCtx, replDesc.NodeID), nil
 	}
-	resolver, _, _, err := dsp.makeSQLInstanceIDForKVNodeIDTenantResolver(ctx)
+	resolver, _, _, err := dsp.makeSQLInstanceIDForKVNodeIDTenantResolver(ctx, planCtx)
 	if err != nil {
 		return 0, err
 	}
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/errors"
@@ -25,22 +26,57 @@ import (
 func (dsp *DistSQLPlanner) SetupAllNodesPlanning(
 	ctx context.Context, evalCtx *extendedEvalContext, execCfg *ExecutorConfig,
 ) (*PlanningCtx, []base.SQLInstanceID, error) {
+	return dsp.SetupAllNodesPlanningWithLocalityFilter(ctx, evalCtx, execCfg, roachpb.Locality{})
+}
+
+// SetupAllNodesPlanningWithLocalityFilter is like SetupAllNodesPlanning, but
+// if the locality filter is not empty, it only returns the nodes whose
+// locality matches the filter, and the spans partitioned with the returned
+// planCtx are only assigned to those nodes. It returns an error if no healthy
+// node matches the filter.
+func (dsp *DistSQLPlanner) SetupAllNodesPlanningWithLocalityFilter(
+	ctx context.Context,
+	evalCtx *extendedEvalContext,
+	execCfg *ExecutorConfig,
+	localityFilter roachpb.Locality,
+) (*PlanningCtx, []base.SQLInstanceID, error) {
+	var planCtx *PlanningCtx
+	var sqlInstanceIDs []base.SQLInstanceID
+	var err error
 	if dsp.codec.ForSystemTenant() {
-		return dsp.setupAllNodesPlanningSystem(ctx, evalCtx, execCfg)
+		planCtx, sqlInstanceIDs, err = dsp.setupAllNodesPlanningSystem(ctx, evalCtx, execCfg, localityFilter)
+	} else {
+		planCtx, sqlInstanceIDs, err = dsp.setupAllNodesPlanningTenant(ctx, evalCtx, execCfg, localityFilter)
 	}
-	return dsp.setupAllNodesPlanningTenant(ctx, evalCtx, execCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(localityFilter.Tiers) > 0 {
+		if len(sqlInstanceIDs) == 0 {
+			return nil, nil, errors.Newf("no healthy nodes match the locality filter %s", localityFilter)
+		}
+		planCtx.localityFilter = localityFilter
+		planCtx.localityFilteredInstances = sqlInstanceIDs
+	}
+	return planCtx, sqlInstanceIDs, nil
 }
 
 // setupAllNodesPlanningSystem creates a planCtx and returns all nodes available
-// in a system tenant.
+// in a system tenant whose locality matches the locality filter, if any.
 func (dsp *DistSQLPlanner) setupAllNodesPlanningSystem(
-	ctx context.Context, evalCtx *extendedEvalContext, execCfg *ExecutorConfig,
+	ctx context.Context,
+	evalCtx *extendedEvalContext,
+	execCfg *ExecutorConfig,
+	localityFilter roachpb.Locality,
 ) (*PlanningCtx, []base.SQLInstanceID, error) {
 	planCtx := dsp.NewPlanningCtx(ctx, evalCtx, nil /* planner */, nil, /* txn */
 		DistributionTypeAlways)
 
 	ss, err := execCfg.NodesStatusServer.OptionalNodesStatusServer(47900)
 	if err != nil {
+		if len(localityFilter.Tiers) > 0 {
+			return nil, nil, errors.Wrap(err, "filtering nodes by locality")
+		}
 		return planCtx, []base.SQLInstanceID{dsp.gatewaySQLInstanceID}, nil //nolint:returnerrcheck
 	}
 	resp, err := ss.ListNodesInternal(ctx, &serverpb.NodesRequest{})
@@ -50,12 +86,17 @@ func (dsp *DistSQLPlanner) setupAllNodesPlanningSystem(
 	// Because we're not going through the normal pathways, we have to set up the
 	// planCtx.nodeStatuses map ourselves. checkInstanceHealthAndVersionSystem() will
 	// populate it.
+	matching := make(map[base.SQLInstanceID]struct{}, len(resp.Nodes))
 	for _, node := range resp.Nodes {
-		_ /* NodeStatus */ = dsp.checkInstanceHealthAndVersionSystem(ctx, planCtx, base.SQLInstanceID(node.Desc.NodeID))
+		sqlInstanceID := base.SQLInstanceID(node.Desc.NodeID)
+		_ /* NodeStatus */ = dsp.checkInstanceHealthAndVersionSystem(ctx, planCtx, sqlInstanceID)
+		if ok, _ := node.Desc.Locality.Matches(localityFilter); ok {
+			matching[sqlInstanceID] = struct{}{}
+		}
 	}
 	nodes := make([]base.SQLInstanceID, 0, len(planCtx.nodeStatuses))
 	for nodeID, status := range planCtx.nodeStatuses {
-		if status == NodeOK {
+		if _, ok := matching[nodeID]; ok && status == NodeOK {
 			nodes = append(nodes, nodeID)
 		}
 	}
@@ -63,9 +104,12 @@ func (dsp *DistSQLPlanner) setupAllNodesPlanningSystem(
 }
 
 // setupAllNodesPlanningTenant creates a planCtx and returns all nodes available
-// in a non-system tenant.
+// in a non-system tenant whose locality matches the locality filter, if any.
 func (dsp *DistSQLPlanner) setupAllNodesPlanningTenant(
-	ctx context.Context, evalCtx *extendedEvalContext, execCfg *ExecutorConfig,
+	ctx context.Context,
+	evalCtx *extendedEvalContext,
+	execCfg *ExecutorConfig,
+	localityFilter roachpb.Locality,
 ) (*PlanningCtx, []base.SQLInstanceID, error) {
 	if dsp.sqlInstanceProvider == nil {
 		return nil, nil, errors.New("sql instance provider not available in multi-tenant environment")
@@ -76,9 +120,11 @@ func (dsp *DistSQLPlanner) setupAllNodesPlanningTenant(
 	if err != nil {
 		return nil, nil, err
 	}
-	sqlInstanceIDs := make([]base.SQLInstanceID, len(pods))
-	for i, pod := range pods {
-		sqlInstanceIDs[i] = pod.InstanceID
+	sqlInstanceIDs := make([]base.SQLInstanceID, 0, len(pods))
+	for _, pod := range pods {
+		if ok, _ := pod.Locality.Matches(localityFilter); ok {
+			sqlInstanceIDs = append(sqlInstanceIDs, pod.InstanceID)
+		}
 	}
 	return planCtx, sqlInstanceIDs, nil
 }
@@ -185,3 +231,63 @@ func PhysicalPlanChangeChecker(
 		}
 	}, func() { close(stop) }
 }
+
+// DrainingInstanceChecker returns a function which will periodically check, at
+// the requested interval until the returned function is called, whether any
+// instance other than the gateway on which the passed plan placed processors
+// has begun draining, returning ErrPlanChanged if one has. Planning does not
+// place processors on draining instances, so the flow can then be re-planned
+// away from it and resumed from its last checkpoint, rather than be canceled
+// when the drain gives up waiting for it. A frequency of 0 disables it, as does
+// running in a secondary tenant, whose instances do not gossip their draining.
+func DrainingInstanceChecker(
+	initial *PhysicalPlan,
+	execCtx interface{ DistSQLPlanner() *DistSQLPlanner },
+	freq func() time.Duration,
+) (func(context.Context) error, func()) {
+	stop := make(chan struct{})
+
+	return func(ctx context.Context) error {
+		dsp := execCtx.DistSQLPlanner()
+		if !dsp.codec.ForSystemTenant() {
+			return nil
+		}
+		instances := make(map[base.SQLInstanceID]struct{})
+		for i := range initial.Processors {
+			if id := initial.Processors[i].SQLInstanceID; id != initial.GatewaySQLInstanceID {
+				instances[id] = struct{}{}
+			}
+		}
+		if len(instances) == 0 {
+			return nil
+		}
+
+		var tickC <-chan time.Time
+		if f := freq(); f > 0 {
+			tick := time.NewTicker(f)
+			defer tick.Stop()
+			tickC = tick.C
+		}
+		done := ctx.Done()
+		for {
+			select {
+			case <-stop:
+				return nil
+			case <-done:
+				return ctx.Err()
+			case <-tickC:
+				for id := range instances {
+					draining, err := dsp.nodeHealth.isDrainingSystem(id)
+					if err != nil {
+						log.Warningf(ctx, "job draining check failed: %v", err)
+						continue
+					}
+					if draining {
+						log.Infof(ctx, "n%d running part of the flow is draining, re-planning", id)
+						return ErrPlanChanged
+					}
+				}
+			}
+		}
+	}, func() { close(stop) }
+}
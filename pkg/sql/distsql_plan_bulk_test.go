@@ -11,12 +11,22 @@
 package sql
 
 import (
+	"context"
 	"math"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
 	"github.com/cockroachdb/cockroach/pkg/sql/physicalplan"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/stretchr/testify/require"
 )
 
@@ -83,3 +93,49 @@ func TestCalculatePlanGrowth(t *testing.T) {
 		})
 	}
 }
+
+type testDistSQLPlannerGetter struct{ dsp *DistSQLPlanner }
+
+func (g testDistSQLPlannerGetter) DistSQLPlanner() *DistSQLPlanner { return g.dsp }
+
+func TestDrainingInstanceChecker(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+
+	mockGossip := gossip.NewTest(roachpb.NodeID(1), nil /* rpcContext */, nil, /* grpcServer */
+		stopper, metric.NewRegistry(), zonepb.DefaultZoneConfigRef())
+	execCtx := testDistSQLPlannerGetter{&DistSQLPlanner{
+		codec:      keys.SystemSQLCodec,
+		nodeHealth: distSQLNodeHealth{gossip: gossip.MakeOptionalGossip(mockGossip)},
+	}}
+	setDraining := func(id base.SQLInstanceID) {
+		require.NoError(t, mockGossip.AddInfoProto(gossip.MakeDistSQLDrainingKey(id),
+			&execinfrapb.DistSQLDrainingInfo{Draining: true}, 0 /* ttl */))
+	}
+
+	plan := &PhysicalPlan{}
+	plan.PhysicalInfrastructure = &physicalplan.PhysicalInfrastructure{
+		GatewaySQLInstanceID: 1,
+		Processors:           []physicalplan.Processor{{SQLInstanceID: 1}, {SQLInstanceID: 2}, {SQLInstanceID: 3}},
+	}
+	freq := func() time.Duration { return time.Millisecond }
+
+	// A new plan would place processors on a draining gateway all the same, so
+	// its draining does not change the plan.
+	setDraining(1)
+	check, stopCheck := DrainingInstanceChecker(plan, execCtx, freq)
+	errCh := make(chan error, 1)
+	go func() { errCh <- check(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	stopCheck()
+	require.NoError(t, <-errCh)
+
+	setDraining(3)
+	check, stopCheck = DrainingInstanceChecker(plan, execCtx, freq)
+	defer stopCheck()
+	require.ErrorIs(t, check(ctx), ErrPlanChanged)
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"reflect"
 	"regexp"
@@ -1330,6 +1331,9 @@ type ExecutorConfig struct {
 	// RangeStatsFetcher is used to fetch RangeStats.
 	RangeStatsFetcher eval.RangeStatsFetcher
 
+	// StorageStatsCache serves crdb_internal.database_storage_stats.
+	StorageStatsCache *StorageStatsCache
+
 	// EventsExporter is the client for the Observability Service.
 	EventsExporter obs.EventsExporter
 }
@@ -1596,6 +1600,10 @@ type BackupRestoreTestingKnobs struct {
 	// testing. This is typically the bulk mem monitor if not
 	// specified here.
 	BackupMemMonitor *mon.BytesMonitor
+
+	// LatestWebhookClient, if set, is the client used to notify the
+	// bulkio.backup.latest_webhook.url webhook.
+	LatestWebhookClient *http.Client
 }
 
 var _ base.ModuleTestingKnobs = &BackupRestoreTestingKnobs{}
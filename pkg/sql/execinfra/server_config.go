@@ -118,6 +118,11 @@ type ServerConfig struct {
 	// the processes in a given sql server when sending bulk ingest (AddSST) reqs.
 	BulkSenderLimiter limit.ConcurrentRequestLimiter
 
+	// BackupLocalityWriteLimiters are the write rate limiters that are shared
+	// across all of the backup processors in a given sql server writing to the
+	// destinations of locality-aware backups.
+	BackupLocalityWriteLimiters *cloud.LocalityWriteLimiters
+
 	// ParentDiskMonitor is normally the root disk monitor. It should only be used
 	// when setting up a server, a child monitor (usually belonging to a sql
 	// execution flow), or in tests. It is used to monitor temporary storage disk
@@ -183,6 +188,11 @@ type ServerConfig struct {
 	// SQLSQLResponseWork.
 	SQLSQLResponseAdmissionQ *admission.WorkQueue
 
+	// AdmissionPacerFactory is used to integrate elastic work done by
+	// processors, like the writing of backup files, with admission control. It
+	// is nil on tenant servers.
+	AdmissionPacerFactory admission.PacerFactory
+
 	// CollectionFactory is used to construct descs.Collections.
 	CollectionFactory *descs.CollectionFactory
 
@@ -72,6 +72,11 @@ func (m *ExportSpec) User() username.SQLUsername {
 	return m.UserProto.Decode()
 }
 
+// User accesses the user field.
+func (m *RestoreDataSpec) User() username.SQLUsername {
+	return m.UserProto.Decode()
+}
+
 // User accesses the user field.
 func (m *ReadImportDataSpec) User() username.SQLUsername {
 	return m.UserProto.Decode()
@@ -17,6 +17,8 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
 	"github.com/cockroachdb/cockroach/pkg/featureflag"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
@@ -143,6 +145,11 @@ func (ef *execFactory) ConstructExport(
 	if !ok {
 		return nil, errors.Errorf("expected string value for the file location")
 	}
+	if err := externalconn.CheckCapability(ef.ctx, ef.planner.ExecCfg().InternalExecutor,
+		ef.planner.Txn(), string(*destination), "EXPORT", connectionpb.CapabilityUnspecified,
+	); err != nil {
+		return nil, err
+	}
 	admin, err := ef.planner.HasAdminRole(ef.ctx)
 	if err != nil {
 		panic(err)
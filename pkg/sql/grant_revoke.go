@@ -14,6 +14,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catpb"
@@ -51,6 +52,11 @@ func (p *planner) Grant(ctx context.Context, n *tree.Grant) (planNode, error) {
 	if err := privilege.ValidatePrivileges(n.Privileges, grantOn); err != nil {
 		return nil, err
 	}
+	if (n.Privileges.Contains(privilege.RESTOREINTO) || n.Privileges.Contains(privilege.BACKUPFROM)) &&
+		!p.ExecCfg().Settings.Version.IsActive(ctx, clusterversion.V23_1FineGrainedRestorePrivileges) {
+		return nil, errors.Newf("the RESTOREINTO and BACKUPFROM privileges are not supported until upgrade to version %s is finalized",
+			clusterversion.V23_1FineGrainedRestorePrivileges.String())
+	}
 
 	grantees, err := decodeusername.FromRoleSpecList(
 		p.SessionData(), username.PurposeValidation, n.Grantees,
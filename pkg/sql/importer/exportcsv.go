@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalioaudit"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
@@ -178,6 +179,7 @@ func (sp *csvWriter) MustBeStreaming() bool {
 func (sp *csvWriter) Run(ctx context.Context) {
 	ctx, span := tracing.ChildSpan(ctx, "csvWriter")
 	defer span.Finish()
+	ctx = externalioaudit.WithPrincipal(ctx, sp.spec.User(), 0 /* jobID */)
 
 	instanceID := sp.flowCtx.EvalCtx.NodeID.SQLInstanceID()
 	uniqueID := builtins.GenerateUniqueInt(builtins.ProcessUniqueID(instanceID))
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalioaudit"
 	"github.com/cockroachdb/cockroach/pkg/geo"
 	"github.com/cockroachdb/cockroach/pkg/geo/geopb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -728,6 +729,7 @@ func (sp *parquetWriterProcessor) MustBeStreaming() bool {
 func (sp *parquetWriterProcessor) Run(ctx context.Context) {
 	ctx, span := tracing.ChildSpan(ctx, "parquetWriter")
 	defer span.Finish()
+	ctx = externalioaudit.WithPrincipal(ctx, sp.spec.User(), 0 /* jobID */)
 
 	instanceID := sp.flowCtx.EvalCtx.NodeID.SQLInstanceID()
 	uniqueID := builtins.GenerateUniqueInt(builtins.ProcessUniqueID(instanceID))
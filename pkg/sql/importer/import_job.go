@@ -14,6 +14,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"time"
 
@@ -939,7 +940,13 @@ func parseAndCreateBundleTableDescs(
 			format.PgDump.IgnoreUnsupported, format.PgDump.IgnoreUnsupportedLog, schemaParsing,
 			p.ExecCfg().DistSQLSrv.ExternalStorage)
 
-		tableDescs, schemaDescs, err = readPostgresCreateTable(ctx, reader, evalCtx, p, tableName,
+		var dump io.Reader
+		dump, err = maybeReadPgDumpArchive(ctx, reader,
+			makePgDumpPartOpener(files[0], p.ExecCfg().DistSQLSrv.ExternalStorage, p.User()))
+		if err != nil {
+			return tableDescs, schemaDescs, err
+		}
+		tableDescs, schemaDescs, err = readPostgresCreateTable(ctx, dump, evalCtx, p, tableName,
 			parentDB, walltime, fks, int(format.PgDump.MaxRowSize), owner, unsupportedStmtLogger)
 
 		logErr := unsupportedStmtLogger.flush()
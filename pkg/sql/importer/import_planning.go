@@ -23,6 +23,8 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/cloud"
 	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn"
+	"github.com/cockroachdb/cockroach/pkg/cloud/externalconn/connectionpb"
 	"github.com/cockroachdb/cockroach/pkg/featureflag"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
@@ -403,6 +405,10 @@ func importPlanHook(
 			if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, []string{file}); err != nil {
 				return err
 			}
+			if err := externalconn.CheckCapability(ctx, p.ExecCfg().InternalExecutor, p.Txn(),
+				file, "IMPORT", connectionpb.CapabilityUnspecified); err != nil {
+				return err
+			}
 		}
 
 		var files []string
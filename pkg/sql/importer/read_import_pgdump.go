@@ -970,6 +970,12 @@ type pgDumpReader struct {
 	jobID                 int64
 	unsupportedStmtLogger *unsupportedStmtLogger
 	evalCtx               *eval.Context
+
+	// dataFiles, makeExternalStorage and user are used to read the data of the
+	// tables of directory format archives, which is kept next to their TOC.
+	dataFiles           map[int32]string
+	makeExternalStorage cloud.ExternalStorageFactory
+	user                username.SQLUsername
 }
 
 var _ inputConverter = &pgDumpReader{}
@@ -1041,6 +1047,7 @@ func (m *pgDumpReader) readFiles(
 	m.unsupportedStmtLogger = makeUnsupportedStmtLogger(ctx, user,
 		m.jobID, format.PgDump.IgnoreUnsupported, format.PgDump.IgnoreUnsupportedLog, dataIngestion,
 		makeExternalStorage)
+	m.dataFiles, m.makeExternalStorage, m.user = dataFiles, makeExternalStorage, user
 
 	err := readInputFiles(ctx, dataFiles, resumePos, format, m.readFile, makeExternalStorage, user)
 	if err != nil {
@@ -1062,7 +1069,12 @@ func (m *pgDumpReader) readFile(
 	tableNameToRowsProcessed := make(map[string]int64)
 	var inserts, count int64
 	rowLimit := m.opts.RowLimit
-	ps := newPostgreStream(ctx, input, int(m.opts.MaxRowSize), m.unsupportedStmtLogger)
+	in, err := maybeReadPgDumpArchive(ctx, input,
+		makePgDumpPartOpener(m.dataFiles[inputIdx], m.makeExternalStorage, m.user))
+	if err != nil {
+		return err
+	}
+	ps := newPostgreStream(ctx, in, int(m.opts.MaxRowSize), m.unsupportedStmtLogger)
 	semaCtx := tree.MakeSemaContext()
 	for _, conv := range m.tables {
 		conv.KvBatch.Source = inputIdx
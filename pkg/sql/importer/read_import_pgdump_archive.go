@@ -0,0 +1,558 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/errors"
+)
+
+// pgDumpArchiveMagic starts the archives pg_dump writes in its custom (-Fc)
+// and directory (-Fd) formats. In the directory format it starts the toc.dat
+// file, which is the file IMPORT PGDUMP is pointed at.
+const pgDumpArchiveMagic = "PGDMP"
+
+// The formats of pg_dump archives, as recorded in their header.
+const (
+	pgDumpArchiveCustom    = 1
+	pgDumpArchiveDirectory = 5
+)
+
+// The compression algorithms of pg_dump archives since version 1.15.
+const (
+	pgDumpCompressionNone = 0
+	pgDumpCompressionGzip = 1
+)
+
+// The types of the data blocks of custom format archives.
+const (
+	pgDumpBlockData  = 1
+	pgDumpBlockBlobs = 3
+)
+
+// pgDumpOffsetNoData is the offset flag of the TOC entries of custom format
+// archives which have no data block.
+const pgDumpOffsetNoData = 3
+
+func pgDumpArchiveVersion(major, minor, rev int) int {
+	return (major*256+minor)*256 + rev
+}
+
+// The range of archive versions that can be read, i.e. those written by
+// pg_dump 9.0 and later.
+var (
+	pgDumpMinArchiveVersion = pgDumpArchiveVersion(1, 12, 0)
+	pgDumpMaxArchiveVersion = pgDumpArchiveVersion(1, 16, 0)
+)
+
+// pgDumpPartOpener opens a file of a directory format archive by its name
+// relative to the archive's toc.dat.
+type pgDumpPartOpener func(ctx context.Context, name string) (io.ReadCloser, error)
+
+// makePgDumpPartOpener returns a pgDumpPartOpener which resolves the files of
+// the archive whose toc.dat is at uri by replacing the last element of its
+// path, so they are read with the same storage and credentials.
+func makePgDumpPartOpener(
+	uri string, makeExternalStorage cloud.ExternalStorageFactory, user username.SQLUsername,
+) pgDumpPartOpener {
+	return func(ctx context.Context, name string) (io.ReadCloser, error) {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		u.Path = path.Join(path.Dir(u.Path), name)
+		conf, err := cloud.ExternalStorageConfFromURI(u.String(), user)
+		if err != nil {
+			return nil, err
+		}
+		es, err := makeExternalStorage(ctx, conf)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := es.ReadFile(ctx, "")
+		if err != nil {
+			es.Close()
+			return nil, errors.Wrapf(err, "reading pg_dump archive file %s", name)
+		}
+		return &pgDumpPart{Reader: ioctx.ReaderCtxAdapter(ctx, raw), ctx: ctx, raw: raw, es: es}, nil
+	}
+}
+
+type pgDumpPart struct {
+	io.Reader
+	ctx context.Context
+	raw ioctx.ReadCloserCtx
+	es  cloud.ExternalStorage
+}
+
+func (p *pgDumpPart) Close() error {
+	err := p.raw.Close(p.ctx)
+	return errors.CombineErrors(err, p.es.Close())
+}
+
+// maybeReadPgDumpArchive returns a reader of the SQL statements of the dump in
+// r. If r is a pg_dump archive, the statements are those pg_restore would print
+// for it, in the order of its TOC; otherwise r is returned as is.
+func maybeReadPgDumpArchive(
+	ctx context.Context, r io.Reader, openPart pgDumpPartOpener,
+) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(pgDumpArchiveMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, []byte(pgDumpArchiveMagic)) {
+		return br, nil
+	}
+	a := &pgDumpArchive{r: br, openPart: openPart}
+	if err := a.readHeader(); err != nil {
+		return nil, errors.Wrap(err, "reading pg_dump archive header")
+	}
+	if err := a.readTOC(); err != nil {
+		return nil, errors.Wrap(err, "reading pg_dump archive TOC")
+	}
+	return &pgDumpArchiveStream{ctx: ctx, a: a}, nil
+}
+
+// pgDumpArchive reads the header and TOC of a pg_dump archive and, in the
+// custom format, the data blocks which follow them.
+type pgDumpArchive struct {
+	r        *bufio.Reader
+	openPart pgDumpPartOpener
+
+	version    int
+	intSize    int
+	offSize    int
+	format     byte
+	compressed bool
+	entries    []pgDumpTOCEntry
+}
+
+// pgDumpTOCEntry is the part of a TOC entry needed to print its statements.
+type pgDumpTOCEntry struct {
+	dumpID int
+	desc   string
+	// defn is the statement creating the object of the entry, if any.
+	defn string
+	// copyStmt is the COPY statement preceding the data of the entry, which is
+	// empty if the data consists of INSERT statements.
+	copyStmt string
+	hasData  bool
+	// filename is the file holding the data of the entry in directory format
+	// archives.
+	filename string
+}
+
+func (a *pgDumpArchive) readHeader() error {
+	if _, err := a.r.Discard(len(pgDumpArchiveMagic)); err != nil {
+		return err
+	}
+	var v [6]byte
+	if _, err := io.ReadFull(a.r, v[:]); err != nil {
+		return err
+	}
+	a.version = pgDumpArchiveVersion(int(v[0]), int(v[1]), int(v[2]))
+	if a.version < pgDumpMinArchiveVersion || a.version > pgDumpMaxArchiveVersion {
+		return errors.Newf("unsupported archive version %d.%d.%d", v[0], v[1], v[2])
+	}
+	a.intSize, a.offSize, a.format = int(v[3]), int(v[4]), v[5]
+	if a.intSize > 8 || a.offSize > 8 {
+		return errors.Newf("unsupported integer size %d or offset size %d", a.intSize, a.offSize)
+	}
+	if a.format != pgDumpArchiveCustom && a.format != pgDumpArchiveDirectory {
+		return errors.Newf("unsupported archive format %d, only the custom and directory formats "+
+			"can be imported", a.format)
+	}
+
+	if a.version >= pgDumpArchiveVersion(1, 15, 0) {
+		algorithm, err := a.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch algorithm {
+		case pgDumpCompressionNone:
+		case pgDumpCompressionGzip:
+			a.compressed = true
+		default:
+			return errors.Newf("unsupported compression algorithm %d, only gzip compressed "+
+				"archives can be imported", algorithm)
+		}
+	} else {
+		level, err := a.readInt()
+		if err != nil {
+			return err
+		}
+		a.compressed = level != 0
+	}
+
+	// The creation time, followed by the names of the database and the versions
+	// of the server and of pg_dump.
+	for i := 0; i < 7; i++ {
+		if _, err := a.readInt(); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := a.readStr(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *pgDumpArchive) readTOC() error {
+	n, err := a.readInt()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return errors.Newf("invalid TOC entry count %d", n)
+	}
+	a.entries = make([]pgDumpTOCEntry, n)
+	for i := range a.entries {
+		if err := a.readTOCEntry(&a.entries[i]); err != nil {
+			return errors.Wrapf(err, "entry %d", i)
+		}
+	}
+	return nil
+}
+
+func (a *pgDumpArchive) readTOCEntry(e *pgDumpTOCEntry) error {
+	var err error
+	if e.dumpID, err = a.readInt(); err != nil {
+		return err
+	}
+	hadDumper, err := a.readInt()
+	if err != nil {
+		return err
+	}
+	e.hasData = hadDumper != 0
+
+	// The table OID, OID and tag of the object.
+	if err := a.skipStrs(3); err != nil {
+		return err
+	}
+	if e.desc, _, err = a.readStr(); err != nil {
+		return err
+	}
+	// The section.
+	if _, err := a.readInt(); err != nil {
+		return err
+	}
+	if e.defn, _, err = a.readStr(); err != nil {
+		return err
+	}
+	// The drop statement.
+	if err := a.skipStrs(1); err != nil {
+		return err
+	}
+	if e.copyStmt, _, err = a.readStr(); err != nil {
+		return err
+	}
+	// The namespace and tablespace.
+	if err := a.skipStrs(2); err != nil {
+		return err
+	}
+	if a.version >= pgDumpArchiveVersion(1, 14, 0) {
+		// The table access method.
+		if err := a.skipStrs(1); err != nil {
+			return err
+		}
+	}
+	if a.version >= pgDumpArchiveVersion(1, 16, 0) {
+		// The relkind.
+		if _, err := a.readInt(); err != nil {
+			return err
+		}
+	}
+	// The owner and whether the table has OIDs.
+	if err := a.skipStrs(2); err != nil {
+		return err
+	}
+	// The dependencies, terminated by a null string.
+	for {
+		_, isNull, err := a.readStr()
+		if err != nil {
+			return err
+		}
+		if isNull {
+			break
+		}
+	}
+
+	switch a.format {
+	case pgDumpArchiveCustom:
+		flag, err := a.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if _, err := a.r.Discard(a.offSize); err != nil {
+			return err
+		}
+		if flag == pgDumpOffsetNoData {
+			e.hasData = false
+		}
+	case pgDumpArchiveDirectory:
+		if e.filename, _, err = a.readStr(); err != nil {
+			return err
+		}
+		if e.filename == "" {
+			e.hasData = false
+		}
+	}
+	return nil
+}
+
+// readInt reads an integer, which is stored as a sign byte followed by intSize
+// bytes of its absolute value, least significant first.
+func (a *pgDumpArchive) readInt() (int, error) {
+	sign, err := a.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for i := 0; i < a.intSize; i++ {
+		b, err := a.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b) << (8 * i)
+	}
+	if sign != 0 {
+		return -int(v), nil
+	}
+	return int(v), nil
+}
+
+// readStr reads a string, which is stored as its length followed by its bytes.
+// A negative length denotes a null string.
+func (a *pgDumpArchive) readStr() (_ string, isNull bool, _ error) {
+	n, err := a.readInt()
+	if err != nil {
+		return "", false, err
+	}
+	if n < 0 {
+		return "", true, nil
+	}
+	var b strings.Builder
+	if _, err := io.CopyN(&b, a.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", false, err
+	}
+	return b.String(), false, nil
+}
+
+func (a *pgDumpArchive) skipStrs(n int) error {
+	for i := 0; i < n; i++ {
+		if _, _, err := a.readStr(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pgDumpChunkReader reads the data of a block of a custom format archive, which
+// is stored as chunks prefixed by their length and terminated by an empty one.
+type pgDumpChunkReader struct {
+	a         *pgDumpArchive
+	remaining int
+	done      bool
+}
+
+func (c *pgDumpChunkReader) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		n, err := c.a.readInt()
+		if err != nil {
+			return 0, err
+		}
+		if n < 0 {
+			return 0, errors.Newf("invalid data chunk length %d", n)
+		}
+		c.remaining = n
+		c.done = n == 0
+	}
+	if len(p) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.a.r.Read(p)
+	c.remaining -= n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// pgDumpArchiveStream prints the statements of the entries of an archive.
+type pgDumpArchiveStream struct {
+	ctx  context.Context
+	a    *pgDumpArchive
+	next int
+	cur  io.Reader
+	// finish is called once cur is exhausted, to consume what remains of the
+	// data of the current entry and release the resources used to read it.
+	finish func() error
+}
+
+var _ io.Reader = &pgDumpArchiveStream{}
+
+func (s *pgDumpArchiveStream) Read(p []byte) (int, error) {
+	for {
+		if s.cur == nil {
+			if s.next == len(s.a.entries) {
+				return 0, io.EOF
+			}
+			if err := s.startEntry(&s.a.entries[s.next]); err != nil {
+				return 0, errors.Wrapf(err, "reading pg_dump archive entry %d", s.a.entries[s.next].dumpID)
+			}
+			s.next++
+			continue
+		}
+		n, err := s.cur.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		s.cur = nil
+		if s.finish != nil {
+			finish := s.finish
+			s.finish = nil
+			if err := finish(); err != nil {
+				return n, err
+			}
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (s *pgDumpArchiveStream) startEntry(e *pgDumpTOCEntry) error {
+	var parts []io.Reader
+	if e.defn != "" {
+		parts = append(parts, strings.NewReader(e.defn), strings.NewReader("\n"))
+	}
+	if e.hasData {
+		data, finish, err := s.openData(e)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			parts = append(parts, strings.NewReader(e.copyStmt), data)
+		}
+		s.finish = finish
+	}
+	s.cur = io.MultiReader(parts...)
+	return nil
+}
+
+// openData returns a reader of the data of e, and a function to call once it
+// has been read. Large objects can't be imported, so the data of their entries
+// is skipped and the returned reader is nil.
+func (s *pgDumpArchiveStream) openData(e *pgDumpTOCEntry) (io.Reader, func() error, error) {
+	isBlobs := e.desc == "BLOBS"
+	if s.a.format == pgDumpArchiveDirectory {
+		if isBlobs {
+			return nil, nil, nil
+		}
+		name := e.filename
+		if s.a.compressed {
+			name += ".gz"
+		}
+		if s.a.openPart == nil {
+			return nil, nil, errors.Newf("cannot read file %s of a directory format archive", name)
+		}
+		rc, err := s.a.openPart(s.ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !s.a.compressed {
+			return rc, rc.Close, nil
+		}
+		gz, err := gzip.NewReader(rc)
+		if err != nil {
+			return nil, nil, errors.CombineErrors(err, rc.Close())
+		}
+		return gz, func() error { return errors.CombineErrors(gz.Close(), rc.Close()) }, nil
+	}
+
+	// The data blocks of custom format archives follow the TOC in its order.
+	typ, err := s.a.r.ReadByte()
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := s.a.readInt()
+	if err != nil {
+		return nil, nil, err
+	}
+	if id != e.dumpID {
+		return nil, nil, errors.Newf("found data of entry %d out of order; archives written "+
+			"to a non-seekable output or reordered can't be imported", id)
+	}
+	if isBlobs {
+		if typ != pgDumpBlockBlobs {
+			return nil, nil, errors.Newf("unexpected block type %d", typ)
+		}
+		return nil, s.skipBlobs, nil
+	}
+	if typ != pgDumpBlockData {
+		return nil, nil, errors.Newf("unexpected block type %d", typ)
+	}
+	chunks := &pgDumpChunkReader{a: s.a}
+	drain := func() error {
+		_, err := io.Copy(io.Discard, chunks)
+		return err
+	}
+	if !s.a.compressed {
+		return chunks, drain, nil
+	}
+	zr, err := zlib.NewReader(chunks)
+	if err != nil {
+		if err == io.EOF {
+			// The block holds no data.
+			return strings.NewReader(""), drain, nil
+		}
+		return nil, nil, err
+	}
+	return zr, func() error { return errors.CombineErrors(zr.Close(), drain()) }, nil
+}
+
+// skipBlobs consumes a block of large objects, each of which is stored as its
+// OID followed by its data. The block is terminated by an OID of 0.
+func (s *pgDumpArchiveStream) skipBlobs() error {
+	for {
+		oid, err := s.a.readInt()
+		if err != nil {
+			return err
+		}
+		if oid == 0 {
+			return nil
+		}
+		if _, err := io.Copy(io.Discard, &pgDumpChunkReader{a: s.a}); err != nil {
+			return err
+		}
+	}
+}
@@ -11,6 +11,9 @@
 package importer
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"fmt"
 	"io"
@@ -25,6 +28,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPostgreStream(t *testing.T) {
@@ -242,3 +247,192 @@ COPY t (array_column, array_column2) FROM STDIN;
 		})
 	}
 }
+
+// pgDumpArchiveWriter writes pg_dump archives for tests.
+type pgDumpArchiveWriter struct {
+	bytes.Buffer
+}
+
+func (w *pgDumpArchiveWriter) writeInt(v int) {
+	if v < 0 {
+		w.WriteByte(1)
+		v = -v
+	} else {
+		w.WriteByte(0)
+	}
+	for i := 0; i < 4; i++ {
+		w.WriteByte(byte(v >> (8 * i)))
+	}
+}
+
+func (w *pgDumpArchiveWriter) writeStr(s string) {
+	w.writeInt(len(s))
+	w.WriteString(s)
+}
+
+func TestPgDumpArchive(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	type entry struct {
+		desc, defn, copyStmt, data string
+	}
+	entries := []entry{
+		{desc: "ENCODING", defn: "SET client_encoding = 'UTF8';\n"},
+		{desc: "TABLE", defn: "CREATE TABLE public.t (\n    a integer\n);\n"},
+		{desc: "TABLE DATA", copyStmt: "COPY public.t (a) FROM stdin;\n", data: "1\n2\n\\.\n\n\n"},
+		{desc: "TABLE DATA", data: "INSERT INTO public.t VALUES (3);\n"},
+		{desc: "BLOBS", data: "blob"},
+		{desc: "CONSTRAINT", defn: "ALTER TABLE ONLY public.t\n    ADD CONSTRAINT t_pkey PRIMARY KEY (a);\n"},
+	}
+	const expected = "SET client_encoding = 'UTF8';\n\n" +
+		"CREATE TABLE public.t (\n    a integer\n);\n\n" +
+		"COPY public.t (a) FROM stdin;\n1\n2\n\\.\n\n\n" +
+		"INSERT INTO public.t VALUES (3);\n" +
+		"ALTER TABLE ONLY public.t\n    ADD CONSTRAINT t_pkey PRIMARY KEY (a);\n\n"
+
+	compress := func(t *testing.T, data string, gz bool) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		if gz {
+			w = gzip.NewWriter(&buf)
+		} else {
+			w = zlib.NewWriter(&buf)
+		}
+		_, err := w.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	writeArchive := func(t *testing.T, format byte, compressed bool) ([]byte, map[string][]byte) {
+		var w pgDumpArchiveWriter
+		w.WriteString(pgDumpArchiveMagic)
+		w.Write([]byte{1, 15, 0, 4, 8, format})
+		if compressed {
+			w.WriteByte(pgDumpCompressionGzip)
+		} else {
+			w.WriteByte(pgDumpCompressionNone)
+		}
+		for i := 0; i < 7; i++ {
+			w.writeInt(0)
+		}
+		for _, s := range []string{"db", "15.1", "15.1"} {
+			w.writeStr(s)
+		}
+
+		parts := make(map[string][]byte)
+		w.writeInt(len(entries))
+		for i, e := range entries {
+			hasData := e.data != ""
+			w.writeInt(i + 1)
+			if hasData {
+				w.writeInt(1)
+			} else {
+				w.writeInt(0)
+			}
+			for _, s := range []string{"0", "0", "t", e.desc} {
+				w.writeStr(s)
+			}
+			w.writeInt(2)
+			for _, s := range []string{e.defn, "", e.copyStmt, "public", "", "heap", "root", "false"} {
+				w.writeStr(s)
+			}
+			w.writeInt(-1)
+			if format == pgDumpArchiveCustom {
+				if hasData {
+					w.WriteByte(1)
+				} else {
+					w.WriteByte(pgDumpOffsetNoData)
+				}
+				w.Write(make([]byte, 8))
+			} else {
+				name := ""
+				if hasData && e.desc != "BLOBS" {
+					name = fmt.Sprintf("%d.dat", i+1)
+					data := []byte(e.data)
+					if compressed {
+						name, data = name+".gz", compress(t, e.data, true /* gz */)
+					}
+					parts[name] = data
+					name = fmt.Sprintf("%d.dat", i+1)
+				}
+				w.writeStr(name)
+			}
+		}
+		if format != pgDumpArchiveCustom {
+			return w.Bytes(), parts
+		}
+
+		writeChunks := func(data string) {
+			b := []byte(data)
+			if compressed {
+				b = compress(t, data, false /* gz */)
+			}
+			// Split the data into two chunks.
+			w.writeInt(len(b) / 2)
+			w.Write(b[:len(b)/2])
+			w.writeInt(len(b) - len(b)/2)
+			w.Write(b[len(b)/2:])
+			w.writeInt(0)
+		}
+		for i, e := range entries {
+			if e.data == "" {
+				continue
+			}
+			if e.desc == "BLOBS" {
+				w.WriteByte(pgDumpBlockBlobs)
+				w.writeInt(i + 1)
+				w.writeInt(1234)
+				writeChunks(e.data)
+				w.writeInt(0)
+				continue
+			}
+			w.WriteByte(pgDumpBlockData)
+			w.writeInt(i + 1)
+			writeChunks(e.data)
+		}
+		return w.Bytes(), parts
+	}
+
+	for _, tc := range []struct {
+		name       string
+		format     byte
+		compressed bool
+	}{
+		{"custom", pgDumpArchiveCustom, false},
+		{"custom-compressed", pgDumpArchiveCustom, true},
+		{"directory", pgDumpArchiveDirectory, false},
+		{"directory-compressed", pgDumpArchiveDirectory, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			archive, parts := writeArchive(t, tc.format, tc.compressed)
+			openPart := func(_ context.Context, name string) (io.ReadCloser, error) {
+				data, ok := parts[name]
+				if !ok {
+					return nil, errors.Newf("no file %s", name)
+				}
+				return io.NopCloser(bytes.NewReader(data)), nil
+			}
+			r, err := maybeReadPgDumpArchive(context.Background(), bytes.NewReader(archive), openPart)
+			require.NoError(t, err)
+			sql, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, expected, string(sql))
+		})
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		r, err := maybeReadPgDumpArchive(context.Background(), strings.NewReader(expected), nil /* openPart */)
+		require.NoError(t, err)
+		sql, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(sql))
+	})
+
+	t.Run("unsupported-format", func(t *testing.T) {
+		archive, _ := writeArchive(t, 3 /* tar */, false /* compressed */)
+		_, err := maybeReadPgDumpArchive(context.Background(), bytes.NewReader(archive), nil /* openPart */)
+		require.ErrorContains(t, err, "unsupported archive format 3")
+	})
+}
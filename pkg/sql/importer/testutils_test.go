@@ -304,10 +304,20 @@ func (es *generatorExternalStorage) List(
 	return errors.New("unsupported")
 }
 
+func (es *generatorExternalStorage) ListWithOptions(
+	ctx context.Context, _ string, _ cloud.ListOptions, _ cloud.ListingFn,
+) error {
+	return errors.New("unsupported")
+}
+
 func (es *generatorExternalStorage) Delete(ctx context.Context, basename string) error {
 	return errors.New("unsupported")
 }
 
+func (es *generatorExternalStorage) Copy(ctx context.Context, src, dst string) error {
+	return errors.New("unsupported")
+}
+
 func (es *generatorExternalStorage) ExternalIOConf() base.ExternalIODirConfig {
 	return base.ExternalIODirConfig{}
 }
@@ -52,6 +52,9 @@ func buildOpaque(
 		if err := p.checkNoConflictingCursors(stmt); err != nil {
 			return nil, err
 		}
+		if err := p.checkBackupProtection(ctx, stmt); err != nil {
+			return nil, err
+		}
 		var err error
 		plan, err = p.SchemaChange(ctx, stmt)
 		if err != nil {
@@ -102,6 +105,10 @@ func planOpaque(ctx context.Context, p *planner, stmt tree.Statement) (planNode,
 		return p.AlterDatabaseDropSecondaryRegion(ctx, n)
 	case *tree.AlterDatabaseSetZoneConfigExtension:
 		return p.AlterDatabaseSetZoneConfigExtension(ctx, n)
+	case *tree.AlterDatabaseSetStorageParams:
+		return p.AlterDatabaseSetStorageParams(ctx, n)
+	case *tree.AlterDatabaseResetStorageParams:
+		return p.AlterDatabaseResetStorageParams(ctx, n)
 	case *tree.AlterDefaultPrivileges:
 		return p.alterDefaultPrivileges(ctx, n)
 	case *tree.AlterFunctionOptions:
@@ -291,6 +298,8 @@ func init() {
 		&tree.AlterDatabaseSecondaryRegion{},
 		&tree.AlterDatabaseDropSecondaryRegion{},
 		&tree.AlterDatabaseSetZoneConfigExtension{},
+		&tree.AlterDatabaseSetStorageParams{},
+		&tree.AlterDatabaseResetStorageParams{},
 		&tree.AlterDefaultPrivileges{},
 		&tree.AlterFunctionOptions{},
 		&tree.AlterFunctionRename{},
@@ -376,6 +385,8 @@ func init() {
 		// CCL statements (without Export which has an optimizer operator).
 		&tree.AlterBackup{},
 		&tree.AlterBackupSchedule{},
+		&tree.AlterBackupCollection{},
+		&tree.AlterBackupSchedules{},
 		&tree.Backup{},
 		&tree.ShowBackup{},
 		&tree.Restore{},
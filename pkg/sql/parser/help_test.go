@@ -503,6 +503,11 @@ func TestContextualHelp(t *testing.T) {
 		{`EXPORT INTO CSV 'a' FROM SELECT a ??`, `SELECT`},
 		{`CREATE SCHEDULE FOR BACKUP ??`, `CREATE SCHEDULE FOR BACKUP`},
 		{`ALTER BACKUP SCHEDULE ??`, `ALTER BACKUP SCHEDULE`},
+		{`ALTER BACKUP COLLECTION ??`, `ALTER BACKUP COLLECTION`},
+		{`ALTER BACKUP COLLECTION 'foo' RECOVER ??`, `ALTER BACKUP COLLECTION`},
+		{`ALTER BACKUP SCHEDULES ??`, `ALTER BACKUP SCHEDULES`},
+		{`ALTER BACKUP SCHEDULES FOR COLLECTION 'foo' ??`, `ALTER BACKUP SCHEDULES`},
+		{`ALTER BACKUP COLLECTION 'foo' UPGRADE ??`, `ALTER BACKUP COLLECTION`},
 
 		{`CREATE FUNCTION ??`, `CREATE FUNCTION`},
 		{`ALTER FUNCTION ??`, `ALTER FUNCTION`},
@@ -509,6 +509,9 @@ func (u *sqlSymUnion) backupTargetList() tree.BackupTargetList {
 func (u *sqlSymUnion) backupTargetListPtr() *tree.BackupTargetList {
     return u.val.(*tree.BackupTargetList)
 }
+func (u *sqlSymUnion) backupTableFilter() *tree.BackupTableFilter {
+    return u.val.(*tree.BackupTableFilter)
+}
 func (u *sqlSymUnion) grantTargetList() tree.GrantTargetList {
     return u.val.(tree.GrantTargetList)
 }
@@ -692,6 +695,12 @@ func (u *sqlSymUnion) showBackupDetails() tree.ShowBackupDetails {
 func (u *sqlSymUnion) restoreOptions() *tree.RestoreOptions {
   return u.val.(*tree.RestoreOptions)
 }
+func (u *sqlSymUnion) restoreTableRename() tree.RestoreTableRename {
+  return u.val.(tree.RestoreTableRename)
+}
+func (u *sqlSymUnion) restoreTableRenames() tree.RestoreTableRenames {
+  return u.val.(tree.RestoreTableRenames)
+}
 func (u *sqlSymUnion) transactionModes() tree.TransactionModes {
     return u.val.(tree.TransactionModes)
 }
@@ -845,7 +854,7 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 
 // Ordinary key words in alphabetical order.
 %token <str> ABORT ABSOLUTE ACCESS ACTION ADD ADMIN AFTER AGGREGATE
-%token <str> ALL ALTER ALWAYS ANALYSE ANALYZE AND AND_AND ANY ANNOTATE_TYPE ARRAY AS ASC
+%token <str> ALL ALLOW_MISSING_LOCALITIES ALTER ALWAYS ANALYSE ANALYZE AND AND_AND ANY ANNOTATE_TYPE APP_TOKEN ARRAY AS ASC
 %token <str> ASENSITIVE ASYMMETRIC AT ATOMIC ATTRIBUTE AUTHORIZATION AUTOMATIC AVAILABILITY
 
 %token <str> BACKUP BACKUPS BACKWARD BEFORE BEGIN BETWEEN BIGINT BIGSERIAL BINARY BIT
@@ -854,25 +863,25 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 
 %token <str> CACHE CALLED CANCEL CANCELQUERY CASCADE CASE CAST CBRT CHANGEFEED CHAR
 %token <str> CHARACTER CHARACTERISTICS CHECK CLOSE
-%token <str> CLUSTER COALESCE COLLATE COLLATION COLUMN COLUMNS COMMENT COMMENTS COMMIT
-%token <str> COMMITTED COMPACT COMPLETE COMPLETIONS CONCAT CONCURRENTLY CONFIGURATION CONFIGURATIONS CONFIGURE
-%token <str> CONFLICT CONNECTION CONNECTIONS CONSTRAINT CONSTRAINTS CONTAINS CONTROLCHANGEFEED CONTROLJOB
-%token <str> CONVERSION CONVERT COPY COST COVERING CREATE CREATEDB CREATELOGIN CREATEROLE
+%token <str> CLUSTER COALESCE COLLATE COLLATION COLLECTION COLUMN COLUMNS COMMENT COMMENTS COMMIT
+%token <str> COMMITTED COMPACT COMPLETE COMPLETIONS COMPRESSION CONCAT CONCURRENTLY CONFIGURATION CONFIGURATIONS CONFIGURE
+%token <str> CONFLICT CONNECTION CONNECTIONS CONSOLIDATE_CHANGES CONSTRAINT CONSTRAINTS CONTAINS CONTROLCHANGEFEED CONTROLJOB
+%token <str> CONVERSION CONVERT COORDINATED_CLUSTERS COORDINATOR COPY COST COVERING CREATE CREATEDB CREATELOGIN CREATEROLE
 %token <str> CROSS CSV CUBE CURRENT CURRENT_CATALOG CURRENT_DATE CURRENT_SCHEMA
 %token <str> CURRENT_ROLE CURRENT_TIME CURRENT_TIMESTAMP
 %token <str> CURRENT_USER CURSOR CYCLE
 
 %token <str> DATA DATABASE DATABASES DATE DAY DEBUG_PAUSE_ON DEC DECIMAL DEFAULT DEFAULTS DEFINER
-%token <str> DEALLOCATE DECLARE DEFERRABLE DEFERRED DELETE DELIMITER DEPENDS DESC DESTINATION DETACHED
-%token <str> DISCARD DISTINCT DO DOMAIN DOUBLE DROP
+%token <str> DEALLOCATE DECLARE DECRYPT_QUORUM DEFERRABLE DEFERRED DEFERRED_DATA DEFER_CONSTRAINT_VALIDATION DELETE DELIMITER DEPENDS DESC DESTINATION DETACHED
+%token <str> DIFF DISCARD DISTINCT DO DOMAIN DOUBLE DROP DRY_RUN
 
 %token <str> ELSE ENCODING ENCRYPTED ENCRYPTION_PASSPHRASE END ENUM ENUMS ESCAPE EXCEPT EXCLUDE EXCLUDING
-%token <str> EXISTS EXECUTE EXECUTION EXPERIMENTAL
+%token <str> EXISTS EXECUTE EXECUTION EXECUTION_LOCALITY EXPERIMENTAL
 %token <str> EXPERIMENTAL_FINGERPRINTS EXPERIMENTAL_REPLICA
 %token <str> EXPERIMENTAL_AUDIT EXPERIMENTAL_RELOCATE
 %token <str> EXPIRATION EXPLAIN EXPORT EXTENSION EXTERNAL EXTRACT EXTRACT_DURATION
 
-%token <str> FAILURE FALSE FAMILY FETCH FETCHVAL FETCHTEXT FETCHVAL_PATH FETCHTEXT_PATH
+%token <str> FAILOVER FAILURE FALSE FAMILY FETCH FETCHVAL FETCHTEXT FETCHVAL_PATH FETCHTEXT_PATH
 %token <str> FILES FILTER
 %token <str> FIRST FLOAT FLOAT4 FLOAT8 FLOORDIV FOLLOWING FOR FORCE FORCE_INDEX FORCE_ZIGZAG
 %token <str> FOREIGN FORWARD FREEZE FROM FULL FUNCTION FUNCTIONS
@@ -894,14 +903,14 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 
 %token <str> JOB JOBS JOIN JSON JSONB JSON_SOME_EXISTS JSON_ALL_EXISTS
 
-%token <str> KEY KEYS KMS KV
+%token <str> KEY KEYS KEY_OFFSET KMS KMS_BY_LOCALITY KV
 
-%token <str> LABEL LANGUAGE LAST LATERAL LATEST LC_CTYPE LC_COLLATE
+%token <str> LABEL LANGUAGE LAST LATERAL LATEST LATEST_AS_OF LATEST_VALUE LAYOUT LC_CTYPE LC_COLLATE
 %token <str> LEADING LEASE LEAST LEAKPROOF LEFT LESS LEVEL LIKE LIMIT
 %token <str> LINESTRING LINESTRINGM LINESTRINGZ LINESTRINGZM
 %token <str> LIST LOCAL LOCALITY LOCALTIME LOCALTIMESTAMP LOCKED LOGIN LOOKUP LOW LSHIFT
 
-%token <str> MATCH MATERIALIZED MERGE MINVALUE MAXVALUE METHOD MINUTE MODIFYCLUSTERSETTING MONTH MOVE
+%token <str> MATCH MATERIALIZED MERGE METADATA_URI MIN_LATEST_TIME MINVALUE MAXVALUE METHOD MINUTE MODIFYCLUSTERSETTING MONTH MOVE
 %token <str> MULTILINESTRING MULTILINESTRINGM MULTILINESTRINGZ MULTILINESTRINGZM
 %token <str> MULTIPOINT MULTIPOINTM MULTIPOINTZ MULTIPOINTZM
 %token <str> MULTIPOLYGON MULTIPOLYGONM MULTIPOLYGONZ MULTIPOLYGONZM
@@ -913,30 +922,31 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 %token <str> NOTNULL
 %token <str> NOVIEWACTIVITY NOVIEWACTIVITYREDACTED NOVIEWCLUSTERSETTING NOWAIT NULL NULLIF NULLS NUMERIC
 
-%token <str> OF OFF OFFSET OID OIDS OIDVECTOR OLD_KMS ON ONLY OPT OPTION OPTIONS OR
+%token <str> OF OFF OFFSET OID OIDS OIDVECTOR OLD_KMS ON ON_CONFLICT ONLY OPT OPTION OPTIONS OR
 %token <str> ORDER ORDINALITY OTHERS OUT OUTER OVER OVERLAPS OVERLAY OWNED OWNER OPERATOR
 
-%token <str> PARALLEL PARENT PARTIAL PARTITION PARTITIONS PASSWORD PAUSE PAUSED PHYSICAL PLACEMENT PLACING
+%token <str> PARALLEL PARENT PARTIAL PARTITION PARTITIONS PART_SIZE PASSWORD PAUSE PAUSED PAUSE_TTL_SCHEDULES PER_TABLE_FILES PHYSICAL PLACEMENT PLACING
 %token <str> PLAN PLANS POINT POINTM POINTZ POINTZM POLYGON POLYGONM POLYGONZ POLYGONZM
 %token <str> POSITION PRECEDING PRECISION PREPARE PRESERVE PRIMARY PRIOR PRIORITY PRIVILEGES
 %token <str> PROCEDURAL PUBLIC PUBLICATION
 
 %token <str> QUERIES QUERY QUOTE
 
-%token <str> RANGE RANGES READ REAL REASON REASSIGN RECURSIVE RECURRING REF REFERENCES REFRESH
+%token <str> RANGE RANGES READ REAL REASON REASSIGN RECOVER RECURSIVE RECURRING REF REFERENCES REFRESH
 %token <str> REGCLASS REGION REGIONAL REGIONS REGNAMESPACE REGPROC REGPROCEDURE REGROLE REGTYPE REINDEX
-%token <str> RELATIVE RELOCATE REMOVE_PATH RENAME REPEATABLE REPLACE REPLICATION
-%token <str> RELEASE RESET RESTART RESTORE RESTRICT RESTRICTED RESUME RETURNING RETURN RETURNS RETRY REVISION_HISTORY
+%token <str> RELATIVE RELOCATE RELY_ON_ENCRYPTION_AT_REST REMAP_REGIONS REMOVE_PATH RENAME REPEATABLE REPLACE REPLICATION
+%token <str> RELEASE RESET RESTART RESTORE RESTORE_FK_TO_EXISTING RESTRICT RESTRICTED RESUME RETURNING RETURN RETURNS RETRY REVISION_HISTORY
 %token <str> REVOKE RIGHT ROLE ROLES ROLLBACK ROLLUP ROUTINES ROW ROWS RSHIFT RULE RUNNING
 
-%token <str> SAVEPOINT SCANS SCATTER SCHEDULE SCHEDULES SCROLL SCHEMA SCHEMA_ONLY SCHEMAS SCRUB
+%token <str> SAVEPOINT SCANS SCATTER SCHEDULE SCHEDULES SCROLL SCHEMA SCHEMA_CHANGE_POLICY SCHEMA_ONLY SCHEMAS SCRUB
 %token <str> SEARCH SECOND SECONDARY SECURITY SELECT SEQUENCE SEQUENCES
 %token <str> SERIALIZABLE SERVER SESSION SESSIONS SESSION_USER SET SETOF SETS SETTING SETTINGS
-%token <str> SHARE SHOW SIMILAR SIMPLE SKIP SKIP_LOCALITIES_CHECK SKIP_MISSING_FOREIGN_KEYS
-%token <str> SKIP_MISSING_SEQUENCES SKIP_MISSING_SEQUENCE_OWNERS SKIP_MISSING_VIEWS SMALLINT SMALLSERIAL SNAPSHOT SOME SPLIT SQL
+%token <str> SHARE SHOW SIMILAR SIMPLE SKIP SKIP_COMMENTS SKIP_IF_UNCHANGED SKIP_JOBS SKIP_LOCALITIES_CHECK SKIP_MISSING_FOREIGN_KEYS
+%token <str> SKIP_MISSING_SEQUENCES SKIP_MISSING_SEQUENCE_OWNERS SKIP_MISSING_VIEWS SKIP_STATISTICS SKIP_ZONE_CONFIGS
+%token <str> SMALLINT SMALLSERIAL SNAPSHOT SOME SPLIT SQL
 %token <str> SQLLOGIN
 
-%token <str> STABLE START STATE STATISTICS STATUS STDIN STREAM STRICT STRING STORAGE STORE STORED STORING SUBSTRING SUPER
+%token <str> STABLE START STATE STATISTICS STATUS STDIN STREAM STRICT STRING STORAGE STORE STORED STORING SUBDIR_FORMAT SUBSTRING SUPER
 %token <str> SUPPORT SURVIVE SURVIVAL SYMMETRIC SYNTAX SYSTEM SQRT SUBSCRIPTION STATEMENTS
 
 %token <str> TABLE TABLES TABLESPACE TEMP TEMPLATE TEMPORARY TENANT TENANTS TESTING_RELOCATE TEXT THEN
@@ -946,7 +956,7 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 %token <str> TRACING
 
 %token <str> UNBOUNDED UNCOMMITTED UNION UNIQUE UNKNOWN UNLISTEN UNLOGGED UNSPLIT
-%token <str> UPDATE UPSERT UNSET UNTIL USE USER USERS USING UUID
+%token <str> UPDATE UPGRADE UPLOAD_BUFFER_MEMORY UPLOAD_PARALLELISM UPSERT UNSET UNTIL USE USER USERS USING UUID
 
 %token <str> VALID VALIDATE VALUE VALUES VARBIT VARCHAR VARIADIC VERIFY_BACKUP_TABLE_DATA VIEW VARYING VIEWACTIVITY VIEWACTIVITYREDACTED VIEWDEBUG
 %token <str> VIEWCLUSTERMETADATA VIEWCLUSTERSETTING VIRTUAL VISIBLE VOLATILE VOTERS
@@ -955,7 +965,7 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 
 %token <str> YEAR
 
-%token <str> ZONE
+%token <str> ZONE ZONE_CONFIGS
 
 // The grammar thinks these are keywords, but they are not in any category
 // and so can never be entered directly. The filter in scan.go creates these
@@ -1047,6 +1057,7 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 %type <tree.Statement> alter_database_set_secondary_region_stmt
 %type <tree.Statement> alter_database_drop_secondary_region
 %type <tree.Statement> alter_database_set_zone_config_extension_stmt
+%type <tree.Statement> alter_database_storage_params_stmt
 
 // ALTER INDEX
 %type <tree.Statement> alter_oneindex_stmt
@@ -1110,6 +1121,8 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 %type <tree.Statement> create_role_stmt
 %type <tree.Statement> create_schedule_for_backup_stmt
 %type <tree.Statement> alter_backup_schedule
+%type <tree.Statement> alter_backup_collection_stmt
+%type <tree.Statement> alter_backup_schedules_stmt
 %type <tree.Statement> create_schema_stmt
 %type <tree.Statement> create_table_stmt
 %type <tree.Statement> create_table_as_stmt
@@ -1161,6 +1174,7 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 %type <tree.Statement> drop_schedule_stmt
 %type <tree.Statement> restore_stmt
 %type <tree.StringOrPlaceholderOptList> string_or_placeholder_opt_list
+%type <tree.StringOrPlaceholderOptList> failover_destination
 %type <[]tree.StringOrPlaceholderOptList> list_of_string_or_placeholder_opt_list
 %type <tree.Statement> revoke_stmt
 %type <tree.Statement> refresh_stmt
@@ -1255,6 +1269,8 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 %type <[]tree.KVOption> kv_option_list opt_with_options var_set_list opt_with_schedule_options
 %type <*tree.BackupOptions> opt_with_backup_options backup_options backup_options_list
 %type <*tree.RestoreOptions> opt_with_restore_options restore_options restore_options_list
+%type <tree.RestoreTableRename> restore_table_rename
+%type <tree.RestoreTableRenames> restore_table_rename_list
 %type <tree.ShowBackupDetails> show_backup_details
 %type <*tree.CopyOptions> opt_with_copy_options copy_options copy_options_list
 %type <str> import_format
@@ -1536,6 +1552,7 @@ func (u *sqlSymUnion) functionObjs() tree.FuncObjs {
 %type <tree.ChangefeedTarget> changefeed_target
 %type <tree.BackupTargetList> backup_targets
 %type <*tree.BackupTargetList> opt_backup_targets
+%type <*tree.BackupTableFilter> backup_table_filter
 
 %type <tree.GrantTargetList> grant_targets targets_roles target_types
 %type <tree.TableExpr> changefeed_target_expr
@@ -1724,6 +1741,8 @@ alter_ddl_stmt:
 | alter_backup_stmt             // EXTEND WITH HELP: ALTER BACKUP
 | alter_func_stmt               // EXTEND WITH HELP: ALTER FUNCTION
 | alter_backup_schedule  // EXTEND WITH HELP: ALTER BACKUP SCHEDULE
+| alter_backup_collection_stmt  // EXTEND WITH HELP: ALTER BACKUP COLLECTION
+| alter_backup_schedules_stmt  // EXTEND WITH HELP: ALTER BACKUP SCHEDULES
 
 // %Help: ALTER TABLE - change the definition of a table
 // %Category: DDL
@@ -1871,6 +1890,8 @@ alter_sequence_options_stmt:
 // ALTER DATABASE <name> PLACEMENT { RESTRICTED | DEFAULT }
 // ALTER DATABASE <name> SET var { TO | = } { value | DEFAULT }
 // ALTER DATABASE <name> RESET { var | ALL }
+// ALTER DATABASE <name> SET ( <storage_parameter> = <value> [, ...] )
+// ALTER DATABASE <name> RESET ( <storage_parameter> [, ...] )
 // ALTER DATABASE <name> ALTER LOCALITY { GLOBAL | REGIONAL [IN <region>] } CONFIGURE ZONE <zone config>
 // %SeeAlso: WEBDOCS/alter-database.html
 alter_database_stmt:
@@ -1890,6 +1911,7 @@ alter_database_stmt:
 | alter_database_set_secondary_region_stmt
 | alter_database_drop_secondary_region
 | alter_database_set_zone_config_extension_stmt
+| alter_database_storage_params_stmt
 
 // %Help: ALTER FUNCTION - change the definition of a function
 // %Category: DDL
@@ -2064,6 +2086,22 @@ alter_database_drop_secondary_region:
       }
     }
 
+alter_database_storage_params_stmt:
+  ALTER DATABASE database_name SET '(' storage_parameter_list ')'
+  {
+    $$.val = &tree.AlterDatabaseSetStorageParams{
+      Name: tree.Name($3),
+      StorageParams: $6.storageParams(),
+    }
+  }
+| ALTER DATABASE database_name RESET '(' storage_parameter_key_list ')'
+  {
+    $$.val = &tree.AlterDatabaseResetStorageParams{
+      Name: tree.Name($3),
+      Params: $6.storageParamKeys(),
+    }
+  }
+
 alter_database_set_zone_config_extension_stmt:
   ALTER DATABASE database_name ALTER LOCALITY GLOBAL set_zone_config
   {
@@ -3047,10 +3085,19 @@ opt_clear_data:
 //        [ AS OF SYSTEM TIME <expr> ]
 //				[ WITH <option> [= <value>] [, ...] ]
 //
+// Write to a primary collection, failing over to a secondary collection for
+// the data files that cannot be written to the primary
+// BACKUP <targets...> INTO [{ <subdir...> | LATEST } IN] FAILOVER (<primary>, <secondary>)
+//        [ AS OF SYSTEM TIME <expr> ]
+//				[ WITH <option> [= <value>] [, ...] ]
+//
 // Targets:
 //    Empty targets list: backup full cluster.
 //    TABLE <pattern> [, ...]
+//    TABLE <pattern> WHERE <predicate>: back up only the rows matching <predicate>
 //    DATABASE <databasename> [, ...]
+//    DATABASE <databasename> [, ...] { EXCLUDE | INCLUDE } TABLES ( <pattern> [, ...] )
+//    VIRTUAL CLUSTER ALL: back up every tenant to its own subdirectory of the collection
 //
 // Destination:
 //    "[scheme]://[host]/[path to backup]?[parameters]"
@@ -3059,8 +3106,43 @@ opt_clear_data:
 //    revision_history: enable revision history
 //    encryption_passphrase="secret": encrypt backups
 //    kms="[kms_provider]://[kms_host]/[master_key_identifier]?[parameters]" : encrypt backups using KMS
+//    decrypt_quorum=<int>: with multiple KMS URIs, split the key of a full backup so that any <int> of them decrypt it
+//    kms_by_locality=('<locality>'='<kms>', ...): encrypt the files of each locality of a locality-aware backup with a key of its own
 //    detached: execute backup job asynchronously, without waiting for its completion
 //    incremental_location: specify a different path to store the incremental backup
+//    upload_parallelism=<int>: number of parts of each file to upload concurrently
+//    part_size="<size>": size of each uploaded part, e.g. '32MiB'
+//    upload_buffer_memory="<size>": memory to buffer the parts of each file being uploaded
+//    statistics[=<bool>]: capture table statistics (default true)
+//    comments[=<bool>]: capture comments on the backed up objects (always true for cluster backups)
+//    zone_configs[=<bool>]: capture zone configurations of the backed up objects (always true for cluster backups)
+//    jobs[=<bool>]: capture the changefeed jobs that are running or paused, only for cluster backups
+//    subdir_format="<format>": name the subdirectory of a new full backup in a collection
+//                              using a Go time layout, where {job_id} is replaced by the job ID
+//    per_table_files[=<bool>]: write the data of each table to separate files under a per-table prefix
+//    schema_change_policy="<policy>": what to do about schema changes in progress on the backed up
+//                                     tables: 'backup_anyway' (default), 'wait' or 'error'
+//    rely_on_encryption_at_rest[=<bool>]: do not encrypt the data files of a backup to nodelocal
+//                                         storage on nodes with encryption-at-rest enabled
+//    consolidate_changes[=<bool>]: write an incremental backup from the changes written to the
+//                                  chain by a changefeed with format=backup_kv
+//    execution_locality="<filter>": only run the backup on nodes whose locality matches the
+//                                   filter, e.g. 'region=us-east1'
+//    priority="<priority>": 'background' (default) to yield to foreground traffic under load,
+//                           or 'high' to be admitted alongside it
+//    metadata_uri="<uri>": write the LATEST file, manifests and encryption info of the backups
+//                          to this collection, and only their data files to the destination
+//    allow_missing_localities[=<bool>]: write the files of a locality whose destination cannot be
+//                                       written to the default destination instead of failing
+//    coordinator="<uri>": negotiate the end time of the backup with the backups of other clusters
+//                         through this location, so that they are all as of the same time
+//    coordinated_clusters=<int>: the number of clusters whose backups are coordinated
+//    compression="<codec>[:level=<level>]": compress the files of the backup with this codec,
+//                                           e.g. 'zstd:level=7', or 'data=<codec>,metadata=<codec>'
+//                                           to compress the data files and the metadata separately
+//    skip_if_unchanged[=<bool>]: do not write an incremental backup if nothing it would back up
+//                                changed since the previous backup in its chain
+//    app_token="<token>": record an application-defined token in the backup, shown by SHOW BACKUP
 //
 // %SeeAlso: RESTORE, WEBDOCS/backup.html
 backup_stmt:
@@ -3097,6 +3179,41 @@ backup_stmt:
       Options: *$8.backupOptions(),
     }
   }
+| BACKUP opt_backup_targets INTO sconst_or_placeholder IN failover_destination opt_as_of_clause opt_with_backup_options
+  {
+    $$.val = &tree.Backup{
+      Targets: $2.backupTargetListPtr(),
+      To: $6.stringOrPlaceholderOptList(),
+      Nested: true,
+      Failover: true,
+      Subdir: $4.expr(),
+      AsOf: $7.asOfClause(),
+      Options: *$8.backupOptions(),
+    }
+  }
+| BACKUP opt_backup_targets INTO failover_destination opt_as_of_clause opt_with_backup_options
+  {
+    $$.val = &tree.Backup{
+      Targets: $2.backupTargetListPtr(),
+      To: $4.stringOrPlaceholderOptList(),
+      Nested: true,
+      Failover: true,
+      AsOf: $5.asOfClause(),
+      Options: *$6.backupOptions(),
+    }
+  }
+| BACKUP opt_backup_targets INTO LATEST IN failover_destination opt_as_of_clause opt_with_backup_options
+  {
+    $$.val = &tree.Backup{
+      Targets: $2.backupTargetListPtr(),
+      To: $6.stringOrPlaceholderOptList(),
+      Nested: true,
+      Failover: true,
+      AppendToLatest: true,
+      AsOf: $7.asOfClause(),
+      Options: *$8.backupOptions(),
+    }
+  }
 | BACKUP opt_backup_targets TO string_or_placeholder_opt_list opt_as_of_clause opt_incremental opt_with_backup_options
   {
     $$.val = &tree.Backup{
@@ -3109,6 +3226,12 @@ backup_stmt:
   }
 | BACKUP error // SHOW HELP: BACKUP
 
+failover_destination:
+  FAILOVER '(' string_or_placeholder ',' string_or_placeholder ')'
+  {
+    $$.val = tree.StringOrPlaceholderOptList{$3.expr(), $5.expr()}
+  }
+
 opt_backup_targets:
   /* EMPTY -- full cluster */
   {
@@ -3119,6 +3242,10 @@ opt_backup_targets:
     t := $1.backupTargetList()
     $$.val = &t
   }
+| VIRTUAL CLUSTER ALL
+  {
+    $$.val = &tree.BackupTargetList{AllTenants: true}
+  }
 
 // Optional backup options.
 opt_with_backup_options:
@@ -3178,10 +3305,138 @@ backup_options:
   {
     $$.val = &tree.BackupOptions{EncryptionKMSURI: $3.stringOrPlaceholderOptList()}
   }
+| KMS_BY_LOCALITY '=' '(' kv_option_list ')'
+  {
+    $$.val = &tree.BackupOptions{KMSURIByLocality: $4.kvOptions()}
+  }
 | INCREMENTAL_LOCATION '=' string_or_placeholder_opt_list
   {
   $$.val = &tree.BackupOptions{IncrementalStorage: $3.stringOrPlaceholderOptList()}
   }
+| UPLOAD_PARALLELISM '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{UploadParallelism: $3.expr()}
+  }
+| PART_SIZE '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{PartSize: $3.expr()}
+  }
+| UPLOAD_BUFFER_MEMORY '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{UploadBufferMemory: $3.expr()}
+  }
+| STATISTICS
+  {
+    $$.val = &tree.BackupOptions{Statistics: tree.MakeDBool(true)}
+  }
+| STATISTICS '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{Statistics: $3.expr()}
+  }
+| COMMENTS
+  {
+    $$.val = &tree.BackupOptions{Comments: tree.MakeDBool(true)}
+  }
+| COMMENTS '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{Comments: $3.expr()}
+  }
+| ZONE_CONFIGS
+  {
+    $$.val = &tree.BackupOptions{ZoneConfigs: tree.MakeDBool(true)}
+  }
+| ZONE_CONFIGS '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{ZoneConfigs: $3.expr()}
+  }
+| DECRYPT_QUORUM '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{DecryptQuorum: $3.expr()}
+  }
+| JOBS
+  {
+    $$.val = &tree.BackupOptions{Jobs: tree.MakeDBool(true)}
+  }
+| JOBS '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{Jobs: $3.expr()}
+  }
+| SUBDIR_FORMAT '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{SubdirFormat: $3.expr()}
+  }
+| PER_TABLE_FILES
+  {
+    $$.val = &tree.BackupOptions{PerTableFiles: tree.MakeDBool(true)}
+  }
+| PER_TABLE_FILES '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{PerTableFiles: $3.expr()}
+  }
+| SCHEMA_CHANGE_POLICY '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{SchemaChangePolicy: $3.expr()}
+  }
+| RELY_ON_ENCRYPTION_AT_REST
+  {
+    $$.val = &tree.BackupOptions{RelyOnEncryptionAtRest: tree.MakeDBool(true)}
+  }
+| RELY_ON_ENCRYPTION_AT_REST '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{RelyOnEncryptionAtRest: $3.expr()}
+  }
+| CONSOLIDATE_CHANGES
+  {
+    $$.val = &tree.BackupOptions{ConsolidateChanges: tree.MakeDBool(true)}
+  }
+| CONSOLIDATE_CHANGES '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{ConsolidateChanges: $3.expr()}
+  }
+| EXECUTION_LOCALITY '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{ExecutionLocality: $3.expr()}
+  }
+| PRIORITY '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{Priority: $3.expr()}
+  }
+| METADATA_URI '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{MetadataURI: $3.expr()}
+  }
+| ALLOW_MISSING_LOCALITIES
+  {
+    $$.val = &tree.BackupOptions{AllowMissingLocalities: tree.MakeDBool(true)}
+  }
+| ALLOW_MISSING_LOCALITIES '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{AllowMissingLocalities: $3.expr()}
+  }
+| COORDINATOR '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{Coordinator: $3.expr()}
+  }
+| COORDINATED_CLUSTERS '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{CoordinatedClusters: $3.expr()}
+  }
+| COMPRESSION '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{Compression: $3.expr()}
+  }
+| SKIP_IF_UNCHANGED
+  {
+    $$.val = &tree.BackupOptions{SkipIfUnchanged: tree.MakeDBool(true)}
+  }
+| SKIP_IF_UNCHANGED '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{SkipIfUnchanged: $3.expr()}
+  }
+| APP_TOKEN '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{AppToken: $3.expr()}
+  }
 
 
 // %Help: CREATE SCHEDULE FOR BACKUP - backup data periodically
@@ -3255,6 +3510,9 @@ backup_options:
 //     If backups were already created in the destination in which a new schedule references,
 //     this flag must be passed in to acknowledge that the new schedule may be backing up different
 //     objects.
+//   * incremental_change_threshold='<size>'
+//     Skip a run of the incremental schedule unless the live bytes of the backed up data
+//     changed by at least <size> since the last backup.
 //
 // %SeeAlso: BACKUP
 create_schedule_for_backup_stmt:
@@ -3299,6 +3557,69 @@ alter_backup_schedule:
   }
   | ALTER BACKUP SCHEDULE error  // SHOW HELP: ALTER BACKUP SCHEDULE
 
+// %Help: ALTER BACKUP COLLECTION - alter a backup collection
+// %Category: CCL
+// %Text:
+// ALTER BACKUP COLLECTION <collection> RECOVER LATEST
+// ALTER BACKUP COLLECTION <collection> UPGRADE LAYOUT
+//
+// Commands:
+//   ALTER BACKUP COLLECTION ... RECOVER LATEST: point LATEST at the most recent
+//     complete full backup in the collection, e.g. if the LATEST file is missing
+//     or corrupt
+//   ALTER BACKUP COLLECTION ... UPGRADE LAYOUT: move the LATEST file and the
+//     incremental backups of a collection written by older versions to where
+//     current versions write them
+//
+// Collection:
+//    "[scheme]://[host]/[path to collection]?[parameters]"
+// %SeeAlso: BACKUP, SHOW BACKUPS
+alter_backup_collection_stmt:
+  ALTER BACKUP COLLECTION sconst_or_placeholder RECOVER LATEST
+  {
+    $$.val = &tree.AlterBackupCollection{
+      Collection: $4.expr(),
+    }
+  }
+  | ALTER BACKUP COLLECTION sconst_or_placeholder UPGRADE LAYOUT
+  {
+    $$.val = &tree.AlterBackupCollection{
+      Collection:    $4.expr(),
+      UpgradeLayout: true,
+    }
+  }
+  | ALTER BACKUP COLLECTION error  // SHOW HELP: ALTER BACKUP COLLECTION
+
+// %Help: ALTER BACKUP SCHEDULES - pause or resume the backup schedules of a collection
+// %Category: CCL
+// %Text:
+// ALTER BACKUP SCHEDULES FOR COLLECTION <collection> PAUSE
+// ALTER BACKUP SCHEDULES FOR COLLECTION <collection> RESUME
+//
+// Pauses or resumes every backup schedule that backs up into the collection,
+// e.g. for the maintenance window of a bucket. The URIs of the schedules are
+// compared without their parameters.
+//
+// Collection:
+//    "[scheme]://[host]/[path to collection]?[parameters]"
+// %SeeAlso: ALTER BACKUP SCHEDULE, PAUSE SCHEDULES, RESUME SCHEDULES
+alter_backup_schedules_stmt:
+  ALTER BACKUP SCHEDULES FOR COLLECTION sconst_or_placeholder PAUSE
+  {
+    $$.val = &tree.AlterBackupSchedules{
+      Collection: $6.expr(),
+      Command:    tree.PauseSchedule,
+    }
+  }
+  | ALTER BACKUP SCHEDULES FOR COLLECTION sconst_or_placeholder RESUME
+  {
+    $$.val = &tree.AlterBackupSchedules{
+      Collection: $6.expr(),
+      Command:    tree.ResumeSchedule,
+    }
+  }
+  | ALTER BACKUP SCHEDULES error  // SHOW HELP: ALTER BACKUP SCHEDULES
+
 
 alter_backup_schedule_cmds:
   alter_backup_schedule_cmd
@@ -3431,19 +3752,24 @@ opt_with_schedule_options:
 // %Help: CREATE EXTERNAL CONNECTION - create a new external connection
 // %Category: Misc
 // %Text:
-// CREATE EXTERNAL CONNECTION [IF NOT EXISTS] <name> AS <endpoint>
+// CREATE EXTERNAL CONNECTION [IF NOT EXISTS] <name> AS <endpoint> [WITH <option> [= <value>] [, ...]]
 //
 // Name:
 //   Unique name for this external connection.
 //
 // Endpoint:
 //   Endpoint of the resource that the external connection represents.
+//
+// Options:
+//   capabilities: comma-separated operations the external connection can be
+//                 used for, out of backup-write, restore-read and list
 create_external_connection_stmt:
-	CREATE EXTERNAL CONNECTION /*$4=*/label_spec AS /*$6=*/string_or_placeholder
+	CREATE EXTERNAL CONNECTION /*$4=*/label_spec AS /*$6=*/string_or_placeholder /*$7=*/opt_with_options
 	{
 		$$.val = &tree.CreateExternalConnection{
 				  ConnectionLabelSpec: *($4.labelSpec()),
 		      As: $6.expr(),
+		      Options: $7.kvOptions(),
 		}
 	}
  | CREATE EXTERNAL CONNECTION error // SHOW HELP: CREATE EXTERNAL CONNECTION
@@ -3474,9 +3800,25 @@ drop_external_connection_stmt:
 // RESTORE SYSTEM USERS FROM <location...>
 //         [ AS OF SYSTEM TIME <expr> ]
 //         [ WITH <option> [= <value>] [, ...] ]
+// or
+// RESTORE INDEX <tablename>@<indexname> FROM <subdir> IN <location...>
+//         [ AS OF SYSTEM TIME <expr> ]
+//         [ WITH <option> [= <value>] [, ...] ]
+// or
+// PREPARE RESTORE [<targets...>] FROM <subdir> IN <location...>
+//         [ AS OF SYSTEM TIME <expr> ]
+//         [ WITH <option> [= <value>] [, ...] ]
+//
+// RESTORE INDEX rebuilds a secondary index of an existing table from the data
+// of the index in the backup, and fails if the rebuilt index does not match the
+// rows of the table.
+//
+// PREPARE RESTORE resolves the backups a RESTORE with the same arguments would
+// read and caches their manifests, without restoring any data.
 //
 // Targets:
 //    TABLE <pattern> [, ...]
+//    TABLE <tablename> AS <newname> [, ...]
 //    DATABASE <databasename> [, ...]
 //
 // Locations:
@@ -3485,15 +3827,37 @@ drop_external_connection_stmt:
 // Options:
 //    into_db: specify target database
 //    skip_missing_foreign_keys: remove foreign key constraints before restoring
+//    restore_fk_to_existing: point foreign keys to tables that are not restored at the existing
+//                            tables of the same name, as NOT VALID constraints
 //    skip_missing_sequences: ignore sequence dependencies
 //    skip_missing_views: skip restoring views because of dependencies that cannot be restored
 //    skip_missing_sequence_owners: remove sequence-table ownership dependencies before restoring
 //    encryption_passphrase=passphrase: decrypt BACKUP with specified passphrase
 //    kms="[kms_provider]://[kms_host]/[master_key_identifier]?[parameters]" : decrypt backups using KMS
+//    kms_by_locality=('<locality>'='<kms>', ...): decrypt the files of each locality encrypted with a key of its own
 //    detached: execute restore job asynchronously, without waiting for its completion
 //    skip_localities_check: ignore difference of zone configuration between restore cluster and backup cluster
 //    debug_pause_on: describes the events that the job should pause itself on for debugging purposes.
 //    new_db_name: renames the restored database. only applies to database restores
+//    latest_value: the backup subdirectory to use for LATEST instead of reading the LATEST file
+//    latest_as_of: resolve LATEST to the most recent full backup taken at or before this timestamp
+//    min_latest_time: wait until LATEST names a backup chain ending at or after this HLC timestamp
+//    skip_statistics: do not restore the table statistics in the backup
+//    skip_comments: do not restore the comments in the backup
+//    skip_zone_configs: do not restore the zone configurations in the backup
+//    skip_jobs: do not restore the jobs in a cluster backup taken with the jobs option
+//    pause_ttl_schedules: restore the row-level TTL schedules of the restored tables paused
+//    remap_regions=('old=new', ...): restore the regions of multi-region databases and tables under new names
+//    on_conflict: 'error' (default), 'skip' or 'replace' tables and databases that already exist
+//    metadata_uri: resolve LATEST and the backups to restore in this read-only replica of the collection
+//    deferred_data: with schema_only, load the data of the backup into the restored tables in a separate job
+//    dry_run: report the descriptors the restore would create and the checks it would fail, without restoring them
+//    execution_locality: only run the restore on nodes whose locality matches this filter, e.g. 'region=us-east1'
+//    key_offset: for testing, restore the descriptors (or the tenant) of the backup under IDs shifted by this
+//                offset, so that the same backup can be restored side by side into different key spans
+//    columns: when restoring a single table, only restore these columns of it, which must include its primary key
+//    defer_constraint_validation: restore CHECK and foreign key constraints unvalidated, and validate them in jobs
+//                                 of their own once the restore completes
 // %SeeAlso: BACKUP, WEBDOCS/restore.html
 restore_stmt:
   RESTORE FROM list_of_string_or_placeholder_opt_list opt_as_of_clause opt_with_restore_options
@@ -3534,6 +3898,34 @@ restore_stmt:
       Options: *($8.restoreOptions()),
     }
   }
+| RESTORE TABLE restore_table_rename_list FROM string_or_placeholder IN list_of_string_or_placeholder_opt_list opt_as_of_clause opt_with_restore_options
+  {
+    renames := $3.restoreTableRenames()
+    patterns := make(tree.TablePatterns, len(renames))
+    for i := range renames {
+      patterns[i] = renames[i].Table.ToUnresolvedName()
+    }
+    $$.val = &tree.Restore{
+      Targets: tree.BackupTargetList{Tables: tree.TableAttrs{TablePatterns: patterns}},
+      TableRenames: renames,
+      Subdir: $5.expr(),
+      From: $7.listOfStringOrPlaceholderOptList(),
+      AsOf: $8.asOfClause(),
+      Options: *($9.restoreOptions()),
+    }
+  }
+| RESTORE INDEX table_name '@' index_name FROM string_or_placeholder IN list_of_string_or_placeholder_opt_list opt_as_of_clause opt_with_restore_options
+  {
+    table := $3.unresolvedObjectName()
+    $$.val = &tree.Restore{
+      Targets: tree.BackupTargetList{Tables: tree.TableAttrs{TablePatterns: tree.TablePatterns{table.ToUnresolvedName()}}},
+      Index: &tree.TableIndexName{Table: table.ToTableName(), Index: tree.UnrestrictedName($5)},
+      Subdir: $7.expr(),
+      From: $9.listOfStringOrPlaceholderOptList(),
+      AsOf: $10.asOfClause(),
+      Options: *($11.restoreOptions()),
+    }
+  }
 | RESTORE SYSTEM USERS FROM list_of_string_or_placeholder_opt_list opt_as_of_clause opt_with_restore_options
   {
     $$.val = &tree.Restore{
@@ -3605,6 +3997,22 @@ opt_as_tenant_clause:
     $$.val = tree.TenantID{Specified: false}
   }
 
+restore_table_rename_list:
+  restore_table_rename
+  {
+    $$.val = tree.RestoreTableRenames{$1.restoreTableRename()}
+  }
+| restore_table_rename_list ',' restore_table_rename
+  {
+    $$.val = append($1.restoreTableRenames(), $3.restoreTableRename())
+  }
+
+restore_table_rename:
+  table_name AS table_name
+  {
+    $$.val = tree.RestoreTableRename{Table: $1.unresolvedObjectName(), NewName: $3.unresolvedObjectName()}
+  }
+
 // Optional restore options.
 opt_with_restore_options:
   WITH restore_options_list
@@ -3643,6 +4051,10 @@ restore_options:
 	{
     $$.val = &tree.RestoreOptions{DecryptionKMSURI: $3.stringOrPlaceholderOptList()}
 	}
+| KMS_BY_LOCALITY '=' '(' kv_option_list ')'
+  {
+    $$.val = &tree.RestoreOptions{KMSURIByLocality: $4.kvOptions()}
+  }
 | INTO_DB '=' string_or_placeholder
   {
     $$.val = &tree.RestoreOptions{IntoDB: $3.expr()}
@@ -3651,6 +4063,10 @@ restore_options:
   {
     $$.val = &tree.RestoreOptions{SkipMissingFKs: true}
   }
+| RESTORE_FK_TO_EXISTING
+  {
+    $$.val = &tree.RestoreOptions{RestoreFKToExisting: true}
+  }
 | SKIP_MISSING_SEQUENCES
   {
     $$.val = &tree.RestoreOptions{SkipMissingSequences: true}
@@ -3695,6 +4111,74 @@ restore_options:
 	{
 		$$.val = &tree.RestoreOptions{VerifyData: true}
 	}
+| LATEST_VALUE '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{LatestValue: $3.expr()}
+  }
+| LATEST_AS_OF '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{LatestAsOf: $3.expr()}
+  }
+| MIN_LATEST_TIME '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{MinLatestTime: $3.expr()}
+  }
+| SKIP_STATISTICS
+  {
+    $$.val = &tree.RestoreOptions{SkipStatistics: true}
+  }
+| SKIP_COMMENTS
+  {
+    $$.val = &tree.RestoreOptions{SkipComments: true}
+  }
+| SKIP_ZONE_CONFIGS
+  {
+    $$.val = &tree.RestoreOptions{SkipZoneConfigs: true}
+  }
+| SKIP_JOBS
+  {
+    $$.val = &tree.RestoreOptions{SkipJobs: true}
+  }
+| PAUSE_TTL_SCHEDULES
+  {
+    $$.val = &tree.RestoreOptions{PauseTTLSchedules: true}
+  }
+| REMAP_REGIONS '=' string_or_placeholder_opt_list
+  {
+    $$.val = &tree.RestoreOptions{RemapRegions: $3.stringOrPlaceholderOptList()}
+  }
+| ON_CONFLICT '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{OnConflict: $3.expr()}
+  }
+| METADATA_URI '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{MetadataURI: $3.expr()}
+  }
+| DEFERRED_DATA
+  {
+    $$.val = &tree.RestoreOptions{DeferredData: true}
+  }
+| DRY_RUN
+  {
+    $$.val = &tree.RestoreOptions{DryRun: true}
+  }
+| EXECUTION_LOCALITY '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{ExecutionLocality: $3.expr()}
+  }
+| KEY_OFFSET '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{KeyOffset: $3.expr()}
+  }
+| COLUMNS '=' '(' name_list ')'
+  {
+    $$.val = &tree.RestoreOptions{Columns: $4.nameList()}
+  }
+| DEFER_CONSTRAINT_VALIDATION
+  {
+    $$.val = &tree.RestoreOptions{DeferConstraintValidation: true}
+  }
 import_format:
   name
   {
@@ -5377,7 +5861,7 @@ backup_kms:
 // %Help: PREPARE - prepare a statement for later execution
 // %Category: Misc
 // %Text: PREPARE <name> [ ( <types...> ) ] AS <query>
-// %SeeAlso: EXECUTE, DEALLOCATE, DISCARD
+// %SeeAlso: EXECUTE, DEALLOCATE, DISCARD, RESTORE
 prepare_stmt:
   PREPARE table_alias_name prep_type_clause AS preparable_stmt
   {
@@ -5396,6 +5880,28 @@ prepare_stmt:
       Statement: &tree.CannedOptPlan{Plan: $7},
     }
   }
+| PREPARE RESTORE FROM string_or_placeholder IN list_of_string_or_placeholder_opt_list opt_as_of_clause opt_with_restore_options
+  {
+    $$.val = &tree.Restore{
+      DescriptorCoverage: tree.AllDescriptors,
+      Subdir: $4.expr(),
+      From: $6.listOfStringOrPlaceholderOptList(),
+      AsOf: $7.asOfClause(),
+      Options: *($8.restoreOptions()),
+      PrepareOnly: true,
+    }
+  }
+| PREPARE RESTORE backup_targets FROM string_or_placeholder IN list_of_string_or_placeholder_opt_list opt_as_of_clause opt_with_restore_options
+  {
+    $$.val = &tree.Restore{
+      Targets: $3.backupTargetList(),
+      Subdir: $5.expr(),
+      From: $7.listOfStringOrPlaceholderOptList(),
+      AsOf: $8.asOfClause(),
+      Options: *($9.restoreOptions()),
+      PrepareOnly: true,
+    }
+  }
 | PREPARE error // SHOW HELP: PREPARE
 
 prep_type_clause:
@@ -6635,7 +7141,9 @@ show_histogram_stmt:
 
 // %Help: SHOW BACKUP - list backup contents
 // %Category: CCL
-// %Text: SHOW BACKUP [SCHEMAS|FILES|RANGES] <location>
+// %Text:
+// SHOW BACKUP [SCHEMAS|FILES|RANGES] <location>
+// SHOW BACKUP DIFF <subdir> AND <subdir> IN <collection>
 // %SeeAlso: WEBDOCS/show-backup.html
 show_backup_stmt:
   SHOW BACKUPS IN string_or_placeholder_opt_list
@@ -6695,6 +7203,16 @@ show_backup_stmt:
 			Options: $5.kvOptions(),
 		}
 	}
+| SHOW BACKUP DIFF string_or_placeholder AND string_or_placeholder IN string_or_placeholder_opt_list opt_with_options
+	{
+		$$.val = &tree.ShowBackup{
+			Details:      tree.BackupDiffDetails,
+			Path:         $4.expr(),
+			DiffPath:     $6.expr(),
+			InCollection: $8.stringOrPlaceholderOptList(),
+			Options:      $9.kvOptions(),
+		}
+	}
 | SHOW BACKUP VALIDATE string_or_placeholder opt_with_options
   	{
   		$$.val = &tree.ShowBackup{
@@ -7898,6 +8416,10 @@ backup_targets:
   {
     $$.val = tree.BackupTargetList{Tables: tree.TableAttrs{SequenceOnly: false, TablePatterns: $2.tablePatterns()}}
   }
+| TABLE table_pattern_list WHERE a_expr
+  {
+    $$.val = tree.BackupTargetList{Tables: tree.TableAttrs{SequenceOnly: false, TablePatterns: $2.tablePatterns()}, RowFilter: $4.expr()}
+  }
 // TODO(knz): This should learn how to parse more complex expressions
 // and placeholders.
 | TENANT iconst64
@@ -7921,6 +8443,22 @@ backup_targets:
   {
     $$.val = tree.BackupTargetList{Databases: $2.nameList()}
   }
+| DATABASE name_list backup_table_filter
+  {
+    $$.val = tree.BackupTargetList{Databases: $2.nameList(), TableFilter: $3.backupTableFilter()}
+  }
+
+// backup_table_filter restricts the tables of the databases targeted by a
+// BACKUP to those whose names do or do not match a list of patterns.
+backup_table_filter:
+  EXCLUDE TABLES '(' string_or_placeholder_list ')'
+  {
+    $$.val = &tree.BackupTableFilter{Exclude: true, Patterns: $4.exprs()}
+  }
+| INCLUDE TABLES '(' string_or_placeholder_list ')'
+  {
+    $$.val = &tree.BackupTableFilter{Patterns: $4.exprs()}
+  }
 
 // target_roles is the variant of targets which recognizes ON ROLES
 // with a name list. This cannot be included in targets directly
@@ -15239,8 +15777,10 @@ unreserved_keyword:
 | ADMIN
 | AFTER
 | AGGREGATE
+| ALLOW_MISSING_LOCALITIES
 | ALTER
 | ALWAYS
+| APP_TOKEN
 | ASENSITIVE
 | AT
 | ATOMIC
@@ -15264,6 +15804,7 @@ unreserved_keyword:
 | CHANGEFEED
 | CLOSE
 | CLUSTER
+| COLLECTION
 | COLUMNS
 | COMMENT
 | COMMENTS
@@ -15272,16 +15813,20 @@ unreserved_keyword:
 | COMPACT
 | COMPLETE
 | COMPLETIONS
+| COMPRESSION
 | CONFLICT
 | CONFIGURATION
 | CONFIGURATIONS
 | CONFIGURE
 | CONNECTION
 | CONNECTIONS
+| CONSOLIDATE_CHANGES
 | CONSTRAINTS
 | CONTROLCHANGEFEED
 | CONTROLJOB
 | CONVERSION
+| COORDINATED_CLUSTERS
+| COORDINATOR
 | CONVERT
 | COPY
 | COST
@@ -15301,18 +15846,23 @@ unreserved_keyword:
 | DEALLOCATE
 | DEBUG_PAUSE_ON
 | DECLARE
+| DECRYPT_QUORUM
 | DELETE
 | DEFAULTS
 | DEFERRED
+| DEFERRED_DATA
+| DEFER_CONSTRAINT_VALIDATION
 | DEFINER
 | DELIMITER
 | DEPENDS
 | DESTINATION
 | DETACHED
+| DIFF
 | DISCARD
 | DOMAIN
 | DOUBLE
 | DROP
+| DRY_RUN
 | ENCODING
 | ENCRYPTED
 | ENCRYPTION_PASSPHRASE
@@ -15323,6 +15873,7 @@ unreserved_keyword:
 | EXCLUDING
 | EXECUTE
 | EXECUTION
+| EXECUTION_LOCALITY
 | EXPERIMENTAL
 | EXPERIMENTAL_AUDIT
 | EXPERIMENTAL_FINGERPRINTS
@@ -15333,6 +15884,7 @@ unreserved_keyword:
 | EXPORT
 | EXTENSION
 | EXTERNAL
+| FAILOVER
 | FAILURE
 | FILES
 | FILTER
@@ -15387,12 +15939,17 @@ unreserved_keyword:
 | JSON
 | KEY
 | KEYS
+| KEY_OFFSET
 | KMS
+| KMS_BY_LOCALITY
 | KV
 | LABEL
 | LANGUAGE
 | LAST
 | LATEST
+| LATEST_AS_OF
+| LATEST_VALUE
+| LAYOUT
 | LC_COLLATE
 | LC_CTYPE
 | LEAKPROOF
@@ -15414,7 +15971,9 @@ unreserved_keyword:
 | MATERIALIZED
 | MAXVALUE
 | MERGE
+| METADATA_URI
 | METHOD
+| MIN_LATEST_TIME
 | MINUTE
 | MINVALUE
 | MODIFYCLUSTERSETTING
@@ -15465,6 +16024,7 @@ unreserved_keyword:
 | OFF
 | OIDS
 | OLD_KMS
+| ON_CONFLICT
 | OPERATOR
 | OPT
 | OPTION
@@ -15479,9 +16039,12 @@ unreserved_keyword:
 | PARTIAL
 | PARTITION
 | PARTITIONS
+| PART_SIZE
 | PASSWORD
 | PAUSE
 | PAUSED
+| PAUSE_TTL_SCHEDULES
+| PER_TABLE_FILES
 | PHYSICAL
 | PLACEMENT
 | PLAN
@@ -15508,6 +16071,7 @@ unreserved_keyword:
 | READ
 | REASON
 | REASSIGN
+| RECOVER
 | RECURRING
 | RECURSIVE
 | REF
@@ -15519,6 +16083,8 @@ unreserved_keyword:
 | RELATIVE
 | RELEASE
 | RELOCATE
+| RELY_ON_ENCRYPTION_AT_REST
+| REMAP_REGIONS
 | RENAME
 | REPEATABLE
 | REPLACE
@@ -15526,6 +16092,7 @@ unreserved_keyword:
 | RESET
 | RESTART
 | RESTORE
+| RESTORE_FK_TO_EXISTING
 | RESTRICT
 | RESTRICTED
 | RESUME
@@ -15544,6 +16111,7 @@ unreserved_keyword:
 | RUNNING
 | SCHEDULE
 | SCHEDULES
+| SCHEMA_CHANGE_POLICY
 | SCHEMA_ONLY
 | SCROLL
 | SETTING
@@ -15571,11 +16139,16 @@ unreserved_keyword:
 | SHOW
 | SIMPLE
 | SKIP
+| SKIP_COMMENTS
+| SKIP_IF_UNCHANGED
+| SKIP_JOBS
 | SKIP_LOCALITIES_CHECK
 | SKIP_MISSING_FOREIGN_KEYS
 | SKIP_MISSING_SEQUENCES
 | SKIP_MISSING_SEQUENCE_OWNERS
 | SKIP_MISSING_VIEWS
+| SKIP_STATISTICS
+| SKIP_ZONE_CONFIGS
 | SNAPSHOT
 | SPLIT
 | SQL
@@ -15592,6 +16165,7 @@ unreserved_keyword:
 | STORING
 | STREAM
 | STRICT
+| SUBDIR_FORMAT
 | SUBSCRIPTION
 | SUPER
 | SUPPORT
@@ -15629,6 +16203,9 @@ unreserved_keyword:
 | UNSPLIT
 | UNTIL
 | UPDATE
+| UPGRADE
+| UPLOAD_BUFFER_MEMORY
+| UPLOAD_PARALLELISM
 | UPSERT
 | USE
 | USERS
@@ -15651,30 +16228,71 @@ unreserved_keyword:
 | WRITE
 | YEAR
 | ZONE
+| ZONE_CONFIGS
 
 // Column label --- keywords that can be column label that doesn't use "AS"
 // before it. This is to guarantee that any new keyword won't break user
 // query like "SELECT col label FROM table" where "label" is a new keyword.
 // Any new keyword should be added to this list.
 bare_label_keywords:
-  ATOMIC
+  ALLOW_MISSING_LOCALITIES
+| APP_TOKEN
+| ATOMIC
 | CALLED
+| COLLECTION
+| COMPRESSION
+| CONSOLIDATE_CHANGES
+| COORDINATED_CLUSTERS
+| COORDINATOR
 | COST
+| DECRYPT_QUORUM
+| DEFERRED_DATA
+| DEFER_CONSTRAINT_VALIDATION
 | DEFINER
 | DEPENDS
+| DIFF
+| DRY_RUN
+| EXECUTION_LOCALITY
 | EXTERNAL
+| FAILOVER
 | IMMUTABLE
 | INPUT
 | INVOKER
+| KEY_OFFSET
+| KMS_BY_LOCALITY
+| LATEST_AS_OF
+| LATEST_VALUE
+| LAYOUT
 | LEAKPROOF
+| METADATA_URI
+| MIN_LATEST_TIME
+| ON_CONFLICT
 | PARALLEL
+| PART_SIZE
+| PAUSE_TTL_SCHEDULES
+| PER_TABLE_FILES
+| RECOVER
+| RELY_ON_ENCRYPTION_AT_REST
+| REMAP_REGIONS
+| RESTORE_FK_TO_EXISTING
 | RETURN
 | RETURNS
+| SCHEMA_CHANGE_POLICY
 | SECURITY
+| SKIP_COMMENTS
+| SKIP_IF_UNCHANGED
+| SKIP_JOBS
+| SKIP_STATISTICS
+| SKIP_ZONE_CONFIGS
 | STABLE
+| SUBDIR_FORMAT
 | SUPPORT
 | TRANSFORM
+| UPGRADE
+| UPLOAD_BUFFER_MEMORY
+| UPLOAD_PARALLELISM
 | VOLATILE
+| ZONE_CONFIGS
 | SETOF
 
 // Column identifier --- keywords that can be column, table, etc names.
@@ -709,6 +709,32 @@ func (p *planner) makeBoolEvalFn(typedE tree.TypedExpr) func(context.Context) (b
 	}
 }
 
+// TypeAsInt enforces (not hints) that the given expression typechecks as an
+// int and returns a function that can be called to get the int value during
+// (planNode).Start.
+func (p *planner) TypeAsInt(
+	ctx context.Context, e tree.Expr, op string,
+) (func() (int64, error), error) {
+	typedE, err := tree.TypeCheckAndRequire(ctx, e, &p.semaCtx, types.Int, op)
+	if err != nil {
+		return nil, err
+	}
+	return func() (int64, error) {
+		d, err := eval.Expr(ctx, p.EvalContext(), typedE)
+		if err != nil {
+			return 0, err
+		}
+		if d == tree.DNull {
+			return 0, errors.Errorf("expected int, got NULL")
+		}
+		i, ok := d.(*tree.DInt)
+		if !ok {
+			return 0, errors.Errorf("failed to cast %T to int", d)
+		}
+		return int64(*i), nil
+	}, nil
+}
+
 // KVStringOptValidate indicates the requested validation of a TypeAsStringOpts
 // option.
 type KVStringOptValidate string
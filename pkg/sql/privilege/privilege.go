@@ -59,6 +59,8 @@ const (
 	RESTORE                  Kind = 24
 	EXTERNALIOIMPLICITACCESS Kind = 25
 	CHANGEFEED               Kind = 26
+	RESTOREINTO              Kind = 27
+	BACKUPFROM               Kind = 28
 )
 
 // Privilege represents a privilege parsed from an Access Privilege Inquiry
@@ -112,11 +114,11 @@ var isDescriptorBacked = map[ObjectType]bool{
 
 // Predefined sets of privileges.
 var (
-	AllPrivileges         = List{ALL, CHANGEFEED, CONNECT, CREATE, DROP, SELECT, INSERT, DELETE, UPDATE, USAGE, ZONECONFIG, EXECUTE, BACKUP, RESTORE, EXTERNALIOIMPLICITACCESS}
+	AllPrivileges         = List{ALL, CHANGEFEED, CONNECT, CREATE, DROP, SELECT, INSERT, DELETE, UPDATE, USAGE, ZONECONFIG, EXECUTE, BACKUP, RESTORE, EXTERNALIOIMPLICITACCESS, RESTOREINTO, BACKUPFROM}
 	ReadData              = List{SELECT}
 	ReadWriteData         = List{SELECT, INSERT, DELETE, UPDATE}
 	ReadWriteSequenceData = List{SELECT, UPDATE, USAGE}
-	DBPrivileges          = List{ALL, BACKUP, CONNECT, CREATE, DROP, RESTORE, ZONECONFIG}
+	DBPrivileges          = List{ALL, BACKUP, CONNECT, CREATE, DROP, RESTORE, RESTOREINTO, ZONECONFIG}
 	TablePrivileges       = List{ALL, BACKUP, CHANGEFEED, CREATE, DROP, SELECT, INSERT, DELETE, UPDATE, ZONECONFIG}
 	SchemaPrivileges      = List{ALL, CREATE, USAGE}
 	TypePrivileges        = List{ALL, USAGE}
@@ -128,7 +130,7 @@ var (
 	SequencePrivileges           = List{ALL, USAGE, SELECT, UPDATE, CREATE, CHANGEFEED, DROP, INSERT, DELETE, ZONECONFIG}
 	GlobalPrivileges             = List{ALL, BACKUP, RESTORE, MODIFYCLUSTERSETTING, EXTERNALCONNECTION, VIEWACTIVITY, VIEWACTIVITYREDACTED, VIEWCLUSTERSETTING, CANCELQUERY, NOSQLLOGIN, VIEWCLUSTERMETADATA, VIEWDEBUG, EXTERNALIOIMPLICITACCESS}
 	VirtualTablePrivileges       = List{ALL, SELECT}
-	ExternalConnectionPrivileges = List{ALL, USAGE, DROP}
+	ExternalConnectionPrivileges = List{ALL, USAGE, DROP, BACKUPFROM}
 )
 
 // Mask returns the bitmask for a given privilege.
@@ -175,6 +177,8 @@ var ByName = map[string]Kind{
 	"BACKUP":                   BACKUP,
 	"RESTORE":                  RESTORE,
 	"EXTERNALIOIMPLICITACCESS": EXTERNALIOIMPLICITACCESS,
+	"RESTOREINTO":              RESTOREINTO,
+	"BACKUPFROM":               BACKUPFROM,
 }
 
 // List is a list of privileges.
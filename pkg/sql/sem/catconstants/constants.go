@@ -83,6 +83,9 @@ const (
 	SpanCountTableName                     SystemTableName = "span_count"
 	SystemPrivilegeTableName               SystemTableName = "privileges"
 	SystemExternalConnectionsTableName     SystemTableName = "external_connections"
+	SystemExternalIOAuditTableName         SystemTableName = "external_io_audit"
+	SystemScheduledBackupRunsTableName     SystemTableName = "scheduled_backup_runs"
+	SystemTableBackupCheckpointsTableName  SystemTableName = "table_backup_checkpoints"
 	RoleIDSequenceName                     SystemTableName = "role_id_seq"
 )
 
@@ -382,5 +385,8 @@ const (
 	PgExtensionGeographyColumnsTableID
 	PgExtensionGeometryColumnsTableID
 	PgExtensionSpatialRefSysTableID
-	MinVirtualID = PgExtensionSpatialRefSysTableID
+	CrdbInternalBackupRemainingSpansTableID
+	CrdbInternalExternalIOAuditTableID
+	CrdbInternalDatabaseStorageStatsTableID
+	MinVirtualID = CrdbInternalDatabaseStorageStatsTableID
 )
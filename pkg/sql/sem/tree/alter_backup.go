@@ -75,3 +75,44 @@ type BackupKMS struct {
 	NewKMSURI StringOrPlaceholderOptList
 	OldKMSURI StringOrPlaceholderOptList
 }
+
+// AlterBackupCollection represents an ALTER BACKUP COLLECTION ... RECOVER
+// LATEST statement, which points the LATEST file of a collection at its most
+// recent complete full backup, or an ALTER BACKUP COLLECTION ... UPGRADE
+// LAYOUT statement, which moves the files of a collection written by older
+// versions to where current versions write them.
+type AlterBackupCollection struct {
+	Collection    Expr
+	UpgradeLayout bool
+}
+
+var _ Statement = &AlterBackupCollection{}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterBackupCollection) Format(ctx *FmtCtx) {
+	ctx.WriteString("ALTER BACKUP COLLECTION ")
+	ctx.FormatNode(node.Collection)
+	if node.UpgradeLayout {
+		ctx.WriteString(" UPGRADE LAYOUT")
+	} else {
+		ctx.WriteString(" RECOVER LATEST")
+	}
+}
+
+// AlterBackupSchedules represents an ALTER BACKUP SCHEDULES FOR COLLECTION
+// ... PAUSE|RESUME statement, which pauses or resumes every backup schedule
+// that backs up into a collection.
+type AlterBackupSchedules struct {
+	Collection Expr
+	Command    ScheduleCommand
+}
+
+var _ Statement = &AlterBackupSchedules{}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterBackupSchedules) Format(ctx *FmtCtx) {
+	ctx.WriteString("ALTER BACKUP SCHEDULES FOR COLLECTION ")
+	ctx.FormatNode(node.Collection)
+	ctx.WriteByte(' ')
+	ctx.WriteString(node.Command.String())
+}
@@ -251,3 +251,39 @@ func (node *AlterDatabaseSetZoneConfigExtension) Format(ctx *FmtCtx) {
 	ctx.WriteString(" CONFIGURE ZONE ")
 	node.ZoneConfigSettings.Format(ctx)
 }
+
+// AlterDatabaseSetStorageParams represents a
+// ALTER DATABASE ... SET (...) statement.
+type AlterDatabaseSetStorageParams struct {
+	Name          Name
+	StorageParams StorageParams
+}
+
+var _ Statement = &AlterDatabaseSetStorageParams{}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterDatabaseSetStorageParams) Format(ctx *FmtCtx) {
+	ctx.WriteString("ALTER DATABASE ")
+	ctx.FormatNode(&node.Name)
+	ctx.WriteString(" SET (")
+	ctx.FormatNode(&node.StorageParams)
+	ctx.WriteString(")")
+}
+
+// AlterDatabaseResetStorageParams represents a
+// ALTER DATABASE ... RESET (...) statement.
+type AlterDatabaseResetStorageParams struct {
+	Name   Name
+	Params NameList
+}
+
+var _ Statement = &AlterDatabaseResetStorageParams{}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterDatabaseResetStorageParams) Format(ctx *FmtCtx) {
+	ctx.WriteString("ALTER DATABASE ")
+	ctx.FormatNode(&node.Name)
+	ctx.WriteString(" RESET (")
+	ctx.FormatNode(&node.Params)
+	ctx.WriteString(")")
+}
@@ -45,6 +45,29 @@ type BackupOptions struct {
 	Detached               *DBool
 	EncryptionKMSURI       StringOrPlaceholderOptList
 	IncrementalStorage     StringOrPlaceholderOptList
+	UploadParallelism      Expr
+	PartSize               Expr
+	UploadBufferMemory     Expr
+	Statistics             Expr
+	Comments               Expr
+	ZoneConfigs            Expr
+	Jobs                   Expr
+	DecryptQuorum          Expr
+	SubdirFormat           Expr
+	PerTableFiles          Expr
+	SchemaChangePolicy     Expr
+	RelyOnEncryptionAtRest Expr
+	ConsolidateChanges     Expr
+	ExecutionLocality      Expr
+	Priority               Expr
+	MetadataURI            Expr
+	AllowMissingLocalities Expr
+	Coordinator            Expr
+	CoordinatedClusters    Expr
+	Compression            Expr
+	SkipIfUnchanged        Expr
+	AppToken               Expr
+	KMSURIByLocality       KVOptions
 }
 
 var _ NodeFormatter = &BackupOptions{}
@@ -76,12 +99,25 @@ type Backup struct {
 	// explicitly specified by the user, then this will be set during BACKUP
 	// planning once the destination has been resolved.
 	Subdir Expr
+
+	// Failover is set to true when the user creates a backup with `BACKUP ...
+	// INTO ... FAILOVER (<primary>, <secondary>)`, in which case To holds the
+	// primary and the secondary collection.
+	Failover bool
+
+	// ExplainOnly is set by the parser when the SQL query is of the form
+	// `EXPLAIN BACKUP ...`. Such a statement only describes the backup the
+	// BACKUP would write, without running it.
+	ExplainOnly bool
 }
 
 var _ Statement = &Backup{}
 
 // Format implements the NodeFormatter interface.
 func (node *Backup) Format(ctx *FmtCtx) {
+	if node.ExplainOnly {
+		ctx.WriteString("EXPLAIN ")
+	}
 	ctx.WriteString("BACKUP ")
 	if node.Targets != nil {
 		ctx.FormatNode(node.Targets)
@@ -95,6 +131,9 @@ func (node *Backup) Format(ctx *FmtCtx) {
 		} else if node.AppendToLatest {
 			ctx.WriteString("LATEST IN ")
 		}
+		if node.Failover {
+			ctx.WriteString("FAILOVER ")
+		}
 	} else {
 		ctx.WriteString("TO ")
 	}
@@ -126,8 +165,10 @@ func (node Backup) Coverage() DescriptorCoverage {
 type RestoreOptions struct {
 	EncryptionPassphrase      Expr
 	DecryptionKMSURI          StringOrPlaceholderOptList
+	KMSURIByLocality          KVOptions
 	IntoDB                    Expr
 	SkipMissingFKs            bool
+	RestoreFKToExisting       bool
 	SkipMissingSequences      bool
 	SkipMissingSequenceOwners bool
 	SkipMissingViews          bool
@@ -139,6 +180,23 @@ type RestoreOptions struct {
 	AsTenant                  Expr
 	SchemaOnly                bool
 	VerifyData                bool
+	LatestValue               Expr
+	LatestAsOf                Expr
+	MinLatestTime             Expr
+	SkipStatistics            bool
+	SkipComments              bool
+	SkipZoneConfigs           bool
+	SkipJobs                  bool
+	PauseTTLSchedules         bool
+	RemapRegions              StringOrPlaceholderOptList
+	OnConflict                Expr
+	MetadataURI               Expr
+	DeferredData              bool
+	DryRun                    bool
+	ExecutionLocality         Expr
+	KeyOffset                 Expr
+	Columns                   NameList
+	DeferConstraintValidation bool
 }
 
 var _ NodeFormatter = &RestoreOptions{}
@@ -163,14 +221,49 @@ type Restore struct {
 	// ... FROM 'from' IN 'subdir'...`. Alternatively, restore_planning.go will set
 	// it for the query `RESTORE ... FROM 'from' IN LATEST...`
 	Subdir Expr
+
+	// TableRenames is set by the parser when the SQL query is of the form
+	// `RESTORE TABLE a AS x, b AS y FROM ...`. When set, TableRenames[i]
+	// describes the new name of the table matched by
+	// Targets.Tables.TablePatterns[i].
+	TableRenames RestoreTableRenames
+
+	// Index is set by the parser when the SQL query is of the form `RESTORE
+	// INDEX t@idx FROM ...`, which rebuilds a secondary index of an existing
+	// table from the data of the index in the backup. Targets then holds the
+	// table of the index.
+	Index *TableIndexName
+
+	// PrepareOnly is set by the parser when the SQL query is of the form
+	// `PREPARE RESTORE ... FROM 'subdir' IN 'from'...`. Such a statement only
+	// resolves and caches the backups the RESTORE would read.
+	PrepareOnly bool
+
+	// ExplainOnly is set by the parser when the SQL query is of the form
+	// `EXPLAIN RESTORE ...`. Such a statement only describes the backups the
+	// RESTORE would read and how it would read them, without running it.
+	ExplainOnly bool
 }
 
 var _ Statement = &Restore{}
 
 // Format implements the NodeFormatter interface.
 func (node *Restore) Format(ctx *FmtCtx) {
+	if node.PrepareOnly {
+		ctx.WriteString("PREPARE ")
+	} else if node.ExplainOnly {
+		ctx.WriteString("EXPLAIN ")
+	}
 	ctx.WriteString("RESTORE ")
-	if node.DescriptorCoverage == RequestedDescriptors {
+	if len(node.TableRenames) > 0 {
+		ctx.WriteString("TABLE ")
+		ctx.FormatNode(&node.TableRenames)
+		ctx.WriteString(" ")
+	} else if node.Index != nil {
+		ctx.WriteString("INDEX ")
+		ctx.FormatNode(node.Index)
+		ctx.WriteString(" ")
+	} else if node.DescriptorCoverage == RequestedDescriptors {
 		ctx.FormatNode(&node.Targets)
 		ctx.WriteString(" ")
 	}
@@ -195,6 +288,34 @@ func (node *Restore) Format(ctx *FmtCtx) {
 	}
 }
 
+// RestoreTableRename maps a table in a backup to the name it is restored
+// under. NewName may be qualified with a schema, in which case the table is
+// restored into that schema of its target database.
+type RestoreTableRename struct {
+	Table   *UnresolvedObjectName
+	NewName *UnresolvedObjectName
+}
+
+// Format implements the NodeFormatter interface.
+func (r *RestoreTableRename) Format(ctx *FmtCtx) {
+	ctx.FormatNode(r.Table)
+	ctx.WriteString(" AS ")
+	ctx.FormatNode(r.NewName)
+}
+
+// RestoreTableRenames is a list of RestoreTableRename.
+type RestoreTableRenames []RestoreTableRename
+
+// Format implements the NodeFormatter interface.
+func (rs *RestoreTableRenames) Format(ctx *FmtCtx) {
+	for i := range *rs {
+		if i > 0 {
+			ctx.WriteString(", ")
+		}
+		ctx.FormatNode(&(*rs)[i])
+	}
+}
+
 // KVOption is a key-value option.
 type KVOption struct {
 	Key   Name
@@ -278,6 +399,145 @@ func (o *BackupOptions) Format(ctx *FmtCtx) {
 		ctx.WriteString("incremental_location = ")
 		ctx.FormatNode(&o.IncrementalStorage)
 	}
+
+	if o.UploadParallelism != nil {
+		maybeAddSep()
+		ctx.WriteString("upload_parallelism = ")
+		ctx.FormatNode(o.UploadParallelism)
+	}
+
+	if o.PartSize != nil {
+		maybeAddSep()
+		ctx.WriteString("part_size = ")
+		ctx.FormatNode(o.PartSize)
+	}
+
+	if o.UploadBufferMemory != nil {
+		maybeAddSep()
+		ctx.WriteString("upload_buffer_memory = ")
+		ctx.FormatNode(o.UploadBufferMemory)
+	}
+
+	if o.Statistics != nil {
+		maybeAddSep()
+		ctx.WriteString("statistics = ")
+		ctx.FormatNode(o.Statistics)
+	}
+
+	if o.Comments != nil {
+		maybeAddSep()
+		ctx.WriteString("comments = ")
+		ctx.FormatNode(o.Comments)
+	}
+
+	if o.ZoneConfigs != nil {
+		maybeAddSep()
+		ctx.WriteString("zone_configs = ")
+		ctx.FormatNode(o.ZoneConfigs)
+	}
+
+	if o.Jobs != nil {
+		maybeAddSep()
+		ctx.WriteString("jobs = ")
+		ctx.FormatNode(o.Jobs)
+	}
+
+	if o.DecryptQuorum != nil {
+		maybeAddSep()
+		ctx.WriteString("decrypt_quorum = ")
+		ctx.FormatNode(o.DecryptQuorum)
+	}
+
+	if o.KMSURIByLocality != nil {
+		maybeAddSep()
+		ctx.WriteString("kms_by_locality = (")
+		ctx.FormatNode(&o.KMSURIByLocality)
+		ctx.WriteString(")")
+	}
+
+	if o.SubdirFormat != nil {
+		maybeAddSep()
+		ctx.WriteString("subdir_format = ")
+		ctx.FormatNode(o.SubdirFormat)
+	}
+
+	if o.PerTableFiles != nil {
+		maybeAddSep()
+		ctx.WriteString("per_table_files = ")
+		ctx.FormatNode(o.PerTableFiles)
+	}
+
+	if o.SchemaChangePolicy != nil {
+		maybeAddSep()
+		ctx.WriteString("schema_change_policy = ")
+		ctx.FormatNode(o.SchemaChangePolicy)
+	}
+
+	if o.RelyOnEncryptionAtRest != nil {
+		maybeAddSep()
+		ctx.WriteString("rely_on_encryption_at_rest = ")
+		ctx.FormatNode(o.RelyOnEncryptionAtRest)
+	}
+
+	if o.ConsolidateChanges != nil {
+		maybeAddSep()
+		ctx.WriteString("consolidate_changes = ")
+		ctx.FormatNode(o.ConsolidateChanges)
+	}
+
+	if o.ExecutionLocality != nil {
+		maybeAddSep()
+		ctx.WriteString("execution_locality = ")
+		ctx.FormatNode(o.ExecutionLocality)
+	}
+
+	if o.Priority != nil {
+		maybeAddSep()
+		ctx.WriteString("priority = ")
+		ctx.FormatNode(o.Priority)
+	}
+
+	if o.MetadataURI != nil {
+		maybeAddSep()
+		ctx.WriteString("metadata_uri = ")
+		ctx.FormatNode(o.MetadataURI)
+	}
+
+	if o.AllowMissingLocalities != nil {
+		maybeAddSep()
+		ctx.WriteString("allow_missing_localities = ")
+		ctx.FormatNode(o.AllowMissingLocalities)
+	}
+
+	if o.Coordinator != nil {
+		maybeAddSep()
+		ctx.WriteString("coordinator = ")
+		ctx.FormatNode(o.Coordinator)
+	}
+
+	if o.CoordinatedClusters != nil {
+		maybeAddSep()
+		ctx.WriteString("coordinated_clusters = ")
+		ctx.FormatNode(o.CoordinatedClusters)
+	}
+
+	if o.Compression != nil {
+		maybeAddSep()
+		ctx.WriteString("compression = ")
+		ctx.FormatNode(o.Compression)
+	}
+
+	if o.SkipIfUnchanged != nil {
+		maybeAddSep()
+		ctx.WriteString("skip_if_unchanged = ")
+		ctx.FormatNode(o.SkipIfUnchanged)
+	}
+
+	if o.AppToken != nil {
+		maybeAddSep()
+		ctx.WriteString("app_token = ")
+		ctx.FormatNode(o.AppToken)
+	}
 }
 
 // CombineWith merges other backup options into this backup options struct.
@@ -317,6 +577,144 @@ func (o *BackupOptions) CombineWith(other *BackupOptions) error {
 		return errors.New("incremental_location option specified multiple times")
 	}
 
+	if o.UploadParallelism == nil {
+		o.UploadParallelism = other.UploadParallelism
+	} else if other.UploadParallelism != nil {
+		return errors.New("upload_parallelism option specified multiple times")
+	}
+
+	if o.PartSize == nil {
+		o.PartSize = other.PartSize
+	} else if other.PartSize != nil {
+		return errors.New("part_size option specified multiple times")
+	}
+
+	if o.UploadBufferMemory == nil {
+		o.UploadBufferMemory = other.UploadBufferMemory
+	} else if other.UploadBufferMemory != nil {
+		return errors.New("upload_buffer_memory option specified multiple times")
+	}
+
+	if o.Statistics == nil {
+		o.Statistics = other.Statistics
+	} else if other.Statistics != nil {
+		return errors.New("statistics option specified multiple times")
+	}
+
+	if o.Comments == nil {
+		o.Comments = other.Comments
+	} else if other.Comments != nil {
+		return errors.New("comments option specified multiple times")
+	}
+
+	if o.ZoneConfigs == nil {
+		o.ZoneConfigs = other.ZoneConfigs
+	} else if other.ZoneConfigs != nil {
+		return errors.New("zone_configs option specified multiple times")
+	}
+
+	if o.Jobs == nil {
+		o.Jobs = other.Jobs
+	} else if other.Jobs != nil {
+		return errors.New("jobs option specified multiple times")
+	}
+
+	if o.DecryptQuorum == nil {
+		o.DecryptQuorum = other.DecryptQuorum
+	} else if other.DecryptQuorum != nil {
+		return errors.New("decrypt_quorum option specified multiple times")
+	}
+
+	if o.KMSURIByLocality == nil {
+		o.KMSURIByLocality = other.KMSURIByLocality
+	} else if other.KMSURIByLocality != nil {
+		return errors.New("kms_by_locality option specified multiple times")
+	}
+
+	if o.SubdirFormat == nil {
+		o.SubdirFormat = other.SubdirFormat
+	} else if other.SubdirFormat != nil {
+		return errors.New("subdir_format option specified multiple times")
+	}
+
+	if o.PerTableFiles == nil {
+		o.PerTableFiles = other.PerTableFiles
+	} else if other.PerTableFiles != nil {
+		return errors.New("per_table_files option specified multiple times")
+	}
+
+	if o.SchemaChangePolicy == nil {
+		o.SchemaChangePolicy = other.SchemaChangePolicy
+	} else if other.SchemaChangePolicy != nil {
+		return errors.New("schema_change_policy option specified multiple times")
+	}
+
+	if o.RelyOnEncryptionAtRest == nil {
+		o.RelyOnEncryptionAtRest = other.RelyOnEncryptionAtRest
+	} else if other.RelyOnEncryptionAtRest != nil {
+		return errors.New("rely_on_encryption_at_rest option specified multiple times")
+	}
+
+	if o.ConsolidateChanges == nil {
+		o.ConsolidateChanges = other.ConsolidateChanges
+	} else if other.ConsolidateChanges != nil {
+		return errors.New("consolidate_changes option specified multiple times")
+	}
+
+	if o.ExecutionLocality == nil {
+		o.ExecutionLocality = other.ExecutionLocality
+	} else if other.ExecutionLocality != nil {
+		return errors.New("execution_locality option specified multiple times")
+	}
+
+	if o.Priority == nil {
+		o.Priority = other.Priority
+	} else if other.Priority != nil {
+		return errors.New("priority option specified multiple times")
+	}
+
+	if o.MetadataURI == nil {
+		o.MetadataURI = other.MetadataURI
+	} else if other.MetadataURI != nil {
+		return errors.New("metadata_uri option specified multiple times")
+	}
+
+	if o.AllowMissingLocalities == nil {
+		o.AllowMissingLocalities = other.AllowMissingLocalities
+	} else if other.AllowMissingLocalities != nil {
+		return errors.New("allow_missing_localities option specified multiple times")
+	}
+
+	if o.Coordinator == nil {
+		o.Coordinator = other.Coordinator
+	} else if other.Coordinator != nil {
+		return errors.New("coordinator option specified multiple times")
+	}
+
+	if o.CoordinatedClusters == nil {
+		o.CoordinatedClusters = other.CoordinatedClusters
+	} else if other.CoordinatedClusters != nil {
+		return errors.New("coordinated_clusters option specified multiple times")
+	}
+
+	if o.Compression == nil {
+		o.Compression = other.Compression
+	} else if other.Compression != nil {
+		return errors.New("compression option specified multiple times")
+	}
+
+	if o.SkipIfUnchanged == nil {
+		o.SkipIfUnchanged = other.SkipIfUnchanged
+	} else if other.SkipIfUnchanged != nil {
+		return errors.New("skip_if_unchanged option specified multiple times")
+	}
+
+	if o.AppToken == nil {
+		o.AppToken = other.AppToken
+	} else if other.AppToken != nil {
+		return errors.New("app_token option specified multiple times")
+	}
+
 	return nil
 }
 
@@ -326,7 +724,30 @@ func (o BackupOptions) IsDefault() bool {
 	return o.CaptureRevisionHistory == options.CaptureRevisionHistory &&
 		o.Detached == options.Detached && cmp.Equal(o.EncryptionKMSURI, options.EncryptionKMSURI) &&
 		o.EncryptionPassphrase == options.EncryptionPassphrase &&
-		cmp.Equal(o.IncrementalStorage, options.IncrementalStorage)
+		cmp.Equal(o.IncrementalStorage, options.IncrementalStorage) &&
+		o.UploadParallelism == options.UploadParallelism &&
+		o.PartSize == options.PartSize &&
+		o.UploadBufferMemory == options.UploadBufferMemory &&
+		o.Statistics == options.Statistics &&
+		o.Comments == options.Comments &&
+		o.ZoneConfigs == options.ZoneConfigs &&
+		o.Jobs == options.Jobs &&
+		o.DecryptQuorum == options.DecryptQuorum &&
+		cmp.Equal(o.KMSURIByLocality, options.KMSURIByLocality) &&
+		o.SubdirFormat == options.SubdirFormat &&
+		o.PerTableFiles == options.PerTableFiles &&
+		o.SchemaChangePolicy == options.SchemaChangePolicy &&
+		o.RelyOnEncryptionAtRest == options.RelyOnEncryptionAtRest &&
+		o.ConsolidateChanges == options.ConsolidateChanges &&
+		o.ExecutionLocality == options.ExecutionLocality &&
+		o.Priority == options.Priority &&
+		o.MetadataURI == options.MetadataURI &&
+		o.AllowMissingLocalities == options.AllowMissingLocalities &&
+		o.Coordinator == options.Coordinator &&
+		o.CoordinatedClusters == options.CoordinatedClusters &&
+		o.Compression == options.Compression &&
+		o.SkipIfUnchanged == options.SkipIfUnchanged &&
+		o.AppToken == options.AppToken
 }
 
 // Format implements the NodeFormatter interface.
@@ -350,6 +771,13 @@ func (o *RestoreOptions) Format(ctx *FmtCtx) {
 		ctx.FormatNode(&o.DecryptionKMSURI)
 	}
 
+	if o.KMSURIByLocality != nil {
+		maybeAddSep()
+		ctx.WriteString("kms_by_locality = (")
+		ctx.FormatNode(&o.KMSURIByLocality)
+		ctx.WriteString(")")
+	}
+
 	if o.IntoDB != nil {
 		maybeAddSep()
 		ctx.WriteString("into_db = ")
@@ -367,6 +795,11 @@ func (o *RestoreOptions) Format(ctx *FmtCtx) {
 		ctx.WriteString("skip_missing_foreign_keys")
 	}
 
+	if o.RestoreFKToExisting {
+		maybeAddSep()
+		ctx.WriteString("restore_fk_to_existing")
+	}
+
 	if o.SkipMissingSequenceOwners {
 		maybeAddSep()
 		ctx.WriteString("skip_missing_sequence_owners")
@@ -417,6 +850,84 @@ func (o *RestoreOptions) Format(ctx *FmtCtx) {
 		maybeAddSep()
 		ctx.WriteString("verify_backup_table_data")
 	}
+	if o.LatestValue != nil {
+		maybeAddSep()
+		ctx.WriteString("latest_value = ")
+		ctx.FormatNode(o.LatestValue)
+	}
+	if o.LatestAsOf != nil {
+		maybeAddSep()
+		ctx.WriteString("latest_as_of = ")
+		ctx.FormatNode(o.LatestAsOf)
+	}
+	if o.MinLatestTime != nil {
+		maybeAddSep()
+		ctx.WriteString("min_latest_time = ")
+		ctx.FormatNode(o.MinLatestTime)
+	}
+	if o.SkipStatistics {
+		maybeAddSep()
+		ctx.WriteString("skip_statistics")
+	}
+	if o.SkipComments {
+		maybeAddSep()
+		ctx.WriteString("skip_comments")
+	}
+	if o.SkipZoneConfigs {
+		maybeAddSep()
+		ctx.WriteString("skip_zone_configs")
+	}
+	if o.SkipJobs {
+		maybeAddSep()
+		ctx.WriteString("skip_jobs")
+	}
+	if o.PauseTTLSchedules {
+		maybeAddSep()
+		ctx.WriteString("pause_ttl_schedules")
+	}
+	if o.RemapRegions != nil {
+		maybeAddSep()
+		ctx.WriteString("remap_regions = ")
+		ctx.FormatNode(&o.RemapRegions)
+	}
+	if o.OnConflict != nil {
+		maybeAddSep()
+		ctx.WriteString("on_conflict = ")
+		ctx.FormatNode(o.OnConflict)
+	}
+	if o.MetadataURI != nil {
+		maybeAddSep()
+		ctx.WriteString("metadata_uri = ")
+		ctx.FormatNode(o.MetadataURI)
+	}
+	if o.DeferredData {
+		maybeAddSep()
+		ctx.WriteString("deferred_data")
+	}
+	if o.DryRun {
+		maybeAddSep()
+		ctx.WriteString("dry_run")
+	}
+	if o.ExecutionLocality != nil {
+		maybeAddSep()
+		ctx.WriteString("execution_locality = ")
+		ctx.FormatNode(o.ExecutionLocality)
+	}
+	if o.KeyOffset != nil {
+		maybeAddSep()
+		ctx.WriteString("key_offset = ")
+		ctx.FormatNode(o.KeyOffset)
+	}
+	if o.Columns != nil {
+		maybeAddSep()
+		ctx.WriteString("columns = (")
+		ctx.FormatNode(&o.Columns)
+		ctx.WriteString(")")
+	}
+	if o.DeferConstraintValidation {
+		maybeAddSep()
+		ctx.WriteString("defer_constraint_validation")
+	}
 }
 
 // CombineWith merges other backup options into this backup options struct.
@@ -434,6 +945,12 @@ func (o *RestoreOptions) CombineWith(other *RestoreOptions) error {
 		return errors.New("kms specified multiple times")
 	}
 
+	if o.KMSURIByLocality == nil {
+		o.KMSURIByLocality = other.KMSURIByLocality
+	} else if other.KMSURIByLocality != nil {
+		return errors.New("kms_by_locality option specified multiple times")
+	}
+
 	if o.IntoDB == nil {
 		o.IntoDB = other.IntoDB
 	} else if other.IntoDB != nil {
@@ -448,6 +965,14 @@ func (o *RestoreOptions) CombineWith(other *RestoreOptions) error {
 		o.SkipMissingFKs = other.SkipMissingFKs
 	}
 
+	if o.RestoreFKToExisting {
+		if other.RestoreFKToExisting {
+			return errors.New("restore_fk_to_existing specified multiple times")
+		}
+	} else {
+		o.RestoreFKToExisting = other.RestoreFKToExisting
+	}
+
 	if o.SkipMissingSequences {
 		if other.SkipMissingSequences {
 			return errors.New("skip_missing_sequences specified multiple times")
@@ -526,6 +1051,124 @@ func (o *RestoreOptions) CombineWith(other *RestoreOptions) error {
 	} else {
 		o.VerifyData = other.VerifyData
 	}
+
+	if o.LatestValue == nil {
+		o.LatestValue = other.LatestValue
+	} else if other.LatestValue != nil {
+		return errors.New("latest_value specified multiple times")
+	}
+
+	if o.LatestAsOf == nil {
+		o.LatestAsOf = other.LatestAsOf
+	} else if other.LatestAsOf != nil {
+		return errors.New("latest_as_of specified multiple times")
+	}
+
+	if o.MinLatestTime == nil {
+		o.MinLatestTime = other.MinLatestTime
+	} else if other.MinLatestTime != nil {
+		return errors.New("min_latest_time specified multiple times")
+	}
+
+	if o.SkipStatistics {
+		if other.SkipStatistics {
+			return errors.New("skip_statistics specified multiple times")
+		}
+	} else {
+		o.SkipStatistics = other.SkipStatistics
+	}
+
+	if o.SkipComments {
+		if other.SkipComments {
+			return errors.New("skip_comments specified multiple times")
+		}
+	} else {
+		o.SkipComments = other.SkipComments
+	}
+
+	if o.SkipZoneConfigs {
+		if other.SkipZoneConfigs {
+			return errors.New("skip_zone_configs specified multiple times")
+		}
+	} else {
+		o.SkipZoneConfigs = other.SkipZoneConfigs
+	}
+
+	if o.SkipJobs {
+		if other.SkipJobs {
+			return errors.New("skip_jobs specified multiple times")
+		}
+	} else {
+		o.SkipJobs = other.SkipJobs
+	}
+
+	if o.PauseTTLSchedules {
+		if other.PauseTTLSchedules {
+			return errors.New("pause_ttl_schedules specified multiple times")
+		}
+	} else {
+		o.PauseTTLSchedules = other.PauseTTLSchedules
+	}
+
+	if o.RemapRegions == nil {
+		o.RemapRegions = other.RemapRegions
+	} else if other.RemapRegions != nil {
+		return errors.New("remap_regions specified multiple times")
+	}
+
+	if o.OnConflict == nil {
+		o.OnConflict = other.OnConflict
+	} else if other.OnConflict != nil {
+		return errors.New("on_conflict specified multiple times")
+	}
+
+	if o.MetadataURI == nil {
+		o.MetadataURI = other.MetadataURI
+	} else if other.MetadataURI != nil {
+		return errors.New("metadata_uri specified multiple times")
+	}
+
+	if o.DeferredData {
+		if other.DeferredData {
+			return errors.New("deferred_data specified multiple times")
+		}
+	} else {
+		o.DeferredData = other.DeferredData
+	}
+
+	if o.DryRun {
+		if other.DryRun {
+			return errors.New("dry_run specified multiple times")
+		}
+	} else {
+		o.DryRun = other.DryRun
+	}
+
+	if o.ExecutionLocality == nil {
+		o.ExecutionLocality = other.ExecutionLocality
+	} else if other.ExecutionLocality != nil {
+		return errors.New("execution_locality option specified multiple times")
+	}
+
+	if o.KeyOffset == nil {
+		o.KeyOffset = other.KeyOffset
+	} else if other.KeyOffset != nil {
+		return errors.New("key_offset option specified multiple times")
+	}
+
+	if o.Columns == nil {
+		o.Columns = other.Columns
+	} else if other.Columns != nil {
+		return errors.New("columns option specified multiple times")
+	}
+
+	if o.DeferConstraintValidation {
+		if other.DeferConstraintValidation {
+			return errors.New("defer_constraint_validation specified multiple times")
+		}
+	} else {
+		o.DeferConstraintValidation = other.DeferConstraintValidation
+	}
 	return nil
 }
 
@@ -533,10 +1176,12 @@ func (o *RestoreOptions) CombineWith(other *RestoreOptions) error {
 func (o RestoreOptions) IsDefault() bool {
 	options := RestoreOptions{}
 	return o.SkipMissingFKs == options.SkipMissingFKs &&
+		o.RestoreFKToExisting == options.RestoreFKToExisting &&
 		o.SkipMissingSequences == options.SkipMissingSequences &&
 		o.SkipMissingSequenceOwners == options.SkipMissingSequenceOwners &&
 		o.SkipMissingViews == options.SkipMissingViews &&
 		cmp.Equal(o.DecryptionKMSURI, options.DecryptionKMSURI) &&
+		cmp.Equal(o.KMSURIByLocality, options.KMSURIByLocality) &&
 		o.EncryptionPassphrase == options.EncryptionPassphrase &&
 		o.IntoDB == options.IntoDB &&
 		o.Detached == options.Detached &&
@@ -546,7 +1191,24 @@ func (o RestoreOptions) IsDefault() bool {
 		cmp.Equal(o.IncrementalStorage, options.IncrementalStorage) &&
 		o.AsTenant == options.AsTenant &&
 		o.SchemaOnly == options.SchemaOnly &&
-		o.VerifyData == options.VerifyData
+		o.VerifyData == options.VerifyData &&
+		o.LatestValue == options.LatestValue &&
+		o.LatestAsOf == options.LatestAsOf &&
+		o.MinLatestTime == options.MinLatestTime &&
+		o.SkipStatistics == options.SkipStatistics &&
+		o.SkipComments == options.SkipComments &&
+		o.SkipZoneConfigs == options.SkipZoneConfigs &&
+		o.SkipJobs == options.SkipJobs &&
+		o.PauseTTLSchedules == options.PauseTTLSchedules &&
+		cmp.Equal(o.RemapRegions, options.RemapRegions) &&
+		o.OnConflict == options.OnConflict &&
+		o.MetadataURI == options.MetadataURI &&
+		o.DeferredData == options.DeferredData &&
+		o.DryRun == options.DryRun &&
+		o.ExecutionLocality == options.ExecutionLocality &&
+		o.KeyOffset == options.KeyOffset &&
+		cmp.Equal(o.Columns, options.Columns) &&
+		o.DeferConstraintValidation == options.DeferConstraintValidation
 }
 
 // BackupTargetList represents a list of targets.
@@ -556,6 +1218,18 @@ type BackupTargetList struct {
 	Schemas   ObjectNamePrefixList
 	Tables    TableAttrs
 	TenantID  TenantID
+
+	// TableFilter, if set, restricts the tables of Databases that are
+	// targeted. It is only valid alongside Databases.
+	TableFilter *BackupTableFilter
+
+	// AllTenants is set by BACKUP VIRTUAL CLUSTER ALL, which backs up each
+	// tenant separately.
+	AllTenants bool
+
+	// RowFilter, if set, restricts the rows of the table in Tables that are
+	// targeted. It is only valid alongside a single table.
+	RowFilter Expr
 }
 
 // Format implements the NodeFormatter interface.
@@ -563,12 +1237,18 @@ func (tl *BackupTargetList) Format(ctx *FmtCtx) {
 	if tl.Databases != nil {
 		ctx.WriteString("DATABASE ")
 		ctx.FormatNode(&tl.Databases)
+		if tl.TableFilter != nil {
+			ctx.WriteByte(' ')
+			ctx.FormatNode(tl.TableFilter)
+		}
 	} else if tl.Schemas != nil {
 		ctx.WriteString("SCHEMA ")
 		ctx.FormatNode(&tl.Schemas)
 	} else if tl.TenantID.Specified {
 		ctx.WriteString("TENANT ")
 		ctx.FormatNode(&tl.TenantID)
+	} else if tl.AllTenants {
+		ctx.WriteString("VIRTUAL CLUSTER ALL")
 	} else {
 		if tl.Tables.SequenceOnly {
 			ctx.WriteString("SEQUENCE ")
@@ -576,5 +1256,28 @@ func (tl *BackupTargetList) Format(ctx *FmtCtx) {
 			ctx.WriteString("TABLE ")
 		}
 		ctx.FormatNode(&tl.Tables.TablePatterns)
+		if tl.RowFilter != nil {
+			ctx.WriteString(" WHERE ")
+			ctx.FormatNode(tl.RowFilter)
+		}
+	}
+}
+
+// BackupTableFilter restricts the tables of the databases targeted by a
+// BACKUP to those whose names match (INCLUDE TABLES) or do not match
+// (EXCLUDE TABLES) any of a list of patterns.
+type BackupTableFilter struct {
+	Exclude  bool
+	Patterns Exprs
+}
+
+// Format implements the NodeFormatter interface.
+func (f *BackupTableFilter) Format(ctx *FmtCtx) {
+	if f.Exclude {
+		ctx.WriteString("EXCLUDE TABLES (")
+	} else {
+		ctx.WriteString("INCLUDE TABLES (")
 	}
+	ctx.FormatNode(&f.Patterns)
+	ctx.WriteByte(')')
 }
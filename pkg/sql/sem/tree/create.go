@@ -2181,6 +2181,7 @@ func (node *CreateExtension) Format(ctx *FmtCtx) {
 type CreateExternalConnection struct {
 	ConnectionLabelSpec LabelSpec
 	As                  Expr
+	Options             KVOptions
 }
 
 var _ Statement = &CreateExternalConnection{}
@@ -2191,4 +2192,8 @@ func (node *CreateExternalConnection) Format(ctx *FmtCtx) {
 	ctx.FormatNode(&node.ConnectionLabelSpec)
 	ctx.WriteString(" AS ")
 	ctx.FormatNode(node.As)
+	if node.Options != nil {
+		ctx.WriteString(" WITH ")
+		ctx.FormatNode(&node.Options)
+	}
 }
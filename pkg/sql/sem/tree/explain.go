@@ -222,8 +222,22 @@ func (node *ExplainAnalyze) doc(p *PrettyCfg) pretty.Doc {
 }
 
 // MakeExplain parses the EXPLAIN option strings and generates an Explain
-// or ExplainAnalyze statement.
+// or ExplainAnalyze statement. A BACKUP or RESTORE explained without options
+// is instead returned with ExplainOnly set.
 func MakeExplain(options []string, stmt Statement) (Statement, error) {
+	// The plan of a BACKUP or RESTORE is an opaque node that runs a job, so its
+	// EXPLAIN describes what the job would do instead, which takes planning
+	// the statement itself.
+	if len(options) == 0 {
+		switch s := stmt.(type) {
+		case *Backup:
+			s.ExplainOnly = true
+			return s, nil
+		case *Restore:
+			s.ExplainOnly = true
+			return s, nil
+		}
+	}
 	for i := range options {
 		options[i] = strings.ToUpper(options[i])
 	}
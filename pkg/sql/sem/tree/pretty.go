@@ -2100,7 +2100,11 @@ func (node *ReferenceActions) doc(p *PrettyCfg) pretty.Doc {
 func (node *Backup) doc(p *PrettyCfg) pretty.Doc {
 	items := make([]pretty.TableRow, 0, 7)
 
-	items = append(items, p.row("BACKUP", pretty.Nil))
+	if node.ExplainOnly {
+		items = append(items, p.row("EXPLAIN BACKUP", pretty.Nil))
+	} else {
+		items = append(items, p.row("BACKUP", pretty.Nil))
+	}
 	if node.Targets != nil {
 		items = append(items, node.Targets.docRow(p))
 	}
@@ -2132,8 +2136,18 @@ func (node *Backup) doc(p *PrettyCfg) pretty.Doc {
 func (node *Restore) doc(p *PrettyCfg) pretty.Doc {
 	items := make([]pretty.TableRow, 0, 6)
 
-	items = append(items, p.row("RESTORE", pretty.Nil))
-	if node.DescriptorCoverage == RequestedDescriptors {
+	if node.PrepareOnly {
+		items = append(items, p.row("PREPARE RESTORE", pretty.Nil))
+	} else if node.ExplainOnly {
+		items = append(items, p.row("EXPLAIN RESTORE", pretty.Nil))
+	} else {
+		items = append(items, p.row("RESTORE", pretty.Nil))
+	}
+	if len(node.TableRenames) > 0 {
+		items = append(items, p.row("TABLE", p.Doc(&node.TableRenames)))
+	} else if node.Index != nil {
+		items = append(items, p.row("INDEX", p.Doc(node.Index)))
+	} else if node.DescriptorCoverage == RequestedDescriptors {
 		items = append(items, node.Targets.docRow(p))
 	}
 	from := make([]pretty.Doc, len(node.From))
@@ -2162,14 +2176,24 @@ func (node *BackupTargetList) doc(p *PrettyCfg) pretty.Doc {
 
 func (node *BackupTargetList) docRow(p *PrettyCfg) pretty.TableRow {
 	if node.Databases != nil {
+		if node.TableFilter != nil {
+			return p.row("DATABASE", p.nestUnder(p.Doc(&node.Databases), p.Doc(node.TableFilter)))
+		}
 		return p.row("DATABASE", p.Doc(&node.Databases))
 	}
 	if node.TenantID.Specified {
 		return p.row("TENANT", p.Doc(&node.TenantID))
 	}
+	if node.AllTenants {
+		return p.row("VIRTUAL CLUSTER", pretty.Keyword("ALL"))
+	}
 	if node.Tables.SequenceOnly {
 		return p.row("SEQUENCE", p.Doc(&node.Tables.TablePatterns))
 	}
+	if node.RowFilter != nil {
+		return p.row("TABLE", p.nestUnder(p.Doc(&node.Tables.TablePatterns),
+			p.nestUnder(pretty.Keyword("WHERE"), p.Doc(node.RowFilter))))
+	}
 	return p.row("TABLE", p.Doc(&node.Tables.TablePatterns))
 }
 
@@ -88,6 +88,8 @@ const (
 	// BackupValidateDetails identifies a SHOW BACKUP VALIDATION
 	// statement.
 	BackupValidateDetails
+	// BackupDiffDetails identifies a SHOW BACKUP DIFF statement.
+	BackupDiffDetails
 )
 
 // TODO (msbutler): 22.2 after removing old style show backup syntax, rename
@@ -100,6 +102,8 @@ type ShowBackup struct {
 	From         bool
 	Details      ShowBackupDetails
 	Options      KVOptions
+	// DiffPath is the backup that Path is compared against by SHOW BACKUP DIFF.
+	DiffPath Expr
 }
 
 // Format implements the NodeFormatter interface.
@@ -111,6 +115,20 @@ func (node *ShowBackup) Format(ctx *FmtCtx) {
 	}
 	ctx.WriteString("SHOW BACKUP ")
 
+	if node.Details == BackupDiffDetails {
+		ctx.WriteString("DIFF ")
+		ctx.FormatNode(node.Path)
+		ctx.WriteString(" AND ")
+		ctx.FormatNode(node.DiffPath)
+		ctx.WriteString(" IN ")
+		ctx.FormatNode(&node.InCollection)
+		if len(node.Options) > 0 {
+			ctx.WriteString(" WITH ")
+			ctx.FormatNode(&node.Options)
+		}
+		return
+	}
+
 	switch node.Details {
 	case BackupRangeDetails:
 		ctx.WriteString("RANGES ")
@@ -172,6 +172,8 @@ type CCLOnlyStatement interface {
 
 var _ CCLOnlyStatement = &AlterBackup{}
 var _ CCLOnlyStatement = &AlterBackupSchedule{}
+var _ CCLOnlyStatement = &AlterBackupCollection{}
+var _ CCLOnlyStatement = &AlterBackupSchedules{}
 var _ CCLOnlyStatement = &Backup{}
 var _ CCLOnlyStatement = &ShowBackup{}
 var _ CCLOnlyStatement = &Restore{}
@@ -204,6 +206,28 @@ func (*AlterBackup) StatementTag() string { return "ALTER BACKUP" }
 
 func (*AlterBackup) cclOnlyStatement() {}
 
+// StatementReturnType implements the Statement interface.
+func (*AlterBackupCollection) StatementReturnType() StatementReturnType { return Rows }
+
+// StatementType implements the Statement interface.
+func (*AlterBackupCollection) StatementType() StatementType { return TypeDML }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*AlterBackupCollection) StatementTag() string { return "ALTER BACKUP COLLECTION" }
+
+func (*AlterBackupCollection) cclOnlyStatement() {}
+
+// StatementReturnType implements the Statement interface.
+func (*AlterBackupSchedules) StatementReturnType() StatementReturnType { return Rows }
+
+// StatementType implements the Statement interface.
+func (*AlterBackupSchedules) StatementType() StatementType { return TypeDML }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*AlterBackupSchedules) StatementTag() string { return "ALTER BACKUP SCHEDULES" }
+
+func (*AlterBackupSchedules) cclOnlyStatement() {}
+
 // StatementReturnType implements the Statement interface.
 func (*AlterDatabaseOwner) StatementReturnType() StatementReturnType { return DDL }
 
@@ -344,6 +368,32 @@ func (*AlterDatabaseSetZoneConfigExtension) StatementTag() string {
 
 func (*AlterDatabaseSetZoneConfigExtension) hiddenFromShowQueries() {}
 
+// StatementReturnType implements the Statement interface.
+func (*AlterDatabaseSetStorageParams) StatementReturnType() StatementReturnType { return DDL }
+
+// StatementType implements the Statement interface.
+func (*AlterDatabaseSetStorageParams) StatementType() StatementType { return TypeDDL }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*AlterDatabaseSetStorageParams) StatementTag() string {
+	return "ALTER DATABASE SET STORAGE PARAMETERS"
+}
+
+func (*AlterDatabaseSetStorageParams) hiddenFromShowQueries() {}
+
+// StatementReturnType implements the Statement interface.
+func (*AlterDatabaseResetStorageParams) StatementReturnType() StatementReturnType { return DDL }
+
+// StatementType implements the Statement interface.
+func (*AlterDatabaseResetStorageParams) StatementType() StatementType { return TypeDDL }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*AlterDatabaseResetStorageParams) StatementTag() string {
+	return "ALTER DATABASE RESET STORAGE PARAMETERS"
+}
+
+func (*AlterDatabaseResetStorageParams) hiddenFromShowQueries() {}
+
 // StatementReturnType implements the Statement interface.
 func (*AlterDefaultPrivileges) StatementReturnType() StatementReturnType { return DDL }
 
@@ -497,7 +547,12 @@ func (*Backup) StatementReturnType() StatementReturnType { return Rows }
 func (*Backup) StatementType() StatementType { return TypeDML }
 
 // StatementTag returns a short string identifying the type of statement.
-func (*Backup) StatementTag() string { return "BACKUP" }
+func (n *Backup) StatementTag() string {
+	if n.ExplainOnly {
+		return "EXPLAIN BACKUP"
+	}
+	return "BACKUP"
+}
 
 func (*Backup) cclOnlyStatement() {}
 
@@ -1195,7 +1250,15 @@ func (*Restore) StatementReturnType() StatementReturnType { return Rows }
 func (*Restore) StatementType() StatementType { return TypeDML }
 
 // StatementTag returns a short string identifying the type of statement.
-func (*Restore) StatementTag() string { return "RESTORE" }
+func (n *Restore) StatementTag() string {
+	if n.PrepareOnly {
+		return "PREPARE RESTORE"
+	}
+	if n.ExplainOnly {
+		return "EXPLAIN RESTORE"
+	}
+	return "RESTORE"
+}
 
 func (*Restore) cclOnlyStatement() {}
 
@@ -1992,8 +2055,10 @@ func (*AlterFunctionDepExtension) StatementTag() string { return "ALTER FUNCTION
 func (n *AlterChangefeed) String() string                     { return AsString(n) }
 func (n *AlterChangefeedCmds) String() string                 { return AsString(n) }
 func (n *AlterBackup) String() string                         { return AsString(n) }
+func (n *AlterBackupCollection) String() string               { return AsString(n) }
 func (n *AlterBackupSchedule) String() string                 { return AsString(n) }
 func (n *AlterBackupScheduleCmds) String() string             { return AsString(n) }
+func (n *AlterBackupSchedules) String() string                { return AsString(n) }
 func (n *AlterIndex) String() string                          { return AsString(n) }
 func (n *AlterIndexVisible) String() string                   { return AsString(n) }
 func (n *AlterDatabaseOwner) String() string                  { return AsString(n) }
@@ -2008,6 +2073,8 @@ func (n *AlterDatabaseAlterSuperRegion) String() string       { return AsString(
 func (n *AlterDatabaseSecondaryRegion) String() string        { return AsString(n) }
 func (n *AlterDatabaseDropSecondaryRegion) String() string    { return AsString(n) }
 func (n *AlterDatabaseSetZoneConfigExtension) String() string { return AsString(n) }
+func (n *AlterDatabaseSetStorageParams) String() string       { return AsString(n) }
+func (n *AlterDatabaseResetStorageParams) String() string     { return AsString(n) }
 func (n *AlterDefaultPrivileges) String() string              { return AsString(n) }
 func (n *AlterFunctionOptions) String() string                { return AsString(n) }
 func (n *AlterFunctionRename) String() string                 { return AsString(n) }
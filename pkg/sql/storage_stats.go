@@ -0,0 +1,215 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvclient"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil/singleflight"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// StorageStatsRefreshInterval is how often the StorageStatsCache collects the
+// MVCC statistics of all ranges in the background.
+var StorageStatsRefreshInterval = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"sql.storage_stats.refresh_interval",
+	"how often the MVCC statistics of all ranges are collected for crdb_internal.database_storage_stats",
+	time.Minute,
+	settings.PositiveDuration,
+).WithPublic()
+
+// StorageStatsMaxStaleness bounds the age of the statistics returned by
+// crdb_internal.database_storage_stats.
+var StorageStatsMaxStaleness = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"sql.storage_stats.max_staleness",
+	"the maximum age of the statistics returned by crdb_internal.database_storage_stats; "+
+		"older statistics are collected anew before being returned",
+	5*time.Minute,
+	settings.PositiveDuration,
+).WithPublic()
+
+// storageStatsIdleRefreshes is the number of refresh intervals without a read
+// of the cache after which the background refreshes stop, until the next read.
+const storageStatsIdleRefreshes = 10
+
+// storageStatsBatchSize is the number of RangeStats requests sent in a batch.
+const storageStatsBatchSize = 100
+
+// StorageStats are the aggregated MVCC statistics of the ranges of a table.
+type StorageStats struct {
+	RangeCount int64
+	enginepb.MVCCStats
+}
+
+// StorageStatsSnapshot are the storage statistics of every table, as collected
+// at AsOf.
+type StorageStatsSnapshot struct {
+	AsOf   time.Time
+	Tables map[descpb.ID]StorageStats
+}
+
+// StorageStatsCache maintains the MVCC statistics of the ranges of every
+// table, so that crdb_internal.database_storage_stats can serve them without
+// sending a RangeStats request to every range each time it is read.
+//
+// The statistics are collected in the background every
+// sql.storage_stats.refresh_interval, while the cache is being read. A read
+// never returns statistics older than sql.storage_stats.max_staleness: older
+// ones, e.g. on the first read of the cache, are collected anew first.
+//
+// A range is attributed to the table its first key belongs to, so the
+// statistics of a range holding the end of a table and the start of the next
+// ones, e.g. after their ranges were merged, are all counted for the former.
+type StorageStatsCache struct {
+	db       *kv.DB
+	codec    keys.SQLCodec
+	settings *cluster.Settings
+	fetcher  eval.RangeStatsFetcher
+	group    singleflight.Group
+
+	mu struct {
+		syncutil.Mutex
+		snapshot *StorageStatsSnapshot
+		// lastRead is when the cache was last read.
+		lastRead time.Time
+	}
+}
+
+// NewStorageStatsCache constructs a new StorageStatsCache.
+func NewStorageStatsCache(
+	db *kv.DB, codec keys.SQLCodec, settings *cluster.Settings, fetcher eval.RangeStatsFetcher,
+) *StorageStatsCache {
+	return &StorageStatsCache{db: db, codec: codec, settings: settings, fetcher: fetcher}
+}
+
+// Start starts the background refreshes of the cache.
+func (c *StorageStatsCache) Start(ctx context.Context, stopper *stop.Stopper) {
+	_ = stopper.RunAsyncTask(ctx, "storage-stats-cache", func(ctx context.Context) {
+		ctx, cancel := stopper.WithCancelOnQuiesce(ctx)
+		defer cancel()
+		for {
+			interval := StorageStatsRefreshInterval.Get(&c.settings.SV)
+			select {
+			case <-time.After(interval):
+			case <-stopper.ShouldQuiesce():
+				return
+			}
+			c.mu.Lock()
+			idle := timeutil.Since(c.mu.lastRead) > storageStatsIdleRefreshes*interval
+			c.mu.Unlock()
+			if idle {
+				continue
+			}
+			if _, err := c.refresh(ctx); err != nil {
+				log.Warningf(ctx, "failed to collect storage statistics: %v", err)
+			}
+		}
+	})
+}
+
+// Get returns the storage statistics of every table, collecting them first if
+// the cached ones are older than sql.storage_stats.max_staleness.
+func (c *StorageStatsCache) Get(ctx context.Context) (*StorageStatsSnapshot, error) {
+	c.mu.Lock()
+	c.mu.lastRead = timeutil.Now()
+	snapshot := c.mu.snapshot
+	c.mu.Unlock()
+	if snapshot != nil &&
+		timeutil.Since(snapshot.AsOf) <= StorageStatsMaxStaleness.Get(&c.settings.SV) {
+		return snapshot, nil
+	}
+	return c.refresh(ctx)
+}
+
+// refresh collects the storage statistics of every table. Concurrent calls
+// share a single collection.
+func (c *StorageStatsCache) refresh(ctx context.Context) (*StorageStatsSnapshot, error) {
+	res, _, err := c.group.Do("refresh", func() (interface{}, error) {
+		snapshot, err := c.collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.mu.snapshot = snapshot
+		return snapshot, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*StorageStatsSnapshot), nil
+}
+
+func (c *StorageStatsCache) collect(ctx context.Context) (*StorageStatsSnapshot, error) {
+	asOf := timeutil.Now()
+	span := roachpb.Span{
+		Key:    c.codec.TablePrefix(0),
+		EndKey: c.codec.TablePrefix(math.MaxUint32).PrefixEnd(),
+	}
+	kvs, err := kvclient.ScanMetaKVs(ctx, c.db.NewTxn(ctx, "storage-stats-ranges"), span)
+	if err != nil {
+		return nil, errors.Wrap(err, "scanning range descriptors")
+	}
+
+	startKeys := make([]roachpb.Key, 0, len(kvs))
+	for _, metaKV := range kvs {
+		var desc roachpb.RangeDescriptor
+		if err := metaKV.ValueProto(&desc); err != nil {
+			return nil, err
+		}
+		startKey := desc.StartKey.AsRawKey()
+		if startKey.Compare(span.Key) < 0 {
+			startKey = span.Key
+		}
+		startKeys = append(startKeys, startKey)
+	}
+
+	snapshot := &StorageStatsSnapshot{AsOf: asOf, Tables: make(map[descpb.ID]StorageStats)}
+	for len(startKeys) > 0 {
+		batch := startKeys
+		if len(batch) > storageStatsBatchSize {
+			batch = batch[:storageStatsBatchSize]
+		}
+		startKeys = startKeys[len(batch):]
+		resps, err := c.fetcher.RangeStats(ctx, batch...)
+		if err != nil {
+			return nil, errors.Wrap(err, "fetching range statistics")
+		}
+		for i, resp := range resps {
+			_, tableID, err := c.codec.DecodeTablePrefix(batch[i])
+			if err != nil {
+				return nil, err
+			}
+			stats := snapshot.Tables[descpb.ID(tableID)]
+			stats.RangeCount++
+			stats.Add(resp.MVCCStats)
+			snapshot.Tables[descpb.ID(tableID)] = stats
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseStorageStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(ctx)
+
+	sqlDB := sqlutils.MakeSQLRunner(db)
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.t (x INT PRIMARY KEY)`)
+	sqlDB.Exec(t, `ALTER TABLE d.t SPLIT AT VALUES (10), (20), (30)`)
+	sqlDB.Exec(t, `INSERT INTO d.t SELECT generate_series(1, 40)`)
+
+	const query = `
+SELECT range_count, live_count, as_of
+  FROM "".crdb_internal.database_storage_stats
+ WHERE database_name = 'd' AND table_name = 't'`
+	// The table is split from its neighbours asynchronously, so the statistics
+	// are collected anew on every read until then.
+	sqlDB.Exec(t, `SET CLUSTER SETTING sql.storage_stats.max_staleness = '1ms'`)
+	var rangeCount, liveCount int
+	var asOf time.Time
+	testutils.SucceedsSoon(t, func() error {
+		sqlDB.QueryRow(t, query).Scan(&rangeCount, &liveCount, &asOf)
+		if rangeCount != 4 || liveCount != 40 {
+			return errors.Newf("expected 4 ranges and 40 rows, got %d and %d", rangeCount, liveCount)
+		}
+		return nil
+	})
+
+	// Statistics younger than the maximum staleness are served from the cache.
+	sqlDB.Exec(t, `SET CLUSTER SETTING sql.storage_stats.max_staleness = '1h'`)
+	sqlDB.QueryRow(t, query).Scan(&rangeCount, &liveCount, &asOf)
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (41)`)
+	var cachedLiveCount int
+	var cachedAsOf time.Time
+	sqlDB.QueryRow(t, query).Scan(&rangeCount, &cachedLiveCount, &cachedAsOf)
+	require.Equal(t, liveCount, cachedLiveCount)
+	require.Equal(t, asOf, cachedAsOf)
+
+	// Older ones are collected anew.
+	sqlDB.Exec(t, `SET CLUSTER SETTING sql.storage_stats.max_staleness = '1ms'`)
+	time.Sleep(time.Millisecond)
+	sqlDB.QueryRow(t, query).Scan(&rangeCount, &liveCount, &asOf)
+	require.Equal(t, 41, liveCount)
+	require.True(t, asOf.After(cachedAsOf))
+}
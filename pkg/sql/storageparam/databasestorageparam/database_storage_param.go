@@ -0,0 +1,87 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// Package databasestorageparam implements storageparam.Setter for
+// dbdesc.Mutable.
+package databasestorageparam
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/paramparse"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/storageparam"
+)
+
+// Setter observes storage parameters for databases.
+type Setter struct {
+	DatabaseDesc *dbdesc.Mutable
+}
+
+var _ storageparam.Setter = (*Setter)(nil)
+
+// Set implements the Setter interface.
+func (po *Setter) Set(
+	ctx context.Context,
+	semaCtx *tree.SemaContext,
+	evalCtx *eval.Context,
+	key string,
+	datum tree.Datum,
+) error {
+	switch key {
+	case `backup_protection`:
+		// Protection is checked against the backup checkpoints of tables, which
+		// are only recorded once every node has their system table.
+		if !evalCtx.Settings.Version.IsActive(ctx, clusterversion.V23_1TableBackupCheckpointsTable) {
+			return pgerror.Newf(pgcode.FeatureNotSupported,
+				"%s is not supported until the cluster is fully upgraded", key)
+		}
+		protect, err := boolFromDatum(ctx, evalCtx, key, datum)
+		if err != nil {
+			return err
+		}
+		po.DatabaseDesc.BackupProtection = protect
+		return nil
+	}
+	return pgerror.Newf(pgcode.InvalidParameterValue, "invalid storage parameter %q", key)
+}
+
+// Reset implements the Setter interface.
+func (po *Setter) Reset(ctx context.Context, evalCtx *eval.Context, key string) error {
+	switch key {
+	case `backup_protection`:
+		po.DatabaseDesc.BackupProtection = false
+		return nil
+	}
+	return pgerror.Newf(pgcode.InvalidParameterValue, "invalid storage parameter %q", key)
+}
+
+// RunPostChecks implements the Setter interface.
+func (po *Setter) RunPostChecks() error {
+	return nil
+}
+
+func boolFromDatum(
+	ctx context.Context, evalCtx *eval.Context, key string, datum tree.Datum,
+) (bool, error) {
+	if stringVal, err := paramparse.DatumAsString(ctx, evalCtx, key, datum); err == nil {
+		return paramparse.ParseBoolVar(key, stringVal)
+	}
+	s, err := paramparse.GetSingleBool(key, datum)
+	if err != nil {
+		return false, err
+	}
+	return bool(*s), nil
+}
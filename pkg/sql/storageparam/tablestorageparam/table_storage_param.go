@@ -16,6 +16,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
@@ -455,6 +456,31 @@ var tableParams = map[string]tableParam{
 			return nil
 		},
 	},
+	`backup_protection`: {
+		onSet: func(ctx context.Context, po *Setter, semaCtx *tree.SemaContext,
+			evalCtx *eval.Context, key string, datum tree.Datum) error {
+			// Protection is checked against the backup checkpoints of tables, which
+			// are only recorded once every node has their system table.
+			if !evalCtx.Settings.Version.IsActive(ctx, clusterversion.V23_1TableBackupCheckpointsTable) {
+				return pgerror.Newf(pgcode.FeatureNotSupported,
+					"%s is not supported until the cluster is fully upgraded", key)
+			}
+			if po.TableDesc.Temporary {
+				return pgerror.Newf(pgcode.FeatureNotSupported,
+					"cannot set %s on a temporary table, which is never backed up", key)
+			}
+			protect, err := boolFromDatum(ctx, evalCtx, key, datum)
+			if err != nil {
+				return err
+			}
+			po.TableDesc.BackupProtection = protect
+			return nil
+		},
+		onReset: func(_ context.Context, po *Setter, evalCtx *eval.Context, key string) error {
+			po.TableDesc.BackupProtection = false
+			return nil
+		},
+	},
 	catpb.AutoStatsEnabledTableSettingName: {
 		onSet:   autoStatsEnabledSettingFunc,
 		onReset: autoStatsTableSettingResetFunc,
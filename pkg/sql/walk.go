@@ -346,6 +346,7 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&alterDatabaseSecondaryRegion{}):            "alter database secondary region",
 	reflect.TypeOf(&alterDatabaseDropSecondaryRegion{}):        "alter database secondary region",
 	reflect.TypeOf(&alterDatabaseSetZoneConfigExtensionNode{}): "alter database configure zone extension",
+	reflect.TypeOf(&alterDatabaseStorageParamsNode{}):          "alter database storage parameters",
 	reflect.TypeOf(&alterDefaultPrivilegesNode{}):              "alter default privileges",
 	reflect.TypeOf(&alterFunctionOptionsNode{}):                "alter function",
 	reflect.TypeOf(&alterFunctionRenameNode{}):                 "alter function rename",
@@ -89,6 +89,119 @@ func (t TxnMeta) Short() redact.SafeString {
 	return redact.SafeString(t.ID.Short())
 }
 
+// MVCCStatsFields is a set of the fields of MVCCStats, which identifies the
+// fields that contain estimates (see MVCCStats.Estimates). The bit of a field
+// is persisted as part of the stats, so it must never change.
+type MVCCStatsFields uint64
+
+// The fields of MVCCStats, apart from ContainsEstimates and LastUpdateNanos.
+const (
+	MVCCStatsIntentAge MVCCStatsFields = 1 << iota
+	MVCCStatsGCBytesAge
+	MVCCStatsLiveBytes
+	MVCCStatsLiveCount
+	MVCCStatsKeyBytes
+	MVCCStatsKeyCount
+	MVCCStatsValBytes
+	MVCCStatsValCount
+	MVCCStatsIntentBytes
+	MVCCStatsIntentCount
+	MVCCStatsSeparatedIntentCount
+	MVCCStatsRangeKeyCount
+	MVCCStatsRangeKeyBytes
+	MVCCStatsRangeValCount
+	MVCCStatsRangeValBytes
+	MVCCStatsSysBytes
+	MVCCStatsSysCount
+	MVCCStatsAbortSpanBytes
+
+	// AllMVCCStatsFields is the set of all the fields of MVCCStats.
+	AllMVCCStatsFields = MVCCStatsAbortSpanBytes<<1 - 1
+)
+
+// gcBytesFields are the fields GCBytes is derived from, and with it
+// GCBytesAge.
+const gcBytesFields = MVCCStatsKeyBytes | MVCCStatsValBytes | MVCCStatsRangeKeyBytes |
+	MVCCStatsRangeValBytes | MVCCStatsLiveBytes
+
+// SafeValue implements the redact.SafeValue interface.
+func (MVCCStatsFields) SafeValue() {}
+
+// Estimates returns the fields of ms which may contain estimates. Since the
+// ages accrue from other fields, they're included whenever the fields they
+// accrue from are.
+func (ms MVCCStats) Estimates() MVCCStatsFields {
+	fields := ms.estimatedFields()
+	if fields&gcBytesFields != 0 {
+		fields |= MVCCStatsGCBytesAge
+	}
+	if fields&MVCCStatsIntentCount != 0 {
+		fields |= MVCCStatsIntentAge
+	}
+	return fields
+}
+
+// estimatedFields is like Estimates, but doesn't add the ages.
+func (ms MVCCStats) estimatedFields() MVCCStatsFields {
+	if ms.ContainsEstimates == 0 {
+		return 0
+	}
+	if fields := ms.EstimatedFields & AllMVCCStatsFields; fields != 0 {
+		return fields
+	}
+	return AllMVCCStatsFields
+}
+
+// HasEstimates returns whether any of fields may contain estimates.
+func (ms MVCCStats) HasEstimates(fields MVCCStatsFields) bool {
+	return ms.Estimates()&fields != 0
+}
+
+// NonZeroFields returns the fields of ms which aren't zero.
+func (ms MVCCStats) NonZeroFields() MVCCStatsFields {
+	var fields MVCCStatsFields
+	for _, f := range [...]struct {
+		field MVCCStatsFields
+		v     int64
+	}{
+		{MVCCStatsIntentAge, ms.IntentAge},
+		{MVCCStatsGCBytesAge, ms.GCBytesAge},
+		{MVCCStatsLiveBytes, ms.LiveBytes},
+		{MVCCStatsLiveCount, ms.LiveCount},
+		{MVCCStatsKeyBytes, ms.KeyBytes},
+		{MVCCStatsKeyCount, ms.KeyCount},
+		{MVCCStatsValBytes, ms.ValBytes},
+		{MVCCStatsValCount, ms.ValCount},
+		{MVCCStatsIntentBytes, ms.IntentBytes},
+		{MVCCStatsIntentCount, ms.IntentCount},
+		{MVCCStatsSeparatedIntentCount, ms.SeparatedIntentCount},
+		{MVCCStatsRangeKeyCount, ms.RangeKeyCount},
+		{MVCCStatsRangeKeyBytes, ms.RangeKeyBytes},
+		{MVCCStatsRangeValCount, ms.RangeValCount},
+		{MVCCStatsRangeValBytes, ms.RangeValBytes},
+		{MVCCStatsSysBytes, ms.SysBytes},
+		{MVCCStatsSysCount, ms.SysCount},
+		{MVCCStatsAbortSpanBytes, ms.AbortSpanBytes},
+	} {
+		if f.v != 0 {
+			fields |= f.field
+		}
+	}
+	return fields
+}
+
+// setEstimates records fields as the estimated fields of ms, unless ms
+// contains no estimates. Estimates in all the fields are recorded as none, like
+// stats written before EstimatedFields existed, so that such stats encode the
+// same way regardless of whether the cluster records estimated fields yet.
+func (ms *MVCCStats) setEstimates(fields MVCCStatsFields) {
+	if ms.ContainsEstimates == 0 || fields&AllMVCCStatsFields == AllMVCCStatsFields {
+		ms.EstimatedFields = 0
+		return
+	}
+	ms.EstimatedFields = fields
+}
+
 // Total returns the range size as the sum of the key and value
 // bytes. This includes all non-live keys and all versioned values,
 // both for point and range keys.
@@ -105,10 +218,11 @@ func (ms MVCCStats) GCBytes() int64 {
 
 // HasNoUserData returns true if there is no user data in the range.
 // User data includes RangeKeyCount, KeyCount and IntentCount as those keys
-// are user writable. ContainsEstimates must also be zero to avoid false
+// are user writable. These must also not be estimates to avoid false
 // positives where range actually has data.
 func (ms MVCCStats) HasNoUserData() bool {
-	return ms.ContainsEstimates == 0 && ms.RangeKeyCount == 0 && ms.KeyCount == 0 && ms.IntentCount == 0
+	return !ms.HasEstimates(MVCCStatsRangeKeyCount|MVCCStatsKeyCount|MVCCStatsIntentCount) &&
+		ms.RangeKeyCount == 0 && ms.KeyCount == 0 && ms.IntentCount == 0
 }
 
 // AvgIntentAge returns the average age of outstanding intents,
@@ -147,14 +261,54 @@ func (ms *MVCCStats) Forward(nowNanos int64) {
 // the max of their LastUpdateNanos is a prerequisite, though Add() takes
 // care of this internally.
 func (ms *MVCCStats) AgeTo(nowNanos int64) {
+	if nowNanos == ms.LastUpdateNanos {
+		return
+	}
 	// Seconds are counted every time each individual nanosecond timestamp
 	// crosses a whole second boundary (i.e. is zero mod 1E9). Thus it would
 	// be a mistake to use the (nonequivalent) expression (a-b)/1E9.
-	diffSeconds := nowNanos/1e9 - ms.LastUpdateNanos/1e9
+	ms.ageSeconds(nowNanos/1e9 - ms.LastUpdateNanos/1e9)
+	ms.LastUpdateNanos = nowNanos
+}
 
-	ms.GCBytesAge += ms.GCBytes() * diffSeconds
+// ageSeconds accrues diffSeconds worth of age.
+func (ms *MVCCStats) ageSeconds(diffSeconds int64) {
+	ms.GCBytesAge += (ms.KeyBytes + ms.ValBytes + ms.RangeKeyBytes + ms.RangeValBytes -
+		ms.LiveBytes) * diffSeconds
 	ms.IntentAge += ms.IntentCount * diffSeconds
-	ms.LastUpdateNanos = nowNanos
+}
+
+// forwardBoth moves the ages of ms and oms forward to the larger of their
+// LastUpdateNanos. It is equivalent to ms.Forward(oms.LastUpdateNanos)
+// followed by oms.Forward(ms.LastUpdateNanos), but divides each timestamp
+// only once, and does nothing in the common case of equal timestamps, such as
+// when a command's stats delta is applied to its range's stats.
+func (ms *MVCCStats) forwardBoth(oms *MVCCStats) {
+	if ms.LastUpdateNanos == oms.LastUpdateNanos {
+		return
+	}
+	msSeconds, omsSeconds := ms.LastUpdateNanos/1e9, oms.LastUpdateNanos/1e9
+	if ms.LastUpdateNanos < oms.LastUpdateNanos {
+		ms.ageSeconds(omsSeconds - msSeconds)
+		ms.LastUpdateNanos = oms.LastUpdateNanos
+	} else {
+		oms.ageSeconds(msSeconds - omsSeconds)
+		oms.LastUpdateNanos = ms.LastUpdateNanos
+	}
+}
+
+// combineEstimates records the estimates of ms combined with those of oms,
+// with containsEstimates as the new ContainsEstimates of ms.
+func (ms *MVCCStats) combineEstimates(oms *MVCCStats, containsEstimates int64) {
+	if ms.ContainsEstimates|oms.ContainsEstimates == 0 {
+		// Neither contains estimates, and so neither records estimated fields.
+		ms.ContainsEstimates = containsEstimates
+		ms.EstimatedFields = 0
+		return
+	}
+	estimates := ms.estimatedFields() | oms.estimatedFields()
+	ms.ContainsEstimates = containsEstimates
+	ms.setEstimates(estimates)
 }
 
 // Add adds values from oms to ms. The ages will be moved forward to the
@@ -162,10 +316,8 @@ func (ms *MVCCStats) AgeTo(nowNanos int64) {
 func (ms *MVCCStats) Add(oms MVCCStats) {
 	// Enforce the max LastUpdateNanos for both ages based on their
 	// pre-addition state.
-	ms.Forward(oms.LastUpdateNanos)
-	oms.Forward(ms.LastUpdateNanos) // on local copy
-
-	ms.ContainsEstimates += oms.ContainsEstimates
+	ms.forwardBoth(&oms) // oms is a local copy
+	ms.combineEstimates(&oms, ms.ContainsEstimates+oms.ContainsEstimates)
 
 	// Now that we've done that, we may just add them.
 	ms.IntentAge += oms.IntentAge
@@ -193,10 +345,8 @@ func (ms *MVCCStats) Add(oms MVCCStats) {
 func (ms *MVCCStats) Subtract(oms MVCCStats) {
 	// Enforce the max LastUpdateNanos for both ages based on their
 	// pre-subtraction state.
-	ms.Forward(oms.LastUpdateNanos)
-	oms.Forward(ms.LastUpdateNanos)
-
-	ms.ContainsEstimates -= oms.ContainsEstimates
+	ms.forwardBoth(&oms)
+	ms.combineEstimates(&oms, ms.ContainsEstimates-oms.ContainsEstimates)
 
 	// Now that we've done that, we may subtract.
 	ms.IntentAge -= oms.IntentAge
@@ -219,6 +369,55 @@ func (ms *MVCCStats) Subtract(oms MVCCStats) {
 	ms.AbortSpanBytes -= oms.AbortSpanBytes
 }
 
+// systemFields are the fields of MVCCStats which only account for the
+// range-local and range-ID-local keys of a range.
+const systemFields = MVCCStatsSysBytes | MVCCStatsSysCount | MVCCStatsAbortSpanBytes
+
+// EstimateSplit estimates the stats of the two parts of a span with stats ms
+// when it is split such that leftFraction of its user data ends up in the left
+// part, without looking at the data. Every quantity of the user data is
+// apportioned by leftFraction, so that the two estimates add up to it exactly,
+// and both have ContainsEstimates set. Only the fields which are non-zero in
+// ms, or which already contain estimates, are estimates in either part.
+//
+// The system fields (SysBytes, SysCount and AbortSpanBytes) are left zero in
+// both parts: the range-local and range-ID-local keys they account for don't
+// take up the user keyspace the fraction is derived from, and are few enough
+// for the caller to compute them exactly.
+func (ms MVCCStats) EstimateSplit(leftFraction float64) (left, right MVCCStats) {
+	leftFraction = math.Max(0, math.Min(1, leftFraction))
+	apportion := func(v int64) int64 {
+		return int64(math.Round(float64(v) * leftFraction))
+	}
+	left = MVCCStats{
+		LastUpdateNanos:      ms.LastUpdateNanos,
+		IntentAge:            apportion(ms.IntentAge),
+		GCBytesAge:           apportion(ms.GCBytesAge),
+		LiveBytes:            apportion(ms.LiveBytes),
+		LiveCount:            apportion(ms.LiveCount),
+		KeyBytes:             apportion(ms.KeyBytes),
+		KeyCount:             apportion(ms.KeyCount),
+		ValBytes:             apportion(ms.ValBytes),
+		ValCount:             apportion(ms.ValCount),
+		IntentBytes:          apportion(ms.IntentBytes),
+		IntentCount:          apportion(ms.IntentCount),
+		SeparatedIntentCount: apportion(ms.SeparatedIntentCount),
+		RangeKeyCount:        apportion(ms.RangeKeyCount),
+		RangeKeyBytes:        apportion(ms.RangeKeyBytes),
+		RangeValCount:        apportion(ms.RangeValCount),
+		RangeValBytes:        apportion(ms.RangeValBytes),
+	}
+	right = ms
+	right.ContainsEstimates, right.EstimatedFields = 0, 0
+	right.SysBytes, right.SysCount, right.AbortSpanBytes = 0, 0, 0
+	right.Subtract(left)
+	if estimates := (ms.estimatedFields() | ms.NonZeroFields()) &^ systemFields; estimates != 0 {
+		left.ContainsEstimates, right.ContainsEstimates = 1, 1
+		left.EstimatedFields, right.EstimatedFields = estimates, estimates
+	}
+	return left, right
+}
+
 // IsInline returns true if the value is inlined in the metadata.
 func (meta MVCCMetadata) IsInline() bool {
 	return meta.RawBytes != nil
@@ -0,0 +1,241 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package enginepb
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/randutil"
+	"github.com/stretchr/testify/require"
+)
+
+// The reference implementations below are the straightforward forms of the
+// arithmetic of MVCCStats, against which the optimized ones are checked and
+// benchmarked.
+
+func referenceAgeTo(ms *MVCCStats, nowNanos int64) {
+	diffSeconds := nowNanos/1e9 - ms.LastUpdateNanos/1e9
+	ms.GCBytesAge += ms.GCBytes() * diffSeconds
+	ms.IntentAge += ms.IntentCount * diffSeconds
+	ms.LastUpdateNanos = nowNanos
+}
+
+func referenceForward(ms *MVCCStats, nowNanos int64) {
+	if ms.LastUpdateNanos >= nowNanos {
+		return
+	}
+	referenceAgeTo(ms, nowNanos)
+}
+
+func referenceAdd(ms *MVCCStats, oms MVCCStats) {
+	referenceForward(ms, oms.LastUpdateNanos)
+	referenceForward(&oms, ms.LastUpdateNanos)
+
+	estimates := ms.estimatedFields() | oms.estimatedFields()
+	ms.ContainsEstimates += oms.ContainsEstimates
+	ms.setEstimates(estimates)
+
+	ms.IntentAge += oms.IntentAge
+	ms.GCBytesAge += oms.GCBytesAge
+	ms.LiveBytes += oms.LiveBytes
+	ms.KeyBytes += oms.KeyBytes
+	ms.ValBytes += oms.ValBytes
+	ms.IntentBytes += oms.IntentBytes
+	ms.LiveCount += oms.LiveCount
+	ms.KeyCount += oms.KeyCount
+	ms.ValCount += oms.ValCount
+	ms.IntentCount += oms.IntentCount
+	ms.SeparatedIntentCount += oms.SeparatedIntentCount
+	ms.RangeKeyCount += oms.RangeKeyCount
+	ms.RangeKeyBytes += oms.RangeKeyBytes
+	ms.RangeValCount += oms.RangeValCount
+	ms.RangeValBytes += oms.RangeValBytes
+	ms.SysBytes += oms.SysBytes
+	ms.SysCount += oms.SysCount
+	ms.AbortSpanBytes += oms.AbortSpanBytes
+}
+
+func referenceSubtract(ms *MVCCStats, oms MVCCStats) {
+	referenceForward(ms, oms.LastUpdateNanos)
+	referenceForward(&oms, ms.LastUpdateNanos)
+
+	estimates := ms.estimatedFields() | oms.estimatedFields()
+	ms.ContainsEstimates -= oms.ContainsEstimates
+	ms.setEstimates(estimates)
+
+	ms.IntentAge -= oms.IntentAge
+	ms.GCBytesAge -= oms.GCBytesAge
+	ms.LiveBytes -= oms.LiveBytes
+	ms.KeyBytes -= oms.KeyBytes
+	ms.ValBytes -= oms.ValBytes
+	ms.IntentBytes -= oms.IntentBytes
+	ms.LiveCount -= oms.LiveCount
+	ms.KeyCount -= oms.KeyCount
+	ms.ValCount -= oms.ValCount
+	ms.IntentCount -= oms.IntentCount
+	ms.SeparatedIntentCount -= oms.SeparatedIntentCount
+	ms.RangeKeyCount -= oms.RangeKeyCount
+	ms.RangeKeyBytes -= oms.RangeKeyBytes
+	ms.RangeValCount -= oms.RangeValCount
+	ms.RangeValBytes -= oms.RangeValBytes
+	ms.SysBytes -= oms.SysBytes
+	ms.SysCount -= oms.SysCount
+	ms.AbortSpanBytes -= oms.AbortSpanBytes
+}
+
+func randomMVCCStats(rng *rand.Rand, baseNanos int64) MVCCStats {
+	n := func() int64 { return rng.Int63n(1 << 20) }
+	ms := MVCCStats{
+		IntentAge:            n(),
+		GCBytesAge:           n(),
+		LiveBytes:            n(),
+		LiveCount:            n(),
+		KeyBytes:             n(),
+		KeyCount:             n(),
+		ValBytes:             n(),
+		ValCount:             n(),
+		IntentBytes:          n(),
+		IntentCount:          n(),
+		SeparatedIntentCount: n(),
+		RangeKeyCount:        n(),
+		RangeKeyBytes:        n(),
+		RangeValCount:        n(),
+		RangeValBytes:        n(),
+		SysBytes:             n(),
+		SysCount:             n(),
+		AbortSpanBytes:       n(),
+	}
+	// Timestamps are often equal, and otherwise a few seconds apart.
+	if rng.Intn(2) == 0 {
+		ms.LastUpdateNanos = baseNanos
+	} else {
+		ms.LastUpdateNanos = baseNanos + rng.Int63n(5e9)
+	}
+	if rng.Intn(3) == 0 {
+		ms.ContainsEstimates = rng.Int63n(3)
+		ms.EstimatedFields = MVCCStatsFields(rng.Int63n(int64(AllMVCCStatsFields) + 1))
+	}
+	return ms
+}
+
+func TestMVCCStatsArithmeticMatchesReference(t *testing.T) {
+	rng, _ := randutil.NewTestRand()
+	const baseNanos = 1e18
+	for i := 0; i < 10000; i++ {
+		ms, oms := randomMVCCStats(rng, baseNanos), randomMVCCStats(rng, baseNanos)
+
+		exp, act := ms, ms
+		referenceAdd(&exp, oms)
+		act.Add(oms)
+		require.Equal(t, exp, act, "%v.Add(%v)", ms, oms)
+
+		exp, act = ms, ms
+		referenceSubtract(&exp, oms)
+		act.Subtract(oms)
+		require.Equal(t, exp, act, "%v.Subtract(%v)", ms, oms)
+
+		now := oms.LastUpdateNanos
+		exp, act = ms, ms
+		referenceAgeTo(&exp, now)
+		act.AgeTo(now)
+		require.Equal(t, exp, act, "%v.AgeTo(%d)", ms, now)
+	}
+}
+
+// benchmarkMVCCStatsApply mimics the application of raft commands to a range:
+// the stats delta of each command is added to the stats of the range, with
+// timestamps that advance by a second every secondEvery commands.
+func benchmarkMVCCStatsApply(
+	b *testing.B, secondEvery int, add func(ms *MVCCStats, oms MVCCStats),
+) {
+	rng, _ := randutil.NewTestRand()
+	const baseNanos = 1e18
+	deltas := make([]MVCCStats, 1024)
+	for i := range deltas {
+		deltas[i] = MVCCStats{
+			LiveBytes: rng.Int63n(100),
+			LiveCount: 1,
+			KeyBytes:  rng.Int63n(20),
+			KeyCount:  1,
+			ValBytes:  rng.Int63n(80),
+			ValCount:  1,
+			LastUpdateNanos: baseNanos +
+				int64(i/secondEvery)*1e9 + rng.Int63n(1e9),
+		}
+	}
+	b.ResetTimer()
+	var ms MVCCStats
+	for i := 0; i < b.N; i++ {
+		j := i % len(deltas)
+		if j == 0 {
+			ms = MVCCStats{LastUpdateNanos: baseNanos}
+		}
+		// Commands evaluate their deltas at the time of the range's stats, as
+		// the ages of the range are brought up to date before each batch.
+		delta := deltas[j]
+		delta.LastUpdateNanos = ms.LastUpdateNanos
+		if j%secondEvery == 0 {
+			delta.LastUpdateNanos = deltas[j].LastUpdateNanos
+		}
+		add(&ms, delta)
+	}
+}
+
+func BenchmarkMVCCStatsApply(b *testing.B) {
+	for _, secondEvery := range []int{1, 100} {
+		b.Run(fmt.Sprintf("second-every=%d", secondEvery), func(b *testing.B) {
+			b.Run("reference", func(b *testing.B) {
+				benchmarkMVCCStatsApply(b, secondEvery, referenceAdd)
+			})
+			b.Run("optimized", func(b *testing.B) {
+				benchmarkMVCCStatsApply(b, secondEvery, (*MVCCStats).Add)
+			})
+		})
+	}
+}
+
+func BenchmarkMVCCStatsArithmetic(b *testing.B) {
+	rng, _ := randutil.NewTestRand()
+	const baseNanos = 1e18
+	ops := make([]MVCCStats, 1024)
+	for i := range ops {
+		ops[i] = randomMVCCStats(rng, baseNanos)
+	}
+	for _, op := range []struct {
+		name      string
+		reference func(ms *MVCCStats, oms MVCCStats)
+		optimized func(ms *MVCCStats, oms MVCCStats)
+	}{
+		{"add", referenceAdd, (*MVCCStats).Add},
+		{"subtract", referenceSubtract, (*MVCCStats).Subtract},
+		{"age-to", func(ms *MVCCStats, oms MVCCStats) { referenceAgeTo(ms, oms.LastUpdateNanos) },
+			func(ms *MVCCStats, oms MVCCStats) { ms.AgeTo(oms.LastUpdateNanos) }},
+	} {
+		b.Run(op.name, func(b *testing.B) {
+			for _, impl := range []struct {
+				name string
+				fn   func(ms *MVCCStats, oms MVCCStats)
+			}{
+				{"reference", op.reference},
+				{"optimized", op.optimized},
+			} {
+				b.Run(impl.name, func(b *testing.B) {
+					var ms MVCCStats
+					for i := 0; i < b.N; i++ {
+						impl.fn(&ms, ops[i%len(ops)])
+					}
+				})
+			}
+		})
+	}
+}
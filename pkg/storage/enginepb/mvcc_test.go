@@ -117,3 +117,112 @@ func TestFormatBytesAsKeyAndValue(t *testing.T) {
 	require.Equal(t, string(enginepb.FormatBytesAsValue(encodedIntVal)), "‹/INT/-8›")
 	require.Equal(t, string(enginepb.FormatBytesAsValue(encodedIntVal).Redact()), "‹×›")
 }
+
+func TestMVCCStatsEstimateSplit(t *testing.T) {
+	ms := enginepb.MVCCStats{
+		LastUpdateNanos: 100,
+		LiveBytes:       1001,
+		LiveCount:       11,
+		KeyBytes:        400,
+		KeyCount:        13,
+		ValBytes:        700,
+		ValCount:        15,
+		GCBytesAge:      99,
+		SysBytes:        50,
+		SysCount:        3,
+	}
+	// The system stats are left for the caller to compute.
+	userMS := ms
+	userMS.SysBytes, userMS.SysCount = 0, 0
+	for _, frac := range []float64{-1, 0, 0.25, 0.5, 0.9, 1, 2} {
+		left, right := ms.EstimateSplit(frac)
+		require.EqualValues(t, 1, left.ContainsEstimates)
+		require.EqualValues(t, 1, right.ContainsEstimates)
+		// Only the fields of the user data that are non-zero are apportioned.
+		require.Equal(t, userMS.NonZeroFields(), left.Estimates())
+		require.Equal(t, userMS.NonZeroFields(), right.Estimates())
+		require.Equal(t, ms.LastUpdateNanos, left.LastUpdateNanos)
+		require.Equal(t, ms.LastUpdateNanos, right.LastUpdateNanos)
+
+		// The estimates add up to the user data that was split.
+		sum := left
+		sum.Add(right)
+		sum.ContainsEstimates, sum.EstimatedFields = 0, 0
+		require.Equal(t, userMS, sum)
+	}
+
+	// Nothing is estimated if there is no user data.
+	left, right := enginepb.MVCCStats{SysBytes: 50, SysCount: 3}.EstimateSplit(0.5)
+	require.Equal(t, enginepb.MVCCStats{}, left)
+	require.Equal(t, enginepb.MVCCStats{}, right)
+
+	left, right = ms.EstimateSplit(0.25)
+	require.EqualValues(t, 250, left.LiveBytes)
+	require.EqualValues(t, 751, right.LiveBytes)
+	require.EqualValues(t, 3, left.KeyCount)
+	require.EqualValues(t, 10, right.KeyCount)
+
+	left, right = ms.EstimateSplit(0)
+	require.Zero(t, left.Total())
+	require.Equal(t, ms.Total(), right.Total())
+}
+
+func TestMVCCStatsEstimates(t *testing.T) {
+	exact := enginepb.MVCCStats{LiveBytes: 10, SysBytes: 5}
+	require.Zero(t, exact.Estimates())
+
+	// Stats which don't say which of their fields are estimated, such as those
+	// written before EstimatedFields existed, may have estimates in any of them.
+	legacy := enginepb.MVCCStats{ContainsEstimates: 1}
+	require.Equal(t, enginepb.AllMVCCStatsFields, legacy.Estimates())
+
+	// The ages are estimated along with the fields they accrue from.
+	sys := enginepb.MVCCStats{ContainsEstimates: 2, EstimatedFields: enginepb.MVCCStatsSysBytes}
+	require.Equal(t, enginepb.MVCCStatsSysBytes, sys.Estimates())
+	require.False(t, sys.HasEstimates(enginepb.MVCCStatsLiveCount))
+	live := enginepb.MVCCStats{ContainsEstimates: 2, EstimatedFields: enginepb.MVCCStatsLiveBytes}
+	require.Equal(t, enginepb.MVCCStatsLiveBytes|enginepb.MVCCStatsGCBytesAge, live.Estimates())
+	intents := enginepb.MVCCStats{ContainsEstimates: 2, EstimatedFields: enginepb.MVCCStatsIntentCount}
+	require.Equal(t, enginepb.MVCCStatsIntentCount|enginepb.MVCCStatsIntentAge, intents.Estimates())
+
+	// Adding stats takes the union of their estimates.
+	ms := exact
+	ms.Add(sys)
+	require.Equal(t, enginepb.MVCCStatsSysBytes, ms.Estimates())
+	ms.Add(live)
+	require.Equal(t, enginepb.MVCCStatsSysBytes|enginepb.MVCCStatsLiveBytes|enginepb.MVCCStatsGCBytesAge,
+		ms.Estimates())
+	ms.Add(legacy)
+	require.Equal(t, enginepb.AllMVCCStatsFields, ms.Estimates())
+
+	// Once the estimates are removed, e.g. by adding the delta from a
+	// recomputation, none of the fields are estimated anymore.
+	ms = exact
+	ms.Add(sys)
+	recomputed := exact
+	recomputed.Subtract(ms)
+	require.Equal(t, enginepb.MVCCStatsSysBytes, recomputed.Estimates())
+	ms.Add(recomputed)
+	require.Zero(t, ms.ContainsEstimates)
+	require.Zero(t, ms.EstimatedFields)
+	require.False(t, ms.HasEstimates(enginepb.AllMVCCStatsFields))
+}
+
+func TestMVCCStatsEstimatedInAllFields(t *testing.T) {
+	// Stats estimated in all their fields record none of them, like those
+	// written before EstimatedFields existed, whichever way they got there.
+	sys := enginepb.MVCCStats{ContainsEstimates: 2, EstimatedFields: enginepb.MVCCStatsSysBytes}
+	legacy := enginepb.MVCCStats{ContainsEstimates: 2}
+	ms := sys
+	ms.Add(legacy)
+	require.EqualValues(t, 4, ms.ContainsEstimates)
+	require.Zero(t, ms.EstimatedFields)
+	require.Equal(t, enginepb.AllMVCCStatsFields, ms.Estimates())
+
+	ms = sys
+	ms.Add(enginepb.MVCCStats{
+		ContainsEstimates: 2, EstimatedFields: enginepb.AllMVCCStatsFields &^ enginepb.MVCCStatsSysBytes,
+	})
+	require.Zero(t, ms.EstimatedFields)
+	require.Equal(t, enginepb.AllMVCCStatsFields, ms.Estimates())
+}
@@ -258,9 +258,11 @@ func updateStatsOnMerge(key roachpb.Key, valSize, nowNanos int64) enginepb.MVCCS
 
 	if sys {
 		ms.SysBytes += valSize
+		ms.EstimatedFields = enginepb.MVCCStatsSysBytes
 	} else {
 		ms.LiveBytes += valSize
 		ms.ValBytes += valSize
+		ms.EstimatedFields = enginepb.MVCCStatsLiveBytes | enginepb.MVCCStatsValBytes
 	}
 	return ms
 }
@@ -627,6 +629,7 @@ func updateStatsOnRangeKeyCoverStats(ts hlc.Timestamp, cur enginepb.MVCCStats) e
 	var ms enginepb.MVCCStats
 	ms.AgeTo(ts.WallTime)
 	ms.ContainsEstimates += cur.ContainsEstimates
+	ms.EstimatedFields = cur.EstimatedFields
 	ms.LiveCount -= cur.LiveCount
 	ms.LiveBytes -= cur.LiveBytes
 	return ms
@@ -5228,7 +5231,7 @@ func MVCCGarbageCollectWholeRange(
 	gcThreshold hlc.Timestamp,
 	rangeStats enginepb.MVCCStats,
 ) error {
-	if rangeStats.ContainsEstimates == 0 && rangeStats.LiveCount > 0 {
+	if !rangeStats.HasEstimates(enginepb.MVCCStatsLiveCount) && rangeStats.LiveCount > 0 {
 		return errors.Errorf("range contains live data, can't use GC clear range")
 	}
 	if _, err := CanGCEntireRange(ctx, rw, start, end, gcThreshold); err != nil {
@@ -5412,6 +5415,37 @@ func willOverflow(a, b int64) bool {
 	return math.MinInt64-b > a
 }
 
+// EstimateSplitStats estimates the MVCC stats of the left and right hand sides
+// of a split of the span [start, end) at splitKey, given ms, the stats of the
+// whole span. Instead of iterating over either side, which is expensive for
+// large spans, it apportions ms by the approximate on-disk size of either side,
+// which approxDiskBytes (typically Engine.ApproximateDiskBytes) derives from
+// the properties and index blocks of the sstables that overlap them. The
+// estimates add up to the user data of ms and have ContainsEstimates set. Their
+// system stats are left zero for the caller to compute, see
+// MVCCStats.EstimateSplit. ok is false if no data
+// of the span is in sstables yet, in which case nothing can be estimated and
+// the stats need to be computed.
+func EstimateSplitStats(
+	approxDiskBytes func(from, to roachpb.Key) (uint64, error),
+	ms enginepb.MVCCStats,
+	start, splitKey, end roachpb.Key,
+) (left, right enginepb.MVCCStats, ok bool, err error) {
+	leftBytes, err := approxDiskBytes(start, splitKey)
+	if err != nil {
+		return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, err
+	}
+	rightBytes, err := approxDiskBytes(splitKey, end)
+	if err != nil {
+		return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, err
+	}
+	if leftBytes+rightBytes == 0 {
+		return enginepb.MVCCStats{}, enginepb.MVCCStats{}, false, nil
+	}
+	left, right = ms.EstimateSplit(float64(leftBytes) / float64(leftBytes+rightBytes))
+	return left, right, true, nil
+}
+
 // ComputeStats scans the given key span and computes MVCC stats. nowNanos
 // specifies the wall time in nanoseconds since the epoch and is used to compute
 // age-related stats quantities.
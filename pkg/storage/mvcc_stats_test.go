@@ -1922,3 +1922,49 @@ func TestMVCCComputeStatsError(t *testing.T) {
 		})
 	}
 }
+
+func TestEstimateSplitStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	engine := NewDefaultInMemForTesting()
+	defer engine.Close()
+
+	var ms enginepb.MVCCStats
+	value := roachpb.MakeValueFromBytes(make([]byte, 1<<10))
+	for i := 0; i < 1000; i++ {
+		key := roachpb.Key(fmt.Sprintf("a%04d", i))
+		require.NoError(t, MVCCPut(ctx, engine, &ms, key, hlc.Timestamp{WallTime: 1},
+			hlc.ClockTimestamp{}, value, nil /* txn */))
+	}
+	start, splitKey, end := roachpb.Key("a"), roachpb.Key("a0500"), roachpb.Key("b")
+
+	// Nothing can be estimated while the data is only in the memtable.
+	_, _, ok, err := EstimateSplitStats(engine.ApproximateDiskBytes, ms, start, splitKey, end)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, engine.Flush())
+	left, right, ok, err := EstimateSplitStats(engine.ApproximateDiskBytes, ms, start, splitKey, end)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.EqualValues(t, 1, left.ContainsEstimates)
+	require.EqualValues(t, 1, right.ContainsEstimates)
+
+	// The estimates add up to the data that was split, which is all user data.
+	sum := left
+	sum.Add(right)
+	require.Equal(t, ms.Total(), sum.Total())
+	require.Equal(t, ms.LiveBytes, sum.LiveBytes)
+	require.Equal(t, ms.LiveCount, sum.LiveCount)
+	require.Zero(t, sum.SysBytes)
+
+	// The split key is in the middle of the data, and so should the estimates
+	// be, give or take the granularity of the sstable blocks.
+	computed, err := ComputeStats(engine, start, splitKey, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 500, computed.LiveCount)
+	require.InDelta(t, computed.LiveCount, left.LiveCount, 100)
+	require.InDelta(t, computed.LiveBytes, left.LiveBytes, float64(computed.LiveBytes)/5)
+}
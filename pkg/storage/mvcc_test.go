@@ -126,6 +126,7 @@ func TestMVCCStatsAddSubForward(t *testing.T) {
 		SysCount:             1,
 		LastUpdateNanos:      1,
 		AbortSpanBytes:       1,
+		EstimatedFields:      enginepb.MVCCStatsLiveBytes,
 	}
 	require.NoError(t, zerofields.NoZeroField(&goldMS))
 
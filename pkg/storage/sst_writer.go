@@ -74,6 +74,16 @@ func MakeIngestionWriterOptions(ctx context.Context, cs *cluster.Settings) sstab
 // MakeBackupSSTWriter creates a new SSTWriter tailored for backup SSTs which
 // are typically only ever iterated in their entirety.
 func MakeBackupSSTWriter(ctx context.Context, cs *cluster.Settings, f io.Writer) SSTWriter {
+	return MakeBackupSSTWriterWithCompression(ctx, cs, f, sstable.DefaultCompression)
+}
+
+// MakeBackupSSTWriterWithCompression is like MakeBackupSSTWriter, but the
+// blocks of the SST are compressed with compression, unless it is
+// sstable.DefaultCompression. Readers of the SST need not know the
+// compression, which is recorded in each block.
+func MakeBackupSSTWriterWithCompression(
+	ctx context.Context, cs *cluster.Settings, f io.Writer, compression sstable.Compression,
+) SSTWriter {
 	// By default, take a conservative approach and assume we don't have newer
 	// table features available. Upgrade to an appropriate version only if the
 	// cluster supports it.
@@ -90,6 +100,9 @@ func MakeBackupSSTWriter(ctx context.Context, cs *cluster.Settings, f io.Writer)
 	// block checksums and more index entries are just overhead and smaller blocks
 	// reduce compression ratio.
 	opts.BlockSize = 128 << 10
+	if compression != sstable.DefaultCompression {
+		opts.Compression = compression
+	}
 
 	opts.MergerName = "nullptr"
 	return SSTWriter{
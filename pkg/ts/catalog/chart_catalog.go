@@ -3092,6 +3092,7 @@ var charts = []sectionDescription{
 					"addsstable.applications",
 					"addsstable.proposals",
 					"addsstable.aswrites",
+					"addsstable.paced",
 				},
 			},
 			{
@@ -3099,6 +3100,7 @@ var charts = []sectionDescription{
 				Metrics: []string{
 					"addsstable.delay.total",
 					"addsstable.delay.enginebackpressure",
+					"addsstable.delay.adaptivepacing",
 				},
 			},
 		},
@@ -3251,6 +3253,13 @@ var charts = []sectionDescription{
 					"schedules.BACKUP.last-completed-time",
 				},
 			},
+			{
+				Title: "RPO",
+				Metrics: []string{
+					"jobs.backup.rpo_seconds",
+				},
+				AxisLabel: "Seconds",
+			},
 		},
 	},
 	{
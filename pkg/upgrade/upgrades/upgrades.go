@@ -164,6 +164,24 @@ var upgrades = []upgrade.Upgrade{
 		NoPrecondition,
 		fixInvalidObjectsThatLookLikeBadUserfileConstraint,
 	),
+	upgrade.NewTenantUpgrade(
+		"add the system.external_io_audit table",
+		toCV(clusterversion.V23_1ExternalIOAuditTable),
+		NoPrecondition,
+		systemExternalIOAuditTableMigration,
+	),
+	upgrade.NewTenantUpgrade(
+		"add the system.scheduled_backup_runs table",
+		toCV(clusterversion.V23_1ScheduledBackupRunsTable),
+		NoPrecondition,
+		systemScheduledBackupRunsTableMigration,
+	),
+	upgrade.NewTenantUpgrade(
+		"add the system.table_backup_checkpoints table",
+		toCV(clusterversion.V23_1TableBackupCheckpointsTable),
+		NoPrecondition,
+		systemTableBackupCheckpointsTableMigration,
+	),
 }
 
 func init() {
@@ -0,0 +1,79 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package admission
+
+import (
+	"context"
+	"time"
+)
+
+// Pacer is used in tight loops of elastic work that run outside of a KV
+// request, like the writing of backup files, to integrate them with the
+// elastic CPU work queue. The loop is admitted for a unit of on-CPU time at a
+// time, and seeks admission again once it has used up what it was granted. A
+// Pacer is bound to the goroutine running the loop, since the CPU time it
+// measures is that of the calling goroutine.
+type Pacer struct {
+	unit time.Duration
+	wi   WorkInfo
+	wq   *ElasticCPUWorkQueue
+
+	cur *ElasticCPUWorkHandle
+}
+
+// Pace is called in every iteration of the paced loop. It blocks until the
+// loop is admitted if it has used up the CPU time it was last granted. A nil
+// Pacer is a no-op.
+func (p *Pacer) Pace(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	if overLimit, _ := p.cur.OverLimit(); overLimit {
+		p.wq.AdmittedWorkDone(p.cur)
+		p.cur = nil
+	}
+	if p.cur == nil {
+		handle, err := p.wq.Admit(ctx, p.unit, p.wi)
+		if err != nil {
+			return err
+		}
+		p.cur = handle
+	}
+	return nil
+}
+
+// Close returns the CPU time that the loop was granted but did not use.
+func (p *Pacer) Close() {
+	if p == nil || p.cur == nil {
+		return
+	}
+	p.wq.AdmittedWorkDone(p.cur)
+	p.cur = nil
+}
+
+// PacerFactory is used to construct Pacers.
+type PacerFactory interface {
+	NewPacer(unit time.Duration, wi WorkInfo) *Pacer
+}
+
+var _ PacerFactory = &ElasticCPUGrantCoordinator{}
+
+// NewPacer implements the PacerFactory interface.
+func (e *ElasticCPUGrantCoordinator) NewPacer(unit time.Duration, wi WorkInfo) *Pacer {
+	if e == nil {
+		return nil
+	}
+	return &Pacer{
+		unit: unit,
+		wi:   wi,
+		wq:   e.ElasticCPUWorkQueue,
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package admission
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacer(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	granter := &testElasticCPUGranter{}
+	workQueue := &testElasticCPUInternalWorkQueue{}
+	wq := makeElasticCPUWorkQueue(
+		cluster.MakeTestingClusterSettings(), workQueue, granter, makeElasticCPUGranterMetrics(),
+	)
+	wq.testingEnabled = true
+	coord := &ElasticCPUGrantCoordinator{ElasticCPUWorkQueue: wq}
+
+	p := coord.NewPacer(50*time.Millisecond, WorkInfo{})
+	require.NoError(t, p.Pace(ctx))
+	require.Equal(t, "admitted=50ms", strings.TrimSpace(workQueue.buf.String()))
+
+	// The loop is not admitted again while it has CPU time left.
+	workQueue.buf.Reset()
+	require.NoError(t, p.Pace(ctx))
+	require.Empty(t, workQueue.buf.String())
+
+	// Once it has used up its grant, the overage is accounted for and it is
+	// admitted again.
+	p.cur.testingOverrideRunningTime = func() time.Duration { return 60 * time.Millisecond }
+	require.NoError(t, p.Pace(ctx))
+	require.Equal(t, "took-without-permission=10ms", strings.TrimSpace(granter.buf.String()))
+	require.Equal(t, "admitted=50ms", strings.TrimSpace(workQueue.buf.String()))
+
+	// Closing the pacer returns what is left of the grant.
+	granter.buf.Reset()
+	p.cur.testingOverrideRunningTime = func() time.Duration { return 20 * time.Millisecond }
+	p.Close()
+	require.Equal(t, "return-grant=30ms", strings.TrimSpace(granter.buf.String()))
+	require.Nil(t, p.cur)
+
+	// A nil pacer, as used when there is no elastic CPU grant coordinator, is a
+	// no-op.
+	var nilCoord *ElasticCPUGrantCoordinator
+	p = nilCoord.NewPacer(50*time.Millisecond, WorkInfo{})
+	require.Nil(t, p)
+	require.NoError(t, p.Pace(ctx))
+	p.Close()
+}